@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// Where a confirmation came from, for the run's events.
+const (
+	confirmFromAPI    = "the API"
+	confirmFromButton = "the confirm button"
+)
+
+// confirmRequest carries a confirmation from the API to the run loop, and
+// back whether the FSM took it.
+type confirmRequest struct {
+	source   string
+	accepted chan bool
+}
+
+// confirm records the operator's go-ahead for the running confirm step. One
+// that arrives while no confirm step is running is dropped: it was meant for a
+// step that has already ended, or for none, and must not carry over to the
+// next one.
+func (p *programFSMController) confirm(source string) bool {
+	if p.state != fsmStateConfirm {
+		log.Debug("FSM: confirmation from %s ignored in %s", source, p.state)
+		return false
+	}
+	if p.confirmedBy == "" {
+		p.confirmedBy = source
+	}
+	return true
+}
+
+// confirmationStatus is what the running confirm step is waiting for, nil in
+// any other state.
+func (p *programFSMController) confirmationStatus() *types.ConfirmationStatus {
+	if p.state != fsmStateConfirm {
+		return nil
+	}
+	step := &p.program.ProgramSteps[p.step]
+	status := &types.ConfirmationStatus{Prompt: step.Prompt, Since: p.stepStarted}
+	if step.Runtime != nil {
+		status.TimeoutSeconds = int64(step.Runtime.Seconds())
+		status.OnTimeout = step.OnTimeout
+		if status.OnTimeout == "" {
+			status.OnTimeout = types.ConfirmTimeoutFail
+		}
+	}
+	return status
+}
@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rmkhl/halko/types"
+)
+
+// confirmFSM builds a run just into a confirm step that holds the fan at 30%
+// with everything else off, waiting on the operator for up to an hour.
+func confirmFSM(t *testing.T, onTimeout types.ConfirmTimeoutAction) (*programFSMController, func() map[string]uint8) {
+	t.Helper()
+
+	psu, commanded := recordingPowerUnit(t)
+	fsm := &programFSMController{
+		state:   fsmStateConfirm,
+		started: time.Now().Unix() - 7200,
+		program: &types.Program{ProgramSteps: []types.ProgramStep{{
+			Name: "pull samples", StepType: types.StepTypeConfirm, Prompt: "Weigh the sample boards",
+			Runtime: &types.StepDuration{Duration: time.Hour}, OnTimeout: onTimeout,
+			Heater: &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(0)},
+			Fan:    &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(30)},
+			Steam:  &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(0)},
+			Vent:   &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(0)},
+		}}},
+		numberOfSteps:       1,
+		psuController:       psu,
+		currentPSUStatus:    &fsmPSUStatus{},
+		currentTemperatures: &fsmTemperatures{},
+		defaults:            &types.Defaults{SensorTimeoutSeconds: testSensorTimeoutSeconds, DoorOpenAction: types.DoorOpenActionFail},
+	}
+	fsm.stateHandlers = map[fsmState]fsmStateHandler{
+		fsmStateConfirm:         &confirmStateHandler{fsm: fsm},
+		fsmStateNextProgramStep: &nextProgramStepHandler{fsm: fsm},
+		fsmStateFailed:          &failedStateHandler{fsm: fsm},
+	}
+	fsm.stateHandlers[fsmStateConfirm].enterState()
+	fsm.stepStarted = time.Now().Unix()
+	return fsm, commanded
+}
+
+func confirmReading(fsm *programFSMController, now int64, inputs sensorInputs) {
+	inputs.known = true
+	fsm.currentTemperatures.observe(temperatureReadings{Kiln: 60, Material: 62, Inputs: inputs}, now)
+}
+
+func TestConfirmStepHoldsUntilConfirmed(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		confirm func(*programFSMController, int64)
+		from    string
+	}{
+		{"from the button", func(fsm *programFSMController, now int64) {
+			confirmReading(fsm, now, sensorInputs{confirm: true})
+		}, confirmFromButton},
+		{"from the API", func(fsm *programFSMController, _ int64) {
+			if !fsm.confirm(confirmFromAPI) {
+				t.Fatal("confirm refused while waiting")
+			}
+		}, confirmFromAPI},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now().Unix()
+			fsm, commanded := confirmFSM(t, "")
+
+			confirmReading(fsm, now, sensorInputs{})
+			fsm.executeTickAt(now)
+			fsm.executeTickAt(now + 1)
+			if fsm.state != fsmStateConfirm {
+				t.Fatalf("state = %q before confirming, want %q", fsm.state, fsmStateConfirm)
+			}
+			if fan, heater := commanded()[psuFan], commanded()[psuOven]; fan != 30 || heater != 0 {
+				t.Errorf("holding with fan %d%% and heater %d%%, want 30%% and 0%%", fan, heater)
+			}
+
+			tt.confirm(fsm, now+2)
+			fsm.executeTickAt(now + 2)
+			if fsm.state != fsmStateNextProgramStep {
+				t.Fatalf("state = %q after confirming, want %q", fsm.state, fsmStateNextProgramStep)
+			}
+			if len(fsm.events) != 1 || fsm.events[0].Kind != types.RunEventOperatorConfirmed {
+				t.Fatalf("events = %+v, want the confirmation", fsm.events)
+			}
+		})
+	}
+}
+
+// A press before the step began was meant for something else.
+func TestConfirmOutsideAConfirmStepIsDropped(t *testing.T) {
+	fsm, _ := confirmFSM(t, "")
+	fsm.state = fsmStateAcclimate
+
+	if fsm.confirm(confirmFromAPI) {
+		t.Error("confirm taken outside a confirm step")
+	}
+	if fsm.confirmedBy != "" {
+		t.Errorf("confirmedBy = %q, want it left empty", fsm.confirmedBy)
+	}
+}
+
+func TestConfirmStepTimeout(t *testing.T) {
+	for _, tt := range []struct {
+		onTimeout types.ConfirmTimeoutAction
+		want      fsmState
+	}{
+		{"", fsmStateFailed},
+		{types.ConfirmTimeoutFail, fsmStateFailed},
+		{types.ConfirmTimeoutContinue, fsmStateNextProgramStep},
+	} {
+		now := time.Now().Unix()
+		fsm, _ := confirmFSM(t, tt.onTimeout)
+		fsm.stepStarted = now - 3600
+
+		confirmReading(fsm, now, sensorInputs{})
+		fsm.executeTickAt(now)
+
+		if fsm.state != tt.want {
+			t.Errorf("on_timeout %q: state = %q, want %q", tt.onTimeout, fsm.state, tt.want)
+		}
+		if len(fsm.events) != 1 || fsm.events[0].Kind != types.RunEventConfirmTimedOut {
+			t.Errorf("on_timeout %q: events = %+v, want the timeout", tt.onTimeout, fsm.events)
+		}
+	}
+}
+
+// The operator is expected to open the door during the step, so even a kiln
+// set to fail on an open door only holds for it.
+func TestConfirmStepHoldsForAnOpenDoor(t *testing.T) {
+	now := time.Now().Unix()
+	fsm, commanded := confirmFSM(t, "")
+
+	confirmReading(fsm, now, sensorInputs{doorOpen: true})
+	fsm.executeTickAt(now)
+
+	if fsm.state != fsmStateConfirm || fsm.pausedAt != now {
+		t.Fatalf("state = %q, paused at %d, want holding in %q", fsm.state, fsm.pausedAt, fsmStateConfirm)
+	}
+	if fan := commanded()[psuFan]; fan != 0 {
+		t.Errorf("fan commanded to %d%% with the door open, want 0%%", fan)
+	}
+}
+
+func TestConfirmationStatus(t *testing.T) {
+	fsm, _ := confirmFSM(t, "")
+	var status types.ExecutionStatus
+
+	fsm.UpdateStatus(&status)
+	want := types.ConfirmationStatus{Prompt: "Weigh the sample boards", Since: fsm.stepStarted,
+		TimeoutSeconds: 3600, OnTimeout: types.ConfirmTimeoutFail}
+	if status.Confirmation == nil || *status.Confirmation != want {
+		t.Fatalf("confirmation = %+v, want %+v", status.Confirmation, want)
+	}
+
+	fsm.state = fsmStateNextProgramStep
+	fsm.UpdateStatus(&status)
+	if status.Confirmation != nil {
+		t.Errorf("confirmation = %+v after the step, want nil", status.Confirmation)
+	}
+}
+
+// A confirmation from the API goes through the run loop, which writes the
+// status while the HTTP handler waits; go test -race checks they never share
+// it. Once the loop has stopped, a confirmation is refused rather than left
+// waiting.
+func TestConfirmThroughTheRunLoop(t *testing.T) {
+	fsm, _ := confirmFSM(t, "")
+	runner := &programRunner{
+		fsmController:  fsm,
+		confirmations:  make(chan confirmRequest),
+		sensorShutdown: make(chan struct{}),
+		programStatus:  &types.ExecutionStatus{},
+	}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case request := <-runner.confirmations:
+				runner.serveConfirmation(request)
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond):
+			}
+			fsm.UpdateStatus(runner.programStatus)
+		}
+	}()
+
+	if !runner.confirm(confirmFromAPI) {
+		t.Error("confirm refused while waiting")
+	}
+	close(stop)
+	<-stopped
+	if fsm.confirmedBy != confirmFromAPI {
+		t.Errorf("confirmedBy = %q, want %q", fsm.confirmedBy, confirmFromAPI)
+	}
+
+	close(runner.sensorShutdown)
+	if runner.confirm(confirmFromAPI) {
+		t.Error("confirm taken after the run loop stopped")
+	}
+}
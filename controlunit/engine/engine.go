@@ -18,6 +18,7 @@ type (
 		halkoConfig      *types.HalkoConfig
 		storage          *storagefs.ExecutorFileStorage
 		runner           *programRunner
+		busy             bool
 		endpoints        *types.APIEndpoints
 		heartbeatManager *heartbeat.Manager
 	}
@@ -26,6 +27,8 @@ type (
 var (
 	ErrProgramAlreadyRunning = errors.New("program already running")
 	ErrNoProgramRunning      = errors.New("no program running")
+	ErrNotAwaitingConfirm    = errors.New("program is not waiting for confirmation")
+	ErrEngineBusy            = errors.New("storage is being repaired")
 )
 
 func NewEngine(halkoConfig *types.HalkoConfig, storage *storagefs.ExecutorFileStorage, endpoints *types.APIEndpoints, heartbeatMgr *heartbeat.Manager) *ControlEngine {
@@ -73,6 +76,10 @@ func (engine *ControlEngine) StartEngine(program *types.Program) error {
 		engine.mu.Unlock()
 		return ErrProgramAlreadyRunning
 	}
+	if engine.busy {
+		engine.mu.Unlock()
+		return ErrEngineBusy
+	}
 
 	// The startup steps go in front of the program's own before the runner is
 	// built, so the executed-program record written by CreateExecutedProgram
@@ -106,6 +113,31 @@ func (engine *ControlEngine) StartEngine(program *types.Program) error {
 	return nil
 }
 
+// WhileIdle runs work, such as a storage repair that moves run files about,
+// with the kiln idle: it refuses if a program is running, and no program can
+// start until work returns. It does not hold the lock meanwhile, so the
+// status can still be read.
+func (engine *ControlEngine) WhileIdle(work func() error) error {
+	engine.mu.Lock()
+	if engine.runner != nil {
+		engine.mu.Unlock()
+		return ErrProgramAlreadyRunning
+	}
+	if engine.busy {
+		engine.mu.Unlock()
+		return ErrEngineBusy
+	}
+	engine.busy = true
+	engine.mu.Unlock()
+
+	defer func() {
+		engine.mu.Lock()
+		engine.busy = false
+		engine.mu.Unlock()
+	}()
+	return work()
+}
+
 func (engine *ControlEngine) StopEngine() error {
 	engine.mu.Lock()
 	runner := engine.runner
@@ -120,6 +152,21 @@ func (engine *ControlEngine) StopEngine() error {
 	return ErrNoProgramRunning
 }
 
+// ConfirmStep confirms the confirm step the running program is waiting on.
+func (engine *ControlEngine) ConfirmStep() error {
+	engine.mu.RLock()
+	runner := engine.runner
+	engine.mu.RUnlock()
+
+	if runner == nil {
+		return ErrNoProgramRunning
+	}
+	if !runner.confirm(confirmFromAPI) {
+		return ErrNotAwaitingConfirm
+	}
+	return nil
+}
+
 func (engine *ControlEngine) Wait() {
 	engine.wg.Wait()
 }
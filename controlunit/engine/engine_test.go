@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+// A program cannot start while the kiln is held idle, and the kiln cannot be
+// held idle while a program runs.
+func TestWhileIdleKeepsProgramsFromStarting(t *testing.T) {
+	engine := &ControlEngine{}
+
+	err := engine.WhileIdle(func() error {
+		if err := engine.StartEngine(&types.Program{}); !errors.Is(err, ErrEngineBusy) {
+			t.Errorf("StartEngine during the work = %v, want %v", err, ErrEngineBusy)
+		}
+		if err := engine.WhileIdle(func() error { return nil }); !errors.Is(err, ErrEngineBusy) {
+			t.Errorf("WhileIdle during the work = %v, want %v", err, ErrEngineBusy)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WhileIdle = %v", err)
+	}
+	if engine.busy {
+		t.Error("engine still busy after the work returned")
+	}
+
+	engine.runner = &programRunner{}
+	ran := false
+	if err := engine.WhileIdle(func() error { ran = true; return nil }); !errors.Is(err, ErrProgramAlreadyRunning) || ran {
+		t.Errorf("WhileIdle during a run = %v (ran %v), want %v", err, ran, ErrProgramAlreadyRunning)
+	}
+}
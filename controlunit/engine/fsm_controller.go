@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/rmkhl/halko/types"
@@ -18,6 +19,9 @@ const (
 	fsmStateAcclimate       fsmState = "acclimate"
 	fsmStateCoolDown        fsmState = "cool_down"
 	fsmStateFailed          fsmState = "failed"
+
+	fsmStateHeatTreatment fsmState = "heat_treatment"
+	fsmStateConfirm       fsmState = "confirm"
 )
 
 type (
@@ -62,6 +66,7 @@ type (
 		fanPower    PowerController
 		heaterPower PowerController
 		steamPower  PowerController
+		ventPower   PowerController
 	}
 
 	acclimateStateHandler struct {
@@ -69,10 +74,33 @@ type (
 		fanPower    PowerController
 		heaterPower PowerController
 		steamPower  PowerController
+		ventPower   PowerController
 		// Resolved when the step is entered. elapsed is a second count, so
 		// keeping the runtime in the same unit avoids converting the step's
-		// duration on every tick.
-		runtimeSeconds int64
+		// duration on every tick. An acclimate ending on moisture need not
+		// have a runtime, so hasRuntimeLimit says whether it has one.
+		runtimeSeconds  int64
+		hasRuntimeLimit bool
+
+		// Set for a step that ends on stability, nil otherwise, with the
+		// least time it runs before the material may end it.
+		stability         *stabilityTracker
+		minRuntimeSeconds int64
+	}
+
+	// heatTreatmentStateHandler holds the kiln at the target like an
+	// acclimate, and ends once the material has held at the threshold for as
+	// long as the step asks. The step's runtime, if it has one, is the most
+	// the kiln may take getting there; running out of it fails the run, since
+	// a load that was not treated must not pass as one that was.
+	heatTreatmentStateHandler struct {
+		fsm             *programFSMController
+		fanPower        PowerController
+		heaterPower     PowerController
+		steamPower      PowerController
+		ventPower       PowerController
+		runtimeSeconds  int64
+		hasRuntimeLimit bool
 	}
 
 	coolDownStateHandler struct {
@@ -80,11 +108,29 @@ type (
 		fanPower    PowerController
 		heaterPower PowerController
 		steamPower  PowerController
+		ventPower   PowerController
 		// Resolved when the step is entered. A cooling step's runtime is
 		// optional, so hasRuntimeLimit says whether runtimeSeconds means
 		// anything.
 		runtimeSeconds  int64
 		hasRuntimeLimit bool
+
+		// Set when a thermal modification's cooling step begins above the
+		// blanket threshold, nil otherwise.
+		ramp *rampDown
+	}
+
+	// confirmStateHandler holds the kiln at the step's constant powers until
+	// the operator confirms, from the API or the sensor unit's button. The
+	// step's runtime, if it has one, is how long it waits for them.
+	confirmStateHandler struct {
+		fsm             *programFSMController
+		fanPower        PowerController
+		heaterPower     PowerController
+		steamPower      PowerController
+		ventPower       PowerController
+		runtimeSeconds  int64
+		hasRuntimeLimit bool
 	}
 
 	failedStateHandler struct {
@@ -124,9 +170,51 @@ type (
 		stateHandlers map[fsmState]fsmStateHandler
 		stepToState   map[types.StepType]fsmState
 		defaults      *types.Defaults
+
+		// When the run started holding for an open door, zero while it is
+		// not. The step's clock is moved on by the length of the hold when
+		// the door shuts, so a timed step still gets its full runtime.
+		pausedAt int64
+
+		// What happened to the run that the execution log cannot show. Only
+		// ever appended to; the runner persists the entries it has not seen.
+		events []types.RunEvent
+
+		// Watches the per-probe readings for one drifting away from its
+		// group, which the fused readings the controllers work from hide.
+		probes probeMonitor
+
+		// Whether the sensor unit last said its kiln probes were degraded,
+		// so the run records the change rather than every tick of it.
+		kilnDegraded bool
+
+		// How many readings the plausibility check has rejected this run.
+		rejectedReadings int
+
+		// The running heat treatment step's hold, nil in any other step. It
+		// is kept on the controller rather than the state handler because it
+		// goes on counting while the door is open and the handler is not run.
+		hold *heatTreatmentHold
+
+		// The kiln's thermal modification settings when the run is one, nil
+		// otherwise, and since when the steam has been short of the blanket
+		// (zero while it is not).
+		thermal          *types.ThermalModificationDefaults
+		blanketThinSince int64
+		// Set once the stop button is pressed, which switches everything
+		// off, the steam blanket too.
+		emergencyStopped bool
+
+		// Where the confirmation of the running confirm step came from,
+		// empty until it has one.
+		confirmedBy string
 	}
 )
 
+// pausedForDoor is what ExecutionStatus.Paused reads while the run is holding
+// for an open door.
+const pausedForDoor = "door_open"
+
 func (h *startStateHandler) executeState() fsmState {
 	log.Debug("FSM: start state - transitioning to waiting")
 	return fsmStateWaiting
@@ -196,6 +284,7 @@ func (h *equalizeStateHandler) executeState() fsmState {
 	h.fsm.psuController.setPower(psuFan, fan)
 	h.fsm.psuController.setPower(psuOven, 0)
 	h.fsm.psuController.setPower(psuSteam, 0)
+	h.fsm.psuController.setPower(psuVent, 0)
 
 	return next
 }
@@ -252,6 +341,7 @@ func (h *steamPrewarmStateHandler) executeState() fsmState {
 	h.fsm.psuController.setPower(psuSteam, 100)
 	h.fsm.psuController.setPower(psuOven, 0)
 	h.fsm.psuController.setPower(psuFan, 0)
+	h.fsm.psuController.setPower(psuVent, 0)
 
 	if kiln-material > h.entryGap+delta {
 		log.Info("FSM: steam warm-up - kiln (%.1f°C) has risen %.1f°C on the material (%.1f°C) since the step began, the generator is producing",
@@ -281,6 +371,8 @@ func (h *steamPrewarmStateHandler) enterState() {
 func (h *nextProgramStepHandler) executeState() fsmState {
 	// Note this assumes that before first call fsm.steps is set to -1
 	h.fsm.step++
+	h.fsm.hold = nil
+	h.fsm.confirmedBy = ""
 	// End of the program reached
 	if h.fsm.step >= h.fsm.numberOfSteps {
 		log.Info("FSM: All steps completed (step %d >= %d), transitioning to idle",
@@ -310,24 +402,33 @@ func (h *heatUpStateHandler) executeState() fsmState {
 			h.fsm.temperatures.reading.Material, h.fsm.program.ProgramSteps[h.fsm.step].TargetTemperature)
 		return fsmStateNextProgramStep
 	}
+	if h.fsm.moistureReached() {
+		return fsmStateNextProgramStep
+	}
 	log.Trace("FSM: heat_up - heating (material: %.1f°C / target: %d°C)",
 		h.fsm.temperatures.reading.Material, h.fsm.program.ProgramSteps[h.fsm.step].TargetTemperature)
 	// If we have new temperature readings, update the power settings
 	if h.fsm.currentTemperatures.updated >= h.fsm.temperatures.updated {
 		log.Debug("FSM: heat_up - updating power (kiln: %.1f°C, material: %.1f°C)",
 			h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material)
-		heaterPower := h.heaterPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material)
-		h.fsm.psuController.setPower(psuOven, heaterPower)
-		log.Trace("FSM: heat_up - heater power: %d%%", heaterPower)
-
-		fanResult := h.fanPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material)
+		// The fan goes first, so that a power unit interlock requiring it
+		// sees this tick's fan rather than the last one's.
+		fanResult := h.fanPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate)
 		h.fsm.psuController.setPower(psuFan, fanResult)
 		log.Trace("FSM: heat_up - fan power: %d%%", fanResult)
 
-		steamResult := h.steamPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material)
+		heaterPower := h.heaterPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate)
+		h.fsm.psuController.setPower(psuOven, heaterPower)
+		log.Trace("FSM: heat_up - heater power: %d%%", heaterPower)
+
+		steamResult := h.steamPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate)
 		h.fsm.psuController.setPower(psuSteam, steamResult)
 		log.Trace("FSM: heat_up - steam power: %d%%", steamResult)
 
+		ventResult := h.ventPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate)
+		h.fsm.psuController.setPower(psuVent, ventResult)
+		log.Trace("FSM: heat_up - vent power: %d%%", ventResult)
+
 		// Mark these temperature readings as processed
 		h.fsm.temperatures.updated = h.fsm.currentTemperatures.updated
 	}
@@ -337,27 +438,65 @@ func (h *heatUpStateHandler) executeState() fsmState {
 func (h *heatUpStateHandler) enterState() {
 	log.Info("FSM: Entered heat_up state - target: %d°C", h.fsm.program.ProgramSteps[h.fsm.step].TargetTemperature)
 	step := &h.fsm.program.ProgramSteps[h.fsm.step]
-	h.fanPower = NewPowerController(step.StepType, 0, step.Fan)
+	if step.TargetMoisture != nil {
+		log.Info("FSM: heat_up also ends once the material is down to %.1f%% moisture", *step.TargetMoisture)
+	}
+	h.fanPower = NewFanController(step.Fan)
 	h.heaterPower = NewPowerController(step.StepType, float32(step.TargetTemperature), step.Heater)
 	h.steamPower = NewPowerController(step.StepType, 0, step.Steam)
+	h.ventPower = NewVentController(step.Vent)
 }
 
 func (h *acclimateStateHandler) executeState() fsmState {
 	// Once we have been acclimating long enough, we can move to the next step
 	elapsed := time.Now().Unix() - h.fsm.stepStarted
-	if elapsed >= h.runtimeSeconds {
-		log.Info("FSM: acclimate - runtime complete (%ds / %ds)", elapsed, h.runtimeSeconds)
+	// Taken at the time the material was last read, so a value held over
+	// an invalid reading is not counted as a second one.
+	if h.stability != nil && h.fsm.temperatures.materialValidAt != 0 {
+		h.stability.observe(h.fsm.temperatures.materialValidAt, h.fsm.temperatures.reading.Material)
+	}
+	if h.hasRuntimeLimit && elapsed >= h.runtimeSeconds {
+		if h.stability != nil {
+			log.Warning("FSM: acclimate - ended on its runtime cap (%ds) before the material settled (material: %.1f°C, moving %.2f°C/h)",
+				h.runtimeSeconds, h.fsm.temperatures.reading.Material, h.stability.rate())
+		} else {
+			log.Info("FSM: acclimate - ended on runtime (%ds / %ds)", elapsed, h.runtimeSeconds)
+		}
+		return fsmStateNextProgramStep
+	}
+	if h.stability != nil && elapsed >= h.minRuntimeSeconds && h.stability.settled() {
+		log.Info("FSM: acclimate - ended on stability after %ds (material: %.1f°C within %.1f°C of target, moving %.2f°C/h, below %.2f°C/h, for %ds)",
+			elapsed, h.fsm.temperatures.reading.Material, h.stability.within, h.stability.rate(), h.stability.maxRate, h.stability.hold)
+		return fsmStateNextProgramStep
+	}
+	if h.fsm.moistureReached() {
+		log.Info("FSM: acclimate - ended on moisture target after %ds", elapsed)
 		return fsmStateNextProgramStep
 	}
+	// Without a runtime the moisture pins are what ends the step, so losing
+	// them would leave it holding the kiln forever. Give them as long as any
+	// other sensor gets.
+	if !h.hasRuntimeLimit && h.fsm.program.ProgramSteps[h.fsm.step].TargetMoisture != nil {
+		now := time.Now().Unix()
+		since := max(h.fsm.temperatures.moistureValidAt, h.fsm.stepStarted)
+		if now-since > h.fsm.defaults.SensorTimeoutSeconds {
+			log.Error("FSM: acclimate - no moisture reading for %ds (limit %ds) and no runtime to end on - failing program",
+				now-since, h.fsm.defaults.SensorTimeoutSeconds)
+			h.fsm.recordEvent(now, types.RunEventSensorTimeout, "No moisture reading for %ds (limit %ds) on a step with no runtime to end on, program failed",
+				now-since, h.fsm.defaults.SensorTimeoutSeconds)
+			return fsmStateFailed
+		}
+	}
 	log.Trace("FSM: acclimate - maintaining temperature (%ds / %ds, material: %.1f°C, target: %d°C)",
 		elapsed, h.runtimeSeconds, h.fsm.temperatures.reading.Material, h.fsm.program.ProgramSteps[h.fsm.step].TargetTemperature)
 	// If we have new temperature readings, update the power settings
 	if h.fsm.currentTemperatures.updated >= h.fsm.temperatures.updated {
 		log.Debug("FSM: acclimate - updating power (kiln: %.1f°C, material: %.1f°C)",
 			h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material)
-		h.fsm.psuController.setPower(psuOven, h.heaterPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material))
-		h.fsm.psuController.setPower(psuFan, h.fanPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material))
-		h.fsm.psuController.setPower(psuSteam, h.steamPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material))
+		h.fsm.psuController.setPower(psuFan, h.fanPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
+		h.fsm.psuController.setPower(psuOven, h.heaterPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
+		h.fsm.psuController.setPower(psuSteam, h.steamPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
+		h.fsm.psuController.setPower(psuVent, h.ventPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
 
 		// Mark these temperature readings as processed
 		h.fsm.temperatures.updated = h.fsm.currentTemperatures.updated
@@ -367,12 +506,87 @@ func (h *acclimateStateHandler) executeState() fsmState {
 
 func (h *acclimateStateHandler) enterState() {
 	step := &h.fsm.program.ProgramSteps[h.fsm.step]
-	h.runtimeSeconds = int64(step.Runtime.Seconds())
-	log.Info("FSM: Entered acclimate state - target: %d°C, duration: %ds",
-		step.TargetTemperature, h.runtimeSeconds)
-	h.fanPower = NewPowerController(step.StepType, 0, step.Fan)
+	h.hasRuntimeLimit = step.Runtime != nil
+	h.runtimeSeconds = 0
+	if h.hasRuntimeLimit {
+		h.runtimeSeconds = int64(step.Runtime.Seconds())
+	}
+	if h.hasRuntimeLimit {
+		log.Info("FSM: Entered acclimate state - target: %d°C, runtime: %ds",
+			step.TargetTemperature, h.runtimeSeconds)
+	} else {
+		log.Info("FSM: Entered acclimate state - target: %d°C, no runtime limit", step.TargetTemperature)
+	}
+	if step.TargetMoisture != nil {
+		log.Info("FSM: acclimate ends once the material is down to %.1f%% moisture", *step.TargetMoisture)
+	}
+	h.stability = nil
+	h.minRuntimeSeconds = 0
+	if step.Stability != nil {
+		h.stability = newStabilityTracker(float32(step.TargetTemperature), step.Stability)
+		if step.Stability.MinRuntime != nil {
+			h.minRuntimeSeconds = int64(step.Stability.MinRuntime.Seconds())
+		}
+		log.Info("FSM: acclimate ends once the material holds within %.1f°C of target, moving under %.2f°C/h, for %ds (no sooner than %ds)",
+			step.Stability.Within, step.Stability.MaxRate, h.stability.hold, h.minRuntimeSeconds)
+	}
+	h.fanPower = NewFanController(step.Fan)
+	h.heaterPower = NewPowerController(step.StepType, float32(step.TargetTemperature), step.Heater)
+	h.steamPower = NewPowerController(step.StepType, 0, step.Steam)
+	h.ventPower = NewVentController(step.Vent)
+}
+
+func (h *heatTreatmentStateHandler) executeState() fsmState {
+	hold := h.fsm.hold
+	if hold.met() {
+		log.Info("FSM: heat_treatment - hold met (%ds at or above %.1f°C, %ds required)",
+			hold.held(), hold.threshold, hold.required)
+		h.fsm.recordEvent(hold.last, types.RunEventHoldMet, "Material held at or above %.1f°C for %ds of the %ds required, %d dips",
+			hold.threshold, hold.held(), hold.required, hold.dips)
+		return fsmStateNextProgramStep
+	}
+	now := time.Now().Unix()
+	elapsed := now - h.fsm.stepStarted
+	if h.hasRuntimeLimit && elapsed >= h.runtimeSeconds {
+		log.Error("FSM: heat_treatment - runtime over (%ds / %ds) with the hold at %ds of %ds - failing program",
+			elapsed, h.runtimeSeconds, hold.held(), hold.required)
+		h.fsm.recordEvent(now, types.RunEventHoldNotMet, "Runtime over with the material held at or above %.1f°C for %ds of the %ds required, program failed",
+			hold.threshold, hold.held(), hold.required)
+		return fsmStateFailed
+	}
+	log.Trace("FSM: heat_treatment - holding (%ds / %ds at or above %.1f°C, material: %.1f°C, target: %d°C)",
+		hold.held(), hold.required, hold.threshold, h.fsm.temperatures.reading.Material, h.fsm.program.ProgramSteps[h.fsm.step].TargetTemperature)
+	// If we have new temperature readings, update the power settings
+	if h.fsm.currentTemperatures.updated >= h.fsm.temperatures.updated {
+		log.Debug("FSM: heat_treatment - updating power (kiln: %.1f°C, material: %.1f°C)",
+			h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material)
+		h.fsm.psuController.setPower(psuFan, h.fanPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
+		h.fsm.psuController.setPower(psuOven, h.heaterPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
+		h.fsm.psuController.setPower(psuSteam, h.steamPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
+		h.fsm.psuController.setPower(psuVent, h.ventPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
+
+		// Mark these temperature readings as processed
+		h.fsm.temperatures.updated = h.fsm.currentTemperatures.updated
+	}
+	return fsmStateHeatTreatment
+}
+
+// The hold starts empty: the first tick in the step observes the material,
+// and the time before that is not the step's to claim.
+func (h *heatTreatmentStateHandler) enterState() {
+	step := &h.fsm.program.ProgramSteps[h.fsm.step]
+	h.hasRuntimeLimit = step.Runtime != nil
+	h.runtimeSeconds = 0
+	if h.hasRuntimeLimit {
+		h.runtimeSeconds = int64(step.Runtime.Seconds())
+	}
+	h.fsm.hold = newHeatTreatmentHold(step.Hold)
+	log.Info("FSM: Entered heat_treatment state - target: %d°C, holding the material at %.1f°C for %ds (%s on dip), runtime limit: %ds",
+		step.TargetTemperature, step.Hold.Threshold, h.fsm.hold.required, step.Hold.OnDip, h.runtimeSeconds)
+	h.fanPower = NewFanController(step.Fan)
 	h.heaterPower = NewPowerController(step.StepType, float32(step.TargetTemperature), step.Heater)
 	h.steamPower = NewPowerController(step.StepType, 0, step.Steam)
+	h.ventPower = NewVentController(step.Vent)
 }
 
 func (h *coolDownStateHandler) executeState() fsmState {
@@ -394,9 +608,11 @@ func (h *coolDownStateHandler) executeState() fsmState {
 	if h.fsm.currentTemperatures.updated >= h.fsm.temperatures.updated {
 		log.Debug("FSM: cool_down - updating power (kiln: %.1f°C, material: %.1f°C)",
 			h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material)
-		h.fsm.psuController.setPower(psuOven, h.heaterPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material))
-		h.fsm.psuController.setPower(psuFan, h.fanPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material))
-		h.fsm.psuController.setPower(psuSteam, h.steamPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material))
+		fan := h.fanPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate)
+		h.fsm.psuController.setPower(psuFan, h.ramp.limitFan(fan, elapsed, h.fsm.temperatures.reading.Kiln))
+		h.fsm.psuController.setPower(psuOven, h.heaterPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
+		h.fsm.psuController.setPower(psuSteam, h.steamPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
+		h.fsm.psuController.setPower(psuVent, h.ventPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
 
 		// Mark these temperature readings as processed
 		h.fsm.temperatures.updated = h.fsm.currentTemperatures.updated
@@ -411,9 +627,62 @@ func (h *coolDownStateHandler) enterState() {
 		h.runtimeSeconds = int64(step.Runtime.Seconds())
 	}
 	log.Info("FSM: Entered cool_down state - target: %d°C", step.TargetTemperature)
-	h.fanPower = NewPowerController(step.StepType, 0, step.Fan)
+	h.fanPower = NewFanController(step.Fan)
 	h.heaterPower = NewPowerController(step.StepType, float32(step.TargetTemperature), step.Heater)
+	if step.MaxCoolingRate != nil {
+		log.Info("FSM: cool_down follows a %.1f°C/h ramp", *step.MaxCoolingRate)
+		h.fanPower, h.heaterPower = NewCoolingRampControllers(step, h.fsm.stepElapsed)
+	}
 	h.steamPower = NewPowerController(step.StepType, 0, step.Steam)
+	h.ventPower = NewVentController(step.Vent)
+	h.ramp = newRampDown(h.fsm.thermal, h.fsm.currentTemperatures.reading.Kiln)
+}
+
+func (h *confirmStateHandler) executeState() fsmState {
+	now := time.Now().Unix()
+	elapsed := now - h.fsm.stepStarted
+	if h.fsm.confirmedBy != "" {
+		log.Info("FSM: confirm - confirmed from %s after %ds", h.fsm.confirmedBy, elapsed)
+		h.fsm.recordEvent(now, types.RunEventOperatorConfirmed, "Confirmed from %s after %ds", h.fsm.confirmedBy, elapsed)
+		return fsmStateNextProgramStep
+	}
+	if h.hasRuntimeLimit && elapsed >= h.runtimeSeconds {
+		if h.fsm.program.ProgramSteps[h.fsm.step].OnTimeout == types.ConfirmTimeoutContinue {
+			log.Warning("FSM: confirm - no confirmation in %ds, continuing", h.runtimeSeconds)
+			h.fsm.recordEvent(now, types.RunEventConfirmTimedOut, "No confirmation in %ds, continuing", h.runtimeSeconds)
+			return fsmStateNextProgramStep
+		}
+		log.Error("FSM: confirm - no confirmation in %ds - failing program", h.runtimeSeconds)
+		h.fsm.recordEvent(now, types.RunEventConfirmTimedOut, "No confirmation in %ds, program failed", h.runtimeSeconds)
+		return fsmStateFailed
+	}
+	log.Trace("FSM: confirm - waiting for the operator (%ds / %ds)", elapsed, h.runtimeSeconds)
+	// Constant powers, but still every tick: the power unit's idle watchdog
+	// zeroes anything it has not been told about within max_idle_time.
+	if h.fsm.currentTemperatures.updated >= h.fsm.temperatures.updated {
+		h.fsm.psuController.setPower(psuFan, h.fanPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
+		h.fsm.psuController.setPower(psuOven, h.heaterPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
+		h.fsm.psuController.setPower(psuSteam, h.steamPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
+		h.fsm.psuController.setPower(psuVent, h.ventPower.Update(h.fsm.temperatures.reading.Kiln, h.fsm.temperatures.reading.Material, h.fsm.temperatures.reading.Climate))
+
+		// Mark these temperature readings as processed
+		h.fsm.temperatures.updated = h.fsm.currentTemperatures.updated
+	}
+	return fsmStateConfirm
+}
+
+func (h *confirmStateHandler) enterState() {
+	step := &h.fsm.program.ProgramSteps[h.fsm.step]
+	h.hasRuntimeLimit = step.Runtime != nil
+	h.runtimeSeconds = 0
+	if h.hasRuntimeLimit {
+		h.runtimeSeconds = int64(step.Runtime.Seconds())
+	}
+	log.Info("FSM: Entered confirm state - waiting for the operator to: %s (runtime limit: %ds)", step.Prompt, h.runtimeSeconds)
+	h.fanPower = NewFanController(step.Fan)
+	h.heaterPower = NewPowerController(step.StepType, 0, step.Heater)
+	h.steamPower = NewPowerController(step.StepType, 0, step.Steam)
+	h.ventPower = NewVentController(step.Vent)
 }
 
 func (h *failedStateHandler) executeState() fsmState {
@@ -436,7 +705,7 @@ func (h *idleStateHandler) enterState() {
 	h.fsm.shutdown()
 }
 
-func newProgramFSMController(psuController *psuController, psuStatus *fsmPSUStatus, temperatures *fsmTemperatures, defaults *types.Defaults) *programFSMController {
+func newProgramFSMController(psuController *psuController, psuStatus *fsmPSUStatus, temperatures *fsmTemperatures, defaults *types.Defaults, calibration map[string]*types.ProbeCalibration) *programFSMController {
 	controller := &programFSMController{
 		psuController:       psuController,
 		currentPSUStatus:    psuStatus,
@@ -447,8 +716,12 @@ func newProgramFSMController(psuController *psuController, psuStatus *fsmPSUStat
 			types.StepTypeCooling:      fsmStateCoolDown,
 			types.StepTypeEqualize:     fsmStateEqualize,
 			types.StepTypeSteamPrewarm: fsmStateSteamPrewarm,
+
+			types.StepTypeHeatTreatment: fsmStateHeatTreatment,
+			types.StepTypeConfirm:       fsmStateConfirm,
 		},
 		defaults: defaults,
+		probes:   probeMonitor{calibration: calibration},
 	}
 	controller.stateHandlers = map[fsmState]fsmStateHandler{
 		fsmStateStart:           &startStateHandler{fsm: controller},
@@ -461,6 +734,9 @@ func newProgramFSMController(psuController *psuController, psuStatus *fsmPSUStat
 		fsmStateAcclimate:       &acclimateStateHandler{fsm: controller},
 		fsmStateCoolDown:        &coolDownStateHandler{fsm: controller},
 		fsmStateFailed:          &failedStateHandler{fsm: controller},
+
+		fsmStateHeatTreatment: &heatTreatmentStateHandler{fsm: controller},
+		fsmStateConfirm:       &confirmStateHandler{fsm: controller},
 	}
 	return controller
 }
@@ -476,15 +752,138 @@ func (p *programFSMController) executeTickAt(now int64) {
 		return
 	}
 
+	// Whatever this tick ends up commanding, the power unit's objections to it
+	// belong in the run's events.
+	defer p.recordPowerNotices(now)
+
+	// A probe the others disagree with is worth knowing about but not worth
+	// stopping for: the fused reading may well still be good.
+	for _, message := range p.probes.check(p.currentTemperatures.reading.Probes) {
+		log.Warning("FSM: %s", message)
+		p.recordEvent(now, types.RunEventProbeDisagreement, "%s", message)
+	}
+
+	// Already kept from the controllers and counted against the sensor
+	// timeout; what remains is to say so.
+	for _, rejection := range p.currentTemperatures.takeRejections() {
+		p.rejectedReadings++
+		log.Warning("FSM: rejected implausible %s", rejection)
+		p.recordEvent(now, types.RunEventReadingRejected, "Rejected implausible %s (%d rejected this run)",
+			rejection, p.rejectedReadings)
+	}
+
+	// The hold goes on while the door is open: the wood does not care why
+	// it is cooling, and a dip is a dip.
+	if p.hold != nil && p.hold.observe(now, p.currentTemperatures.reading.Material) {
+		log.Warning("FSM: material (%.1f°C) dipped below the %.1f°C hold threshold", p.currentTemperatures.reading.Material, p.hold.threshold)
+		if p.hold.onDip == types.HoldDipPause {
+			p.recordEvent(now, types.RunEventHoldDipped, "Material dipped below %.1f°C, hold paused at %ds of %ds",
+				p.hold.threshold, p.hold.held(), p.hold.required)
+		} else {
+			p.recordEvent(now, types.RunEventHoldDipped, "Material dipped below %.1f°C, hold starts over", p.hold.threshold)
+		}
+	}
+
+	// Taken every tick whatever the state, so a press meant for nothing in
+	// particular cannot confirm a step that comes along later. A press
+	// while the door is open still counts: shutting the door and pressing
+	// the button are the same job.
+	if p.currentTemperatures.takeConfirmPress() {
+		p.confirm(confirmFromButton)
+	}
+
+	inputs := p.currentTemperatures.reading.Inputs
+
+	// The stop button means stop. Nothing here waits for it to be released:
+	// whoever pressed it decides whether the load is worth a new run.
+	if inputs.emergencyStop {
+		log.Error("FSM: emergency stop pressed - failing program")
+		p.recordEvent(now, types.RunEventEmergencyStop, "Emergency stop pressed, program failed and all power switched off")
+		p.emergencyStopped = true
+		p.failAt(now)
+		return
+	}
+
 	// A sensor that has stopped reporting valid readings leaves the
 	// controllers working from a frozen value, so stop the program and
 	// switch everything off rather than keep heating blind.
 	if sensor, seconds := p.currentTemperatures.invalidFor(now, p.started); seconds > p.defaults.SensorTimeoutSeconds {
-		log.Error("FSM: no valid %s temperature for %ds (limit %ds) - failing program",
-			sensor, seconds, p.defaults.SensorTimeoutSeconds)
-		p.state = fsmStateFailed
-		p.stepStarted = now
-		p.stateHandlers[p.state].enterState()
+		// The probes' own fault bits say whether to look at a connector,
+		// a sheath or the board; without them it is only a timeout.
+		cause := ""
+		if faults := p.currentTemperatures.reading.Health.faultsOf(sensor); faults != "" {
+			cause = ": " + faults
+		}
+		log.Error("FSM: no valid %s temperature for %ds (limit %ds)%s - failing program",
+			sensor, seconds, p.defaults.SensorTimeoutSeconds, cause)
+		p.recordEvent(now, types.RunEventSensorTimeout, "No valid %s temperature for %ds (limit %ds), program failed%s",
+			sensor, seconds, p.defaults.SensorTimeoutSeconds, cause)
+		p.failAt(now)
+		return
+	}
+
+	// The sensor unit owns the kiln probes and decides when they disagree
+	// for too long; the run decides what that is worth.
+	if degraded := p.currentTemperatures.reading.Health; degraded.known && degraded.degraded != p.kilnDegraded {
+		p.kilnDegraded = degraded.degraded
+		if !degraded.degraded {
+			log.Info("FSM: sensor unit reports the kiln probes agree again")
+			p.recordEvent(now, types.RunEventSensorRestored, "Kiln probes agree again")
+		} else if p.defaults.DegradedSensorAction == types.DegradedSensorActionFail {
+			log.Error("FSM: kiln probes degraded (%s) - failing program", degraded.reason)
+			p.recordEvent(now, types.RunEventSensorDegraded, "Kiln probes degraded, program failed: %s", degraded.reason)
+			p.failAt(now)
+			return
+		} else {
+			log.Warning("FSM: kiln probes degraded (%s) - continuing", degraded.reason)
+			p.recordEvent(now, types.RunEventSensorDegraded, "Kiln probes degraded, continuing: %s", degraded.reason)
+		}
+	}
+
+	if inputs.doorOpen {
+		// Opening the door lets air at wood hot enough to burn in it, so
+		// there is no holding on for the door to shut.
+		if p.aboveBlanketThreshold() {
+			log.Error("FSM: door opened with the kiln at %.1f°C - failing program", p.currentTemperatures.reading.Kiln)
+			p.recordEvent(now, types.RunEventDoorOpened, "Door opened with the kiln at %.1f°C, above the %d°C steam blanket threshold, program failed with the steam blanket kept on",
+				p.currentTemperatures.reading.Kiln, p.thermal.SteamBlanketAbove)
+			p.failAt(now)
+			return
+		}
+		// A confirm step is there for the door to be opened, so it only
+		// ever holds for it.
+		if p.defaults.DoorOpenAction == types.DoorOpenActionFail && p.state != fsmStateConfirm {
+			log.Error("FSM: door opened - failing program")
+			p.recordEvent(now, types.RunEventDoorOpened, "Door opened, program failed and all power switched off")
+			p.failAt(now)
+			return
+		}
+		if p.pausedAt == 0 {
+			log.Warning("FSM: door opened - holding in %s with all power off", p.state)
+			p.recordEvent(now, types.RunEventDoorOpened, "Door opened, holding with all power off")
+			p.pausedAt = now
+		}
+		// Every channel, every tick, like any state that wants power off: the
+		// state handler that would otherwise refresh them is not running.
+		p.holdPowerOff()
+		p.psuStatus = *p.currentPSUStatus
+		p.temperatures = *p.currentTemperatures
+		return
+	}
+
+	if p.pausedAt != 0 {
+		held := now - p.pausedAt
+		log.Info("FSM: door closed after %ds - resuming %s", held, p.state)
+		p.recordEvent(now, types.RunEventDoorClosed, "Door closed after %ds, resuming", held)
+		p.stepStarted += held
+		p.pausedAt = 0
+	}
+
+	if p.steamBlanketLost(now) {
+		log.Error("FSM: steam blanket lost - failing program")
+		p.recordEvent(now, types.RunEventSteamBlanketLost, "Steam at %d%%, short of the %d%% blanket for over %ds with the kiln at %.1f°C, program failed with steam kept at the blanket power",
+			p.currentPSUStatus.reading.Steam.Percent, p.thermal.BlanketSteamPower, blanketGraceSeconds, p.currentTemperatures.reading.Kiln)
+		p.failAt(now)
 		return
 	}
 
@@ -504,7 +903,99 @@ func (p *programFSMController) executeTickAt(now int64) {
 	p.temperatures = *p.currentTemperatures
 }
 
-// Shutdown the program. If the program has not completed normally we need to turn off all power.
+// stepElapsed is how long the current step has run. A door pause moves
+// stepStarted on by the time it held, so this stops while the run is paused.
+func (p *programFSMController) stepElapsed() int64 {
+	return time.Now().Unix() - p.stepStarted
+}
+
+// failAt ends the program as failed, switching everything off.
+func (p *programFSMController) failAt(now int64) {
+	p.state = fsmStateFailed
+	p.stepStarted = now
+	p.stateHandlers[p.state].enterState()
+}
+
+// moistureReached reports whether the current step ends on moisture and the
+// sample boards are down to it. Without a reading the step carries on: it is
+// only ever ended on a moisture content actually measured.
+func (p *programFSMController) moistureReached() bool {
+	target := p.program.ProgramSteps[p.step].TargetMoisture
+	moisture := p.temperatures.reading.Moisture
+	if target == nil || moisture == nil || moisture.Material > *target {
+		return false
+	}
+	log.Info("FSM: moisture target reached (material: %.1f%% <= target: %.1f%%)", moisture.Material, *target)
+	return true
+}
+
+// holdPowerOff commands every channel to zero without ending the program,
+// but for the steam blanket (see powerOff).
+func (p *programFSMController) holdPowerOff() {
+	if p.psuController == nil {
+		return
+	}
+	p.powerOff()
+}
+
+// powerOff switches the heater, fan, steam and vent off. A thermal
+// modification above the blanket threshold keeps its steam at the blanket
+// power with the vent shut: cutting the blanket with the wood that hot is what
+// the class exists to prevent, whatever the run stopped for. Only the stop
+// button switches the blanket off too.
+func (p *programFSMController) powerOff() {
+	steam := uint8(0)
+	if p.aboveBlanketThreshold() && !p.emergencyStopped {
+		log.Warning("FSM: kiln at %.1f°C, above the %d°C steam blanket threshold - keeping steam at %d%%",
+			p.currentTemperatures.reading.Kiln, p.thermal.SteamBlanketAbove, p.thermal.BlanketSteamPower)
+		steam = p.thermal.BlanketSteamPower
+	}
+	p.psuController.setPower(psuOven, 0)
+	p.psuController.setPower(psuSteam, steam)
+	p.psuController.setPower(psuFan, 0)
+	p.psuController.setPower(psuVent, 0)
+}
+
+// recordPowerNotices turns the power unit's refusals and adjustments into run
+// events. A refused command leaves its channel where it was, which a step's
+// controller has no way of noticing; the event is how the operator does.
+func (p *programFSMController) recordPowerNotices(now int64) {
+	if p.psuController == nil {
+		return
+	}
+	for _, notice := range p.psuController.takeNotices() {
+		if notice.refused {
+			p.recordEvent(now, types.RunEventPowerRefused, "Power unit refused %s: %s", notice.psu, notice.message)
+		} else {
+			p.recordEvent(now, types.RunEventPowerAdjusted, "Power unit adjusted %s: %s", notice.psu, notice.message)
+		}
+	}
+}
+
+// recordEvent notes something that happened to the run, attributed to the
+// step that was running when it did.
+func (p *programFSMController) recordEvent(now int64, kind types.RunEventKind, format string, args ...interface{}) {
+	p.events = append(p.events, types.RunEvent{
+		Time:    now,
+		Step:    p.currentStepName(),
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (p *programFSMController) currentStepName() string {
+	switch {
+	case p.step >= 0 && p.step < p.numberOfSteps:
+		return p.program.ProgramSteps[p.step].Name
+	case p.step < 0:
+		return "Waiting"
+	default:
+		return "Completed"
+	}
+}
+
+// Shutdown the program. If the program has not completed normally we need to
+// turn off all power, but for a thermal modification's steam blanket.
 func (p *programFSMController) shutdown() {
 	if p.stopped == 0 {
 		p.stopped = time.Now().Unix()
@@ -513,9 +1004,7 @@ func (p *programFSMController) shutdown() {
 			return
 		}
 		log.Info("FSM: Shutting down - turning off all power")
-		p.psuController.setPower(psuOven, 0)
-		p.psuController.setPower(psuFan, 0)
-		p.psuController.setPower(psuSteam, 0)
+		p.powerOff()
 		log.Debug("FSM: Shutdown complete at %d", p.stopped)
 	}
 }
@@ -525,6 +1014,10 @@ func (p *programFSMController) Start(program *types.Program, startTime int64) {
 	p.state = fsmStateStart
 	p.numberOfSteps = len(program.ProgramSteps)
 	p.started = startTime
+	p.thermal = nil
+	if program.IsThermalModification() {
+		p.thermal = p.defaults.ThermalModification
+	}
 	log.Info("FSM: Starting program '%s' with %d steps at %s", program.ProgramName, p.numberOfSteps, time.Unix(startTime, 0).Format(time.RFC3339))
 	p.stateHandlers[p.state].enterState()
 }
@@ -541,14 +1034,25 @@ func (p *programFSMController) UpdateStatus(status *types.ExecutionStatus) {
 	status.StartedAt = p.started
 	status.CurrentStepStartedAt = p.stepStarted
 
-	// Set current step name based on state
-	switch {
-	case p.step >= 0 && p.step < p.numberOfSteps:
-		status.CurrentStep = p.program.ProgramSteps[p.step].Name
-	case p.step < 0:
-		status.CurrentStep = "Waiting"
-	default:
-		status.CurrentStep = "Completed"
+	status.CurrentStep = p.currentStepName()
+	status.CurrentStepNumber = 0
+	if p.step >= 0 && p.step < p.numberOfSteps {
+		status.CurrentStepNumber = p.step + 1
+	}
+
+	status.Paused = ""
+	if p.pausedAt != 0 {
+		status.Paused = pausedForDoor
+	}
+	status.HeatTreatment = nil
+	if p.hold != nil {
+		status.HeatTreatment = p.hold.status()
+	}
+	status.Confirmation = p.confirmationStatus()
+	// Copied rather than shared, so a reader of the status never sees the
+	// slice grow underneath it.
+	if len(status.Events) != len(p.events) {
+		status.Events = append([]types.RunEvent(nil), p.events...)
 	}
 
 	status.Temperatures.Material = p.temperatures.reading.Material
@@ -556,7 +1060,11 @@ func (p *programFSMController) UpdateStatus(status *types.ExecutionStatus) {
 	status.Temperatures.MaterialDie = p.temperatures.reading.MaterialDie
 	status.Temperatures.KilnPrimaryDie = p.temperatures.reading.KilnPrimaryDie
 	status.Temperatures.KilnSecondaryDie = p.temperatures.reading.KilnSecondaryDie
+	status.Temperatures.Probes = p.temperatures.reading.Probes
+	status.Temperatures.Climate = p.temperatures.reading.Climate
+	status.Temperatures.Moisture = p.temperatures.reading.Moisture
 	status.PowerStatus.Heater = int8(p.psuStatus.reading.Heater.Percent)
 	status.PowerStatus.Fan = int8(p.psuStatus.reading.Fan.Percent)
 	status.PowerStatus.Steam = int8(p.psuStatus.reading.Steam.Percent)
+	status.PowerStatus.Vent = int8(p.psuStatus.reading.Vent.Percent)
 }
@@ -170,6 +170,136 @@ func TestTimedStepsEndOnTheirRuntime(t *testing.T) {
 	}
 }
 
+// A step with a moisture target ends once the charge is down to it, and only
+// on a reading: a tick without one leaves it running, and an acclimate without
+// a runtime waits on the pins however long the drying takes.
+func TestStepsEndOnTheirMoistureTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   fsmState
+		step    types.ProgramStep
+		handler func(*programFSMController) fsmStateHandler
+	}{
+		{
+			name:  "heating",
+			state: fsmStateHeatUp,
+			step: types.ProgramStep{
+				Name: "heat", StepType: types.StepTypeHeating, TargetTemperature: 100,
+				TargetMoisture: f32(12),
+				Heater:         &types.PowerPidSettings{Type: types.PowerSettingTypeDelta, MinDelta: f32(5), MaxDelta: f32(10)},
+				Fan:            &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(100)},
+				Steam:          &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(0)},
+			},
+			handler: func(f *programFSMController) fsmStateHandler { return &heatUpStateHandler{fsm: f} },
+		},
+		{
+			name:  "acclimate without a runtime",
+			state: fsmStateAcclimate,
+			step: types.ProgramStep{
+				Name: "dry", StepType: types.StepTypeAcclimate, TargetTemperature: 100,
+				TargetMoisture: f32(12),
+				Heater:         &types.PowerPidSettings{Type: types.PowerSettingTypeDelta, MinDelta: f32(-1), MaxDelta: f32(3)},
+				Fan:            &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(100)},
+				Steam:          &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(0)},
+			},
+			handler: func(f *programFSMController) fsmStateHandler { return &acclimateStateHandler{fsm: f} },
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsm := &programFSMController{
+				state:               tt.state,
+				program:             &types.Program{ProgramSteps: []types.ProgramStep{tt.step}},
+				psuController:       &psuController{client: server.Client(), powerControlURL: server.URL},
+				currentPSUStatus:    &fsmPSUStatus{},
+				currentTemperatures: &fsmTemperatures{},
+				defaults:            &types.Defaults{SensorTimeoutSeconds: testSensorTimeoutSeconds},
+			}
+			// Material stays below the temperature target, so only the
+			// moisture can end either step.
+			fsm.temperatures.reading.Material = 50
+			handler := tt.handler(fsm)
+
+			// A day in, to show an acclimate without a runtime is not timed,
+			// with the pins last read a moment ago.
+			fsm.stepStarted = time.Now().Unix() - 86400
+			fsm.temperatures.moistureValidAt = time.Now().Unix() - 1
+			handler.enterState()
+			if got := handler.executeState(); got != tt.state {
+				t.Fatalf("step ended without a moisture reading: %v", got)
+			}
+
+			fsm.temperatures.reading.Moisture = &types.MoistureResponse{Valid: true, Material: 12.1}
+			if got := handler.executeState(); got != tt.state {
+				t.Fatalf("step ended above its moisture target: %v", got)
+			}
+
+			fsm.temperatures.reading.Moisture.Material = 12
+			if got := handler.executeState(); got != fsmStateNextProgramStep {
+				t.Fatalf("step did not end on its moisture target: %v", got)
+			}
+		})
+	}
+}
+
+// An acclimate that only ends on moisture fails once the pins have gone a
+// sensor timeout without a reading, rather than hold the kiln forever. One
+// with a runtime carries on and ends on that instead.
+func TestAcclimateOnMoistureFailsWithoutReadings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	for _, runtime := range []*types.StepDuration{nil, stepDuration(72 * 3600)} {
+		step := types.ProgramStep{
+			Name: "dry", StepType: types.StepTypeAcclimate, TargetTemperature: 100,
+			TargetMoisture: f32(12),
+			Runtime:        runtime,
+			Heater:         &types.PowerPidSettings{Type: types.PowerSettingTypeDelta, MinDelta: f32(-1), MaxDelta: f32(3)},
+			Fan:            &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(100)},
+			Steam:          &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(0)},
+		}
+		fsm := &programFSMController{
+			state:               fsmStateAcclimate,
+			program:             &types.Program{ProgramSteps: []types.ProgramStep{step}},
+			numberOfSteps:       1,
+			psuController:       &psuController{client: server.Client(), powerControlURL: server.URL},
+			currentPSUStatus:    &fsmPSUStatus{},
+			currentTemperatures: &fsmTemperatures{},
+			defaults:            &types.Defaults{SensorTimeoutSeconds: testSensorTimeoutSeconds},
+		}
+		fsm.temperatures.reading.Material = 50
+		handler := &acclimateStateHandler{fsm: fsm}
+		handler.enterState()
+
+		fsm.stepStarted = time.Now().Unix() - testSensorTimeoutSeconds
+		if got := handler.executeState(); got != fsmStateAcclimate {
+			t.Fatalf("runtime %v: step failed within the sensor timeout: %v", runtime, got)
+		}
+
+		fsm.stepStarted = time.Now().Unix() - testSensorTimeoutSeconds - 1
+		want := fsmStateAcclimate
+		if runtime == nil {
+			want = fsmStateFailed
+		}
+		if got := handler.executeState(); got != want {
+			t.Fatalf("runtime %v: step without moisture readings past the sensor timeout is %v, want %v", runtime, got, want)
+		}
+		if runtime == nil && (len(fsm.events) != 1 || fsm.events[0].Kind != types.RunEventSensorTimeout) {
+			t.Errorf("events = %+v, want one sensor timeout", fsm.events)
+		}
+	}
+}
+
 // equalizeProgram builds a program carrying the startup steps the engine
 // synthesizes, with the given band. The heating step is the one the equalize
 // step reads its upper bound from, so it carries the deltas a real one would.
@@ -441,3 +571,314 @@ func TestEqualizeHasNoTimeout(t *testing.T) {
 		t.Errorf("state = %v, want the step to keep waiting regardless of elapsed time", got)
 	}
 }
+
+// recordingPowerUnit stands in for the power unit and remembers the last
+// percentage each channel was commanded to.
+func recordingPowerUnit(t *testing.T) (*psuController, func() map[string]uint8) {
+	t.Helper()
+
+	var mu sync.Mutex
+	commanded := map[string]uint8{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		psuName := strings.TrimPrefix(r.URL.Path, "/")
+		var cmd PowerCommand
+		if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+			t.Errorf("decoding power command for %q: %v", psuName, err)
+		}
+		mu.Lock()
+		commanded[psuName] = cmd.Percent
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+
+	snapshot := func() map[string]uint8 {
+		mu.Lock()
+		defer mu.Unlock()
+		copied := make(map[string]uint8, len(commanded))
+		for k, v := range commanded {
+			copied[k] = v
+		}
+		return copied
+	}
+	return &psuController{client: server.Client(), powerControlURL: server.URL}, snapshot
+}
+
+// inputsFSM builds a controller 100s into an hour-long acclimate step. The
+// handlers read the wall clock, so the step is placed relative to it, and
+// callers tick at now-relative times too.
+func inputsFSM(t *testing.T, action types.DoorOpenAction, now int64) (*programFSMController, func() map[string]uint8) {
+	t.Helper()
+
+	psu, commanded := recordingPowerUnit(t)
+	fsm := &programFSMController{
+		state:   fsmStateAcclimate,
+		started: now - 200,
+		program: &types.Program{ProgramSteps: []types.ProgramStep{{
+			Name: "hold", StepType: types.StepTypeAcclimate, TargetTemperature: 100,
+			Runtime: stepDuration(3600),
+			Heater:  &types.PowerPidSettings{Type: types.PowerSettingTypeDelta, MinDelta: f32(-1), MaxDelta: f32(3)},
+			Fan:     &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(100)},
+			Steam:   &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(0)},
+		}}},
+		numberOfSteps:       1,
+		psuController:       psu,
+		currentPSUStatus:    &fsmPSUStatus{},
+		currentTemperatures: &fsmTemperatures{},
+		defaults:            &types.Defaults{SensorTimeoutSeconds: testSensorTimeoutSeconds, DoorOpenAction: action},
+	}
+	fsm.stateHandlers = map[fsmState]fsmStateHandler{
+		fsmStateAcclimate: &acclimateStateHandler{fsm: fsm},
+		fsmStateFailed:    &failedStateHandler{fsm: fsm},
+	}
+	fsm.stateHandlers[fsmStateAcclimate].enterState()
+	fsm.stepStarted = now - 100
+	return fsm, commanded
+}
+
+func setInputs(fsm *programFSMController, now int64, emergencyStop, doorOpen bool) {
+	fsm.currentTemperatures.observe(temperatureReadings{
+		Kiln: 99, Material: 90,
+		Inputs: sensorInputs{known: true, emergencyStop: emergencyStop, doorOpen: doorOpen},
+	}, now)
+}
+
+func TestEmergencyStopFailsTheProgram(t *testing.T) {
+	now := time.Now().Unix()
+	fsm, commanded := inputsFSM(t, types.DoorOpenActionPause, now)
+	setInputs(fsm, now, true, false)
+
+	fsm.executeTickAt(now)
+
+	if fsm.state != fsmStateFailed {
+		t.Fatalf("state = %q, want %q", fsm.state, fsmStateFailed)
+	}
+	for _, psuName := range []string{psuOven, psuFan, psuSteam} {
+		if percent, ok := commanded()[psuName]; !ok || percent != 0 {
+			t.Errorf("psu %q commanded to %d%% (sent: %t), want 0%%", psuName, percent, ok)
+		}
+	}
+	if len(fsm.events) != 1 || fsm.events[0].Kind != types.RunEventEmergencyStop {
+		t.Errorf("events = %+v, want one emergency stop", fsm.events)
+	}
+}
+
+// With the pause policy an open door holds the run with everything off, and
+// the time it was open does not count against the step's runtime.
+func TestOpenDoorPausesTheStepAndItsClock(t *testing.T) {
+	now := time.Now().Unix()
+	fsm, commanded := inputsFSM(t, types.DoorOpenActionPause, now)
+
+	setInputs(fsm, now, false, true)
+	fsm.executeTickAt(now)
+	setInputs(fsm, now+300, false, true)
+	fsm.executeTickAt(now + 300)
+
+	if fsm.state != fsmStateAcclimate {
+		t.Fatalf("state = %q, want the step still held", fsm.state)
+	}
+	for _, psuName := range []string{psuOven, psuFan, psuSteam} {
+		if percent := commanded()[psuName]; percent != 0 {
+			t.Errorf("psu %q commanded to %d%% while the door was open, want 0%%", psuName, percent)
+		}
+	}
+	var status types.ExecutionStatus
+	fsm.UpdateStatus(&status)
+	if status.Paused != pausedForDoor {
+		t.Errorf("status.Paused = %q, want %q", status.Paused, pausedForDoor)
+	}
+
+	setInputs(fsm, now+400, false, false)
+	fsm.executeTickAt(now + 400)
+
+	if fsm.state != fsmStateAcclimate {
+		t.Fatalf("state = %q after the door shut, want the step resumed", fsm.state)
+	}
+	if fsm.stepStarted != now-100+400 {
+		t.Errorf("stepStarted = %d, want it moved on by the 400s the door was open", fsm.stepStarted)
+	}
+	fsm.UpdateStatus(&status)
+	if status.Paused != "" {
+		t.Errorf("status.Paused = %q after the door shut, want empty", status.Paused)
+	}
+	if len(status.Events) != 2 ||
+		status.Events[0].Kind != types.RunEventDoorOpened || status.Events[1].Kind != types.RunEventDoorClosed {
+		t.Errorf("events = %+v, want door opened then closed", status.Events)
+	}
+}
+
+// A cooling ramp stands still while the door holds the run, or the fan would
+// come back at full power to catch up with a line that went on falling.
+func TestOpenDoorPausesTheCoolingRamp(t *testing.T) {
+	now := time.Now().Unix()
+	psu, commanded := recordingPowerUnit(t)
+	fsm := &programFSMController{
+		state:   fsmStateCoolDown,
+		started: now,
+		program: &types.Program{ProgramSteps: []types.ProgramStep{{
+			Name: "cool", StepType: types.StepTypeCooling, TargetTemperature: 20,
+			MaxCoolingRate: f32(30),
+			Heater:         &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(0)},
+			Fan:            &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(80)},
+			Steam:          &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(0)},
+		}}},
+		numberOfSteps:       1,
+		psuController:       psu,
+		currentPSUStatus:    &fsmPSUStatus{},
+		currentTemperatures: &fsmTemperatures{},
+		defaults:            &types.Defaults{SensorTimeoutSeconds: testSensorTimeoutSeconds, DoorOpenAction: types.DoorOpenActionPause},
+		stepStarted:         now,
+	}
+	fsm.stateHandlers = map[fsmState]fsmStateHandler{
+		fsmStateCoolDown: &coolDownStateHandler{fsm: fsm},
+		fsmStateFailed:   &failedStateHandler{fsm: fsm},
+	}
+	fsm.stateHandlers[fsmStateCoolDown].enterState()
+	tick := func(at int64, kiln float32, doorOpen bool) {
+		fsm.currentTemperatures.observe(temperatureReadings{
+			Kiln: kiln, Material: 60, Inputs: sensorInputs{known: true, doorOpen: doorOpen},
+		}, at)
+		// The handlers act on the readings the previous tick left.
+		fsm.temperatures = *fsm.currentTemperatures
+		fsm.executeTickAt(at)
+	}
+
+	// The ramp starts at 100°C, on the line, with the fan at half.
+	tick(now, 100, false)
+	if fan := commanded()[psuFan]; fan != 40 {
+		t.Fatalf("fan = %d%% on the line, want 40%%", fan)
+	}
+
+	// Forty minutes on, twenty of them with the door open: 30°C/h has
+	// brought the line down 10°C, not 20°C.
+	fsm.stepStarted -= 2400
+	tick(now+1200, 90, true)
+	tick(now+2400, 90, false)
+	if fsm.state != fsmStateCoolDown {
+		t.Fatalf("state = %q, want the step resumed", fsm.state)
+	}
+	if fan := commanded()[psuFan]; fan != 40 {
+		t.Errorf("fan = %d%% with the kiln on the paused ramp, want 40%%", fan)
+	}
+}
+
+func TestOpenDoorFailsTheProgramWhenConfiguredTo(t *testing.T) {
+	now := time.Now().Unix()
+	fsm, _ := inputsFSM(t, types.DoorOpenActionFail, now)
+	setInputs(fsm, now, false, true)
+
+	fsm.executeTickAt(now)
+
+	if fsm.state != fsmStateFailed {
+		t.Fatalf("state = %q, want %q", fsm.state, fsmStateFailed)
+	}
+}
+
+// A sensor unit that cannot be asked about the switches has said nothing
+// about them. A pressed stop must not be forgotten because the next query
+// failed.
+func TestUnknownInputsKeepTheLastKnownState(t *testing.T) {
+	var temperatures fsmTemperatures
+	temperatures.observe(temperatureReadings{Inputs: sensorInputs{known: true, doorOpen: true}}, 100)
+	temperatures.observe(temperatureReadings{}, 110)
+
+	if !temperatures.reading.Inputs.doorOpen {
+		t.Error("door reads shut after a failed query, want it still open")
+	}
+}
+
+func TestPowerUnitRefusalBecomesARunEvent(t *testing.T) {
+	now := time.Now().Unix()
+	fsm, _ := inputsFSM(t, types.DoorOpenActionPause, now)
+	setInputs(fsm, now, false, false)
+	fsm.psuController.notice(psuOven, true, "Refused by interlock: heater requires fan at 30% or more, fan would be at 0%")
+
+	fsm.executeTickAt(now)
+
+	if len(fsm.events) != 1 {
+		t.Fatalf("expected one event, got %+v", fsm.events)
+	}
+	event := fsm.events[0]
+	if event.Kind != types.RunEventPowerRefused || event.Step != "hold" || !strings.Contains(event.Message, "heater requires fan") {
+		t.Fatalf("expected the refusal recorded against the step, got %+v", event)
+	}
+}
+
+// A disagreeing probe is recorded but does not stop the run: the fused reading
+// the controllers work from may well still be good.
+func TestProbeDisagreementBecomesARunEvent(t *testing.T) {
+	now := time.Now().Unix()
+	fsm, _ := inputsFSM(t, types.DoorOpenActionPause, now)
+	fsm.currentTemperatures.observe(temperatureReadings{
+		Kiln: 99, Material: 90,
+		Probes: map[string]float32{"material": 90, "material_2": 91, "material_3": 70},
+		Inputs: sensorInputs{known: true},
+	}, now)
+
+	fsm.executeTickAt(now)
+	fsm.executeTickAt(now + 1)
+
+	if fsm.state != fsmStateAcclimate {
+		t.Fatalf("state = %q, want the run to carry on in %q", fsm.state, fsmStateAcclimate)
+	}
+	if len(fsm.events) != 1 {
+		t.Fatalf("expected one event for the onset, got %+v", fsm.events)
+	}
+	if event := fsm.events[0]; event.Kind != types.RunEventProbeDisagreement || !strings.Contains(event.Message, "material_3") {
+		t.Fatalf("expected the disagreeing probe recorded, got %+v", event)
+	}
+}
+
+func setDegraded(fsm *programFSMController, now int64, degraded bool) {
+	fsm.currentTemperatures.observe(temperatureReadings{
+		Kiln: 99, Material: 90,
+		Inputs: sensorInputs{known: true},
+		Health: sensorHealth{known: true, degraded: degraded, reason: "kiln probes read 12.0°C apart"},
+	}, now)
+}
+
+// With the continue policy degraded kiln probes are recorded once, when the
+// sensor unit says so, and again when it says they have recovered.
+func TestDegradedKilnProbesAreRecordedAndTheRunContinues(t *testing.T) {
+	now := time.Now().Unix()
+	fsm, _ := inputsFSM(t, types.DoorOpenActionPause, now)
+	fsm.defaults.DegradedSensorAction = types.DegradedSensorActionContinue
+
+	setDegraded(fsm, now, true)
+	fsm.executeTickAt(now)
+	fsm.executeTickAt(now + 1)
+	setDegraded(fsm, now+2, false)
+	fsm.executeTickAt(now + 2)
+
+	if fsm.state != fsmStateAcclimate {
+		t.Fatalf("state = %q, want the run to carry on in %q", fsm.state, fsmStateAcclimate)
+	}
+	if len(fsm.events) != 2 || fsm.events[0].Kind != types.RunEventSensorDegraded || fsm.events[1].Kind != types.RunEventSensorRestored {
+		t.Fatalf("events = %+v, want sensor_degraded then sensor_restored", fsm.events)
+	}
+	if !strings.Contains(fsm.events[0].Message, "12.0°C apart") {
+		t.Errorf("expected the sensor unit's reason in %q", fsm.events[0].Message)
+	}
+}
+
+func TestDegradedKilnProbesFailTheProgramWhenConfigured(t *testing.T) {
+	now := time.Now().Unix()
+	fsm, commanded := inputsFSM(t, types.DoorOpenActionPause, now)
+	fsm.defaults.DegradedSensorAction = types.DegradedSensorActionFail
+
+	setDegraded(fsm, now, true)
+	fsm.executeTickAt(now)
+
+	if fsm.state != fsmStateFailed {
+		t.Fatalf("state = %q, want %q", fsm.state, fsmStateFailed)
+	}
+	for _, psuName := range []string{psuOven, psuFan, psuSteam} {
+		if percent, ok := commanded()[psuName]; !ok || percent != 0 {
+			t.Errorf("psu %q commanded to %d%% (sent: %t), want 0%%", psuName, percent, ok)
+		}
+	}
+	if len(fsm.events) != 1 || fsm.events[0].Kind != types.RunEventSensorDegraded {
+		t.Errorf("events = %+v, want one sensor_degraded", fsm.events)
+	}
+}
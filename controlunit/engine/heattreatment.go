@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"github.com/rmkhl/halko/types"
+)
+
+// heatTreatmentHold keeps the time a heat treatment step's material has spent
+// at or above the hold threshold. It judges the material as the execution log
+// records it, to the tenth of a degree, so that the compliance report built
+// from the log afterwards finds the same crossings the run acted on.
+type heatTreatmentHold struct {
+	threshold float32
+	required  int64
+	onDip     types.HoldDipPolicy
+
+	// Whether the material is at or above the threshold, and since when.
+	// banked is the time held before the last dip that the policy lets the
+	// hold keep. The hold is only ever as far along as its last reading,
+	// taken at last.
+	above  bool
+	since  int64
+	banked int64
+	last   int64
+	dips   int
+}
+
+func newHeatTreatmentHold(hold *types.HeatTreatmentHold) *heatTreatmentHold {
+	return &heatTreatmentHold{
+		threshold: hold.Threshold,
+		required:  int64(hold.Duration.Seconds()),
+		onDip:     hold.OnDip,
+	}
+}
+
+// observe takes the material reading at now and reports whether it has just
+// dipped below the threshold after holding.
+func (h *heatTreatmentHold) observe(now int64, material float32) bool {
+	h.last = now
+	if types.LoggedTemperature(material) >= h.threshold {
+		if !h.above {
+			h.above = true
+			h.since = now
+		}
+		return false
+	}
+	if !h.above {
+		return false
+	}
+	if h.onDip == types.HoldDipPause {
+		h.banked += now - h.since
+	} else {
+		h.banked = 0
+	}
+	h.above = false
+	h.dips++
+	return true
+}
+
+func (h *heatTreatmentHold) held() int64 {
+	if !h.above {
+		return h.banked
+	}
+	return h.banked + h.last - h.since
+}
+
+// met reports whether the hold is done. The log times its rows in whole
+// seconds, a little after or before the tick that decided them, so the hold
+// runs a second past the requirement rather than leave the report a second
+// short of it.
+func (h *heatTreatmentHold) met() bool {
+	return h.held() > h.required
+}
+
+func (h *heatTreatmentHold) status() *types.HeatTreatmentStatus {
+	return &types.HeatTreatmentStatus{
+		Threshold:       h.threshold,
+		RequiredSeconds: h.required,
+		HeldSeconds:     h.held(),
+		Holding:         h.above,
+	}
+}
@@ -0,0 +1,139 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func TestHeatTreatmentHoldCountsTimeAtTemperature(t *testing.T) {
+	type reading struct {
+		at       int64
+		material float32
+	}
+	tests := []struct {
+		name     string
+		onDip    types.HoldDipPolicy
+		readings []reading
+		wantHeld int64
+		wantDips int
+	}{
+		{"continuous", types.HoldDipReset, []reading{{0, 55}, {10, 56}, {100, 57}}, 90, 0},
+		{"a dip starts it over", types.HoldDipReset, []reading{{0, 56}, {100, 55.9}, {110, 56}, {150, 56}}, 40, 1},
+		{"a dip only pauses it", types.HoldDipPause, []reading{{0, 56}, {100, 55.9}, {110, 56}, {150, 56}}, 140, 1},
+		// The log would write 55.96 as 56.0, so the hold has to count it.
+		{"judged as the log rounds", types.HoldDipReset, []reading{{0, 55.96}, {60, 55.96}}, 60, 0},
+		{"never up to it", types.HoldDipReset, []reading{{0, 50}, {60, 55.94}}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hold := newHeatTreatmentHold(&types.HeatTreatmentHold{
+				Threshold: 56, Duration: &types.StepDuration{Duration: time.Minute}, OnDip: tt.onDip,
+			})
+			for _, r := range tt.readings {
+				hold.observe(r.at, r.material)
+			}
+			if hold.held() != tt.wantHeld || hold.dips != tt.wantDips {
+				t.Errorf("held %ds with %d dips, want %ds with %d dips", hold.held(), hold.dips, tt.wantHeld, tt.wantDips)
+			}
+		})
+	}
+}
+
+// treatmentFSM builds a controller at the start of a heat treatment step
+// holding 56°C for 10 minutes, with an hour to do it in.
+func treatmentFSM(t *testing.T, onDip types.HoldDipPolicy, now int64) *programFSMController {
+	t.Helper()
+
+	psu, _ := recordingPowerUnit(t)
+	fsm := &programFSMController{
+		state:   fsmStateHeatTreatment,
+		started: now - 200,
+		program: &types.Program{ProgramSteps: []types.ProgramStep{{
+			Name: "ISPM-15", StepType: types.StepTypeHeatTreatment, TargetTemperature: 60,
+			Runtime: stepDuration(3600),
+			Hold:    &types.HeatTreatmentHold{Threshold: 56, Duration: &types.StepDuration{Duration: 10 * time.Minute}, OnDip: onDip},
+			Heater:  &types.PowerPidSettings{Type: types.PowerSettingTypeDelta, MinDelta: f32(-1), MaxDelta: f32(3)},
+			Fan:     &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(100)},
+			Steam:   &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(0)},
+		}}},
+		numberOfSteps:       1,
+		psuController:       psu,
+		currentPSUStatus:    &fsmPSUStatus{},
+		currentTemperatures: &fsmTemperatures{},
+		defaults:            &types.Defaults{SensorTimeoutSeconds: testSensorTimeoutSeconds, DoorOpenAction: types.DoorOpenActionPause},
+	}
+	fsm.stateHandlers = map[fsmState]fsmStateHandler{
+		fsmStateHeatTreatment:   &heatTreatmentStateHandler{fsm: fsm},
+		fsmStateNextProgramStep: &nextProgramStepHandler{fsm: fsm},
+		fsmStateFailed:          &failedStateHandler{fsm: fsm},
+	}
+	fsm.stateHandlers[fsmStateHeatTreatment].enterState()
+	fsm.stepStarted = now
+	return fsm
+}
+
+func treatAt(fsm *programFSMController, now int64, material float32) {
+	fsm.currentTemperatures.observe(temperatureReadings{Kiln: 60, Material: material}, now)
+	fsm.executeTickAt(now)
+}
+
+func TestHeatTreatmentEndsOnceTheHoldIsMet(t *testing.T) {
+	now := time.Now().Unix()
+	fsm := treatmentFSM(t, types.HoldDipReset, now)
+
+	treatAt(fsm, now, 56.5)
+	treatAt(fsm, now+600, 57)
+	if fsm.state != fsmStateHeatTreatment {
+		t.Fatalf("state = %q at exactly the required time, want the step to run a second past it", fsm.state)
+	}
+	var status types.ExecutionStatus
+	fsm.UpdateStatus(&status)
+	if status.HeatTreatment == nil || !status.HeatTreatment.Holding || status.HeatTreatment.HeldSeconds != 600 {
+		t.Fatalf("status.HeatTreatment = %+v, want holding with 600s held", status.HeatTreatment)
+	}
+
+	treatAt(fsm, now+601, 57)
+	if fsm.state != fsmStateNextProgramStep {
+		t.Fatalf("state = %q, want the step over", fsm.state)
+	}
+	if len(fsm.events) != 1 || fsm.events[0].Kind != types.RunEventHoldMet {
+		t.Errorf("events = %+v, want hold met", fsm.events)
+	}
+}
+
+func TestHeatTreatmentRecordsADip(t *testing.T) {
+	now := time.Now().Unix()
+	fsm := treatmentFSM(t, types.HoldDipReset, now)
+
+	treatAt(fsm, now, 56.5)
+	treatAt(fsm, now+300, 55.5)
+	treatAt(fsm, now+310, 56.5)
+	treatAt(fsm, now+700, 56.5)
+
+	if fsm.state != fsmStateHeatTreatment {
+		t.Fatalf("state = %q, want the hold started over after the dip", fsm.state)
+	}
+	if len(fsm.events) != 1 || fsm.events[0].Kind != types.RunEventHoldDipped {
+		t.Errorf("events = %+v, want one dip", fsm.events)
+	}
+}
+
+// A load that was not treated must not pass as one that was, so running out
+// of time fails the run rather than moving on to cooling.
+func TestHeatTreatmentFailsWhenTheRuntimeRunsOut(t *testing.T) {
+	now := time.Now().Unix()
+	fsm := treatmentFSM(t, types.HoldDipReset, now)
+	fsm.stepStarted = now - 3600
+
+	treatAt(fsm, now, 55)
+
+	if fsm.state != fsmStateFailed {
+		t.Fatalf("state = %q, want %q", fsm.state, fsmStateFailed)
+	}
+	if len(fsm.events) != 1 || fsm.events[0].Kind != types.RunEventHoldNotMet {
+		t.Errorf("events = %+v, want hold not met", fsm.events)
+	}
+}
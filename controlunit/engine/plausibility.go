@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/rmkhl/halko/types"
+)
+
+// plausibilityCheck decides whether one sensor's readings are believable. The
+// invalid sentinel is not the only way a reading goes wrong: a garbled serial
+// frame or a glitching probe reads a number, and one reading 300 °C turns the
+// heater off while one reading low turns it on and can pass a step's target
+// check. A reading that moved faster than the kiln can is held back, unless
+// enough readings in a row agree on the new level, which is what a real jump
+// (a probe pushed back into the wood) looks like.
+//
+// The zero value accepts every reading.
+type plausibilityCheck struct {
+	maxRate float32
+	confirm int
+
+	// The last accepted reading.
+	last   float32
+	lastAt int64
+	seeded bool
+
+	// The readings held back since the last accepted one, as long as they
+	// agree with each other.
+	candidate   float32
+	candidateAt int64
+	candidates  int
+}
+
+// newPlausibilityCheck returns a check using the configured filter, or one
+// that accepts everything when there is none.
+func newPlausibilityCheck(filter *types.PlausibilityDefaults) plausibilityCheck {
+	if filter == nil {
+		return plausibilityCheck{}
+	}
+	return plausibilityCheck{maxRate: filter.MaxRate, confirm: filter.Confirm}
+}
+
+// check reports whether a valid reading taken at now is plausible, and if not
+// why. The first reading has nothing to be compared with and is accepted.
+func (c *plausibilityCheck) check(value float32, now int64) (bool, string) {
+	if c.maxRate <= 0 || !c.seeded {
+		c.accept(value, now)
+		return true, ""
+	}
+
+	if withinRate(value, c.last, now-c.lastAt, c.maxRate) {
+		c.accept(value, now)
+		return true, ""
+	}
+
+	if c.candidates > 0 && withinRate(value, c.candidate, now-c.candidateAt, c.maxRate) {
+		c.candidates++
+	} else {
+		c.candidates = 1
+	}
+	c.candidate = value
+	c.candidateAt = now
+	if c.candidates >= c.confirm {
+		c.accept(value, now)
+		return true, ""
+	}
+
+	elapsed := max(now-c.lastAt, 1)
+	return false, fmt.Sprintf("%.1f°C is %.1f°C from %.1f°C in %ds, faster than %.1f°C/s",
+		value, math.Abs(float64(value-c.last)), c.last, elapsed, c.maxRate)
+}
+
+func (c *plausibilityCheck) accept(value float32, now int64) {
+	c.last = value
+	c.lastAt = now
+	c.seeded = true
+	c.candidates = 0
+}
+
+// withinRate reports whether value could have followed from since in the
+// given number of seconds. Readings taken within the same second are allowed
+// a second's worth of change, since the clock only counts whole seconds.
+func withinRate(value, since float32, seconds int64, maxRate float32) bool {
+	return math.Abs(float64(value-since)) <= float64(maxRate)*float64(max(seconds, 1))
+}
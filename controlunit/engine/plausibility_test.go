@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func TestPlausibilityCheckRejectsASpike(t *testing.T) {
+	check := newPlausibilityCheck(&types.PlausibilityDefaults{MaxRate: 2, Confirm: 3})
+
+	if ok, _ := check.check(85, 1000); !ok {
+		t.Fatal("first reading rejected, want it accepted")
+	}
+	if ok, _ := check.check(88, 1002); !ok {
+		t.Fatal("a 3°C rise in 2s rejected, want it accepted at 2°C/s")
+	}
+	ok, reason := check.check(300, 1004)
+	if ok {
+		t.Fatal("a 212°C jump in 2s accepted, want it rejected")
+	}
+	if reason == "" {
+		t.Error("rejection without a reason")
+	}
+	// The spike leaves the last accepted reading where it was.
+	if ok, _ := check.check(89, 1006); !ok {
+		t.Fatal("reading after the spike rejected, want it measured from 88°C")
+	}
+}
+
+// A level that holds for confirm readings in a row is a real change, not a
+// glitch, however fast it got there.
+func TestPlausibilityCheckConfirmsANewLevel(t *testing.T) {
+	check := newPlausibilityCheck(&types.PlausibilityDefaults{MaxRate: 2, Confirm: 3})
+	check.check(20, 1000)
+
+	for n, now := range []int64{1002, 1004} {
+		if ok, _ := check.check(60, now); ok {
+			t.Fatalf("reading %d at the new level accepted before it was confirmed", n+1)
+		}
+	}
+	if ok, _ := check.check(60.5, 1006); !ok {
+		t.Fatal("third consistent reading rejected, want the new level confirmed")
+	}
+	if ok, _ := check.check(61, 1008); !ok {
+		t.Fatal("reading after the confirmed level rejected")
+	}
+}
+
+// Spikes that disagree with each other do not add up to a confirmation.
+func TestPlausibilityCheckDoesNotConfirmScatteredSpikes(t *testing.T) {
+	check := newPlausibilityCheck(&types.PlausibilityDefaults{MaxRate: 2, Confirm: 2})
+	check.check(80, 1000)
+
+	for n, value := range []float32{300, -40, 250, 0} {
+		if ok, _ := check.check(value, 1002+int64(n)); ok {
+			t.Fatalf("spike %v accepted, want every scattered spike rejected", value)
+		}
+	}
+}
+
+func TestZeroPlausibilityCheckAcceptsEverything(t *testing.T) {
+	var check plausibilityCheck
+	for n, value := range []float32{20, 300, -40} {
+		if ok, _ := check.check(value, int64(n)); !ok {
+			t.Fatalf("reading %v rejected by the zero check", value)
+		}
+	}
+}
@@ -1,16 +1,23 @@
-// Implements simple and per-step delta based power controllers.
+// Implements simple, per-step delta, climate band and cooling ramp based power
+// controllers.
 package engine
 
 import (
 	"github.com/rmkhl/halko/types"
 )
 
+// coolingRampBand is how far, in °C, the kiln may stray either side of a
+// cooling ramp before the fan is at its full power or off.
+const coolingRampBand = 1.0
+
 type (
 	// PowerController decides the power percentage from the latest temperature
-	// readings. Implementations own whatever state they need; the returned value
-	// is re-commanded to the power unit on every reading.
+	// and climate readings. Implementations own whatever state they need and
+	// read only what they control on; the returned value is re-commanded to
+	// the power unit on every reading. climate is nil without a valid humidity
+	// reading.
 	PowerController interface {
-		Update(kilnTemperature, materialTemperature float32) uint8
+		Update(kilnTemperature, materialTemperature float32, climate *types.ClimateResponse) uint8
 	}
 )
 
@@ -34,17 +41,61 @@ func NewPowerController(stepType types.StepType, targetTemperature float32, sett
 		switch stepType {
 		case types.StepTypeHeating:
 			return &heatingDeltaController{minDelta: *settings.MinDelta, maxDelta: *settings.MaxDelta, heaterOn: true}
-		case types.StepTypeAcclimate:
+		case types.StepTypeAcclimate, types.StepTypeHeatTreatment:
 			return &acclimateDeltaController{target: targetTemperature, minDelta: *settings.MinDelta, maxDelta: *settings.MaxDelta}
 		default:
 			return failSafe
 		}
 
+	case types.PowerSettingTypeHumidity, types.PowerSettingTypeEMC:
+		// Only steam validates to a climate band here, and steam humidifies.
+		return newClimateBandController(settings, true)
+
 	default:
 		return failSafe
 	}
 }
 
+// NewVentController builds the vent's controller. The vent dries the air, so a
+// climate band runs it the opposite way round from steam.
+func NewVentController(settings *types.PowerPidSettings) PowerController {
+	if settings.BandsOnClimate() {
+		return newClimateBandController(settings, false)
+	}
+	if settings == nil || settings.Type != types.PowerSettingTypeSimple || settings.Power == nil {
+		return &simplePowerController{power: 0}
+	}
+	return &simplePowerController{power: *settings.Power}
+}
+
+// NewFanController builds the fan's controller. A delta band runs the fan on
+// the kiln/material gap rather than at a constant power.
+func NewFanController(settings *types.PowerPidSettings) PowerController {
+	if settings != nil && settings.Type == types.PowerSettingTypeDelta && settings.MinDelta != nil && settings.MaxDelta != nil {
+		fan := &fanDeltaController{minDelta: *settings.MinDelta, maxDelta: *settings.MaxDelta}
+		if settings.MinPower != nil {
+			fan.minPower = *settings.MinPower
+		}
+		return fan
+	}
+	if settings == nil || settings.Type != types.PowerSettingTypeSimple || settings.Power == nil {
+		return &simplePowerController{power: 0}
+	}
+	return &simplePowerController{power: *settings.Power}
+}
+
+func newClimateBandController(settings *types.PowerPidSettings, humidifies bool) PowerController {
+	onEMC := settings.Type == types.PowerSettingTypeEMC
+	lower, upper := settings.MinHumidity, settings.MaxHumidity
+	if onEMC {
+		lower, upper = settings.MinEMC, settings.MaxEMC
+	}
+	if lower == nil || upper == nil {
+		return &simplePowerController{power: 0}
+	}
+	return &climateBandController{onEMC: onEMC, lower: *lower, upper: *upper, humidifies: humidifies}
+}
+
 // heatingDeltaController keeps the kiln inside the band
 // [material+minDelta, material+maxDelta] with hysteresis: the heater turns
 // off at the upper bound, back on at the lower bound, and holds its previous
@@ -55,7 +106,7 @@ type heatingDeltaController struct {
 	heaterOn bool
 }
 
-func (c *heatingDeltaController) Update(kilnTemperature, materialTemperature float32) uint8 {
+func (c *heatingDeltaController) Update(kilnTemperature, materialTemperature float32, _ *types.ClimateResponse) uint8 {
 	switch {
 	case kilnTemperature >= materialTemperature+c.maxDelta:
 		c.heaterOn = false
@@ -91,7 +142,7 @@ type acclimateDeltaController struct {
 	heaterOn bool
 }
 
-func (c *acclimateDeltaController) Update(kilnTemperature, materialTemperature float32) uint8 {
+func (c *acclimateDeltaController) Update(kilnTemperature, materialTemperature float32, _ *types.ClimateResponse) uint8 {
 	lower, upper := c.target+c.minDelta, c.target
 	if materialTemperature < c.target {
 		lower, upper = materialTemperature, materialTemperature+c.maxDelta
@@ -108,11 +159,158 @@ func (c *acclimateDeltaController) Update(kilnTemperature, materialTemperature f
 	return 0
 }
 
+// fanDeltaController runs the fan in proportion to the gap between the kiln
+// and the material. A wide gap is heat waiting to get into the wood, which
+// moving air carries there, so the fan is at full power once the kiln is
+// maxDelta above the material. As the two converge there is less to carry, and
+// air blown past the boards only dries their surface, so the fan slows with
+// the gap down to minPower at minDelta.
+//
+// It never stops below minPower: the gap closes just as the heater band fires
+// the heater, and a power unit interlock may refuse the heater without the fan.
+type fanDeltaController struct {
+	minDelta float32
+	maxDelta float32
+	minPower uint8
+}
+
+func (c *fanDeltaController) Update(kilnTemperature, materialTemperature float32, _ *types.ClimateResponse) uint8 {
+	gap := kilnTemperature - materialTemperature
+	switch {
+	case gap >= c.maxDelta:
+		return 100
+	case gap <= c.minDelta:
+		return c.minPower
+	}
+	span := float32(100 - c.minPower)
+	return c.minPower + uint8(span*(gap-c.minDelta)/(c.maxDelta-c.minDelta))
+}
+
+// climateBandController holds the kiln air inside a relative humidity or EMC
+// band with the same hysteresis as the heater. Steam humidifies: it comes on at
+// the bottom of the band and goes off at the top. The vent dehumidifies: it
+// opens at the top and shuts at the bottom. Inside the band either holds its
+// previous state.
+//
+// Without a climate reading the channel is switched off rather than held.
+// Steam left on against a humidity sensor that has stopped answering saturates
+// the charge, and a vent left open dries it faster than the schedule allows;
+// off is the state nothing is ruined in, and the band picks up again from the
+// next reading.
+type climateBandController struct {
+	onEMC      bool
+	lower      float32
+	upper      float32
+	humidifies bool
+	on         bool
+}
+
+func (c *climateBandController) Update(_, _ float32, climate *types.ClimateResponse) uint8 {
+	if climate == nil || !climate.Valid {
+		c.on = false
+		return 0
+	}
+	value := climate.RelativeHumidity
+	if c.onEMC {
+		value = climate.EMC
+	}
+	switch {
+	case value <= c.lower:
+		c.on = c.humidifies
+	case value >= c.upper:
+		c.on = !c.humidifies
+	}
+	if c.on {
+		return 100
+	}
+	return 0
+}
+
+// NewCoolingRampControllers builds the fan and heater controllers for a
+// cooling step with a max_cooling_rate. The two share the ramp, so the heater
+// only ever comes in once the fan is already off. elapsed reads how far into
+// the step the run is, on the step's clock, which stops while the run is
+// paused: the kiln is not cooled along the ramp with the door open, so the
+// ramp waits for it.
+func NewCoolingRampControllers(step *types.ProgramStep, elapsed func() int64) (fan, heater PowerController) {
+	ramp := &coolingRamp{perHour: *step.MaxCoolingRate, elapsed: elapsed}
+	if step.Fan.Power != nil {
+		ramp.fanMax = *step.Fan.Power
+	}
+	if step.Heater.Power != nil {
+		ramp.heaterMax = *step.Heater.Power
+	}
+	return &coolingRampFan{ramp}, &coolingRampHeater{ramp}
+}
+
+// coolingRamp is the descending line a rate-limited cooling step follows: from
+// the kiln temperature it first sees, down at perHour °C an hour. The fan
+// follows it proportionally, at fanMax with the kiln coolingRampBand above the
+// line and off at coolingRampBand below. Weather colder than the ramp can pull
+// the kiln down with the fan off, so past that the heater comes in at
+// heaterMax, with the same hysteresis as the other heater bands: on at twice
+// the band below the line, off again once back within the band.
+type coolingRamp struct {
+	perHour   float32
+	fanMax    uint8
+	heaterMax uint8
+	elapsed   func() int64
+
+	started  bool
+	from     float32
+	since    int64
+	heaterOn bool
+}
+
+// line is where the kiln should be now. The first reading starts the ramp.
+func (r *coolingRamp) line(kilnTemperature float32) float32 {
+	elapsed := r.elapsed()
+	if !r.started {
+		r.started = true
+		r.from = kilnTemperature
+		r.since = elapsed
+	}
+	return r.from - r.perHour*float32(elapsed-r.since)/3600
+}
+
+type coolingRampFan struct {
+	ramp *coolingRamp
+}
+
+func (c *coolingRampFan) Update(kilnTemperature, _ float32, _ *types.ClimateResponse) uint8 {
+	above := kilnTemperature - c.ramp.line(kilnTemperature)
+	switch {
+	case above >= coolingRampBand:
+		return c.ramp.fanMax
+	case above <= -coolingRampBand:
+		return 0
+	}
+	return uint8(float32(c.ramp.fanMax) * (above + coolingRampBand) / (2 * coolingRampBand))
+}
+
+type coolingRampHeater struct {
+	ramp *coolingRamp
+}
+
+func (c *coolingRampHeater) Update(kilnTemperature, _ float32, _ *types.ClimateResponse) uint8 {
+	above := kilnTemperature - c.ramp.line(kilnTemperature)
+	switch {
+	case above <= -2*coolingRampBand:
+		c.ramp.heaterOn = true
+	case above >= -coolingRampBand:
+		c.ramp.heaterOn = false
+	}
+	if c.ramp.heaterOn {
+		return c.ramp.heaterMax
+	}
+	return 0
+}
+
 // simplePowerController always returns its configured power.
 type simplePowerController struct {
 	power uint8
 }
 
-func (c *simplePowerController) Update(_, _ float32) uint8 {
+func (c *simplePowerController) Update(_, _ float32, _ *types.ClimateResponse) uint8 {
 	return c.power
 }
@@ -15,13 +15,13 @@ type reading struct {
 
 // updater is satisfied by every power controller implementation.
 type updater interface {
-	Update(kilnTemperature, materialTemperature float32) uint8
+	Update(kilnTemperature, materialTemperature float32, climate *types.ClimateResponse) uint8
 }
 
 func runSequence(t *testing.T, c updater, seq []reading) {
 	t.Helper()
 	for i, r := range seq {
-		if got := c.Update(r.kiln, r.material); got != r.want {
+		if got := c.Update(r.kiln, r.material, nil); got != r.want {
 			t.Fatalf("step %d: Update(kiln=%v, material=%v) = %d, want %d", i, r.kiln, r.material, got, r.want)
 		}
 	}
@@ -97,6 +97,12 @@ func TestNewPowerControllerSelection(t *testing.T) {
 			&simplePowerController{}},
 		{"heating delta", types.StepTypeHeating, 80, deltaSettings, &heatingDeltaController{}},
 		{"acclimate delta", types.StepTypeAcclimate, 80, deltaSettings, &acclimateDeltaController{}},
+		{"emc steam", types.StepTypeAcclimate, 60,
+			&types.PowerPidSettings{Type: types.PowerSettingTypeEMC, MinEMC: f32(10), MaxEMC: f32(11)},
+			&climateBandController{}},
+		{"humidity band without its bounds", types.StepTypeHeating, 60,
+			&types.PowerPidSettings{Type: types.PowerSettingTypeHumidity, MinEMC: f32(10), MaxEMC: f32(11)},
+			&simplePowerController{}},
 		// fail-safes: anything unresolvable heats at 0%
 		{"delta on cooling step", types.StepTypeCooling, 0, deltaSettings, &simplePowerController{}},
 		{"nil settings", types.StepTypeHeating, 80, nil, &simplePowerController{}},
@@ -219,3 +225,154 @@ func TestAcclimateFiresOnKilnSagNotMaterialSag(t *testing.T) {
 		{kiln: 148.5, material: 150.8, want: 100}, // sagged past target-1, heat
 	})
 }
+
+// climateAt is a valid climate reading with the given humidity and EMC.
+func climateAt(humidity, emc float32) *types.ClimateResponse {
+	return &types.ClimateResponse{Valid: true, RelativeHumidity: humidity, EMC: emc}
+}
+
+// Steam humidifies: on at the bottom of the band, off at the top, holding its
+// state in between.
+func TestSteamBandsOnEMC(t *testing.T) {
+	c := NewPowerController(types.StepTypeAcclimate, 60,
+		&types.PowerPidSettings{Type: types.PowerSettingTypeEMC, MinEMC: f32(10), MaxEMC: f32(11)})
+
+	for i, tt := range []struct {
+		emc  float32
+		want uint8
+	}{
+		{10.5, 0},   // inside the band, starts off
+		{10.0, 100}, // reached the bottom, steam
+		{10.5, 100}, // rising through the band, keep steaming
+		{11.0, 0},   // reached the top, off
+		{10.5, 0},   // falling through the band, stay off
+	} {
+		if got := c.Update(60, 50, climateAt(70, tt.emc)); got != tt.want {
+			t.Fatalf("step %d: Update(emc=%v) = %d, want %d", i, tt.emc, got, tt.want)
+		}
+	}
+}
+
+// The vent dehumidifies, so the same band runs it the other way round.
+func TestVentBandsOnHumidity(t *testing.T) {
+	c := NewVentController(&types.PowerPidSettings{Type: types.PowerSettingTypeHumidity, MinHumidity: f32(60), MaxHumidity: f32(70)})
+
+	for i, tt := range []struct {
+		humidity float32
+		want     uint8
+	}{
+		{65, 0},   // inside the band, starts shut
+		{70, 100}, // reached the top, open
+		{65, 100}, // drying through the band, stay open
+		{60, 0},   // reached the bottom, shut
+		{65, 0},   // rising through the band, stay shut
+	} {
+		if got := c.Update(60, 50, climateAt(tt.humidity, 10)); got != tt.want {
+			t.Fatalf("step %d: Update(humidity=%v) = %d, want %d", i, tt.humidity, got, tt.want)
+		}
+	}
+}
+
+// Running steam or the vent blind ruins a charge, so a missing reading switches
+// the channel off, and the band resumes from the next one.
+func TestClimateBandSwitchesOffWithoutAReading(t *testing.T) {
+	c := NewPowerController(types.StepTypeAcclimate, 60,
+		&types.PowerPidSettings{Type: types.PowerSettingTypeEMC, MinEMC: f32(10), MaxEMC: f32(11)})
+
+	if got := c.Update(60, 50, climateAt(50, 9)); got != 100 {
+		t.Fatalf("below the band: got %d, want 100", got)
+	}
+	if got := c.Update(60, 50, nil); got != 0 {
+		t.Fatalf("without a reading: got %d, want 0", got)
+	}
+	if got := c.Update(60, 50, &types.ClimateResponse{EMC: 9}); got != 0 {
+		t.Fatalf("with an invalid reading: got %d, want 0", got)
+	}
+	if got := c.Update(60, 50, climateAt(50, 9)); got != 100 {
+		t.Fatalf("reading back below the band: got %d, want 100", got)
+	}
+}
+
+func TestVentControllerFailSafes(t *testing.T) {
+	if got := NewVentController(nil).Update(60, 50, nil); got != 0 {
+		t.Fatalf("nil vent settings: got %d, want 0", got)
+	}
+	if got := NewVentController(&types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(30)}).Update(60, 50, nil); got != 30 {
+		t.Fatalf("constant vent: got %d, want 30", got)
+	}
+}
+
+// A 10°C/h ramp from 100°C stands at 90°C an hour in. The fan follows it
+// proportionally; the heater only comes in once the kiln has fallen two bands
+// below it and goes off again once back within one.
+func TestCoolingRampModulatesTheFanAndBringsInTheHeater(t *testing.T) {
+	clock := int64(1000)
+	step := &types.ProgramStep{
+		StepType:       types.StepTypeCooling,
+		MaxCoolingRate: f32(10),
+		Fan:            &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(80)},
+		Heater:         &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(20)},
+	}
+	fan, heater := NewCoolingRampControllers(step, func() int64 { return clock })
+
+	// The first reading starts the ramp where the kiln is.
+	if got := fan.Update(100, 105, nil); got != 40 {
+		t.Fatalf("fan on the line = %d%%, want half of 80%%", got)
+	}
+	clock += 3600
+
+	tests := []struct {
+		kiln       float32
+		wantFan    uint8
+		wantHeater uint8
+	}{
+		{95, 80, 0},   // cooling too slowly: full fan
+		{90.5, 60, 0}, // in the band: part fan
+		{89, 0, 0},    // a band below: fan off
+		{88, 0, 20},   // two bands below: heater in
+		{88.5, 0, 20}, // climbing back, still below the band: heater stays
+		{89, 0, 0},    // back within the band: heater off
+	}
+	for _, tt := range tests {
+		gotFan, gotHeater := fan.Update(tt.kiln, 95, nil), heater.Update(tt.kiln, 95, nil)
+		if gotFan != tt.wantFan || gotHeater != tt.wantHeater {
+			t.Errorf("kiln at %.1f°C: fan %d%%, heater %d%%, want fan %d%%, heater %d%%",
+				tt.kiln, gotFan, gotHeater, tt.wantFan, tt.wantHeater)
+		}
+	}
+}
+
+func TestFanDeltaFollowsTheKilnMaterialGap(t *testing.T) {
+	fan := NewFanController(&types.PowerPidSettings{Type: types.PowerSettingTypeDelta, MinDelta: f32(1), MaxDelta: f32(5)})
+	runSequence(t, fan, []reading{
+		{kiln: 65, material: 60, want: 100}, // gap at max_delta, full power
+		{kiln: 64, material: 60, want: 75},  // three quarters of the band
+		{kiln: 62, material: 60, want: 25},  // a quarter of the band
+		{kiln: 60, material: 59, want: 0},   // gap closed to min_delta, off
+		{kiln: 63, material: 60, want: 50},  // opening again, half way
+	})
+}
+
+// A fan with a floor slows to it rather than stopping, so a heater the power
+// unit interlocks to the fan can still run once the gap has closed.
+func TestFanDeltaHoldsItsMinPower(t *testing.T) {
+	fan := NewFanController(&types.PowerPidSettings{Type: types.PowerSettingTypeDelta, MinDelta: f32(1), MaxDelta: f32(5), MinPower: u8(30)})
+	runSequence(t, fan, []reading{
+		{kiln: 60, material: 60, want: 30},  // converged, at the floor
+		{kiln: 61, material: 60, want: 30},  // at min_delta, still the floor
+		{kiln: 63, material: 60, want: 65},  // half way from the floor to full
+		{kiln: 70, material: 60, want: 100}, // past max_delta, full power
+	})
+}
+
+func TestFanControllerFailSafes(t *testing.T) {
+	for name, settings := range map[string]*types.PowerPidSettings{
+		"nil":          nil,
+		"half a delta": {Type: types.PowerSettingTypeDelta, MinDelta: f32(1)},
+		"no power":     {Type: types.PowerSettingTypeSimple},
+	} {
+		if got := NewFanController(settings).Update(80, 20, nil); got != 0 {
+			t.Errorf("%s: Update = %d, want 0", name, got)
+		}
+	}
+}
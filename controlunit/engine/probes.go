@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// probeDisagreementLimit is how far, in °C, a probe may read from the median
+// of its group before it is reported. Calibrated probes in the same place
+// agree to within a degree or two; five is beyond anything a board's
+// gradient explains, and well short of what a thermocouple that has worked
+// loose or out of the wood reads.
+const probeDisagreementLimit = 5.0
+
+// minProbesToCompare is how many valid probes a group needs before one of
+// them can be said to disagree with the others. With two there is no telling
+// which one is wrong.
+const minProbesToCompare = 3
+
+// probeMonitor watches each probe's own reading for one that has wandered
+// away from the rest of its group. The sensor unit fuses the probes before
+// the controllers see them, so a failing probe does not show up as a failed
+// reading; a mean would be pulled along with it and a minimum could follow it
+// all the way. Only the material probes are compared, since the two kiln
+// probes cannot outvote each other.
+//
+// The zero value compares uncalibrated readings.
+type probeMonitor struct {
+	calibration map[string]*types.ProbeCalibration
+	disagreeing map[string]bool
+}
+
+// check compares the latest per-probe readings, as the sensor unit reported
+// them, and returns a sentence for every probe that has started disagreeing
+// since the last check. A probe that keeps disagreeing is reported once.
+func (m *probeMonitor) check(probes map[string]float32) []string {
+	type reading struct {
+		probe string
+		value float64
+	}
+
+	var readings []reading
+	for n := 1; n <= types.MaxMaterialProbes; n++ {
+		probe := types.MaterialProbeName(n)
+		raw, ok := probes[probe]
+		if !ok || raw == types.InvalidTemperatureReading {
+			continue
+		}
+		// Compared calibrated, like the sensor unit fuses them: the raw
+		// readings of two probes can differ by their corrections alone.
+		readings = append(readings, reading{probe: probe, value: float64(m.calibration[probe].Apply(raw))})
+	}
+
+	// Too few to outvote one another: whatever was said about a probe
+	// stands until there are enough to say otherwise.
+	if len(readings) < minProbesToCompare {
+		return nil
+	}
+
+	values := make([]float64, len(readings))
+	for i, r := range readings {
+		values[i] = r.value
+	}
+	median := medianOf(values)
+
+	if m.disagreeing == nil {
+		m.disagreeing = make(map[string]bool)
+	}
+	var started []string
+	for _, r := range readings {
+		difference := math.Abs(r.value - median)
+		disagrees := difference > probeDisagreementLimit
+		switch {
+		case disagrees && !m.disagreeing[r.probe]:
+			started = append(started, fmt.Sprintf("%s reads %.1f°C, %.1f°C from the median of the material probes (%.1f°C)",
+				r.probe, r.value, difference, median))
+		case !disagrees && m.disagreeing[r.probe]:
+			log.Info("Probe %s agrees with the other material probes again", r.probe)
+		}
+		m.disagreeing[r.probe] = disagrees
+	}
+	return started
+}
+
+func medianOf(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func TestProbeMonitorReportsTheProbeThatDisagrees(t *testing.T) {
+	var m probeMonitor
+
+	messages := m.check(map[string]float32{"material": 60, "material_2": 61, "material_3": 48})
+	if len(messages) != 1 || !strings.HasPrefix(messages[0], "material_3 reads 48.0°C") {
+		t.Fatalf("expected material_3 to be reported, got %v", messages)
+	}
+
+	// Still disagreeing: already reported.
+	if messages := m.check(map[string]float32{"material": 60, "material_2": 61, "material_3": 47}); len(messages) != 0 {
+		t.Fatalf("expected one report per onset, got %v", messages)
+	}
+
+	// Back in line, then out again: a new onset.
+	m.check(map[string]float32{"material": 60, "material_2": 61, "material_3": 59})
+	if messages := m.check(map[string]float32{"material": 60, "material_2": 61, "material_3": 70}); len(messages) != 1 {
+		t.Fatalf("expected the second onset to be reported, got %v", messages)
+	}
+}
+
+// Two probes cannot outvote each other, and an invalid one does not count.
+func TestProbeMonitorNeedsThreeValidProbes(t *testing.T) {
+	var m probeMonitor
+
+	probes := map[string]float32{"material": 60, "material_2": 40, "material_3": types.InvalidTemperatureReading}
+	if messages := m.check(probes); len(messages) != 0 {
+		t.Fatalf("expected no verdict from two probes, got %v", messages)
+	}
+}
+
+// A probe whose raw reading is off by its known correction agrees once the
+// correction is applied.
+func TestProbeMonitorComparesCalibratedReadings(t *testing.T) {
+	m := probeMonitor{calibration: map[string]*types.ProbeCalibration{
+		"material_3": {Gain: 1, Offset: 7},
+	}}
+
+	if messages := m.check(map[string]float32{"material": 60, "material_2": 61, "material_3": 53}); len(messages) != 0 {
+		t.Fatalf("expected the calibrated probe to agree, got %v", messages)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 
 	"github.com/rmkhl/halko/types"
 	"github.com/rmkhl/halko/types/log"
@@ -16,6 +17,7 @@ const (
 	psuOven  = "heater"
 	psuFan   = "fan"
 	psuSteam = "steam"
+	psuVent  = "vent"
 )
 
 type (
@@ -23,9 +25,27 @@ type (
 		Percent uint8 `json:"percent"`
 	}
 
+	// powerNotice is the power unit refusing or adjusting a command because of
+	// its limits and interlocks.
+	powerNotice struct {
+		psu     string
+		refused bool
+		message string
+	}
+
 	psuController struct {
 		client          *http.Client
 		powerControlURL string
+		// The channels the power unit has, from its power mapping. A kiln
+		// without a vent never hears about one: switching it off would only
+		// be refused. Nil sends every command.
+		channels map[string]int
+
+		// The last notice per channel, so that a command refused on every
+		// tick is reported once rather than once a tick, and the notices not
+		// yet collected by takeNotices.
+		lastNotice map[string]string
+		notices    []powerNotice
 	}
 )
 
@@ -37,6 +57,7 @@ func newPSUController(halkoConfig *types.HalkoConfig, endpoints *types.APIEndpoi
 	return &psuController{
 		client:          &http.Client{},
 		powerControlURL: endpoints.PowerUnit.GetPowerURL(),
+		channels:        halkoConfig.PowerUnit.PowerMapping,
 	}, nil
 }
 
@@ -47,6 +68,10 @@ func newPSUCommand(percentage uint8) *PowerCommand {
 }
 
 func (p *psuController) setPower(psu string, percentage uint8) {
+	if _, ok := p.channels[psu]; p.channels != nil && !ok {
+		log.Trace("No %s channel on the power unit, not commanding %d%%", psu, percentage)
+		return
+	}
 	cmd, err := json.Marshal(newPSUCommand(percentage))
 	if err != nil {
 		log.Error("Error marshalling power command: %v", err)
@@ -78,8 +103,38 @@ func (p *psuController) setPower(psu string, percentage uint8) {
 		var errorResponse types.APIErrorResponse
 		if err := json.Unmarshal(body, &errorResponse); err == nil && errorResponse.Err != "" {
 			log.Error("Cannot set power %s: %s (%s)", psu, errorResponse.Err, response.Status)
+			if response.StatusCode == http.StatusConflict {
+				p.notice(psu, true, errorResponse.Err)
+			}
 			return
 		}
 		log.Error("Cannot set power %s: %s", psu, response.Status)
+		return
+	}
+
+	var powerResponse types.APIResponse[types.PowerResponse]
+	if err := json.Unmarshal(body, &powerResponse); err == nil && len(powerResponse.Data.Adjustments) > 0 {
+		log.Warning("Power %s adjusted by the power unit: %s", psu, strings.Join(powerResponse.Data.Adjustments, "; "))
+		p.notice(psu, false, strings.Join(powerResponse.Data.Adjustments, "; "))
+		return
+	}
+	delete(p.lastNotice, psu)
+}
+
+func (p *psuController) notice(psu string, refused bool, message string) {
+	if p.lastNotice[psu] == message {
+		return
+	}
+	if p.lastNotice == nil {
+		p.lastNotice = make(map[string]string)
 	}
+	p.lastNotice[psu] = message
+	p.notices = append(p.notices, powerNotice{psu: psu, refused: refused, message: message})
+}
+
+// takeNotices returns the refusals and adjustments seen since the last call.
+func (p *psuController) takeNotices() []powerNotice {
+	notices := p.notices
+	p.notices = nil
+	return notices
 }
@@ -107,3 +107,79 @@ func TestSetPowerLogsNothingOnSuccess(t *testing.T) {
 		t.Fatalf("expected no error log on success, got %q", buf.String())
 	}
 }
+
+// A step refreshes every channel every tick, so a refused command is refused
+// every tick too. It is one event for the run, not one a tick.
+func TestRefusalIsNotedOncePerOccurrence(t *testing.T) {
+	refuse := true
+	p := newTestPSUController(t, func(w http.ResponseWriter, _ *http.Request) {
+		if refuse {
+			w.WriteHeader(http.StatusConflict)
+			_, _ = w.Write([]byte(`{"error":"Refused by interlock: heater requires fan at 30% or more, fan would be at 0%"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"percent":40}}`))
+	})
+
+	p.setPower(psuOven, 40)
+	p.setPower(psuOven, 40)
+	notices := p.takeNotices()
+	if len(notices) != 1 || !notices[0].refused || notices[0].psu != psuOven {
+		t.Fatalf("expected one refusal for the heater, got %+v", notices)
+	}
+
+	// Accepted once, the next refusal is news again.
+	refuse = false
+	p.setPower(psuOven, 40)
+	refuse = true
+	p.setPower(psuOven, 40)
+	if notices := p.takeNotices(); len(notices) != 1 {
+		t.Fatalf("expected the recurrence noted, got %+v", notices)
+	}
+}
+
+func TestAdjustmentIsNoted(t *testing.T) {
+	p := newTestPSUController(t, func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"percent":60,"adjustments":["steam limited to 60% (asked for 100%)"]}}`))
+	})
+
+	p.setPower(psuSteam, 100)
+
+	notices := p.takeNotices()
+	if len(notices) != 1 || notices[0].refused || !strings.Contains(notices[0].message, "steam limited to 60%") {
+		t.Fatalf("expected the adjustment noted, got %+v", notices)
+	}
+}
+
+// Other failures are the power unit being unreachable or broken, which the
+// log already reports; they are not the interlocks speaking.
+func TestOtherErrorsAreNotNoted(t *testing.T) {
+	p := newTestPSUController(t, func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"Unknown power 'heater'"}`))
+	})
+
+	p.setPower(psuOven, 40)
+
+	if notices := p.takeNotices(); len(notices) != 0 {
+		t.Fatalf("expected no notices, got %+v", notices)
+	}
+}
+
+// A kiln without a vent has no vent channel, and every run switches it off
+// along with the rest; the power unit would only refuse that.
+func TestSetPowerSkipsChannelsThePowerUnitDoesNotHave(t *testing.T) {
+	var paths []string
+	p := newTestPSUController(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		_, _ = w.Write([]byte(`{"data":{"percent":0}}`))
+	})
+	p.channels = map[string]int{psuOven: 0, psuSteam: 1, psuFan: 2}
+
+	p.setPower(psuVent, 0)
+	p.setPower(psuFan, 0)
+
+	if len(paths) != 1 || paths[0] != "/power/fan" {
+		t.Fatalf("expected only the fan commanded, got %v", paths)
+	}
+}
@@ -3,6 +3,7 @@ package engine
 import (
 	"errors"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
@@ -32,10 +33,17 @@ type (
 		temperatureSensorReader    *temperatureSensorReader
 		// Closed once the run loop has stopped, releasing both sensor readers
 		// wherever they are blocked.
-		sensorShutdown   chan struct{}
-		programStatus    *types.ExecutionStatus
-		statusWriter     *storagefs.StateWriter
-		logWriter        *storagefs.ExecutionLogWriter
+		sensorShutdown chan struct{}
+		// Confirmations from the API, handed to the FSM by the run loop so
+		// they never land in the middle of a tick, nor read the status the
+		// loop is writing.
+		confirmations chan confirmRequest
+		programStatus *types.ExecutionStatus
+		statusWriter  *storagefs.StateWriter
+		logWriter     *storagefs.ExecutionLogWriter
+		eventWriter   *storagefs.RunEventWriter
+		// How many of the status's events eventWriter has been handed.
+		eventsWritten    int
 		previousStep     string
 		heartbeatManager *heartbeat.Manager
 		programName      string
@@ -59,25 +67,44 @@ func newProgramRunner(halkoConfig *types.HalkoConfig, programStorage *storagefs.
 		psuSensorCommands:          make(chan string),
 		psuSensorResponses:         make(chan psuReadings),
 		sensorShutdown:             make(chan struct{}),
+		confirmations:              make(chan confirmRequest),
 		currentProgram:             program,
 		programStatus:              &types.ExecutionStatus{Program: *program},
 		defaults:                   halkoConfig.ControlUnitConfig.Defaults,
 		heartbeatManager:           heartbeatMgr,
 		programStorage:             programStorage,
 		halkoConfig:                halkoConfig,
+		temperatureStatus: fsmTemperatures{
+			kilnCheck:     newPlausibilityCheck(halkoConfig.ControlUnitConfig.Defaults.Plausibility),
+			materialCheck: newPlausibilityCheck(halkoConfig.ControlUnitConfig.Defaults.Plausibility),
+		},
 	}
 
 	if halkoConfig.APIEndpoints == nil {
 		return nil, errors.New("API endpoints not configured")
 	}
 
+	if err := checkProgramHardware(halkoConfig, program); err != nil {
+		return nil, err
+	}
+
 	psuSensorReader, err := newPSUSensorReader(endpoints.PowerUnit.GetPowerURL(), runner.psuSensorCommands, runner.psuSensorResponses, runner.sensorShutdown)
 	if err != nil {
 		return nil, err
 	}
 	runner.psuSensorReader = psuSensorReader
 
-	temperatureSensorReader, err := newTemperatureSensorReader(endpoints.SensorUnit.GetTemperaturesURL(), endpoints.SensorUnit.GetDieTemperaturesURL(), runner.temperatureSensorCommands, runner.temperatureSensorResponses, runner.sensorShutdown)
+	// The climate is only asked for when there is a sensor to read it from.
+	climateURL := ""
+	if halkoConfig.SensorUnit.HumiditySensor != "" {
+		climateURL = endpoints.SensorUnit.GetClimateURL()
+	}
+	// And the moisture only when there are pins to read it from.
+	moistureURL := ""
+	if halkoConfig.SensorUnit.MoistureProbes != nil {
+		moistureURL = endpoints.SensorUnit.GetMoistureURL()
+	}
+	temperatureSensorReader, err := newTemperatureSensorReader(endpoints.SensorUnit.GetTemperaturesURL(), endpoints.SensorUnit.GetDieTemperaturesURL(), endpoints.SensorUnit.GetInputsURL(), endpoints.SensorUnit.GetStatusURL(), climateURL, moistureURL, runner.temperatureSensorCommands, runner.temperatureSensorResponses, runner.sensorShutdown)
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +114,7 @@ func newProgramRunner(halkoConfig *types.HalkoConfig, programStorage *storagefs.
 		return nil, err
 	}
 
-	runner.fsmController = newProgramFSMController(psuController, &runner.psuStatus, &runner.temperatureStatus, runner.defaults)
+	runner.fsmController = newProgramFSMController(psuController, &runner.psuStatus, &runner.temperatureStatus, runner.defaults, halkoConfig.SensorUnit.Calibration)
 	runner.previousStep = ""
 
 	programName := fmt.Sprintf("%s@%s", program.ProgramName, time.Now().Format(time.RFC3339))
@@ -106,9 +133,40 @@ func newProgramRunner(halkoConfig *types.HalkoConfig, programStorage *storagefs.
 	startTime := time.Now().Unix()
 	runner.logWriter = storagefs.NewExecutionLogWriter(programStorage, programName,
 		runner.defaults.ExecutionLogIntervalSeconds, startTime)
+	runner.eventWriter = storagefs.NewRunEventWriter(programStorage, programName)
 	return &runner, nil
 }
 
+// checkProgramHardware refuses a program this kiln cannot run: one that bands
+// steam or the vent on the air without a humidity sensor to read it, ends a
+// step on moisture without pins to read that, or opens a vent the power unit
+// has no channel for. Validation cannot catch either, as
+// programs are written without knowing the kiln they will run in.
+func checkProgramHardware(halkoConfig *types.HalkoConfig, program *types.Program) error {
+	if program.BandsOnClimate() && halkoConfig.SensorUnit.HumiditySensor == "" {
+		return fmt.Errorf("program %q controls steam or the vent on humidity, but the sensor unit has no humidity_sensor", program.ProgramName)
+	}
+	if program.EndsOnMoisture() && halkoConfig.SensorUnit.MoistureProbes == nil {
+		return fmt.Errorf("program %q ends a step on moisture, but the sensor unit has no moisture_probes", program.ProgramName)
+	}
+	if _, ok := halkoConfig.PowerUnit.PowerMapping[psuVent]; program.UsesVent() && !ok {
+		return fmt.Errorf("program %q opens the vent, but the power unit has no %s channel in its power_mapping", program.ProgramName, psuVent)
+	}
+	if program.IsThermalModification() {
+		// Validation saw the blanket asked for; the power unit has to be
+		// able to deliver it, or the run would only find out above the
+		// threshold.
+		blanket := int(halkoConfig.ControlUnitConfig.Defaults.ThermalModification.BlanketSteamPower)
+		if _, ok := halkoConfig.PowerUnit.PowerMapping[psuSteam]; !ok {
+			return fmt.Errorf("program %q needs a steam blanket, but the power unit has no %s channel in its power_mapping", program.ProgramName, psuSteam)
+		}
+		if limit, ok := halkoConfig.PowerUnit.Limits[psuSteam]; ok && limit < blanket {
+			return fmt.Errorf("program %q needs steam at %d%% for its blanket, but the power unit limits %s to %d%%", program.ProgramName, blanket, psuSteam, limit)
+		}
+	}
+	return nil
+}
+
 // requestSensorRead asks a sensor reader for a fresh sample. It reports
 // whether the request was taken; a reader that is still serving the previous
 // request is left alone instead of blocking the caller.
@@ -147,16 +205,24 @@ func (runner *programRunner) Run() {
 			now := time.Now().Unix()
 			runner.temperatureStatus.updated = now
 			runner.temperatureStatus.observe(temperatures, now)
+		case request := <-runner.confirmations:
+			runner.serveConfirmation(request)
 		}
 		runner.fsmController.UpdateStatus(runner.programStatus)
 
-		// Update display if current step changed
-		if runner.programStatus.CurrentStep != runner.previousStep {
-			runner.updateDisplay(runner.programStatus.CurrentStep)
-			runner.previousStep = runner.programStatus.CurrentStep
+		// Update display if current step changed. A step waiting on the
+		// operator says so, since the display is where they look first.
+		display := runner.programStatus.CurrentStep
+		if runner.programStatus.Confirmation != nil {
+			display = "Confirm: " + display
+		}
+		if display != runner.previousStep {
+			runner.updateDisplay(display)
+			runner.previousStep = display
 		}
 
 		runner.logWriter.AddLine(runner.programStatus)
+		runner.writeNewEvents()
 	}
 	if runner.fsmController.Completed() {
 		if runner.fsmController.Failed() {
@@ -168,7 +234,9 @@ func (runner *programRunner) Run() {
 		_ = runner.statusWriter.UpdateState(types.ProgramStateCanceled)
 	}
 	runner.logWriter.Close()
+	runner.eventWriter.Close()
 	runner.fsmController.shutdown()
+	runner.fileComplianceReport()
 
 	// Reset display to idle
 	runner.heartbeatManager.SetDisplayMessage(heartbeat.DisplayIdle)
@@ -190,6 +258,54 @@ func (runner *programRunner) Run() {
 	log.Debug("Runner: Run() method completing")
 }
 
+// fileComplianceReport builds the compliance report of a run that heat
+// treated from its finished execution log, signs it with the kiln's key if it
+// has one, and files it with the run. It is made for every such run however it
+// ended, since a failed treatment needs its record as much as a good one.
+func (runner *programRunner) fileComplianceReport() {
+	if !runner.currentProgram.HeatTreats() {
+		return
+	}
+	logPath, err := runner.programStorage.GetRunningLogPath(runner.programName)
+	if err != nil {
+		log.Error("Failed to find the execution log for the compliance report: %v", err)
+		return
+	}
+	executionLog, err := os.ReadFile(logPath)
+	if err != nil {
+		log.Error("Failed to read the execution log for the compliance report: %v", err)
+		return
+	}
+	report, err := types.BuildComplianceReport(runner.programName, runner.currentProgram,
+		runner.halkoConfig.SensorUnit.MaterialFusion, executionLog)
+	if err != nil {
+		log.Error("Failed to build the compliance report: %v", err)
+		return
+	}
+	if keyText := runner.halkoConfig.ControlUnitConfig.ComplianceKey; keyText != "" {
+		// Validated when the configuration was loaded.
+		key, _ := types.DecodeComplianceKey(keyText)
+		if err := report.Sign(key); err != nil {
+			log.Error("Failed to sign the compliance report: %v", err)
+		}
+	}
+	if err := runner.programStorage.SaveComplianceReport(runner.programName, report); err != nil {
+		log.Error("Failed to save the compliance report: %v", err)
+		return
+	}
+	log.Info("Compliance report for '%s' filed: compliant=%v", runner.programName, report.Compliant())
+}
+
+// writeNewEvents persists the events the FSM has recorded since the last call.
+func (runner *programRunner) writeNewEvents() {
+	events := runner.programStatus.Events
+	if len(events) <= runner.eventsWritten {
+		return
+	}
+	runner.eventWriter.Add(events[runner.eventsWritten:])
+	runner.eventsWritten = len(events)
+}
+
 // updateDisplay sets the display message via heartbeat manager
 func (runner *programRunner) updateDisplay(stepName string) {
 	if runner.heartbeatManager == nil {
@@ -201,6 +317,24 @@ func (runner *programRunner) updateDisplay(stepName string) {
 	log.Debug("Runner: Display message updated to: %s", stepName)
 }
 
+// confirm passes the operator's confirmation to the run loop and waits for
+// the FSM's answer. It reports false when the run is not waiting for one, or
+// has already ended.
+func (runner *programRunner) confirm(source string) bool {
+	request := confirmRequest{source: source, accepted: make(chan bool, 1)}
+	select {
+	case runner.confirmations <- request:
+		return <-request.accepted
+	case <-runner.sensorShutdown:
+		return false
+	}
+}
+
+// serveConfirmation hands a confirmation to the FSM, from the run loop.
+func (runner *programRunner) serveConfirmation(request confirmRequest) {
+	request.accepted <- runner.fsmController.confirm(request.source)
+}
+
 func (runner *programRunner) Stop() {
 	runner.active = false
 	// Don't wait here - let the runner complete asynchronously
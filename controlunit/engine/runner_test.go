@@ -1,8 +1,11 @@
 package engine
 
 import (
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/rmkhl/halko/types"
 )
 
 // The run loop asks for a sample every tick, so the request must never block
@@ -48,3 +51,81 @@ func TestRequestSensorReadDeliversWhenReaderIsListening(t *testing.T) {
 		t.Fatal("reader did not receive the command")
 	}
 }
+
+func TestCheckProgramHardware(t *testing.T) {
+	emc := func() *types.PowerPidSettings {
+		return &types.PowerPidSettings{Type: types.PowerSettingTypeEMC, MinEMC: f32(10), MaxEMC: f32(11)}
+	}
+	off := func() *types.PowerPidSettings {
+		return &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(0)}
+	}
+	program := func(steam, vent *types.PowerPidSettings) *types.Program {
+		return &types.Program{ProgramName: "schedule", ProgramSteps: []types.ProgramStep{{Steam: steam, Vent: vent}}}
+	}
+	config := func(sensor types.HumiditySensor, channels ...string) *types.HalkoConfig {
+		mapping := map[string]int{}
+		for i, channel := range channels {
+			mapping[channel] = i
+		}
+		return &types.HalkoConfig{
+			SensorUnit: &types.SensorUnitConfig{HumiditySensor: sensor},
+			PowerUnit:  &types.PowerUnit{PowerMapping: mapping},
+		}
+	}
+
+	drying := func(program *types.Program) *types.Program {
+		program.ProgramSteps[0].TargetMoisture = f32(12)
+		return program
+	}
+	withPins := func(config *types.HalkoConfig) *types.HalkoConfig {
+		config.SensorUnit.MoistureProbes = &types.MoistureProbes{Count: 2, SpeciesSlope: 1}
+		return config
+	}
+
+	thermal := func(program *types.Program) *types.Program {
+		program.Class = types.ProgramClassThermalModification
+		return program
+	}
+	thermalKiln := func(config *types.HalkoConfig, steamLimit int) *types.HalkoConfig {
+		config.ControlUnitConfig = &types.ControlUnitConfig{Defaults: &types.Defaults{
+			ThermalModification: &types.ThermalModificationDefaults{SteamBlanketAbove: 150, BlanketSteamPower: 80},
+		}}
+		if steamLimit != 0 {
+			config.PowerUnit.Limits = map[string]int{"steam": steamLimit}
+		}
+		return config
+	}
+
+	tests := []struct {
+		name    string
+		config  *types.HalkoConfig
+		program *types.Program
+		wantErr string
+	}{
+		{"plain program on a plain kiln", config("", "heater", "fan", "steam"), program(off(), off()), ""},
+		{"emc steam without a humidity sensor", config("", "heater", "fan", "steam"), program(emc(), off()), "humidity_sensor"},
+		{"emc steam with a humidity sensor", config(types.HumiditySensorSHT3x, "heater", "fan", "steam"), program(emc(), off()), ""},
+		{"vent without a vent channel", config(types.HumiditySensorSHT3x, "heater", "fan", "steam"), program(off(), emc()), "no vent channel"},
+		{"vent with a vent channel", config(types.HumiditySensorSHT3x, "heater", "fan", "steam", "vent"), program(off(), emc()), ""},
+		{"moisture target without moisture pins", config("", "heater", "fan", "steam"), drying(program(off(), off())), "moisture_probes"},
+		{"moisture target with moisture pins", withPins(config("", "heater", "fan", "steam")), drying(program(off(), off())), ""},
+		{"thermal modification without steam", thermalKiln(config("", "heater", "fan"), 0), thermal(program(off(), off())), "no steam channel"},
+		{"thermal modification with steam held down", thermalKiln(config("", "heater", "fan", "steam"), 60), thermal(program(off(), off())), "limits steam to 60%"},
+		{"thermal modification with steam", thermalKiln(config("", "heater", "fan", "steam"), 100), thermal(program(off(), off())), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkProgramHardware(tt.config, tt.program)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected the program accepted, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"sync"
 
 	"github.com/rmkhl/halko/types"
@@ -24,6 +25,7 @@ type (
 		Fan    PowerResponse
 		Heater PowerResponse
 		Steam  PowerResponse
+		Vent   PowerResponse
 	}
 
 	temperatureResponse struct {
@@ -38,6 +40,57 @@ type (
 		MaterialDie      float32
 		KilnPrimaryDie   float32
 		KilnSecondaryDie float32
+		// Each probe's uncorrected reading before fusion, keyed by probe
+		// name, for the execution log and the disagreement check.
+		Probes map[string]float32
+		// The safety switches, read alongside the temperatures so the FSM
+		// sees them on the same tick.
+		Inputs sensorInputs
+		// Whether the sensor unit reports its kiln probes degraded and
+		// which probes report faults, read from its status alongside the
+		// temperatures.
+		Health sensorHealth
+		// The kiln air's humidity, for the execution log. Nil without a
+		// humidity sensor, or when it could not be read.
+		Climate *types.ClimateResponse
+		// The sample boards' moisture content, which a step may end on.
+		// Nil without moisture pins, or when they could not be read.
+		Moisture *types.MoistureResponse
+	}
+
+	// sensorInputs is the emergency stop and door switch state. known is false
+	// when the sensor unit could not be asked, which is not the same as both
+	// switches being safe. confirm is the operator's button, pressed since
+	// the sensor unit was last asked.
+	sensorInputs struct {
+		known         bool
+		emergencyStop bool
+		doorOpen      bool
+		confirm       bool
+	}
+
+	// sensorHealth is the sensor unit's verdict on its probes. known is
+	// false when its status could not be read, which is not the same as the
+	// probes agreeing.
+	sensorHealth struct {
+		known    bool
+		degraded bool
+		reason   string
+		// The faults the thermocouple chips report, by probe name, for
+		// the probes that have them.
+		faults map[string][]types.ProbeFault
+	}
+
+	// sensorStatusDetails is the part of the sensor unit's status the
+	// control unit acts on.
+	sensorStatusDetails struct {
+		Degraded       bool                          `json:"degraded"`
+		DegradedReason string                        `json:"degraded_reason"`
+		ProbeFaults    map[string][]types.ProbeFault `json:"probe_faults"`
+	}
+
+	inputsResponse struct {
+		Data types.SensorInputsResponse `json:"data"`
 	}
 
 	sensorReader struct {
@@ -57,6 +110,22 @@ type (
 		// never controlled on, so failing to fetch them does not fail a
 		// temperature read.
 		dieURL string
+		// inputsURL serves the safety switches. Failing to fetch them does
+		// not fail the read either: the kiln reading is what the sensor
+		// timeout protects, and the FSM holds the last known switch state.
+		inputsURL string
+		// statusURL serves the sensor unit's status, which says whether its
+		// kiln probes are degraded. Failing to fetch it leaves the FSM with
+		// the last known verdict, like the inputs.
+		statusURL string
+		// climateURL serves the kiln air's humidity, and is empty when the
+		// sensor unit has no humidity sensor to ask. Like the cold
+		// junctions it is logged, so failing to fetch it costs a column.
+		climateURL string
+		// moistureURL serves the sample boards' moisture content, and is
+		// empty without moisture pins. Failing to fetch it leaves a step
+		// waiting on it running until the next reading that succeeds.
+		moistureURL string
 	}
 
 	psuSensorReader struct {
@@ -106,6 +175,49 @@ func (controller *temperatureSensorReader) readTemperatures() (*temperatureReadi
 		MaterialDie:      types.InvalidTemperatureReading,
 		KilnPrimaryDie:   types.InvalidTemperatureReading,
 		KilnSecondaryDie: types.InvalidTemperatureReading,
+		Probes:           make(map[string]float32),
+	}
+	for name, value := range dataResponse.Data {
+		if probe, ok := strings.CutSuffix(name, types.RawReadingSuffix); ok {
+			readings.Probes[probe] = value
+		}
+	}
+
+	inputs, err := controller.readInputs()
+	if err != nil {
+		log.Warning("Failed to read safety inputs: %v", err)
+	} else {
+		readings.Inputs = sensorInputs{
+			known:         true,
+			emergencyStop: inputs.EmergencyStop,
+			doorOpen:      inputs.DoorOpen,
+			confirm:       inputs.Confirm,
+		}
+	}
+
+	degraded, err := controller.readHealth()
+	if err != nil {
+		log.Warning("Failed to read sensor unit status: %v", err)
+	} else {
+		readings.Health = *degraded
+	}
+
+	if controller.climateURL != "" {
+		climate, err := controller.readClimate()
+		if err != nil {
+			log.Warning("Failed to read kiln climate: %v", err)
+		} else if climate.Valid {
+			readings.Climate = climate
+		}
+	}
+
+	if controller.moistureURL != "" {
+		moisture, err := controller.readMoisture()
+		if err != nil {
+			log.Warning("Failed to read material moisture: %v", err)
+		} else if moisture.Valid {
+			readings.Moisture = moisture
+		}
 	}
 
 	// The cold junctions come from their own endpoint and only reach the
@@ -123,6 +235,97 @@ func (controller *temperatureSensorReader) readTemperatures() (*temperatureReadi
 	return &readings, nil
 }
 
+// readInputs fetches the emergency stop and door switch states.
+func (controller *temperatureSensorReader) readInputs() (*types.SensorInputsResponse, error) {
+	var dataResponse inputsResponse
+
+	request, err := http.NewRequest("GET", controller.inputsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Add("Content-Type", "application/json")
+	response, err := controller.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot read safety inputs (%s)", response.Status)
+	}
+
+	if err := json.Unmarshal(body, &dataResponse); err != nil {
+		return nil, err
+	}
+
+	return &dataResponse.Data, nil
+}
+
+// readHealth fetches the sensor unit's status and picks out whether it
+// reports its kiln probes degraded, and any probe faults.
+func (controller *temperatureSensorReader) readHealth() (*sensorHealth, error) {
+	var dataResponse types.APIResponse[struct {
+		Details sensorStatusDetails `json:"details"`
+	}]
+
+	request, err := http.NewRequest("GET", controller.statusURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Add("Content-Type", "application/json")
+	response, err := controller.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot read sensor unit status (%s)", response.Status)
+	}
+
+	if err := json.Unmarshal(body, &dataResponse); err != nil {
+		return nil, err
+	}
+
+	details := dataResponse.Data.Details
+	return &sensorHealth{
+		known:    true,
+		degraded: details.Degraded,
+		reason:   details.DegradedReason,
+		faults:   details.ProbeFaults,
+	}, nil
+}
+
+// faultsOf describes the faults reported by the probes behind sensor, "kiln"
+// or "material", e.g. "kiln_primary open_circuit". It is empty when none of
+// them reports one.
+func (h sensorHealth) faultsOf(sensor string) string {
+	var described []string
+	for _, probe := range types.TemperatureProbes {
+		if types.IsMaterialProbe(probe) != (sensor == "material") {
+			continue
+		}
+		for _, fault := range h.faults[probe] {
+			described = append(described, probe+" "+string(fault))
+		}
+	}
+	return strings.Join(described, ", ")
+}
+
 // readDieTemperatures fetches the cold junction readings the sensor unit
 // recorded on its last device read.
 func (controller *temperatureSensorReader) readDieTemperatures() (map[string]float32, error) {
@@ -157,6 +360,75 @@ func (controller *temperatureSensorReader) readDieTemperatures() (map[string]flo
 	return dataResponse.Data, nil
 }
 
+// readClimate fetches the kiln air's humidity. It is read after the
+// temperatures, whose kiln reading the sensor unit works it out against.
+func (controller *temperatureSensorReader) readClimate() (*types.ClimateResponse, error) {
+	var dataResponse types.APIResponse[types.ClimateResponse]
+
+	request, err := http.NewRequest("GET", controller.climateURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Add("Content-Type", "application/json")
+	response, err := controller.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot read climate (%s)", response.Status)
+	}
+
+	if err := json.Unmarshal(body, &dataResponse); err != nil {
+		return nil, err
+	}
+
+	return &dataResponse.Data, nil
+}
+
+// readMoisture fetches the sample boards' moisture content. Like the climate
+// it is read after the temperatures, whose material reading the sensor unit
+// corrects it to.
+func (controller *temperatureSensorReader) readMoisture() (*types.MoistureResponse, error) {
+	var dataResponse types.APIResponse[types.MoistureResponse]
+
+	request, err := http.NewRequest("GET", controller.moistureURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Add("Content-Type", "application/json")
+	response, err := controller.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot read moisture (%s)", response.Status)
+	}
+
+	if err := json.Unmarshal(body, &dataResponse); err != nil {
+		return nil, err
+	}
+
+	return &dataResponse.Data, nil
+}
+
 // readingOrInvalid returns the named temperature, or the invalid sentinel
 // when the sensor unit did not report it at all. A missing key would
 // otherwise decode to a plausible looking 0 degrees.
@@ -168,7 +440,7 @@ func readingOrInvalid(data map[string]float32, name string) float32 {
 	return value
 }
 
-func newTemperatureSensorReader(url, dieURL string, commands <-chan string, responses chan<- temperatureReadings, shutdown <-chan struct{}) (*temperatureSensorReader, error) {
+func newTemperatureSensorReader(url, dieURL, inputsURL, statusURL, climateURL, moistureURL string, commands <-chan string, responses chan<- temperatureReadings, shutdown <-chan struct{}) (*temperatureSensorReader, error) {
 	controller := temperatureSensorReader{
 		sensorReader: sensorReader{
 			client:    &http.Client{},
@@ -176,8 +448,13 @@ func newTemperatureSensorReader(url, dieURL string, commands <-chan string, resp
 			commands:  commands,
 			shutdown:  shutdown,
 		},
-		runner: responses,
-		dieURL: dieURL,
+		runner:     responses,
+		dieURL:     dieURL,
+		inputsURL:  inputsURL,
+		statusURL:  statusURL,
+		climateURL: climateURL,
+
+		moistureURL: moistureURL,
 	}
 
 	// verify we can read from the sensors
@@ -224,7 +501,7 @@ func (controller *psuSensorReader) readSensors() (*psuReadings, error) {
 		return nil, err
 	}
 
-	return &psuReadings{Fan: dataResponse.Data["fan"], Heater: dataResponse.Data["heater"], Steam: dataResponse.Data["steam"]}, nil
+	return &psuReadings{Fan: dataResponse.Data["fan"], Heater: dataResponse.Data["heater"], Steam: dataResponse.Data["steam"], Vent: dataResponse.Data["vent"]}, nil
 }
 
 func newPSUSensorReader(url string, commands <-chan string, responses chan<- psuReadings, shutdown <-chan struct{}) (*psuSensorReader, error) {
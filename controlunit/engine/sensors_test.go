@@ -96,12 +96,77 @@ func TestReadTemperaturesMapsKilnAndMaterial(t *testing.T) {
 	}
 }
 
+// The per-probe readings ride along under their _raw names and come out keyed
+// by probe, leaving the fused values where they were.
+func TestReadTemperaturesCollectsTheProbeReadings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"kiln":28.5,"material":22.25,"kiln_primary_raw":28.5,"kiln_secondary_raw":27.75,"material_raw":22.25,"material_2_raw":24}}`))
+	}))
+	defer server.Close()
+
+	reader := temperatureSensorReader{
+		sensorReader: sensorReader{client: server.Client(), sensorURL: server.URL},
+	}
+
+	readings, err := reader.readTemperatures()
+	if err != nil {
+		t.Fatalf("readTemperatures() returned error: %v", err)
+	}
+
+	want := map[string]float32{"kiln_primary": 28.5, "kiln_secondary": 27.75, "material": 22.25, "material_2": 24}
+	if len(readings.Probes) != len(want) {
+		t.Fatalf("Probes = %v, want %v", readings.Probes, want)
+	}
+	for probe, value := range want {
+		if readings.Probes[probe] != value {
+			t.Errorf("%s = %v, want %v", probe, readings.Probes[probe], value)
+		}
+	}
+	if readings.Material != 22.25 {
+		t.Errorf("Material = %v, want 22.25", readings.Material)
+	}
+}
+
+// The sensor unit's status says whether its kiln probes are degraded, and why,
+// and which probes report faults.
+func TestReadTemperaturesCollectsTheSensorHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/status" {
+			_, _ = w.Write([]byte(`{"data":{"status":"degraded","details":{"degraded":true,"degraded_reason":"kiln probes read 12.0°C apart","probe_faults":{"material":["open_circuit"]}}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"kiln":28.5,"material":22.25}}`))
+	}))
+	defer server.Close()
+
+	reader := temperatureSensorReader{
+		sensorReader: sensorReader{client: server.Client(), sensorURL: server.URL},
+		statusURL:    server.URL + "/status",
+	}
+
+	readings, err := reader.readTemperatures()
+	if err != nil {
+		t.Fatalf("readTemperatures() returned error: %v", err)
+	}
+	if !readings.Health.known || !readings.Health.degraded {
+		t.Fatalf("Health = %+v, want known and degraded", readings.Health)
+	}
+	if readings.Health.reason != "kiln probes read 12.0°C apart" {
+		t.Errorf("reason = %q", readings.Health.reason)
+	}
+	if got := readings.Health.faultsOf("material"); got != "material open_circuit" {
+		t.Errorf("material faults = %q, want material open_circuit", got)
+	}
+}
+
 // A reader parked on its command channel must notice the shutdown signal.
 func TestTemperatureReaderStopsWhileWaitingForACommand(t *testing.T) {
 	server := temperatureServer(t, nil)
 
 	shutdown := make(chan struct{})
-	reader, err := newTemperatureSensorReader(server.URL, temperatureServer(t, nil).URL, make(chan string), make(chan temperatureReadings), shutdown)
+	reader, err := newTemperatureSensorReader(server.URL, temperatureServer(t, nil).URL, temperatureServer(t, nil).URL, temperatureServer(t, nil).URL, "", "", make(chan string), make(chan temperatureReadings), shutdown)
 	if err != nil {
 		t.Fatalf("newTemperatureSensorReader() returned error: %v", err)
 	}
@@ -123,7 +188,7 @@ func TestTemperatureReaderStopsWhileBlockedPublishingAReading(t *testing.T) {
 	commands := make(chan string)
 	shutdown := make(chan struct{})
 	// Unbuffered and never received from: the runner has already gone away.
-	reader, err := newTemperatureSensorReader(server.URL, temperatureServer(t, nil).URL, commands, make(chan temperatureReadings), shutdown)
+	reader, err := newTemperatureSensorReader(server.URL, temperatureServer(t, nil).URL, temperatureServer(t, nil).URL, temperatureServer(t, nil).URL, "", "", commands, make(chan temperatureReadings), shutdown)
 	if err != nil {
 		t.Fatalf("newTemperatureSensorReader() returned error: %v", err)
 	}
@@ -148,7 +213,7 @@ func TestTemperatureReaderStopsWhenShutdownArrivesDuringARead(t *testing.T) {
 
 	commands := make(chan string)
 	shutdown := make(chan struct{})
-	reader, err := newTemperatureSensorReader(server.URL, temperatureServer(t, nil).URL, commands, make(chan temperatureReadings), shutdown)
+	reader, err := newTemperatureSensorReader(server.URL, temperatureServer(t, nil).URL, temperatureServer(t, nil).URL, temperatureServer(t, nil).URL, "", "", commands, make(chan temperatureReadings), shutdown)
 	if err != nil {
 		t.Fatalf("newTemperatureSensorReader() returned error: %v", err)
 	}
@@ -253,3 +318,149 @@ func TestReadTemperaturesSurvivesAFailingDieEndpoint(t *testing.T) {
 		t.Errorf("MaterialDie = %v, want the invalid sentinel", readings.MaterialDie)
 	}
 }
+
+func TestReadTemperaturesFetchesTheSafetyInputs(t *testing.T) {
+	inputsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"emergency_stop":false,"door_open":true}}`))
+	}))
+	defer inputsServer.Close()
+
+	server := temperatureServer(t, nil)
+	reader := temperatureSensorReader{
+		sensorReader: sensorReader{client: server.Client(), sensorURL: server.URL},
+		inputsURL:    inputsServer.URL,
+	}
+
+	readings, err := reader.readTemperatures()
+	if err != nil {
+		t.Fatalf("readTemperatures() returned error: %v", err)
+	}
+
+	want := sensorInputs{known: true, doorOpen: true}
+	if readings.Inputs != want {
+		t.Errorf("Inputs = %+v, want %+v", readings.Inputs, want)
+	}
+}
+
+func TestReadTemperaturesMarksUnreadableInputsUnknown(t *testing.T) {
+	inputsServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer inputsServer.Close()
+
+	server := temperatureServer(t, nil)
+	reader := temperatureSensorReader{
+		sensorReader: sensorReader{client: server.Client(), sensorURL: server.URL},
+		inputsURL:    inputsServer.URL,
+	}
+
+	readings, err := reader.readTemperatures()
+	if err != nil {
+		t.Fatalf("readTemperatures() returned error: %v", err)
+	}
+	if readings.Inputs.known {
+		t.Errorf("Inputs = %+v, want them marked unknown", readings.Inputs)
+	}
+}
+
+func TestReadTemperaturesFetchesTheClimate(t *testing.T) {
+	climateServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"sensor":"sht3x","valid":true,"dry_bulb":60,"wet_bulb":50,"relative_humidity":58,"emc":8.1}}`))
+	}))
+	defer climateServer.Close()
+
+	server := temperatureServer(t, nil)
+	reader := temperatureSensorReader{
+		sensorReader: sensorReader{client: server.Client(), sensorURL: server.URL},
+		climateURL:   climateServer.URL,
+	}
+
+	readings, err := reader.readTemperatures()
+	if err != nil {
+		t.Fatalf("readTemperatures() returned error: %v", err)
+	}
+	if readings.Climate == nil || readings.Climate.RelativeHumidity != 58 || readings.Climate.EMC != 8.1 {
+		t.Errorf("Climate = %+v, want the served reading", readings.Climate)
+	}
+}
+
+// An invalid climate and a failing endpoint both leave the reading without
+// one, rather than logging zeros as if the air were bone dry.
+func TestReadTemperaturesDropsAnUnusableClimate(t *testing.T) {
+	for name, handler := range map[string]http.HandlerFunc{
+		"invalid": func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"data":{"sensor":"sht3x","valid":false}}`))
+		},
+		"failing": func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			climateServer := httptest.NewServer(handler)
+			defer climateServer.Close()
+
+			server := temperatureServer(t, nil)
+			reader := temperatureSensorReader{
+				sensorReader: sensorReader{client: server.Client(), sensorURL: server.URL},
+				climateURL:   climateServer.URL,
+			}
+
+			readings, err := reader.readTemperatures()
+			if err != nil {
+				t.Fatalf("readTemperatures() returned error: %v", err)
+			}
+			if readings.Climate != nil {
+				t.Errorf("Climate = %+v, want none", readings.Climate)
+			}
+		})
+	}
+}
+
+func TestReadTemperaturesFetchesTheMoisture(t *testing.T) {
+	moistureServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"valid":true,"material":14.5,"temperature":55,"probes":{"mc_1":14.5,"mc_2":13.2}}}`))
+	}))
+	defer moistureServer.Close()
+
+	server := temperatureServer(t, nil)
+	reader := temperatureSensorReader{
+		sensorReader: sensorReader{client: server.Client(), sensorURL: server.URL},
+		moistureURL:  moistureServer.URL,
+	}
+
+	readings, err := reader.readTemperatures()
+	if err != nil {
+		t.Fatalf("readTemperatures() returned error: %v", err)
+	}
+	if readings.Moisture == nil || readings.Moisture.Material != 14.5 || len(readings.Moisture.Probes) != 2 {
+		t.Errorf("Moisture = %+v, want the served reading", readings.Moisture)
+	}
+}
+
+// An invalid moisture reading is dropped, so a step ending on moisture cannot
+// end on the zero it carries.
+func TestReadTemperaturesDropsAnInvalidMoisture(t *testing.T) {
+	moistureServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"valid":false,"material":0}}`))
+	}))
+	defer moistureServer.Close()
+
+	server := temperatureServer(t, nil)
+	reader := temperatureSensorReader{
+		sensorReader: sensorReader{client: server.Client(), sensorURL: server.URL},
+		moistureURL:  moistureServer.URL,
+	}
+
+	readings, err := reader.readTemperatures()
+	if err != nil {
+		t.Fatalf("readTemperatures() returned error: %v", err)
+	}
+	if readings.Moisture != nil {
+		t.Errorf("Moisture = %+v, want none", readings.Moisture)
+	}
+}
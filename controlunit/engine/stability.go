@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"math"
+
+	"github.com/rmkhl/halko/types"
+)
+
+type (
+	stabilitySample struct {
+		at       int64
+		material float32
+	}
+
+	// stabilityTracker watches an acclimate's material for the settled core
+	// its stability asks for. The rate is taken across the whole hold rather
+	// than from one reading to the next, which at a tick's spacing would be
+	// mostly the probe's last digit flickering.
+	stabilityTracker struct {
+		target  float32
+		within  float32
+		maxRate float32
+		hold    int64
+
+		// When the material last came into the band, and whether it is in
+		// it now. samples go back to the last one at or before a hold ago,
+		// the one the rate is measured from.
+		inBand  bool
+		since   int64
+		samples []stabilitySample
+	}
+)
+
+func newStabilityTracker(target float32, stability *types.AcclimateStability) *stabilityTracker {
+	return &stabilityTracker{
+		target:  target,
+		within:  stability.Within,
+		maxRate: stability.MaxRate,
+		hold:    int64(stability.Hold.Seconds()),
+	}
+}
+
+// observe takes the material reading made at at. A reading already seen is
+// ignored.
+func (s *stabilityTracker) observe(at int64, material float32) {
+	if n := len(s.samples); n > 0 && s.samples[n-1].at >= at {
+		return
+	}
+	s.samples = append(s.samples, stabilitySample{at, material})
+	for len(s.samples) > 1 && s.samples[1].at <= at-s.hold {
+		s.samples = s.samples[1:]
+	}
+
+	inBand := float32(math.Abs(float64(material-s.target))) <= s.within
+	if inBand && !s.inBand {
+		s.since = at
+	}
+	s.inBand = inBand
+}
+
+// rate is how fast, in °C an hour, the material has moved over the last
+// hold, or as long as there are readings for if that is shorter.
+func (s *stabilityTracker) rate() float32 {
+	if len(s.samples) < 2 {
+		return 0
+	}
+	first, last := s.samples[0], s.samples[len(s.samples)-1]
+	return float32(math.Abs(float64(last.material-first.material))) * 3600 / float32(last.at-first.at)
+}
+
+// settled reports whether the material has been in the band for the hold and
+// moved slower than the rate across it.
+func (s *stabilityTracker) settled() bool {
+	if !s.inBand || len(s.samples) == 0 {
+		return false
+	}
+	last := s.samples[len(s.samples)-1].at
+	return last-s.since >= s.hold && s.rate() < s.maxRate
+}
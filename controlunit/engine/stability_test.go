@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func TestStabilityTrackerWantsTheMaterialSettledForTheHold(t *testing.T) {
+	type reading struct {
+		at       int64
+		material float32
+	}
+	tests := []struct {
+		name     string
+		readings []reading
+		want     bool
+	}{
+		{"settled", []reading{{0, 99.6}, {300, 99.5}, {600, 99.6}}, true},
+		{"not for long enough", []reading{{0, 99.6}, {300, 99.5}, {599, 99.6}}, false},
+		{"still climbing", []reading{{0, 99.0}, {300, 99.5}, {600, 100.0}}, false},
+		{"out of the band", []reading{{0, 98.5}, {300, 98.6}, {600, 98.6}}, false},
+		{"came back into the band", []reading{{0, 99.5}, {300, 98.5}, {600, 99.5}, {900, 99.5}}, false},
+		{"settled once back", []reading{{0, 99.5}, {300, 98.5}, {600, 99.5}, {900, 99.5}, {1200, 99.5}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStabilityTracker(100, &types.AcclimateStability{
+				Within: 1, MaxRate: 0.5, Hold: &types.StepDuration{Duration: 10 * time.Minute},
+			})
+			for _, r := range tt.readings {
+				s.observe(r.at, r.material)
+			}
+			if got := s.settled(); got != tt.want {
+				t.Errorf("settled = %t (rate %.2f°C/h), want %t", got, s.rate(), tt.want)
+			}
+		})
+	}
+}
+
+// stabilityFSM builds a controller elapsed seconds into an hour-long
+// acclimate at 100°C that ends once the material holds within 1°C of it,
+// moving under 0.5°C an hour, for ten minutes, and runs at least 20.
+func stabilityFSM(t *testing.T, now, elapsed int64) *programFSMController {
+	t.Helper()
+
+	psu, _ := recordingPowerUnit(t)
+	fsm := &programFSMController{
+		state:   fsmStateAcclimate,
+		started: now - elapsed - 100,
+		program: &types.Program{ProgramSteps: []types.ProgramStep{{
+			Name: "settle", StepType: types.StepTypeAcclimate, TargetTemperature: 100,
+			Runtime: stepDuration(3600),
+			Stability: &types.AcclimateStability{
+				Within: 1, MaxRate: 0.5,
+				Hold:       &types.StepDuration{Duration: 10 * time.Minute},
+				MinRuntime: &types.StepDuration{Duration: 20 * time.Minute},
+			},
+			Heater: &types.PowerPidSettings{Type: types.PowerSettingTypeDelta, MinDelta: f32(-1), MaxDelta: f32(3)},
+			Fan:    &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(100)},
+			Steam:  &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(0)},
+		}}},
+		numberOfSteps:       1,
+		psuController:       psu,
+		currentPSUStatus:    &fsmPSUStatus{},
+		currentTemperatures: &fsmTemperatures{},
+		defaults:            &types.Defaults{SensorTimeoutSeconds: testSensorTimeoutSeconds, DoorOpenAction: types.DoorOpenActionPause},
+	}
+	fsm.stateHandlers = map[fsmState]fsmStateHandler{
+		fsmStateAcclimate:       &acclimateStateHandler{fsm: fsm},
+		fsmStateNextProgramStep: &nextProgramStepHandler{fsm: fsm},
+	}
+	fsm.stateHandlers[fsmStateAcclimate].enterState()
+	fsm.stepStarted = now - elapsed
+	return fsm
+}
+
+// settleFor feeds the controller a steady material reading every minute for
+// the given span, ending at now, and reports the state it ends in. The
+// handler works from the readings of the tick before, so the span has to run
+// a reading past the hold.
+func settleFor(fsm *programFSMController, now, span int64) fsmState {
+	for at := now - span; at <= now; at += 60 {
+		fsm.currentTemperatures.observe(temperatureReadings{Kiln: 100.5, Material: 99.8}, at)
+		fsm.executeTickAt(at)
+		if fsm.state != fsmStateAcclimate {
+			break
+		}
+	}
+	return fsm.state
+}
+
+func TestAcclimateEndsOnceTheMaterialSettles(t *testing.T) {
+	now := time.Now().Unix()
+	fsm := stabilityFSM(t, now, 25*60)
+
+	if state := settleFor(fsm, now, 12*60); state != fsmStateNextProgramStep {
+		t.Fatalf("state = %q, want the step over once settled", state)
+	}
+}
+
+// However settled the probe looks, the step runs its min_runtime.
+func TestAcclimateStabilityWaitsForTheMinimumRuntime(t *testing.T) {
+	now := time.Now().Unix()
+	fsm := stabilityFSM(t, now, 15*60)
+
+	if state := settleFor(fsm, now, 12*60); state != fsmStateAcclimate {
+		t.Fatalf("state = %q, want the step still running before min_runtime", state)
+	}
+}
+
+// The runtime caps a step whose material never settles.
+func TestAcclimateStabilityEndsOnItsRuntimeCap(t *testing.T) {
+	now := time.Now().Unix()
+	fsm := stabilityFSM(t, now, 3600)
+
+	fsm.currentTemperatures.observe(temperatureReadings{Kiln: 103, Material: 90}, now)
+	fsm.executeTickAt(now)
+
+	if fsm.state != fsmStateNextProgramStep {
+		t.Fatalf("state = %q, want the step over at its runtime", fsm.state)
+	}
+}
@@ -9,11 +9,23 @@ type (
 	// A failed probe reports types.InvalidTemperatureReading; keeping the
 	// previous value stops that sentinel reaching the power controllers,
 	// and the per-sensor timestamps say how long that has been going on.
+	// A reading the plausibility check rejects is treated the same way.
 	fsmTemperatures struct {
 		updated         int64
 		reading         temperatureReadings
 		kilnValidAt     int64
 		materialValidAt int64
+		moistureValidAt int64
+
+		kilnCheck     plausibilityCheck
+		materialCheck plausibilityCheck
+		// Why readings were rejected since the FSM last took them, for
+		// the run's events.
+		rejections []string
+		// Whether the confirm button has been pressed since the FSM last
+		// took it. A press is reported once, by whichever reading comes
+		// after it, so it is latched here until a tick sees it.
+		confirmPressed bool
 	}
 )
 
@@ -22,14 +34,15 @@ func validReading(value float32) bool {
 }
 
 // observe records a sample, keeping the previous value for any sensor that
-// reported an invalid reading. The cold junction readings are the exception:
-// they are logged, never controlled or failsafed on.
+// reported an invalid reading, and the previous switch state when the inputs
+// could not be read. The cold junction readings are the exception: they are
+// logged, never controlled or failsafed on.
 func (t *fsmTemperatures) observe(sample temperatureReadings, now int64) {
-	if validReading(sample.Kiln) {
+	if t.plausible("kiln", &t.kilnCheck, sample.Kiln, now) {
 		t.reading.Kiln = sample.Kiln
 		t.kilnValidAt = now
 	}
-	if validReading(sample.Material) {
+	if t.plausible("material", &t.materialCheck, sample.Material, now) {
 		t.reading.Material = sample.Material
 		t.materialValidAt = now
 	}
@@ -39,6 +52,51 @@ func (t *fsmTemperatures) observe(sample temperatureReadings, now int64) {
 	t.reading.MaterialDie = sample.MaterialDie
 	t.reading.KilnPrimaryDie = sample.KilnPrimaryDie
 	t.reading.KilnSecondaryDie = sample.KilnSecondaryDie
+	t.reading.Probes = sample.Probes
+	t.reading.Climate = sample.Climate
+	// Not held either: a step ending on moisture must only ever end on a
+	// reading, and a missing one just means it waits for the next.
+	t.reading.Moisture = sample.Moisture
+	if sample.Moisture != nil {
+		t.moistureValidAt = now
+	}
+	// Held like a temperature: a failed query says nothing about the
+	// switches, so it must neither clear a pressed stop nor raise one.
+	if sample.Inputs.known {
+		t.reading.Inputs = sample.Inputs
+		t.confirmPressed = t.confirmPressed || sample.Inputs.confirm
+	}
+	if sample.Health.known {
+		t.reading.Health = sample.Health
+	}
+}
+
+// plausible reports whether a reading is valid and passes the sensor's
+// plausibility check, noting why it did not when the check rejected it.
+func (t *fsmTemperatures) plausible(sensor string, check *plausibilityCheck, value float32, now int64) bool {
+	if !validReading(value) {
+		return false
+	}
+	ok, reason := check.check(value, now)
+	if !ok {
+		t.rejections = append(t.rejections, sensor+" reading "+reason)
+	}
+	return ok
+}
+
+// takeRejections returns why readings were rejected since the last call.
+func (t *fsmTemperatures) takeRejections() []string {
+	rejections := t.rejections
+	t.rejections = nil
+	return rejections
+}
+
+// takeConfirmPress reports whether the confirm button has been pressed since
+// the last call.
+func (t *fsmTemperatures) takeConfirmPress() bool {
+	pressed := t.confirmPressed
+	t.confirmPressed = false
+	return pressed
 }
 
 // invalidFor names the sensor that has gone longest without a valid reading
@@ -115,6 +115,93 @@ func TestExecuteTickFailsProgramWhenSensorInvalidTooLong(t *testing.T) {
 	}
 }
 
+// The sensor-timeout event names the faults the timed-out sensor's probes
+// report, and only those.
+func TestSensorTimeoutNamesTheProbeFaults(t *testing.T) {
+	fsm := &programFSMController{
+		state:               fsmStateWaiting,
+		started:             1000,
+		currentPSUStatus:    &fsmPSUStatus{},
+		currentTemperatures: &fsmTemperatures{},
+		defaults:            &types.Defaults{SensorTimeoutSeconds: testSensorTimeoutSeconds},
+	}
+	fsm.stateHandlers = map[fsmState]fsmStateHandler{
+		fsmStateWaiting: &waitingStateHandler{fsm: fsm},
+		fsmStateFailed:  &failedStateHandler{fsm: fsm},
+	}
+	now := fsm.started + testSensorTimeoutSeconds + 1
+	fsm.currentTemperatures.observe(temperatureReadings{
+		Kiln:     types.InvalidTemperatureReading,
+		Material: 50,
+		Health: sensorHealth{known: true, faults: map[string][]types.ProbeFault{
+			types.ProbeKilnPrimary:   {types.ProbeFaultOpenCircuit},
+			types.ProbeKilnSecondary: {types.ProbeFaultShortToGND},
+			types.ProbeMaterial:      {types.ProbeFaultNoResponse},
+		}},
+	}, now)
+
+	fsm.executeTickAt(now)
+
+	if fsm.state != fsmStateFailed {
+		t.Fatalf("state = %q, want %q", fsm.state, fsmStateFailed)
+	}
+	if len(fsm.events) != 1 || fsm.events[0].Kind != types.RunEventSensorTimeout {
+		t.Fatalf("events = %+v, want one sensor timeout", fsm.events)
+	}
+	message := fsm.events[0].Message
+	if !strings.Contains(message, "kiln_primary open_circuit, kiln_secondary short_to_gnd") {
+		t.Errorf("message %q does not name the kiln probe faults", message)
+	}
+	if strings.Contains(message, "no_response") {
+		t.Errorf("message %q names a material probe fault for a kiln timeout", message)
+	}
+}
+
+// A rejected reading is kept from the controllers and counts towards the
+// sensor timeout like an invalid one, and the FSM records it as an event.
+func TestRejectedReadingsAreHeldBackAndRecorded(t *testing.T) {
+	filter := &types.PlausibilityDefaults{MaxRate: 2, Confirm: 3}
+	fsm := &programFSMController{
+		state:            fsmStateWaiting,
+		started:          1000,
+		currentPSUStatus: &fsmPSUStatus{},
+		currentTemperatures: &fsmTemperatures{
+			kilnCheck:     newPlausibilityCheck(filter),
+			materialCheck: newPlausibilityCheck(filter),
+		},
+		defaults: &types.Defaults{SensorTimeoutSeconds: testSensorTimeoutSeconds},
+	}
+	fsm.stateHandlers = map[fsmState]fsmStateHandler{
+		fsmStateWaiting: &waitingStateHandler{fsm: fsm},
+		fsmStateFailed:  &failedStateHandler{fsm: fsm},
+	}
+	temps := fsm.currentTemperatures
+
+	temps.observe(temperatureReadings{Kiln: 85, Material: 50}, 1000)
+	temps.observe(temperatureReadings{Kiln: 300, Material: 51}, 1010)
+
+	if temps.reading.Kiln != 85 {
+		t.Errorf("kiln = %v, want the spike held back at 85", temps.reading.Kiln)
+	}
+	if temps.reading.Material != 51 {
+		t.Errorf("material = %v, want 51", temps.reading.Material)
+	}
+	if sensor, seconds := temps.invalidFor(1010, 1000); sensor != "kiln" || seconds != 10 {
+		t.Errorf("invalidFor = %s %ds, want kiln 10s", sensor, seconds)
+	}
+
+	fsm.executeTickAt(1010)
+	temps.observe(temperatureReadings{Kiln: -40, Material: 51}, 1012)
+	fsm.executeTickAt(1012)
+
+	if len(fsm.events) != 2 || fsm.events[0].Kind != types.RunEventReadingRejected {
+		t.Fatalf("events = %+v, want two rejections", fsm.events)
+	}
+	if !strings.Contains(fsm.events[1].Message, "2 rejected this run") {
+		t.Errorf("message %q does not count the rejections", fsm.events[1].Message)
+	}
+}
+
 func TestExecuteTickKeepsRunningWhileReadingsAreValid(t *testing.T) {
 	fsm := &programFSMController{
 		state:               fsmStateWaiting,
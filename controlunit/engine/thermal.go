@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// blanketGraceSeconds is how long the power unit may report steam short of
+// the blanket before a thermal modification run gives up on it. The power
+// unit's cycle and the generator's own thermostat both dip the reading for
+// a moment now and then; a blanket that stays thin past this is gone.
+const blanketGraceSeconds = 30
+
+// aboveBlanketThreshold reports whether the run is a thermal modification
+// with the kiln hot enough that the wood needs the steam blanket.
+func (p *programFSMController) aboveBlanketThreshold() bool {
+	return p.thermal != nil && p.currentTemperatures.reading.Kiln > float32(p.thermal.SteamBlanketAbove)
+}
+
+// steamBlanketLost watches what the power unit says the steam channel is
+// running at while the kiln is above the blanket threshold in a step that
+// asks for the blanket. It reports true once steam has been short of the
+// blanket power for longer than the grace.
+func (p *programFSMController) steamBlanketLost(now int64) bool {
+	if !p.aboveBlanketThreshold() || p.step < 0 || p.step >= p.numberOfSteps || p.currentPSUStatus.updated == 0 {
+		p.blanketThinSince = 0
+		return false
+	}
+	steam := p.program.ProgramSteps[p.step].Steam
+	if steam == nil || steam.Power == nil || *steam.Power < p.thermal.BlanketSteamPower ||
+		p.currentPSUStatus.reading.Steam.Percent >= int(p.thermal.BlanketSteamPower) {
+		p.blanketThinSince = 0
+		return false
+	}
+	if p.blanketThinSince == 0 {
+		log.Warning("FSM: steam at %d%%, short of the %d%% blanket with the kiln at %.1f°C",
+			p.currentPSUStatus.reading.Steam.Percent, p.thermal.BlanketSteamPower, p.currentTemperatures.reading.Kiln)
+		p.blanketThinSince = now
+	}
+	return now-p.blanketThinSince > blanketGraceSeconds
+}
+
+// rampDown keeps a thermal modification's cooling from outrunning the
+// configured rate while the kiln is above the blanket threshold. The kiln
+// may fall no further than a line drawn from where it was as the step
+// began, at max_cooling_rate; below the line the fan, which is what does
+// the cooling, is stopped until the line catches up.
+type rampDown struct {
+	from    float32
+	perHour float32
+}
+
+func newRampDown(thermal *types.ThermalModificationDefaults, kiln float32) *rampDown {
+	if thermal == nil || kiln <= float32(thermal.SteamBlanketAbove) {
+		return nil
+	}
+	return &rampDown{from: kiln, perHour: thermal.MaxCoolingRate}
+}
+
+// limitFan returns the fan power to command elapsed seconds into the step.
+func (r *rampDown) limitFan(fan uint8, elapsed int64, kiln float32) uint8 {
+	if r == nil {
+		return fan
+	}
+	floor := r.from - r.perHour*float32(elapsed)/3600
+	if kiln >= floor {
+		return fan
+	}
+	log.Debug("FSM: cool_down - kiln %.1f°C below the %.1f°C ramp line, fan held off", kiln, floor)
+	return 0
+}
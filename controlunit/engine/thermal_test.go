@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rmkhl/halko/types"
+)
+
+// thermalFSM builds a thermal modification run just into a cooling step that
+// brings the kiln down from 210°C to the 150°C blanket threshold under the
+// steam blanket, with a door that would only pause an ordinary run.
+func thermalFSM(t *testing.T, now int64) (*programFSMController, func() map[string]uint8) {
+	t.Helper()
+
+	psu, commanded := recordingPowerUnit(t)
+	fsm := &programFSMController{
+		state:   fsmStateCoolDown,
+		started: now - 7200,
+		program: &types.Program{Class: types.ProgramClassThermalModification, ProgramSteps: []types.ProgramStep{{
+			Name: "cool under the blanket", StepType: types.StepTypeCooling, TargetTemperature: 150,
+			Heater: &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(0)},
+			Fan:    &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(100)},
+			Steam:  &types.PowerPidSettings{Type: types.PowerSettingTypeSimple, Power: u8(80)},
+		}}},
+		numberOfSteps:       1,
+		psuController:       psu,
+		currentPSUStatus:    &fsmPSUStatus{},
+		currentTemperatures: &fsmTemperatures{},
+		defaults:            &types.Defaults{SensorTimeoutSeconds: testSensorTimeoutSeconds, DoorOpenAction: types.DoorOpenActionPause},
+		thermal:             &types.ThermalModificationDefaults{MaxTargetTemperature: 220, SteamBlanketAbove: 150, BlanketSteamPower: 80, MaxCoolingRate: 10},
+	}
+	fsm.stateHandlers = map[fsmState]fsmStateHandler{
+		fsmStateCoolDown: &coolDownStateHandler{fsm: fsm},
+		fsmStateFailed:   &failedStateHandler{fsm: fsm},
+	}
+	fsm.currentTemperatures.observe(temperatureReadings{Kiln: 210, Material: 212}, now)
+	fsm.temperatures = *fsm.currentTemperatures
+	fsm.stateHandlers[fsmStateCoolDown].enterState()
+	fsm.stepStarted = now
+	return fsm, commanded
+}
+
+func thermalReading(fsm *programFSMController, now int64, kiln float32, doorOpen bool) {
+	fsm.currentTemperatures.observe(temperatureReadings{
+		Kiln: kiln, Material: kiln + 5,
+		Inputs: sensorInputs{known: true, doorOpen: doorOpen},
+	}, now)
+}
+
+func steamReading(fsm *programFSMController, now int64, percent int) {
+	fsm.currentPSUStatus.updated = now
+	fsm.currentPSUStatus.reading.Steam.Percent = percent
+}
+
+func TestOpenDoorAboveTheBlanketFailsTheRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		kiln       float32
+		wantFailed bool
+	}{
+		{"above the threshold", 180, true},
+		{"at the threshold", 150, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now().Unix()
+			fsm, _ := thermalFSM(t, now)
+
+			thermalReading(fsm, now, tt.kiln, true)
+			fsm.executeTickAt(now)
+
+			if failed := fsm.state == fsmStateFailed; failed != tt.wantFailed {
+				t.Fatalf("state = %q, want failed %t", fsm.state, tt.wantFailed)
+			}
+			if len(fsm.events) != 1 || fsm.events[0].Kind != types.RunEventDoorOpened {
+				t.Errorf("events = %+v, want the door opening", fsm.events)
+			}
+		})
+	}
+}
+
+func TestSteamBlanketLostFailsTheRunAfterTheGrace(t *testing.T) {
+	now := time.Now().Unix()
+	fsm, _ := thermalFSM(t, now)
+
+	steamReading(fsm, now, 80)
+	thermalReading(fsm, now, 205, false)
+	fsm.executeTickAt(now)
+
+	// A moment short of the blanket is forgiven once steam is back.
+	steamReading(fsm, now+6, 40)
+	fsm.executeTickAt(now + 6)
+	steamReading(fsm, now+30, 80)
+	fsm.executeTickAt(now + 30)
+	if fsm.state == fsmStateFailed {
+		t.Fatal("run failed on a short dip in steam")
+	}
+
+	steamReading(fsm, now+40, 40)
+	fsm.executeTickAt(now + 40)
+	fsm.executeTickAt(now + 40 + blanketGraceSeconds)
+	if fsm.state == fsmStateFailed {
+		t.Fatal("run failed before the grace was over")
+	}
+	fsm.executeTickAt(now + 41 + blanketGraceSeconds)
+	if fsm.state != fsmStateFailed {
+		t.Fatalf("state = %q, want %q", fsm.state, fsmStateFailed)
+	}
+	if len(fsm.events) != 1 || fsm.events[0].Kind != types.RunEventSteamBlanketLost {
+		t.Errorf("events = %+v, want the blanket lost", fsm.events)
+	}
+}
+
+// Below the threshold there is nothing for the steam to keep off the wood.
+func TestSteamBelowTheBlanketIsFineBelowTheThreshold(t *testing.T) {
+	now := time.Now().Unix()
+	fsm, _ := thermalFSM(t, now)
+
+	steamReading(fsm, now, 0)
+	thermalReading(fsm, now, 150, false)
+	fsm.executeTickAt(now)
+	fsm.executeTickAt(now + 2*blanketGraceSeconds)
+
+	if fsm.state == fsmStateFailed {
+		t.Fatalf("run failed with the kiln at the threshold: %+v", fsm.events)
+	}
+}
+
+// An hour into the step at 10°C an hour, the ramp line stands at 200°C: a
+// kiln below it has its fan stopped, one above it keeps cooling.
+func TestRampDownHoldsTheFanBelowTheLine(t *testing.T) {
+	tests := []struct {
+		kiln    float32
+		wantFan uint8
+	}{
+		{195, 0},
+		{205, 100},
+	}
+
+	for _, tt := range tests {
+		now := time.Now().Unix()
+		fsm, commanded := thermalFSM(t, now)
+		fsm.stepStarted = now - 3600
+
+		// The handler works from the readings of the tick before.
+		thermalReading(fsm, now, tt.kiln, false)
+		fsm.executeTickAt(now)
+		fsm.executeTickAt(now)
+
+		if fan := commanded()[psuFan]; fan != tt.wantFan {
+			t.Errorf("kiln at %.0f°C: fan commanded to %d%%, want %d%%", tt.kiln, fan, tt.wantFan)
+		}
+	}
+}
+
+// A thermal modification that fails above the threshold keeps its steam
+// blanket on with the vent shut, and switches off only the heater and fan.
+// The stop button is the one exception: it switches everything off.
+func TestFailingAboveTheBlanketKeepsTheSteamOn(t *testing.T) {
+	tests := []struct {
+		name      string
+		fail      func(fsm *programFSMController, now int64)
+		wantSteam uint8
+	}{
+		{"door opened", func(fsm *programFSMController, now int64) {
+			thermalReading(fsm, now, 180, true)
+			fsm.executeTickAt(now)
+		}, 80},
+		{"steam blanket lost", func(fsm *programFSMController, now int64) {
+			steamReading(fsm, now, 40)
+			thermalReading(fsm, now, 205, false)
+			fsm.executeTickAt(now)
+			fsm.executeTickAt(now + blanketGraceSeconds + 1)
+		}, 80},
+		{"emergency stop", func(fsm *programFSMController, now int64) {
+			fsm.currentTemperatures.observe(temperatureReadings{
+				Kiln: 205, Material: 210,
+				Inputs: sensorInputs{known: true, emergencyStop: true},
+			}, now)
+			fsm.executeTickAt(now)
+		}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now().Unix()
+			fsm, commanded := thermalFSM(t, now)
+
+			tt.fail(fsm, now)
+			if fsm.state != fsmStateFailed {
+				t.Fatalf("state = %q, want %q", fsm.state, fsmStateFailed)
+			}
+			power := commanded()
+			if power[psuSteam] != tt.wantSteam || power[psuOven] != 0 || power[psuFan] != 0 || power[psuVent] != 0 {
+				t.Errorf("power after failing = %v, want steam at %d%% and everything else off", power, tt.wantSteam)
+			}
+		})
+	}
+}
@@ -20,8 +20,11 @@ func addCORSHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type")
-		w.Header().Set("Access-Control-Expose-Headers", "Content-Length")
+		// Stored program writes carry If-Match with the ETag their read
+		// returned, so a cross-origin client has to be let send the one and
+		// read the other.
+		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, If-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "Content-Length, ETag")
 		w.Header().Set("Access-Control-Max-Age", "43200") // 12 hours
 
 		if r.Method == "OPTIONS" {
@@ -53,6 +56,15 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// Move aside anything a power cut left unreadable before it is listed
+	quarantined, err := storagefs.CheckConsistency(configuration.ControlUnitConfig.BasePath)
+	if err != nil {
+		log.Printf("Warning: Failed to check storage consistency: %v", err)
+	}
+	if len(quarantined) > 0 {
+		log.Printf("Warning: Quarantined %d unreadable storage file(s)", len(quarantined))
+	}
+
 	// Clean up any orphaned running programs from previous crashes
 	if err := storage.CleanupOrphanedRunning(); err != nil {
 		log.Printf("Warning: Failed to cleanup orphaned running programs: %v", err)
@@ -62,6 +74,13 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	reconciled, err := programStorage.ReconcileRevisions()
+	if err != nil {
+		log.Printf("Warning: Failed to reconcile stored program revisions: %v", err)
+	}
+	if len(reconciled) > 0 {
+		log.Printf("Warning: Recorded unindexed revisions of %d stored program(s)", len(reconciled))
+	}
 
 	heartbeatManager, err := heartbeat.NewManager(configuration.ControlUnitConfig.NetworkInterface, configuration.APIEndpoints)
 	if err != nil {
@@ -1,8 +1,8 @@
 package router
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -23,7 +23,7 @@ func getCurrentProgram(engine *engine.ControlEngine) http.HandlerFunc {
 	}
 }
 
-func startNewProgram(engine *engine.ControlEngine) http.HandlerFunc {
+func startNewProgram(controlEngine *engine.ControlEngine, storage types.ProgramStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		body, err := io.ReadAll(r.Body)
 		if err != nil {
@@ -33,13 +33,9 @@ func startNewProgram(engine *engine.ControlEngine) http.HandlerFunc {
 		}
 		log.Trace("Raw request body: %s", string(body))
 
-		r.Body = io.NopCloser(bytes.NewBuffer(body))
-
-		var program types.Program
-
-		err = json.NewDecoder(r.Body).Decode(&program)
+		program, status, err := programToStart(body, storage)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, fmt.Sprintf("Does not compute (%s)", err.Error()))
+			writeError(w, status, err.Error())
 			return
 		}
 
@@ -48,20 +44,89 @@ func startNewProgram(engine *engine.ControlEngine) http.HandlerFunc {
 			log.Debug("  Step %d: %s (%s) - Target: %d°C", i+1, step.Name, step.StepType, step.TargetTemperature)
 		}
 
-		program.ApplyDefaults(engine.GetDefaults())
+		program.ApplyDefaults(controlEngine.GetDefaults())
 
 		err = program.Validate()
 		if err != nil {
 			writeError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		err = engine.StartEngine(&program)
+		err = controlEngine.StartEngine(program)
+		if errors.Is(err, engine.ErrEngineBusy) {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
 		if err != nil {
 			writeError(w, http.StatusBadRequest, err.Error())
 			return
 		}
-		writeJSON(w, http.StatusCreated, types.APIResponse[types.Program]{Data: program})
+		writeJSON(w, http.StatusCreated, types.APIResponse[types.Program]{Data: *program})
+	}
+}
+
+// programToStart makes the program a POST /engine/running body asks for, up
+// to where defaults are applied: the program it carries, or the stored one it
+// names with the overrides for this run, rendered if it is a template and
+// with its includes resolved against the stored programs. It is the program
+// that runs, and goes into the execution record, whatever form it was stored
+// in. On failure it also returns the status to answer with: a program that
+// cannot be made to run is the request's fault, storage that cannot be read
+// is the server's.
+func programToStart(body []byte, storage types.ProgramStorage) (*types.Program, int, error) {
+	var request types.StartProgramRequest
+	err := json.Unmarshal(body, &request)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("Does not compute (%s)", err.Error())
+	}
+
+	program := &types.Program{}
+	var stored *types.StoredProgramRef
+	if request.Template != "" {
+		program, err = storage.LoadStoredProgram(request.Template)
+		if err != nil {
+			return nil, storedProgramStatus(err), err
+		}
+		hash, err := program.ContentHash()
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		stored = &types.StoredProgramRef{Name: request.Template, Hash: hash, Overrides: request.Overrides}
+	} else {
+		if request.Overrides != nil {
+			return nil, http.StatusBadRequest, errors.New("overrides are for a stored program started by name")
+		}
+		err = json.Unmarshal(body, program)
+		if err != nil {
+			return nil, http.StatusBadRequest, fmt.Errorf("Does not compute (%s)", err.Error())
+		}
+	}
+
+	// An include of a program that is not stored is a mistake in the program;
+	// one the storage fails to read is not.
+	storageFailed := false
+	load := func(name string) (*types.Program, error) {
+		included, err := storage.LoadStoredProgram(name)
+		if storedProgramStatus(err) == http.StatusInternalServerError {
+			storageFailed = true
+		}
+		return included, err
+	}
+
+	err = program.Render(request.Values)
+	if err == nil {
+		err = program.ExpandSteps(load)
+	}
+	if err == nil {
+		err = program.ApplyOverrides(request.Overrides)
+	}
+	if err != nil && storageFailed {
+		return nil, http.StatusInternalServerError, err
 	}
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	program.StoredProgram = stored
+	return program, 0, nil
 }
 
 func cancelRunningProgram(engine *engine.ControlEngine) http.HandlerFunc {
@@ -75,6 +140,21 @@ func cancelRunningProgram(engine *engine.ControlEngine) http.HandlerFunc {
 	}
 }
 
+// confirmRunningStep gives the operator's go-ahead to a running confirm step.
+func confirmRunningStep(controlEngine *engine.ControlEngine) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		err := controlEngine.ConfirmStep()
+		switch {
+		case errors.Is(err, engine.ErrNoProgramRunning):
+			writeError(w, http.StatusNotFound, err.Error())
+		case err != nil:
+			writeError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSON(w, http.StatusOK, types.APIResponse[string]{Data: "Confirmed"})
+		}
+	}
+}
+
 func getDefaults(engine *engine.ControlEngine) http.HandlerFunc {
 	return func(w http.ResponseWriter, _ *http.Request) {
 		defaults := engine.GetDefaults()
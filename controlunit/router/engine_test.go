@@ -0,0 +1,172 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/rmkhl/halko/controlunit/storagefs"
+	"github.com/rmkhl/halko/types"
+)
+
+const storedOak = `{
+  "name": "Oak",
+  "steps": [
+    {"name": "heat", "type": "heating", "temperature_target": 60},
+    {"name": "hold", "type": "acclimate", "temperature_target": 60, "runtime": "6h"},
+    {"name": "cool", "type": "cooling", "temperature_target": 30}
+  ]
+}`
+
+func oakStorage(t *testing.T) *storagefs.ProgramStorage {
+	t.Helper()
+
+	storage, err := storagefs.NewProgramStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating storage: %v", err)
+	}
+	var program types.Program
+	if err := json.Unmarshal([]byte(storedOak), &program); err != nil {
+		t.Fatalf("decoding the stored program: %v", err)
+	}
+	if err := storage.CreateStoredProgram("Oak", &program); err != nil {
+		t.Fatalf("storing the program: %v", err)
+	}
+	return storage
+}
+
+func TestStartStoredProgramWithOverrides(t *testing.T) {
+	storage := oakStorage(t)
+
+	body := `{"template": "Oak", "overrides": {
+	  "equalize": {"delta": 2.5},
+	  "steps": {"hold": {"runtime": "9h", "moisture_target": 12}}
+	}}`
+	program, _, err := programToStart([]byte(body), storage)
+	if err != nil {
+		t.Fatalf("programToStart: %v", err)
+	}
+
+	hold := program.ProgramSteps[1]
+	if hold.Runtime.Duration != 9*time.Hour || hold.TargetMoisture == nil || *hold.TargetMoisture != 12 {
+		t.Errorf("hold = %s runtime, %v moisture; want the overrides", hold.Runtime, hold.TargetMoisture)
+	}
+	if program.Equalize == nil || *program.Equalize.Delta != 2.5 {
+		t.Errorf("equalize = %+v, want the overridden delta", program.Equalize)
+	}
+
+	stored, _ := storage.LoadStoredProgram("Oak")
+	hash, _ := stored.ContentHash()
+	ref := program.StoredProgram
+	if ref == nil || ref.Name != "Oak" || ref.Hash != hash || ref.Overrides == nil {
+		t.Fatalf("stored_program = %+v, want Oak at %s with its overrides", ref, hash)
+	}
+	if stored.ProgramSteps[1].Runtime.Duration != 6*time.Hour {
+		t.Error("override reached the stored program")
+	}
+}
+
+// unreadableStorage holds a program, Unreadable, whose file the card can no
+// longer read.
+type unreadableStorage struct {
+	*storagefs.ProgramStorage
+}
+
+var errUnreadable = errors.New("input/output error")
+
+func (s unreadableStorage) LoadStoredProgram(name string) (*types.Program, error) {
+	if name == "Unreadable" {
+		return nil, errUnreadable
+	}
+	return s.ProgramStorage.LoadStoredProgram(name)
+}
+
+func (s unreadableStorage) ListProgramRevisions(name string) ([]types.ProgramRevision, error) {
+	if name == "Unreadable" {
+		return nil, errUnreadable
+	}
+	return s.ProgramStorage.ListProgramRevisions(name)
+}
+
+func TestStartRefuses(t *testing.T) {
+	storage := oakStorage(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"unknown program", `{"template": "Pine"}`, http.StatusNotFound},
+		{"overrides on a program sent in full", `{"name": "Oak", "steps": [], "overrides": {"equalize": {"delta": 2}}}`, http.StatusBadRequest},
+		{"unknown step", `{"template": "Oak", "overrides": {"steps": {"soak": {"runtime": "1h"}}}}`, http.StatusBadRequest},
+		{"misspelt field", `{"template": "Oak", "overrides": {"steps": {"hold": {"runtme": "1h"}}}}`, http.StatusBadRequest},
+		{"renamed step", `{"template": "Oak", "overrides": {"steps": {"hold": {"name": "soak"}}}}`, http.StatusBadRequest},
+		{"values for a plain program", `{"template": "Oak", "values": {"thickness": 32}}`, http.StatusBadRequest},
+		{"bad name", `{"template": "../Oak"}`, http.StatusBadRequest},
+		{"unreadable program", `{"template": "Unreadable"}`, http.StatusInternalServerError},
+		{"include of an unknown program", `{"name": "Run", "steps": [{"include": "Pine"}]}`, http.StatusBadRequest},
+		{"include of an unreadable program", `{"name": "Run", "steps": [{"include": "Unreadable"}]}`, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, status, err := programToStart([]byte(tt.body), unreadableStorage{storage})
+			if err == nil || status != tt.status {
+				t.Fatalf("programToStart = %d, %v; want %d", status, err, tt.status)
+			}
+		})
+	}
+}
+
+// The run keeps the hash of the stored program it was started from, so the
+// history can say whether that program has been edited since.
+func TestStoredProgramState(t *testing.T) {
+	storage := oakStorage(t)
+	program, _, err := programToStart([]byte(`{"template": "Oak"}`), storage)
+	if err != nil {
+		t.Fatalf("programToStart: %v", err)
+	}
+
+	startedAt := time.Now().Unix()
+	if got, revision := storedProgramState(program, startedAt, storage); got != types.StoredProgramUnchanged || revision != 1 {
+		t.Errorf("state = %q at revision %d before any edit, want %q at 1", got, revision, types.StoredProgramUnchanged)
+	}
+
+	stored, _ := storage.LoadStoredProgram("Oak")
+	stored.Description = "Now for 50mm boards"
+	if err := storage.UpdateStoredProgram("Oak", stored, "", "*"); err != nil {
+		t.Fatalf("updating: %v", err)
+	}
+	if got, revision := storedProgramState(program, startedAt, storage); got != types.StoredProgramChanged || revision != 1 {
+		t.Errorf("state = %q at revision %d after an edit, want %q at 1", got, revision, types.StoredProgramChanged)
+	}
+
+	if err := storage.DeleteStoredProgram("Oak", "*"); err != nil {
+		t.Fatalf("deleting: %v", err)
+	}
+	if got, _ := storedProgramState(program, startedAt, storage); got != types.StoredProgramDeleted {
+		t.Errorf("state = %q after deleting, want %q", got, types.StoredProgramDeleted)
+	}
+
+	program.StoredProgram = nil
+	if got, _ := storedProgramState(program, startedAt, storage); got != "" {
+		t.Errorf("state = %q for a program sent in full, want none", got)
+	}
+}
+
+// A stored program the card cannot read may well still be there, so it is
+// not reported deleted.
+func TestStoredProgramStateOfAnUnreadableProgram(t *testing.T) {
+	storage := oakStorage(t)
+	program, _, err := programToStart([]byte(`{"template": "Oak"}`), storage)
+	if err != nil {
+		t.Fatalf("programToStart: %v", err)
+	}
+
+	program.StoredProgram.Name = "Unreadable"
+	if got, _ := storedProgramState(program, time.Now().Unix(), unreadableStorage{storage}); got != "" {
+		t.Errorf("state = %q for a program that cannot be read, want none", got)
+	}
+}
@@ -3,9 +3,7 @@ package router
 import (
 	"bytes"
 	"encoding/csv"
-	"fmt"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -98,21 +96,8 @@ func StreamLiveRunLog(engine *engine.ControlEngine) http.HandlerFunc {
 				// Compose CSV line
 				var line bytes.Buffer
 				csvw := csv.NewWriter(&line)
-				elapsed := int(time.Now().Unix() - status.StartedAt)
-				steptime := int(time.Now().Unix() - status.CurrentStepStartedAt)
-				if err := csvw.Write([]string{
-					strconv.Itoa(elapsed),
-					status.CurrentStep,
-					strconv.Itoa(steptime),
-					fmt.Sprintf("%.1f", status.Temperatures.Material),
-					fmt.Sprintf("%.1f", status.Temperatures.Kiln),
-					strconv.Itoa(int(status.PowerStatus.Heater)),
-					strconv.Itoa(int(status.PowerStatus.Fan)),
-					strconv.Itoa(int(status.PowerStatus.Steam)),
-					fmt.Sprintf("%.1f", status.Temperatures.MaterialDie),
-					fmt.Sprintf("%.1f", status.Temperatures.KilnPrimaryDie),
-					fmt.Sprintf("%.1f", status.Temperatures.KilnSecondaryDie),
-				}); err != nil {
+				now := time.Now().Unix()
+				if err := csvw.Write(storagefs.ExecutionLogRow(status, now-status.StartedAt, now-status.CurrentStepStartedAt)); err != nil {
 					log.Warning("CSV line write error: %v", err)
 					continue
 				}
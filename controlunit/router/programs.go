@@ -1,6 +1,7 @@
 package router
 
 import (
+	"errors"
 	"net/http"
 	"os"
 	"sort"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/rmkhl/halko/controlunit/engine"
 	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
 )
 
 func listAllRuns(storage types.ExecutionStorage) http.HandlerFunc {
@@ -63,7 +65,7 @@ func startTimeFromName(name string) int64 {
 	return 0
 }
 
-func getRun(storage types.ExecutionStorage) http.HandlerFunc {
+func getRun(storage types.ExecutionStorage, programStorage types.ProgramStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		programName := r.PathValue("name")
 		program, err := storage.LoadExecutedProgram(programName)
@@ -72,15 +74,66 @@ func getRun(storage types.ExecutionStorage) http.HandlerFunc {
 			return
 		}
 		state, updatedAt, _ := storage.LoadState(programName)
+		// Events are context for the run, not the run itself: one that cannot
+		// be read is reported in the log and the record served without them.
+		events, err := storage.LoadRunEvents(programName)
+		if err != nil {
+			log.Warning("Failed to load run events for '%s': %v", programName, err)
+		}
+		// So is the compliance report, which only restates what the run's
+		// log, served all the same, shows.
+		compliance, err := storage.LoadComplianceReport(programName)
+		if err != nil {
+			log.Warning("Failed to load compliance report for '%s': %v", programName, err)
+		}
+		storedState, storedRevision := storedProgramState(program, startTimeFromName(programName), programStorage)
 		writeJSON(w, http.StatusOK, types.APIResponse[types.ExecutedProgram]{
 			Data: types.ExecutedProgram{
 				RunHistory: types.RunHistory{State: state, CompletedAt: updatedAt, StartedAt: startTimeFromName(programName)},
 				Program:    *program,
+				Events:     events,
+				Compliance: compliance,
+
+				StoredProgramState:    storedState,
+				StoredProgramRevision: storedRevision,
 			},
 		})
 	}
 }
 
+// storedProgramState compares a run started by name with the stored program
+// it was started from, as that program is now, and finds which of its
+// revisions the run was started from. Only a program that is no longer
+// stored is reported deleted; one that cannot be read is left unreported.
+func storedProgramState(program *types.Program, startedAt int64, programStorage types.ProgramStorage) (types.StoredProgramState, int) {
+	if program.StoredProgram == nil {
+		return "", 0
+	}
+	revisions, err := programStorage.ListProgramRevisions(program.StoredProgram.Name)
+	if errors.Is(err, types.ErrProgramDoesNotExist) {
+		return types.StoredProgramDeleted, 0
+	}
+	if err != nil {
+		log.Warning("Failed to list revisions of stored program '%s': %v", program.StoredProgram.Name, err)
+		return "", 0
+	}
+
+	// A restore brings back content an earlier revision had, so the run
+	// came from the newest revision with the hash saved before it started.
+	revision := 0
+	for _, candidate := range revisions {
+		savedAt, err := time.Parse(time.RFC3339, candidate.SavedAt)
+		if candidate.Hash != program.StoredProgram.Hash || (err == nil && startedAt > 0 && savedAt.Unix() > startedAt) {
+			continue
+		}
+		revision = candidate.Revision
+	}
+	if revisions[len(revisions)-1].Hash != program.StoredProgram.Hash {
+		return types.StoredProgramChanged, revision
+	}
+	return types.StoredProgramUnchanged, revision
+}
+
 func deleteRun(storage types.ExecutionStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		programName := r.PathValue("name")
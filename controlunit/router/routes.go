@@ -14,7 +14,8 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		// Allow requests from any origin (for development)
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, If-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
@@ -46,12 +47,13 @@ func SetupRoutes(mux *http.ServeMux, execStorage types.ExecutionStorage, program
 	// Engine execution endpoints
 	mux.HandleFunc("GET "+endpoints.ControlUnit.Engine+"/running/log", corsMiddleware(getRunningLog(execStorage, engine)))
 	mux.HandleFunc("GET "+endpoints.ControlUnit.Engine+"/history", corsMiddleware(listAllRuns(execStorage)))
-	mux.HandleFunc("GET "+endpoints.ControlUnit.Engine+"/history/{name}", corsMiddleware(getRun(execStorage)))
+	mux.HandleFunc("GET "+endpoints.ControlUnit.Engine+"/history/{name}", corsMiddleware(getRun(execStorage, programStorage)))
 	mux.HandleFunc("GET "+endpoints.ControlUnit.Engine+"/history/{name}/log", corsMiddleware(getRunLog(execStorage)))
 	mux.HandleFunc("DELETE "+endpoints.ControlUnit.Engine+"/history/{name}", corsMiddleware(deleteRun(execStorage)))
 	mux.HandleFunc("GET "+endpoints.ControlUnit.Engine+"/running", corsMiddleware(getCurrentProgram(engine)))
-	mux.HandleFunc("POST "+endpoints.ControlUnit.Engine+"/running", corsMiddleware(startNewProgram(engine)))
+	mux.HandleFunc("POST "+endpoints.ControlUnit.Engine+"/running", corsMiddleware(startNewProgram(engine, programStorage)))
 	mux.HandleFunc("DELETE "+endpoints.ControlUnit.Engine+"/running", corsMiddleware(cancelRunningProgram(engine)))
+	mux.HandleFunc("POST "+endpoints.ControlUnit.Engine+"/running/confirm", corsMiddleware(confirmRunningStep(engine)))
 	mux.HandleFunc("GET "+endpoints.ControlUnit.Engine+"/defaults", corsMiddleware(getDefaults(engine)))
 
 	// Status endpoint
@@ -60,6 +62,8 @@ func SetupRoutes(mux *http.ServeMux, execStorage types.ExecutionStorage, program
 	// System endpoints
 	mux.HandleFunc("GET /system/status", corsMiddleware(getSystemStatus(execStorage, config)))
 	mux.HandleFunc("GET /system/hardware", corsMiddleware(getHardwareStatus(endpoints)))
+	mux.HandleFunc("GET /system/storage", corsMiddleware(checkStorage(execStorage, engine)))
+	mux.HandleFunc("POST /system/storage/repair", corsMiddleware(repairStorage(execStorage, engine)))
 
 	// Program storage endpoints (stored/saved programs)
 	mux.HandleFunc("GET "+endpoints.ControlUnit.Programs, corsMiddleware(listAllStoredPrograms(programStorage)))
@@ -67,4 +71,8 @@ func SetupRoutes(mux *http.ServeMux, execStorage types.ExecutionStorage, program
 	mux.HandleFunc("POST "+endpoints.ControlUnit.Programs, corsMiddleware(createStoredProgram(programStorage)))
 	mux.HandleFunc("POST "+endpoints.ControlUnit.Programs+"/{name}", corsMiddleware(updateStoredProgram(programStorage)))
 	mux.HandleFunc("DELETE "+endpoints.ControlUnit.Programs+"/{name}", corsMiddleware(deleteStoredProgram(programStorage)))
+	mux.HandleFunc("GET "+endpoints.ControlUnit.Programs+"/{name}/revisions", corsMiddleware(listProgramRevisions(programStorage)))
+	mux.HandleFunc("GET "+endpoints.ControlUnit.Programs+"/{name}/revisions/{revision}", corsMiddleware(getProgramRevision(programStorage)))
+	mux.HandleFunc("POST "+endpoints.ControlUnit.Programs+"/{name}/revisions/{revision}/restore", corsMiddleware(restoreProgramRevision(programStorage)))
+	mux.HandleFunc("GET "+endpoints.ControlUnit.Programs+"/{name}/diff", corsMiddleware(diffProgramRevisions(programStorage)))
 }
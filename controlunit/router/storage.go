@@ -3,7 +3,9 @@ package router
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/rmkhl/halko/types"
 )
@@ -24,9 +26,10 @@ func getStoredProgram(storage types.ProgramStorage) http.HandlerFunc {
 		programName := r.PathValue("name")
 		program, err := storage.LoadStoredProgram(programName)
 		if err != nil {
-			writeError(w, http.StatusNotFound, err.Error())
+			storedProgramError(w, err)
 			return
 		}
+		setProgramETag(w, program)
 		writeJSON(w, http.StatusOK, types.APIResponse[types.Program]{Data: *program})
 	}
 }
@@ -47,15 +50,16 @@ func createStoredProgram(storage types.ProgramStorage) http.HandlerFunc {
 		}
 
 		err = storage.CreateStoredProgram(program.ProgramName, &program)
-		if errors.Is(err, types.ErrInvalidStorageName) {
-			writeError(w, http.StatusBadRequest, err.Error())
+		if errors.Is(err, types.ErrProgramExists) {
+			writeError(w, http.StatusConflict, err.Error())
 			return
 		}
 		if err != nil {
-			writeError(w, http.StatusConflict, err.Error())
+			storedProgramError(w, err)
 			return
 		}
 
+		setProgramETag(w, &program)
 		writeJSON(w, http.StatusCreated, types.APIResponse[types.Program]{Data: program})
 	}
 }
@@ -73,16 +77,17 @@ func updateStoredProgram(storage types.ProgramStorage) http.HandlerFunc {
 
 		program.ProgramName = programName
 
-		err = storage.UpdateStoredProgram(programName, &program)
-		if errors.Is(err, types.ErrInvalidStorageName) {
-			writeError(w, http.StatusBadRequest, err.Error())
+		err = storage.UpdateStoredProgram(programName, &program, r.URL.Query().Get("note"), r.Header.Get("If-Match"))
+		if errors.Is(err, types.ErrProgramChanged) {
+			writeProgramConflict(w, storage, programName)
 			return
 		}
 		if err != nil {
-			writeError(w, http.StatusNotFound, err.Error())
+			storedProgramError(w, err)
 			return
 		}
 
+		setProgramETag(w, &program)
 		writeJSON(w, http.StatusOK, types.APIResponse[types.Program]{Data: program})
 	}
 }
@@ -91,16 +96,176 @@ func deleteStoredProgram(storage types.ProgramStorage) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		programName := r.PathValue("name")
 
-		err := storage.DeleteStoredProgram(programName)
-		if errors.Is(err, types.ErrInvalidStorageName) {
-			writeError(w, http.StatusBadRequest, err.Error())
+		err := storage.DeleteStoredProgram(programName, r.Header.Get("If-Match"))
+		if errors.Is(err, types.ErrProgramChanged) {
+			writeProgramConflict(w, storage, programName)
 			return
 		}
 		if err != nil {
-			writeError(w, http.StatusNotFound, err.Error())
+			storedProgramError(w, err)
 			return
 		}
 
 		writeJSON(w, http.StatusOK, types.APIResponse[string]{Data: "deleted"})
 	}
 }
+
+// setProgramETag tags the response with the version of the stored program it
+// carries, for the client to send back as If-Match when it writes.
+func setProgramETag(w http.ResponseWriter, program *types.Program) {
+	if hash, err := program.ContentHash(); err == nil {
+		w.Header().Set("ETag", types.ProgramETag(hash))
+	}
+}
+
+// writeProgramConflict answers a write made against an earlier version of the
+// program with the version that is stored now, so the client can see what it
+// would have overwritten.
+func writeProgramConflict(w http.ResponseWriter, storage types.ProgramStorage, programName string) {
+	current, err := storage.LoadStoredProgram(programName)
+	if err != nil {
+		storedProgramError(w, err)
+		return
+	}
+	hash, err := current.ContentHash()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	conflict := types.ProgramConflict{
+		Err:     fmt.Sprintf("program '%s' has been changed since it was read", programName),
+		ETag:    types.ProgramETag(hash),
+		Current: *current,
+	}
+	if revisions, err := storage.ListProgramRevisions(programName); err == nil {
+		conflict.Revision = revisions[len(revisions)-1].Revision
+	}
+	w.Header().Set("ETag", conflict.ETag)
+	writeJSON(w, http.StatusConflict, conflict)
+}
+
+// storedProgramError answers for a failed read or write of a stored program or
+// one of its revisions: an unusable name is bad input, a write without
+// If-Match lacks its precondition, and a program or revision that is not there
+// is not found. Anything else is the storage failing, which the client cannot
+// do anything about.
+func storedProgramError(w http.ResponseWriter, err error) {
+	writeError(w, storedProgramStatus(err), err.Error())
+}
+
+// storedProgramStatus is the status storedProgramError answers err with.
+func storedProgramStatus(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, types.ErrInvalidStorageName):
+		return http.StatusBadRequest
+	case errors.Is(err, types.ErrIfMatchRequired):
+		return http.StatusPreconditionRequired
+	case errors.Is(err, types.ErrProgramDoesNotExist), errors.Is(err, types.ErrRevisionDoesNotExist):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func listProgramRevisions(storage types.ProgramStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		revisions, err := storage.ListProgramRevisions(r.PathValue("name"))
+		if err != nil {
+			storedProgramError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, types.APIResponse[[]types.ProgramRevision]{Data: revisions})
+	}
+}
+
+func getProgramRevision(storage types.ProgramStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		revision, err := strconv.Atoi(r.PathValue("revision"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Revision must be a number")
+			return
+		}
+		program, err := storage.LoadProgramRevision(r.PathValue("name"), revision)
+		if err != nil {
+			storedProgramError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, types.APIResponse[types.Program]{Data: *program})
+	}
+}
+
+// diffProgramRevisions compares two revisions of a program, given as the from
+// and to query parameters. Left out, to is the current revision and from the
+// one before it, which answers "what did the last update change".
+func diffProgramRevisions(storage types.ProgramStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		programName := r.PathValue("name")
+		revisions, err := storage.ListProgramRevisions(programName)
+		if err != nil {
+			storedProgramError(w, err)
+			return
+		}
+
+		to := revisions[len(revisions)-1].Revision
+		if value := r.URL.Query().Get("to"); value != "" {
+			if to, err = strconv.Atoi(value); err != nil {
+				writeError(w, http.StatusBadRequest, "to must be a revision number")
+				return
+			}
+		}
+		from := max(to-1, 1)
+		if value := r.URL.Query().Get("from"); value != "" {
+			if from, err = strconv.Atoi(value); err != nil {
+				writeError(w, http.StatusBadRequest, "from must be a revision number")
+				return
+			}
+		}
+
+		fromProgram, err := storage.LoadProgramRevision(programName, from)
+		if err != nil {
+			storedProgramError(w, fmt.Errorf("revision %d: %w", from, err))
+			return
+		}
+		toProgram, err := storage.LoadProgramRevision(programName, to)
+		if err != nil {
+			storedProgramError(w, fmt.Errorf("revision %d: %w", to, err))
+			return
+		}
+		lines, err := types.DiffPrograms(fromProgram, toProgram)
+		if errors.Is(err, types.ErrDiffTooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, types.APIResponse[types.ProgramDiff]{Data: types.ProgramDiff{From: from, To: to, Lines: lines}})
+	}
+}
+
+// restoreProgramRevision makes an earlier revision the current one again. It
+// is saved as a new revision, so nothing is lost by restoring.
+func restoreProgramRevision(storage types.ProgramStorage) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		revision, err := strconv.Atoi(r.PathValue("revision"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Revision must be a number")
+			return
+		}
+		programName := r.PathValue("name")
+		program, err := storage.RestoreProgramRevision(programName, revision, r.URL.Query().Get("note"), r.Header.Get("If-Match"))
+		if errors.Is(err, types.ErrProgramChanged) {
+			writeProgramConflict(w, storage, programName)
+			return
+		}
+		if err != nil {
+			storedProgramError(w, err)
+			return
+		}
+		setProgramETag(w, program)
+		writeJSON(w, http.StatusOK, types.APIResponse[types.Program]{Data: *program})
+	}
+}
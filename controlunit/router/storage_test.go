@@ -0,0 +1,143 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rmkhl/halko/controlunit/storagefs"
+	"github.com/rmkhl/halko/types"
+)
+
+func programRequest(method, body, ifMatch string) *http.Request {
+	req := httptest.NewRequest(method, "/programs/Oak", strings.NewReader(body))
+	req.SetPathValue("name", "Oak")
+	if ifMatch != "" {
+		req.Header.Set("If-Match", ifMatch)
+	}
+	return req
+}
+
+// Two clients read the same version; the first to write wins, and the second
+// is told so with the version it would have overwritten.
+func TestUpdateStoredProgramIfMatch(t *testing.T) {
+	storage := oakStorage(t)
+
+	rec := httptest.NewRecorder()
+	getStoredProgram(storage)(rec, programRequest(http.MethodGet, "", ""))
+	read := rec.Header().Get("ETag")
+	if !strings.HasPrefix(read, `"sha256:`) {
+		t.Fatalf("ETag = %q, want the quoted content hash", read)
+	}
+
+	first := strings.Replace(storedOak, `"6h"`, `"8h"`, 1)
+	rec = httptest.NewRecorder()
+	updateStoredProgram(storage)(rec, programRequest(http.MethodPost, first, read))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first update = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+	written := rec.Header().Get("ETag")
+	if written == "" || written == read {
+		t.Fatalf("ETag after the update = %q, want a new one", written)
+	}
+
+	second := strings.Replace(storedOak, `"6h"`, `"4h"`, 1)
+	rec = httptest.NewRecorder()
+	updateStoredProgram(storage)(rec, programRequest(http.MethodPost, second, read))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("second update = %d (%s), want 409", rec.Code, rec.Body.String())
+	}
+	var conflict types.ProgramConflict
+	if err := json.Unmarshal(rec.Body.Bytes(), &conflict); err != nil {
+		t.Fatalf("decoding the conflict: %v", err)
+	}
+	if conflict.ETag != written || conflict.Revision != 2 || conflict.Current.ProgramSteps[1].Runtime.String() != "8h0m0s" {
+		t.Errorf("conflict = %+v, want the first update as revision 2", conflict)
+	}
+
+	stored, _ := storage.LoadStoredProgram("Oak")
+	if stored.ProgramSteps[1].Runtime.String() != "8h0m0s" {
+		t.Error("the stale update reached the stored program")
+	}
+}
+
+func TestDeleteStoredProgramIfMatch(t *testing.T) {
+	storage := oakStorage(t)
+
+	rec := httptest.NewRecorder()
+	deleteStoredProgram(storage)(rec, programRequest(http.MethodDelete, "", `"sha256:0123"`))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("delete of another version = %d, want 409", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	deleteStoredProgram(storage)(rec, programRequest(http.MethodDelete, "", "*"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("delete of any version = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+}
+
+// A write has to say which version it replaces, even if that is any: without
+// If-Match the last writer would silently win.
+func TestUpdateStoredProgramWithoutIfMatch(t *testing.T) {
+	storage := oakStorage(t)
+
+	rec := httptest.NewRecorder()
+	updateStoredProgram(storage)(rec, programRequest(http.MethodPost, storedOak, ""))
+	if rec.Code != http.StatusPreconditionRequired {
+		t.Fatalf("update without If-Match = %d (%s), want 428", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	deleteStoredProgram(storage)(rec, programRequest(http.MethodDelete, "", ""))
+	if rec.Code != http.StatusPreconditionRequired {
+		t.Fatalf("delete without If-Match = %d (%s), want 428", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	updateStoredProgram(storage)(rec, programRequest(http.MethodPost, storedOak, "*"))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update of any version = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+}
+
+// failingStorage is a card that has stopped taking writes.
+type failingStorage struct {
+	*storagefs.ProgramStorage
+}
+
+var errCardFull = errors.New("no space left on device")
+
+func (failingStorage) UpdateStoredProgram(string, *types.Program, string, string) error {
+	return errCardFull
+}
+
+func (failingStorage) DeleteStoredProgram(string, string) error {
+	return errCardFull
+}
+
+// A write the storage fails is the server's fault, not a missing program.
+func TestStoredProgramWriteFailures(t *testing.T) {
+	storage := failingStorage{oakStorage(t)}
+
+	rec := httptest.NewRecorder()
+	updateStoredProgram(storage)(rec, programRequest(http.MethodPost, storedOak, "*"))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("failed update = %d, want 500", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	deleteStoredProgram(storage)(rec, programRequest(http.MethodDelete, "", "*"))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("failed delete = %d, want 500", rec.Code)
+	}
+
+	missing := httptest.NewRequest(http.MethodGet, "/programs/Pine", nil)
+	missing.SetPathValue("name", "Pine")
+	rec = httptest.NewRecorder()
+	getStoredProgram(storage)(rec, missing)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing program = %d, want 404", rec.Code)
+	}
+}
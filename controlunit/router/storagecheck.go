@@ -0,0 +1,56 @@
+package router
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/rmkhl/halko/controlunit/engine"
+	"github.com/rmkhl/halko/types"
+)
+
+// runningProgram is the part of the engine the storage check needs: which
+// run's files are meant to be in running/.
+type runningProgram interface {
+	CurrentProgramName() string
+}
+
+func checkStorage(storage types.ExecutionStorage, engine runningProgram) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		issues, err := storage.CheckStorage(engine.CurrentProgramName())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, types.APIResponse[[]types.StorageIssue]{Data: issues})
+	}
+}
+
+// idleEngine is the part of the engine a storage repair needs: a way to
+// keep the kiln idle while files are moved.
+type idleEngine interface {
+	WhileIdle(work func() error) error
+}
+
+// repairStorage repairs what checkStorage reports. It runs with the kiln idle
+// and no run able to start, as a run starting while files are moved could
+// lose its own.
+func repairStorage(storage types.ExecutionStorage, controlEngine idleEngine) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		var issues []types.StorageIssue
+		err := controlEngine.WhileIdle(func() error {
+			var err error
+			issues, err = storage.RepairStorage()
+			return err
+		})
+		switch {
+		case errors.Is(err, engine.ErrProgramAlreadyRunning):
+			writeError(w, http.StatusConflict, types.ErrRepairWhileRunning.Error())
+		case errors.Is(err, engine.ErrEngineBusy):
+			writeError(w, http.StatusConflict, err.Error())
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		default:
+			writeJSON(w, http.StatusOK, types.APIResponse[[]types.StorageIssue]{Data: issues})
+		}
+	}
+}
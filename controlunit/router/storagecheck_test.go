@@ -0,0 +1,49 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rmkhl/halko/controlunit/engine"
+	"github.com/rmkhl/halko/controlunit/storagefs"
+)
+
+type fakeRunning string
+
+func (name fakeRunning) CurrentProgramName() string { return string(name) }
+
+func (name fakeRunning) WhileIdle(work func() error) error {
+	switch name {
+	case "":
+		return work()
+	case "repairing":
+		return engine.ErrEngineBusy
+	}
+	return engine.ErrProgramAlreadyRunning
+}
+
+func TestRepairStorageWaitsForTheKilnToBeIdle(t *testing.T) {
+	storage, err := storagefs.NewExecutorFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating storage: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	repairStorage(storage, fakeRunning("Oak-1700000000"))(rec, httptest.NewRequest(http.MethodPost, "/system/storage/repair", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("repair during a run = %d, want 409", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	repairStorage(storage, fakeRunning("repairing"))(rec, httptest.NewRequest(http.MethodPost, "/system/storage/repair", nil))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("repair during a repair = %d, want 409", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	repairStorage(storage, fakeRunning(""))(rec, httptest.NewRequest(http.MethodPost, "/system/storage/repair", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("repair when idle = %d (%s), want 200", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,46 @@
+package storagefs
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// SaveComplianceReport files a finished run's compliance report. It goes
+// straight into history rather than through running/, as it is only made once
+// the run is over: a report in running/ would be one more file for a crash to
+// strand there, and one the search for orphaned runs would mistake for a run.
+func (storage *ExecutorFileStorage) SaveComplianceReport(name string, report *types.ComplianceReport) error {
+	if err := types.ValidateStorageName(name); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	log.Info("Saving compliance report for program '%s'", name)
+	return types.WriteFileAtomic(filepath.Join(storage.compliancePath, name+".json"), content, 0o644)
+}
+
+// LoadComplianceReport returns a finished run's compliance report. A run
+// without a heat treatment step has none, and gets nil rather than an error.
+func (storage *ExecutorFileStorage) LoadComplianceReport(name string) (*types.ComplianceReport, error) {
+	if err := types.ValidateStorageName(name); err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(filepath.Join(storage.compliancePath, name+".json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var report types.ComplianceReport
+	if err := json.Unmarshal(content, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
@@ -0,0 +1,62 @@
+package storagefs
+
+import (
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func TestComplianceReportRoundTrip(t *testing.T) {
+	storage := newTestStorage(t)
+
+	report := &types.ComplianceReport{
+		Run:            runName,
+		Program:        "Pallets",
+		MaterialFusion: types.MaterialFusionMin,
+		HeatTreatments: []types.HeatTreatmentResult{
+			{Step: "ISPM-15", Threshold: 56, RequiredSeconds: 1800, OnDip: types.HoldDipReset, HeldSeconds: 1801, Met: true, MetAt: 4000},
+		},
+		LogSHA256: "abc",
+	}
+	if err := storage.SaveComplianceReport(runName, report); err != nil {
+		t.Fatalf("SaveComplianceReport: %v", err)
+	}
+
+	loaded, err := storage.LoadComplianceReport(runName)
+	if err != nil {
+		t.Fatalf("LoadComplianceReport: %v", err)
+	}
+	if loaded == nil || !loaded.Compliant() || loaded.HeatTreatments[0].MetAt != 4000 {
+		t.Fatalf("report came back as %+v", loaded)
+	}
+}
+
+// Most runs have no heat treatment and so no report; that is not an error.
+func TestLoadComplianceReportWithoutAFileIsNil(t *testing.T) {
+	storage := newTestStorage(t)
+
+	report, err := storage.LoadComplianceReport(runName)
+	if err != nil || report != nil {
+		t.Fatalf("expected no report and no error, got %v (%v)", report, err)
+	}
+}
+
+func TestDeleteExecutedProgramRemovesTheComplianceReport(t *testing.T) {
+	storage := newTestStorage(t)
+	startRun(t, storage, runName)
+	if err := storage.MoveToHistory(runName); err != nil {
+		t.Fatalf("failed to move to history: %v", err)
+	}
+	if err := storage.SaveComplianceReport(runName, &types.ComplianceReport{Run: runName}); err != nil {
+		t.Fatalf("SaveComplianceReport: %v", err)
+	}
+
+	if err := storage.DeleteExecutedProgram(runName); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	report, err := storage.LoadComplianceReport(runName)
+	if err != nil || report != nil {
+		t.Fatalf("expected the report gone, got %v (%v)", report, err)
+	}
+}
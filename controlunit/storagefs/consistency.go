@@ -0,0 +1,179 @@
+package storagefs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// quarantineDir is where files the consistency scan cannot read are moved,
+// under the path they had, so they are out of the way of the control unit but
+// still there for someone to look at.
+const quarantineDir = "quarantine"
+
+type (
+	// QuarantinedFile is a file the consistency scan moved aside. Path is
+	// where it was and QuarantinedAs where it went, both relative to the
+	// storage base path.
+	QuarantinedFile struct {
+		Path          string
+		QuarantinedAs string
+		Reason        string
+	}
+
+	fileCheck struct {
+		pattern string
+		check   func(content []byte) error
+	}
+)
+
+// Every file the control unit rewrites as a whole, and what it has to hold.
+// The logs and run events are only ever appended to, and their readers skip a
+// torn last line, so they are left alone.
+var fileChecks = []fileCheck{
+	{"programs/*.json", checkProgram},
+	{"programs/revisions/*/" + revisionIndexFile, checkRevisionIndex},
+	{"programs/revisions/*/[0-9]*.json", checkProgram},
+	{"history/*.json", checkProgram},
+	{"history/status/*.txt", checkState},
+	{"history/compliance/*.json", checkComplianceReport},
+	{"running/*.json", checkProgram},
+	{"running/*.txt", checkState},
+}
+
+// CheckConsistency looks over the storage at startup for what a power cut in
+// the middle of a write can leave behind. Since writes go through
+// types.WriteFileAtomic that is no more than an unfinished temporary file,
+// which is removed; but files written before then, or damaged on the card,
+// can be empty or cut short. Those are moved to quarantine/, so that listing
+// programs and history works on what is left rather than failing on them.
+func CheckConsistency(basePath string) ([]QuarantinedFile, error) {
+	log.Info("Checking storage consistency in %s", basePath)
+	if err := removeUnfinishedWrites(basePath); err != nil {
+		return nil, err
+	}
+
+	var quarantined []QuarantinedFile
+	for _, fileCheck := range fileChecks {
+		files, err := filepath.Glob(filepath.Join(basePath, fileCheck.pattern))
+		if err != nil {
+			return quarantined, err
+		}
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				return quarantined, err
+			}
+			problem := fileCheck.check(content)
+			if problem == nil {
+				continue
+			}
+			moved, err := quarantine(basePath, file, problem)
+			if err != nil {
+				return quarantined, err
+			}
+			quarantined = append(quarantined, moved)
+		}
+	}
+
+	if len(quarantined) == 0 {
+		log.Info("Storage is consistent")
+	}
+	return quarantined, nil
+}
+
+// removeUnfinishedWrites deletes the temporary files of writes that never
+// reached their rename. The file they were to replace is still whole.
+func removeUnfinishedWrites(basePath string) error {
+	return filepath.WalkDir(basePath, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != basePath && (entry.Name() == quarantineDir || entry.Name() == archiveDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") && strings.HasSuffix(entry.Name(), types.TempFileSuffix) {
+			log.Warning("Removing unfinished write %s", path)
+			return os.Remove(path)
+		}
+		return nil
+	})
+}
+
+func quarantine(basePath, file string, problem error) (QuarantinedFile, error) {
+	relative, target, err := moveAside(basePath, quarantineDir, file)
+	if err != nil {
+		return QuarantinedFile{}, err
+	}
+	log.Warning("Quarantined %s as %s: %v", relative, target, problem)
+	return QuarantinedFile{Path: relative, QuarantinedAs: target, Reason: problem.Error()}, nil
+}
+
+// moveAside moves the file into the directory under the base path, keeping
+// the path it had below the base, and returns both paths relative to the
+// base. The time keeps a second copy of the same file from replacing the
+// first.
+func moveAside(basePath, intoDir, file string) (relative, target string, err error) {
+	relative, err = filepath.Rel(basePath, file)
+	if err != nil {
+		return "", "", err
+	}
+	target = filepath.Join(intoDir, fmt.Sprintf("%s.%d", relative, time.Now().Unix()))
+	if err := os.MkdirAll(filepath.Join(basePath, filepath.Dir(target)), os.ModePerm); err != nil {
+		return "", "", err
+	}
+	if err := os.Rename(file, filepath.Join(basePath, target)); err != nil {
+		return "", "", err
+	}
+	return relative, target, nil
+}
+
+func checkProgram(content []byte) error {
+	var program types.Program
+	return checkJSON(content, &program)
+}
+
+func checkRevisionIndex(content []byte) error {
+	var index []types.ProgramRevision
+	if err := checkJSON(content, &index); err != nil {
+		return err
+	}
+	if len(index) == 0 {
+		return errors.New("no revisions")
+	}
+	return nil
+}
+
+func checkComplianceReport(content []byte) error {
+	var report types.ComplianceReport
+	return checkJSON(content, &report)
+}
+
+func checkJSON(content []byte, value any) error {
+	if len(content) == 0 {
+		return errors.New("empty file")
+	}
+	return json.Unmarshal(content, value)
+}
+
+func checkState(content []byte) error {
+	switch state := types.ProgramState(content); state {
+	case types.ProgramStateCanceled, types.ProgramStateCompleted, types.ProgramStateFailed,
+		types.ProgramStatePending, types.ProgramStateRunning, types.ProgramStateUnknown:
+		return nil
+	case "":
+		return errors.New("empty file")
+	default:
+		return fmt.Errorf("unknown state %q", state)
+	}
+}
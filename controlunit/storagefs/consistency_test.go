@@ -0,0 +1,86 @@
+package storagefs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckConsistencyQuarantinesCorruptFiles(t *testing.T) {
+	storage := newTestStorage(t)
+	programs, err := NewProgramStorage(storage.BasePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := programs.CreateStoredProgram("Oak", testProgram("Oak")); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.SaveProgram(filepath.Join(storage.executedProgramsPath, "run1.json"), testProgram("run1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.UpdateState("run1", types.ProgramStateCompleted); err != nil {
+		t.Fatal(err)
+	}
+
+	// What a power cut part way through the old, unsafe writes left behind.
+	writeFile(t, filepath.Join(programs.programPath, "Pine.json"), `{"name": "Pine", "steps": [`)
+	writeFile(t, filepath.Join(storage.executedProgramsPath, "run2.json"), "")
+	writeFile(t, filepath.Join(storage.statusPath, "run2.txt"), "")
+	writeFile(t, filepath.Join(storage.runningPath, "run3.txt"), "runn")
+	unfinished := filepath.Join(programs.programPath, ".Oak.json.123"+types.TempFileSuffix)
+	writeFile(t, unfinished, `{"na`)
+
+	quarantined, err := CheckConsistency(storage.BasePath)
+	if err != nil {
+		t.Fatalf("CheckConsistency: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, file := range quarantined {
+		got[file.Path] = file.Reason
+		if _, err := os.Stat(filepath.Join(storage.BasePath, file.QuarantinedAs)); err != nil {
+			t.Errorf("%s not in quarantine: %v", file.Path, err)
+		}
+		if !strings.HasPrefix(file.QuarantinedAs, filepath.Join(quarantineDir, file.Path)) {
+			t.Errorf("%s quarantined as %s, want it under its own path", file.Path, file.QuarantinedAs)
+		}
+	}
+	for _, path := range []string{"programs/Pine.json", "history/run2.json", "history/status/run2.txt", "running/run3.txt"} {
+		if _, ok := got[path]; !ok {
+			t.Errorf("%s not quarantined; quarantined %v", path, got)
+		}
+	}
+	if len(got) != 4 {
+		t.Errorf("quarantined %v, want only the four corrupt files", got)
+	}
+	if _, err := os.Stat(unfinished); !os.IsNotExist(err) {
+		t.Errorf("unfinished write left behind: %v", err)
+	}
+
+	// What is left lists and loads.
+	if names, _ := programs.ListStoredPrograms(); len(names) != 1 || names[0] != "Oak" {
+		t.Errorf("stored programs = %v, want Oak alone", names)
+	}
+	if names, _ := storage.ListExecutedPrograms(); len(names) != 1 || names[0] != "run1" {
+		t.Errorf("history = %v, want run1 alone", names)
+	}
+
+	// A second scan finds nothing more, and leaves the quarantine alone.
+	if again, err := CheckConsistency(storage.BasePath); err != nil || len(again) != 0 {
+		t.Errorf("second scan = %v, %v; want nothing", again, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rmkhl/halko/types"
@@ -13,10 +14,10 @@ import (
 )
 
 // ExecutionLogColumns is the execution log's CSV header, shared with the
-// websocket stream that serves the same rows live so the two cannot drift
-// apart. The die columns are appended rather than placed beside the readings
-// they belong to, so logs written before them stay readable against the same
-// code.
+// websocket stream that serves the same rows live (see ExecutionLogRow) so
+// the two cannot drift apart. The die and per-probe columns are appended
+// rather than placed beside the readings they belong to, so logs written
+// before them stay readable against the same code.
 var ExecutionLogColumns = []string{
 	"time",
 	"step",
@@ -29,6 +30,86 @@ var ExecutionLogColumns = []string{
 	"material_die",
 	"kiln_primary_die",
 	"kiln_secondary_die",
+	"kiln_primary",
+	"kiln_secondary",
+	"material_probes",
+	"relative_humidity",
+	"wet_bulb",
+	"emc",
+	"vent",
+	"moisture",
+	"moisture_probes",
+	"step_number",
+}
+
+// ExecutionLogRow formats a status as one execution log row, with elapsed
+// and steptime in seconds since the run and the step started.
+//
+// Per-probe readings are uncorrected, as the sensor unit reports them. The
+// material probes share one column, their readings separated by spaces in
+// probe order, since how many there are depends on the board. The climate
+// columns are empty while there is no humidity reading, and the moisture
+// columns while there is no moisture reading; the moisture pins share one
+// column the way the material probes do.
+func ExecutionLogRow(status *types.ExecutionStatus, elapsed, steptime int64) []string {
+	var materialProbes []string
+	for n := 1; n <= types.MaxMaterialProbes; n++ {
+		if value, ok := status.Temperatures.Probes[types.MaterialProbeName(n)]; ok {
+			materialProbes = append(materialProbes, fmt.Sprintf("%.1f", value))
+		}
+	}
+
+	var humidity, wetBulb, emc string
+	if climate := status.Temperatures.Climate; climate != nil {
+		humidity = fmt.Sprintf("%.1f", climate.RelativeHumidity)
+		wetBulb = fmt.Sprintf("%.1f", climate.WetBulb)
+		emc = fmt.Sprintf("%.1f", climate.EMC)
+	}
+
+	var moisture string
+	var moistureProbes []string
+	if reading := status.Temperatures.Moisture; reading != nil {
+		moisture = fmt.Sprintf("%.1f", reading.Material)
+		for n := 1; n <= types.MaxMoistureProbes; n++ {
+			if value, ok := reading.Probes[types.MoistureProbeName(n)]; ok {
+				moistureProbes = append(moistureProbes, fmt.Sprintf("%.1f", value))
+			}
+		}
+	}
+
+	return []string{
+		strconv.FormatInt(elapsed, 10),
+		status.CurrentStep,
+		strconv.FormatInt(steptime, 10),
+		fmt.Sprintf("%.1f", status.Temperatures.Material),
+		fmt.Sprintf("%.1f", status.Temperatures.Kiln),
+		strconv.Itoa(int(status.PowerStatus.Heater)),
+		strconv.Itoa(int(status.PowerStatus.Fan)),
+		strconv.Itoa(int(status.PowerStatus.Steam)),
+		fmt.Sprintf("%.1f", status.Temperatures.MaterialDie),
+		fmt.Sprintf("%.1f", status.Temperatures.KilnPrimaryDie),
+		fmt.Sprintf("%.1f", status.Temperatures.KilnSecondaryDie),
+		probeColumn(status.Temperatures.Probes, types.ProbeKilnPrimary),
+		probeColumn(status.Temperatures.Probes, types.ProbeKilnSecondary),
+		strings.Join(materialProbes, " "),
+		humidity,
+		wetBulb,
+		emc,
+		strconv.Itoa(int(status.PowerStatus.Vent)),
+		moisture,
+		strings.Join(moistureProbes, " "),
+		strconv.Itoa(status.CurrentStepNumber),
+	}
+}
+
+// probeColumn formats a probe's reading, leaving the column empty when the
+// sensor unit did not report the probe at all.
+func probeColumn(probes map[string]float32, probe string) string {
+	value, ok := probes[probe]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("%.1f", value)
 }
 
 type (
@@ -41,6 +122,13 @@ type (
 		startedAt  int64
 		lastUpdate int64
 		lastStep   string
+		// Steps may share a name, so a change of step is told by its number.
+		lastStepNumber int
+
+		// Whether a heat treatment hold was holding at the last row. A row
+		// goes in whenever it changes, so the log shows every crossing of
+		// the threshold the compliance report is built from.
+		lastHolding bool
 	}
 )
 
@@ -76,39 +164,32 @@ func (writer *ExecutionLogWriter) AddLine(status *types.ExecutionStatus) {
 		return
 	}
 
-	// Only skip logging during true initialization (Waiting state)
-	if status.CurrentStep == "" || status.CurrentStep == "Waiting" || status.CurrentStep == "Completed" {
+	// Only skip logging outside the steps. A step's number says it is
+	// running even when the step has no name.
+	if status.CurrentStepNumber == 0 && (status.CurrentStep == "" || status.CurrentStep == "Waiting" || status.CurrentStep == "Completed") {
 		return
 	}
 
 	now := time.Now().Unix()
 
-	// Log if: step changed OR resolution time has elapsed
-	stepChanged := status.CurrentStep != writer.lastStep
+	// Log if: step changed OR hold crossed its threshold OR resolution time has elapsed
+	stepChanged := status.CurrentStep != writer.lastStep || status.CurrentStepNumber != writer.lastStepNumber
+	holding := status.HeatTreatment != nil && status.HeatTreatment.Holding
+	holdChanged := holding != writer.lastHolding
 	timeElapsed := now-writer.lastUpdate >= writer.resolution
 
-	if !stepChanged && !timeElapsed {
+	if !stepChanged && !holdChanged && !timeElapsed {
 		log.Trace("Execution log: Skipping line - step unchanged and resolution not met (last update %ds ago)", now-writer.lastUpdate)
 		return
 	}
 
-	log.Trace("Execution log: Adding line for step '%s' (changed=%v, elapsed=%v)", status.CurrentStep, stepChanged, timeElapsed)
-	_ = writer.csvWriter.Write([]string{
-		strconv.FormatInt(now-writer.startedAt, 10),
-		status.CurrentStep,
-		strconv.FormatInt(now-status.CurrentStepStartedAt, 10),
-		fmt.Sprintf("%.1f", status.Temperatures.Material),
-		fmt.Sprintf("%.1f", status.Temperatures.Kiln),
-		strconv.Itoa(int(status.PowerStatus.Heater)),
-		strconv.Itoa(int(status.PowerStatus.Fan)),
-		strconv.Itoa(int(status.PowerStatus.Steam)),
-		fmt.Sprintf("%.1f", status.Temperatures.MaterialDie),
-		fmt.Sprintf("%.1f", status.Temperatures.KilnPrimaryDie),
-		fmt.Sprintf("%.1f", status.Temperatures.KilnSecondaryDie),
-	})
+	log.Trace("Execution log: Adding line for step '%s' (changed=%v, hold changed=%v, elapsed=%v)", status.CurrentStep, stepChanged, holdChanged, timeElapsed)
+	_ = writer.csvWriter.Write(ExecutionLogRow(status, now-writer.startedAt, now-status.CurrentStepStartedAt))
 	writer.csvWriter.Flush()
 	writer.lastUpdate = now
 	writer.lastStep = status.CurrentStep
+	writer.lastStepNumber = status.CurrentStepNumber
+	writer.lastHolding = holding
 }
 
 func (writer *ExecutionLogWriter) GetStartTime() int64 {
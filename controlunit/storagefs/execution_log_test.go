@@ -3,6 +3,7 @@ package storagefs
 import (
 	"encoding/csv"
 	"os"
+	"slices"
 	"testing"
 	"time"
 
@@ -52,6 +53,9 @@ func TestExecutionLogWritesItsHeaderOnCreation(t *testing.T) {
 	want := []string{
 		"time", "step", "steptime", "material", "kiln", "heater", "fan", "steam",
 		"material_die", "kiln_primary_die", "kiln_secondary_die",
+		"kiln_primary", "kiln_secondary", "material_probes",
+		"relative_humidity", "wet_bulb", "emc", "vent",
+		"moisture", "moisture_probes", "step_number",
 	}
 	if len(rows[0]) != len(want) {
 		t.Fatalf("expected %d columns, got %v", len(want), rows[0])
@@ -107,6 +111,62 @@ func TestExecutionLogWritesOnEveryStepChange(t *testing.T) {
 	}
 }
 
+// Steps need no name, and two in a row may share one; the step number is what
+// tells them apart in the log.
+func TestExecutionLogTellsUnnamedStepsApart(t *testing.T) {
+	storage := newTestStorage(t)
+
+	writer := NewExecutionLogWriter(storage, runName, 3600, time.Now().Unix())
+	defer writer.Close()
+
+	for number := 1; number <= 2; number++ {
+		status := statusAt("", 50, 40)
+		status.CurrentStepNumber = number
+		writer.AddLine(status)
+		writer.AddLine(status)
+	}
+
+	rows := readLog(t, runningLogPathOf(t, storage, runName))
+	if len(rows) != 3 {
+		t.Fatalf("expected a header and 2 step rows, got %d rows: %v", len(rows), rows)
+	}
+	for i, number := range []string{"1", "2"} {
+		if got := rows[i+1][len(rows[i+1])-1]; got != number {
+			t.Fatalf("row %d: expected step_number %s, got %q", i+1, number, got)
+		}
+	}
+}
+
+// The compliance report credits the hold from the log alone, so every time the
+// material crosses the threshold has to be in it, resolution or not.
+func TestExecutionLogWritesOnEveryHoldCrossing(t *testing.T) {
+	storage := newTestStorage(t)
+
+	writer := NewExecutionLogWriter(storage, runName, 3600, time.Now().Unix())
+	defer writer.Close()
+
+	treating := func(material float32, holding bool) *types.ExecutionStatus {
+		status := statusAt("ISPM-15", 60, material)
+		status.HeatTreatment = &types.HeatTreatmentStatus{Threshold: 56, Holding: holding}
+		return status
+	}
+	writer.AddLine(treating(55, false))
+	writer.AddLine(treating(56, true))
+	writer.AddLine(treating(57, true))
+	writer.AddLine(treating(55.9, false))
+	writer.AddLine(treating(55.8, false))
+
+	rows := readLog(t, runningLogPathOf(t, storage, runName))
+	if len(rows) != 4 {
+		t.Fatalf("expected a header, the step's first row and 2 crossings, got %d rows: %v", len(rows), rows)
+	}
+	for i, material := range []string{"55.0", "56.0", "55.9"} {
+		if rows[i+1][3] != material {
+			t.Errorf("row %d: expected material %s, got %s", i+1, material, rows[i+1][3])
+		}
+	}
+}
+
 func TestExecutionLogWritesWithinTheSameStepOnceTheResolutionElapses(t *testing.T) {
 	storage := newTestStorage(t)
 
@@ -243,3 +303,123 @@ func TestExecutionLogRowMatchesHeaderWidth(t *testing.T) {
 		t.Fatalf("row has %d columns, header has %d", len(rows[1]), len(rows[0]))
 	}
 }
+
+// Each probe's own reading is what tells a failing thermocouple from the kiln,
+// and the material probes share a column however many the board has.
+func TestExecutionLogWritesProbeReadings(t *testing.T) {
+	storage := newTestStorage(t)
+
+	writer := NewExecutionLogWriter(storage, runName, 0, time.Now().Unix())
+	defer writer.Close()
+
+	status := statusAt(stepHeating, 131.75, 122.75)
+	status.Temperatures.Probes = map[string]float32{
+		types.ProbeKilnPrimary:   131.75,
+		types.ProbeKilnSecondary: 129.5,
+		"material":               122.75,
+		"material_2":             118.25,
+	}
+	writer.AddLine(status)
+
+	row := readLog(t, runningLogPathOf(t, storage, runName))[1]
+	for i, want := range map[int]string{
+		11: "131.8",
+		12: "129.5",
+		13: "122.8 118.2",
+	} {
+		if row[i] != want {
+			t.Fatalf("column %d: expected %q, got %q (row %v)", i, want, row[i], row)
+		}
+	}
+}
+
+// The climate columns are empty without a humidity reading, and carry it
+// when there is one.
+func TestExecutionLogWritesClimate(t *testing.T) {
+	storage := newTestStorage(t)
+
+	writer := NewExecutionLogWriter(storage, runName, 0, time.Now().Unix())
+	defer writer.Close()
+
+	columns := map[string]int{}
+	for i, name := range ExecutionLogColumns {
+		columns[name] = i
+	}
+
+	without := ExecutionLogRow(statusAt(stepHeating, 60, 50), 0, 0)
+	for _, name := range []string{"relative_humidity", "wet_bulb", "emc"} {
+		if got := without[columns[name]]; got != "" {
+			t.Errorf("%s without a climate reading = %q, want empty", name, got)
+		}
+	}
+
+	status := statusAt(stepHeating, 60, 50)
+	status.Temperatures.Climate = &types.ClimateResponse{Valid: true, DryBulb: 60, WetBulb: 50.04, RelativeHumidity: 58.26, EMC: 8.12}
+	writer.AddLine(status)
+
+	rows := readLog(t, runningLogPathOf(t, storage, runName))
+	if len(rows) != 2 {
+		t.Fatalf("expected a header and one row, got %v", rows)
+	}
+	for name, want := range map[string]string{
+		"relative_humidity": "58.3",
+		"wet_bulb":          "50.0",
+		"emc":               "8.1",
+	} {
+		if got := rows[1][columns[name]]; got != want {
+			t.Errorf("%s = %q, want %q (row %v)", name, got, want, rows[1])
+		}
+	}
+}
+
+// The vent is logged with the other channels' power, in its own column at the
+// end so older logs keep their layout.
+func TestExecutionLogWritesVentPower(t *testing.T) {
+	storage := newTestStorage(t)
+
+	writer := NewExecutionLogWriter(storage, runName, 0, time.Now().Unix())
+	defer writer.Close()
+
+	status := statusAt(stepHeating, 60, 50)
+	status.PowerStatus.Vent = 100
+	writer.AddLine(status)
+
+	rows := readLog(t, runningLogPathOf(t, storage, runName))
+	vent := slices.Index(ExecutionLogColumns, "vent")
+	if rows[0][vent] != "vent" || rows[1][vent] != "100" {
+		t.Fatalf("expected the vent column to read 100, got header %q, value %q", rows[0][vent], rows[1][vent])
+	}
+}
+
+// The moisture columns are empty without a moisture reading, and carry the
+// charge's and each pin's when there is one.
+func TestExecutionLogWritesMoisture(t *testing.T) {
+	storage := newTestStorage(t)
+
+	writer := NewExecutionLogWriter(storage, runName, 0, time.Now().Unix())
+	defer writer.Close()
+
+	moisture := slices.Index(ExecutionLogColumns, "moisture")
+	probes := slices.Index(ExecutionLogColumns, "moisture_probes")
+
+	without := ExecutionLogRow(statusAt(stepHeating, 60, 50), 0, 0)
+	if without[moisture] != "" || without[probes] != "" {
+		t.Errorf("moisture without a reading = %q, %q, want both empty", without[moisture], without[probes])
+	}
+
+	status := statusAt(stepHeating, 60, 50)
+	status.Temperatures.Moisture = &types.MoistureResponse{
+		Valid:    true,
+		Material: 18.04,
+		Probes:   map[string]float32{"mc_1": 14.96, "mc_3": 18.04},
+	}
+	writer.AddLine(status)
+
+	rows := readLog(t, runningLogPathOf(t, storage, runName))
+	if len(rows) != 2 {
+		t.Fatalf("expected a header and one row, got %v", rows)
+	}
+	if rows[1][moisture] != "18.0" || rows[1][probes] != "15.0 18.0" {
+		t.Errorf("moisture = %q, probes = %q, want 18.0 and 15.0 18.0", rows[1][moisture], rows[1][probes])
+	}
+}
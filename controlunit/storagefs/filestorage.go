@@ -15,7 +15,10 @@ type ExecutorFileStorage struct {
 	executedProgramsPath string
 	statusPath           string
 	logPath              string
+	eventsPath           string
 	runningPath          string
+
+	compliancePath string
 }
 
 func NewExecutorFileStorage(basePath string) (*ExecutorFileStorage, error) {
@@ -55,6 +58,22 @@ func NewExecutorFileStorage(basePath string) (*ExecutorFileStorage, error) {
 		return nil, err
 	}
 
+	executorStorage.eventsPath = filepath.Join(executorStorage.executedProgramsPath, "events")
+	log.Debug("Creating events directory: %s", executorStorage.eventsPath)
+	err = os.MkdirAll(executorStorage.eventsPath, os.ModePerm)
+	if err != nil {
+		log.Error("Failed to create events directory: %v", err)
+		return nil, err
+	}
+
+	executorStorage.compliancePath = filepath.Join(executorStorage.executedProgramsPath, "compliance")
+	log.Debug("Creating compliance directory: %s", executorStorage.compliancePath)
+	err = os.MkdirAll(executorStorage.compliancePath, os.ModePerm)
+	if err != nil {
+		log.Error("Failed to create compliance directory: %v", err)
+		return nil, err
+	}
+
 	executorStorage.runningPath = filepath.Join(baseStorage.BasePath, "running")
 	log.Debug("Creating running directory: %s", executorStorage.runningPath)
 	err = os.MkdirAll(executorStorage.runningPath, os.ModePerm)
@@ -63,8 +82,9 @@ func NewExecutorFileStorage(basePath string) (*ExecutorFileStorage, error) {
 		return nil, err
 	}
 
-	log.Info("Successfully created ExecutorFileStorage with paths - history: %s, status: %s, logs: %s, running: %s",
-		executorStorage.executedProgramsPath, executorStorage.statusPath, executorStorage.logPath, executorStorage.runningPath)
+	log.Info("Successfully created ExecutorFileStorage with paths - history: %s, status: %s, logs: %s, events: %s, running: %s",
+		executorStorage.executedProgramsPath, executorStorage.statusPath, executorStorage.logPath,
+		executorStorage.eventsPath, executorStorage.runningPath)
 	return executorStorage, nil
 }
 
@@ -75,16 +95,8 @@ func (storage *ExecutorFileStorage) UpdateState(name string, status types.Progra
 	}
 	filePath := filepath.Join(storage.statusPath, name+".txt")
 
-	statusFile, err := os.Create(filePath)
-	if err != nil {
-		log.Error("Failed to create status file for program '%s': %v", name, err)
-		return err
-	}
-	defer statusFile.Close()
-
-	_, err = statusFile.WriteString(string(status))
-	if err != nil {
-		log.Error("Failed to write status to file for program '%s': %v", name, err)
+	if err := types.WriteFileAtomic(filePath, []byte(status), 0o644); err != nil {
+		log.Error("Failed to write status file for program '%s': %v", name, err)
 		return err
 	}
 	log.Info("Successfully updated state for program '%s' to '%s'", name, status)
@@ -198,6 +210,24 @@ func (storage *ExecutorFileStorage) DeleteExecutedProgram(programName string) er
 		log.Debug("Successfully deleted execution log for '%s'", programName)
 	}
 
+	// Delete the run events
+	eventsFilePath := filepath.Join(storage.eventsPath, programName+".jsonl")
+	if err := os.Remove(eventsFilePath); err != nil && !os.IsNotExist(err) {
+		log.Error("Failed to delete run events for '%s': %v", programName, err)
+		errors = append(errors, "failed to delete run events: "+err.Error())
+	} else {
+		log.Debug("Successfully deleted run events for '%s'", programName)
+	}
+
+	// Delete the compliance report
+	complianceFilePath := filepath.Join(storage.compliancePath, programName+".json")
+	if err := os.Remove(complianceFilePath); err != nil && !os.IsNotExist(err) {
+		log.Error("Failed to delete compliance report for '%s': %v", programName, err)
+		errors = append(errors, "failed to delete compliance report: "+err.Error())
+	} else {
+		log.Debug("Successfully deleted compliance report for '%s'", programName)
+	}
+
 	// Delete the state file
 	statusFilePath := filepath.Join(storage.statusPath, programName+".txt")
 	if err := os.Remove(statusFilePath); err != nil && !os.IsNotExist(err) {
@@ -254,6 +284,16 @@ func (storage *ExecutorFileStorage) MoveToHistory(programName string) error {
 		log.Debug("Moved execution log for '%s' to history", programName)
 	}
 
+	// Move run events
+	runningEvents := filepath.Join(storage.runningPath, programName+".jsonl")
+	historyEvents := filepath.Join(storage.eventsPath, programName+".jsonl")
+	if err := os.Rename(runningEvents, historyEvents); err != nil && !os.IsNotExist(err) {
+		log.Error("Failed to move run events for '%s': %v", programName, err)
+		errors = append(errors, "failed to move run events: "+err.Error())
+	} else if err == nil {
+		log.Debug("Moved run events for '%s' to history", programName)
+	}
+
 	if len(errors) > 0 {
 		log.Warning("Some file moves failed for program '%s': %s", programName, strings.Join(errors, "; "))
 		return fmt.Errorf("move errors: %s", strings.Join(errors, "; "))
@@ -285,7 +325,7 @@ func (storage *ExecutorFileStorage) CleanupOrphanedRunning() error {
 	for _, programName := range runningPrograms {
 		// Update status to canceled before moving
 		statusPath := filepath.Join(storage.runningPath, programName+".txt")
-		if err := os.WriteFile(statusPath, []byte(types.ProgramStateCanceled), 0644); err != nil {
+		if err := types.WriteFileAtomic(statusPath, []byte(types.ProgramStateCanceled), 0o644); err != nil {
 			log.Warning("Failed to update status for orphaned program '%s': %v", programName, err)
 		}
 
@@ -75,6 +75,7 @@ func TestNewExecutorFileStorageCreatesItsDirectories(t *testing.T) {
 		storage.statusPath,
 		storage.logPath,
 		storage.runningPath,
+		storage.compliancePath,
 	} {
 		info, err := os.Stat(dir)
 		if err != nil {
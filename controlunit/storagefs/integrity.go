@@ -0,0 +1,175 @@
+package storagefs
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// archiveDir is where a repair moves the files of runs whose program is gone,
+// under the path they had. Nothing a repair does deletes a file.
+const archiveDir = "archive"
+
+// runFile is one of the files a run is made of: where it sits in history/
+// and the extension it has there and in running/. The compliance report is
+// written straight to history/, so it is never in running/.
+type runFile struct {
+	historyPath string
+	extension   string
+	inRunning   bool
+}
+
+// runFiles lists the files of a run: its program, its state, and the rest,
+// which mean nothing without the program.
+func (storage *ExecutorFileStorage) runFiles() (program, state runFile, attached []runFile) {
+	program = runFile{storage.executedProgramsPath, ".json", true}
+	state = runFile{storage.statusPath, ".txt", true}
+	attached = []runFile{
+		{storage.logPath, ".csv", true},
+		{storage.eventsPath, ".jsonl", true},
+		{storage.compliancePath, ".json", false},
+	}
+	return program, state, attached
+}
+
+// CheckStorage looks for runs whose files are not all where they belong:
+// files left in running/ by a move to history that was cut short, programs
+// without a state, and files of runs whose program has been deleted by hand.
+// activeRun is the run in progress, whose files are meant to be in running/.
+func (storage *ExecutorFileStorage) CheckStorage(activeRun string) ([]types.StorageIssue, error) {
+	program, state, attached := storage.runFiles()
+	all := append([]runFile{program, state}, attached...)
+
+	var names []string
+	for _, file := range all {
+		for _, dir := range []string{file.historyPath, storage.runningPath} {
+			if dir == storage.runningPath && !file.inRunning {
+				continue
+			}
+			matches, err := filepath.Glob(filepath.Join(dir, "*"+file.extension))
+			if err != nil {
+				return nil, err
+			}
+			for _, match := range matches {
+				names = append(names, strings.TrimSuffix(filepath.Base(match), file.extension))
+			}
+		}
+	}
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	issues := []types.StorageIssue{}
+	for _, name := range names {
+		if name == activeRun || types.ValidateStorageName(name) != nil {
+			continue
+		}
+		programExists := exists(storage.historyFile(program, name)) || exists(storage.runningFile(program, name))
+
+		for _, file := range all {
+			if !file.inRunning || !exists(storage.runningFile(file, name)) {
+				continue
+			}
+			issue := types.StorageIssue{Run: name, Path: storage.relative(storage.runningFile(file, name))}
+			switch {
+			case !programExists:
+				issue.Kind, issue.Repair = types.StorageIssueOrphaned, types.StorageRepairArchive
+			case exists(storage.historyFile(file, name)):
+				// History already has the file; the copy left behind
+				// is the one that cannot be put in its place.
+				issue.Kind, issue.Repair = types.StorageIssueStranded, types.StorageRepairArchive
+			default:
+				issue.Kind, issue.Repair = types.StorageIssueStranded, types.StorageRepairRelink
+			}
+			issues = append(issues, issue)
+		}
+
+		if programExists {
+			if !exists(storage.historyFile(state, name)) && !exists(storage.runningFile(state, name)) {
+				issues = append(issues, types.StorageIssue{
+					Run:    name,
+					Kind:   types.StorageIssueNoState,
+					Path:   storage.relative(storage.historyFile(state, name)),
+					Repair: types.StorageRepairMarkUnknown,
+				})
+			}
+			continue
+		}
+		for _, file := range append([]runFile{state}, attached...) {
+			if exists(storage.historyFile(file, name)) {
+				issues = append(issues, types.StorageIssue{
+					Run:    name,
+					Kind:   types.StorageIssueOrphaned,
+					Path:   storage.relative(storage.historyFile(file, name)),
+					Repair: types.StorageRepairArchive,
+				})
+			}
+		}
+	}
+	return issues, nil
+}
+
+// RepairStorage repairs every issue CheckStorage finds and returns them, each
+// marked with whether its repair worked. It is not to be run while a program
+// is running: a run starting part way through would have its files taken for
+// stranded ones.
+func (storage *ExecutorFileStorage) RepairStorage() ([]types.StorageIssue, error) {
+	issues, err := storage.CheckStorage("")
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Repairing %d storage issue(s)", len(issues))
+
+	program, state, attached := storage.runFiles()
+	inRunning := map[string]runFile{}
+	for _, file := range append([]runFile{program, state}, attached...) {
+		if file.inRunning {
+			inRunning[file.extension] = file
+		}
+	}
+	for i := range issues {
+		issue := &issues[i]
+		path := filepath.Join(storage.BasePath, issue.Path)
+
+		switch issue.Repair {
+		case types.StorageRepairRelink:
+			err = os.Rename(path, storage.historyFile(inRunning[filepath.Ext(path)], issue.Run))
+		case types.StorageRepairMarkUnknown:
+			err = types.WriteFileAtomic(path, []byte(types.ProgramStateUnknown), 0o644)
+		case types.StorageRepairArchive:
+			_, _, err = moveAside(storage.BasePath, archiveDir, path)
+		}
+
+		if err != nil {
+			log.Error("Failed to %s %s: %v", issue.Repair, issue.Path, err)
+			issue.RepairError = err.Error()
+			continue
+		}
+		log.Info("Repaired %s %s: %s", issue.Kind, issue.Path, issue.Repair)
+		issue.Repaired = true
+	}
+	return issues, nil
+}
+
+func (storage *ExecutorFileStorage) historyFile(file runFile, name string) string {
+	return filepath.Join(file.historyPath, name+file.extension)
+}
+
+func (storage *ExecutorFileStorage) runningFile(file runFile, name string) string {
+	return filepath.Join(storage.runningPath, name+file.extension)
+}
+
+func (storage *ExecutorFileStorage) relative(path string) string {
+	if relative, err := filepath.Rel(storage.BasePath, path); err == nil {
+		return relative
+	}
+	return path
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,115 @@
+package storagefs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+// brokenStorage sets up one run of each kind the check looks for, beside a
+// finished run that is whole and a run still in progress.
+func brokenStorage(t *testing.T) *ExecutorFileStorage {
+	t.Helper()
+
+	storage := newTestStorage(t)
+	whole := func(name string) {
+		startRun(t, storage, name)
+		writeFile(t, filepath.Join(storage.runningPath, name+".txt"), string(types.ProgramStateCompleted))
+		writeFile(t, filepath.Join(storage.runningPath, name+".csv"), "time\n")
+	}
+
+	whole("finished")
+	if err := storage.MoveToHistory("finished"); err != nil {
+		t.Fatal(err)
+	}
+	whole("active")
+
+	// A move to history cut short after the program went.
+	whole("cut-short")
+	if err := os.Rename(filepath.Join(storage.runningPath, "cut-short.json"), filepath.Join(storage.executedProgramsPath, "cut-short.json")); err != nil {
+		t.Fatal(err)
+	}
+	// A state file deleted by hand.
+	whole("stateless")
+	if err := storage.MoveToHistory("stateless"); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(storage.statusPath, "stateless.txt"))
+	// A program deleted by hand.
+	whole("deleted")
+	if err := storage.MoveToHistory("deleted"); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(storage.executedProgramsPath, "deleted.json"))
+
+	return storage
+}
+
+func TestCheckStorage(t *testing.T) {
+	storage := brokenStorage(t)
+
+	issues, err := storage.CheckStorage("active")
+	if err != nil {
+		t.Fatalf("CheckStorage: %v", err)
+	}
+
+	want := []types.StorageIssue{
+		{Run: "cut-short", Kind: types.StorageIssueStranded, Path: "running/cut-short.txt", Repair: types.StorageRepairRelink},
+		{Run: "cut-short", Kind: types.StorageIssueStranded, Path: "running/cut-short.csv", Repair: types.StorageRepairRelink},
+		{Run: "deleted", Kind: types.StorageIssueOrphaned, Path: "history/status/deleted.txt", Repair: types.StorageRepairArchive},
+		{Run: "deleted", Kind: types.StorageIssueOrphaned, Path: "history/logs/deleted.csv", Repair: types.StorageRepairArchive},
+		{Run: "stateless", Kind: types.StorageIssueNoState, Path: "history/status/stateless.txt", Repair: types.StorageRepairMarkUnknown},
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("issues = %+v, want %+v", issues, want)
+	}
+	for i := range want {
+		if issues[i] != want[i] {
+			t.Errorf("issue %d = %+v, want %+v", i, issues[i], want[i])
+		}
+	}
+
+	// Without a run in progress, its files are stranded too.
+	issues, _ = storage.CheckStorage("")
+	if len(issues) != len(want)+3 {
+		t.Errorf("issues with no run in progress = %+v, want the active run's three files as well", issues)
+	}
+}
+
+func TestRepairStorage(t *testing.T) {
+	storage := brokenStorage(t)
+	// The run in progress has finished, which the repair insists on.
+	if err := storage.MoveToHistory("active"); err != nil {
+		t.Fatal(err)
+	}
+	// A log left behind by a run that is in history with one already.
+	writeFile(t, filepath.Join(storage.runningPath, "finished.csv"), "time\n")
+
+	issues, err := storage.RepairStorage()
+	if err != nil {
+		t.Fatalf("RepairStorage: %v", err)
+	}
+	for _, issue := range issues {
+		if !issue.Repaired || issue.RepairError != "" {
+			t.Errorf("%s not repaired: %s", issue.Path, issue.RepairError)
+		}
+	}
+
+	mustContain(t, filepath.Join(storage.statusPath, "cut-short.txt"), string(types.ProgramStateCompleted))
+	mustContain(t, filepath.Join(storage.statusPath, "stateless.txt"), string(types.ProgramStateUnknown))
+	mustNotExist(t, filepath.Join(storage.runningPath, "finished.csv"))
+	mustNotExist(t, filepath.Join(storage.logPath, "deleted.csv"))
+	archived, _ := filepath.Glob(filepath.Join(storage.BasePath, archiveDir, "history", "logs", "deleted.csv.*"))
+	if len(archived) != 1 {
+		t.Errorf("archived logs = %v, want the deleted run's", archived)
+	}
+	if archived, _ := filepath.Glob(filepath.Join(storage.BasePath, archiveDir, "running", "finished.csv.*")); len(archived) != 1 {
+		t.Errorf("archived running files = %v, want the finished run's second log", archived)
+	}
+
+	if again, _ := storage.CheckStorage(""); len(again) != 0 {
+		t.Errorf("issues after repair = %+v, want none", again)
+	}
+}
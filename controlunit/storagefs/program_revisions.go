@@ -0,0 +1,198 @@
+package storagefs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// A stored program's earlier versions are kept in programs/revisions/<name>/,
+// one file per revision number, beside an index of every revision including
+// the current one, which stays in programs/<name>.json where it always was.
+
+const revisionIndexFile = "index.json"
+
+func (storage *ProgramStorage) revisionPath(programName string) string {
+	return filepath.Join(storage.programPath, "revisions", programName)
+}
+
+// loadRevisionIndex reads the program's revisions, oldest first. A program
+// stored before revisions were kept has no index; its file is its only
+// revision.
+func (storage *ProgramStorage) loadRevisionIndex(programName string) ([]types.ProgramRevision, error) {
+	filePath := filepath.Join(storage.programPath, programName+".json")
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return nil, types.ErrProgramDoesNotExist
+	}
+
+	content, err := os.ReadFile(filepath.Join(storage.revisionPath(programName), revisionIndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		program, err := storage.LoadProgram(filePath)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := program.ContentHash()
+		if err != nil {
+			return nil, err
+		}
+		return []types.ProgramRevision{{Revision: 1, SavedAt: fileInfo.ModTime().Format(time.RFC3339), Hash: hash}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var index []types.ProgramRevision
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, fmt.Errorf("revision index of '%s': %w", programName, err)
+	}
+	if len(index) == 0 {
+		return nil, fmt.Errorf("revision index of '%s' is empty", programName)
+	}
+	return index, nil
+}
+
+func (storage *ProgramStorage) saveRevisionIndex(programName string, index []types.ProgramRevision) error {
+	revisionPath := storage.revisionPath(programName)
+	if err := os.MkdirAll(revisionPath, os.ModePerm); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return types.WriteFileAtomic(filepath.Join(revisionPath, revisionIndexFile), content, 0o644)
+}
+
+// newRevision is the index entry for a program just saved.
+func newRevision(number int, program *types.Program, note string) (types.ProgramRevision, error) {
+	hash, err := program.ContentHash()
+	if err != nil {
+		return types.ProgramRevision{}, err
+	}
+	return types.ProgramRevision{Revision: number, SavedAt: time.Now().Format(time.RFC3339), Note: note, Hash: hash}, nil
+}
+
+func (storage *ProgramStorage) ListProgramRevisions(programName string) ([]types.ProgramRevision, error) {
+	if err := types.ValidateStorageName(programName); err != nil {
+		return nil, err
+	}
+	index, err := storage.loadRevisionIndex(programName)
+	if err != nil {
+		return nil, err
+	}
+	index[len(index)-1].Current = true
+	return index, nil
+}
+
+func (storage *ProgramStorage) LoadProgramRevision(programName string, revision int) (*types.Program, error) {
+	log.Debug("Loading revision %d of stored program: %s", revision, programName)
+	if err := types.ValidateStorageName(programName); err != nil {
+		return nil, err
+	}
+	index, err := storage.loadRevisionIndex(programName)
+	if err != nil {
+		return nil, err
+	}
+	if revision < 1 || revision > index[len(index)-1].Revision {
+		return nil, types.ErrRevisionDoesNotExist
+	}
+	if revision == index[len(index)-1].Revision {
+		return storage.LoadStoredProgram(programName)
+	}
+	return storage.LoadProgram(filepath.Join(storage.revisionPath(programName), strconv.Itoa(revision)+".json"))
+}
+
+func (storage *ProgramStorage) RestoreProgramRevision(programName string, revision int, note, ifMatch string) (*types.Program, error) {
+	log.Info("Restoring revision %d of stored program: %s", revision, programName)
+	program, err := storage.LoadProgramRevision(programName, revision)
+	if err != nil {
+		return nil, err
+	}
+	if note == "" {
+		note = fmt.Sprintf("Restored revision %d", revision)
+	}
+	// Restoring is an update like any other, so the version it replaces is
+	// kept and the restore can itself be undone.
+	program.ProgramName = programName
+	if err := storage.UpdateStoredProgram(programName, program, note, ifMatch); err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+// archiveCurrentRevision copies the program's file into its revisions before
+// it is overwritten. A revision already archived is left as it is: an update
+// cut short after archiving it kept the same content, and one cut short after
+// writing the program has moved the file past it.
+func (storage *ProgramStorage) archiveCurrentRevision(programName string, current types.ProgramRevision) error {
+	revisionPath := storage.revisionPath(programName)
+	archived := filepath.Join(revisionPath, strconv.Itoa(current.Revision)+".json")
+	if _, err := os.Stat(archived); err == nil {
+		return nil
+	}
+	content, err := os.ReadFile(filepath.Join(storage.programPath, programName+".json"))
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(revisionPath, os.ModePerm); err != nil {
+		return err
+	}
+	return types.WriteFileAtomic(archived, content, 0o644)
+}
+
+// ReconcileRevisions records, as a new revision, each stored program whose
+// file does not hash as the newest revision in its index. That is an update
+// the power went out on between writing the program and writing the index, or
+// a file changed by hand; either way the next update archives it rather than
+// losing it. A program whose index or file cannot be read is logged and left
+// as it is, so one damaged program does not keep the rest from being
+// reconciled. It returns the programs it recorded a revision for.
+func (storage *ProgramStorage) ReconcileRevisions() ([]string, error) {
+	storage.writeMu.Lock()
+	defer storage.writeMu.Unlock()
+
+	programs, err := storage.ListStoredPrograms()
+	if err != nil {
+		return nil, err
+	}
+	var reconciled []string
+	for _, programName := range programs {
+		if _, err := os.Stat(filepath.Join(storage.revisionPath(programName), revisionIndexFile)); err != nil {
+			// Without an index the file is its only revision.
+			continue
+		}
+		index, err := storage.loadRevisionIndex(programName)
+		if err != nil {
+			log.Error("Failed to reconcile stored program '%s': %v", programName, err)
+			continue
+		}
+		program, err := storage.LoadStoredProgram(programName)
+		if err != nil {
+			log.Error("Failed to reconcile stored program '%s': %v", programName, err)
+			continue
+		}
+		head := index[len(index)-1]
+		next, err := newRevision(head.Revision+1, program, "Recorded at startup: not in the revision index")
+		if err != nil {
+			log.Error("Failed to reconcile stored program '%s': %v", programName, err)
+			continue
+		}
+		if next.Hash == head.Hash {
+			continue
+		}
+		if err := storage.saveRevisionIndex(programName, append(index, next)); err != nil {
+			return reconciled, err
+		}
+		log.Warning("Stored program '%s' did not match revision %d; recorded it as revision %d", programName, head.Revision, next.Revision)
+		reconciled = append(reconciled, programName)
+	}
+	return reconciled, nil
+}
@@ -0,0 +1,286 @@
+package storagefs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func newTestProgramStorage(t *testing.T) *ProgramStorage {
+	t.Helper()
+
+	storage, err := NewProgramStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+	return storage
+}
+
+// storeWithTargets creates the program and updates it once for each further
+// target, so that revision n heats to targets[n-1].
+func storeWithTargets(t *testing.T, storage *ProgramStorage, targets ...uint16) {
+	t.Helper()
+
+	program := testProgram("Oak")
+	program.ProgramSteps[0].TargetTemperature = targets[0]
+	if err := storage.CreateStoredProgram("Oak", program); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	for _, target := range targets[1:] {
+		program.ProgramSteps[0].TargetTemperature = target
+		if err := storage.UpdateStoredProgram("Oak", program, "heat hotter", "*"); err != nil {
+			t.Fatalf("update: %v", err)
+		}
+	}
+}
+
+func TestUpdateKeepsEveryRevision(t *testing.T) {
+	storage := newTestProgramStorage(t)
+	storeWithTargets(t, storage, 60, 65, 70)
+
+	revisions, err := storage.ListProgramRevisions("Oak")
+	if err != nil {
+		t.Fatalf("ListProgramRevisions: %v", err)
+	}
+	if len(revisions) != 3 || !revisions[2].Current || revisions[1].Current {
+		t.Fatalf("revisions = %+v, want three with the last current", revisions)
+	}
+	if revisions[0].Note != "" || revisions[1].Note != "heat hotter" || revisions[1].SavedAt == "" {
+		t.Errorf("revisions = %+v, want the update's note and time on revision 2", revisions)
+	}
+
+	for n, want := range map[int]uint16{1: 60, 2: 65, 3: 70} {
+		program, err := storage.LoadProgramRevision("Oak", n)
+		if err != nil {
+			t.Fatalf("LoadProgramRevision(%d): %v", n, err)
+		}
+		if got := program.ProgramSteps[0].TargetTemperature; got != want {
+			t.Errorf("revision %d heats to %d, want %d", n, got, want)
+		}
+		// The listed hash is what the revision's ETag is when it is current.
+		if hash, _ := program.ContentHash(); hash != revisions[n-1].Hash {
+			t.Errorf("revision %d loads as %s, listed as %s", n, hash, revisions[n-1].Hash)
+		}
+	}
+
+	for _, n := range []int{0, 4} {
+		if _, err := storage.LoadProgramRevision("Oak", n); !errors.Is(err, types.ErrRevisionDoesNotExist) {
+			t.Errorf("LoadProgramRevision(%d) = %v, want ErrRevisionDoesNotExist", n, err)
+		}
+	}
+}
+
+// interruptUpdate does what an update to target does up to the power going
+// out: the revision it replaces archived and the program written, but not
+// the index.
+func interruptUpdate(t *testing.T, storage *ProgramStorage, target uint16) {
+	t.Helper()
+
+	index, err := storage.loadRevisionIndex("Oak")
+	if err != nil {
+		t.Fatalf("loadRevisionIndex: %v", err)
+	}
+	if err := storage.archiveCurrentRevision("Oak", index[len(index)-1]); err != nil {
+		t.Fatalf("archiveCurrentRevision: %v", err)
+	}
+	program := testProgram("Oak")
+	program.ProgramSteps[0].TargetTemperature = target
+	if err := storage.SaveStoredProgram("Oak", program); err != nil {
+		t.Fatalf("SaveStoredProgram: %v", err)
+	}
+}
+
+func assertRevisionTargets(t *testing.T, storage *ProgramStorage, want map[int]uint16) {
+	t.Helper()
+
+	for n, target := range want {
+		program, err := storage.LoadProgramRevision("Oak", n)
+		if err != nil {
+			t.Fatalf("LoadProgramRevision(%d): %v", n, err)
+		}
+		if got := program.ProgramSteps[0].TargetTemperature; got != target {
+			t.Errorf("revision %d heats to %d, want %d", n, got, target)
+		}
+	}
+}
+
+// An update cut short before its index is written leaves the program ahead of
+// the index. The startup reconcile records it, and the next update keeps it.
+func TestReconcileRecordsAnInterruptedUpdate(t *testing.T) {
+	storage := newTestProgramStorage(t)
+	storeWithTargets(t, storage, 60, 65)
+	interruptUpdate(t, storage, 70)
+
+	reconciled, err := storage.ReconcileRevisions()
+	if err != nil || len(reconciled) != 1 || reconciled[0] != "Oak" {
+		t.Fatalf("ReconcileRevisions = %v, %v; want Oak", reconciled, err)
+	}
+	if again, _ := storage.ReconcileRevisions(); len(again) != 0 {
+		t.Errorf("second ReconcileRevisions = %v, want nothing left", again)
+	}
+
+	program := testProgram("Oak")
+	program.ProgramSteps[0].TargetTemperature = 75
+	if err := storage.UpdateStoredProgram("Oak", program, "", "*"); err != nil {
+		t.Fatalf("UpdateStoredProgram: %v", err)
+	}
+	assertRevisionTargets(t, storage, map[int]uint16{1: 60, 2: 65, 3: 70, 4: 75})
+}
+
+// A program with a damaged index is passed over, and the programs after it are
+// still reconciled.
+func TestReconcileCarriesOnPastADamagedIndex(t *testing.T) {
+	storage := newTestProgramStorage(t)
+	program := testProgram("Ash")
+	if err := storage.CreateStoredProgram("Ash", program); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := storage.UpdateStoredProgram("Ash", program, "", "*"); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(storage.revisionPath("Ash"), revisionIndexFile), []byte("{"), 0o644); err != nil {
+		t.Fatalf("damaging the index: %v", err)
+	}
+	storeWithTargets(t, storage, 60, 65)
+	interruptUpdate(t, storage, 70)
+
+	reconciled, err := storage.ReconcileRevisions()
+	if err != nil || len(reconciled) != 1 || reconciled[0] != "Oak" {
+		t.Fatalf("ReconcileRevisions = %v, %v; want Oak", reconciled, err)
+	}
+}
+
+// Even without the reconcile, the next update must not write the unrecorded
+// program over the revision the index still has as newest.
+func TestUpdateAfterAnInterruptedUpdateKeepsTheRevision(t *testing.T) {
+	storage := newTestProgramStorage(t)
+	storeWithTargets(t, storage, 60, 65)
+	interruptUpdate(t, storage, 70)
+
+	program := testProgram("Oak")
+	program.ProgramSteps[0].TargetTemperature = 75
+	if err := storage.UpdateStoredProgram("Oak", program, "", "*"); err != nil {
+		t.Fatalf("UpdateStoredProgram: %v", err)
+	}
+	assertRevisionTargets(t, storage, map[int]uint16{1: 60, 2: 65, 3: 75})
+}
+
+// Restoring is an update, so the revision it replaces is kept too.
+func TestRestoreRevision(t *testing.T) {
+	storage := newTestProgramStorage(t)
+	storeWithTargets(t, storage, 60, 65)
+
+	program, err := storage.RestoreProgramRevision("Oak", 1, "", "*")
+	if err != nil {
+		t.Fatalf("RestoreProgramRevision: %v", err)
+	}
+	if program.ProgramSteps[0].TargetTemperature != 60 {
+		t.Errorf("restored program heats to %d, want 60", program.ProgramSteps[0].TargetTemperature)
+	}
+
+	revisions, _ := storage.ListProgramRevisions("Oak")
+	if len(revisions) != 3 || revisions[2].Note != "Restored revision 1" {
+		t.Fatalf("revisions = %+v, want the restore as revision 3", revisions)
+	}
+	if revisions[2].Hash != revisions[0].Hash {
+		t.Error("restored revision does not hash as the one it restored")
+	}
+	if stored, _ := storage.LoadStoredProgram("Oak"); stored.ProgramSteps[0].TargetTemperature != 60 {
+		t.Error("stored program not restored")
+	}
+}
+
+// A program stored before revisions were kept has no index; its file is its
+// first revision, and is kept as such when it is first updated.
+func TestProgramWithoutRevisions(t *testing.T) {
+	storage := newTestProgramStorage(t)
+	if err := storage.SaveStoredProgram("Pine", testProgram("Pine")); err != nil {
+		t.Fatalf("SaveStoredProgram: %v", err)
+	}
+
+	revisions, err := storage.ListProgramRevisions("Pine")
+	if err != nil || len(revisions) != 1 || revisions[0].Revision != 1 {
+		t.Fatalf("ListProgramRevisions = %+v, %v; want the file as revision 1", revisions, err)
+	}
+
+	updated := testProgram("Pine")
+	updated.ProgramSteps[0].TargetTemperature = 75
+	if err := storage.UpdateStoredProgram("Pine", updated, "", "*"); err != nil {
+		t.Fatalf("UpdateStoredProgram: %v", err)
+	}
+	first, err := storage.LoadProgramRevision("Pine", 1)
+	if err != nil || first.ProgramSteps[0].TargetTemperature != 60 {
+		t.Fatalf("LoadProgramRevision(1) = %+v, %v; want the original", first, err)
+	}
+}
+
+// A program created again under a deleted one's name does not inherit its
+// history.
+func TestDeleteRemovesRevisions(t *testing.T) {
+	storage := newTestProgramStorage(t)
+	storeWithTargets(t, storage, 60, 65)
+
+	if err := storage.DeleteStoredProgram("Oak", "*"); err != nil {
+		t.Fatalf("DeleteStoredProgram: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(storage.programPath, "revisions", "Oak")); !os.IsNotExist(err) {
+		t.Errorf("revisions left behind: %v", err)
+	}
+	if _, err := storage.ListProgramRevisions("Oak"); !errors.Is(err, types.ErrProgramDoesNotExist) {
+		t.Errorf("ListProgramRevisions = %v, want ErrProgramDoesNotExist", err)
+	}
+
+	storeWithTargets(t, storage, 50)
+	if revisions, _ := storage.ListProgramRevisions("Oak"); len(revisions) != 1 {
+		t.Errorf("revisions = %+v, want a fresh history", revisions)
+	}
+}
+
+func TestUpdateIfMatch(t *testing.T) {
+	storage := newTestProgramStorage(t)
+	storeWithTargets(t, storage, 60)
+	revisions, _ := storage.ListProgramRevisions("Oak")
+	read := types.ProgramETag(revisions[0].Hash)
+
+	program := testProgram("Oak")
+	program.ProgramSteps[0].TargetTemperature = 65
+	if err := storage.UpdateStoredProgram("Oak", program, "", read); err != nil {
+		t.Fatalf("update from the current version: %v", err)
+	}
+	program.ProgramSteps[0].TargetTemperature = 70
+	if err := storage.UpdateStoredProgram("Oak", program, "", read); !errors.Is(err, types.ErrProgramChanged) {
+		t.Errorf("update from an earlier version = %v, want ErrProgramChanged", err)
+	}
+	if err := storage.DeleteStoredProgram("Oak", read); !errors.Is(err, types.ErrProgramChanged) {
+		t.Errorf("delete of an earlier version = %v, want ErrProgramChanged", err)
+	}
+	if _, err := storage.RestoreProgramRevision("Oak", 1, "", read); !errors.Is(err, types.ErrProgramChanged) {
+		t.Errorf("restore over an earlier version = %v, want ErrProgramChanged", err)
+	}
+	if err := storage.UpdateStoredProgram("Oak", program, "", ""); !errors.Is(err, types.ErrIfMatchRequired) {
+		t.Errorf("update without If-Match = %v, want ErrIfMatchRequired", err)
+	}
+	if err := storage.DeleteStoredProgram("Oak", ""); !errors.Is(err, types.ErrIfMatchRequired) {
+		t.Errorf("delete without If-Match = %v, want ErrIfMatchRequired", err)
+	}
+	if _, err := storage.RestoreProgramRevision("Oak", 1, "", ""); !errors.Is(err, types.ErrIfMatchRequired) {
+		t.Errorf("restore without If-Match = %v, want ErrIfMatchRequired", err)
+	}
+	if err := storage.UpdateStoredProgram("Pine", program, "", read); !errors.Is(err, types.ErrProgramDoesNotExist) {
+		t.Errorf("update of a missing program = %v, want ErrProgramDoesNotExist", err)
+	}
+}
+
+func TestRevisionsRefuseEscapingNames(t *testing.T) {
+	storage := newTestProgramStorage(t)
+
+	if _, err := storage.ListProgramRevisions(escapingName); !errors.Is(err, types.ErrInvalidStorageName) {
+		t.Errorf("ListProgramRevisions: expected ErrInvalidStorageName, got %v", err)
+	}
+	if _, err := storage.RestoreProgramRevision(escapingName, 1, "", ""); !errors.Is(err, types.ErrInvalidStorageName) {
+		t.Errorf("RestoreProgramRevision: expected ErrInvalidStorageName, got %v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/rmkhl/halko/types"
@@ -13,6 +15,10 @@ import (
 type ProgramStorage struct {
 	*types.FileStorage
 	programPath string
+
+	// writeMu is held from checking a write's If-Match to the end of the
+	// write, so two clients holding the same version cannot both replace it.
+	writeMu sync.Mutex
 }
 
 func NewProgramStorage(basePath string) (*ProgramStorage, error) {
@@ -77,6 +83,9 @@ func (storage *ProgramStorage) LoadStoredProgram(programName string) (*types.Pro
 	}
 	filePath := filepath.Join(storage.programPath, programName+".json")
 	program, err := storage.LoadProgram(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, types.ErrProgramDoesNotExist
+	}
 	if err != nil {
 		log.Error("Failed to load stored program '%s': %v", programName, err)
 		return nil, err
@@ -98,6 +107,8 @@ func (storage *ProgramStorage) CreateStoredProgram(programName string, program *
 	if err := types.ValidateStorageName(programName); err != nil {
 		return err
 	}
+	storage.writeMu.Lock()
+	defer storage.writeMu.Unlock()
 	filePath := filepath.Join(storage.programPath, programName+".json")
 
 	_, err := os.Stat(filePath)
@@ -110,26 +121,61 @@ func (storage *ProgramStorage) CreateStoredProgram(programName string, program *
 		return types.ErrProgramExists
 	}
 
+	// A program of the name may have been deleted before; its revisions
+	// went with it, so this one starts over from 1. Any the delete left
+	// behind are cleared first, as archiving never replaces a revision.
+	if err := os.RemoveAll(storage.revisionPath(programName)); err != nil {
+		log.Error("Failed to clear old revisions of stored program '%s': %v", programName, err)
+		return err
+	}
 	err = storage.SaveProgram(filePath, program)
 	if err != nil {
 		log.Error("Failed to create stored program '%s': %v", programName, err)
 		return err
 	}
+	first, err := newRevision(1, program, "")
+	if err == nil {
+		err = storage.saveRevisionIndex(programName, []types.ProgramRevision{first})
+	}
+	if err != nil {
+		log.Error("Failed to start the revisions of stored program '%s': %v", programName, err)
+		return err
+	}
 	log.Info("Successfully created stored program: %s", programName)
 	return nil
 }
 
-func (storage *ProgramStorage) UpdateStoredProgram(programName string, program *types.Program) error {
+// UpdateStoredProgram replaces the stored program, keeping the version it
+// replaces as a revision. note says what the update changed.
+//
+// The revision is archived first, then the program written, then the index.
+// A power cut before the index is written leaves a program newer than the
+// index's newest revision, which ReconcileRevisions records at startup.
+func (storage *ProgramStorage) UpdateStoredProgram(programName string, program *types.Program, note, ifMatch string) error {
 	log.Info("Updating stored program: %s", programName)
 	if err := types.ValidateStorageName(programName); err != nil {
 		return err
 	}
+	storage.writeMu.Lock()
+	defer storage.writeMu.Unlock()
+	if err := storage.checkIfMatch(programName, ifMatch); err != nil {
+		return err
+	}
 	filePath := filepath.Join(storage.programPath, programName+".json")
 
-	_, err := os.Stat(filePath)
-	if err != nil {
+	index, err := storage.loadRevisionIndex(programName)
+	if errors.Is(err, types.ErrProgramDoesNotExist) {
 		log.Warning("Program '%s' does not exist for update", programName)
-		return types.ErrProgramDoesNotExist
+		return err
+	}
+	if err != nil {
+		log.Error("Failed to read the revisions of stored program '%s': %v", programName, err)
+		return err
+	}
+	current := index[len(index)-1]
+	if err := storage.archiveCurrentRevision(programName, current); err != nil {
+		log.Error("Failed to keep revision %d of stored program '%s': %v", current.Revision, programName, err)
+		return err
 	}
 
 	err = storage.SaveProgram(filePath, program)
@@ -137,21 +183,61 @@ func (storage *ProgramStorage) UpdateStoredProgram(programName string, program *
 		log.Error("Failed to update stored program '%s': %v", programName, err)
 		return err
 	}
-	log.Info("Successfully updated stored program: %s", programName)
+	next, err := newRevision(current.Revision+1, program, note)
+	if err == nil {
+		err = storage.saveRevisionIndex(programName, append(index, next))
+	}
+	if err != nil {
+		log.Error("Failed to record revision %d of stored program '%s': %v", current.Revision+1, programName, err)
+		return err
+	}
+	log.Info("Successfully updated stored program: %s (revision %d)", programName, next.Revision)
 	return nil
 }
 
-func (storage *ProgramStorage) DeleteStoredProgram(programName string) error {
+func (storage *ProgramStorage) DeleteStoredProgram(programName, ifMatch string) error {
 	log.Info("Deleting stored program: %s", programName)
 	if err := types.ValidateStorageName(programName); err != nil {
 		return err
 	}
+	storage.writeMu.Lock()
+	defer storage.writeMu.Unlock()
+	if err := storage.checkIfMatch(programName, ifMatch); err != nil {
+		return err
+	}
 	filePath := filepath.Join(storage.programPath, programName+".json")
 	err := storage.DeleteProgram(filePath)
 	if err != nil {
 		log.Error("Failed to delete stored program '%s': %v", programName, err)
 		return err
 	}
+	if err := os.RemoveAll(storage.revisionPath(programName)); err != nil {
+		log.Warning("Failed to delete the revisions of stored program '%s': %v", programName, err)
+	}
 	log.Info("Successfully deleted stored program: %s", programName)
 	return nil
 }
+
+// checkIfMatch refuses a write made against another version of the program
+// than the stored one, or that does not say which version it was made
+// against. The hash is taken over the program as it loads, the same as the
+// ETag it was read with.
+func (storage *ProgramStorage) checkIfMatch(programName, ifMatch string) error {
+	current, err := storage.LoadStoredProgram(programName)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(ifMatch) == "" {
+		log.Warning("Refusing a write to stored program '%s' without If-Match", programName)
+		return types.ErrIfMatchRequired
+	}
+	hash, err := current.ContentHash()
+	if err != nil {
+		return err
+	}
+	if !types.IfMatches(ifMatch, hash) {
+		log.Warning("Stored program '%s' is no longer at %s", programName, ifMatch)
+		return types.ErrProgramChanged
+	}
+	return nil
+}
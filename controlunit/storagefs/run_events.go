@@ -0,0 +1,90 @@
+package storagefs
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+type (
+	// RunEventWriter appends a running program's events to running/, one
+	// JSON object per line. Appending rather than rewriting the file means a
+	// crash mid-run leaves every event recorded before it readable.
+	RunEventWriter struct {
+		name    string
+		file    *os.File
+		encoder *json.Encoder
+	}
+)
+
+func NewRunEventWriter(fileStorage *ExecutorFileStorage, name string) *RunEventWriter {
+	log.Info("Creating run event writer for program '%s'", name)
+	filePath := filepath.Join(fileStorage.runningPath, name+".jsonl")
+	eventFile, err := os.OpenFile(filePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Error("Failed to create run event file for program '%s': %v", name, err)
+		return nil
+	}
+	return &RunEventWriter{name: name, file: eventFile, encoder: json.NewEncoder(eventFile)}
+}
+
+// Add appends events to the file. A failed write is logged and otherwise
+// ignored: losing the record of a pause must not stop the run it describes.
+func (writer *RunEventWriter) Add(events []types.RunEvent) {
+	if writer == nil || writer.encoder == nil {
+		return
+	}
+	for _, event := range events {
+		if err := writer.encoder.Encode(event); err != nil {
+			log.Error("Failed to write run event for program '%s': %v", writer.name, err)
+			return
+		}
+	}
+}
+
+func (writer *RunEventWriter) Close() {
+	if writer == nil || writer.file == nil {
+		return
+	}
+	_ = writer.file.Close()
+	writer.file = nil
+	writer.encoder = nil
+}
+
+// LoadRunEvents returns the events recorded for a finished run. A run with
+// none, or one filed before events were recorded, has no file and gets an
+// empty list rather than an error.
+func (storage *ExecutorFileStorage) LoadRunEvents(name string) ([]types.RunEvent, error) {
+	if err := types.ValidateStorageName(name); err != nil {
+		return nil, err
+	}
+	eventFile, err := os.Open(filepath.Join(storage.eventsPath, name+".jsonl"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer eventFile.Close()
+
+	var events []types.RunEvent
+	scanner := bufio.NewScanner(eventFile)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event types.RunEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// A torn last line is what a crash mid-write leaves; everything
+			// before it is still worth returning.
+			log.Warning("Skipping unreadable run event for program '%s': %v", name, err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
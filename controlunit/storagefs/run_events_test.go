@@ -0,0 +1,80 @@
+package storagefs
+
+import (
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func TestRunEventsFollowTheRunIntoHistory(t *testing.T) {
+	storage := newTestStorage(t)
+	startRun(t, storage, runName)
+
+	writer := NewRunEventWriter(storage, runName)
+	if writer == nil {
+		t.Fatal("expected a writer")
+	}
+	writer.Add([]types.RunEvent{
+		{Time: 100, Step: stepHeating, Kind: types.RunEventDoorOpened, Message: "door opened"},
+	})
+	writer.Add([]types.RunEvent{
+		{Time: 130, Step: stepHeating, Kind: types.RunEventDoorClosed, Message: "door closed"},
+	})
+	writer.Close()
+
+	if err := storage.MoveToHistory(runName); err != nil {
+		t.Fatalf("failed to move to history: %v", err)
+	}
+
+	events, err := storage.LoadRunEvents(runName)
+	if err != nil {
+		t.Fatalf("LoadRunEvents: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %v", events)
+	}
+	if events[0].Kind != types.RunEventDoorOpened || events[1].Time != 130 {
+		t.Errorf("events came back as %+v", events)
+	}
+}
+
+// Runs filed before events existed have no events file, and that is not an
+// error: they simply had none recorded.
+func TestLoadRunEventsWithoutAFileIsEmpty(t *testing.T) {
+	storage := newTestStorage(t)
+
+	events, err := storage.LoadRunEvents(runName)
+	if err != nil {
+		t.Fatalf("LoadRunEvents: %v", err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected no events, got %v", events)
+	}
+}
+
+func TestDeleteExecutedProgramRemovesTheRunEvents(t *testing.T) {
+	storage := newTestStorage(t)
+	startRun(t, storage, runName)
+
+	writer := NewRunEventWriter(storage, runName)
+	writer.Add([]types.RunEvent{{Time: 1, Kind: types.RunEventEmergencyStop, Message: "stop"}})
+	writer.Close()
+	if err := storage.MoveToHistory(runName); err != nil {
+		t.Fatalf("failed to move to history: %v", err)
+	}
+
+	if err := storage.DeleteExecutedProgram(runName); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	events, err := storage.LoadRunEvents(runName)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("expected the events gone, got %v (%v)", events, err)
+	}
+}
+
+func TestNilRunEventWriterIsSafe(t *testing.T) {
+	var writer *RunEventWriter
+	writer.Add([]types.RunEvent{{Time: 1}})
+	writer.Close()
+}
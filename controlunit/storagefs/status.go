@@ -1,7 +1,6 @@
 package storagefs
 
 import (
-	"os"
 	"path/filepath"
 
 	"github.com/rmkhl/halko/types"
@@ -30,16 +29,10 @@ func (statusWriter *StateWriter) UpdateState(status types.ProgramState) error {
 	}
 
 	log.Debug("Updating state for program '%s' to '%s' in %s", statusWriter.name, status, filePath)
-	statusFile, err := os.Create(filePath)
-	if err != nil {
-		log.Error("Failed to create status file for program '%s': %v", statusWriter.name, err)
-		return err
-	}
-	defer statusFile.Close()
-
-	_, err = statusFile.WriteString(string(status))
-	if err != nil {
-		log.Error("Failed to write status to file for program '%s': %v", statusWriter.name, err)
+	// The state is rewritten at every step of a run, so it is the file most
+	// likely to be mid-write when the power goes.
+	if err := types.WriteFileAtomic(filePath, []byte(status), 0o644); err != nil {
+		log.Error("Failed to write status file for program '%s': %v", statusWriter.name, err)
 		return err
 	}
 	log.Info("Successfully updated state for program '%s' to '%s'", statusWriter.name, status)
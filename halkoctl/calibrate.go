@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rmkhl/halko/types"
+)
+
+const defaultPointsFile = "calibration-points.json"
+
+// calibrationPoints is the points file: the reference points recorded so far,
+// by probe. It outlives a single invocation because the references are hours
+// apart - an ice bath now, boiling water after lunch.
+type calibrationPoints map[string][]types.CalibrationPoint
+
+func handleCalibrateCommand() {
+	if len(os.Args) < 3 {
+		fmt.Fprintf(os.Stderr, "Error: calibrate command requires a subcommand\n\n")
+		showCalibrateHelp()
+		os.Exit(exitError)
+	}
+
+	subcommand := os.Args[2]
+	flags := flag.NewFlagSet("calibrate "+subcommand, flag.ExitOnError)
+	pointsFile := flags.String("points", defaultPointsFile, "File the reference points are kept in")
+	samples := flags.Int("samples", 5, "Readings averaged for one reference point, a second apart")
+	var help bool
+	flags.BoolVar(&help, "h", false, "Show help message")
+	flags.BoolVar(&help, "help", false, "Show help message")
+	if err := flags.Parse(os.Args[3:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		os.Exit(exitError)
+	}
+	if help {
+		showCalibrateHelp()
+		os.Exit(exitSuccess)
+	}
+
+	var err error
+	switch subcommand {
+	case "read":
+		err = calibrateRead()
+	case "record":
+		err = calibrateRecord(flags.Args(), *pointsFile, *samples)
+	case "show":
+		err = calibrateShow(*pointsFile)
+	case "write":
+		err = calibrateWrite(*pointsFile, globalOpts.ConfigPath)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: Unknown calibrate subcommand '%s'\n\n", subcommand)
+		showCalibrateHelp()
+		os.Exit(exitError)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+func showCalibrateHelp() {
+	fmt.Println("halkoctl calibrate - Calibrate the temperature probes")
+	fmt.Println()
+	fmt.Println("Records what each probe reads at known reference temperatures and")
+	fmt.Println("writes the corrections into the sensorunit section of the config.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Printf("  %s [global-options] calibrate read\n", os.Args[0])
+	fmt.Printf("  %s [global-options] calibrate record [options] <probe> <reference-°C>\n", os.Args[0])
+	fmt.Printf("  %s [global-options] calibrate show [options]\n", os.Args[0])
+	fmt.Printf("  %s [global-options] calibrate write [options]\n", os.Args[0])
+	fmt.Println()
+	fmt.Println("Subcommands:")
+	fmt.Println("  read      Show the uncorrected reading of every probe")
+	fmt.Println("  record    Average a probe's readings and record them against the reference")
+	fmt.Println("  show      Show the recorded points and the corrections they give")
+	fmt.Println("  write     Write the corrections into the config file given with -c")
+	fmt.Println()
+	fmt.Printf("Probes: %s\n", strings.Join(types.TemperatureProbes, ", "))
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Printf("  -points string\n        File the reference points are kept in (default %q)\n", defaultPointsFile)
+	fmt.Println("  -samples int")
+	fmt.Println("        Readings averaged for one reference point, a second apart (default 5)")
+	fmt.Println()
+	fmt.Println("One point gives an offset, two a gain and offset, three or more a table")
+	fmt.Println("interpolated between them. A probe with no points keeps whatever the")
+	fmt.Println("config already has. The sensor unit applies the result once restarted.")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Printf("  %s calibrate record kiln_primary 0      # probe in an ice bath\n", os.Args[0])
+	fmt.Printf("  %s calibrate record kiln_primary 100    # probe in boiling water\n", os.Args[0])
+	fmt.Printf("  %s calibrate show\n", os.Args[0])
+	fmt.Printf("  %s -c /etc/opt/halko.cfg calibrate write\n", os.Args[0])
+}
+
+func calibrateRead() error {
+	readings, err := readRawTemperatures()
+	if err != nil {
+		return err
+	}
+	fmt.Println("Uncorrected readings:")
+	for _, probe := range types.TemperatureProbes {
+		if _, ok := readings[probe]; !ok {
+			continue
+		}
+		if readings[probe] == types.InvalidTemperatureReading {
+			fmt.Printf("  %-15s invalid\n", probe)
+			continue
+		}
+		fmt.Printf("  %-15s %.2f°C\n", probe, readings[probe])
+	}
+	return nil
+}
+
+func calibrateRecord(args []string, pointsFile string, samples int) error {
+	if len(args) != 2 {
+		return errors.New("record needs a probe and a reference temperature")
+	}
+	probe := args[0]
+	if !isProbe(probe) {
+		return fmt.Errorf("unknown probe %q", probe)
+	}
+	reference, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return fmt.Errorf("reference temperature %q is not a number", args[1])
+	}
+	if samples < 1 {
+		return errors.New("samples must be at least 1")
+	}
+
+	points, err := loadCalibrationPoints(pointsFile)
+	if err != nil {
+		return err
+	}
+
+	// Averaged, because a single MAX31855 reading is only good to its 0.25°C
+	// resolution and the point is to correct differences of about that size.
+	var sum float64
+	for i := range samples {
+		if i > 0 {
+			time.Sleep(time.Second)
+		}
+		readings, err := readRawTemperatures()
+		if err != nil {
+			return err
+		}
+		value, ok := readings[probe]
+		if !ok {
+			return fmt.Errorf("the sensor unit does not report %s, nothing recorded", probe)
+		}
+		if value == types.InvalidTemperatureReading {
+			return fmt.Errorf("%s gave an invalid reading, nothing recorded", probe)
+		}
+		sum += float64(readings[probe])
+	}
+	raw := round(sum/float64(samples), 3)
+
+	points[probe] = append(points[probe], types.CalibrationPoint{Raw: raw, Actual: reference})
+	if err := saveCalibrationPoints(pointsFile, points); err != nil {
+		return err
+	}
+	fmt.Printf("Recorded %s reading %.3f°C at %g°C (%d points for it in %s)\n",
+		probe, raw, reference, len(points[probe]), pointsFile)
+	return nil
+}
+
+func calibrateShow(pointsFile string) error {
+	points, err := loadCalibrationPoints(pointsFile)
+	if err != nil {
+		return err
+	}
+	if len(points) == 0 {
+		fmt.Printf("No points recorded in %s\n", pointsFile)
+		return nil
+	}
+	for _, probe := range types.TemperatureProbes {
+		if len(points[probe]) == 0 {
+			continue
+		}
+		fmt.Printf("%s\n", probe)
+		for _, point := range points[probe] {
+			fmt.Printf("  read %8.3f°C at %g°C\n", point.Raw, point.Actual)
+		}
+		calibration, err := fitCalibration(points[probe])
+		if err != nil {
+			fmt.Printf("  cannot fit: %v\n", err)
+			continue
+		}
+		fmt.Printf("  correction: %s\n", describeCalibration(calibration))
+	}
+	return nil
+}
+
+func calibrateWrite(pointsFile, configPath string) error {
+	if configPath == "" {
+		return errors.New("name the config file to update with -c")
+	}
+	points, err := loadCalibrationPoints(pointsFile)
+	if err != nil {
+		return err
+	}
+	if len(points) == 0 {
+		return fmt.Errorf("no points recorded in %s", pointsFile)
+	}
+
+	calibration := make(map[string]*types.ProbeCalibration, len(points))
+	for probe, probePoints := range points {
+		fitted, err := fitCalibration(probePoints)
+		if err != nil {
+			return fmt.Errorf("%s: %w", probe, err)
+		}
+		calibration[probe] = fitted
+	}
+
+	original, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	updated, err := setConfigCalibration(original, calibration)
+	if err != nil {
+		return fmt.Errorf("updating %s: %w", configPath, err)
+	}
+
+	// Check the result the way the services will before it replaces anything:
+	// a config they refuse to start on is worse than an uncalibrated one.
+	staged := configPath + ".new"
+	if err := os.WriteFile(staged, updated, 0644); err != nil {
+		return err
+	}
+	if _, err := types.LoadConfig(staged); err != nil {
+		os.Remove(staged)
+		return fmt.Errorf("the updated config would not load, left %s untouched: %w", configPath, err)
+	}
+	if err := os.WriteFile(configPath+".bak", original, 0644); err != nil {
+		os.Remove(staged)
+		return err
+	}
+	if err := os.Rename(staged, configPath); err != nil {
+		return err
+	}
+
+	for _, probe := range types.TemperatureProbes {
+		if c, ok := calibration[probe]; ok {
+			fmt.Printf("%-15s %s\n", probe, describeCalibration(c))
+		}
+	}
+	fmt.Printf("Written to %s (previous version in %s.bak). Restart the sensor unit to apply.\n", configPath, configPath)
+	return nil
+}
+
+func isProbe(name string) bool {
+	for _, probe := range types.TemperatureProbes {
+		if name == probe {
+			return true
+		}
+	}
+	return false
+}
+
+// readRawTemperatures returns the uncorrected reading of every probe the
+// sensor unit reports, keyed by probe name.
+func readRawTemperatures() (types.TemperatureResponse, error) {
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(globalConfig.APIEndpoints.SensorUnit.GetTemperaturesURL())
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the sensor unit: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errorResp types.APIErrorResponse
+		if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Err != "" {
+			return nil, fmt.Errorf("sensor unit: %s", errorResp.Err)
+		}
+		return nil, fmt.Errorf("sensor unit: HTTP %d", resp.StatusCode)
+	}
+
+	var response types.APIResponse[types.TemperatureResponse]
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return rawReadings(response.Data), nil
+}
+
+// rawReadings picks the per-probe readings out of a temperature response,
+// leaving the fused kiln and material values behind.
+func rawReadings(temperatures types.TemperatureResponse) types.TemperatureResponse {
+	readings := make(types.TemperatureResponse)
+	for name, value := range temperatures {
+		if probe, ok := strings.CutSuffix(name, types.RawReadingSuffix); ok {
+			readings[probe] = value
+		}
+	}
+	return readings
+}
+
+func loadCalibrationPoints(path string) (calibrationPoints, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return calibrationPoints{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var points calibrationPoints
+	if err := json.Unmarshal(data, &points); err != nil {
+		return nil, fmt.Errorf("%s is not a points file: %w", path, err)
+	}
+	return points, nil
+}
+
+func saveCalibrationPoints(path string, points calibrationPoints) error {
+	data, err := json.MarshalIndent(points, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// fitCalibration turns a probe's reference points into the simplest correction
+// that passes through all of them: one point is an offset, two a line, and
+// more a table. A least-squares line through three points would be simpler to
+// write down, but would not read true at any of the references it was taken at.
+func fitCalibration(points []types.CalibrationPoint) (*types.ProbeCalibration, error) {
+	sorted := append([]types.CalibrationPoint(nil), points...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Raw < sorted[j].Raw })
+
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Raw == sorted[i-1].Raw {
+			return nil, fmt.Errorf("two points read %.3f°C, drop one from the points file", sorted[i].Raw)
+		}
+		if sorted[i].Actual <= sorted[i-1].Actual {
+			return nil, errors.New("a higher reading was recorded against a lower reference, check the points file")
+		}
+	}
+
+	switch len(sorted) {
+	case 0:
+		return nil, errors.New("no points recorded")
+	case 1:
+		return &types.ProbeCalibration{Gain: 1, Offset: round(sorted[0].Actual-sorted[0].Raw, 4)}, nil
+	case 2:
+		gain := (sorted[1].Actual - sorted[0].Actual) / (sorted[1].Raw - sorted[0].Raw)
+		return &types.ProbeCalibration{
+			Gain:   round(gain, 6),
+			Offset: round(sorted[0].Actual-gain*sorted[0].Raw, 4),
+		}, nil
+	default:
+		return &types.ProbeCalibration{Points: sorted}, nil
+	}
+}
+
+func describeCalibration(c *types.ProbeCalibration) string {
+	if len(c.Points) > 0 {
+		return fmt.Sprintf("table of %d points", len(c.Points))
+	}
+	if c.Gain == 0 {
+		return fmt.Sprintf("offset %+g°C", c.Offset)
+	}
+	return fmt.Sprintf("gain %g, offset %+g°C", c.Gain, c.Offset)
+}
+
+func round(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
+
+// configMember is one key of a JSON object with its value left undecoded.
+type configMember struct {
+	key   string
+	value json.RawMessage
+}
+
+// setConfigCalibration replaces the named probes' calibration in a config file,
+// keeping every other probe's and every other setting as it was. The file is
+// edited as JSON objects in their original key order rather than through
+// HalkoConfig, which would drop anything it does not model and reorder the
+// rest.
+func setConfigCalibration(config []byte, calibration map[string]*types.ProbeCalibration) ([]byte, error) {
+	top, err := decodeObject(config)
+	if err != nil {
+		return nil, err
+	}
+	sensorIndex := -1
+	for i, member := range top {
+		if member.key == "sensorunit" {
+			sensorIndex = i
+		}
+	}
+	if sensorIndex < 0 {
+		return nil, errors.New("no sensorunit section")
+	}
+	sensor, err := decodeObject(top[sensorIndex].value)
+	if err != nil {
+		return nil, fmt.Errorf("sensorunit: %w", err)
+	}
+
+	merged := map[string]*types.ProbeCalibration{}
+	calibrationIndex := -1
+	for i, member := range sensor {
+		if member.key == "calibration" {
+			calibrationIndex = i
+			if err := json.Unmarshal(member.value, &merged); err != nil {
+				return nil, fmt.Errorf("sensorunit.calibration: %w", err)
+			}
+		}
+	}
+	for probe, c := range calibration {
+		merged[probe] = c
+	}
+	value, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	if calibrationIndex < 0 {
+		sensor = append(sensor, configMember{key: "calibration", value: value})
+	} else {
+		sensor[calibrationIndex].value = value
+	}
+
+	top[sensorIndex].value = encodeObject(sensor)
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, encodeObject(top), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+func decodeObject(data []byte) ([]configMember, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, errors.New("not a JSON object")
+	}
+	var members []configMember
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		members = append(members, configMember{key: key, value: value})
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func encodeObject(members []configMember) json.RawMessage {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, member := range members {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(member.key)
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(member.value)
+	}
+	b.WriteByte('}')
+	return b.Bytes()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func TestFitCalibration(t *testing.T) {
+	t.Run("one point is an offset", func(t *testing.T) {
+		c, err := fitCalibration([]types.CalibrationPoint{{Raw: 0.8, Actual: 0}})
+		if err != nil {
+			t.Fatalf("fitCalibration: %v", err)
+		}
+		if c.Gain != 1 || c.Offset != -0.8 {
+			t.Fatalf("expected gain 1 offset -0.8, got %+v", c)
+		}
+	})
+
+	t.Run("two points are a line through both", func(t *testing.T) {
+		c, err := fitCalibration([]types.CalibrationPoint{{Raw: 101.2, Actual: 100}, {Raw: 0.8, Actual: 0}})
+		if err != nil {
+			t.Fatalf("fitCalibration: %v", err)
+		}
+		for raw, want := range map[float32]float64{0.8: 0, 101.2: 100} {
+			if got := c.Apply(raw); math.Abs(float64(got)-want) > 0.001 {
+				t.Errorf("Apply(%v) = %v, want %v", raw, got, want)
+			}
+		}
+	})
+
+	t.Run("three points are a sorted table", func(t *testing.T) {
+		c, err := fitCalibration([]types.CalibrationPoint{{Raw: 101.2, Actual: 100}, {Raw: 0.8, Actual: 0}, {Raw: 50.5, Actual: 50}})
+		if err != nil {
+			t.Fatalf("fitCalibration: %v", err)
+		}
+		if len(c.Points) != 3 || c.Points[0].Raw != 0.8 || c.Points[2].Raw != 101.2 {
+			t.Fatalf("expected the points sorted by reading, got %+v", c.Points)
+		}
+	})
+
+	t.Run("duplicate readings are refused", func(t *testing.T) {
+		if _, err := fitCalibration([]types.CalibrationPoint{{Raw: 1, Actual: 0}, {Raw: 1, Actual: 100}}); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+
+	t.Run("crossed points are refused", func(t *testing.T) {
+		if _, err := fitCalibration([]types.CalibrationPoint{{Raw: 1, Actual: 100}, {Raw: 99, Actual: 0}}); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+// The rewrite must leave everything but the calibration as the operator wrote
+// it, in the same order, and the result must still load.
+func TestSetConfigCalibrationKeepsTheRestOfTheConfig(t *testing.T) {
+	original, err := os.ReadFile("../templates/halko.cfg")
+	if err != nil {
+		t.Fatalf("read template: %v", err)
+	}
+
+	updated, err := setConfigCalibration(original, map[string]*types.ProbeCalibration{
+		types.ProbeKilnPrimary: {Gain: 0.99, Offset: -0.5},
+	})
+	if err != nil {
+		t.Fatalf("setConfigCalibration: %v", err)
+	}
+
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(original, &before); err != nil {
+		t.Fatalf("decode original: %v", err)
+	}
+	if err := json.Unmarshal(updated, &after); err != nil {
+		t.Fatalf("decode updated: %v", err)
+	}
+	for key := range before {
+		if key == "sensorunit" {
+			continue
+		}
+		if compact(t, before[key]) != compact(t, after[key]) {
+			t.Errorf("%s changed:\n%s\n%s", key, before[key], after[key])
+		}
+	}
+
+	// Key order survives: the first top-level key is still first.
+	firstKey := strings.SplitN(strings.TrimSpace(string(original)), "\"", 3)[1]
+	if !strings.HasPrefix(strings.TrimSpace(string(updated)), "{\n  \""+firstKey+"\"") {
+		t.Errorf("expected %q to stay first:\n%s", firstKey, updated)
+	}
+
+	path := filepath.Join(t.TempDir(), "halko.cfg")
+	if err := os.WriteFile(path, updated, 0644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	config, err := types.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("updated config does not load: %v", err)
+	}
+	if c := config.SensorUnit.Calibration[types.ProbeKilnPrimary]; c == nil || c.Gain != 0.99 || c.Offset != -0.5 {
+		t.Fatalf("calibration not written, got %+v", config.SensorUnit.Calibration)
+	}
+}
+
+// Calibrating one probe must not throw away another's earlier calibration.
+func TestSetConfigCalibrationMergesWithExistingProbes(t *testing.T) {
+	config := []byte(`{"sensorunit": {"serial_device": "/dev/ttyUSB0", "calibration": {"material": {"gain": 1, "offset": 0.25}}}}`)
+
+	updated, err := setConfigCalibration(config, map[string]*types.ProbeCalibration{
+		types.ProbeKilnPrimary: {Gain: 1, Offset: -1},
+	})
+	if err != nil {
+		t.Fatalf("setConfigCalibration: %v", err)
+	}
+
+	var parsed struct {
+		SensorUnit types.SensorUnitConfig `json:"sensorunit"`
+	}
+	if err := json.Unmarshal(updated, &parsed); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if parsed.SensorUnit.Calibration[types.ProbeMaterial] == nil || parsed.SensorUnit.Calibration[types.ProbeKilnPrimary] == nil {
+		t.Fatalf("expected both probes calibrated, got %s", updated)
+	}
+}
+
+func compact(t *testing.T, raw json.RawMessage) string {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	out, _ := json.Marshal(v)
+	return string(out)
+}
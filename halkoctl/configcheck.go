@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 
 	"github.com/rmkhl/halko/types"
@@ -35,6 +36,9 @@ func describeConfig(config *types.HalkoConfig) string {
 	fmt.Fprintf(&b, "Control unit\n")
 	fmt.Fprintf(&b, "  base_path                 %s\n", config.ControlUnitConfig.BasePath)
 	fmt.Fprintf(&b, "  tick_length               %s\n", config.ControlUnitConfig.TickLength)
+	if key, err := types.DecodeComplianceKey(config.ControlUnitConfig.ComplianceKey); err == nil {
+		fmt.Fprintf(&b, "  compliance_key            signs with public key %s\n", types.CompliancePublicKey(key))
+	}
 	fmt.Fprintf(&b, "\nDefaults applied to a program that names none\n")
 	for _, stepType := range []types.StepType{types.StepTypeHeating, types.StepTypeAcclimate} {
 		band := d.Deltas[stepType]
@@ -51,10 +55,83 @@ func describeConfig(config *types.HalkoConfig) string {
 	fmt.Fprintf(&b, "  steam_ceiling             %d°C\n", *d.SteamCeiling)
 	fmt.Fprintf(&b, "  sensor_timeout            %s\n", d.SensorTimeout)
 	fmt.Fprintf(&b, "  execution_log_interval    %s\n", d.ExecutionLogInterval)
+	fmt.Fprintf(&b, "  door_open_action          %s\n", d.DoorOpenAction)
+	fmt.Fprintf(&b, "  degraded_sensor_action    %s\n", d.DegradedSensorAction)
+	fmt.Fprintf(&b, "  plausibility              %.1f°C/s, %d readings confirm a jump\n", d.Plausibility.MaxRate, d.Plausibility.Confirm)
+	fmt.Fprintf(&b, "\nPower unit limits and interlocks\n")
+	if len(config.PowerUnit.Limits) == 0 && len(config.PowerUnit.Interlocks) == 0 {
+		fmt.Fprintf(&b, "  none, every command is applied as sent\n")
+	}
+	for _, name := range sortedKeys(config.PowerUnit.Limits) {
+		fmt.Fprintf(&b, "  %-25s at most %d%%\n", name, config.PowerUnit.Limits[name])
+	}
+	for _, rule := range config.PowerUnit.Interlocks {
+		fmt.Fprintf(&b, "  %-25s requires %s at %d%% or more\n", rule.Channel, rule.Requires, rule.MinPercent)
+	}
+	fmt.Fprintf(&b, "\nSensor fusion\n")
+	fmt.Fprintf(&b, "  %-25s %s\n", "kiln_fusion", config.SensorUnit.KilnFusion)
+	fmt.Fprintf(&b, "  %-25s %s\n", "material_fusion", config.SensorUnit.MaterialFusion)
+	for n := 1; n <= types.MaxMaterialProbes; n++ {
+		probe := types.MaterialProbeName(n)
+		if weight, ok := config.SensorUnit.MaterialWeights[probe]; ok {
+			fmt.Fprintf(&b, "  %-25s weight %g\n", probe, weight)
+		}
+	}
+	if disagreement := config.SensorUnit.KilnDisagreement; disagreement != nil {
+		fmt.Fprintf(&b, "  %-25s over %.1f°C for %s\n", "kiln_disagreement", disagreement.Threshold, disagreement.Window)
+	}
+	fmt.Fprintf(&b, "\nProbe calibration\n")
+	for _, probe := range types.TemperatureProbes {
+		c, ok := config.SensorUnit.Calibration[probe]
+		switch {
+		case ok:
+			fmt.Fprintf(&b, "  %-25s %s\n", probe, describeCalibration(c))
+		case types.IsMaterialProbe(probe) && probe != types.ProbeMaterial:
+			// Extra material probes are optional hardware; listing them
+			// uncalibrated would suggest every kiln has them.
+		default:
+			fmt.Fprintf(&b, "  %-25s none, read as the device reports it\n", probe)
+		}
+	}
+	fmt.Fprintf(&b, "\nCold junction\n")
+	switch cj := config.SensorUnit.ColdJunction; {
+	case cj == nil:
+		fmt.Fprintf(&b, "  none, die temperatures are only reported\n")
+	default:
+		fmt.Fprintf(&b, "  %-25s %.1f°C\n", "reference", cj.Reference)
+		fmt.Fprintf(&b, "  %-25s %.1f°C\n", "max_spread", cj.MaxSpread)
+		if cj.Correction != nil {
+			fmt.Fprintf(&b, "  %-25s %.2f of each die's drift\n", "correction", *cj.Correction)
+		} else {
+			fmt.Fprintf(&b, "  %-25s none\n", "correction")
+		}
+	}
+	fmt.Fprintf(&b, "\nHumidity sensor\n")
+	if sensor := config.SensorUnit.HumiditySensor; sensor != "" {
+		fmt.Fprintf(&b, "  %s, climate served on %s\n", sensor, config.APIEndpoints.SensorUnit.Climate)
+	} else {
+		fmt.Fprintf(&b, "  none, no climate is read or logged\n")
+	}
+	fmt.Fprintf(&b, "\nMoisture probes\n")
+	if probes := config.SensorUnit.MoistureProbes; probes != nil {
+		fmt.Fprintf(&b, "  %d, moisture served on %s\n", probes.Count, config.APIEndpoints.SensorUnit.Moisture)
+		fmt.Fprintf(&b, "  %-25s %.3f * MC %+.2f\n", "species reading", probes.SpeciesSlope, probes.SpeciesIntercept)
+	} else {
+		fmt.Fprintf(&b, "  none, no step can end on moisture\n")
+	}
 
 	return b.String()
 }
 
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func showConfigHelp() {
 	fmt.Println("Usage: halkoctl config")
 	fmt.Println()
@@ -22,7 +22,8 @@ func TestDescribeConfigShowsResolvedDefaults(t *testing.T) {
 		"heating", "acclimate",
 		"fan_power", "steam_power", "equalize", "delta", "steam_prewarm", "steam_prewarm_timeout",
 		"max_target_temperature", "steam_ceiling",
-		"sensor_timeout", "execution_log_interval",
+		"sensor_timeout", "execution_log_interval", "door_open_action",
+		"degraded_sensor_action", "plausibility", "kiln_fusion", "material_fusion", "kiln_disagreement",
 	} {
 		if !strings.Contains(out, want) {
 			t.Errorf("description does not mention %q:\n%s", want, out)
@@ -42,3 +43,78 @@ func TestDescribeConfigShowsDeltaBandValues(t *testing.T) {
 		t.Errorf("acclimate band %v/%v not shown:\n%s", acclimate.MinDelta, acclimate.MaxDelta, out)
 	}
 }
+
+// The power unit's rules change what a program's power settings actually do,
+// so the description spells them out, including their absence.
+func TestDescribeConfigShowsPowerRules(t *testing.T) {
+	config, err := types.LoadConfig("../templates/halko.cfg")
+	if err != nil {
+		t.Fatalf("load template config: %v", err)
+	}
+
+	if out := describeConfig(config); !strings.Contains(out, "none, every command is applied as sent") {
+		t.Errorf("description does not say there are no rules:\n%s", out)
+	}
+
+	config.PowerUnit.Limits = map[string]int{"steam": 60}
+	config.PowerUnit.Interlocks = []types.PowerInterlock{{Channel: "heater", Requires: "fan", MinPercent: 30}}
+	out := describeConfig(config)
+	for _, want := range []string{"at most 60%", "requires fan at 30% or more"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("description does not mention %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDescribeConfigShowsColdJunction(t *testing.T) {
+	config, err := types.LoadConfig("../templates/halko.cfg")
+	if err != nil {
+		t.Fatalf("load template config: %v", err)
+	}
+
+	if out := describeConfig(config); !strings.Contains(out, "die temperatures are only reported") {
+		t.Errorf("description does not say there is no cold junction reference:\n%s", out)
+	}
+
+	correction := float32(0.8)
+	config.SensorUnit.ColdJunction = &types.ColdJunction{Reference: 25, MaxSpread: 5, Correction: &correction}
+	out := describeConfig(config)
+	for _, want := range []string{"25.0°C", "max_spread", "0.80 of each die's drift"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("description does not mention %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDescribeConfigShowsHumiditySensor(t *testing.T) {
+	config, err := types.LoadConfig("../templates/halko.cfg")
+	if err != nil {
+		t.Fatalf("load template config: %v", err)
+	}
+
+	if out := describeConfig(config); !strings.Contains(out, "no climate is read or logged") {
+		t.Errorf("description does not say there is no humidity sensor:\n%s", out)
+	}
+
+	config.SensorUnit.HumiditySensor = types.HumiditySensorWetBulb
+	if out := describeConfig(config); !strings.Contains(out, "wet_bulb, climate served on /climate") {
+		t.Errorf("description does not name the humidity sensor:\n%s", out)
+	}
+}
+
+func TestDescribeConfigShowsMoistureProbes(t *testing.T) {
+	config, err := types.LoadConfig("../templates/halko.cfg")
+	if err != nil {
+		t.Fatalf("load template config: %v", err)
+	}
+
+	if out := describeConfig(config); !strings.Contains(out, "no step can end on moisture") {
+		t.Errorf("description does not say there are no moisture probes:\n%s", out)
+	}
+
+	config.SensorUnit.MoistureProbes = &types.MoistureProbes{Count: 2, SpeciesSlope: 0.9, SpeciesIntercept: 1.2}
+	out := describeConfig(config)
+	if !strings.Contains(out, "2, moisture served on /moisture") || !strings.Contains(out, "0.900 * MC +1.20") {
+		t.Errorf("description does not show the moisture probes:\n%s", out)
+	}
+}
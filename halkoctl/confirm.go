@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func handleConfirmCommand() {
+	if len(os.Args) > 2 {
+		arg := os.Args[2]
+		if arg == "-h" || arg == "--help" {
+			showConfirmHelp()
+			os.Exit(exitSuccess)
+		}
+	}
+
+	confirmRunningStep()
+	os.Exit(exitSuccess)
+}
+
+func showConfirmHelp() {
+	fmt.Println("halkoctl confirm - Confirm the step the running program is waiting on")
+	fmt.Println()
+	fmt.Println("A program's confirm step holds the kiln until the operator says it may go on.")
+	fmt.Println("This confirms it, the same as the confirm button on the sensor unit.")
+	fmt.Println("'halkoctl running' shows what the step is waiting for.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Printf("  %s [global-options] confirm\n", os.Args[0])
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  -h, --help")
+	fmt.Println("        Show this help message")
+	fmt.Println()
+	fmt.Println("Global Options:")
+	fmt.Println("  -c, --config string")
+	fmt.Println("        Path to the halko.cfg configuration file")
+	fmt.Println("  -v, --verbose")
+	fmt.Println("        Enable verbose output for HTTP requests")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Printf("  %s confirm                    # Let the running program go on\n", os.Args[0])
+	fmt.Println()
+}
+
+func confirmRunningStep() {
+	url := globalConfig.APIEndpoints.ControlUnit.URL + "/engine/running/confirm"
+
+	if globalOpts.Verbose {
+		fmt.Printf("POST %s\n", url)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Post(url, "application/json", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to controlunit: %v\n", err)
+		os.Exit(exitError)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading response: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	if globalOpts.Verbose {
+		fmt.Printf("HTTP Status: %d %s\n", resp.StatusCode, resp.Status)
+		if len(respBody) > 0 {
+			fmt.Printf("Raw Response: %s\n", string(respBody))
+		}
+		fmt.Println()
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse types.APIErrorResponse
+		if err := json.Unmarshal(respBody, &errorResponse); err == nil && errorResponse.Err != "" {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", errorResponse.Err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: HTTP %d - %s\n", resp.StatusCode, string(respBody))
+		}
+		os.Exit(exitError)
+	}
+
+	fmt.Println("✓ Step confirmed, the program goes on")
+}
@@ -27,6 +27,7 @@ func showHelp() {
 	fmt.Println("  stream                Debug: stream live WebSocket data")
 	fmt.Println("  running               Show currently running program")
 	fmt.Println("  stop                  Stop currently running program")
+	fmt.Println("  confirm               Confirm the step the running program is waiting on")
 	fmt.Println("  history               Show program execution history")
 	fmt.Println("  validate              Validate a program file")
 	fmt.Println("  display               Send text to sensor unit display")
@@ -34,6 +35,9 @@ func showHelp() {
 	fmt.Println("  programs              Manage stored programs")
 	fmt.Println("  nginx                 Generate nginx proxy configuration")
 	fmt.Println("  config                Check the configuration and show what it resolves to")
+	fmt.Println("  calibrate             Calibrate the temperature probes")
+	fmt.Println("  schedule              Make a program from a drying schedule table")
+	fmt.Println("  storage               Check the control unit's data directory and repair it")
 	fmt.Println("  version               Print the Halko version this binary was built from")
 	fmt.Println()
 	fmt.Println("Command Help:")
@@ -48,6 +52,9 @@ func showHelp() {
 	fmt.Printf("  %s --verbose validate my-program.json\n", os.Args[0])
 	fmt.Printf("  %s display \"Hello World\"\n", os.Args[0])
 	fmt.Printf("  %s temperatures\n", os.Args[0])
+	fmt.Printf("  %s calibrate record kiln_primary 0\n", os.Args[0])
+	fmt.Printf("  %s schedule -name \"Oak 4/4\" -cool-to 35 t4d2.csv\n", os.Args[0])
+	fmt.Printf("  %s storage check --repair\n", os.Args[0])
 	fmt.Printf("  %s programs list\n", os.Args[0])
 	fmt.Printf("  %s programs create my-program.json\n", os.Args[0])
 	fmt.Printf("  %s nginx -port 8080 -output /etc/nginx/sites-available/halko\n", os.Args[0])
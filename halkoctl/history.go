@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rmkhl/halko/types"
@@ -308,6 +309,9 @@ func queryProgramDetails(programName string) {
 	fmt.Println()
 	fmt.Printf("Name:         %s\n", run.Program.ProgramName)
 	fmt.Printf("Steps:        %d\n", len(run.Program.ProgramSteps))
+	if line := describeStoredProgram(run); line != "" {
+		fmt.Printf("Stored As:    %s\n", line)
+	}
 	fmt.Println()
 
 	fmt.Println("Program Steps:")
@@ -318,6 +322,12 @@ func queryProgramDetails(programName string) {
 		if step.Runtime != nil {
 			fmt.Printf("    Runtime:           %s\n", step.Runtime.String())
 		}
+		if step.TargetMoisture != nil {
+			fmt.Printf("    Target Moisture:   %.1f%%\n", *step.TargetMoisture)
+		}
+		if step.Hold != nil && step.Hold.Duration != nil {
+			fmt.Printf("    Hold:              %.1f°C for %s, %s on dip\n", step.Hold.Threshold, step.Hold.Duration.String(), step.Hold.OnDip)
+		}
 		if step.Heater != nil {
 			fmt.Printf("    Heater Control:    %s\n", formatPowerControl(step.Heater))
 		}
@@ -327,8 +337,122 @@ func queryProgramDetails(programName string) {
 		if step.Steam != nil {
 			fmt.Printf("    Steam Control: %s\n", formatPowerControl(step.Steam))
 		}
+		if step.Vent != nil {
+			fmt.Printf("    Vent Control:      %s\n", formatPowerControl(step.Vent))
+		}
 	}
 	fmt.Println()
+
+	if run.Compliance != nil {
+		// The log is what the report claims to be built from; without it
+		// the report is shown unchecked against it rather than not at all.
+		executionLog, err := fetchRunLog(programName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot fetch the execution log to check the report against: %v\n", err)
+		}
+		fmt.Print(describeCompliance(run.Compliance, globalConfig.ControlUnitConfig.ComplianceKey, executionLog))
+	}
+}
+
+// describeStoredProgram says which stored program a run was started from by
+// name, and whether that program is still what was run.
+func describeStoredProgram(run types.ExecutedProgram) string {
+	ref := run.Program.StoredProgram
+	if ref == nil {
+		return ""
+	}
+	hash := strings.TrimPrefix(ref.Hash, "sha256:")
+	if len(hash) > 12 {
+		hash = hash[:12]
+	}
+	line := fmt.Sprintf("%s (%s)", ref.Name, hash)
+	if run.StoredProgramRevision > 0 {
+		line = fmt.Sprintf("%s revision %d (%s)", ref.Name, run.StoredProgramRevision, hash)
+	}
+	switch run.StoredProgramState {
+	case types.StoredProgramChanged:
+		line += ", edited since this run"
+	case types.StoredProgramDeleted:
+		line += ", since deleted"
+	case types.StoredProgramUnchanged:
+		line += ", unchanged since this run"
+	}
+	if ref.Overrides != nil {
+		line += ", with overrides"
+	}
+	return line
+}
+
+// describeCompliance formats a run's heat treatment compliance report, with
+// what can be checked of it: the signature, whether the key that made it is
+// this kiln's, and whether the log it pins is the log the run serves. kilnKey
+// and executionLog are left empty when they are not to hand.
+func describeCompliance(report *types.ComplianceReport, kilnKey string, executionLog []byte) string {
+	var out strings.Builder
+	fmt.Fprintln(&out, "Heat Treatment Compliance")
+	fmt.Fprintln(&out, "=========================")
+	fmt.Fprintln(&out)
+
+	verdict := "NOT COMPLIANT"
+	if report.Compliant() {
+		verdict = "COMPLIANT"
+	}
+	fmt.Fprintf(&out, "Result:       %s\n", verdict)
+	fmt.Fprintf(&out, "Material:     %s of the material probes\n", report.MaterialFusion)
+	for _, treatment := range report.HeatTreatments {
+		fmt.Fprintf(&out, "\n  %s: %.1f°C for %s (%s on dip)\n", treatment.Step, treatment.Threshold,
+			formatDurationLong(time.Duration(treatment.RequiredSeconds)*time.Second), treatment.OnDip)
+		fmt.Fprintf(&out, "    Held:              %s, %d dips\n",
+			formatDurationLong(time.Duration(treatment.HeldSeconds)*time.Second), treatment.Dips)
+		if treatment.Met {
+			fmt.Fprintf(&out, "    Met:               %s into the run\n", formatDurationLong(time.Duration(treatment.MetAt)*time.Second))
+		} else {
+			fmt.Fprintln(&out, "    Met:               no")
+		}
+	}
+	fmt.Fprintln(&out)
+
+	switch err := report.Verify(); {
+	case report.Signature == "":
+		fmt.Fprintln(&out, "Signature:    none, the kiln has no compliance_key")
+	case err != nil:
+		fmt.Fprintf(&out, "Signature:    INVALID (%v)\n", err)
+	default:
+		fmt.Fprintf(&out, "Signature:    valid, key %s\n", report.PublicKey)
+		if key, keyErr := types.DecodeComplianceKey(kilnKey); keyErr == nil {
+			if types.CompliancePublicKey(key) == report.PublicKey {
+				fmt.Fprintln(&out, "              this kiln's key")
+			} else {
+				fmt.Fprintln(&out, "              NOT this kiln's key")
+			}
+		}
+	}
+
+	switch {
+	case executionLog == nil:
+		fmt.Fprintf(&out, "Log SHA-256:  %s (not checked)\n", report.LogSHA256)
+	case types.ComplianceLogDigest(executionLog) == report.LogSHA256:
+		fmt.Fprintf(&out, "Log SHA-256:  %s (matches the run's log)\n", report.LogSHA256)
+	default:
+		fmt.Fprintf(&out, "Log SHA-256:  %s (DOES NOT match the run's log)\n", report.LogSHA256)
+	}
+	fmt.Fprintln(&out)
+	return out.String()
+}
+
+// fetchRunLog returns a finished run's execution log.
+func fetchRunLog(programName string) ([]byte, error) {
+	url := globalConfig.APIEndpoints.ControlUnit.URL + "/engine/history/" + programName + "/log"
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
 }
 
 func queryProgramLog(programName string, outputFile string) {
@@ -404,8 +528,17 @@ func formatPowerControl(power *types.PowerPidSettings) string {
 	if power.Power != nil {
 		return fmt.Sprintf("Simple (%d%%)", *power.Power)
 	}
+	if power.MinDelta != nil && power.MaxDelta != nil && power.MinPower != nil {
+		return fmt.Sprintf("Delta (min: %.1f°C, max: %.1f°C, from %d%%)", *power.MinDelta, *power.MaxDelta, *power.MinPower)
+	}
 	if power.MinDelta != nil && power.MaxDelta != nil {
 		return fmt.Sprintf("Delta (min: %.1f°C, max: %.1f°C)", *power.MinDelta, *power.MaxDelta)
 	}
+	if power.MinHumidity != nil && power.MaxHumidity != nil {
+		return fmt.Sprintf("Humidity (min: %.0f%%, max: %.0f%%)", *power.MinHumidity, *power.MaxHumidity)
+	}
+	if power.MinEMC != nil && power.MaxEMC != nil {
+		return fmt.Sprintf("EMC (min: %.1f%%, max: %.1f%%)", *power.MinEMC, *power.MaxEMC)
+	}
 	return "Not specified"
 }
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/base64"
+	"strings"
 	"testing"
 	"time"
 
@@ -32,6 +34,8 @@ func TestFormatDurationLong(t *testing.T) {
 func TestFormatPowerControl(t *testing.T) {
 	power := uint8(60)
 	minDelta, maxDelta := float32(2.5), float32(8.0)
+	minHumidity, maxHumidity := float32(60), float32(70)
+	minEMC, maxEMC := float32(10), float32(11.5)
 
 	tests := []struct {
 		name     string
@@ -44,6 +48,21 @@ func TestFormatPowerControl(t *testing.T) {
 			&types.PowerPidSettings{MinDelta: &minDelta, MaxDelta: &maxDelta},
 			"Delta (min: 2.5°C, max: 8.0°C)",
 		},
+		{
+			"fan delta with a floor",
+			&types.PowerPidSettings{MinDelta: &minDelta, MaxDelta: &maxDelta, MinPower: &power},
+			"Delta (min: 2.5°C, max: 8.0°C, from 60%)",
+		},
+		{
+			"humidity",
+			&types.PowerPidSettings{MinHumidity: &minHumidity, MaxHumidity: &maxHumidity},
+			"Humidity (min: 60%, max: 70%)",
+		},
+		{
+			"emc",
+			&types.PowerPidSettings{MinEMC: &minEMC, MaxEMC: &maxEMC},
+			"EMC (min: 10.0%, max: 11.5%)",
+		},
 		{"nothing set", &types.PowerPidSettings{}, "Not specified"},
 	}
 
@@ -55,3 +74,102 @@ func TestFormatPowerControl(t *testing.T) {
 		})
 	}
 }
+
+func TestDescribeCompliance(t *testing.T) {
+	kilnKey := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	otherKey := base64.StdEncoding.EncodeToString([]byte("fedcba9876543210fedcba9876543210"))
+	executionLog := []byte("time,step,steptime,material\n0,ISPM-15,0,56.5\n1900,Cool,0,57.0\n")
+
+	signed := func(t *testing.T, key string) *types.ComplianceReport {
+		t.Helper()
+		report := &types.ComplianceReport{
+			Run:            "Pallets@2026-10-19T08:00:00Z",
+			Program:        "Pallets",
+			MaterialFusion: types.MaterialFusionMin,
+			HeatTreatments: []types.HeatTreatmentResult{{
+				Step: "ISPM-15", Threshold: 56, RequiredSeconds: 1800, OnDip: types.HoldDipReset,
+				HeldSeconds: 1900, Met: true, MetAt: 1900,
+			}},
+			LogSHA256: types.ComplianceLogDigest(executionLog),
+		}
+		if key == "" {
+			return report
+		}
+		private, err := types.DecodeComplianceKey(key)
+		if err != nil {
+			t.Fatalf("DecodeComplianceKey: %v", err)
+		}
+		if err := report.Sign(private); err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		return report
+	}
+
+	tests := []struct {
+		name   string
+		report func(t *testing.T) *types.ComplianceReport
+		log    []byte
+		want   []string
+	}{
+		{
+			"signed by this kiln",
+			func(t *testing.T) *types.ComplianceReport { return signed(t, kilnKey) },
+			executionLog,
+			[]string{"Result:       COMPLIANT", "Met:               31m 40s into the run", "this kiln's key", "matches the run's log"},
+		},
+		{
+			"signed by another kiln",
+			func(t *testing.T) *types.ComplianceReport { return signed(t, otherKey) },
+			nil,
+			[]string{"NOT this kiln's key", "(not checked)"},
+		},
+		{
+			"changed after signing",
+			func(t *testing.T) *types.ComplianceReport {
+				report := signed(t, kilnKey)
+				report.HeatTreatments[0].HeldSeconds = 3600
+				return report
+			},
+			executionLog,
+			[]string{"Signature:    INVALID"},
+		},
+		{
+			"unsigned, log replaced",
+			func(t *testing.T) *types.ComplianceReport { return signed(t, "") },
+			[]byte("time,step,steptime,material\n0,ISPM-15,0,60.0\n"),
+			[]string{"Signature:    none", "DOES NOT match the run's log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := describeCompliance(tt.report(t), kilnKey, tt.log)
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("expected the description to contain %q, got:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestDescribeStoredProgram(t *testing.T) {
+	run := types.ExecutedProgram{Program: types.Program{StoredProgram: &types.StoredProgramRef{
+		Name: "Oak", Hash: "sha256:0123456789abcdef0123",
+	}}}
+
+	run.StoredProgramState = types.StoredProgramChanged
+	if got, want := describeStoredProgram(run), "Oak (0123456789ab), edited since this run"; got != want {
+		t.Errorf("describeStoredProgram = %q, want %q", got, want)
+	}
+
+	run.StoredProgramRevision = 3
+	if got, want := describeStoredProgram(run), "Oak revision 3 (0123456789ab), edited since this run"; got != want {
+		t.Errorf("describeStoredProgram = %q, want %q", got, want)
+	}
+
+	run.Program.StoredProgram = nil
+	if got := describeStoredProgram(run); got != "" {
+		t.Errorf("describeStoredProgram = %q for a program sent in full, want nothing", got)
+	}
+}
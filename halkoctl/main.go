@@ -59,6 +59,9 @@ func main() {
 			case "stop":
 				showStopHelp()
 				os.Exit(exitSuccess)
+			case "confirm":
+				showConfirmHelp()
+				os.Exit(exitSuccess)
 			case "history":
 				showHistoryHelp()
 				os.Exit(exitSuccess)
@@ -80,6 +83,15 @@ func main() {
 			case "config":
 				showConfigHelp()
 				os.Exit(exitSuccess)
+			case "calibrate":
+				showCalibrateHelp()
+				os.Exit(exitSuccess)
+			case "schedule":
+				showScheduleHelp()
+				os.Exit(exitSuccess)
+			case "storage":
+				showStorageHelp()
+				os.Exit(exitSuccess)
 			case "version":
 				showVersionHelp()
 				os.Exit(exitSuccess)
@@ -124,6 +136,8 @@ func main() {
 		handleRunningCommand()
 	case "stop":
 		handleStopCommand()
+	case "confirm":
+		handleConfirmCommand()
 	case "history":
 		handleHistoryCommand()
 	case "validate":
@@ -136,6 +150,12 @@ func main() {
 		handleProgramsCommand()
 	case "nginx":
 		handleNginxCommand()
+	case "calibrate":
+		handleCalibrateCommand()
+	case "schedule":
+		handleScheduleCommand()
+	case "storage":
+		handleStorageCommand()
 	case "help", "-help", helpFlag:
 		showHelp()
 		os.Exit(exitSuccess)
@@ -149,7 +149,8 @@ server {
         # CORS headers
         add_header Access-Control-Allow-Origin *;
         add_header Access-Control-Allow-Methods "GET, POST, PUT, DELETE, OPTIONS";
-        add_header Access-Control-Allow-Headers "Origin, Content-Type";
+        add_header Access-Control-Allow-Headers "Origin, Content-Type, If-Match";
+        add_header Access-Control-Expose-Headers ETag;
 
         if ($request_method = OPTIONS) {
             return 204;
@@ -168,7 +169,8 @@ server {
         # CORS headers
         add_header Access-Control-Allow-Origin *;
         add_header Access-Control-Allow-Methods "GET, POST, PUT, DELETE, OPTIONS";
-        add_header Access-Control-Allow-Headers "Origin, Content-Type";
+        add_header Access-Control-Allow-Headers "Origin, Content-Type, If-Match";
+        add_header Access-Control-Expose-Headers ETag;
 
         if ($request_method = OPTIONS) {
             return 204;
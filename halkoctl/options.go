@@ -1,9 +1,11 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/rmkhl/halko/types"
@@ -17,7 +19,10 @@ type CommonOptions struct {
 // SendOptions represents options specific to the send command
 type SendOptions struct {
 	CommonOptions
-	ProgramPath string // Path to the program.json file (positional argument)
+	ProgramPath string          // Path to the program.json file (positional argument)
+	Template    string          // Name of a stored template to start instead of a file
+	Values      ParameterValues // Values for the template's parameters
+	Overrides   types.ProgramOverrides
 }
 
 // StatusOptions represents options specific to the status command
@@ -30,7 +35,33 @@ type StatusOptions struct {
 // ValidateOptions represents options specific to the validate command
 type ValidateOptions struct {
 	CommonOptions
-	ProgramPath string // Path to the program.json file (positional argument)
+	ProgramPath string          // Path to the program.json file (positional argument)
+	Values      ParameterValues // Values for the template's parameters
+}
+
+// ParameterValues collects the repeatable --set name=value flag that gives a
+// template's parameters their values.
+type ParameterValues map[string]types.ParameterValue
+
+func (v ParameterValues) String() string {
+	settings := make([]string, 0, len(v))
+	for name, value := range v {
+		settings = append(settings, name+"="+value.String())
+	}
+	return strings.Join(settings, ",")
+}
+
+func (v ParameterValues) Set(setting string) error {
+	name, text, ok := strings.Cut(setting, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("%q is not name=value", setting)
+	}
+	value, err := types.ParseParameterValue(text)
+	if err != nil {
+		return err
+	}
+	v[name] = value
+	return nil
 }
 
 // DisplayOptions represents options specific to the display command
@@ -99,10 +130,32 @@ func SetupCommonFlags(flagSet *flag.FlagSet, opts *CommonOptions) {
 
 // ParseSendOptions parses command-line options for the send command
 func ParseSendOptions() (*SendOptions, error) {
-	opts := &SendOptions{}
+	opts := &SendOptions{Values: ParameterValues{}}
 	sendFlags := flag.NewFlagSet("send", flag.ExitOnError)
 
 	SetupCommonFlags(sendFlags, &opts.CommonOptions)
+	sendFlags.StringVar(&opts.Template, "template", "", "Name of a stored template to start")
+	sendFlags.Var(opts.Values, "set", "Parameter value as name=value (repeatable)")
+	sendFlags.Func("step", "Step field override as step:field=value (repeatable)", func(setting string) error {
+		return overrideStepField(&opts.Overrides, setting)
+	})
+	sendFlags.Func("equalize-delta", "Override the equalize delta", func(value string) error {
+		delta, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return err
+		}
+		equalizeDelta := float32(delta)
+		equalizeOverride(&opts.Overrides).Delta = &equalizeDelta
+		return nil
+	})
+	sendFlags.Func("steam-prewarm", "Override steam prewarm (true or false)", func(value string) error {
+		prewarm, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		equalizeOverride(&opts.Overrides).SteamPrewarm = &prewarm
+		return nil
+	})
 
 	if err := sendFlags.Parse(os.Args[2:]); err != nil {
 		return nil, err
@@ -117,6 +170,42 @@ func ParseSendOptions() (*SendOptions, error) {
 	return opts, nil
 }
 
+// overrideStepField reads a --step override, step:field=value. The value is
+// taken as JSON if it is JSON, so numbers and power settings can be given, and
+// as a string otherwise, so a runtime needs no quotes: "Hold:runtime=9h".
+func overrideStepField(overrides *types.ProgramOverrides, setting string) error {
+	target, value, ok := strings.Cut(setting, "=")
+	step, field, hasField := strings.Cut(target, ":")
+	if !ok || !hasField || step == "" || field == "" {
+		return fmt.Errorf("%q is not step:field=value", setting)
+	}
+
+	raw := json.RawMessage(value)
+	if !json.Valid(raw) {
+		quoted, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		raw = quoted
+	}
+
+	if overrides.Steps == nil {
+		overrides.Steps = map[string]map[string]json.RawMessage{}
+	}
+	if overrides.Steps[step] == nil {
+		overrides.Steps[step] = map[string]json.RawMessage{}
+	}
+	overrides.Steps[step][field] = raw
+	return nil
+}
+
+func equalizeOverride(overrides *types.ProgramOverrides) *types.EqualizeSettings {
+	if overrides.Equalize == nil {
+		overrides.Equalize = &types.EqualizeSettings{}
+	}
+	return overrides.Equalize
+}
+
 // ParseStatusOptions parses command-line options for the status command
 func ParseStatusOptions() (*StatusOptions, error) {
 	opts := &StatusOptions{}
@@ -156,10 +245,11 @@ func ParseStatusOptions() (*StatusOptions, error) {
 
 // ParseValidateOptions parses command-line options for the validate command
 func ParseValidateOptions() (*ValidateOptions, error) {
-	opts := &ValidateOptions{}
+	opts := &ValidateOptions{Values: ParameterValues{}}
 	validateFlags := flag.NewFlagSet("validate", flag.ExitOnError)
 
 	SetupCommonFlags(validateFlags, &opts.CommonOptions)
+	validateFlags.Var(opts.Values, "set", "Parameter value as name=value (repeatable)")
 
 	if err := validateFlags.Parse(os.Args[2:]); err != nil {
 		return nil, err
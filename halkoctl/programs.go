@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"path/filepath"
 	"strings"
@@ -42,6 +44,12 @@ func handleProgramsCommand() {
 		handleProgramUpdateCommand()
 	case "delete":
 		handleProgramDeleteCommand()
+	case "history":
+		handleProgramHistoryCommand()
+	case "diff":
+		handleProgramDiffCommand()
+	case "restore":
+		handleProgramRestoreCommand()
 	default:
 		fmt.Fprintf(os.Stderr, "Error: Unknown programs subcommand '%s'\n\n", subcommand)
 		showProgramsHelp()
@@ -219,14 +227,22 @@ func handleProgramCreateCommand() {
 }
 
 func handleProgramUpdateCommand() {
-	if len(os.Args) < 5 {
+	flags := flag.NewFlagSet("programs update", flag.ExitOnError)
+	note := flags.String("note", "", "What the update changes, kept with the revision it makes")
+	fromRevision := flags.Int("from-revision", 0, "Revision the file was edited from; refuse the update if the program has moved on")
+	force := flags.Bool("force", false, "Replace whatever revision is current")
+	if err := flags.Parse(os.Args[3:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		os.Exit(exitError)
+	}
+	if flags.NArg() < 2 {
 		fmt.Fprintf(os.Stderr, "Error: program name and file path are required\n")
-		fmt.Fprintf(os.Stderr, "Usage: %s programs update <program-name> <program-file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s programs update [--note <text>] (--from-revision <n> | --force) <program-name> <program-file>\n", os.Args[0])
 		os.Exit(exitError)
 	}
 
-	programName := os.Args[3]
-	programPath := os.Args[4]
+	programName := flags.Arg(0)
+	programPath := flags.Arg(1)
 
 	// Read and parse the program file
 	program, err := loadProgramFromFile(programPath)
@@ -240,10 +256,20 @@ func handleProgramUpdateCommand() {
 
 	baseURL := getStorageAPIURL(globalConfig)
 	url := baseURL + globalConfig.APIEndpoints.ControlUnit.Programs + "/" + programName
+	if *note != "" {
+		url += "?note=" + neturl.QueryEscape(*note)
+	}
+
+	ifMatch, err := writeIfMatch(programName, *fromRevision, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
 
 	if globalOpts.Verbose {
 		fmt.Printf("Updating program '%s' from file: %s\n", programName, programPath)
 		fmt.Printf("Storage endpoint: %s\n", url)
+		fmt.Printf("If-Match: %s\n", ifMatch)
 		fmt.Println()
 	}
 
@@ -255,7 +281,14 @@ func handleProgramUpdateCommand() {
 	}
 
 	// Make POST request (the API uses POST for updates)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(programJSON))
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(programJSON))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create request: %v\n", err)
+		os.Exit(exitError)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("If-Match", ifMatch)
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to connect to storage service: %v\n", err)
 		os.Exit(exitError)
@@ -268,6 +301,15 @@ func handleProgramUpdateCommand() {
 		os.Exit(exitError)
 	}
 
+	if resp.StatusCode == http.StatusConflict {
+		resp.Body.Close()
+		var conflict types.ProgramConflict
+		if err := json.Unmarshal(body, &conflict); err == nil && conflict.ETag != "" {
+			fmt.Fprint(os.Stderr, describeConflict(&conflict, program))
+			os.Exit(exitError)
+		}
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		resp.Body.Close()
 		var errorResp types.APIErrorResponse
@@ -283,13 +325,25 @@ func handleProgramUpdateCommand() {
 }
 
 func handleProgramDeleteCommand() {
-	if len(os.Args) < 4 {
+	flags := flag.NewFlagSet("programs delete", flag.ExitOnError)
+	fromRevision := flags.Int("from-revision", 0, "Revision you last saw as the current one; refuse the delete if the program has moved on")
+	force := flags.Bool("force", false, "Delete whatever revision is current")
+	if err := flags.Parse(os.Args[3:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		os.Exit(exitError)
+	}
+	if flags.NArg() != 1 {
 		fmt.Fprintf(os.Stderr, "Error: program name is required\n")
-		fmt.Fprintf(os.Stderr, "Usage: %s programs delete <program-name>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Usage: %s programs delete (--from-revision <n> | --force) <program-name>\n", os.Args[0])
 		os.Exit(exitError)
 	}
 
-	programName := os.Args[3]
+	programName := flags.Arg(0)
+	ifMatch, err := writeIfMatch(programName, *fromRevision, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
 	baseURL := getStorageAPIURL(globalConfig)
 	url := baseURL + globalConfig.APIEndpoints.ControlUnit.Programs + "/" + programName
 
@@ -304,6 +358,7 @@ func handleProgramDeleteCommand() {
 		fmt.Fprintf(os.Stderr, "Failed to create request: %v\n", err)
 		os.Exit(exitError)
 	}
+	req.Header.Set("If-Match", ifMatch)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
@@ -392,16 +447,37 @@ func showProgramsHelp() {
 	fmt.Println("  create <program-file>    Create a new program from JSON file")
 	fmt.Println("  update <name> <file>     Update existing program with new content")
 	fmt.Println("  delete <program-name>    Delete a stored program")
+	fmt.Println("  history <program-name>   List the program's revisions, newest first")
+	fmt.Println("  diff <name> [from] [to]  Show what changed between two revisions")
+	fmt.Println("                           (default: the current one and the one before it)")
+	fmt.Println("  restore <name> <rev>     Make an earlier revision the current one again")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --note <text>            With update or restore: say what changed, kept")
+	fmt.Println("                           with the revision it makes")
+	fmt.Println("  --from-revision <n>      With update, delete or restore: the revision the")
+	fmt.Println("                           change was decided on (update: the one the file was")
+	fmt.Println("                           edited from). If the program has been changed since,")
+	fmt.Println("                           nothing is written; update shows what your file")
+	fmt.Println("                           would change. One of it and --force is required")
+	fmt.Println("  --force                  With update, delete or restore: replace or delete")
+	fmt.Println("                           whatever revision is current")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Printf("  %s programs list\n", os.Args[0])
 	fmt.Printf("  %s programs get my-program\n", os.Args[0])
 	fmt.Printf("  %s programs create example/example-program-delta.json\n", os.Args[0])
-	fmt.Printf("  %s programs update my-program updated-program.json\n", os.Args[0])
-	fmt.Printf("  %s programs delete old-program\n", os.Args[0])
+	fmt.Printf("  %s programs update --from-revision 3 my-program updated-program.json\n", os.Args[0])
+	fmt.Printf("  %s programs delete --from-revision 5 old-program\n", os.Args[0])
+	fmt.Printf("  %s programs update --from-revision 3 --note \"Longer hold for 50mm boards\" my-program updated-program.json\n", os.Args[0])
+	fmt.Printf("  %s programs update --force my-program updated-program.json\n", os.Args[0])
+	fmt.Printf("  %s programs history my-program\n", os.Args[0])
+	fmt.Printf("  %s programs diff my-program 2 4\n", os.Args[0])
+	fmt.Printf("  %s programs restore --from-revision 4 my-program 2\n", os.Args[0])
 	fmt.Println()
 	fmt.Println("Notes:")
 	fmt.Println("  - Program files must be valid JSON")
 	fmt.Println("  - Program names are derived from filenames if not specified in JSON")
+	fmt.Println("  - Every update keeps the version it replaces; restoring is itself an update")
 	fmt.Println("  - Use --verbose for detailed operation information")
 }
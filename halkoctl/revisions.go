@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rmkhl/halko/types"
+)
+
+// ifMatchAny is the If-Match of a write meant to replace whatever version of
+// the program is stored; the control unit refuses a write without one.
+const ifMatchAny = "*"
+
+// errNoRevisionToWriteFrom refuses a write that names no version to make it
+// against, which would silently replace whatever another client saved.
+var errNoRevisionToWriteFrom = errors.New("--from-revision is required: the revision you last saw as the current one (see 'programs history'), or --force to replace whatever is stored")
+
+func handleProgramHistoryCommand() {
+	if len(os.Args) != 4 {
+		fmt.Fprintf(os.Stderr, "Error: program name is required\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s programs history <program-name>\n", os.Args[0])
+		os.Exit(exitError)
+	}
+
+	programName := os.Args[3]
+	var revisions []types.ProgramRevision
+	if err := callControlUnitAPI(http.MethodGet, programURL(programName)+"/revisions", &revisions); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	fmt.Printf("Revisions of '%s':\n", programName)
+	fmt.Print(describeRevisions(revisions))
+}
+
+func handleProgramDiffCommand() {
+	if len(os.Args) < 4 || len(os.Args) > 6 {
+		fmt.Fprintf(os.Stderr, "Error: program name is required\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s programs diff <program-name> [from] [to]\n", os.Args[0])
+		os.Exit(exitError)
+	}
+
+	programName := os.Args[3]
+	query := url.Values{}
+	for i, key := range []string{"from", "to"} {
+		if len(os.Args) <= 4+i {
+			break
+		}
+		if _, err := strconv.Atoi(os.Args[4+i]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s must be a revision number, not '%s'\n", key, os.Args[4+i])
+			os.Exit(exitError)
+		}
+		query.Set(key, os.Args[4+i])
+	}
+
+	target := programURL(programName) + "/diff"
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var diff types.ProgramDiff
+	if err := callControlUnitAPI(http.MethodGet, target, &diff); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	fmt.Printf("Changes to '%s' from revision %d to %d:\n", programName, diff.From, diff.To)
+	if len(diff.Lines) == 0 {
+		fmt.Println("  (none)")
+		return
+	}
+	for _, line := range diff.Lines {
+		fmt.Println(line)
+	}
+}
+
+func handleProgramRestoreCommand() {
+	flags := flag.NewFlagSet("programs restore", flag.ExitOnError)
+	note := flags.String("note", "", "Why the revision is restored (default \"Restored revision N\")")
+	fromRevision := flags.Int("from-revision", 0, "Revision you last saw as the current one; refuse the restore if the program has moved on")
+	force := flags.Bool("force", false, "Restore over whatever revision is current")
+	if err := flags.Parse(os.Args[3:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		os.Exit(exitError)
+	}
+	if flags.NArg() != 2 {
+		fmt.Fprintf(os.Stderr, "Error: program name and revision are required\n")
+		fmt.Fprintf(os.Stderr, "Usage: %s programs restore [--note <text>] (--from-revision <n> | --force) <program-name> <revision>\n", os.Args[0])
+		os.Exit(exitError)
+	}
+
+	programName, revision := flags.Arg(0), flags.Arg(1)
+	if _, err := strconv.Atoi(revision); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: revision must be a number, not '%s'\n", revision)
+		os.Exit(exitError)
+	}
+	ifMatch, err := writeIfMatch(programName, *fromRevision, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	target := programURL(programName) + "/revisions/" + revision + "/restore"
+	if *note != "" {
+		target += "?note=" + url.QueryEscape(*note)
+	}
+	if globalOpts.Verbose {
+		fmt.Printf("Restoring revision %s of program '%s' at: %s\n", revision, programName, target)
+		fmt.Println()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	req.Header.Set("If-Match", ifMatch)
+	var program types.Program
+	if err := doControlUnitRequest(req, &program); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Printf("✓ Revision %s of program '%s' restored as its newest revision\n", revision, programName)
+}
+
+func programURL(programName string) string {
+	return getStorageAPIURL(globalConfig) + globalConfig.APIEndpoints.ControlUnit.Programs + "/" + url.PathEscape(programName)
+}
+
+// callControlUnitAPI makes a request with no body and decodes the data of the
+// response into data, or returns the error the service gave.
+func callControlUnitAPI[T any](method, target string, data *T) error {
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return err
+	}
+	return doControlUnitRequest(req, data)
+}
+
+// doControlUnitRequest is callControlUnitAPI for a request already made.
+func doControlUnitRequest[T any](req *http.Request, data *T) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to storage service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errorResp types.APIErrorResponse
+		if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Err != "" {
+			return errors.New(errorResp.Err)
+		}
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var response types.APIResponse[T]
+	if err := json.Unmarshal(body, &response); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	*data = response.Data
+	return nil
+}
+
+// describeRevisions lists a program's revisions newest first, which is the
+// order they are usually looked for in.
+func describeRevisions(revisions []types.ProgramRevision) string {
+	var out strings.Builder
+	for i := len(revisions) - 1; i >= 0; i-- {
+		revision := revisions[i]
+		marker := " "
+		if revision.Current {
+			marker = "*"
+		}
+		fmt.Fprintf(&out, "%s %3d  %s", marker, revision.Revision, revision.SavedAt)
+		if revision.Note != "" {
+			fmt.Fprintf(&out, "  %s", revision.Note)
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// writeIfMatch is the If-Match for a write to the program: the ETag of
+// fromRevision, the version the write was decided on, or with force, any
+// version at all.
+func writeIfMatch(programName string, fromRevision int, force bool) (string, error) {
+	switch {
+	case force && fromRevision > 0:
+		return "", errors.New("--force and --from-revision cannot be used together")
+	case force:
+		return ifMatchAny, nil
+	case fromRevision > 0:
+		return revisionETag(programName, fromRevision)
+	}
+	return "", errNoRevisionToWriteFrom
+}
+
+// revisionETag is the If-Match for an update made from the revision: the
+// revision's content hash, which is what the program's ETag is while it is
+// still the current one.
+func revisionETag(programName string, revision int) (string, error) {
+	var revisions []types.ProgramRevision
+	if err := callControlUnitAPI(http.MethodGet, programURL(programName)+"/revisions", &revisions); err != nil {
+		return "", err
+	}
+	for _, candidate := range revisions {
+		if candidate.Revision == revision {
+			return types.ProgramETag(candidate.Hash), nil
+		}
+	}
+	return "", fmt.Errorf("program '%s' has no revision %d", programName, revision)
+}
+
+// describeConflict explains an update refused because the program was changed
+// after the revision it was made from: what the update would change in the
+// program as it is now, and how to write it anyway.
+func describeConflict(conflict *types.ProgramConflict, update *types.Program) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "Error: %s\n", conflict.Err)
+	fmt.Fprintf(&out, "It is now at revision %d. Your file against it:\n", conflict.Revision)
+
+	lines, err := types.DiffPrograms(&conflict.Current, update)
+	switch {
+	case err != nil:
+		fmt.Fprintf(&out, "  (cannot compare: %v)\n", err)
+	case len(lines) == 0:
+		out.WriteString("  (none, your file matches it)\n")
+	default:
+		for _, line := range lines {
+			out.WriteString(line + "\n")
+		}
+	}
+	fmt.Fprintf(&out, "Merge what you need, then update with --from-revision %d to replace it.\n", conflict.Revision)
+	return out.String()
+}
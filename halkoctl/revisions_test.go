@@ -0,0 +1,61 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func TestDescribeRevisions(t *testing.T) {
+	revisions := []types.ProgramRevision{
+		{Revision: 1, SavedAt: "2026-03-01T08:00:00Z"},
+		{Revision: 2, SavedAt: "2026-03-04T17:30:00Z", Note: "Longer hold", Current: true},
+	}
+
+	want := "*   2  2026-03-04T17:30:00Z  Longer hold\n" +
+		"    1  2026-03-01T08:00:00Z\n"
+	if got := describeRevisions(revisions); got != want {
+		t.Errorf("describeRevisions =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestDescribeConflict(t *testing.T) {
+	current := types.Program{ProgramName: "Oak", ProgramSteps: []types.ProgramStep{
+		{Name: "heat", StepType: types.StepTypeHeating, TargetTemperature: 60},
+	}}
+	update := current
+	update.ProgramSteps = []types.ProgramStep{{Name: "heat", StepType: types.StepTypeHeating, TargetTemperature: 65}}
+	conflict := &types.ProgramConflict{Err: "program 'Oak' has been changed since it was read", Revision: 4, Current: current}
+
+	got := describeConflict(conflict, &update)
+	for _, want := range []string{
+		"It is now at revision 4",
+		`-      "temperature_target": 60`,
+		`+      "temperature_target": 65`,
+		"--from-revision 4",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("describeConflict lacks %q:\n%s", want, got)
+		}
+	}
+
+	if got := describeConflict(conflict, &current); !strings.Contains(got, "your file matches it") {
+		t.Errorf("describeConflict of the same program:\n%s", got)
+	}
+}
+
+// A write names the revision it was decided on, or says outright with --force
+// that it replaces whatever is stored; never neither.
+func TestWriteIfMatch(t *testing.T) {
+	if got, err := writeIfMatch("Oak", 0, true); err != nil || got != ifMatchAny {
+		t.Errorf("writeIfMatch with --force = %q, %v; want %q", got, err, ifMatchAny)
+	}
+	if _, err := writeIfMatch("Oak", 0, false); !errors.Is(err, errNoRevisionToWriteFrom) {
+		t.Errorf("writeIfMatch without a revision = %v, want %v", err, errNoRevisionToWriteFrom)
+	}
+	if _, err := writeIfMatch("Oak", 3, true); err == nil {
+		t.Error("writeIfMatch took both --force and --from-revision")
+	}
+}
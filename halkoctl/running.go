@@ -117,7 +117,7 @@ func queryRunningProgram() {
 	elapsedTime := int(time.Now().Unix() - result.Data.StartedAt)
 
 	// Find current step to get target temperature and calculate remaining time
-	var targetTemp uint8
+	var targetTemp uint16
 	var remainingTime int
 	var hasRuntime bool
 	for _, step := range result.Data.Program.ProgramSteps {
@@ -146,6 +146,14 @@ func queryRunningProgram() {
 	}
 	fmt.Printf("Current Temp:       %.1f°C\n", result.Data.Temperatures.Material)
 	fmt.Printf("Target Temp:        %d°C\n", targetTemp)
+	if confirmation := result.Data.Confirmation; confirmation != nil {
+		fmt.Printf("Waiting For:        %s\n", confirmation.Prompt)
+		if confirmation.TimeoutSeconds > 0 {
+			fmt.Printf("On Timeout:         %s\n", confirmation.OnTimeout)
+		}
+		fmt.Println()
+		fmt.Printf("Run '%s confirm' once it is done.\n", os.Args[0])
+	}
 }
 
 func formatDuration(seconds int) string {
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/rmkhl/halko/types"
+)
+
+// scheduleSettings are the parts of a schedule a table does not have a
+// column for.
+type scheduleSettings struct {
+	name    string
+	coolTo  uint
+	emcBand float64
+	vent    bool
+}
+
+func handleScheduleCommand() {
+	flags := flag.NewFlagSet("schedule", flag.ExitOnError)
+	var settings scheduleSettings
+	flags.StringVar(&settings.name, "name", "", "Name of the program (required)")
+	flags.UintVar(&settings.coolTo, "cool-to", 0, "Temperature the final cooling step brings the charge down to (required)")
+	flags.Float64Var(&settings.emcBand, "emc-band", types.DefaultScheduleEMCBand, "How far the steam and vent bands reach from each row's EMC")
+	flags.BoolVar(&settings.vent, "vent", false, "Band the vent above each row's EMC as well as the steam below it")
+	output := flags.String("output", "", "File to write the program to (default standard output)")
+	var help bool
+	flags.BoolVar(&help, "h", false, "Show help message")
+	flags.BoolVar(&help, "help", false, "Show help message")
+	if err := flags.Parse(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		os.Exit(exitError)
+	}
+	if help {
+		showScheduleHelp()
+		os.Exit(exitSuccess)
+	}
+	if flags.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Error: schedule table path is required\n\n")
+		showScheduleHelp()
+		os.Exit(exitError)
+	}
+
+	table, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	defer table.Close()
+
+	program, err := importSchedule(table, settings, globalConfig.ControlUnitConfig.Defaults)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	if *output == "" {
+		fmt.Println(string(program))
+		return
+	}
+	if err := os.WriteFile(*output, append(program, '\n'), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if globalOpts.Verbose {
+		fmt.Printf("Wrote %s\n", *output)
+	}
+}
+
+func showScheduleHelp() {
+	fmt.Println("halkoctl schedule - Make a program from a drying schedule table")
+	fmt.Println()
+	fmt.Println("Reads a CSV schedule table and writes a program that runs it: each row")
+	fmt.Println("holds the kiln at its temperature and EMC until the sample boards are")
+	fmt.Println("down to the row's moisture content. The program is checked against the")
+	fmt.Println("defaults in the configuration before it is written.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Printf("  %s [global-options] schedule [options] <table.csv>\n", os.Args[0])
+	fmt.Println()
+	fmt.Println("The first line of the table names its columns: until_moisture and")
+	fmt.Println("temperature, and one of wet_bulb, wet_bulb_depression or emc. Lines")
+	fmt.Println("starting with # are comments.")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  -name string")
+	fmt.Println("        Name of the program (required)")
+	fmt.Println("  -cool-to uint")
+	fmt.Println("        Temperature the final cooling step brings the charge down to (required)")
+	fmt.Println("  -emc-band float")
+	fmt.Printf("        How far the steam and vent bands reach from each row's EMC (default %v)\n", types.DefaultScheduleEMCBand)
+	fmt.Println("  -vent")
+	fmt.Println("        Band the vent above each row's EMC as well as the steam below it")
+	fmt.Println("  -output string")
+	fmt.Println("        File to write the program to (default standard output)")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Printf("  %s schedule -name \"Oak 4/4\" -cool-to 35 -vent t4d2.csv\n", os.Args[0])
+	fmt.Printf("  %s schedule -name Birch -cool-to 30 -output birch.json birch.csv\n", os.Args[0])
+}
+
+// importSchedule turns a schedule table into the program JSON that runs it,
+// refusing one the control unit would refuse.
+func importSchedule(table io.Reader, settings scheduleSettings, defaults *types.Defaults) ([]byte, error) {
+	if settings.name == "" {
+		return nil, errors.New("-name is required")
+	}
+	if settings.coolTo == 0 || settings.coolTo > math.MaxUint16 {
+		return nil, errors.New("-cool-to is required, in whole degrees")
+	}
+
+	schedule, err := types.ParseScheduleCSV(table)
+	if err != nil {
+		return nil, err
+	}
+	band := float32(settings.emcBand)
+	schedule.EMCBand = &band
+	schedule.Vent = settings.vent
+	schedule.CoolTo = uint16(settings.coolTo)
+
+	program := types.Program{ProgramName: settings.name, ProgramSteps: []types.ProgramStep{}, Schedule: schedule}
+	encoded, err := json.MarshalIndent(program, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate a copy: the program written out carries the schedule alone,
+	// not the steps compiling it gives.
+	check := program
+	check.ApplyDefaults(defaults)
+	if err := check.Validate(); err != nil {
+		return nil, fmt.Errorf("schedule does not make a valid program: %w", err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+const oakTable = `until_moisture,temperature,wet_bulb_depression
+30,50,3
+20,50,6
+8,65,22
+`
+
+func templateDefaults(t *testing.T) *types.Defaults {
+	t.Helper()
+	config, err := types.LoadConfig("../templates/halko.cfg")
+	if err != nil {
+		t.Fatalf("Failed to read template config: %v", err)
+	}
+	return config.ControlUnitConfig.Defaults
+}
+
+// The program written out is the schedule, which the control unit compiles
+// the same way the check here did.
+func TestImportScheduleWritesTheSchedule(t *testing.T) {
+	settings := scheduleSettings{name: "Oak 4/4", coolTo: 35, emcBand: 1, vent: true}
+	encoded, err := importSchedule(strings.NewReader(oakTable), settings, templateDefaults(t))
+	if err != nil {
+		t.Fatalf("importSchedule: %v", err)
+	}
+
+	var program types.Program
+	if err := json.Unmarshal(encoded, &program); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if program.ProgramName != "Oak 4/4" || len(program.ProgramSteps) != 0 {
+		t.Fatalf("imported %q with %d steps, want Oak 4/4 with none", program.ProgramName, len(program.ProgramSteps))
+	}
+	if schedule := program.Schedule; schedule == nil || len(schedule.Rows) != 3 || schedule.CoolTo != 35 || !schedule.Vent {
+		t.Fatalf("imported schedule = %+v", program.Schedule)
+	}
+
+	program.ApplyDefaults(templateDefaults(t))
+	if err := program.Validate(); err != nil {
+		t.Fatalf("the imported program does not validate: %v", err)
+	}
+}
+
+func TestImportScheduleRefusesAnInvalidProgram(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings scheduleSettings
+		wantErr  string
+	}{
+		{"no name", scheduleSettings{coolTo: 35, emcBand: 1}, "-name"},
+		{"no cooling target", scheduleSettings{name: "Oak", emcBand: 1}, "-cool-to"},
+		{"cooling above the last row", scheduleSettings{name: "Oak", coolTo: 70, emcBand: 1}, "cooling step temperature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := importSchedule(strings.NewReader(oakTable), tt.settings, templateDefaults(t))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
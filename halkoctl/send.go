@@ -25,8 +25,8 @@ func handleSendCommand() {
 		os.Exit(exitSuccess)
 	}
 
-	if opts.ProgramPath == "" {
-		fmt.Fprintf(os.Stderr, "Error: program file path is required\n\n")
+	if (opts.ProgramPath == "") == (opts.Template == "") {
+		fmt.Fprintf(os.Stderr, "Error: either a program file path or --template is required\n\n")
 		showSendHelp()
 		os.Exit(exitError)
 	}
@@ -34,12 +34,31 @@ func handleSendCommand() {
 	url := getControlUnitAPIURL(globalConfig)
 
 	if globalOpts.Verbose {
-		fmt.Printf("Sending program: %s\n", opts.ProgramPath)
+		if opts.Template != "" {
+			fmt.Printf("Starting template: %s\n", opts.Template)
+		} else {
+			fmt.Printf("Sending program: %s\n", opts.ProgramPath)
+		}
 		fmt.Printf("ControlUnit endpoint: %s\n", url)
 		fmt.Println()
 	}
 
-	err = sendProgram(opts.ProgramPath, url, globalOpts.Verbose)
+	hasOverrides := opts.Overrides.Equalize != nil || len(opts.Overrides.Steps) > 0
+	if hasOverrides && opts.Template == "" {
+		fmt.Fprintf(os.Stderr, "Error: overrides are for a stored program started with --template\n\n")
+		showSendHelp()
+		os.Exit(exitError)
+	}
+
+	if opts.Template != "" {
+		request := types.StartProgramRequest{Template: opts.Template, Values: opts.Values}
+		if hasOverrides {
+			request.Overrides = &opts.Overrides
+		}
+		err = sendTemplate(request, url, globalOpts.Verbose)
+	} else {
+		err = sendProgram(opts.ProgramPath, opts.Values, url, globalOpts.Verbose)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to send program: %v\n", err)
 		os.Exit(exitError)
@@ -52,15 +71,30 @@ func handleSendCommand() {
 func showSendHelp() {
 	fmt.Println("halkoctl send - Send program to controlunit")
 	fmt.Println()
-	fmt.Println("Sends a program.json file to the Halko controlunit to start execution.")
+	fmt.Println("Sends a program.json file to the Halko controlunit to start execution, or")
+	fmt.Println("starts a program or template stored on the controlunit by name, with values")
+	fmt.Println("for its parameters and overrides for this run.")
 	fmt.Println()
 	fmt.Println("Usage:")
-	fmt.Printf("  %s [global-options] send <program-file> [options]\n", os.Args[0])
+	fmt.Printf("  %s [global-options] send [options] <program-file>\n", os.Args[0])
+	fmt.Printf("  %s [global-options] send --template <name> [--set name=value ...]\n", os.Args[0])
 	fmt.Println()
 	fmt.Println("Arguments:")
-	fmt.Println("  program-file      Path to the program.json file to send (required)")
+	fmt.Println("  program-file      Path to the program.json file to send")
 	fmt.Println()
 	fmt.Println("Options:")
+	fmt.Println("  --template string")
+	fmt.Println("        Name of a stored template to start instead of a file")
+	fmt.Println("  --set name=value")
+	fmt.Println("        Value for a template parameter (repeatable); parameters left out")
+	fmt.Println("        take their defaults")
+	fmt.Println("  --step step:field=value")
+	fmt.Println("        Override a step field for this run (repeatable, --template only).")
+	fmt.Println("        The value is JSON, or a string if it is not: Hold:runtime=9h")
+	fmt.Println("  --equalize-delta float")
+	fmt.Println("        Override the equalize delta for this run (--template only)")
+	fmt.Println("  --steam-prewarm bool")
+	fmt.Println("        Override steam prewarm for this run (--template only)")
 	fmt.Println("  -h, --help")
 	fmt.Println("        Show this help message")
 	fmt.Println()
@@ -74,12 +108,14 @@ func showSendHelp() {
 	fmt.Printf("  %s send example/example-program-delta.json\n", os.Args[0])
 	fmt.Printf("  %s --config /path/to/halko.cfg send my-program.json\n", os.Args[0])
 	fmt.Printf("  %s --verbose send my-program.json\n", os.Args[0])
+	fmt.Printf("  %s send --template Oak --set thickness=32 --set final_temp=65\n", os.Args[0])
+	fmt.Printf("  %s send --template Oak --step Hold:runtime=9h --equalize-delta 2.5\n", os.Args[0])
 	fmt.Println()
 	fmt.Println("The program will be sent to the controlunit's POST /engine/running endpoint")
 	fmt.Println("to start immediate execution. The controlunit will validate the program.")
 }
 
-func sendProgram(programPath, controlunitURL string, verbose bool) error {
+func sendProgram(programPath string, values ParameterValues, controlunitURL string, verbose bool) error {
 	if _, err := os.Stat(programPath); os.IsNotExist(err) {
 		return fmt.Errorf("program file does not exist: %s", programPath)
 	}
@@ -112,12 +148,31 @@ func sendProgram(programPath, controlunitURL string, verbose bool) error {
 			program.ProgramName, len(program.ProgramSteps))
 	}
 
-	// Send the program directly without wrapping (consistent with storage endpoints)
-	jsonData, err := json.Marshal(program)
+	// Send the program directly without wrapping (consistent with storage
+	// endpoints); a template's values go alongside its fields.
+	jsonData, err := json.Marshal(struct {
+		types.Program
+		Values ParameterValues `json:"values,omitempty"`
+	}{program, values})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	return postProgram(jsonData, controlunitURL, verbose)
+}
+
+// sendTemplate starts a program or template the controlunit has stored, which
+// renders it with the values given and applies the overrides.
+func sendTemplate(request types.StartProgramRequest, controlunitURL string, verbose bool) error {
+	jsonData, err := json.Marshal(request)
 	if err != nil {
 		return fmt.Errorf("failed to marshal request: %w", err)
 	}
 
+	return postProgram(jsonData, controlunitURL, verbose)
+}
+
+func postProgram(jsonData []byte, controlunitURL string, verbose bool) error {
 	if verbose {
 		fmt.Println("Sending HTTP request...")
 	}
@@ -183,4 +238,10 @@ func displaySendResponse(respBody []byte) {
 
 	fmt.Printf("Started program: %s\n", response.Data.ProgramName)
 	fmt.Printf("Number of steps: %d\n", len(response.Data.ProgramSteps))
+	if run := response.Data.FromTemplate; run != nil {
+		fmt.Printf("Rendered from template: %s\n", run.Name)
+		for name, value := range run.Values {
+			fmt.Printf("  %s = %s\n", name, value)
+		}
+	}
 }
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func handleStorageCommand() {
+	if len(os.Args) < 3 || os.Args[2] != "check" {
+		fmt.Fprintf(os.Stderr, "Error: storage command requires the check subcommand\n\n")
+		showStorageHelp()
+		os.Exit(exitError)
+	}
+
+	flags := flag.NewFlagSet("storage check", flag.ExitOnError)
+	repair := flags.Bool("repair", false, "Repair what the check finds")
+	var help bool
+	flags.BoolVar(&help, "h", false, "Show help message")
+	flags.BoolVar(&help, "help", false, "Show help message")
+	if err := flags.Parse(os.Args[3:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		os.Exit(exitError)
+	}
+	if help {
+		showStorageHelp()
+		os.Exit(exitSuccess)
+	}
+
+	method, target := http.MethodGet, getStorageAPIURL(globalConfig)+"/system/storage"
+	if *repair {
+		method, target = http.MethodPost, target+"/repair"
+	}
+	if globalOpts.Verbose {
+		fmt.Printf("Checking storage at: %s\n", target)
+		fmt.Println()
+	}
+
+	var issues []types.StorageIssue
+	if err := callControlUnitAPI(method, target, &issues); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	fmt.Print(describeStorageIssues(issues, *repair))
+	for _, issue := range issues {
+		if !issue.Repaired {
+			os.Exit(exitError)
+		}
+	}
+}
+
+// describeStorageIssues lists what the check found, or after a repair what
+// was done about it, one line per file.
+func describeStorageIssues(issues []types.StorageIssue, repaired bool) string {
+	if len(issues) == 0 {
+		return "✓ Storage is consistent\n"
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "%d storage issue(s):\n", len(issues))
+	for _, issue := range issues {
+		fmt.Fprintf(&out, "  %-40s %s", issue.Path, describeIssueKind(issue.Kind))
+		switch {
+		case !repaired:
+			fmt.Fprintf(&out, ", repair: %s", issue.Repair)
+		case issue.Repaired:
+			fmt.Fprintf(&out, ", repaired: %s", issue.Repair)
+		default:
+			fmt.Fprintf(&out, ", %s failed: %s", issue.Repair, issue.RepairError)
+		}
+		out.WriteString("\n")
+	}
+	if !repaired {
+		out.WriteString("Run with --repair to repair them.\n")
+	}
+	return out.String()
+}
+
+func describeIssueKind(kind types.StorageIssueKind) string {
+	switch kind {
+	case types.StorageIssueStranded:
+		return "left in running/ by a finished run"
+	case types.StorageIssueNoState:
+		return "run has no state"
+	case types.StorageIssueOrphaned:
+		return "run's program is gone"
+	}
+	return string(kind)
+}
+
+func showStorageHelp() {
+	fmt.Println("halkoctl storage - Check the control unit's data directory")
+	fmt.Println()
+	fmt.Println("Looks for runs whose files are not all where they belong: files left in")
+	fmt.Println("running/ by a move to history that was cut short, runs without a state,")
+	fmt.Println("and logs, states or events of runs whose program was deleted by hand.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Printf("  %s [global-options] storage check [--repair]\n", os.Args[0])
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  --repair")
+	fmt.Println("        Repair what is found: move stranded files into history, mark a")
+	fmt.Println("        missing state as unknown, and move orphaned files to archive/.")
+	fmt.Println("        Nothing is deleted. Refused while a program is running")
+	fmt.Println()
+	fmt.Println("Exits with 1 while there are issues left unrepaired.")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Printf("  %s storage check\n", os.Args[0])
+	fmt.Printf("  %s storage check --repair\n", os.Args[0])
+}
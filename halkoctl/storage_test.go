@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func TestDescribeStorageIssues(t *testing.T) {
+	if got := describeStorageIssues(nil, false); got != "✓ Storage is consistent\n" {
+		t.Errorf("describeStorageIssues of none = %q", got)
+	}
+
+	issues := []types.StorageIssue{
+		{Run: "Oak-1", Kind: types.StorageIssueNoState, Path: "history/status/Oak-1.txt", Repair: types.StorageRepairMarkUnknown},
+	}
+	want := "1 storage issue(s):\n" +
+		"  history/status/Oak-1.txt                 run has no state, repair: mark_unknown\n" +
+		"Run with --repair to repair them.\n"
+	if got := describeStorageIssues(issues, false); got != want {
+		t.Errorf("describeStorageIssues =\n%s\nwant\n%s", got, want)
+	}
+
+	issues[0].RepairError = "permission denied"
+	want = "1 storage issue(s):\n" +
+		"  history/status/Oak-1.txt                 run has no state, mark_unknown failed: permission denied\n"
+	if got := describeStorageIssues(issues, true); got != want {
+		t.Errorf("describeStorageIssues after a repair =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -136,8 +137,15 @@ func getTemperatures(sensorunitURL string, verbose bool) error {
 		return nil
 	}
 
-	// Display temperatures in a formatted way
-	for name, temp := range response.Data {
+	// Display temperatures in a formatted way, in name order so each fused
+	// value is followed by the per-probe readings it came from
+	names := make([]string, 0, len(response.Data))
+	for name := range response.Data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		temp := response.Data[name]
 		if temp == types.InvalidTemperatureReading {
 			fmt.Printf("  %s: Invalid reading\n", formatTemperatureName(name))
 		} else {
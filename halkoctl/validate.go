@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/rmkhl/halko/types"
 )
@@ -36,7 +40,7 @@ func handleValidateCommand() {
 		fmt.Println()
 	}
 
-	err = validateProgram(opts.ProgramPath, globalOpts.Verbose)
+	err = validateProgram(opts.ProgramPath, opts.Values, globalOpts.Verbose)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Validation failed: %v\n", err)
 		os.Exit(exitError)
@@ -50,6 +54,8 @@ func showValidateHelp() {
 	fmt.Println("halkoctl validate - Validate a Halko program file")
 	fmt.Println()
 	fmt.Println("This command validates a program.json file against the Halko program schema and business rules.")
+	fmt.Println("A program that includes stored programs fetches them from the control unit.")
+	fmt.Println("A template is rendered with the --set values, and its defaults for the rest.")
 	fmt.Println()
 	fmt.Println("Usage:")
 	fmt.Printf("  %s [global-options] validate <program-file> [options]\n", os.Args[0])
@@ -58,6 +64,8 @@ func showValidateHelp() {
 	fmt.Println("  program-file      Path to the program.json file to validate (required)")
 	fmt.Println()
 	fmt.Println("Options:")
+	fmt.Println("  --set name=value")
+	fmt.Println("        Value for a template parameter (repeatable)")
 	fmt.Println("  -h, --help")
 	fmt.Println("        Show this help message")
 	fmt.Println()
@@ -71,9 +79,10 @@ func showValidateHelp() {
 	fmt.Printf("  %s validate example/example-program-delta.json\n", os.Args[0])
 	fmt.Printf("  %s --config /path/to/halko.cfg validate my-program.json\n", os.Args[0])
 	fmt.Printf("  %s --verbose validate my-program.json\n", os.Args[0])
+	fmt.Printf("  %s validate --set thickness=32 --set final_temp=65 oak-template.json\n", os.Args[0])
 }
 
-func validateProgram(programPath string, verbose bool) error {
+func validateProgram(programPath string, values ParameterValues, verbose bool) error {
 	if globalConfig == nil {
 		return errors.New("no configuration loaded - this should not happen")
 	}
@@ -120,10 +129,39 @@ func validateProgram(programPath string, verbose bool) error {
 			program.ProgramName, len(program.ProgramSteps))
 	}
 
+	if program.IsTemplate() || len(values) > 0 {
+		if verbose {
+			fmt.Println("Rendering template...")
+		}
+		err = program.Render(values)
+		if err != nil {
+			return fmt.Errorf("failed to render template: %w", err)
+		}
+		if verbose {
+			fmt.Println("✓ Template rendered successfully")
+		}
+	}
+
+	if program.HasBlocks() {
+		if verbose {
+			fmt.Println("Expanding repeats and includes...")
+		}
+		err = program.ExpandSteps(fetchStoredProgram)
+		if err != nil {
+			return fmt.Errorf("failed to expand program: %w", err)
+		}
+		if verbose {
+			fmt.Printf("✓ Program expanded to %d steps\n", len(program.ProgramSteps))
+		}
+	}
+
 	if verbose {
 		fmt.Println("Applying defaults...")
 	}
 
+	// Applying the defaults compiles a schedule into steps and drops it, but
+	// the description still says where the steps came from.
+	schedule := program.Schedule
 	program.ApplyDefaults(config.ControlUnitConfig.Defaults)
 
 	if verbose {
@@ -140,12 +178,44 @@ func validateProgram(programPath string, verbose bool) error {
 		fmt.Println("✓ Program validation completed successfully")
 
 		fmt.Println()
+		program.Schedule = schedule
 		fmt.Print(describeProgram(&program))
 	}
 
 	return nil
 }
 
+// fetchStoredProgram loads an included program from the control unit, which
+// is where the program being validated will find it when it is started.
+func fetchStoredProgram(name string) (*types.Program, error) {
+	url := getStorageAPIURL(globalConfig) + globalConfig.APIEndpoints.ControlUnit.Programs + "/" + name
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to storage service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var errorResp types.APIErrorResponse
+		if err := json.Unmarshal(body, &errorResp); err == nil {
+			return nil, errors.New(errorResp.Err)
+		}
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	var response types.APIResponse[types.Program]
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return &response.Data, nil
+}
+
 // describeProgram renders what a program actually contains, for `validate
 // --verbose`. The description is operator-written and may run to several
 // lines, so its continuation lines are indented under the first.
@@ -163,6 +233,20 @@ func describeProgram(program *types.Program) string {
 			fmt.Fprintf(&out, "               %s\n", line)
 		}
 	}
+	if program.Schedule != nil {
+		fmt.Fprintf(&out, "  Schedule: %d rows, compiled into the steps below\n", len(program.Schedule.Rows))
+	}
+	if program.FromTemplate != nil {
+		names := make([]string, 0, len(program.FromTemplate.Values))
+		for name := range program.FromTemplate.Values {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		out.WriteString("  Parameters:\n")
+		for _, name := range names {
+			fmt.Fprintf(&out, "    %s = %s\n", name, program.FromTemplate.Values[name])
+		}
+	}
 	fmt.Fprintf(&out, "  Steps: %d\n", len(program.ProgramSteps))
 	for i, step := range program.ProgramSteps {
 		fmt.Fprintf(&out, "    %d. %s (%s) - Target: %d°C\n",
@@ -170,6 +254,9 @@ func describeProgram(program *types.Program) string {
 		if step.Runtime != nil {
 			fmt.Fprintf(&out, "       Runtime: %s\n", step.Runtime.String())
 		}
+		if step.Hold != nil && step.Hold.Duration != nil {
+			fmt.Fprintf(&out, "       Hold: %.1f°C for %s, %s on dip\n", step.Hold.Threshold, step.Hold.Duration.String(), step.Hold.OnDip)
+		}
 	}
 
 	return out.String()
@@ -3,6 +3,7 @@ package main
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/rmkhl/halko/types"
 )
@@ -38,3 +39,68 @@ func TestDescribeProgramOmitsAnAbsentDescription(t *testing.T) {
 		t.Errorf("description of a program without one still has a Description line:\n%s", out)
 	}
 }
+
+func TestDescribeProgramMentionsASchedule(t *testing.T) {
+	program := describedProgram()
+	program.Schedule = &types.DryingSchedule{Rows: make([]types.ScheduleRow, 3)}
+
+	if out := describeProgram(program); !strings.Contains(out, "Schedule: 3 rows") {
+		t.Errorf("description of a scheduled program does not mention the schedule:\n%s", out)
+	}
+}
+
+func TestDescribeProgramListsTemplateValues(t *testing.T) {
+	program := describedProgram()
+	program.FromTemplate = &types.TemplateRun{Name: "birch", Values: map[string]types.ParameterValue{
+		"thickness": types.NumberValue(50),
+		"soak":      types.DurationValue(90 * time.Minute),
+	}}
+
+	out := describeProgram(program)
+	if !strings.Contains(out, "    soak = 1h30m0s\n    thickness = 50\n") {
+		t.Errorf("description of a rendered template does not list its values in order:\n%s", out)
+	}
+}
+
+func TestParameterValuesFlag(t *testing.T) {
+	values := ParameterValues{}
+	for _, setting := range []string{"thickness=32", "soak=90m"} {
+		if err := values.Set(setting); err != nil {
+			t.Fatalf("Set(%q): %v", setting, err)
+		}
+	}
+	if values["thickness"].Number() != 32 || values["soak"].Duration() != 90*time.Minute {
+		t.Errorf("values = %v, want thickness 32 and soak 90m", values)
+	}
+
+	for _, setting := range []string{"thickness", "=32", "thickness=thick"} {
+		if err := values.Set(setting); err == nil {
+			t.Errorf("Set(%q) accepted", setting)
+		}
+	}
+}
+
+func TestOverrideStepField(t *testing.T) {
+	var overrides types.ProgramOverrides
+	for _, setting := range []string{"Hold:runtime=9h", "Hold:temperature_target=65", `Heat:fan={"power":40}`} {
+		if err := overrideStepField(&overrides, setting); err != nil {
+			t.Fatalf("overrideStepField(%q): %v", setting, err)
+		}
+	}
+
+	for _, tt := range []struct{ step, field, want string }{
+		{"Hold", "runtime", `"9h"`},
+		{"Hold", "temperature_target", `65`},
+		{"Heat", "fan", `{"power":40}`},
+	} {
+		if got := string(overrides.Steps[tt.step][tt.field]); got != tt.want {
+			t.Errorf("%s %s = %s, want %s", tt.step, tt.field, got, tt.want)
+		}
+	}
+
+	for _, setting := range []string{"Hold=9h", "runtime=9h", ":runtime=9h", "Hold:runtime"} {
+		if err := overrideStepField(&overrides, setting); err == nil {
+			t.Errorf("overrideStepField(%q) accepted", setting)
+		}
+	}
+}
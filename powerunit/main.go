@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
 	"time"
 
@@ -43,9 +44,12 @@ func main() {
 		cycleLength, maxIdleTime, powerMapping)
 
 	idMapping := [shelly.NumberOfDevices]string{}
+	devices := make([]int, 0, len(powerMapping))
 	for name, id := range powerMapping {
 		idMapping[id] = name
+		devices = append(devices, id)
 	}
+	slices.Sort(devices)
 	log.Trace("Created ID mapping: %v", idMapping)
 
 	port, err := configuration.APIEndpoints.PowerUnit.GetPort()
@@ -55,10 +59,17 @@ func main() {
 	serverAddr := ":" + port
 	log.Debug("Server will listen on %s", serverAddr)
 
-	p := power.New(maxIdleTime, cycleLength, shellyController)
+	p := power.New(maxIdleTime, cycleLength, shellyController, devices)
 	log.Trace("Created power controller")
 
-	r := router.New(p, powerMapping, idMapping, configuration.APIEndpoints)
+	rules := power.NewRules(configuration.PowerUnit)
+	if rules == nil {
+		log.Info("No power limits or interlocks configured")
+	} else {
+		log.Info("Power rules: limits=%v, interlocks=%v", configuration.PowerUnit.Limits, configuration.PowerUnit.Interlocks)
+	}
+
+	r := router.New(p, rules, powerMapping, idMapping, configuration.APIEndpoints)
 	log.Trace("Created HTTP router")
 
 	log.Info("Starting power unit server on %s", serverAddr)
@@ -16,7 +16,8 @@ type (
 	}
 
 	Controller struct {
-		powerStates  [shelly.NumberOfDevices]*powerTracker // Fixed array for power states with IDs 0, 1, and 2
+		powerStates  [shelly.NumberOfDevices]*powerTracker // Fixed array for power states with IDs 0 to 3
+		devices      []int                                 // Switch ids in the power mapping; the rest are never switched
 		mu           sync.RWMutex
 		ctx          context.Context
 		cancel       context.CancelFunc
@@ -30,7 +31,10 @@ type (
 	}
 )
 
-func New(maxIdleTime time.Duration, cycleLength time.Duration, shellyCtrl *shelly.Shelly) *Controller {
+// New returns a controller switching the given devices, the ids in the power
+// mapping. A kiln without a vent leaves a switch id unused, which on a
+// three-output Shelly does not exist.
+func New(maxIdleTime time.Duration, cycleLength time.Duration, shellyCtrl *shelly.Shelly, devices []int) *Controller {
 	log.Trace("Creating new power controller")
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -47,6 +51,7 @@ func New(maxIdleTime time.Duration, cycleLength time.Duration, shellyCtrl *shell
 
 	controller := &Controller{
 		powerStates:  powerStates,
+		devices:      devices,
 		ctx:          ctx,
 		cancel:       cancel,
 		cycleLength:  cycleLength,
@@ -71,7 +76,7 @@ func (c *Controller) Start() error {
 	maxRetries := 5
 	retryDelay := 500 * time.Millisecond
 
-	for i := range shelly.NumberOfDevices {
+	for _, i := range c.devices {
 		success := false
 		for attempt := 0; attempt < maxRetries; attempt++ {
 			if _, err := c.shelly.SetState(shelly.Off, i); err != nil {
@@ -129,7 +134,7 @@ func (c *Controller) processTick() error {
 
 	if c.tickCount == 0 {
 		log.Trace("Beginning of cycle (tick 0) - checking devices to turn on")
-		for id := range shelly.NumberOfDevices {
+		for _, id := range c.devices {
 			tracker := c.powerStates[id]
 			if tracker.percentage > 0 && tracker.currentState == shelly.Off {
 				log.Debug("Turning on device %d (percentage: %d%%)", id, tracker.percentage)
@@ -142,7 +147,7 @@ func (c *Controller) processTick() error {
 		}
 	}
 
-	for id := range shelly.NumberOfDevices {
+	for _, id := range c.devices {
 		tracker := c.powerStates[id]
 		// Turn off devices when tickCount reaches their percentage (including 0%)
 		if c.tickCount >= int(tracker.percentage) && tracker.currentState == shelly.On {
@@ -187,7 +192,7 @@ func (c *Controller) Stop() {
 	log.Info("Stopping power controller and shutting down all devices")
 	c.cancel()
 
-	if err := c.shelly.Shutdown(); err != nil {
+	if err := c.shelly.Shutdown(c.devices); err != nil {
 		log.Error("Error shutting down devices: %v", err)
 	} else {
 		log.Debug("All devices shut down successfully")
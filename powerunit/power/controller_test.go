@@ -57,7 +57,7 @@ func newTestController(t *testing.T, maxIdleTime time.Duration) (*Controller, *r
 	server := httptest.NewServer(recorder.handler())
 	t.Cleanup(server.Close)
 
-	c := New(maxIdleTime, 100*time.Second, shelly.New(server.URL))
+	c := New(maxIdleTime, 100*time.Second, shelly.New(server.URL), []int{0, 1, 2, 3})
 	t.Cleanup(c.cancel)
 
 	for i := range shelly.NumberOfDevices {
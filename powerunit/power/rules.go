@@ -0,0 +1,114 @@
+package power
+
+import (
+	"fmt"
+
+	"github.com/rmkhl/halko/powerunit/shelly"
+	"github.com/rmkhl/halko/types"
+)
+
+type (
+	interlock struct {
+		channel    int
+		requires   int
+		minPercent uint8
+	}
+
+	// Rules are the configured limits and interlocks every command is checked
+	// against before it reaches the controller. They protect the kiln from
+	// combinations the control unit should never ask for but could: steam or
+	// the heater running with no air moving past them.
+	//
+	// A nil *Rules has no rules and passes every command through unchanged.
+	Rules struct {
+		names      [shelly.NumberOfDevices]string
+		limits     [shelly.NumberOfDevices]uint8
+		interlocks []interlock
+	}
+
+	// InterlockError is returned for a command that would switch a channel on
+	// while a channel it requires is below its minimum.
+	InterlockError struct {
+		Reason string
+	}
+)
+
+func (e *InterlockError) Error() string {
+	return e.Reason
+}
+
+// NewRules resolves the validated power unit configuration against the device
+// ids. It returns nil when nothing is configured.
+func NewRules(config *types.PowerUnit) *Rules {
+	if len(config.Limits) == 0 && len(config.Interlocks) == 0 {
+		return nil
+	}
+
+	r := &Rules{}
+	for id := range shelly.NumberOfDevices {
+		r.limits[id] = 100
+	}
+	for name, id := range config.PowerMapping {
+		r.names[id] = name
+	}
+	for name, limit := range config.Limits {
+		r.limits[config.PowerMapping[name]] = uint8(limit)
+	}
+	for _, rule := range config.Interlocks {
+		r.interlocks = append(r.interlocks, interlock{
+			channel:    config.PowerMapping[rule.Channel],
+			requires:   config.PowerMapping[rule.Requires],
+			minPercent: uint8(rule.MinPercent),
+		})
+	}
+	return r
+}
+
+// Apply checks the requested percentages against the rules, given the ones in
+// force now, and returns what should actually be applied along with one
+// sentence for every change it made.
+//
+// Limits clamp. An interlock refuses a command that raises the dependent
+// channel while its requirement is unmet, since obeying it is impossible and
+// quietly doing something else would hide that. A command that lowers the
+// requirement is never refused: switching something off is always allowed, so
+// anything that depended on it is switched off with it instead. Refusing it
+// would leave the kiln running in exactly the state the interlock forbids.
+//
+// Forcing a channel off can break a rule that depends on that channel in turn,
+// so the interlocks are applied until nothing changes.
+func (r *Rules) Apply(current, requested [shelly.NumberOfDevices]uint8) ([shelly.NumberOfDevices]uint8, []string, error) {
+	if r == nil {
+		return requested, nil, nil
+	}
+
+	applied := requested
+	var adjustments []string
+
+	for id := range shelly.NumberOfDevices {
+		if applied[id] > r.limits[id] {
+			adjustments = append(adjustments, fmt.Sprintf("%s limited to %d%% (asked for %d%%)",
+				r.names[id], r.limits[id], applied[id]))
+			applied[id] = r.limits[id]
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, rule := range r.interlocks {
+			if applied[rule.channel] == 0 || applied[rule.requires] >= rule.minPercent {
+				continue
+			}
+			if applied[rule.channel] > current[rule.channel] {
+				return current, nil, &InterlockError{Reason: fmt.Sprintf("%s requires %s at %d%% or more, %s would be at %d%%",
+					r.names[rule.channel], r.names[rule.requires], rule.minPercent, r.names[rule.requires], applied[rule.requires])}
+			}
+			adjustments = append(adjustments, fmt.Sprintf("%s switched off: it requires %s at %d%% or more, %s is at %d%%",
+				r.names[rule.channel], r.names[rule.requires], rule.minPercent, r.names[rule.requires], applied[rule.requires]))
+			applied[rule.channel] = 0
+			changed = true
+		}
+	}
+
+	return applied, adjustments, nil
+}
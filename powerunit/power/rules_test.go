@@ -0,0 +1,125 @@
+package power
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rmkhl/halko/powerunit/shelly"
+	"github.com/rmkhl/halko/types"
+)
+
+// Device ids as the test power_mapping assigns them.
+const (
+	heaterID = 0
+	fanID    = 1
+	steamID  = 2
+)
+
+func testRules(limits map[string]int, interlocks ...types.PowerInterlock) *Rules {
+	return NewRules(&types.PowerUnit{
+		PowerMapping: map[string]int{"heater": heaterID, "fan": fanID, "steam": steamID},
+		Limits:       limits,
+		Interlocks:   interlocks,
+	})
+}
+
+var heaterNeedsFan = types.PowerInterlock{Channel: "heater", Requires: "fan", MinPercent: 30}
+
+func TestNoRulesPassEveryCommandThrough(t *testing.T) {
+	rules := testRules(nil)
+	if rules != nil {
+		t.Fatal("expected an empty configuration to produce no rules")
+	}
+
+	requested := [shelly.NumberOfDevices]uint8{100, 0, 100}
+	applied, adjustments, err := rules.Apply([shelly.NumberOfDevices]uint8{}, requested)
+	if err != nil || applied != requested || adjustments != nil {
+		t.Fatalf("expected %v unchanged, got %v %v %v", requested, applied, adjustments, err)
+	}
+}
+
+func TestLimitClampsTheChannel(t *testing.T) {
+	rules := testRules(map[string]int{"steam": 60})
+
+	applied, adjustments, err := rules.Apply([shelly.NumberOfDevices]uint8{}, [shelly.NumberOfDevices]uint8{0, 50, 100})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if want := [shelly.NumberOfDevices]uint8{0, 50, 60}; applied != want {
+		t.Fatalf("expected %v, got %v", want, applied)
+	}
+	if len(adjustments) != 1 || !strings.Contains(adjustments[0], "steam limited to 60%") {
+		t.Fatalf("expected the clamp to be reported, got %v", adjustments)
+	}
+}
+
+func TestInterlockRefusesSwitchingOnWithoutTheRequirement(t *testing.T) {
+	rules := testRules(nil, heaterNeedsFan)
+	current := [shelly.NumberOfDevices]uint8{0, 10, 0}
+
+	applied, _, err := rules.Apply(current, [shelly.NumberOfDevices]uint8{80, 10, 0})
+
+	var interlockErr *InterlockError
+	if !errors.As(err, &interlockErr) {
+		t.Fatalf("expected an InterlockError, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "heater requires fan at 30% or more") {
+		t.Fatalf("expected the rule in the error, got %q", err.Error())
+	}
+	if applied != current {
+		t.Fatalf("a refused command must leave %v in force, got %v", current, applied)
+	}
+}
+
+func TestInterlockAllowsTheChannelOnceTheRequirementIsMet(t *testing.T) {
+	rules := testRules(nil, heaterNeedsFan)
+
+	requested := [shelly.NumberOfDevices]uint8{80, 30, 0}
+	applied, adjustments, err := rules.Apply([shelly.NumberOfDevices]uint8{0, 30, 0}, requested)
+	if err != nil || applied != requested || len(adjustments) != 0 {
+		t.Fatalf("expected %v applied as sent, got %v %v %v", requested, applied, adjustments, err)
+	}
+}
+
+// Stopping the fan under a running heater must work - it is how everything
+// gets switched off - so the heater goes off with it rather than the stop
+// being refused.
+func TestLoweringTheRequirementSwitchesTheDependentOff(t *testing.T) {
+	rules := testRules(nil, heaterNeedsFan)
+
+	applied, adjustments, err := rules.Apply([shelly.NumberOfDevices]uint8{80, 50, 0}, [shelly.NumberOfDevices]uint8{80, 0, 0})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if want := [shelly.NumberOfDevices]uint8{0, 0, 0}; applied != want {
+		t.Fatalf("expected %v, got %v", want, applied)
+	}
+	if len(adjustments) != 1 || !strings.Contains(adjustments[0], "heater switched off") {
+		t.Fatalf("expected the switch-off to be reported, got %v", adjustments)
+	}
+}
+
+func TestInterlocksChain(t *testing.T) {
+	steamNeedsHeater := types.PowerInterlock{Channel: "steam", Requires: "heater", MinPercent: 10}
+	rules := testRules(nil, steamNeedsHeater, heaterNeedsFan)
+
+	applied, adjustments, err := rules.Apply([shelly.NumberOfDevices]uint8{50, 50, 50}, [shelly.NumberOfDevices]uint8{50, 0, 50})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if want := [shelly.NumberOfDevices]uint8{0, 0, 0}; applied != want {
+		t.Fatalf("expected the fan to take the heater and then the steam with it, got %v (%v)", applied, adjustments)
+	}
+}
+
+// A limit that clamps the requirement below an interlock's minimum counts as
+// the requirement being unmet.
+func TestLimitAppliesBeforeInterlocks(t *testing.T) {
+	rules := testRules(map[string]int{"fan": 20}, heaterNeedsFan)
+
+	_, _, err := rules.Apply([shelly.NumberOfDevices]uint8{}, [shelly.NumberOfDevices]uint8{50, 100, 0})
+	if err == nil {
+		t.Fatal("expected the heater to be refused with the fan limited below its minimum")
+	}
+}
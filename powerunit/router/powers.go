@@ -2,6 +2,7 @@ package router
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/rmkhl/halko/powerunit/power"
@@ -30,6 +31,10 @@ func getAllPercentages(p *power.Controller, idMapping [shelly.NumberOfDevices]st
 
 		response := make(types.PowerStatusResponse)
 		for id := range shelly.NumberOfDevices {
+			// A kiln without a vent leaves a switch unmapped.
+			if idMapping[id] == "" {
+				continue
+			}
 			response[idMapping[id]] = types.PowerResponse{Percent: percentages[id]}
 		}
 		log.Debug("Returning power status: %v", response)
@@ -38,7 +43,26 @@ func getAllPercentages(p *power.Controller, idMapping [shelly.NumberOfDevices]st
 	}
 }
 
-func setAllPercentages(p *power.Controller, powerMapping map[string]int) http.HandlerFunc {
+// applyRules runs a command past the limits and interlocks and writes the
+// refusal itself when they reject it, reporting whether the caller should go on.
+func applyRules(w http.ResponseWriter, rules *power.Rules, current, requested [shelly.NumberOfDevices]uint8) ([shelly.NumberOfDevices]uint8, []string, bool) {
+	applied, adjustments, err := rules.Apply(current, requested)
+	if err != nil {
+		var interlockErr *power.InterlockError
+		if errors.As(err, &interlockErr) {
+			writeError(w, http.StatusConflict, "Refused by interlock: "+interlockErr.Reason)
+			return current, nil, false
+		}
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return current, nil, false
+	}
+	for _, adjustment := range adjustments {
+		log.Warning("Power command adjusted: %s", adjustment)
+	}
+	return applied, adjustments, true
+}
+
+func setAllPercentages(p *power.Controller, rules *power.Rules, powerMapping map[string]int) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log.Trace("POST /power request from %s", r.RemoteAddr)
 		var commands types.PowersCommand
@@ -64,20 +88,29 @@ func setAllPercentages(p *power.Controller, powerMapping map[string]int) http.Ha
 				return
 			}
 			percentages[id] = command.Percent
-			if currentPercentages[id] != command.Percent {
-				log.Info("Power percentage for %s updated to %d%% (was %d%%)", powerName, command.Percent, currentPercentages[id])
+		}
+
+		// A refused command is not forwarded at all, so it does not refresh
+		// the idle watchdog either: nothing it asked for was done.
+		applied, adjustments, ok := applyRules(w, rules, currentPercentages, percentages)
+		if !ok {
+			return
+		}
+		for powerName, id := range powerMapping {
+			if currentPercentages[id] != applied[id] {
+				log.Info("Power percentage for %s updated to %d%% (was %d%%)", powerName, applied[id], currentPercentages[id])
 			} else {
-				log.Trace("Power percentage for %s unchanged at %d%%", powerName, command.Percent)
+				log.Trace("Power percentage for %s unchanged at %d%%", powerName, applied[id])
 			}
 		}
 
 		// Always forward, even when nothing changed: a command is what tells the
 		// controller the control unit is still alive, and steps that hold a
 		// constant power would otherwise starve the idle watchdog.
-		p.SetAllPercentages(percentages)
+		p.SetAllPercentages(applied)
 
 		writeJSON(w, http.StatusOK, types.APIResponse[types.PowerOperationResponse]{
-			Data: types.PowerOperationResponse{Message: "completed"},
+			Data: types.PowerOperationResponse{Message: "completed", Adjustments: adjustments},
 		})
 	}
 }
@@ -103,7 +136,7 @@ func getPercentage(p *power.Controller, powerMapping map[string]int) http.Handle
 	}
 }
 
-func setPercentage(p *power.Controller, powerMapping map[string]int) http.HandlerFunc {
+func setPercentage(p *power.Controller, rules *power.Rules, powerMapping map[string]int) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		powerName := r.PathValue("power")
 		log.Trace("POST /power/%s request from %s", powerName, r.RemoteAddr)
@@ -124,22 +157,30 @@ func setPercentage(p *power.Controller, powerMapping map[string]int) http.Handle
 		}
 		log.Debug("Received power command for %s: %d%%", powerName, command.Percent)
 
-		percentages := p.GetAllPercentages()
-		currentPercent := percentages[id]
+		currentPercentages := p.GetAllPercentages()
+		percentages := currentPercentages
 		percentages[id] = command.Percent
 
+		applied, adjustments, ok := applyRules(w, rules, currentPercentages, percentages)
+		if !ok {
+			return
+		}
+
 		// Forwarded unconditionally so that a repeated command still refreshes
 		// the idle watchdog. See setAllPercentages.
-		p.SetAllPercentages(percentages)
+		p.SetAllPercentages(applied)
 
-		if currentPercent != command.Percent {
-			log.Info("Power percentage for %s updated to %d%% (was %d%%)", powerName, command.Percent, currentPercent)
+		if currentPercentages[id] != applied[id] {
+			log.Info("Power percentage for %s updated to %d%% (was %d%%)", powerName, applied[id], currentPercentages[id])
 		} else {
-			log.Debug("Power percentage for %s unchanged at %d%%", powerName, command.Percent)
+			log.Debug("Power percentage for %s unchanged at %d%%", powerName, applied[id])
 		}
 
+		// The percentage reported is the one applied, which a limit may have
+		// clamped; the adjustments also name any other channel an interlock
+		// switched off as a consequence of this one.
 		writeJSON(w, http.StatusOK, types.APIResponse[types.PowerResponse]{
-			Data: types.PowerResponse(command),
+			Data: types.PowerResponse{Percent: applied[id], Adjustments: adjustments},
 		})
 	}
 }
@@ -29,20 +29,25 @@ var (
 // real (if unattached) Shelly endpoint, so requests travel the whole path.
 func newTestRouter(t *testing.T) (http.Handler, *power.Controller) {
 	t.Helper()
+	return newTestRouterWithRules(t, nil)
+}
+
+func newTestRouterWithRules(t *testing.T, rules *power.Rules) (http.Handler, *power.Controller) {
+	t.Helper()
 
 	shellyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		_, _ = w.Write([]byte(`{"output":false}`))
 	}))
 	t.Cleanup(shellyServer.Close)
 
-	controller := power.New(time.Hour, 100*time.Second, shelly.New(shellyServer.URL))
+	controller := power.New(time.Hour, 100*time.Second, shelly.New(shellyServer.URL), []int{0, 1, 2})
 	t.Cleanup(controller.Stop)
 
 	endpoints := &types.APIEndpoints{}
 	endpoints.PowerUnit.Power = "/power"
 	endpoints.PowerUnit.Status = "/status"
 
-	return New(controller, testPowerMapping, testIDMapping, endpoints), controller
+	return New(controller, rules, testPowerMapping, testIDMapping, endpoints), controller
 }
 
 func do(t *testing.T, handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
@@ -86,6 +91,11 @@ func TestGetAllPercentagesNamesEveryDevice(t *testing.T) {
 			t.Fatalf("expected %s at %d%%, got %d%%", name, want, got.Percent)
 		}
 	}
+	// The fourth switch is unmapped, as on a kiln without a vent, and must
+	// not appear under an empty name.
+	if len(response.Data) != len(testPowerMapping) {
+		t.Fatalf("expected only the mapped devices, got %v", response.Data)
+	}
 }
 
 // Regression: a command naming only some devices used to zero the rest, so
@@ -245,3 +255,86 @@ func TestRepeatedCommandStillReachesTheController(t *testing.T) {
 		}
 	})
 }
+
+func heaterNeedsFanRules() *power.Rules {
+	return power.NewRules(&types.PowerUnit{
+		PowerMapping: testPowerMapping,
+		Limits:       map[string]int{steam: 60},
+		Interlocks:   []types.PowerInterlock{{Channel: heater, Requires: fan, MinPercent: 30}},
+	})
+}
+
+func TestInterlockRefusalIsAConflictAndMovesNothing(t *testing.T) {
+	for _, tc := range []struct{ name, target, body string }{
+		{"single device", "/power/heater", `{"percent":80}`},
+		{"bulk", "/power", `{"heater":{"percent":80}}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, controller := newTestRouterWithRules(t, heaterNeedsFanRules())
+			controller.SetAllPercentages([shelly.NumberOfDevices]uint8{0, 10, 0})
+
+			rec := do(t, handler, http.MethodPost, tc.target, tc.body)
+			if rec.Code != http.StatusConflict {
+				t.Fatalf("expected 409, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			var response types.APIErrorResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if !strings.Contains(response.Err, "heater requires fan at 30% or more") {
+				t.Fatalf("expected the interlock named in the error, got %q", response.Err)
+			}
+
+			want := [shelly.NumberOfDevices]uint8{0, 10, 0}
+			if got := controller.GetAllPercentages(); got != want {
+				t.Fatalf("expected %v to be untouched, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestLimitedCommandReportsTheAppliedPercentage(t *testing.T) {
+	handler, controller := newTestRouterWithRules(t, heaterNeedsFanRules())
+
+	rec := do(t, handler, http.MethodPost, "/power/steam", `{"percent":100}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response types.APIResponse[types.PowerResponse]
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if response.Data.Percent != 60 || len(response.Data.Adjustments) != 1 {
+		t.Fatalf("expected 60%% with the clamp reported, got %+v", response.Data)
+	}
+	if got := controller.GetAllPercentages()[2]; got != 60 {
+		t.Fatalf("expected steam at 60%%, got %d%%", got)
+	}
+}
+
+// Switching the fan off must never be refused, even under a running heater;
+// the heater goes off with it and the response says so.
+func TestStoppingTheRequirementSwitchesTheDependentOff(t *testing.T) {
+	handler, controller := newTestRouterWithRules(t, heaterNeedsFanRules())
+	controller.SetAllPercentages([shelly.NumberOfDevices]uint8{80, 50, 0})
+
+	rec := do(t, handler, http.MethodPost, "/power/fan", `{"percent":0}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var response types.APIResponse[types.PowerResponse]
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(response.Data.Adjustments) != 1 || !strings.Contains(response.Data.Adjustments[0], "heater switched off") {
+		t.Fatalf("expected the heater switch-off reported, got %+v", response.Data)
+	}
+
+	want := [shelly.NumberOfDevices]uint8{0, 0, 0}
+	if got := controller.GetAllPercentages(); got != want {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
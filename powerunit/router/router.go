@@ -9,11 +9,11 @@ import (
 	"github.com/rmkhl/halko/types/log"
 )
 
-func New(p *power.Controller, powerMapping map[string]int, idMapping [shelly.NumberOfDevices]string, endpoints *types.APIEndpoints) http.Handler {
+func New(p *power.Controller, rules *power.Rules, powerMapping map[string]int, idMapping [shelly.NumberOfDevices]string, endpoints *types.APIEndpoints) http.Handler {
 	log.Trace("Creating HTTP router")
 	mux := http.NewServeMux()
 
-	setupRoutes(mux, p, powerMapping, idMapping, endpoints)
+	setupRoutes(mux, p, rules, powerMapping, idMapping, endpoints)
 	log.Debug("HTTP routes configured")
 
 	handler := addCORSHeaders(mux)
@@ -26,12 +26,12 @@ func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-func setupRoutes(mux *http.ServeMux, p *power.Controller, powerMapping map[string]int, idMapping [shelly.NumberOfDevices]string, endpoints *types.APIEndpoints) {
+func setupRoutes(mux *http.ServeMux, p *power.Controller, rules *power.Rules, powerMapping map[string]int, idMapping [shelly.NumberOfDevices]string, endpoints *types.APIEndpoints) {
 	mux.HandleFunc("GET "+endpoints.PowerUnit.Power, corsMiddleware(getAllPercentages(p, idMapping)))
-	mux.HandleFunc("POST "+endpoints.PowerUnit.Power, corsMiddleware(setAllPercentages(p, powerMapping)))
+	mux.HandleFunc("POST "+endpoints.PowerUnit.Power, corsMiddleware(setAllPercentages(p, rules, powerMapping)))
 	mux.HandleFunc("GET "+endpoints.PowerUnit.Power+"/{power}", corsMiddleware(getPercentage(p, powerMapping)))
-	mux.HandleFunc("POST "+endpoints.PowerUnit.Power+"/{power}", corsMiddleware(setPercentage(p, powerMapping)))
-	mux.HandleFunc("PUT "+endpoints.PowerUnit.Power+"/{power}", corsMiddleware(setPercentage(p, powerMapping)))
-	mux.HandleFunc("PATCH "+endpoints.PowerUnit.Power+"/{power}", corsMiddleware(setPercentage(p, powerMapping)))
+	mux.HandleFunc("POST "+endpoints.PowerUnit.Power+"/{power}", corsMiddleware(setPercentage(p, rules, powerMapping)))
+	mux.HandleFunc("PUT "+endpoints.PowerUnit.Power+"/{power}", corsMiddleware(setPercentage(p, rules, powerMapping)))
+	mux.HandleFunc("PATCH "+endpoints.PowerUnit.Power+"/{power}", corsMiddleware(setPercentage(p, rules, powerMapping)))
 	mux.HandleFunc("GET "+endpoints.PowerUnit.Status, corsMiddleware(getStatus(p)))
 }
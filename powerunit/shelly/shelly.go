@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/rmkhl/halko/types"
 	"github.com/rmkhl/halko/types/log"
 )
 
@@ -16,7 +17,7 @@ const (
 	On      PowerState = "on"
 	Unknown PowerState = "unknown"
 
-	NumberOfDevices = 3 // Number of devices controlled by Shelly
+	NumberOfDevices = types.PowerUnitSwitches // Number of devices controlled by Shelly
 )
 
 type Shelly struct {
@@ -119,14 +120,17 @@ func (s *Shelly) SetState(state PowerState, id int) (PowerState, error) {
 	return state, nil
 }
 
-func (s *Shelly) Shutdown() error {
-	log.Info("Shutting down all Shelly devices")
-	for id := range NumberOfDevices {
+// Shutdown switches off the given devices, stopping at the first that fails.
+// Only the devices in use are named: asking a three-output Shelly for its
+// fourth is an error.
+func (s *Shelly) Shutdown(devices []int) error {
+	log.Info("Shutting down Shelly devices %v", devices)
+	for _, id := range devices {
 		if _, err := s.SetState(Off, id); err != nil {
 			log.Error("Failed to shut down device %d: %v", id, err)
 			return fmt.Errorf("failed to shut down device %d: %w", id, err)
 		}
 	}
-	log.Debug("All Shelly devices shut down successfully")
+	log.Debug("Shelly devices shut down successfully")
 	return nil
 }
@@ -183,7 +183,9 @@ func TestSetStateSurfacesTheDeviceMessage(t *testing.T) {
 	}
 }
 
-func TestShutdownTurnsOffEveryDevice(t *testing.T) {
+// A three-output Shelly leaves the last switch id unmapped, and asking it for
+// that one is an error, so only the named devices are switched off.
+func TestShutdownTurnsOffTheNamedDevices(t *testing.T) {
 	var (
 		mu    sync.Mutex
 		calls []string
@@ -196,12 +198,12 @@ func TestShutdownTurnsOffEveryDevice(t *testing.T) {
 		_, _ = w.Write([]byte(`{}`))
 	})
 
-	if err := s.Shutdown(); err != nil {
+	if err := s.Shutdown([]int{0, 1, 2}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if len(calls) != NumberOfDevices {
-		t.Fatalf("expected %d calls, got %d: %v", NumberOfDevices, len(calls), calls)
+	if len(calls) != 3 {
+		t.Fatalf("expected 3 calls, got %d: %v", len(calls), calls)
 	}
 	for id, call := range calls {
 		want := strconv.Itoa(id) + "=false"
@@ -232,7 +234,7 @@ func TestShutdownStopsAtTheFirstFailure(t *testing.T) {
 		_, _ = w.Write([]byte(`{}`))
 	})
 
-	if err := s.Shutdown(); err == nil {
+	if err := s.Shutdown([]int{0, 1, 2}); err == nil {
 		t.Fatal("expected an error when a device fails to switch off, got nil")
 	}
 
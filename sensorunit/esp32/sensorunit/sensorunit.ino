@@ -3,19 +3,32 @@
 // ESP32 replacement for Arduino Nano with improved SPI handling
 // WiFi and Bluetooth disabled for power savings and simplicity
 //
-// Read temperatures from 3 MAX31855 thermocouples and display
-// them on an I2C OLED display. Values can be queried via USB serial port.
+// Read temperatures from 3 to 5 MAX31855 thermocouples (two in the kiln air,
+// one to three in the wood) and display them on an I2C OLED display. Values can be queried via USB serial port.
 // Status line can be updated via serial command.
 //
 // Commands (identical to Arduino version):
 // - "show <text>" - Set the status line text
 // - "addr <text>" - Set the IP address line text
 // - "read" - Read the current temperature values, followed by the cold
-//            junction (chip die) temperature each one is referenced to
+//            junction (chip die) temperature each one is referenced to.
+//            Extra material probes are named Wood2 and Wood3. A failed
+//            thermocouple reads "NaN:<bits>", the MAX31855 fault bits
+//            (1 open, 2 short to GND, 4 short to VCC; 0 no answer).
 // - "helo" - Respond with "helo" (initial handshake)
+// - "inpt" - Report the safety inputs as "EStop=0|1,Door=0|1,Confirm=0|1", 1
+//            meaning the emergency stop is pressed, the door is open or the
+//            confirm button has been pressed since the last "inpt"
+// - "clim" - Report the humidity sensor as "Humidity=XX.XX%,WetBulb=XX.XXC",
+//            each "NaN" when the fitted sensor does not measure it, it has
+//            failed, or there is no sensor (HUMIDITY_SENSOR below)
+// - "mois" - Report the moisture pins as "MC1=XX.XX%,...,MC4=XX.XX%" on the
+//            reference scale, before temperature and species correction,
+//            each "NaN" when the pair is not fitted (MOISTURE_PROBES below)
+//            or out of range
 //
 // Hardware: ESP32 DevKit (Micro-USB)
-// Sensors: 3x MAX31855 thermocouple amplifiers (K-type thermocouples)
+// Sensors: 3-5x MAX31855 thermocouple amplifiers (K-type thermocouples)
 // Display: 0.96" or 1.3" I2C OLED (SSD1306 or SH1106)
 //
 // Libraries required:
@@ -32,6 +45,21 @@
 // - SDA: GPIO21
 // - SCL: GPIO22
 //
+// Safety inputs (normally closed to GND, internal pull-up):
+// - Emergency stop: GPIO25
+// - Door switch:    GPIO26
+//
+// Confirm button (normally open to GND, internal pull-up):
+// - Confirm:        GPIO13
+//
+// Humidity sensor (optional, one of):
+// - SHT3x on the I2C bus at 0x44
+// - Wet-bulb MAX31855: CS GPIO33
+//
+// Moisture pins (optional, up to 4 pairs):
+// - Excitation: GPIO4
+// - Sense:      GPIO36, GPIO39, GPIO34, GPIO35
+//
 
 #include <Adafruit_GFX.h>
 #include <Adafruit_SSD1306.h>
@@ -50,38 +78,103 @@
 
 Adafruit_SSD1306 display(SCREEN_WIDTH, SCREEN_HEIGHT, &Wire, OLED_RESET);
 
+// Number of thermocouples in the wood, 1 to 3. A board with more than one
+// reports them as Wood, Wood2 and Wood3 and the sensor unit combines them
+// as sensorunit.material_fusion says; set this to what is fitted, since an
+// unfitted module reads as a permanently failed probe.
+#define MATERIAL_PROBES 1
+#define SENSOR_COUNT (2 + MATERIAL_PROBES)
+
 // MAX31855 Chip Select pins
 #define KILN_PRIMARY_CS   5
 #define KILN_SECONDARY_CS 17
 #define WOOD_CS           16
+#define WOOD2_CS          27
+#define WOOD3_CS          32
 
 // Sensor unit identifiers
 #define KILN_PRIMARY   0
 #define KILN_SECONDARY 1
 #define WOOD           2
 
-const int cs_pin[3] = {KILN_PRIMARY_CS, KILN_SECONDARY_CS, WOOD_CS};
+// The display has room for three columns: both kiln probes and the first
+// material probe.
+#define DISPLAYED_SENSORS 3
+
+const int cs_pin[5] = {KILN_PRIMARY_CS, KILN_SECONDARY_CS, WOOD_CS, WOOD2_CS, WOOD3_CS};
+
+// Safety inputs. Both switches are wired normally closed to GND with the
+// internal pull-up enabled, so a closed (safe) switch reads LOW. A pressed
+// stop, an open door and a broken wire all read HIGH: the input fails towards
+// stopping the kiln rather than towards ignoring it.
+#define ESTOP_PIN 25
+#define DOOR_PIN  26
+
+// The operator's confirm button, for a program step waiting on them. Unlike
+// the safety inputs it is normally open: a broken wire must read as nobody
+// pressing it. A press is latched in loop() until "inpt" reports it, since the
+// control unit only asks every few seconds and a press is over in a moment.
+#define CONFIRM_PIN 13
+bool confirm_pressed = false;
+
+// Humidity sensor. HUMIDITY_SHT3X is an SHT3x on the display's I2C bus,
+// reporting relative humidity; HUMIDITY_WET_BULB is one more MAX31855 whose
+// thermocouple sits under a wetted wick in the fan's air stream. The sensor
+// unit works the rest out against the kiln temperature. Set this to what is
+// fitted and set sensorunit.humidity_sensor in the configuration to match.
+#define HUMIDITY_NONE     0
+#define HUMIDITY_SHT3X    1
+#define HUMIDITY_WET_BULB 2
+#define HUMIDITY_SENSOR   HUMIDITY_NONE
+
+#define SHT3X_ADDRESS 0x44
+#define WET_BULB_CS   33
+
+// Moisture pins. Each pair of stainless pins driven into a sample board is
+// the top half of a divider: GPIO4 excites all of them, and each pair's far
+// pin goes to ground through MOISTURE_REFERENCE_OHMS and, through a unity
+// gain buffer with a high impedance input, to its sense pin. The sense pins
+// are input-only ADC1 pins. Set MOISTURE_PROBES to the number of pairs fitted
+// (0 to 4) and sensorunit.moisture_probes.count in the configuration to match.
+#define MOISTURE_PROBES         0
+#define MOISTURE_EXCITE_PIN     4
+#define MOISTURE_REFERENCE_OHMS 100e6
+#define MOISTURE_SETTLE_MS      50
+const int moisture_pin[4] = {36, 39, 34, 35};
+
+// The reference scale: log10 of a pair's resistance in ohms against the
+// moisture content it means, from 7% to 25%, in steps of 1%. These are
+// approximately the published curve for Douglas fir at room temperature,
+// which is what meter species tables are relative to; replace them with the
+// calibration of the meter being matched. Outside the table a reading is
+// out of range: drier than 7% the divider cannot resolve the resistance, and
+// wetter than 25% it barely changes with the moisture.
+#define MOISTURE_TABLE_MIN 7
+const float moisture_log_ohms[] = {
+    10.35, 9.68, 9.22, 8.80, 8.42, 8.08, 7.78, 7.52, 7.27, 7.05,
+    6.85, 6.66, 6.49, 6.33, 6.18, 6.04, 5.90, 5.78, 5.66};
+#define MOISTURE_TABLE_SIZE (sizeof(moisture_log_ohms) / sizeof(moisture_log_ohms[0]))
 
 // MAX31855 fault bits (D2..D0 of the data frame)
 #define FAULT_OPEN 0x1  // thermocouple circuit broken
 #define FAULT_GND  0x2  // thermocouple shorted/leaking to ground
 #define FAULT_VCC  0x4  // thermocouple shorted to supply
 
-const char * const sensorName[3] = {"KilnPrimary", "KilnSecondary", "Wood"};
-const char * const dieName[3] = {"KilnPrimaryDie", "KilnSecondaryDie", "WoodDie"};
+const char * const sensorName[5] = {"KilnPrimary", "KilnSecondary", "Wood", "Wood2", "Wood3"};
+const char * const dieName[5] = {"KilnPrimaryDie", "KilnSecondaryDie", "WoodDie", "Wood2Die", "Wood3Die"};
 
-float temperature[3] = {0.0, 0.0, 0.0};
-bool is_valid[3] = {false, false, false};
-uint8_t last_fault[3] = {0, 0, 0};
+float temperature[SENSOR_COUNT];
+bool is_valid[SENSOR_COUNT];
+uint8_t last_fault[SENSOR_COUNT];
 char addr_text[32] = "";
-uint16_t fault_total[3] = {0, 0, 0};
+uint16_t fault_total[SENSOR_COUNT];
 
 // Cold junction (chip die) temperature per sensor. The MAX31855 references
 // the thermocouple voltage to this, so when it and the screw terminals drift
 // apart every reading on the board shifts by the difference. Reporting it is
 // the only way to tell that apart from the kiln actually changing.
-float die_temperature[3] = {0.0, 0.0, 0.0};
-bool die_valid[3] = {false, false, false};
+float die_temperature[SENSOR_COUNT];
+bool die_valid[SENSOR_COUNT];
 
 // One SPI transaction returns the whole 32-bit MAX31855 frame, so the
 // temperature and the fault bits always come from the same conversion.
@@ -166,18 +259,162 @@ unsigned long previousMillis = 0;
 // fault-bit glitches (e.g. from lead noise) keep the last good value.
 #define FAULT_LIMIT 4
 
-float measurement[3][SAMPLE_COUNT];
-int sample_index[3] = {0, 0, 0};
-bool seeded[3] = {false, false, false};
-int fault_count[3] = {0, 0, 0};
+float measurement[SENSOR_COUNT][SAMPLE_COUNT];
+int sample_index[SENSOR_COUNT];
+bool seeded[SENSOR_COUNT];
+int fault_count[SENSOR_COUNT];
 
 // The die reading gets the same treatment, but on its own counters: it
 // survives thermocouple faults, so it goes stale only when the chip stops
 // answering altogether.
-float die_measurement[3][SAMPLE_COUNT];
-int die_sample_index[3] = {0, 0, 0};
-bool die_seeded[3] = {false, false, false};
-int die_fault_count[3] = {0, 0, 0};
+float die_measurement[SENSOR_COUNT][SAMPLE_COUNT];
+int die_sample_index[SENSOR_COUNT];
+bool die_seeded[SENSOR_COUNT];
+int die_fault_count[SENSOR_COUNT];
+
+// The humidity reading, NAN until the sensor has answered and again after
+// FAULT_LIMIT failed reads in a row, like a thermocouple.
+float humidity = NAN;
+float wet_bulb = NAN;
+int humidity_fault_count = 0;
+
+// CRC-8 of an SHT3x data word: polynomial 0x31, initial value 0xFF.
+uint8_t sht3xCrc(const uint8_t *data)
+{
+    uint8_t crc = 0xFF;
+    for (int i = 0; i < 2; i++)
+    {
+        crc ^= data[i];
+        for (int bit = 0; bit < 8; bit++)
+        {
+            crc = (crc & 0x80) ? (crc << 1) ^ 0x31 : crc << 1;
+        }
+    }
+    return crc;
+}
+
+// One single-shot, high repeatability measurement without clock stretching,
+// which takes up to 15 ms. Returns the relative humidity in percent, or NAN
+// when the sensor does not answer or the checksum is wrong.
+float readSht3xHumidity()
+{
+    Wire.beginTransmission(SHT3X_ADDRESS);
+    Wire.write(0x24);
+    Wire.write(0x00);
+    if (Wire.endTransmission() != 0)
+    {
+        return NAN;
+    }
+    delay(16);
+
+    uint8_t data[6];
+    if (Wire.requestFrom(SHT3X_ADDRESS, 6) != 6)
+    {
+        return NAN;
+    }
+    for (int i = 0; i < 6; i++)
+    {
+        data[i] = Wire.read();
+    }
+    // Bytes 0-2 are the temperature word and its CRC, 3-5 the humidity.
+    if (sht3xCrc(data + 3) != data[5])
+    {
+        return NAN;
+    }
+    uint16_t raw = (data[3] << 8) | data[4];
+    return 100.0f * raw / 65535.0f;
+}
+
+// Reads whichever humidity sensor is fitted, keeping the last good value
+// through transient failures.
+void readHumiditySensor()
+{
+    float value = NAN;
+#if HUMIDITY_SENSOR == HUMIDITY_SHT3X
+    value = readSht3xHumidity();
+#elif HUMIDITY_SENSOR == HUMIDITY_WET_BULB
+    uint32_t raw = readRawFrame(WET_BULB_CS);
+    if (raw != 0 && (raw & 0x7) == 0)
+    {
+        value = ((int32_t)raw >> 18) * 0.25f;
+    }
+#else
+    return;
+#endif
+
+    if (isnan(value))
+    {
+        if (humidity_fault_count < FAULT_LIMIT)
+        {
+            humidity_fault_count++;
+        }
+        if (humidity_fault_count >= FAULT_LIMIT)
+        {
+            humidity = NAN;
+            wet_bulb = NAN;
+        }
+        return;
+    }
+    humidity_fault_count = 0;
+#if HUMIDITY_SENSOR == HUMIDITY_SHT3X
+    humidity = value;
+#else
+    wet_bulb = value;
+#endif
+}
+
+// The moisture pins' readings on the reference scale, NAN for a pair out of
+// range or not fitted.
+float moisture[4] = {NAN, NAN, NAN, NAN};
+
+// Converts a pair's resistance to the reference scale by interpolating in
+// the table, or NAN outside it.
+float moistureFromResistance(float ohms)
+{
+    if (ohms <= 0)
+    {
+        return NAN;
+    }
+    float log_ohms = log10(ohms);
+    for (int i = 0; i + 1 < MOISTURE_TABLE_SIZE; i++)
+    {
+        float high = moisture_log_ohms[i];
+        float low = moisture_log_ohms[i + 1];
+        if (log_ohms <= high && log_ohms >= low)
+        {
+            return MOISTURE_TABLE_MIN + i + (high - log_ohms) / (high - low);
+        }
+    }
+    return NAN;
+}
+
+// Reads every fitted pair. The pins are only excited while being read: a
+// steady current through wet wood polarises the pins and drifts the reading.
+void readMoisturePins()
+{
+    if (MOISTURE_PROBES == 0)
+    {
+        return;
+    }
+    digitalWrite(MOISTURE_EXCITE_PIN, HIGH);
+    delay(MOISTURE_SETTLE_MS);
+    for (int i = 0; i < MOISTURE_PROBES; i++)
+    {
+        uint32_t millivolts = 0;
+        for (int sample = 0; sample < 16; sample++)
+        {
+            millivolts += analogReadMilliVolts(moisture_pin[i]);
+        }
+        float sense = millivolts / 16.0f;
+        if (sense < 1.0f || sense >= 3299.0f)
+        {
+            moisture[i] = NAN;
+            continue;
+        }
+        moisture[i] = moistureFromResistance(MOISTURE_REFERENCE_OHMS * (3300.0f - sense) / sense);
+    }
+    digitalWrite(MOISTURE_EXCITE_PIN, LOW);
+}
 
 float medianOfSamples(const float *samples)
 {
@@ -229,7 +466,7 @@ void displayTemperatures()
 
     // Row 1: temperatures in large font (size 2, 16px tall)
     display.setTextSize(2);
-    for (int i = 0; i < 3; i++) {
+    for (int i = 0; i < DISPLAYED_SENSORS; i++) {
         display.setCursor(colX[i], 0);
         if (is_valid[i]) {
             display.print(lroundf(temperature[i]));
@@ -246,7 +483,7 @@ void displayTemperatures()
 
     // Row 2: labels in small font (size 1, 8px tall) at y=17
     display.setTextSize(1);
-    for (int i = 0; i < 3; i++) {
+    for (int i = 0; i < DISPLAYED_SENSORS; i++) {
         display.setCursor(colX[i], 17);
         display.print(labels[i]);
     }
@@ -281,14 +518,14 @@ void displayTemperatures()
 
     display.setCursor(0, 48);
     display.print("F");
-    for (int i = 0; i < 3; i++) {
+    for (int i = 0; i < DISPLAYED_SENSORS; i++) {
         display.setCursor(diagX[i], 48);
         display.print(fault_total[i] > 9999 ? 9999 : fault_total[i]);
     }
 
     display.setCursor(0, 56);
     display.print("L");
-    for (int i = 0; i < 3; i++) {
+    for (int i = 0; i < DISPLAYED_SENSORS; i++) {
         display.setCursor(diagX[i], 56);
         if (last_fault[i] & FAULT_GND) {
             display.print("GND");
@@ -355,15 +592,18 @@ void processSerial()
         }
         else if (strcmp(command, "read") == 0)
         {
-            // Three thermocouple readings, then the three cold junction
-            // readings they are referenced to, in one line.
-            for (int i = 0; i < 3; i++)
+            // The thermocouple readings, then the cold junction readings
+            // they are referenced to in the same order, in one line.
+            for (int i = 0; i < SENSOR_COUNT; i++)
             {
                 Serial.print(sensorName[i]);
                 Serial.print("=");
                 if (!is_valid[i])
                 {
-                    Serial.print("NaN");
+                    // Why it failed goes along with it: the sensor unit
+                    // cannot see the display that would otherwise say.
+                    Serial.print("NaN:");
+                    Serial.print(last_fault[i]);
                 }
                 else
                 {
@@ -372,7 +612,7 @@ void processSerial()
                 }
                 Serial.print(",");
             }
-            for (int i = 0; i < 3; i++)
+            for (int i = 0; i < SENSOR_COUNT; i++)
             {
                 Serial.print(dieName[i]);
                 Serial.print("=");
@@ -385,7 +625,7 @@ void processSerial()
                     Serial.print(die_temperature[i]);
                     Serial.print("C");
                 }
-                if (i < 2)
+                if (i < SENSOR_COUNT - 1)
                 {
                     Serial.print(",");
                 }
@@ -395,6 +635,60 @@ void processSerial()
                 }
             }
         }
+        else if (strcmp(command, "inpt") == 0)
+        {
+            // Sampled on request rather than in loop(): the answer has to
+            // describe the switch now, not as of the last sensor cycle.
+            Serial.print("EStop=");
+            Serial.print(digitalRead(ESTOP_PIN) == HIGH ? 1 : 0);
+            Serial.print(",Door=");
+            Serial.print(digitalRead(DOOR_PIN) == HIGH ? 1 : 0);
+            Serial.print(",Confirm=");
+            Serial.println(confirm_pressed ? 1 : 0);
+            confirm_pressed = false;
+        }
+        else if (strcmp(command, "clim") == 0)
+        {
+            Serial.print("Humidity=");
+            if (isnan(humidity))
+            {
+                Serial.print("NaN");
+            }
+            else
+            {
+                Serial.print(humidity);
+                Serial.print("%");
+            }
+            Serial.print(",WetBulb=");
+            if (isnan(wet_bulb))
+            {
+                Serial.println("NaN");
+            }
+            else
+            {
+                Serial.print(wet_bulb);
+                Serial.println("C");
+            }
+        }
+        else if (strcmp(command, "mois") == 0)
+        {
+            for (int i = 0; i < 4; i++)
+            {
+                Serial.print(i == 0 ? "MC" : ",MC");
+                Serial.print(i + 1);
+                Serial.print("=");
+                if (isnan(moisture[i]))
+                {
+                    Serial.print("NaN");
+                }
+                else
+                {
+                    Serial.print(moisture[i]);
+                    Serial.print("%");
+                }
+            }
+            Serial.println();
+        }
         else if (strcmp(command, "helo") == 0)
         {
             Serial.println("helo");
@@ -435,12 +729,24 @@ void setup()
 
     // Initialize SPI and the MAX31855 chip selects (idle high)
     SPI.begin();
-    for (int i = 0; i < 3; i++)
+    for (int i = 0; i < SENSOR_COUNT; i++)
     {
         pinMode(cs_pin[i], OUTPUT);
         digitalWrite(cs_pin[i], HIGH);
     }
 
+#if HUMIDITY_SENSOR == HUMIDITY_WET_BULB
+    pinMode(WET_BULB_CS, OUTPUT);
+    digitalWrite(WET_BULB_CS, HIGH);
+#endif
+
+    pinMode(ESTOP_PIN, INPUT_PULLUP);
+    pinMode(DOOR_PIN, INPUT_PULLUP);
+    pinMode(CONFIRM_PIN, INPUT_PULLUP);
+
+    pinMode(MOISTURE_EXCITE_PIN, OUTPUT);
+    digitalWrite(MOISTURE_EXCITE_PIN, LOW);
+
     // Wait for sensors to stabilize
     delay(500);
 
@@ -449,7 +755,7 @@ void setup()
     displayTemperatures();
 
     Serial.println("Initialization complete");
-    Serial.println("Commands: helo; read; show TEXT; addr TEXT;");
+    Serial.println("Commands: helo; read; inpt; clim; mois; show TEXT; addr TEXT;");
 }
 
 void loop()
@@ -458,6 +764,11 @@ void loop()
 
     unsigned long currentMillis = millis();
 
+    if (digitalRead(CONFIRM_PIN) == LOW)
+    {
+        confirm_pressed = true;
+    }
+
     // Process serial commands
     if (Serial.available())
     {
@@ -519,10 +830,15 @@ void loop()
         }
 
         // Sensor counter drives display refresh timing
-        current_sensor = (current_sensor + 1) % 3;
+        current_sensor = (current_sensor + 1) % SENSOR_COUNT;
 
         if (current_sensor == 0)
         {
+            // Once per sweep of the thermocouples is plenty for air
+            // humidity, and keeps the SHT3x's conversion wait out of
+            // every cycle.
+            readHumiditySensor();
+            readMoisturePins();
             displayTemperatures();
         }
 
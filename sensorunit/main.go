@@ -58,7 +58,36 @@ func main() {
 		return
 	}
 
-	api := router.NewAPI(sensorUnit)
+	log.Info("Kiln fusion: %s, material fusion: %s", halkoConfig.SensorUnit.KilnFusion, halkoConfig.SensorUnit.MaterialFusion)
+	log.Info("Kiln probes degraded after reading more than %.1f°C apart for %s",
+		halkoConfig.SensorUnit.KilnDisagreement.Threshold, halkoConfig.SensorUnit.KilnDisagreement.WindowDuration)
+	if len(halkoConfig.SensorUnit.MaterialWeights) > 0 {
+		log.Info("Material probe weights: %v", halkoConfig.SensorUnit.MaterialWeights)
+	}
+	for _, probe := range types.TemperatureProbes {
+		if calibration, ok := halkoConfig.SensorUnit.Calibration[probe]; ok {
+			log.Info("Calibration for %s: %+v", probe, *calibration)
+		}
+	}
+
+	if cj := halkoConfig.SensorUnit.ColdJunction; cj != nil {
+		log.Info("Cold junction reference %.1f°C, boards uneven beyond %.1f°C apart", cj.Reference, cj.MaxSpread)
+		if cj.Correction != nil {
+			log.Info("Cold junction correction: %.2f of each die's drift taken off its probe", *cj.Correction)
+		}
+	}
+	if sensor := halkoConfig.SensorUnit.HumiditySensor; sensor != "" {
+		log.Info("Humidity sensor: %s", sensor)
+	} else {
+		log.Info("No humidity sensor, climate not served")
+	}
+	if probes := halkoConfig.SensorUnit.MoistureProbes; probes != nil {
+		log.Info("Moisture probes: %d, species correction %.3f*MC%+.2f", probes.Count, probes.SpeciesSlope, probes.SpeciesIntercept)
+	} else {
+		log.Info("No moisture probes, moisture content not served")
+	}
+
+	api := router.NewAPI(sensorUnit, halkoConfig.SensorUnit)
 	r := router.SetupRouter(api, halkoConfig.APIEndpoints)
 
 	srv := &http.Server{
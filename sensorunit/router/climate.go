@@ -0,0 +1,81 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/rmkhl/halko/sensorunit/serial"
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// storeDryBulb records the fused kiln temperature of a device read, which is
+// the dry bulb the humidity reading is worked out against.
+func (api *API) storeDryBulb(kiln float32) {
+	api.climateMu.Lock()
+	defer api.climateMu.Unlock()
+	api.dryBulb = kiln
+}
+
+func (api *API) lastDryBulb() float32 {
+	api.climateMu.Lock()
+	defer api.climateMu.Unlock()
+	return api.dryBulb
+}
+
+// climateFrom works out the kiln air's climate from what the humidity sensor
+// measured and the dry-bulb temperature. Only the sensor's own quantity is
+// used, so a board reporting both cannot mix two sensors' opinions.
+func climateFrom(sensor types.HumiditySensor, dryBulb float32, reading serial.Climate) types.ClimateResponse {
+	climate := types.ClimateResponse{Sensor: sensor}
+	if dryBulb == types.InvalidTemperatureReading {
+		return climate
+	}
+
+	switch sensor {
+	case types.HumiditySensorSHT3x:
+		if reading.RelativeHumidity == types.InvalidTemperatureReading {
+			return climate
+		}
+		climate.RelativeHumidity = reading.RelativeHumidity
+		climate.WetBulb = types.WetBulbTemperature(dryBulb, reading.RelativeHumidity)
+	case types.HumiditySensorWetBulb:
+		if reading.WetBulb == types.InvalidTemperatureReading {
+			return climate
+		}
+		climate.WetBulb = reading.WetBulb
+		climate.RelativeHumidity = types.RelativeHumidity(dryBulb, reading.WetBulb)
+	default:
+		return climate
+	}
+
+	climate.Valid = true
+	climate.DryBulb = dryBulb
+	climate.EMC = types.EquilibriumMoistureContent(dryBulb, climate.RelativeHumidity)
+	return climate
+}
+
+// getClimate serves the kiln air's humidity, wet-bulb temperature and EMC.
+// Like the inputs it reads the device on every request; the dry bulb is the
+// kiln temperature of the last read, which the control unit fetches just
+// before this.
+func (api *API) getClimate(w http.ResponseWriter, r *http.Request) {
+	log.Debug("Processing climate request from %s", r.RemoteAddr)
+
+	if api.humiditySensor == "" {
+		writeError(w, http.StatusNotFound, "no humidity sensor configured")
+		return
+	}
+
+	reading, err := api.sensorUnit.GetClimate()
+	if err != nil {
+		log.Error("Failed to get humidity from sensor unit: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	climate := climateFrom(api.humiditySensor, api.lastDryBulb(), *reading)
+	log.Debug("Returning climate: %+v", climate)
+	writeJSON(w, http.StatusOK, types.APIResponse[types.ClimateResponse]{
+		Data: climate,
+	})
+}
@@ -0,0 +1,82 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rmkhl/halko/sensorunit/serial"
+	"github.com/rmkhl/halko/types"
+)
+
+func TestClimateFromHumiditySensor(t *testing.T) {
+	reading := serial.Climate{RelativeHumidity: 58, WetBulb: types.InvalidTemperatureReading}
+	climate := climateFrom(types.HumiditySensorSHT3x, 60, reading)
+
+	if !climate.Valid || climate.RelativeHumidity != 58 || climate.DryBulb != 60 {
+		t.Fatalf("climate = %+v, want valid at 58%% and 60°C", climate)
+	}
+	if climate.WetBulb < 49 || climate.WetBulb > 51 {
+		t.Errorf("wet bulb = %.1f, want about 50", climate.WetBulb)
+	}
+	if climate.EMC < 7 || climate.EMC > 9 {
+		t.Errorf("EMC = %.1f, want about 8", climate.EMC)
+	}
+}
+
+func TestClimateFromWetBulb(t *testing.T) {
+	reading := serial.Climate{RelativeHumidity: types.InvalidTemperatureReading, WetBulb: 50}
+	climate := climateFrom(types.HumiditySensorWetBulb, 60, reading)
+
+	if !climate.Valid || climate.WetBulb != 50 {
+		t.Fatalf("climate = %+v, want valid with the measured wet bulb", climate)
+	}
+	if climate.RelativeHumidity < 56 || climate.RelativeHumidity > 60 {
+		t.Errorf("relative humidity = %.1f, want about 58", climate.RelativeHumidity)
+	}
+}
+
+// A reading is only as good as both of its inputs, and the quantity the
+// fitted sensor does not measure must not be picked up from the report.
+func TestClimateFromIsInvalidWithoutItsInputs(t *testing.T) {
+	tests := []struct {
+		name    string
+		sensor  types.HumiditySensor
+		dryBulb float32
+		reading serial.Climate
+	}{
+		{"no kiln temperature", types.HumiditySensorSHT3x, types.InvalidTemperatureReading, serial.Climate{RelativeHumidity: 50, WetBulb: types.InvalidTemperatureReading}},
+		{"failed sensor", types.HumiditySensorSHT3x, 60, serial.Climate{RelativeHumidity: types.InvalidTemperatureReading, WetBulb: types.InvalidTemperatureReading}},
+		{"other sensor's quantity", types.HumiditySensorWetBulb, 60, serial.Climate{RelativeHumidity: 50, WetBulb: types.InvalidTemperatureReading}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			climate := climateFrom(tt.sensor, tt.dryBulb, tt.reading)
+			if climate.Valid || climate.RelativeHumidity != 0 || climate.EMC != 0 {
+				t.Errorf("climate = %+v, want invalid and zero", climate)
+			}
+			if climate.Sensor != tt.sensor {
+				t.Errorf("sensor = %q, want %q", climate.Sensor, tt.sensor)
+			}
+		})
+	}
+}
+
+// Without a humidity sensor the device is never asked: firmware from before
+// the question would leave the request waiting on a reply that never comes.
+func TestGetClimateWithoutHumiditySensor(t *testing.T) {
+	api := NewAPI(nil, &types.SensorUnitConfig{})
+
+	recorder := httptest.NewRecorder()
+	api.getClimate(recorder, httptest.NewRequest(http.MethodGet, "/climate", nil))
+
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+	var response types.APIErrorResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil || response.Err == "" {
+		t.Errorf("body = %s, want an error response", recorder.Body.String())
+	}
+}
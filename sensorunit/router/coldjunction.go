@@ -0,0 +1,84 @@
+package router
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rmkhl/halko/types"
+)
+
+// dieSuffix is what a probe's cold junction reading is keyed under, after the
+// probe name.
+const dieSuffix = "_die"
+
+// coldJunctionMonitor watches the die temperatures of the thermocouple chips
+// for boards heating unevenly. Dies that warm together are the sensor box
+// warming, which the correction and the deltas account for; dies that drift
+// apart are one board near a heat source, or a fan that has stopped, and
+// that board's probes read wrong by the difference.
+//
+// The zero value, without a configuration, never reports anything.
+type coldJunctionMonitor struct {
+	config *types.ColdJunction
+
+	uneven  bool
+	spread  float32
+	coolest string
+	warmest string
+}
+
+// Observe records the die readings of one device read and reports whether the
+// uneven state changed. Fewer than two valid dies cannot be compared and leave
+// the state as it was.
+func (m *coldJunctionMonitor) Observe(dies types.TemperatureResponse) bool {
+	if m.config == nil {
+		return false
+	}
+
+	var names []string
+	for name, value := range dies {
+		if value != types.InvalidTemperatureReading {
+			names = append(names, name)
+		}
+	}
+	if len(names) < 2 {
+		return false
+	}
+	sort.Slice(names, func(i, j int) bool { return dies[names[i]] < dies[names[j]] })
+
+	m.coolest = strings.TrimSuffix(names[0], dieSuffix)
+	m.warmest = strings.TrimSuffix(names[len(names)-1], dieSuffix)
+	m.spread = dies[names[len(names)-1]] - dies[names[0]]
+
+	uneven := m.spread > m.config.MaxSpread
+	changed := uneven != m.uneven
+	m.uneven = uneven
+	return changed
+}
+
+// Uneven reports whether the boards are heating unevenly, and if so how.
+func (m *coldJunctionMonitor) Uneven() (bool, string) {
+	if !m.uneven {
+		return false, ""
+	}
+	return true, fmt.Sprintf("the %s board reads %.1f°C warmer than the %s board, more than %.1f°C",
+		m.warmest, m.spread, m.coolest, m.config.MaxSpread)
+}
+
+// deltas returns how far each valid die reads from the configured reference,
+// keyed by the die's name with "_delta" appended, or nothing without a
+// configuration. A die that has risen with the probe readings says the board
+// warmed; probe readings that moved on their own say the kiln did.
+func (m *coldJunctionMonitor) deltas(dies types.TemperatureResponse) types.TemperatureResponse {
+	deltas := make(types.TemperatureResponse)
+	if m.config == nil {
+		return deltas
+	}
+	for name, value := range dies {
+		if value != types.InvalidTemperatureReading {
+			deltas[name+"_delta"] = m.config.Delta(value)
+		}
+	}
+	return deltas
+}
@@ -0,0 +1,67 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func TestColdJunctionMonitorReportsUnevenBoards(t *testing.T) {
+	m := coldJunctionMonitor{config: &types.ColdJunction{Reference: 25, MaxSpread: 5}}
+
+	if m.Observe(types.TemperatureResponse{"kiln_primary_die": 30, "kiln_secondary_die": 31, materialDie: 32}) {
+		t.Fatal("boards within the spread reported as a change")
+	}
+	if !m.Observe(types.TemperatureResponse{"kiln_primary_die": 30, "kiln_secondary_die": 31, materialDie: 38}) {
+		t.Fatal("boards 8°C apart not reported")
+	}
+	uneven, reason := m.Uneven()
+	if !uneven || !strings.Contains(reason, "material board") || !strings.Contains(reason, "kiln_primary board") {
+		t.Fatalf("Uneven() = %t, %q, want the material board named against kiln_primary", uneven, reason)
+	}
+
+	// A die that stops reading leaves too few to compare; the verdict stands.
+	if m.Observe(types.TemperatureResponse{"kiln_primary_die": types.InvalidTemperatureReading, materialDie: 38}) {
+		t.Fatal("a single valid die changed the state")
+	}
+	if !m.Observe(types.TemperatureResponse{"kiln_primary_die": 35, materialDie: 38}) {
+		t.Fatal("boards back within the spread not reported")
+	}
+}
+
+func TestZeroColdJunctionMonitorReportsNothing(t *testing.T) {
+	var m coldJunctionMonitor
+	if m.Observe(types.TemperatureResponse{"kiln_primary_die": 20, materialDie: 80}) {
+		t.Fatal("the zero monitor reported a change")
+	}
+	if deltas := m.deltas(types.TemperatureResponse{materialDie: 80}); len(deltas) != 0 {
+		t.Fatalf("deltas = %v, want none without a reference", deltas)
+	}
+}
+
+// The die endpoint serves each die's distance from the reference beside it.
+func TestDieTemperaturesIncludeTheDeltas(t *testing.T) {
+	api := NewAPI(nil, &types.SensorUnitConfig{ColdJunction: &types.ColdJunction{Reference: 25, MaxSpread: 5}})
+	api.storeDieReadings(types.TemperatureResponse{
+		"kiln_primary_die": 27.5,
+		materialDie:        types.InvalidTemperatureReading,
+	})
+
+	recorder := httptest.NewRecorder()
+	api.getDieTemperatures(recorder, httptest.NewRequest(http.MethodGet, "/temperatures/die", nil))
+
+	var response types.APIResponse[types.TemperatureResponse]
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if got := response.Data["kiln_primary_die_delta"]; got != 2.5 {
+		t.Errorf("kiln_primary_die_delta = %v, want 2.5", got)
+	}
+	if _, ok := response.Data["material_die_delta"]; ok {
+		t.Error("an invalid die got a delta")
+	}
+}
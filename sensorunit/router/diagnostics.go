@@ -0,0 +1,94 @@
+package router
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/rmkhl/halko/sensorunit/serial"
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// probeDiagnostics picks each thermocouple's state out of one device read,
+// keyed by probe name. The probes every board has are always present, like
+// in probeReadings; a probe the device did not report reads as failed for an
+// unknown reason.
+func probeDiagnostics(temperatures []serial.Temperature) types.ProbeDiagnosticsResponse {
+	diagnostics := types.ProbeDiagnosticsResponse{
+		types.ProbeKilnPrimary:   {},
+		types.ProbeKilnSecondary: {},
+		types.ProbeMaterial:      {},
+	}
+	for _, temp := range temperatures {
+		probe, ok := firmwareProbeNames[temp.Name]
+		if !ok {
+			continue
+		}
+		diagnostics[probe] = types.ProbeDiagnostics{
+			Valid:  temp.Value != types.InvalidTemperatureReading,
+			Faults: temp.Faults(),
+		}
+	}
+	return diagnostics
+}
+
+// storeDiagnostics records the probe states from a device read for the
+// diagnostics endpoint and the status, logging each probe whose faults
+// changed rather than every read that repeats them.
+func (api *API) storeDiagnostics(diagnostics types.ProbeDiagnosticsResponse) {
+	api.diagMu.Lock()
+	defer api.diagMu.Unlock()
+
+	for probe, current := range diagnostics {
+		previous, seen := api.diagnostics[probe]
+		if seen && slices.Equal(previous.Faults, current.Faults) {
+			continue
+		}
+		switch {
+		case len(current.Faults) > 0:
+			log.Error("Probe %s reports faults: %v", probe, current.Faults)
+		case len(previous.Faults) > 0:
+			log.Info("Probe %s no longer reports faults", probe)
+		}
+	}
+	api.diagnostics = diagnostics
+}
+
+// probeFaults returns the faults of every probe that reported one on the last
+// read, or nil when none did.
+func (api *API) probeFaults() map[string][]types.ProbeFault {
+	api.diagMu.Lock()
+	defer api.diagMu.Unlock()
+
+	var faults map[string][]types.ProbeFault
+	for probe, diagnostics := range api.diagnostics {
+		if len(diagnostics.Faults) == 0 {
+			continue
+		}
+		if faults == nil {
+			faults = make(map[string][]types.ProbeFault)
+		}
+		faults[probe] = slices.Clone(diagnostics.Faults)
+	}
+	return faults
+}
+
+// getDiagnostics serves the probe states recorded by the last temperature
+// read. Like the die endpoint it does not read the device itself: the control
+// unit's polling keeps the record fresh, and a fault is only worth asking
+// about once a reading has failed.
+func (api *API) getDiagnostics(w http.ResponseWriter, r *http.Request) {
+	log.Debug("Processing diagnostics request from %s", r.RemoteAddr)
+
+	api.diagMu.Lock()
+	response := make(types.ProbeDiagnosticsResponse, len(api.diagnostics))
+	for probe, diagnostics := range api.diagnostics {
+		diagnostics.Faults = slices.Clone(diagnostics.Faults)
+		response[probe] = diagnostics
+	}
+	api.diagMu.Unlock()
+
+	writeJSON(w, http.StatusOK, types.APIResponse[types.ProbeDiagnosticsResponse]{
+		Data: response,
+	})
+}
@@ -0,0 +1,88 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rmkhl/halko/sensorunit/serial"
+	"github.com/rmkhl/halko/types"
+)
+
+func TestProbeDiagnosticsCarryTheFaults(t *testing.T) {
+	diagnostics := probeDiagnostics([]serial.Temperature{
+		{Name: "KilnPrimary", Value: 80, Unit: "C"},
+		{Name: "KilnSecondary", Value: types.InvalidTemperatureReading, Unit: "C", Faulted: true, FaultBits: 2},
+		{Name: "KilnSecondaryDie", Value: 30, Unit: "C"},
+	})
+
+	if !diagnostics[types.ProbeKilnPrimary].Valid || len(diagnostics[types.ProbeKilnPrimary].Faults) != 0 {
+		t.Errorf("kiln_primary = %+v, want valid without faults", diagnostics[types.ProbeKilnPrimary])
+	}
+	secondary := diagnostics[types.ProbeKilnSecondary]
+	if secondary.Valid || len(secondary.Faults) != 1 || secondary.Faults[0] != types.ProbeFaultShortToGND {
+		t.Errorf("kiln_secondary = %+v, want invalid with short_to_gnd", secondary)
+	}
+	// Not reported at all is failed, for a reason nobody knows.
+	if material, ok := diagnostics[types.ProbeMaterial]; !ok || material.Valid {
+		t.Errorf("material = %+v (present: %t), want present and invalid", material, ok)
+	}
+}
+
+// The status lists the faulted probes, and only while they are faulted.
+func TestStatusReportsProbeFaults(t *testing.T) {
+	sensorUnit, err := serial.NewSensorUnit(t.TempDir()+"/absent-device", 9600)
+	if err != nil {
+		t.Fatalf("creating sensor unit: %v", err)
+	}
+	t.Cleanup(func() { _ = sensorUnit.Shutdown() })
+	api := NewAPI(sensorUnit, &types.SensorUnitConfig{})
+
+	status := func() map[string]interface{} {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		api.getStatus(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+		var response types.APIResponse[types.ServiceStatusResponse]
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatalf("decoding status response: %v", err)
+		}
+		return response.Data.Details
+	}
+
+	api.storeDiagnostics(types.ProbeDiagnosticsResponse{
+		types.ProbeKilnPrimary: {Valid: false, Faults: []types.ProbeFault{types.ProbeFaultOpenCircuit}},
+		types.ProbeMaterial:    {Valid: true},
+	})
+	faults, ok := status()["probe_faults"].(map[string]interface{})
+	if !ok || len(faults) != 1 {
+		t.Fatalf("probe_faults = %v, want kiln_primary only", status()["probe_faults"])
+	}
+	if got, _ := faults[types.ProbeKilnPrimary].([]interface{}); len(got) != 1 || got[0] != string(types.ProbeFaultOpenCircuit) {
+		t.Errorf("kiln_primary faults = %v, want [open_circuit]", faults[types.ProbeKilnPrimary])
+	}
+
+	api.storeDiagnostics(types.ProbeDiagnosticsResponse{types.ProbeKilnPrimary: {Valid: true}})
+	if _, ok := status()["probe_faults"]; ok {
+		t.Errorf("probe_faults still present after the probe recovered")
+	}
+}
+
+func TestDiagnosticsServeTheLastRead(t *testing.T) {
+	api := NewAPI(nil, &types.SensorUnitConfig{})
+	api.storeDiagnostics(types.ProbeDiagnosticsResponse{
+		types.ProbeMaterial: {Valid: false, Faults: []types.ProbeFault{types.ProbeFaultShortToVCC}},
+	})
+
+	recorder := httptest.NewRecorder()
+	api.getDiagnostics(recorder, httptest.NewRequest(http.MethodGet, "/temperatures/diagnostics", nil))
+
+	var response types.APIResponse[types.ProbeDiagnosticsResponse]
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	material := response.Data[types.ProbeMaterial]
+	if material.Valid || len(material.Faults) != 1 || material.Faults[0] != types.ProbeFaultShortToVCC {
+		t.Fatalf("material = %+v, want invalid with short_to_vcc", material)
+	}
+}
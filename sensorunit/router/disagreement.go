@@ -0,0 +1,74 @@
+package router
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rmkhl/halko/types"
+)
+
+// kilnDisagreement tracks the difference between the two kiln probes over
+// time. Two good probes read apart by whatever the airflow puts between them,
+// and briefly by more while the heater cycles; a difference beyond the
+// threshold that lasts longer than the window is a probe that has come loose
+// or drifted. Fusion cannot see that: max quietly reports whichever probe
+// reads hot, and mean splits the error between them.
+//
+// The zero value never reports anything.
+type kilnDisagreement struct {
+	threshold float32
+	window    time.Duration
+
+	// When the current disagreement started, zero while the probes agree.
+	since      time.Time
+	difference float32
+	degraded   bool
+}
+
+// Observe records one pair of calibrated readings and reports whether the
+// degraded state changed. A pair with an invalid reading cannot be compared:
+// it restarts the clock on a disagreement that has not yet become degraded,
+// and leaves one that has in place, since a probe that is failing outright
+// is no evidence the other one has recovered.
+func (d *kilnDisagreement) Observe(primary, secondary float32, now time.Time) bool {
+	if d.threshold <= 0 {
+		return false
+	}
+	if primary == types.InvalidTemperatureReading || secondary == types.InvalidTemperatureReading {
+		d.since = time.Time{}
+		return false
+	}
+
+	difference := primary - secondary
+	if difference < 0 {
+		difference = -difference
+	}
+	d.difference = difference
+
+	if difference <= d.threshold {
+		d.since = time.Time{}
+		if d.degraded {
+			d.degraded = false
+			return true
+		}
+		return false
+	}
+
+	if d.since.IsZero() {
+		d.since = now
+	}
+	if !d.degraded && now.Sub(d.since) >= d.window {
+		d.degraded = true
+		return true
+	}
+	return false
+}
+
+// Degraded reports whether the probes are degraded, and if so why.
+func (d *kilnDisagreement) Degraded() (bool, string) {
+	if !d.degraded {
+		return false, ""
+	}
+	return true, fmt.Sprintf("kiln probes read %.1f°C apart, more than %.1f°C for longer than %s",
+		d.difference, d.threshold, d.window)
+}
@@ -0,0 +1,93 @@
+package router
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func testDisagreement() kilnDisagreement {
+	return kilnDisagreement{threshold: 10, window: 5 * time.Minute}
+}
+
+func TestKilnDisagreementNeedsToLastTheWindow(t *testing.T) {
+	d := testDisagreement()
+	start := time.Unix(1000, 0)
+
+	if d.Observe(120, 105, start) {
+		t.Fatal("expected no change at the start of the disagreement")
+	}
+	if d.Observe(120, 105, start.Add(4*time.Minute)) {
+		t.Fatal("expected no change inside the window")
+	}
+	if !d.Observe(120, 105, start.Add(5*time.Minute)) {
+		t.Fatal("expected the probes to become degraded once the window has passed")
+	}
+
+	degraded, reason := d.Degraded()
+	if !degraded || !strings.Contains(reason, "15.0°C apart") {
+		t.Fatalf("expected degraded with the difference in the reason, got %v %q", degraded, reason)
+	}
+}
+
+// A difference that comes and goes with the heater cycle is airflow, not a
+// failing probe: each return within the threshold restarts the clock.
+func TestKilnDisagreementRestartsWhenTheProbesAgree(t *testing.T) {
+	d := testDisagreement()
+	start := time.Unix(1000, 0)
+
+	d.Observe(120, 105, start)
+	d.Observe(120, 115, start.Add(3*time.Minute))
+	d.Observe(120, 105, start.Add(4*time.Minute))
+	if d.Observe(120, 105, start.Add(6*time.Minute)) {
+		t.Fatal("expected the window to count from the latest onset")
+	}
+}
+
+func TestKilnDisagreementRecovers(t *testing.T) {
+	d := testDisagreement()
+	start := time.Unix(1000, 0)
+
+	d.Observe(120, 105, start)
+	d.Observe(120, 105, start.Add(5*time.Minute))
+
+	if !d.Observe(120, 118, start.Add(6*time.Minute)) {
+		t.Fatal("expected the recovery to be a change")
+	}
+	if degraded, _ := d.Degraded(); degraded {
+		t.Fatal("expected the probes to be trusted again")
+	}
+}
+
+// A failed probe cannot be compared, and says nothing about whether the
+// disagreement is over.
+func TestKilnDisagreementHoldsThroughAnInvalidReading(t *testing.T) {
+	d := testDisagreement()
+	start := time.Unix(1000, 0)
+
+	d.Observe(120, 105, start)
+	d.Observe(120, 105, start.Add(5*time.Minute))
+
+	if d.Observe(120, types.InvalidTemperatureReading, start.Add(6*time.Minute)) {
+		t.Fatal("expected an invalid reading to change nothing")
+	}
+	if degraded, _ := d.Degraded(); !degraded {
+		t.Fatal("expected the probes to stay degraded")
+	}
+}
+
+func TestAPIReportsDegradedKilnProbes(t *testing.T) {
+	api := NewAPI(nil, &types.SensorUnitConfig{
+		KilnDisagreement: &types.KilnDisagreement{Threshold: 10, WindowDuration: time.Minute},
+	})
+	start := time.Unix(1000, 0)
+	api.trackKilnDisagreement(120, 105, start)
+	api.trackKilnDisagreement(120, 105, start.Add(time.Minute))
+
+	degraded, reason := api.kilnDegraded()
+	if !degraded || reason == "" {
+		t.Fatalf("expected the API to report degraded probes, got %v %q", degraded, reason)
+	}
+}
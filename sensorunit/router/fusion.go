@@ -0,0 +1,113 @@
+package router
+
+import (
+	"sort"
+
+	"github.com/rmkhl/halko/types"
+)
+
+// fusion reduces the calibrated probe readings to the one kiln and the one
+// material temperature the control unit works with, as configured. Only the
+// max strategy carries state between reads (which probe it last reported);
+// the others are a function of the readings alone.
+type fusion struct {
+	kiln       types.KilnFusion
+	material   types.MaterialFusion
+	weights    map[string]float64
+	kilnSelect kilnSelector
+}
+
+// Kiln returns the kiln temperature for the two kiln probe readings, either of
+// which may be types.InvalidTemperatureReading.
+func (f *fusion) Kiln(primary, secondary float32) float32 {
+	switch f.kiln {
+	case types.KilnFusionMean:
+		return mean(validReadings(primary, secondary))
+	case types.KilnFusionMedian:
+		return median(validReadings(primary, secondary))
+	case types.KilnFusionPrimary:
+		if primary != types.InvalidTemperatureReading {
+			return primary
+		}
+		return secondary
+	default:
+		return f.kilnSelect.Select(primary, secondary)
+	}
+}
+
+// Material returns the material temperature from the material probe readings
+// in probes. A probe that is missing or invalid is left out, so the result
+// is invalid only when none of the probes it would use read.
+func (f *fusion) Material(probes types.TemperatureResponse) float32 {
+	var values, weights []float64
+	for n := 1; n <= types.MaxMaterialProbes; n++ {
+		name := types.MaterialProbeName(n)
+		value, ok := probes[name]
+		if !ok || value == types.InvalidTemperatureReading {
+			continue
+		}
+		if f.material == types.MaterialFusionCoreWeighted {
+			weight := f.weights[name]
+			if weight <= 0 {
+				continue
+			}
+			weights = append(weights, weight)
+		}
+		values = append(values, float64(value))
+	}
+	if len(values) == 0 {
+		return types.InvalidTemperatureReading
+	}
+
+	switch f.material {
+	case types.MaterialFusionMean:
+		return mean(values)
+	case types.MaterialFusionCoreWeighted:
+		var sum, total float64
+		for i, value := range values {
+			sum += value * weights[i]
+			total += weights[i]
+		}
+		return float32(sum / total)
+	default:
+		lowest := values[0]
+		for _, value := range values[1:] {
+			lowest = min(lowest, value)
+		}
+		return float32(lowest)
+	}
+}
+
+func validReadings(readings ...float32) []float64 {
+	var values []float64
+	for _, reading := range readings {
+		if reading != types.InvalidTemperatureReading {
+			values = append(values, float64(reading))
+		}
+	}
+	return values
+}
+
+func mean(values []float64) float32 {
+	if len(values) == 0 {
+		return types.InvalidTemperatureReading
+	}
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	return float32(sum / float64(len(values)))
+}
+
+func median(values []float64) float32 {
+	if len(values) == 0 {
+		return types.InvalidTemperatureReading
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return float32((sorted[middle-1] + sorted[middle]) / 2)
+	}
+	return float32(sorted[middle])
+}
@@ -0,0 +1,83 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+const invalid = types.InvalidTemperatureReading
+
+func TestKilnFusion(t *testing.T) {
+	tests := []struct {
+		strategy           types.KilnFusion
+		primary, secondary float32
+		want               float32
+	}{
+		{types.KilnFusionMax, 60, 64, 64},
+		{types.KilnFusionMean, 60, 64, 62},
+		{types.KilnFusionMean, invalid, 64, 64},
+		{types.KilnFusionMedian, 60, 64, 62},
+		{types.KilnFusionPrimary, 60, 64, 60},
+		{types.KilnFusionPrimary, invalid, 64, 64},
+		{types.KilnFusionMean, invalid, invalid, invalid},
+		{types.KilnFusionPrimary, invalid, invalid, invalid},
+	}
+
+	for _, tt := range tests {
+		f := fusion{kiln: tt.strategy}
+		if got := f.Kiln(tt.primary, tt.secondary); got != tt.want {
+			t.Errorf("%s of %v and %v = %v, want %v", tt.strategy, tt.primary, tt.secondary, got, tt.want)
+		}
+	}
+}
+
+// The max strategy is the selector the sensor unit has always used, hysteresis
+// included.
+func TestKilnFusionMaxKeepsTheHysteresis(t *testing.T) {
+	f := fusion{kiln: types.KilnFusionMax}
+
+	f.Kiln(20.0, 19.8)
+	if got := f.Kiln(20.0, 20.3); got != 20.0 {
+		t.Fatalf("expected to stick with the primary within the margin, got %v", got)
+	}
+}
+
+func TestMaterialFusion(t *testing.T) {
+	probes := types.TemperatureResponse{
+		"material":   40,
+		"material_2": 34,
+		"material_3": invalid,
+	}
+	weights := map[string]float64{"material": 1, "material_2": 3, "material_3": 10}
+
+	tests := []struct {
+		strategy types.MaterialFusion
+		want     float32
+	}{
+		{types.MaterialFusionMin, 34},
+		{types.MaterialFusionMean, 37},
+		// The invalid core probe drops out with its weight: (40*1 + 34*3) / 4.
+		{types.MaterialFusionCoreWeighted, 35.5},
+	}
+
+	for _, tt := range tests {
+		f := fusion{material: tt.strategy, weights: weights}
+		if got := f.Material(probes); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.strategy, got, tt.want)
+		}
+	}
+}
+
+// A probe without a weight does not count towards a core-weighted value, so
+// one left in for logging cannot pull the result.
+func TestCoreWeightedIgnoresUnweightedProbes(t *testing.T) {
+	f := fusion{material: types.MaterialFusionCoreWeighted, weights: map[string]float64{"material_2": 1}}
+
+	if got := f.Material(types.TemperatureResponse{"material": 80, "material_2": 30}); got != 30 {
+		t.Fatalf("expected only the weighted probe, got %v", got)
+	}
+	if got := f.Material(types.TemperatureResponse{"material": 80, "material_2": invalid}); got != invalid {
+		t.Fatalf("expected invalid with the weighted probe failed, got %v", got)
+	}
+}
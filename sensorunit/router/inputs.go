@@ -0,0 +1,27 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// getInputs serves the emergency stop and door switch states. Unlike the die
+// temperatures it reads the device on every request: a switch changes state in
+// an instant, and an answer one poll old is exactly the one that matters.
+func (api *API) getInputs(w http.ResponseWriter, r *http.Request) {
+	log.Debug("Processing inputs request from %s", r.RemoteAddr)
+
+	inputs, err := api.sensorUnit.GetInputs()
+	if err != nil {
+		log.Error("Failed to get inputs from sensor unit: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	log.Debug("Returning inputs: emergency_stop=%t, door_open=%t", inputs.EmergencyStop, inputs.DoorOpen)
+	writeJSON(w, http.StatusOK, types.APIResponse[types.SensorInputsResponse]{
+		Data: *inputs,
+	})
+}
@@ -0,0 +1,78 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// storeWoodTemperature records the fused material temperature of a device
+// read, which the moisture pins' readings are corrected to.
+func (api *API) storeWoodTemperature(material float32) {
+	api.moistureMu.Lock()
+	defer api.moistureMu.Unlock()
+	api.woodTemperature = material
+}
+
+func (api *API) lastWoodTemperature() float32 {
+	api.moistureMu.Lock()
+	defer api.moistureMu.Unlock()
+	return api.woodTemperature
+}
+
+// moistureFrom corrects the fitted pins' reference-scale readings to the
+// wood's temperature and species. The charge is reported by its wettest
+// board: drying is done when that one is, not when the average is.
+func moistureFrom(probes *types.MoistureProbes, temperature float32, readings []float32) types.MoistureResponse {
+	var moisture types.MoistureResponse
+	if temperature == types.InvalidTemperatureReading {
+		return moisture
+	}
+
+	for n := 1; n <= probes.Count && n <= len(readings); n++ {
+		reading := readings[n-1]
+		if reading == types.InvalidTemperatureReading {
+			continue
+		}
+		if moisture.Probes == nil {
+			moisture.Probes = make(map[string]float32, probes.Count)
+		}
+		mc := probes.CorrectMoistureReading(reading, temperature)
+		moisture.Probes[types.MoistureProbeName(n)] = mc
+		moisture.Material = max(moisture.Material, mc)
+	}
+
+	if moisture.Probes == nil {
+		return types.MoistureResponse{}
+	}
+	moisture.Valid = true
+	moisture.Temperature = temperature
+	return moisture
+}
+
+// getMoisture serves the sample boards' moisture content. Like the climate it
+// reads the device on every request, correcting against the material
+// temperature of the last read, which the control unit fetches just before
+// this.
+func (api *API) getMoisture(w http.ResponseWriter, r *http.Request) {
+	log.Debug("Processing moisture request from %s", r.RemoteAddr)
+
+	if api.moistureProbes == nil {
+		writeError(w, http.StatusNotFound, "no moisture probes configured")
+		return
+	}
+
+	readings, err := api.sensorUnit.GetMoisture()
+	if err != nil {
+		log.Error("Failed to get moisture from sensor unit: %v", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	moisture := moistureFrom(api.moistureProbes, api.lastWoodTemperature(), readings)
+	log.Debug("Returning moisture: %+v", moisture)
+	writeJSON(w, http.StatusOK, types.APIResponse[types.MoistureResponse]{
+		Data: moisture,
+	})
+}
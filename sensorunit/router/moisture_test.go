@@ -0,0 +1,64 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+// The charge reads as its wettest board, and pins that are not fitted or
+// have no reading are left out.
+func TestMoistureFromReportsTheWettestBoard(t *testing.T) {
+	probes := &types.MoistureProbes{Count: 3, SpeciesSlope: 1}
+	readings := []float32{12, types.InvalidTemperatureReading, 16, 30}
+
+	moisture := moistureFrom(probes, 20, readings)
+
+	if !moisture.Valid || moisture.Temperature != 20 {
+		t.Fatalf("moisture = %+v, want valid at 20°C", moisture)
+	}
+	if len(moisture.Probes) != 2 {
+		t.Fatalf("probes = %v, want mc_1 and mc_3", moisture.Probes)
+	}
+	if moisture.Material != moisture.Probes["mc_3"] {
+		t.Errorf("material = %v, want the wettest probe %v", moisture.Material, moisture.Probes["mc_3"])
+	}
+	if _, found := moisture.Probes["mc_4"]; found {
+		t.Error("a pin beyond the configured count was reported")
+	}
+}
+
+func TestMoistureFromIsInvalidWithoutItsInputs(t *testing.T) {
+	probes := &types.MoistureProbes{Count: 2, SpeciesSlope: 1}
+	tests := []struct {
+		name        string
+		temperature float32
+		readings    []float32
+	}{
+		{"no material temperature", types.InvalidTemperatureReading, []float32{12, 14, 0, 0}},
+		{"no pin reading", 40, []float32{types.InvalidTemperatureReading, types.InvalidTemperatureReading, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moisture := moistureFrom(probes, tt.temperature, tt.readings)
+			if moisture.Valid || moisture.Material != 0 || moisture.Probes != nil {
+				t.Errorf("moisture = %+v, want invalid and zero", moisture)
+			}
+		})
+	}
+}
+
+// Like the climate, the device is never asked without pins configured.
+func TestGetMoistureWithoutProbes(t *testing.T) {
+	api := NewAPI(nil, &types.SensorUnitConfig{})
+
+	recorder := httptest.NewRecorder()
+	api.getMoisture(recorder, httptest.NewRequest(http.MethodGet, "/moisture", nil))
+
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
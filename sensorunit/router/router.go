@@ -3,6 +3,7 @@ package router
 import (
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/rmkhl/halko/sensorunit/serial"
 	"github.com/rmkhl/halko/types"
@@ -22,12 +23,15 @@ const (
 )
 
 type API struct {
-	sensorUnit *serial.SensorUnit
+	sensorUnit  *serial.SensorUnit
+	calibration map[string]*types.ProbeCalibration
 
 	statusMu      sync.Mutex
 	kilnStatus    kilnSensorStatus
-	kilnSelect    kilnSelector
+	fusion        fusion
 	materialValid bool
+	disagreement  kilnDisagreement
+	coldJunction  coldJunctionMonitor
 
 	// Cold junction readings arrive on the same device read as the
 	// thermocouple values and are kept here for their own endpoint. Serving
@@ -35,22 +39,61 @@ type API struct {
 	// board's thermal time constant makes immaterial.
 	dieMu   sync.Mutex
 	dieRead types.TemperatureResponse
+
+	// Each thermocouple's state and faults as of the last device read,
+	// kept for the diagnostics endpoint and the status.
+	diagMu      sync.Mutex
+	diagnostics types.ProbeDiagnosticsResponse
+
+	// The humidity sensor, if the board has one, and the kiln temperature
+	// of the last read, which the climate is worked out against.
+	humiditySensor types.HumiditySensor
+	climateMu      sync.Mutex
+	dryBulb        float32
+
+	// The moisture pins, if the board has any, and the material
+	// temperature of the last read, which their readings are corrected to.
+	moistureProbes  *types.MoistureProbes
+	moistureMu      sync.Mutex
+	woodTemperature float32
 }
 
-// selectKilnTemperature applies hysteresis-based kiln sensor selection,
-// serialized by the status mutex since handlers may run concurrently.
-func (api *API) selectKilnTemperature(primary, secondary float32) float32 {
+// fuseTemperatures reduces the calibrated probe readings to the kiln and
+// material temperatures, serialized by the status mutex since handlers may
+// run concurrently and kiln selection keeps state between them.
+func (api *API) fuseTemperatures(probes types.TemperatureResponse) (kiln, material float32) {
 	api.statusMu.Lock()
 	defer api.statusMu.Unlock()
-	return api.kilnSelect.Select(primary, secondary)
+	return api.fusion.Kiln(probes[types.ProbeKilnPrimary], probes[types.ProbeKilnSecondary]),
+		api.fusion.Material(probes)
 }
 
-func NewAPI(sensorUnit *serial.SensorUnit) *API {
-	return &API{
-		sensorUnit:    sensorUnit,
+func NewAPI(sensorUnit *serial.SensorUnit, config *types.SensorUnitConfig) *API {
+	api := &API{
+		sensorUnit:  sensorUnit,
+		calibration: config.Calibration,
+		fusion: fusion{
+			kiln:     config.KilnFusion,
+			material: config.MaterialFusion,
+			weights:  config.MaterialWeights,
+		},
 		materialValid: true,
+		coldJunction:  coldJunctionMonitor{config: config.ColdJunction},
 		dieRead:       make(types.TemperatureResponse),
+
+		humiditySensor: config.HumiditySensor,
+		dryBulb:        types.InvalidTemperatureReading,
+
+		moistureProbes:  config.MoistureProbes,
+		woodTemperature: types.InvalidTemperatureReading,
+	}
+	if config.KilnDisagreement != nil {
+		api.disagreement = kilnDisagreement{
+			threshold: config.KilnDisagreement.Threshold,
+			window:    config.KilnDisagreement.WindowDuration,
+		}
 	}
+	return api
 }
 
 // storeDieReadings records the cold junction values from a device read so the
@@ -102,6 +145,53 @@ func (api *API) updateKilnStatus(newStatus kilnSensorStatus) {
 	api.kilnStatus = newStatus
 }
 
+// trackKilnDisagreement feeds the calibrated kiln readings to the disagreement
+// tracker, logging when the probes become degraded or recover.
+func (api *API) trackKilnDisagreement(primary, secondary float32, now time.Time) {
+	api.statusMu.Lock()
+	defer api.statusMu.Unlock()
+
+	if !api.disagreement.Observe(primary, secondary, now) {
+		return
+	}
+	if degraded, reason := api.disagreement.Degraded(); degraded {
+		log.Error("Kiln probes degraded: %s", reason)
+	} else {
+		log.Info("Kiln probes agree again, no longer degraded")
+	}
+}
+
+// trackColdJunction feeds the die readings of a device read to the cold
+// junction monitor, logging when the boards start or stop heating unevenly.
+func (api *API) trackColdJunction(dies types.TemperatureResponse) {
+	api.statusMu.Lock()
+	defer api.statusMu.Unlock()
+
+	if !api.coldJunction.Observe(dies) {
+		return
+	}
+	if uneven, reason := api.coldJunction.Uneven(); uneven {
+		log.Warning("Thermocouple boards heating unevenly: %s", reason)
+	} else {
+		log.Info("Thermocouple boards read within %.1f°C of each other again", api.coldJunction.config.MaxSpread)
+	}
+}
+
+// coldJunctionUneven reports whether the boards are heating unevenly, and if
+// so how.
+func (api *API) coldJunctionUneven() (bool, string) {
+	api.statusMu.Lock()
+	defer api.statusMu.Unlock()
+	return api.coldJunction.Uneven()
+}
+
+// kilnDegraded reports whether the kiln probes are degraded, and if so why.
+func (api *API) kilnDegraded() (bool, string) {
+	api.statusMu.Lock()
+	defer api.statusMu.Unlock()
+	return api.disagreement.Degraded()
+}
+
 // updateMaterialStatus logs a message when the material (wood) temperature
 // reading becomes invalid or becomes valid again, instead of on every poll.
 func (api *API) updateMaterialStatus(valid bool) {
@@ -20,6 +20,33 @@ func (api *API) getStatus(w http.ResponseWriter, r *http.Request) {
 	details := make(map[string]interface{})
 	details["sensor_connected"] = isConnected
 
+	// Degraded rather than unhealthy: the unit still answers and one of the
+	// kiln probes is presumably still right, but which one is not known.
+	degraded, reason := api.kilnDegraded()
+	details["degraded"] = degraded
+	if degraded {
+		details["degraded_reason"] = reason
+		if status == types.ServiceStatusHealthy {
+			status = types.ServiceStatusDegraded
+		}
+	}
+
+	// Also informational: uneven boards shift readings by a degree or two,
+	// which the die deltas let an operator judge.
+	if api.coldJunction.config != nil {
+		uneven, reason := api.coldJunctionUneven()
+		details["cold_junction_uneven"] = uneven
+		if uneven {
+			details["cold_junction_reason"] = reason
+		}
+	}
+
+	// Informational: a faulted probe already shows as an invalid reading,
+	// and whether that matters is up to the fusion and the control unit.
+	if faults := api.probeFaults(); faults != nil {
+		details["probe_faults"] = faults
+	}
+
 	response := types.ServiceStatusResponse{
 		Status:  status,
 		Version: types.Version,
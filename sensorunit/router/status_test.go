@@ -20,7 +20,7 @@ func TestStatusReportsVersionWhenDisconnected(t *testing.T) {
 	}
 	t.Cleanup(func() { _ = sensorUnit.Shutdown() })
 
-	api := NewAPI(sensorUnit)
+	api := NewAPI(sensorUnit, &types.SensorUnitConfig{})
 
 	rec := httptest.NewRecorder()
 	api.getStatus(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
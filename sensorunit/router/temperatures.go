@@ -3,6 +3,7 @@ package router
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/rmkhl/halko/sensorunit/serial"
@@ -47,44 +48,30 @@ func (api *API) getTemperatures(w http.ResponseWriter, r *http.Request) {
 		}
 		log.Debug("Retrieved %d temperature readings from sensor unit (attempt %d/%d)", len(temperatures), attempt, maxAttempts)
 
-		response := make(types.TemperatureResponse)
-		// A reading the device did not report must not read as 0 degrees.
-		kilnPrimary := float32(types.InvalidTemperatureReading)
-		kilnSecondary := float32(types.InvalidTemperatureReading)
-
-		// Cold junction values ride along on the same read but stay out of
-		// this response: they are diagnostics about the measurement, not
-		// temperatures the system controls on. They are kept per chip rather
-		// than folded into one value because whether they moved together is
-		// what says a shift is the board and not the kiln.
-		dies := make(types.TemperatureResponse, dieSensorCount)
-
-		for _, temp := range temperatures {
-			switch temp.Name {
-			case "KilnPrimary":
-				kilnPrimary = temp.Value
-			case "KilnSecondary":
-				kilnSecondary = temp.Value
-			case "Wood":
-				response["material"] = temp.Value
-			case "KilnPrimaryDie":
-				dies["kiln_primary_die"] = temp.Value
-			case "KilnSecondaryDie":
-				dies["kiln_secondary_die"] = temp.Value
-			case "WoodDie":
-				dies["material_die"] = temp.Value
-			}
-		}
+		probes, dies := probeReadings(temperatures)
 		api.storeDieReadings(dies)
+		api.storeDiagnostics(probeDiagnostics(temperatures))
+
+		// Correct each probe before anything compares or combines them:
+		// fusing readings is only meaningful once each one reads true.
+		// The cold junction correction comes after calibration: a probe is
+		// calibrated with its board at the reference temperature, and the
+		// correction accounts for the board drifting from there.
+		corrected := make(types.TemperatureResponse, len(probes))
+		for name, raw := range probes {
+			corrected[name] = api.coldJunction.config.Correct(api.calibration[name].Apply(raw), dies[name+dieSuffix])
+		}
+		api.trackColdJunction(dies)
+		kilnPrimary := corrected[types.ProbeKilnPrimary]
+		kilnSecondary := corrected[types.ProbeKilnSecondary]
 
-		log.Debug("Temperature readings processed (attempt %d/%d): KilnPrimary=%.2f°C, KilnSecondary=%.2f°C, Material=%.2f°C, dies %.2f/%.2f/%.2f°C",
-			attempt, maxAttempts, kilnPrimary, kilnSecondary, response["material"],
-			dies["kiln_primary_die"], dies["kiln_secondary_die"], dies["material_die"])
+		log.Debug("Temperature readings processed (attempt %d/%d): %v, dies %v", attempt, maxAttempts, corrected, dies)
 
 		// Check if all readings are invalid
-		allInvalid := (kilnPrimary == types.InvalidTemperatureReading &&
-			kilnSecondary == types.InvalidTemperatureReading &&
-			response["material"] == types.InvalidTemperatureReading)
+		allInvalid := true
+		for _, value := range corrected {
+			allInvalid = allInvalid && value == types.InvalidTemperatureReading
+		}
 
 		if allInvalid && attempt < maxAttempts {
 			log.Warning("All temperature readings are invalid on attempt %d/%d, retrying in 500ms...", attempt, maxAttempts)
@@ -103,8 +90,19 @@ func (api *API) getTemperatures(w http.ResponseWriter, r *http.Request) {
 		default:
 			api.updateKilnStatus(kilnSensorBothInvalid)
 		}
-		response["kiln"] = api.selectKilnTemperature(kilnPrimary, kilnSecondary)
-		api.updateMaterialStatus(response["material"] != types.InvalidTemperatureReading)
+		api.trackKilnDisagreement(kilnPrimary, kilnSecondary, time.Now())
+		kiln, material := api.fuseTemperatures(corrected)
+		api.updateMaterialStatus(material != types.InvalidTemperatureReading)
+		api.storeDryBulb(kiln)
+		api.storeWoodTemperature(material)
+
+		// The fused values are what the system controls on. Each probe's
+		// own reading rides along, uncorrected, for calibration and for the
+		// control unit to log and compare.
+		response := types.TemperatureResponse{"kiln": kiln, "material": material}
+		for name, raw := range probes {
+			response[name+types.RawReadingSuffix] = raw
+		}
 
 		log.Debug("Temperature selection complete: kiln=%.1f°C, material=%.1f°C",
 			response["kiln"], response["material"])
@@ -117,8 +115,51 @@ func (api *API) getTemperatures(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// dieSensorCount is how many cold junctions the unit reports, one per chip.
-const dieSensorCount = 3
+// firmwareProbeNames maps the names the firmware reports thermocouples by to
+// probe names. Each one's cold junction is reported under the same name with
+// firmwareDieSuffix, and served under the probe name with "_die".
+var firmwareProbeNames = map[string]string{
+	"KilnPrimary":   types.ProbeKilnPrimary,
+	"KilnSecondary": types.ProbeKilnSecondary,
+	"Wood":          types.MaterialProbeName(1),
+	"Wood2":         types.MaterialProbeName(2),
+	"Wood3":         types.MaterialProbeName(3),
+}
+
+const firmwareDieSuffix = "Die"
+
+// probeReadings sorts one device read into the thermocouple readings and the
+// cold junction readings, both keyed by probe name. Cold junction values ride
+// along on the same read but stay out of the temperature response: they are
+// diagnostics about the measurement, not temperatures the system controls
+// on. They are kept per chip rather than folded into one value because
+// whether they moved together is what says a shift is the board and not the
+// kiln.
+//
+// The probes every board has are always present: a reading the device did
+// not report must not read as 0 degrees. Extra material probes are present
+// only when the board reports them.
+func probeReadings(temperatures []serial.Temperature) (probes, dies types.TemperatureResponse) {
+	probes = types.TemperatureResponse{
+		types.ProbeKilnPrimary:   types.InvalidTemperatureReading,
+		types.ProbeKilnSecondary: types.InvalidTemperatureReading,
+		types.ProbeMaterial:      types.InvalidTemperatureReading,
+	}
+	dies = make(types.TemperatureResponse)
+
+	for _, temp := range temperatures {
+		if probe, ok := firmwareProbeNames[temp.Name]; ok {
+			probes[probe] = temp.Value
+			continue
+		}
+		if probe, ok := firmwareProbeNames[strings.TrimSuffix(temp.Name, firmwareDieSuffix)]; ok {
+			dies[probe+dieSuffix] = temp.Value
+			continue
+		}
+		log.Debug("Ignoring reading %q the sensor unit does not know", temp.Name)
+	}
+	return probes, dies
+}
 
 // getDieTemperatures serves the cold junction readings recorded by the last
 // temperature read. It deliberately does not trigger a read of its own: the
@@ -128,7 +169,12 @@ const dieSensorCount = 3
 func (api *API) getDieTemperatures(w http.ResponseWriter, r *http.Request) {
 	log.Debug("Processing die temperature request from %s", r.RemoteAddr)
 
+	readings := api.dieReadings()
+	for name, delta := range api.coldJunction.deltas(readings) {
+		readings[name] = delta
+	}
+
 	writeJSON(w, http.StatusOK, types.APIResponse[types.TemperatureResponse]{
-		Data: api.dieReadings(),
+		Data: readings,
 	})
 }
@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/rmkhl/halko/sensorunit/serial"
 	"github.com/rmkhl/halko/types"
 )
 
@@ -16,7 +17,7 @@ const materialDie = "material_die"
 // The die endpoint answers from what the last device read recorded, so a
 // caller gets values without a serial round trip of its own.
 func TestDieTemperaturesServeTheLastRecordedReadings(t *testing.T) {
-	api := NewAPI(nil)
+	api := NewAPI(nil, &types.SensorUnitConfig{})
 	api.storeDieReadings(types.TemperatureResponse{
 		"kiln_primary_die":   27.5,
 		"kiln_secondary_die": 28.125,
@@ -50,7 +51,7 @@ func TestDieTemperaturesServeTheLastRecordedReadings(t *testing.T) {
 // Before any read has happened there is nothing to serve, which must be an
 // empty answer rather than three readings of 0 °C.
 func TestDieTemperaturesAreEmptyBeforeTheFirstRead(t *testing.T) {
-	api := NewAPI(nil)
+	api := NewAPI(nil, &types.SensorUnitConfig{})
 
 	recorder := httptest.NewRecorder()
 	api.getDieTemperatures(recorder, httptest.NewRequest(http.MethodGet, "/temperatures/die", nil))
@@ -67,7 +68,7 @@ func TestDieTemperaturesAreEmptyBeforeTheFirstRead(t *testing.T) {
 // Handing out the live map would let a caller read it while the next device
 // read is writing to it.
 func TestDieReadingsReturnsACopy(t *testing.T) {
-	api := NewAPI(nil)
+	api := NewAPI(nil, &types.SensorUnitConfig{})
 	api.storeDieReadings(types.TemperatureResponse{materialDie: 41.875})
 
 	readings := api.dieReadings()
@@ -77,3 +78,33 @@ func TestDieReadingsReturnsACopy(t *testing.T) {
 		t.Fatalf("stored reading = %v, want 41.875 (caller mutated the source)", got)
 	}
 }
+
+// Extra material probes and their dies are named after the probe, and a probe
+// every board has but the read left out is invalid rather than 0 °C.
+func TestProbeReadingsNamesEveryProbe(t *testing.T) {
+	probes, dies := probeReadings([]serial.Temperature{
+		{Name: "KilnPrimary", Value: 60},
+		{Name: "Wood", Value: 40},
+		{Name: "Wood2", Value: 34},
+		{Name: "WoodDie", Value: 41.875},
+		{Name: "Wood2Die", Value: 40.5},
+	})
+
+	want := types.TemperatureResponse{
+		types.ProbeKilnPrimary:   60,
+		types.ProbeKilnSecondary: types.InvalidTemperatureReading,
+		types.ProbeMaterial:      40,
+		"material_2":             34,
+	}
+	if len(probes) != len(want) {
+		t.Fatalf("probes = %v, want %v", probes, want)
+	}
+	for name, value := range want {
+		if probes[name] != value {
+			t.Errorf("%s = %v, want %v", name, probes[name], value)
+		}
+	}
+	if dies[materialDie] != 41.875 || dies["material_2_die"] != 40.5 {
+		t.Errorf("dies = %v", dies)
+	}
+}
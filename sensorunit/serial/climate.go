@@ -0,0 +1,82 @@
+package serial
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// Climate is one reading of the board's humidity sensor. Only the quantity
+// the fitted sensor measures is valid; the other, and both on a board without
+// a sensor, are types.InvalidTemperatureReading.
+type Climate struct {
+	// RelativeHumidity in percent, from an SHT3x.
+	RelativeHumidity float32
+	// WetBulb in °C, from a thermocouple under a wetted wick.
+	WetBulb float32
+}
+
+// GetClimate reads the humidity sensor.
+func (s *SensorUnit) GetClimate() (*Climate, error) {
+	log.Debug("Reading humidity sensor from sensor unit")
+	if err := s.Connect(); err != nil {
+		log.Error("Failed to connect for humidity reading: %v", err)
+		return nil, err
+	}
+
+	response, err := s.sendCommand(ClimateCommand)
+	if err != nil {
+		log.Error("Failed to read humidity from sensor unit: %v", err)
+		return nil, err
+	}
+
+	climate, err := parseClimateResponse(response)
+	if err != nil {
+		log.Warning("Failed to parse humidity response: %v", err)
+		return nil, err
+	}
+	return climate, nil
+}
+
+// parseClimateResponse turns a `clim` response of the form
+// `Humidity=XX.X%,WetBulb=XX.XC` into a reading, either value `NaN` when the
+// board does not measure it or the sensor failed. Like the other reports it is
+// rejected whole when any part will not parse.
+func parseClimateResponse(response string) (*Climate, error) {
+	fields := strings.Split(response, ",")
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid humidity format, expected 2 fields: %q", response)
+	}
+
+	climate := Climate{RelativeHumidity: types.InvalidTemperatureReading, WetBulb: types.InvalidTemperatureReading}
+	for n, target := range []struct {
+		name  string
+		unit  string
+		value *float32
+	}{
+		{"Humidity", "%", &climate.RelativeHumidity},
+		{"WetBulb", "C", &climate.WetBulb},
+	} {
+		name, value, found := strings.Cut(fields[n], "=")
+		if !found || name != target.name {
+			return nil, fmt.Errorf("expected %s in field %d of humidity response %q", target.name, n+1, response)
+		}
+		if value == "NaN" {
+			continue
+		}
+		number, ok := strings.CutSuffix(value, target.unit)
+		if !ok {
+			return nil, fmt.Errorf("malformed %s value %q in humidity response %q", name, value, response)
+		}
+		parsed, err := strconv.ParseFloat(number, 32)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %s %q in humidity response %q: %w", name, value, response, err)
+		}
+		*target.value = float32(parsed)
+	}
+
+	return &climate, nil
+}
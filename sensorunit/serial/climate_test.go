@@ -0,0 +1,49 @@
+package serial
+
+import (
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func TestParseClimateResponse(t *testing.T) {
+	tests := []struct {
+		response    string
+		wantRH      float32
+		wantWetBulb float32
+	}{
+		{"Humidity=54.25%,WetBulb=NaN", 54.25, types.InvalidTemperatureReading},
+		{"Humidity=NaN,WetBulb=41.50C", types.InvalidTemperatureReading, 41.5},
+		{"Humidity=NaN,WetBulb=NaN", types.InvalidTemperatureReading, types.InvalidTemperatureReading},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.response, func(t *testing.T) {
+			got, err := parseClimateResponse(tt.response)
+			if err != nil {
+				t.Fatalf("parseClimateResponse() error = %v", err)
+			}
+			if got.RelativeHumidity != tt.wantRH || got.WetBulb != tt.wantWetBulb {
+				t.Errorf("got %+v, want humidity %v and wet bulb %v", *got, tt.wantRH, tt.wantWetBulb)
+			}
+		})
+	}
+}
+
+func TestParseClimateResponseRejectsMalformed(t *testing.T) {
+	for _, response := range []string{
+		"",
+		"Humidity=50.0%",
+		"WetBulb=NaN,Humidity=50.0%",
+		"Humidity=50.0,WetBulb=NaN",
+		"Humidity=50.0%,WetBulb=40.0F",
+		"Humidity=fifty%,WetBulb=NaN",
+		"Humidity=50.0%,WetBulb=NaN,Extra=1",
+	} {
+		t.Run(response, func(t *testing.T) {
+			if got, err := parseClimateResponse(response); err == nil {
+				t.Errorf("parseClimateResponse(%q) = %+v, want an error", response, got)
+			}
+		})
+	}
+}
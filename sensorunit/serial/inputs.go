@@ -0,0 +1,69 @@
+package serial
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// GetInputs reads the emergency stop and door switch states, and whether the
+// confirm button has been pressed, from the unit.
+func (s *SensorUnit) GetInputs() (*types.SensorInputsResponse, error) {
+	log.Debug("Reading safety inputs from sensor unit")
+	if err := s.Connect(); err != nil {
+		log.Error("Failed to connect for input reading: %v", err)
+		return nil, err
+	}
+
+	response, err := s.sendCommand(InputsCommand)
+	if err != nil {
+		log.Error("Failed to read inputs from sensor unit: %v", err)
+		return nil, err
+	}
+
+	inputs, err := parseInputsResponse(response)
+	if err != nil {
+		log.Warning("Failed to parse inputs response: %v", err)
+		return nil, err
+	}
+	return inputs, nil
+}
+
+// parseInputsResponse turns an `inpt` response of the form
+// `EStop=0|1,Door=0|1,Confirm=0|1` into switch states. Firmware without the
+// confirm button leaves the last field out, which reads as never pressed.
+// Anything else is rejected: a misread here must not turn a pressed stop into
+// a released one, and the caller can retry.
+func parseInputsResponse(response string) (*types.SensorInputsResponse, error) {
+	fields := strings.Split(response, ",")
+	if len(fields) != 2 && len(fields) != 3 {
+		return nil, fmt.Errorf("invalid inputs format, expected 2 or 3 fields: %q", response)
+	}
+
+	var inputs types.SensorInputsResponse
+	for n, target := range []struct {
+		name  string
+		value *bool
+	}{
+		{"EStop", &inputs.EmergencyStop},
+		{"Door", &inputs.DoorOpen},
+		{"Confirm", &inputs.Confirm},
+	}[:len(fields)] {
+		name, value, found := strings.Cut(fields[n], "=")
+		if !found || name != target.name {
+			return nil, fmt.Errorf("expected %s in field %d of inputs response %q", target.name, n+1, response)
+		}
+		switch value {
+		case "0":
+			*target.value = false
+		case "1":
+			*target.value = true
+		default:
+			return nil, fmt.Errorf("malformed %s state %q in inputs response %q", name, value, response)
+		}
+	}
+
+	return &inputs, nil
+}
@@ -0,0 +1,54 @@
+package serial
+
+import "testing"
+
+func TestParseInputsResponse(t *testing.T) {
+	tests := []struct {
+		response    string
+		wantEStop   bool
+		wantDoor    bool
+		wantConfirm bool
+	}{
+		{"EStop=0,Door=0", false, false, false},
+		{"EStop=1,Door=0", true, false, false},
+		{"EStop=0,Door=1", false, true, false},
+		{"EStop=1,Door=1", true, true, false},
+		{"EStop=0,Door=0,Confirm=0", false, false, false},
+		{"EStop=0,Door=1,Confirm=1", false, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.response, func(t *testing.T) {
+			got, err := parseInputsResponse(tt.response)
+			if err != nil {
+				t.Fatalf("parseInputsResponse() error = %v", err)
+			}
+			if got.EmergencyStop != tt.wantEStop || got.DoorOpen != tt.wantDoor || got.Confirm != tt.wantConfirm {
+				t.Errorf("got estop=%v door=%v confirm=%v, want estop=%v door=%v confirm=%v",
+					got.EmergencyStop, got.DoorOpen, got.Confirm, tt.wantEStop, tt.wantDoor, tt.wantConfirm)
+			}
+		})
+	}
+}
+
+// A garbled report must fail rather than read as "released and shut": the
+// zero value of both switches is the safe one.
+func TestParseInputsResponseRejectsMalformed(t *testing.T) {
+	for _, response := range []string{
+		"",
+		"EStop=0",
+		"EStop=0,Door=0,Extra=1",
+		"EStop=0,Door=0,Confirm=2",
+		"EStop=0,Door=0,Confirm=0,Extra=1",
+		"Door=0,EStop=0",
+		"EStop=2,Door=0",
+		"EStop=0,Door=",
+		"EStop0,Door=0",
+	} {
+		t.Run(response, func(t *testing.T) {
+			if got, err := parseInputsResponse(response); err == nil {
+				t.Errorf("parseInputsResponse(%q) = %+v, want an error", response, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package serial
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// GetMoisture reads the moisture pins. Each of the types.MaxMoistureProbes
+// pairs is reported on the firmware's reference scale, before any correction;
+// a pair that is not fitted, or is out of the meter's range, is
+// types.InvalidTemperatureReading.
+func (s *SensorUnit) GetMoisture() ([]float32, error) {
+	log.Debug("Reading moisture pins from sensor unit")
+	if err := s.Connect(); err != nil {
+		log.Error("Failed to connect for moisture reading: %v", err)
+		return nil, err
+	}
+
+	response, err := s.sendCommand(MoistureCommand)
+	if err != nil {
+		log.Error("Failed to read moisture from sensor unit: %v", err)
+		return nil, err
+	}
+
+	readings, err := parseMoistureResponse(response)
+	if err != nil {
+		log.Warning("Failed to parse moisture response: %v", err)
+		return nil, err
+	}
+	return readings, nil
+}
+
+// parseMoistureResponse turns a `mois` response of the form
+// `MC1=XX.X%,MC2=XX.X%,MC3=XX.X%,MC4=XX.X%` into the readings in pin order,
+// `NaN` for a pair without one. Like the other reports it is rejected whole
+// when any part will not parse.
+func parseMoistureResponse(response string) ([]float32, error) {
+	fields := strings.Split(response, ",")
+	if len(fields) != types.MaxMoistureProbes {
+		return nil, fmt.Errorf("invalid moisture format, expected %d fields: %q", types.MaxMoistureProbes, response)
+	}
+
+	readings := make([]float32, len(fields))
+	for n, field := range fields {
+		want := "MC" + strconv.Itoa(n+1)
+		name, value, found := strings.Cut(field, "=")
+		if !found || name != want {
+			return nil, fmt.Errorf("expected %s in field %d of moisture response %q", want, n+1, response)
+		}
+		if value == "NaN" {
+			readings[n] = types.InvalidTemperatureReading
+			continue
+		}
+		number, ok := strings.CutSuffix(value, "%")
+		if !ok {
+			return nil, fmt.Errorf("malformed %s value %q in moisture response %q", name, value, response)
+		}
+		parsed, err := strconv.ParseFloat(number, 32)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %s %q in moisture response %q: %w", name, value, response, err)
+		}
+		readings[n] = float32(parsed)
+	}
+
+	return readings, nil
+}
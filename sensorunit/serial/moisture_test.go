@@ -0,0 +1,36 @@
+package serial
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rmkhl/halko/types"
+)
+
+func TestParseMoistureResponse(t *testing.T) {
+	got, err := parseMoistureResponse("MC1=14.20%,MC2=NaN,MC3=9.05%,MC4=NaN")
+	if err != nil {
+		t.Fatalf("parseMoistureResponse() error = %v", err)
+	}
+	want := []float32{14.2, types.InvalidTemperatureReading, 9.05, types.InvalidTemperatureReading}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestParseMoistureResponseRejectsMalformed(t *testing.T) {
+	for _, response := range []string{
+		"",
+		"MC1=14.2%,MC2=NaN,MC3=NaN",
+		"MC2=14.2%,MC1=NaN,MC3=NaN,MC4=NaN",
+		"MC1=14.2,MC2=NaN,MC3=NaN,MC4=NaN",
+		"MC1=wet%,MC2=NaN,MC3=NaN,MC4=NaN",
+		"MC1=14.2%,MC2=NaN,MC3=NaN,MC4=NaN,MC5=NaN",
+	} {
+		t.Run(response, func(t *testing.T) {
+			if got, err := parseMoistureResponse(response); err == nil {
+				t.Errorf("parseMoistureResponse(%q) = %v, want an error", response, got)
+			}
+		})
+	}
+}
@@ -10,16 +10,23 @@ import (
 	"sync"
 	"time"
 
+	"github.com/rmkhl/halko/types"
 	"github.com/rmkhl/halko/types/log"
 	"github.com/tarm/serial"
 )
 
 const (
-	HeloCommand  = "helo;"
-	ReadCommand  = "read;"
-	ShowCommand  = "show"
-	AddrCommand  = "addr"
-	HeloResponse = "helo"
+	HeloCommand      = "helo;"
+	ReadCommand      = "read;"
+	InputsCommand    = "inpt;"
+	ClimateCommand   = "clim;"
+	MoistureCommand  = "mois;"
+	ShowCommand      = "show"
+	AddrCommand      = "addr"
+	HeloResponse     = "helo"
+	InputsResponse   = "EStop="
+	ClimateResponse  = "Humidity="
+	MoistureResponse = "MC1="
 )
 
 type SensorUnit struct {
@@ -38,6 +45,18 @@ type Temperature struct {
 	Name  string  `json:"name"`
 	Value float32 `json:"value"`
 	Unit  string  `json:"unit"`
+	// Set when a failed reading came with the chip's fault bits, which
+	// firmware older than the fault report leaves out.
+	Faulted   bool  `json:"faulted,omitempty"`
+	FaultBits uint8 `json:"fault_bits,omitempty"`
+}
+
+// Faults decodes why the reading failed, or nil when that is not known.
+func (t Temperature) Faults() []types.ProbeFault {
+	if !t.Faulted {
+		return nil
+	}
+	return types.ProbeFaultsFromBits(t.FaultBits)
 }
 
 func NewSensorUnit(device string, baudRate int) (*SensorUnit, error) {
@@ -321,6 +340,28 @@ func (s *SensorUnit) sendCommand(cmd string) (string, error) {
 			break
 		}
 
+		// For inpt commands, the switch report. Checked by prefix rather than
+		// by "=" as a read is, so a late temperature line cannot pass for it.
+		if strings.HasPrefix(cmd, InputsCommand) && strings.HasPrefix(line, InputsResponse) {
+			log.Debug("Received input states from sensor unit")
+			response = line
+			break
+		}
+
+		// For clim commands, the humidity report, by prefix like inpt.
+		if strings.HasPrefix(cmd, ClimateCommand) && strings.HasPrefix(line, ClimateResponse) {
+			log.Debug("Received humidity reading from sensor unit")
+			response = line
+			break
+		}
+
+		// For mois commands, the moisture report, by prefix like clim.
+		if strings.HasPrefix(cmd, MoistureCommand) && strings.HasPrefix(line, MoistureResponse) {
+			log.Debug("Received moisture reading from sensor unit")
+			response = line
+			break
+		}
+
 		// For helo commands, we're looking for the helo response
 		if strings.HasPrefix(cmd, HeloCommand) && line == HeloResponse {
 			log.Debug("Received handshake response from sensor unit")
@@ -9,23 +9,31 @@ import (
 	"github.com/rmkhl/halko/types/log"
 )
 
-// expectedReadings is how many values the unit reports per read: three
-// thermocouples, then the cold junction each one is referenced to.
-const expectedReadings = 6
+// Each read reports the thermocouples and then the cold junction each one is
+// referenced to: two kiln probes and one to types.MaxMaterialProbes material
+// probes, so between 6 and 10 values.
+const (
+	minThermocouples = 3
+	maxThermocouples = 2 + types.MaxMaterialProbes
+)
 
 // parseTemperatureResponse turns a `read` response of the form
 // `KilnPrimary=XX.XC,KilnSecondary=XX.XC,Wood=XX.XC,KilnPrimaryDie=XX.XC,
-// KilnSecondaryDie=XX.XC,WoodDie=XX.XC` into readings. A sensor that failed
-// reports `NaN`, which becomes types.InvalidTemperatureReading. Anything
-// else that will not parse rejects the whole response: a partially read line
-// would silently lose a sensor, and the caller can retry.
+// KilnSecondaryDie=XX.XC,WoodDie=XX.XC` into readings. A board with more
+// material probes adds Wood2 and Wood3 before the dies, and their dies after
+// WoodDie. A sensor that failed reports `NaN`, which becomes
+// types.InvalidTemperatureReading; a thermocouple reports it as `NaN:<bits>`,
+// the MAX31855 fault bits saying why. Anything else that will not parse
+// rejects the whole response: a partially read line would silently lose a
+// sensor, and the caller can retry.
 func parseTemperatureResponse(response string) ([]Temperature, error) {
 	readings := strings.Split(response, ",")
-	if len(readings) != expectedReadings {
-		return nil, fmt.Errorf("invalid temperature reading format, expected %d readings: %q", expectedReadings, response)
+	if len(readings)%2 != 0 || len(readings) < 2*minThermocouples || len(readings) > 2*maxThermocouples {
+		return nil, fmt.Errorf("invalid temperature reading format, expected %d to %d readings in pairs: %q",
+			2*minThermocouples, 2*maxThermocouples, response)
 	}
 
-	temperatures := make([]Temperature, 0, expectedReadings)
+	temperatures := make([]Temperature, 0, len(readings))
 	for _, reading := range readings {
 		name, valueStr, found := strings.Cut(reading, "=")
 		if !found {
@@ -41,6 +49,21 @@ func parseTemperatureResponse(response string) ([]Temperature, error) {
 			})
 			continue
 		}
+		if bitsStr, ok := strings.CutPrefix(valueStr, "NaN:"); ok {
+			bits, err := strconv.ParseUint(bitsStr, 10, 3)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse fault bits %q for sensor %q: %w", bitsStr, name, err)
+			}
+			log.Debug("Sensor %q has invalid reading (NaN, faults %v)", name, types.ProbeFaultsFromBits(uint8(bits)))
+			temperatures = append(temperatures, Temperature{
+				Name:      name,
+				Value:     types.InvalidTemperatureReading,
+				Unit:      "C",
+				Faulted:   true,
+				FaultBits: uint8(bits),
+			})
+			continue
+		}
 
 		if len(valueStr) < 2 {
 			return nil, fmt.Errorf("malformed temperature value %q for sensor %q", valueStr, name)
@@ -34,6 +34,29 @@ func TestParseTemperatureResponseValid(t *testing.T) {
 	}
 }
 
+// A board with extra material probes reports them after Wood, and their dies
+// after WoodDie, in the same order.
+func TestParseTemperatureResponseExtraMaterialProbes(t *testing.T) {
+	got, err := parseTemperatureResponse(
+		"KilnPrimary=20.5C,KilnSecondary=21.0C,Wood=19.25C,Wood2=18.5C,Wood3=NaN," +
+			"KilnPrimaryDie=27.5C,KilnSecondaryDie=28.125C,WoodDie=41.875C,Wood2Die=40.0C,Wood3Die=39.5C")
+	if err != nil {
+		t.Fatalf("parseTemperatureResponse() error = %v", err)
+	}
+	if len(got) != 10 {
+		t.Fatalf("got %d readings, want 10", len(got))
+	}
+	if got[3] != (Temperature{Name: "Wood2", Value: 18.5, Unit: "C"}) {
+		t.Errorf("second material probe = %+v", got[3])
+	}
+	if got[4].Value != types.InvalidTemperatureReading {
+		t.Errorf("failed third material probe = %v, want invalid", got[4].Value)
+	}
+	if got[9] != (Temperature{Name: "Wood3Die", Value: 39.5, Unit: "C"}) {
+		t.Errorf("third material die = %+v", got[9])
+	}
+}
+
 // NaN is a valid report of a failed sensor, not a parse failure.
 func TestParseTemperatureResponseNaN(t *testing.T) {
 	tests := []struct {
@@ -62,8 +85,8 @@ func TestParseTemperatureResponseNaN(t *testing.T) {
 			if err != nil {
 				t.Fatalf("parseTemperatureResponse() error = %v", err)
 			}
-			if len(got) != expectedReadings {
-				t.Fatalf("got %d readings, want %d", len(got), expectedReadings)
+			if len(got) != 2*len(tt.want) {
+				t.Fatalf("got %d readings, want %d", len(got), 2*len(tt.want))
 			}
 			for i, want := range tt.want {
 				if got[i].Value != want {
@@ -74,6 +97,25 @@ func TestParseTemperatureResponseNaN(t *testing.T) {
 	}
 }
 
+// A failed thermocouple's fault bits say why it failed; a bare NaN, as older
+// firmware sends, leaves that unknown.
+func TestParseTemperatureResponseFaultBits(t *testing.T) {
+	got, err := parseTemperatureResponse(
+		"KilnPrimary=NaN:1,KilnSecondary=NaN:0,Wood=NaN" + dieSuffix)
+	if err != nil {
+		t.Fatalf("parseTemperatureResponse() error = %v", err)
+	}
+	want := [][]types.ProbeFault{{types.ProbeFaultOpenCircuit}, {types.ProbeFaultNoResponse}, nil}
+	for i, faults := range want {
+		if got[i].Value != types.InvalidTemperatureReading {
+			t.Errorf("reading %d value = %v, want invalid", i, got[i].Value)
+		}
+		if gotFaults := got[i].Faults(); len(gotFaults) != len(faults) || (len(faults) > 0 && gotFaults[0] != faults[0]) {
+			t.Errorf("reading %d faults = %v, want %v", i, gotFaults, faults)
+		}
+	}
+}
+
 // A thermocouple faults independently of the chip reporting it, so NaN in
 // one half of the line says nothing about the other.
 func TestParseTemperatureResponseDieFailsIndependently(t *testing.T) {
@@ -107,6 +149,10 @@ func TestParseTemperatureResponseRejectsCorruption(t *testing.T) {
 		{"thermocouples only", "KilnPrimary=20.5C,KilnSecondary=21.0C,Wood=19.0C"},
 		{"truncated die group", "KilnPrimary=20.5C,KilnSecondary=21.0C,Wood=19.0C,KilnPrimaryDie=27.5C"},
 		{"too many fields", "KilnPrimary=20.5C,KilnSecondary=21.0C,Wood=19.0C" + dieSuffix + ",Extra=1.0C"},
+		{"more probes than the firmware reads", "KilnPrimary=1C,KilnSecondary=1C,Wood=1C,Wood2=1C,Wood3=1C,Wood4=1C," +
+			"KilnPrimaryDie=1C,KilnSecondaryDie=1C,WoodDie=1C,Wood2Die=1C,Wood3Die=1C,Wood4Die=1C"},
+		{"fault bits out of range", "KilnPrimary=NaN:8,KilnSecondary=21.0C,Wood=19.0C" + dieSuffix},
+		{"unparseable fault bits", "KilnPrimary=NaN:x,KilnSecondary=21.0C,Wood=19.0C" + dieSuffix},
 		{"empty response", ""},
 	}
 	for _, tt := range tests {
@@ -10,6 +10,12 @@ import (
 	"github.com/rmkhl/halko/types/log"
 )
 
+// A room in a heated building and green sawn timber.
+const (
+	defaultEnvironmentHumidity     = 50.0
+	defaultInitialMaterialMoisture = 60.0
+)
+
 type SimulatorConfig struct {
 	StatusInterval      int                    `json:"status_interval"`
 	InitialKilnTemp     float64                `json:"initial_kiln_temp"`
@@ -17,6 +23,13 @@ type SimulatorConfig struct {
 	EnvironmentTemp     float64                `json:"environment_temp"`
 	SimulationEngine    string                 `json:"simulation_engine"`
 	EngineConfig        map[string]interface{} `json:"engine_config"`
+
+	// The room's relative humidity and the wood's starting moisture
+	// content, both in percent, for the moisture model. Left out they
+	// default to defaultEnvironmentHumidity and
+	// defaultInitialMaterialMoisture.
+	EnvironmentHumidity     float64 `json:"environment_humidity,omitempty"`
+	InitialMaterialMoisture float64 `json:"initial_material_moisture,omitempty"`
 }
 
 func LoadSimulatorConfig(configPath string) (*SimulatorConfig, error) {
@@ -51,6 +64,19 @@ func LoadSimulatorConfig(configPath string) (*SimulatorConfig, error) {
 		return nil, fmt.Errorf("engine_config is required for simulation engine '%s'", config.SimulationEngine)
 	}
 
+	if config.EnvironmentHumidity == 0 {
+		config.EnvironmentHumidity = defaultEnvironmentHumidity
+	}
+	if config.EnvironmentHumidity < 0 || config.EnvironmentHumidity > 100 {
+		return nil, fmt.Errorf("environment_humidity must be between 0 and 100, not %g", config.EnvironmentHumidity)
+	}
+	if config.InitialMaterialMoisture == 0 {
+		config.InitialMaterialMoisture = defaultInitialMaterialMoisture
+	}
+	if config.InitialMaterialMoisture < 0 {
+		return nil, fmt.Errorf("initial_material_moisture must not be negative, not %g", config.InitialMaterialMoisture)
+	}
+
 	log.Info("Simulator configuration loaded successfully from: %s", configPath)
 	return &config, nil
 }
@@ -0,0 +1,119 @@
+package elements
+
+import (
+	"sync"
+
+	"github.com/rmkhl/halko/types"
+)
+
+// Rates of the moisture model. Nothing here has been fitted against a real
+// kiln: they are picked so that steam visibly raises the humidity within a
+// step, the kiln vents back towards ambient over a quarter of an hour, and
+// wet wood keeps the air measurably damper than the room while it dries.
+const (
+	// How fast the kiln air trades vapour with the room, as a time constant
+	// in seconds, with the vent shut and with it open.
+	airExchangeTime  = 900
+	ventExchangeTime = 120
+	// Vapour pressure added per second of steam, in kPa.
+	steamVapourRate = 0.02
+	// How fast the wood approaches the EMC of the air around it, as a time
+	// constant in seconds.
+	woodDryingTime = 36 * 3600
+	// Vapour pressure added to the kiln air per percentage point of moisture
+	// content the wood gives up, in kPa.
+	vapourPerMoisture = 5.0
+)
+
+// KilnAir models the water in the kiln: the vapour pressure of its air and the
+// moisture content of the wood. The air trades vapour with the room, gains it
+// from the steam generator and from the drying wood, and can hold no more than
+// saturation at the kiln temperature; the wood dries, or takes up water, towards
+// the EMC of that air.
+type KilnAir struct {
+	mutex           sync.RWMutex
+	vapour          float32 // kPa
+	moisture        float32 // % of dry weight
+	kilnTemp        float32
+	ambientVapour   float32
+	environmentTemp float32
+	initialMoisture float32
+}
+
+// NewKilnAir returns kiln air at room temperature and humidity, in percent,
+// around wood of the given moisture content.
+func NewKilnAir(environmentTemp, environmentHumidity, woodMoisture float32) *KilnAir {
+	ambient := float32(types.SaturationVapourPressure(float64(environmentTemp))) * environmentHumidity / 100
+	return &KilnAir{
+		vapour:          ambient,
+		moisture:        woodMoisture,
+		kilnTemp:        environmentTemp,
+		ambientVapour:   ambient,
+		environmentTemp: environmentTemp,
+		initialMoisture: woodMoisture,
+	}
+}
+
+// Tick advances the model by seconds at the given kiln temperature.
+func (a *KilnAir) Tick(kilnTemp float32, steamOn, ventOn bool, seconds float32) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.kilnTemp = kilnTemp
+	emc := types.EquilibriumMoistureContent(kilnTemp, a.relativeHumidity())
+	dried := (a.moisture - emc) * min(seconds/woodDryingTime, 1)
+	a.moisture -= dried
+
+	exchangeTime := float32(airExchangeTime)
+	if ventOn {
+		exchangeTime = ventExchangeTime
+	}
+	a.vapour += dried * vapourPerMoisture
+	a.vapour -= (a.vapour - a.ambientVapour) * min(seconds/exchangeTime, 1)
+	if steamOn {
+		a.vapour += steamVapourRate * seconds
+	}
+
+	// Anything above saturation condenses out on the walls.
+	a.vapour = min(max(a.vapour, 0), float32(types.SaturationVapourPressure(float64(kilnTemp))))
+}
+
+// relativeHumidity is the air's humidity in percent. The caller holds the
+// mutex.
+func (a *KilnAir) relativeHumidity() float32 {
+	return 100 * a.vapour / float32(types.SaturationVapourPressure(float64(a.kilnTemp)))
+}
+
+// RelativeHumidity returns the kiln air's relative humidity in percent, what an
+// SHT3x in the kiln would read.
+func (a *KilnAir) RelativeHumidity() float32 {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.relativeHumidity()
+}
+
+// WetBulb returns the kiln air's wet-bulb temperature, what a thermocouple
+// under a wetted wick would read.
+func (a *KilnAir) WetBulb() float32 {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return types.WetBulbTemperature(a.kilnTemp, a.relativeHumidity())
+}
+
+// MoistureContent returns the wood's moisture content in percent of dry
+// weight.
+func (a *KilnAir) MoistureContent() float32 {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.moisture
+}
+
+// Reset returns the air to room conditions and the wood to its initial
+// moisture content.
+func (a *KilnAir) Reset() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.vapour = a.ambientVapour
+	a.moisture = a.initialMoisture
+	a.kilnTemp = a.environmentTemp
+}
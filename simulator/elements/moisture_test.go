@@ -0,0 +1,75 @@
+package elements
+
+import "testing"
+
+func TestKilnAirStartsAtRoomHumidity(t *testing.T) {
+	air := NewKilnAir(20, 60, 50)
+
+	if got := air.RelativeHumidity(); got < 59.9 || got > 60.1 {
+		t.Errorf("RelativeHumidity() = %.2f, want 60", got)
+	}
+	if got := air.WetBulb(); got >= 20 {
+		t.Errorf("WetBulb() = %.2f, want below the 20°C dry bulb", got)
+	}
+}
+
+func TestKilnAirSteamRaisesHumidityUpToSaturation(t *testing.T) {
+	air := NewKilnAir(20, 60, 20)
+
+	air.Tick(50, false, false, 60)
+	dry := air.RelativeHumidity()
+	for range 60 {
+		air.Tick(50, true, false, 60)
+	}
+
+	steamed := air.RelativeHumidity()
+	if steamed <= dry {
+		t.Errorf("humidity after steaming = %.1f%%, want above %.1f%%", steamed, dry)
+	}
+	if steamed > 100.01 {
+		t.Errorf("humidity after steaming = %.1f%%, want at most saturation", steamed)
+	}
+}
+
+// Heating the kiln with the vents shut drops the relative humidity, and the
+// wood gives up water towards the drier air's EMC.
+func TestKilnAirDriesTheWoodWhenHeated(t *testing.T) {
+	air := NewKilnAir(20, 60, 40)
+
+	for range 24 * 60 {
+		air.Tick(60, false, false, 60)
+	}
+
+	if got := air.RelativeHumidity(); got >= 60 {
+		t.Errorf("humidity at 60°C = %.1f%%, want below the room's 60%%", got)
+	}
+	if got := air.MoistureContent(); got >= 40 {
+		t.Errorf("moisture content after a day at 60°C = %.1f%%, want below 40%%", got)
+	}
+
+	air.Reset()
+	if got := air.MoistureContent(); got != 40 {
+		t.Errorf("moisture content after Reset() = %.1f%%, want 40%%", got)
+	}
+}
+
+// Opening the vent trades the damp kiln air for the room's much faster than
+// the leaks of a shut kiln do.
+func TestKilnAirVentDriesTheAir(t *testing.T) {
+	shut := NewKilnAir(20, 60, 20)
+	open := NewKilnAir(20, 60, 20)
+	for range 30 {
+		shut.Tick(50, true, false, 60)
+		open.Tick(50, true, false, 60)
+	}
+
+	for range 5 {
+		shut.Tick(50, false, false, 60)
+		open.Tick(50, false, true, 60)
+	}
+
+	if open.RelativeHumidity() >= shut.RelativeHumidity() {
+		t.Errorf("humidity with the vent open = %.1f%%, want below %.1f%% with it shut",
+			open.RelativeHumidity(), shut.RelativeHumidity())
+	}
+}
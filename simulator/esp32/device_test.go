@@ -73,7 +73,7 @@ func newTestDevice(t *testing.T) (*Device, string) {
 		{Name: "KilnPrimary", Sensor: heater},
 		{Name: "KilnSecondary", Sensor: heater},
 		{Name: "Wood", Sensor: wood},
-	}, faults.New(false), &recordingDisplay{})
+	}, faults.New(false, ""), &recordingDisplay{})
 
 	go device.Serve(responder)
 	return device, path
@@ -219,7 +219,7 @@ func TestServeReturnsAfterClose(t *testing.T) {
 		t.Fatalf("failed to open the device: %v", err)
 	}
 
-	responder, _ := newTestResponder(faults.New(false))
+	responder, _ := newTestResponder(faults.New(false, ""))
 
 	done := make(chan struct{})
 	go func() {
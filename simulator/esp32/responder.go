@@ -3,6 +3,7 @@ package esp32
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rmkhl/halko/simulator/engine"
@@ -25,6 +26,19 @@ type Probe struct {
 	Sensor engine.TemperatureSensor
 }
 
+// ClimateSource is what the humidity sensor reads: the kiln air's relative
+// humidity and wet-bulb temperature.
+type ClimateSource interface {
+	RelativeHumidity() float32
+	WetBulb() float32
+}
+
+// MoistureSource is what the moisture pins read: the wood's moisture content,
+// in percent of dry weight.
+type MoistureSource interface {
+	MoistureContent() float32
+}
+
 // DisplayObserver receives the text of each `show` command, which is how the
 // simulator learns that a program has started or stopped.
 type DisplayObserver interface {
@@ -37,6 +51,28 @@ type Responder struct {
 	probes  []Probe
 	faults  *faults.Injector
 	display DisplayObserver
+
+	// The humidity sensor the board is fitted with, and what it reads.
+	// Without one `clim` reports neither value, as the firmware does.
+	humiditySensor types.HumiditySensor
+	climate        ClimateSource
+
+	// The moisture pins the board is fitted with, what they read and the
+	// wood temperature that reading is taken at. Without them `mois`
+	// reports every pair unfitted, as the firmware does.
+	moistureProbes *types.MoistureProbes
+	moisture       MoistureSource
+	wood           engine.TemperatureSensor
+
+	// The safety switches. Set over HTTP while the device goroutine answers
+	// `inpt` from them, hence the lock. Both start in their safe state, which
+	// the real hardware only reports with the stop released and the door shut.
+	// The confirm button is latched like the firmware's: pressed until the
+	// next `inpt` reports it.
+	inputsMu       sync.Mutex
+	emergencyStop  bool
+	doorOpen       bool
+	confirmPressed bool
 }
 
 // NewResponder returns a responder over the given probes, which are reported
@@ -45,6 +81,23 @@ func NewResponder(probes []Probe, injector *faults.Injector, display DisplayObse
 	return &Responder{probes: probes, faults: injector, display: display}
 }
 
+// SetClimate fits the board with a humidity sensor of the given kind, reading
+// from source.
+func (r *Responder) SetClimate(sensor types.HumiditySensor, source ClimateSource) {
+	r.humiditySensor = sensor
+	r.climate = source
+}
+
+// SetMoisture fits the board with moisture pins, reading source's moisture
+// content in wood at the temperature of wood. The pins answer on the
+// reference scale, so the configured species correction is worked backwards
+// for the sensor unit to work it forwards again.
+func (r *Responder) SetMoisture(probes *types.MoistureProbes, source MoistureSource, wood engine.TemperatureSensor) {
+	r.moistureProbes = probes
+	r.moisture = source
+	r.wood = wood
+}
+
 // Respond returns the bytes to write back for one command, or nil where the
 // firmware stays silent.
 func (r *Responder) Respond(command string, now time.Time) []byte {
@@ -55,6 +108,12 @@ func (r *Responder) Respond(command string, now time.Time) []byte {
 		return []byte("helo\r\n")
 	case readCommand:
 		return r.readLine(now)
+	case "inpt":
+		return r.inputsLine()
+	case "clim":
+		return r.climateLine()
+	case "mois":
+		return r.moistureLine()
 	case "show":
 		r.display.OnDisplayMessage(argument)
 		return nil
@@ -68,13 +127,14 @@ func (r *Responder) Respond(command string, now time.Time) []byte {
 }
 
 // readLine formats one temperature report, applying any injected failures
-// first so a failed probe reports NaN exactly as the firmware does.
+// first so a failed probe reports NaN and its fault bits exactly as the
+// firmware does.
 func (r *Responder) readLine(now time.Time) []byte {
 	values := make(types.TemperatureResponse, len(r.probes))
 	for _, probe := range r.probes {
 		values[probe.Name] = probe.Sensor.Temperature()
 	}
-	r.faults.Apply(values, now)
+	faults := r.faults.Apply(values, now)
 
 	var line strings.Builder
 	for n, probe := range r.probes {
@@ -84,7 +144,10 @@ func (r *Responder) readLine(now time.Time) []byte {
 		line.WriteString(probe.Name)
 		line.WriteByte('=')
 		if values[probe.Name] == types.InvalidTemperatureReading {
-			line.WriteString("NaN")
+			// A probe failing without an injected fault is one the
+			// simulation cannot read at all, which is what a chip that
+			// does not answer looks like.
+			fmt.Fprintf(&line, "NaN:%d", faults[probe.Name].Bits())
 		} else {
 			fmt.Fprintf(&line, "%.2fC", values[probe.Name])
 		}
@@ -93,3 +156,77 @@ func (r *Responder) readLine(now time.Time) []byte {
 
 	return []byte(line.String())
 }
+
+// SetInputs sets the emergency stop and door switch states the next `inpt`
+// command reports.
+func (r *Responder) SetInputs(emergencyStop, doorOpen bool) {
+	r.inputsMu.Lock()
+	defer r.inputsMu.Unlock()
+
+	if r.emergencyStop != emergencyStop || r.doorOpen != doorOpen {
+		log.Info("Safety inputs set: emergency stop %t, door open %t", emergencyStop, doorOpen)
+	}
+	r.emergencyStop = emergencyStop
+	r.doorOpen = doorOpen
+}
+
+// Inputs returns the emergency stop and door switch states.
+func (r *Responder) Inputs() (emergencyStop, doorOpen bool) {
+	r.inputsMu.Lock()
+	defer r.inputsMu.Unlock()
+	return r.emergencyStop, r.doorOpen
+}
+
+// PressConfirm presses the operator's confirm button.
+func (r *Responder) PressConfirm() {
+	r.inputsMu.Lock()
+	defer r.inputsMu.Unlock()
+
+	log.Info("Confirm button pressed")
+	r.confirmPressed = true
+}
+
+// inputsLine formats the switch report the way the firmware prints it,
+// releasing a confirm press it reports.
+func (r *Responder) inputsLine() []byte {
+	r.inputsMu.Lock()
+	defer r.inputsMu.Unlock()
+
+	confirm := r.confirmPressed
+	r.confirmPressed = false
+	return []byte(fmt.Sprintf("EStop=%d,Door=%d,Confirm=%d\r\n", bit(r.emergencyStop), bit(r.doorOpen), bit(confirm)))
+}
+
+// climateLine formats the humidity report the way the firmware prints it:
+// only the quantity the fitted sensor measures has a value.
+func (r *Responder) climateLine() []byte {
+	humidity, wetBulb := "NaN", "NaN"
+	switch r.humiditySensor {
+	case types.HumiditySensorSHT3x:
+		humidity = fmt.Sprintf("%.2f%%", r.climate.RelativeHumidity())
+	case types.HumiditySensorWetBulb:
+		wetBulb = fmt.Sprintf("%.2fC", r.climate.WetBulb())
+	}
+	return []byte(fmt.Sprintf("Humidity=%s,WetBulb=%s\r\n", humidity, wetBulb))
+}
+
+// moistureLine formats the moisture report the way the firmware prints it:
+// every pair, the fitted ones reading the same wood.
+func (r *Responder) moistureLine() []byte {
+	fields := make([]string, types.MaxMoistureProbes)
+	for n := range fields {
+		value := "NaN"
+		if r.moistureProbes != nil && n < r.moistureProbes.Count {
+			value = fmt.Sprintf("%.2f%%", r.moistureProbes.MoistureReading(r.moisture.MoistureContent(), r.wood.Temperature()))
+		}
+		fields[n] = fmt.Sprintf("MC%d=%s", n+1, value)
+	}
+	return []byte(strings.Join(fields, ",") + "\r\n")
+}
+
+func bit(set bool) int {
+	if set {
+		return 1
+	}
+	return 0
+}
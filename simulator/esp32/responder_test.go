@@ -1,11 +1,13 @@
 package esp32
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/rmkhl/halko/simulator/elements"
 	"github.com/rmkhl/halko/simulator/faults"
+	"github.com/rmkhl/halko/types"
 )
 
 // recordingDisplay captures the display texts the responder forwards. It is
@@ -34,7 +36,7 @@ func newTestResponder(injector *faults.Injector) (*Responder, *recordingDisplay)
 }
 
 func TestRespondHelo(t *testing.T) {
-	r, _ := newTestResponder(faults.New(false))
+	r, _ := newTestResponder(faults.New(false, ""))
 
 	if got := string(r.Respond("helo", time.Now())); got != "helo\r\n" {
 		t.Fatalf("expected %q, got %q", "helo\r\n", got)
@@ -42,7 +44,7 @@ func TestRespondHelo(t *testing.T) {
 }
 
 func TestRespondReadMatchesTheFirmwareFormat(t *testing.T) {
-	r, _ := newTestResponder(faults.New(false))
+	r, _ := newTestResponder(faults.New(false, ""))
 
 	want := "KilnPrimary=20.00C,KilnSecondary=20.00C,Wood=20.00C\r\n"
 	if got := string(r.Respond(readCommand, time.Now())); got != want {
@@ -51,7 +53,7 @@ func TestRespondReadMatchesTheFirmwareFormat(t *testing.T) {
 }
 
 func TestRespondReadReportsFailedProbesAsNaN(t *testing.T) {
-	injector := faults.New(true)
+	injector := faults.New(true, types.ProbeFaultShortToGND)
 	r, _ := newTestResponder(injector)
 
 	// Arm the schedule, then read past the final stage, where every probe
@@ -59,14 +61,14 @@ func TestRespondReadReportsFailedProbesAsNaN(t *testing.T) {
 	start := time.Now()
 	injector.Observe(90.0, 20.0, start)
 
-	want := "KilnPrimary=NaN,KilnSecondary=NaN,Wood=NaN\r\n"
+	want := "KilnPrimary=NaN:2,KilnSecondary=NaN:2,Wood=NaN:2\r\n"
 	if got := string(r.Respond(readCommand, start.Add(5*time.Minute))); got != want {
 		t.Fatalf("expected %q, got %q", want, got)
 	}
 }
 
 func TestRespondShowForwardsTheDisplayTextAndStaysSilent(t *testing.T) {
-	r, display := newTestResponder(faults.New(false))
+	r, display := newTestResponder(faults.New(false, ""))
 
 	if got := r.Respond("show Pre-Heat", time.Now()); got != nil {
 		t.Fatalf("expected no response to show, got %q", got)
@@ -77,7 +79,7 @@ func TestRespondShowForwardsTheDisplayTextAndStaysSilent(t *testing.T) {
 }
 
 func TestRespondShowWithoutTextForwardsAnEmptyString(t *testing.T) {
-	r, display := newTestResponder(faults.New(false))
+	r, display := newTestResponder(faults.New(false, ""))
 
 	r.Respond("show", time.Now())
 	if len(display.messages) != 1 || display.messages[0] != "" {
@@ -86,7 +88,7 @@ func TestRespondShowWithoutTextForwardsAnEmptyString(t *testing.T) {
 }
 
 func TestRespondAddrStaysSilentAndDoesNotTouchTheDisplay(t *testing.T) {
-	r, display := newTestResponder(faults.New(false))
+	r, display := newTestResponder(faults.New(false, ""))
 
 	if got := r.Respond("addr 192.168.1.10", time.Now()); got != nil {
 		t.Fatalf("expected no response to addr, got %q", got)
@@ -97,9 +99,103 @@ func TestRespondAddrStaysSilentAndDoesNotTouchTheDisplay(t *testing.T) {
 }
 
 func TestRespondIgnoresUnknownCommands(t *testing.T) {
-	r, _ := newTestResponder(faults.New(false))
+	r, _ := newTestResponder(faults.New(false, ""))
 
 	if got := r.Respond("wibble", time.Now()); got != nil {
 		t.Fatalf("expected no response to an unknown command, got %q", got)
 	}
 }
+
+func TestRespondInputsStartsSafe(t *testing.T) {
+	r, _ := newTestResponder(faults.New(false, ""))
+
+	want := "EStop=0,Door=0,Confirm=0\r\n"
+	if got := string(r.Respond("inpt", time.Now())); got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+func TestRespondInputsReportsWhatWasSet(t *testing.T) {
+	r, _ := newTestResponder(faults.New(false, ""))
+
+	tests := []struct {
+		emergencyStop, doorOpen bool
+		want                    string
+	}{
+		{true, false, "EStop=1,Door=0,Confirm=0\r\n"},
+		{false, true, "EStop=0,Door=1,Confirm=0\r\n"},
+		{true, true, "EStop=1,Door=1,Confirm=0\r\n"},
+		{false, false, "EStop=0,Door=0,Confirm=0\r\n"},
+	}
+	for _, tt := range tests {
+		r.SetInputs(tt.emergencyStop, tt.doorOpen)
+		if got := string(r.Respond("inpt", time.Now())); got != tt.want {
+			t.Errorf("with estop=%t door=%t: expected %q, got %q", tt.emergencyStop, tt.doorOpen, tt.want, got)
+		}
+	}
+}
+
+// A press is reported by the one `inpt` that follows it, like the firmware's
+// latch.
+func TestRespondInputsReportsAConfirmPressOnce(t *testing.T) {
+	r, _ := newTestResponder(faults.New(false, ""))
+
+	r.PressConfirm()
+	for _, want := range []string{"EStop=0,Door=0,Confirm=1\r\n", "EStop=0,Door=0,Confirm=0\r\n"} {
+		if got := string(r.Respond("inpt", time.Now())); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+// fixedClimate is a climate source that always reads the same.
+type fixedClimate struct{}
+
+func (fixedClimate) RelativeHumidity() float32 { return 58.25 }
+func (fixedClimate) WetBulb() float32          { return 50.5 }
+
+func TestRespondClimateReportsWhatTheSensorMeasures(t *testing.T) {
+	tests := []struct {
+		sensor types.HumiditySensor
+		want   string
+	}{
+		{"", "Humidity=NaN,WetBulb=NaN\r\n"},
+		{types.HumiditySensorSHT3x, "Humidity=58.25%,WetBulb=NaN\r\n"},
+		{types.HumiditySensorWetBulb, "Humidity=NaN,WetBulb=50.50C\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.sensor), func(t *testing.T) {
+			r, _ := newTestResponder(faults.New(false, ""))
+			r.SetClimate(tt.sensor, fixedClimate{})
+
+			if got := string(r.Respond("clim", time.Now())); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// fixedMoisture is a moisture source that always reads the same.
+type fixedMoisture struct{}
+
+func (fixedMoisture) MoistureContent() float32 { return 14 }
+
+func TestRespondMoistureReportsTheFittedPairs(t *testing.T) {
+	r, _ := newTestResponder(faults.New(false, ""))
+	if got, want := string(r.Respond("mois", time.Now())), "MC1=NaN,MC2=NaN,MC3=NaN,MC4=NaN\r\n"; got != want {
+		t.Errorf("without pins expected %q, got %q", want, got)
+	}
+
+	probes := &types.MoistureProbes{Count: 2, SpeciesSlope: 0.9, SpeciesIntercept: 1}
+	wood := elements.NewWood(60, 20)
+	r.SetMoisture(probes, fixedMoisture{}, wood)
+
+	// Read on the reference scale at the wood's temperature, which the
+	// sensor unit's correction takes back to the 14% the wood holds.
+	reading := fmt.Sprintf("%.2f%%", probes.MoistureReading(14, 60))
+	want := "MC1=" + reading + ",MC2=" + reading + ",MC3=NaN,MC4=NaN\r\n"
+	if got := string(r.Respond("mois", time.Now())); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
@@ -46,28 +46,37 @@ func SensorNames() []string {
 // Injector rewrites temperature readings to types.InvalidTemperatureReading on
 // an escalating schedule. It stays inert until Observe sees the kiln rise
 // above the material, which is the simulator's proxy for a program heating.
+// Each failure carries a fault kind, for the responder to report the way the
+// MAX31855 does: the configured one, or one picked at random per failure.
 //
 // The zero value is not usable; construct one with New. All methods are safe
 // to call on a nil or disabled Injector, in which case they do nothing.
 type Injector struct {
 	mu      sync.Mutex
 	enabled bool
+	// kind is the fault every failure reports, or empty to pick one at
+	// random for each.
+	kind    types.ProbeFault
 	rng     *rand.Rand
 	armed   bool
 	armedAt time.Time
 	// lost holds the sensors that fail on every read, in the order they
-	// were lost.
-	lost []string
+	// were lost, and lostFault the fault each one keeps reporting.
+	lost      []string
+	lostFault map[string]types.ProbeFault
 	// dropoutsLogged keeps the intermittent-stage announcement to one line
 	// rather than one per read.
 	dropoutsLogged bool
 }
 
-// New returns an Injector seeded from the clock. When enabled is false the
-// returned Injector never alters a reading.
-func New(enabled bool) *Injector {
+// New returns an Injector seeded from the clock that injects faults of the
+// given kind, or of every kind at random when kind is empty. When enabled is
+// false the returned Injector never alters a reading.
+func New(enabled bool, kind types.ProbeFault) *Injector {
 	seed := uint64(time.Now().UnixNano())
-	return newWithRNG(enabled, rand.New(rand.NewPCG(seed, seed>>32)))
+	i := newWithRNG(enabled, rand.New(rand.NewPCG(seed, seed>>32)))
+	i.kind = kind
+	return i
 }
 
 // newWithRNG builds an Injector over a caller-supplied source, so tests can
@@ -111,23 +120,25 @@ func (i *Injector) Reset() {
 	i.armed = false
 	i.armedAt = time.Time{}
 	i.lost = nil
+	i.lostFault = nil
 	i.dropoutsLogged = false
 	log.Info("Sensor failure injection reset")
 }
 
 // Apply rewrites readings that the current stage of the schedule says have
-// failed, replacing them with types.InvalidTemperatureReading. It mutates the
-// map in place and does nothing until the injector is armed.
-func (i *Injector) Apply(readings types.TemperatureResponse, now time.Time) {
+// failed, replacing them with types.InvalidTemperatureReading, and returns
+// the fault each rewritten sensor reports. It mutates the map in place and
+// does nothing until the injector is armed.
+func (i *Injector) Apply(readings types.TemperatureResponse, now time.Time) map[string]types.ProbeFault {
 	if i == nil || !i.enabled {
-		return
+		return nil
 	}
 
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
 	if !i.armed {
-		return
+		return nil
 	}
 
 	elapsed := now.Sub(i.armedAt)
@@ -138,15 +149,35 @@ func (i *Injector) Apply(readings types.TemperatureResponse, now time.Time) {
 	}
 	i.loseSensors(lostByElapsed(elapsed))
 
+	var faults map[string]types.ProbeFault
 	for _, sensor := range sensorNames {
 		if _, ok := readings[sensor]; !ok {
 			continue
 		}
-		if i.isLost(sensor) || (elapsed >= dropoutsBegin && i.rng.Float64() < dropoutProbability) {
-			readings[sensor] = types.InvalidTemperatureReading
-			log.Debug("Sensor failure injection: reporting %s as invalid", sensor)
+		fault, lost := i.lostFault[sensor]
+		if !lost {
+			if elapsed < dropoutsBegin || i.rng.Float64() >= dropoutProbability {
+				continue
+			}
+			fault = i.pickFault()
+		}
+		if faults == nil {
+			faults = make(map[string]types.ProbeFault)
 		}
+		readings[sensor] = types.InvalidTemperatureReading
+		faults[sensor] = fault
+		log.Debug("Sensor failure injection: reporting %s as invalid (%s)", sensor, fault)
 	}
+	return faults
+}
+
+// pickFault returns the configured fault kind, or a random one when none is.
+// Callers hold i.mu.
+func (i *Injector) pickFault() types.ProbeFault {
+	if i.kind != "" {
+		return i.kind
+	}
+	return types.ProbeFaults[i.rng.IntN(len(types.ProbeFaults))]
 }
 
 // lostByElapsed returns how many probes should be failing permanently by the
@@ -179,9 +210,14 @@ func (i *Injector) loseSensors(wanted int) {
 		}
 
 		sensor := remaining[i.rng.IntN(len(remaining))]
+		fault := i.pickFault()
 		i.lost = append(i.lost, sensor)
-		log.Info("Sensor failure injection: %s sensor lost, now failing on every read (%d of %d lost)",
-			sensor, len(i.lost), len(sensorNames))
+		if i.lostFault == nil {
+			i.lostFault = make(map[string]types.ProbeFault)
+		}
+		i.lostFault[sensor] = fault
+		log.Info("Sensor failure injection: %s sensor lost (%s), now failing on every read (%d of %d lost)",
+			sensor, fault, len(i.lost), len(sensorNames))
 	}
 }
 
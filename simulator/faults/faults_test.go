@@ -284,3 +284,40 @@ func TestNilInjectorApplyIsSafe(t *testing.T) {
 		t.Fatalf("expected a nil injector to leave readings alone, got %v", r)
 	}
 }
+
+// A configured kind is what every failure reports, and a lost probe keeps
+// reporting the fault it was lost with.
+func TestApplyReportsTheConfiguredFault(t *testing.T) {
+	for _, kind := range types.ProbeFaults {
+		t.Run(string(kind), func(t *testing.T) {
+			i, start := armedInjector(t)
+			i.kind = kind
+
+			r := readings()
+			faults := i.Apply(r, start.Add(241*time.Second))
+			if len(faults) != len(sensorNames) {
+				t.Fatalf("faults = %v, want one for every probe", faults)
+			}
+			for sensor, fault := range faults {
+				if fault != kind {
+					t.Errorf("%s reports %s, want %s", sensor, fault, kind)
+				}
+			}
+		})
+	}
+}
+
+func TestLostProbesKeepTheirFault(t *testing.T) {
+	i, start := armedInjector(t)
+	at := start.Add(241 * time.Second)
+
+	first := i.Apply(readings(), at)
+	for n := 0; n < 20; n++ {
+		again := i.Apply(readings(), at)
+		for sensor, fault := range first {
+			if again[sensor] != fault {
+				t.Fatalf("%s reported %s, then %s", sensor, fault, again[sensor])
+			}
+		}
+	}
+}
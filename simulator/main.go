@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
 	"sync"
 	"syscall"
 	"time"
@@ -30,6 +31,7 @@ func main() {
 
 	// Sensor failure injection, off unless requested
 	failSensors := flag.Bool("fail-sensors", false, "Inject escalating temperature sensor failures during a run")
+	sensorFault := flag.String("sensor-fault", "", "Fault the injected failures report: open_circuit, short_to_gnd, short_to_vcc or no_response (default: each at random)")
 
 	// Parse global options and load configurations
 	opts, err := types.ParseGlobalOptions()
@@ -105,15 +107,25 @@ func main() {
 	fan.TurnOn(false) // Start the power controller in off state
 	steam := elements.NewPower("Steam")
 	steam.TurnOn(false) // Start the power controller in off state
+	vent := elements.NewPower("Vent")
+	vent.TurnOn(false) // Start the power controller in off state
 	wood := elements.NewWood(float32(simConfig.InitialMaterialTemp), float32(simConfig.EnvironmentTemp))
 	heater := elements.NewHeater("kiln", float32(simConfig.InitialKilnTemp), float32(simConfig.EnvironmentTemp), wood)
 	heater.TurnOn(false) // Start the heater power controller in off state
-	log.Info("Initialized simulation elements: Fan, Steam, Heater (kiln: %.1f°C), Wood (material: %.1f°C), Environment: %.1f°C",
-		simConfig.InitialKilnTemp, simConfig.InitialMaterialTemp, simConfig.EnvironmentTemp)
+	kilnAir := elements.NewKilnAir(float32(simConfig.EnvironmentTemp), float32(simConfig.EnvironmentHumidity), float32(simConfig.InitialMaterialMoisture))
+	log.Info("Initialized simulation elements: Fan, Steam, Vent, Heater (kiln: %.1f°C), Wood (material: %.1f°C, %.0f%% moisture), Environment: %.1f°C at %.0f%% RH",
+		simConfig.InitialKilnTemp, simConfig.InitialMaterialTemp, simConfig.InitialMaterialMoisture, simConfig.EnvironmentTemp, simConfig.EnvironmentHumidity)
 
-	faultInjector := faults.New(*failSensors)
+	faultKind := types.ProbeFault(*sensorFault)
+	if faultKind != "" && !slices.Contains(types.ProbeFaults, faultKind) {
+		log.Fatal("Unknown -sensor-fault %q, want one of %v", *sensorFault, types.ProbeFaults)
+	}
+	faultInjector := faults.New(*failSensors, faultKind)
 	if *failSensors {
 		log.Info("Sensor failure injection enabled: the schedule starts once the kiln temperature rises above the material temperature")
+		if faultKind != "" {
+			log.Info("Injected failures report %s", faultKind)
+		}
 	}
 
 	// Initialize physics state
@@ -131,6 +143,7 @@ func main() {
 		"heater": heater,
 		"fan":    fan,
 		"steam":  steam,
+		"vent":   vent,
 	}
 
 	// Map power controls using configuration
@@ -161,6 +174,8 @@ func main() {
 		Wood:                wood,
 		Fan:                 fan,
 		Steam:               steam,
+		Vent:                vent,
+		Air:                 kilnAir,
 		PhysicsState:        physicsState,
 		Faults:              faultInjector,
 		InitialKilnTemp:     float32(simConfig.InitialKilnTemp),
@@ -201,6 +216,15 @@ func main() {
 	}
 
 	responder := esp32.NewResponder(probes, faultInjector, resetter)
+	if sensor := config.SensorUnit.HumiditySensor; sensor != "" {
+		responder.SetClimate(sensor, kilnAir)
+		log.Info("Emulating a %s humidity sensor", sensor)
+	}
+	if probes := config.SensorUnit.MoistureProbes; probes != nil {
+		responder.SetMoisture(probes, kilnAir, wood)
+		log.Info("Emulating %d pairs of moisture pins", probes.Count)
+	}
+	router.SetupInputRoutes(shellyMux, responder)
 
 	shellySrv := &http.Server{
 		Addr:    ":" + shellyPort,
@@ -223,6 +247,7 @@ func main() {
 				// Advance power state machines
 				fan.Tick()
 				steam.Tick()
+				vent.Tick()
 				heater.Tick()
 
 				// Update physics state from power states
@@ -236,6 +261,10 @@ func main() {
 				// Apply physics results back to elements
 				heater.SetTemperature(physicsState.KilnTemp)
 				wood.SetTemperature(physicsState.MaterialTemp)
+				// The vent only moves moisture here: the physics engines have no
+				// term for the heat it lets out.
+				_, ventOn := vent.Info()
+				kilnAir.Tick(physicsState.KilnTemp, physicsState.SteamIsOn, ventOn, float32(timeStepSeconds))
 
 				faultInjector.Observe(physicsState.KilnTemp, physicsState.MaterialTemp, time.Now())
 
@@ -244,8 +273,9 @@ func main() {
 					_, heaterPower := heater.Info()
 					_, fanPower := fan.Info()
 					_, steamPower := steam.Info()
-					log.Info("Simulation status - Tick #%d: Kiln=%.1f°C, Material=%.1f°C, Heater=%v, Fan=%v, Steam=%v",
-						tickCount, heater.Temperature(), wood.Temperature(), heaterPower, fanPower, steamPower)
+					_, ventPower := vent.Info()
+					log.Info("Simulation status - Tick #%d: Kiln=%.1f°C, Material=%.1f°C, RH=%.0f%%, MC=%.1f%%, Heater=%v, Fan=%v, Steam=%v, Vent=%v",
+						tickCount, heater.Temperature(), wood.Temperature(), kilnAir.RelativeHumidity(), kilnAir.MoistureContent(), heaterPower, fanPower, steamPower, ventPower)
 				}
 			case <-stop:
 				log.Info("Stopping simulation loop at tick #%d", tickCount)
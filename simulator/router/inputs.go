@@ -0,0 +1,85 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/rmkhl/halko/types"
+	"github.com/rmkhl/halko/types/log"
+)
+
+// SafetyInputs is the emulated ESP32's emergency stop and door switch, and
+// the operator's confirm button beside them.
+type SafetyInputs interface {
+	Inputs() (emergencyStop, doorOpen bool)
+	SetInputs(emergencyStop, doorOpen bool)
+	PressConfirm()
+}
+
+// SetupInputRoutes adds the endpoints that flip the emulated safety switches.
+// They have no Shelly counterpart; they sit on the same server so there is one
+// simulator port to remember.
+func SetupInputRoutes(mux *http.ServeMux, inputs SafetyInputs) {
+	mux.HandleFunc("GET /sim/inputs", readInputs(inputs))
+	mux.HandleFunc("POST /sim/inputs", setInputs(inputs))
+	mux.HandleFunc("POST /sim/confirm", pressConfirm(inputs))
+	log.Info("Safety input emulation initialized with 3 endpoints: GET /sim/inputs, POST /sim/inputs, POST /sim/confirm")
+}
+
+func writeInputs(w http.ResponseWriter, inputs SafetyInputs) {
+	emergencyStop, doorOpen := inputs.Inputs()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(types.SensorInputsResponse{
+		EmergencyStop: emergencyStop,
+		DoorOpen:      doorOpen,
+	}); err != nil {
+		log.Error("Failed to encode inputs response: %v", err)
+	}
+}
+
+func readInputs(inputs SafetyInputs) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		writeInputs(w, inputs)
+	}
+}
+
+// setInputs changes only the switches named in the query, so pressing the
+// stop does not silently shut a door someone opened a moment ago.
+func setInputs(inputs SafetyInputs) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		emergencyStop, doorOpen := inputs.Inputs()
+
+		for _, param := range []struct {
+			name  string
+			value *bool
+		}{
+			{"estop", &emergencyStop},
+			{"door", &doorOpen},
+		} {
+			raw := r.URL.Query().Get(param.name)
+			if raw == "" {
+				continue
+			}
+			value, err := strconv.ParseBool(raw)
+			if err != nil {
+				log.Warning("Invalid %s value in inputs request: %s", param.name, raw)
+				http.Error(w, "Invalid "+param.name+" value "+raw, http.StatusBadRequest)
+				return
+			}
+			*param.value = value
+		}
+
+		inputs.SetInputs(emergencyStop, doorOpen)
+		writeInputs(w, inputs)
+	}
+}
+
+// pressConfirm presses the confirm button once. It is a button rather than a
+// switch, so there is no state to set.
+func pressConfirm(inputs SafetyInputs) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		inputs.PressConfirm()
+		writeInputs(w, inputs)
+	}
+}
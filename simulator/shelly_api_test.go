@@ -39,6 +39,9 @@ const shellyTestConfig = `{
       "steam_ceiling": 100,
       "sensor_timeout": "120s",
       "execution_log_interval": "60s",
+      "door_open_action": "pause",
+      "degraded_sensor_action": "continue",
+      "plausibility": {"max_rate": 2.0, "confirm": 3},
       "equalize": {
         "delta": 2.0,
         "steam_prewarm": false,
@@ -52,10 +55,10 @@ const shellyTestConfig = `{
     "max_idle_time": "70s",
     "power_mapping": {"heater": 0, "steam": 1, "fan": 2}
   },
-  "sensorunit": {"serial_device": "%s", "baud_rate": 9600},
+  "sensorunit": {"serial_device": "%s", "baud_rate": 9600, "kiln_fusion": "max", "material_fusion": "min", "kiln_disagreement": {"threshold": 10.0, "window": "5m"}},
   "api_endpoints": {
     "controlunit": {"url": "http://localhost:8090", "status": "/status", "programs": "/programs", "engine": "/engine"},
-    "sensorunit": {"url": "http://localhost:8093", "status": "/status", "temperatures": "/temperatures", "die_temperatures": "/temperatures/die", "display": "/display"},
+    "sensorunit": {"url": "http://localhost:8093", "status": "/status", "temperatures": "/temperatures", "die_temperatures": "/temperatures/die", "diagnostics": "/temperatures/diagnostics", "climate": "/climate", "moisture": "/moisture", "inputs": "/inputs", "display": "/display"},
     "powerunit": {"url": "http://localhost:8092", "status": "/status", "power": "/power"}
   }
 }`
@@ -25,6 +25,11 @@ type TemperatureSetter interface {
 	SetTemperature(float32)
 }
 
+// Restorer is an element that knows its own starting state.
+type Restorer interface {
+	Reset()
+}
+
 // Switch is an element with only a power state.
 type Switch interface {
 	TurnOn(bool)
@@ -36,6 +41,11 @@ type Resetter struct {
 	Wood   TemperatureSetter
 	Fan    Switch
 	Steam  Switch
+	// Vent is the vent's power. Optional: a kiln without one has nothing to
+	// switch off.
+	Vent Switch
+	// Air is the moisture model. Optional: nil leaves nothing to reset.
+	Air Restorer
 	// PhysicsState is written here under mu, but the tick loop in
 	// simulator/main.go writes the same struct on every tick with no lock.
 	// That race predates this type — the old HTTP display handler had it
@@ -81,6 +91,12 @@ func (r *Resetter) Reset() {
 	r.Heater.TurnOn(false)
 	r.Fan.TurnOn(false)
 	r.Steam.TurnOn(false)
+	if r.Vent != nil {
+		r.Vent.TurnOn(false)
+	}
+	if r.Air != nil {
+		r.Air.Reset()
+	}
 
 	r.PhysicsState.KilnTemp = r.InitialKilnTemp
 	r.PhysicsState.MaterialTemp = r.InitialMaterialTemp
@@ -18,7 +18,7 @@ func newTestResetter() (*Resetter, *physics.SimulationState, *faults.Injector) {
 	fan := elements.NewPower("Fan")
 	steam := elements.NewPower("Steam")
 	state := &physics.SimulationState{KilnTemp: 20.0, MaterialTemp: 20.0, EnvironmentTemp: 20.0}
-	injector := faults.New(true)
+	injector := faults.New(true, "")
 
 	return &Resetter{
 		Heater:              heater,
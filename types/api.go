@@ -31,6 +31,30 @@ const (
 	ServiceNameSensorUnit  = "sensorunit"
 )
 
+// RunEventKind values
+const (
+	RunEventEmergencyStop RunEventKind = "emergency_stop"
+	RunEventDoorOpened    RunEventKind = "door_opened"
+	RunEventDoorClosed    RunEventKind = "door_closed"
+	RunEventSensorTimeout RunEventKind = "sensor_timeout"
+	RunEventPowerRefused  RunEventKind = "power_refused"
+	RunEventPowerAdjusted RunEventKind = "power_adjusted"
+
+	RunEventProbeDisagreement RunEventKind = "probe_disagreement"
+	RunEventSensorDegraded    RunEventKind = "sensor_degraded"
+	RunEventSensorRestored    RunEventKind = "sensor_restored"
+	RunEventReadingRejected   RunEventKind = "reading_rejected"
+
+	RunEventHoldDipped RunEventKind = "hold_dipped"
+	RunEventHoldMet    RunEventKind = "hold_met"
+	RunEventHoldNotMet RunEventKind = "hold_not_met"
+
+	RunEventSteamBlanketLost RunEventKind = "steam_blanket_lost"
+
+	RunEventOperatorConfirmed RunEventKind = "operator_confirmed"
+	RunEventConfirmTimedOut   RunEventKind = "confirm_timed_out"
+)
+
 const (
 	// signals invalid temperature reading
 	InvalidTemperatureReading = -273.15 // Absolute zero in Celsius, used to indicate an invalid reading
@@ -41,6 +65,16 @@ type StatusRequest struct {
 	Message string `json:"message"`
 }
 
+// StartProgramRequest is what a POST /engine/running body may carry besides
+// a program: the name of a stored program or template to start instead,
+// values for a template's parameters, and what to override in the stored
+// program for this run.
+type StartProgramRequest struct {
+	Template  string                    `json:"template,omitempty"`
+	Values    map[string]ParameterValue `json:"values,omitempty"`
+	Overrides *ProgramOverrides         `json:"overrides,omitempty"`
+}
+
 // DisplayRequest defines the structure for a display update request body
 type DisplayRequest struct {
 	Message string `json:"message"`
@@ -77,6 +111,16 @@ type (
 		Data T `json:"data"`
 	}
 
+	// ProgramConflict answers a write to a stored program whose If-Match no
+	// longer names the current version. It carries that version, so the
+	// client can show what changed underneath it and try again against ETag.
+	ProgramConflict struct {
+		Err      string  `json:"error"`
+		ETag     string  `json:"etag"`
+		Revision int     `json:"revision"`
+		Current  Program `json:"current"`
+	}
+
 	RunHistory struct {
 		Name        string       `json:"name"`
 		State       ProgramState `json:"state"`
@@ -86,7 +130,36 @@ type (
 
 	ExecutedProgram struct {
 		RunHistory
-		Program Program `json:"program"`
+		Program Program    `json:"program"`
+		Events  []RunEvent `json:"events,omitempty"`
+
+		// Compliance is the run's heat treatment record, for a program with
+		// a heat treatment step.
+		Compliance *ComplianceReport `json:"compliance,omitempty"`
+
+		// StoredProgramState says whether the stored program the run was
+		// started from has been edited or deleted since, for a run started
+		// by name.
+		StoredProgramState StoredProgramState `json:"stored_program_state,omitempty"`
+		// StoredProgramRevision is the revision of the stored program the
+		// run was started from, where it is still among its revisions.
+		StoredProgramRevision int `json:"stored_program_revision,omitempty"`
+	}
+
+	// RunEventKind says what kind of thing a RunEvent records, so a client
+	// can pick out, say, every pause in a run without parsing messages.
+	RunEventKind string
+
+	// RunEvent is something that happened to a run that its execution log
+	// cannot show: the log samples readings and power on an interval, so a
+	// door opened for twenty seconds or the reason a run failed falls between
+	// its lines. Events are kept with the run record for the same reason the
+	// log is - the question "why did this run do that" is asked afterwards.
+	RunEvent struct {
+		Time    int64        `json:"time"`
+		Step    string       `json:"step,omitempty"`
+		Kind    RunEventKind `json:"kind"`
+		Message string       `json:"message"`
 	}
 
 	ProgramListing struct {
@@ -109,22 +182,51 @@ type (
 		MaterialDie      float32 `json:"material_die"`
 		KilnPrimaryDie   float32 `json:"kiln_primary_die"`
 		KilnSecondaryDie float32 `json:"kiln_secondary_die"`
+		// Probes is each thermocouple's own reading, uncorrected and before
+		// the sensor unit fused them into Kiln and Material, keyed by probe
+		// name.
+		Probes map[string]float32 `json:"probes,omitempty"`
+		// Climate is the kiln air's humidity, present while the sensor
+		// unit has a humidity sensor reporting.
+		Climate *ClimateResponse `json:"climate,omitempty"`
+		// Moisture is the sample boards' moisture content, present while
+		// the sensor unit has moisture pins reporting.
+		Moisture *MoistureResponse `json:"moisture,omitempty"`
 	}
 
-	// PSUStatus represents the power level (in percentage) of the heater, fan, and steam.
+	// PSUStatus represents the power level (in percentage) of the heater, fan,
+	// steam and vent.
 	PSUStatus struct {
 		Heater int8 `json:"heater"`
 		Fan    int8 `json:"fan"`
 		Steam  int8 `json:"steam"`
+		Vent   int8 `json:"vent"`
 	}
 
 	ExecutionStatus struct {
-		Program              Program           `json:"program"`
-		StartedAt            int64             `json:"started_at,omitempty"`
-		CurrentStep          string            `json:"current_step,omitempty"`
-		CurrentStepStartedAt int64             `json:"current_step_started_at,omitempty"`
-		Temperatures         TemperatureStatus `json:"temperatures,omitempty"`
-		PowerStatus          PSUStatus         `json:"power_status,omitempty"`
+		Program              Program `json:"program"`
+		StartedAt            int64   `json:"started_at,omitempty"`
+		CurrentStep          string  `json:"current_step,omitempty"`
+		CurrentStepStartedAt int64   `json:"current_step_started_at,omitempty"`
+		// CurrentStepNumber is the running step's place in the program,
+		// counting from 1, and 0 before the first step and after the last.
+		// Step names are optional and may repeat; the number tells steps apart.
+		CurrentStepNumber int               `json:"current_step_number,omitempty"`
+		Temperatures      TemperatureStatus `json:"temperatures,omitempty"`
+		PowerStatus       PSUStatus         `json:"power_status,omitempty"`
+		// Paused names what the run is holding for with all power off, and is
+		// empty while it is running normally. The step's clock is stopped for
+		// as long as it is set.
+		Paused string     `json:"paused,omitempty"`
+		Events []RunEvent `json:"events,omitempty"`
+
+		// HeatTreatment is the hold's progress while a heat treatment step
+		// runs, and nil otherwise.
+		HeatTreatment *HeatTreatmentStatus `json:"heat_treatment,omitempty"`
+
+		// Confirmation is what the run is waiting for the operator to do
+		// while a confirm step runs, and nil otherwise.
+		Confirmation *ConfirmationStatus `json:"confirmation,omitempty"`
 	}
 )
 
@@ -132,6 +234,9 @@ type (
 type (
 	PowerResponse struct {
 		Percent uint8 `json:"percent"`
+		// What the power unit's limits and interlocks changed about the
+		// command, one sentence each. Empty when it was applied as sent.
+		Adjustments []string `json:"adjustments,omitempty"`
 	}
 
 	PowerStatusResponse map[string]PowerResponse
@@ -143,7 +248,8 @@ type (
 	PowersCommand map[string]PowerCommand
 
 	PowerOperationResponse struct {
-		Message string `json:"message"`
+		Message     string   `json:"message"`
+		Adjustments []string `json:"adjustments,omitempty"`
 	}
 )
 
@@ -156,6 +262,57 @@ type VPNOperationResponse struct {
 // Temperature sensor API
 type TemperatureResponse map[string]float32
 
+// ProbeDiagnostics is one probe's state as of the sensor unit's last read.
+// Faults is empty while the probe reads, and also for a failed probe whose
+// firmware does not report why.
+type ProbeDiagnostics struct {
+	Valid  bool         `json:"valid"`
+	Faults []ProbeFault `json:"faults,omitempty"`
+}
+
+// ProbeDiagnosticsResponse maps probe names to their diagnostics.
+type ProbeDiagnosticsResponse map[string]ProbeDiagnostics
+
+// ClimateResponse is the kiln air's moisture as the sensor unit's humidity
+// sensor reads it. One of RelativeHumidity and WetBulb is what Sensor
+// measured and the other is derived from it and DryBulb, the kiln
+// temperature; EMC is the moisture content wood settles at in that air.
+// Valid is false, and the values are left at zero, when the sensor failed or
+// there is no kiln temperature yet to work from.
+type ClimateResponse struct {
+	Sensor           HumiditySensor `json:"sensor"`
+	Valid            bool           `json:"valid"`
+	DryBulb          float32        `json:"dry_bulb"`
+	WetBulb          float32        `json:"wet_bulb"`
+	RelativeHumidity float32        `json:"relative_humidity"`
+	EMC              float32        `json:"emc"`
+}
+
+// MoistureResponse is the sample boards' moisture content, in percent of dry
+// weight, as the sensor unit's moisture pins read it once corrected for the
+// wood's temperature and species. Probes holds each pin pair that has a
+// reading, keyed by probe name (mc_1 upwards); Material is the wettest of
+// them, since a charge is only as dry as its wettest board. Temperature is
+// the material temperature the readings were corrected at. Valid is false,
+// and the values are left at zero, when no pair could be read or there is no
+// material temperature to correct them with.
+type MoistureResponse struct {
+	Valid       bool               `json:"valid"`
+	Material    float32            `json:"material"`
+	Temperature float32            `json:"temperature"`
+	Probes      map[string]float32 `json:"probes,omitempty"`
+}
+
+// SensorInputsResponse reports the safety switches wired to the sensor unit.
+// Both are true in their unsafe state, which is also what a broken wire
+// reads as. Confirm is the operator's confirm button, true when it has been
+// pressed since the sensor unit was last asked.
+type SensorInputsResponse struct {
+	EmergencyStop bool `json:"emergency_stop"`
+	DoorOpen      bool `json:"door_open"`
+	Confirm       bool `json:"confirm"`
+}
+
 // Shelly API responses
 type (
 	ShellySwitchGetStatusResponse struct {
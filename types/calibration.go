@@ -0,0 +1,86 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+type (
+	// ProbeCalibration corrects one thermocouple's reading. It is either linear,
+	// actual = raw*Gain + Offset with a Gain left out taken as 1, or a table of
+	// reference points interpolated between and extrapolated past along the
+	// nearest segment. Thermocouples from the same reel still disagree by a
+	// degree or two, and a correction taken at two or three known temperatures
+	// removes most of it.
+	ProbeCalibration struct {
+		Gain   float64            `json:"gain,omitempty"`
+		Offset float64            `json:"offset,omitempty"`
+		Points []CalibrationPoint `json:"points,omitempty"`
+	}
+
+	// CalibrationPoint pairs what a probe read with what it should have read,
+	// e.g. 0.8 in an ice bath that was 0.0.
+	CalibrationPoint struct {
+		Raw    float64 `json:"raw"`
+		Actual float64 `json:"actual"`
+	}
+)
+
+// validate rejects a calibration that could not be applied, or that would
+// reorder temperatures: a correction that made a hotter probe read colder
+// would hand the controllers a falling reading for a rising kiln.
+func (c *ProbeCalibration) validate() error {
+	if c == nil {
+		return errors.New("is empty")
+	}
+	if len(c.Points) == 0 {
+		if c.Gain < 0 {
+			return errors.New("needs a positive gain")
+		}
+		if c.Gain == 0 && c.Offset == 0 {
+			return errors.New("needs a gain, an offset or points")
+		}
+		return nil
+	}
+	if c.Gain != 0 || c.Offset != 0 {
+		return errors.New("has both gain/offset and points, use one")
+	}
+	if len(c.Points) < 2 {
+		return errors.New("needs at least two points (use offset for one)")
+	}
+	for i := 1; i < len(c.Points); i++ {
+		if c.Points[i].Raw <= c.Points[i-1].Raw || c.Points[i].Actual <= c.Points[i-1].Actual {
+			return fmt.Errorf("points must rise in both raw and actual, point %d does not", i+1)
+		}
+	}
+	return nil
+}
+
+// Apply returns the corrected reading. An invalid reading stays invalid, and a
+// nil calibration returns the reading unchanged.
+func (c *ProbeCalibration) Apply(raw float32) float32 {
+	if c == nil || raw == InvalidTemperatureReading {
+		return raw
+	}
+	if len(c.Points) == 0 {
+		return float32(float64(raw)*c.gain() + c.Offset)
+	}
+
+	// The segment containing the reading, or the end segment nearest to it.
+	value := float64(raw)
+	i := 1
+	for i < len(c.Points)-1 && value > c.Points[i].Raw {
+		i++
+	}
+	low, high := c.Points[i-1], c.Points[i]
+	slope := (high.Actual - low.Actual) / (high.Raw - low.Raw)
+	return float32(low.Actual + (value-low.Raw)*slope)
+}
+
+// gain is the linear calibration's gain, 1 when only an offset is given.
+func (c *ProbeCalibration) gain() float64 {
+	if c.Gain == 0 {
+		return 1
+	}
+	return c.Gain
+}
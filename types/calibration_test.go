@@ -0,0 +1,124 @@
+package types
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeConfigWithCalibration writes the standard test config with the given
+// JSON as its sensorunit calibration.
+func writeConfigWithCalibration(t *testing.T, calibration string) string {
+	t.Helper()
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test_halko.cfg")
+
+	data := strings.Replace(testConfigData, "/dev/ttyUSB0", filepath.Join(tempDir, "esp32"), 1)
+	data = strings.Replace(data, `"baud_rate": 9600`, `"baud_rate": 9600, "calibration": `+calibration, 1)
+
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return configPath
+}
+
+func near(got float32, want float64) bool {
+	return math.Abs(float64(got)-want) < 1e-4
+}
+
+func TestLinearCalibration(t *testing.T) {
+	c := &ProbeCalibration{Gain: 1.01, Offset: -0.8}
+
+	if got := c.Apply(100); !near(got, 100.2) {
+		t.Fatalf("Apply(100) = %v, want 100.2", got)
+	}
+}
+
+// The ice bath and boiling water readings that motivated this: a probe that
+// reads 0.8 and 101.2 should read 0 and 100.
+func TestPointCalibrationInterpolates(t *testing.T) {
+	c := &ProbeCalibration{Points: []CalibrationPoint{{Raw: 0.8, Actual: 0}, {Raw: 101.2, Actual: 100}}}
+
+	for raw, want := range map[float32]float64{0.8: 0, 101.2: 100, 51: 50} {
+		if got := c.Apply(raw); !near(got, want) {
+			t.Errorf("Apply(%v) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+// An offset on its own shifts the reading, a gain left out being 1.
+func TestOffsetOnlyCalibration(t *testing.T) {
+	c := &ProbeCalibration{Offset: -1.5}
+	if got := c.Apply(100); got != 98.5 {
+		t.Fatalf("Apply(100) = %v, want 98.5", got)
+	}
+}
+
+// Drying runs well past the last reference point, so the table has to carry on
+// along its end segments rather than stop at them.
+func TestPointCalibrationExtrapolatesAlongTheEndSegments(t *testing.T) {
+	c := &ProbeCalibration{Points: []CalibrationPoint{
+		{Raw: 1, Actual: 0},
+		{Raw: 51, Actual: 50},
+		{Raw: 102, Actual: 100},
+	}}
+
+	if got := c.Apply(153); !near(got, 150) {
+		t.Errorf("Apply(153) = %v, want 150 along the upper segment", got)
+	}
+	if got := c.Apply(-9); !near(got, -10) {
+		t.Errorf("Apply(-9) = %v, want -10 along the lower segment", got)
+	}
+	if got := c.Apply(76.5); !near(got, 75) {
+		t.Errorf("Apply(76.5) = %v, want 75 in the upper segment", got)
+	}
+}
+
+func TestCalibrationLeavesInvalidReadingsInvalid(t *testing.T) {
+	c := &ProbeCalibration{Gain: 1, Offset: 5}
+
+	if got := c.Apply(InvalidTemperatureReading); got != InvalidTemperatureReading {
+		t.Fatalf("Apply(invalid) = %v, want it left invalid", got)
+	}
+	var none *ProbeCalibration
+	if got := none.Apply(42); got != 42 {
+		t.Fatalf("nil calibration changed 42 to %v", got)
+	}
+}
+
+func TestLoadConfigValidatesCalibration(t *testing.T) {
+	tests := []struct {
+		name        string
+		calibration string
+		wantErr     string
+	}{
+		{"linear", `{"kiln_primary": {"gain": 1.0, "offset": -0.75}}`, ""},
+		{"points", `{"material": {"points": [{"raw": 0.8, "actual": 0}, {"raw": 101.2, "actual": 100}]}}`, ""},
+		{"second material probe", `{"material_2": {"gain": 1.0, "offset": 0.5}}`, ""},
+		{"unknown probe", `{"kiln": {"gain": 1.0}}`, "unknown probe"},
+		{"material probe beyond the firmware", `{"material_4": {"gain": 1.0}}`, "unknown probe"},
+		{"offset without gain", `{"kiln_primary": {"offset": -0.75}}`, ""},
+		{"negative gain", `{"kiln_primary": {"gain": -1.0}}`, "positive gain"},
+		{"nothing to correct with", `{"kiln_primary": {}}`, "needs a gain"},
+		{"both forms", `{"kiln_primary": {"gain": 1.0, "points": [{"raw": 0, "actual": 0}, {"raw": 100, "actual": 100}]}}`, "use one"},
+		{"one point", `{"kiln_primary": {"points": [{"raw": 0.8, "actual": 0}]}}`, "at least two"},
+		{"unordered points", `{"kiln_primary": {"points": [{"raw": 100, "actual": 100}, {"raw": 0, "actual": 0}]}}`, "must rise"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfigWithCalibration(t, tt.calibration))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("LoadConfig: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package types
+
+import (
+	"fmt"
+	"math"
+)
+
+// HumiditySensor values
+const (
+	HumiditySensorSHT3x   HumiditySensor = "sht3x"
+	HumiditySensorWetBulb HumiditySensor = "wet_bulb"
+)
+
+// The psychrometer equation relating a wet-bulb reading to the vapour
+// pressure of the air: e = es(wet) - A * P * (dry - wet). A is the
+// coefficient of a ventilated wet bulb, which is what one in the fan's air
+// stream is, and P is taken as standard pressure: the kiln is not sealed, and
+// the weather moves the answer by less than the sensor's own error.
+const (
+	psychrometerCoefficient = 6.6e-4 // per °C
+	atmosphericPressure     = 101.325
+)
+
+// HumiditySensor is what the sensor unit's board measures the kiln air's
+// moisture with, as the firmware's HUMIDITY_SENSOR says.
+//
+//   - sht3x is a capacitive sensor reporting relative humidity directly.
+//   - wet_bulb is a thermocouple under a wetted wick, whose evaporative
+//     cooling below the dry-bulb temperature gives the humidity.
+//
+// Whichever it is, the sensor unit derives the other quantity from it and
+// the kiln (dry-bulb) temperature, so schedules can be written in either.
+type HumiditySensor string
+
+func (s HumiditySensor) validate() error {
+	switch s {
+	case "", HumiditySensorSHT3x, HumiditySensorWetBulb:
+		return nil
+	default:
+		return fmt.Errorf("sensor unit humidity_sensor must be %q or %q, or left out without one, not %q",
+			HumiditySensorSHT3x, HumiditySensorWetBulb, s)
+	}
+}
+
+// SaturationVapourPressure returns the vapour pressure of saturated air at a
+// temperature in °C, in kPa, by the Buck equation. It holds to within a few
+// tenths of a percent from freezing to past the boiling point, which covers
+// every kiln schedule.
+func SaturationVapourPressure(temperature float64) float64 {
+	return 0.61121 * math.Exp((18.678-temperature/234.5)*(temperature/(257.14+temperature)))
+}
+
+// RelativeHumidity returns the relative humidity, in percent, that a wet-bulb
+// reading implies at a dry-bulb temperature. A wet bulb reading above the dry
+// bulb is condensation on the wick or a swapped pair of probes; it is taken as
+// saturated air rather than reported as over 100%.
+func RelativeHumidity(dryBulb, wetBulb float32) float32 {
+	dry, wet := float64(dryBulb), float64(min(wetBulb, dryBulb))
+	vapour := SaturationVapourPressure(wet) - psychrometerCoefficient*atmosphericPressure*(dry-wet)
+	return float32(math.Max(0, 100*vapour/SaturationVapourPressure(dry)))
+}
+
+// WetBulbTemperature returns the wet-bulb temperature a relative humidity in
+// percent implies at a dry-bulb temperature. The psychrometer equation has no
+// closed form in this direction, so it is solved by bisection: the implied
+// vapour pressure only grows with the wet-bulb temperature, and the answer
+// lies between the dew point region far below and the dry bulb itself.
+func WetBulbTemperature(dryBulb, relativeHumidity float32) float32 {
+	dry := float64(dryBulb)
+	vapour := math.Min(math.Max(float64(relativeHumidity), 0), 100) / 100 * SaturationVapourPressure(dry)
+
+	low, high := dry-60, dry
+	for range 50 {
+		wet := (low + high) / 2
+		if SaturationVapourPressure(wet)-psychrometerCoefficient*atmosphericPressure*(dry-wet) < vapour {
+			low = wet
+		} else {
+			high = wet
+		}
+	}
+	return float32((low + high) / 2)
+}
+
+// EquilibriumMoistureContent returns the moisture content, in percent of dry
+// weight, that wood settles at in air of the given temperature and relative
+// humidity. This is the Hailwood-Horrobin fit from the USDA Wood Handbook,
+// which is what drying schedules quoting EMC are written against; it is for
+// softwoods and is a fair guide for most hardwoods.
+func EquilibriumMoistureContent(temperature, relativeHumidity float32) float32 {
+	t := float64(temperature)
+	h := math.Min(math.Max(float64(relativeHumidity), 0), 100) / 100
+
+	w := 349 + 1.29*t + 0.0135*t*t
+	k := 0.805 + 0.000736*t - 0.00000273*t*t
+	k1 := 6.27 - 0.00938*t - 0.000303*t*t
+	k2 := 1.91 + 0.0407*t - 0.000293*t*t
+
+	kh := k * h
+	return float32(1800 / w * (kh/(1-kh) + (k1*kh+2*k1*k2*kh*kh)/(1+k1*kh+k1*k2*kh*kh)))
+}
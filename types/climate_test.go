@@ -0,0 +1,86 @@
+package types
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLoadConfigValidatesHumiditySensor(t *testing.T) {
+	tests := []struct {
+		name    string
+		sensor  string
+		wantErr bool
+	}{
+		{"absent", ``, false},
+		{"sht3x", `"humidity_sensor": "sht3x",`, false},
+		{"wet bulb", `"humidity_sensor": "wet_bulb",`, false},
+		{"unknown", `"humidity_sensor": "dht22",`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfigWithColdJunction(t, tt.sensor))
+			if tt.wantErr && err == nil {
+				t.Fatal("expected LoadConfig to fail, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+func within(got, want, tolerance float32) bool {
+	return math.Abs(float64(got-want)) <= float64(tolerance)
+}
+
+// Reference values from psychrometric tables at sea level.
+func TestRelativeHumidityFromWetBulb(t *testing.T) {
+	tests := []struct {
+		dry, wet, want float32
+	}{
+		{20, 20, 100},
+		{20, 15, 59},
+		{60, 50, 58},
+		{80, 70, 64},
+		{20, 25, 100}, // wet above dry reads as saturated
+	}
+
+	for _, tt := range tests {
+		if got := RelativeHumidity(tt.dry, tt.wet); !within(got, tt.want, 2) {
+			t.Errorf("RelativeHumidity(%v, %v) = %.1f, want about %v", tt.dry, tt.wet, got, tt.want)
+		}
+	}
+}
+
+func TestWetBulbInvertsRelativeHumidity(t *testing.T) {
+	for _, dry := range []float32{20, 45, 70, 90} {
+		for _, rh := range []float32{20, 50, 80, 100} {
+			wet := WetBulbTemperature(dry, rh)
+			if wet > dry+0.01 {
+				t.Errorf("WetBulbTemperature(%v, %v) = %.2f, above the dry bulb", dry, rh, wet)
+			}
+			if got := RelativeHumidity(dry, wet); !within(got, rh, 0.1) {
+				t.Errorf("RelativeHumidity(%v, WetBulbTemperature(%v, %v) = %.2f) = %.2f", dry, dry, rh, wet, got)
+			}
+		}
+	}
+}
+
+// Reference values from the Wood Handbook's EMC table.
+func TestEquilibriumMoistureContent(t *testing.T) {
+	tests := []struct {
+		temperature, rh, want float32
+	}{
+		{20, 65, 12.0},
+		{20, 30, 6.1},
+		{60, 80, 14.0},
+		{80, 50, 6.9},
+	}
+
+	for _, tt := range tests {
+		if got := EquilibriumMoistureContent(tt.temperature, tt.rh); !within(got, tt.want, 0.5) {
+			t.Errorf("EquilibriumMoistureContent(%v, %v) = %.1f, want about %v", tt.temperature, tt.rh, got, tt.want)
+		}
+	}
+}
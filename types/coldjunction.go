@@ -0,0 +1,55 @@
+package types
+
+import "errors"
+
+// ColdJunction is what the sensor unit checks the MAX31855 die temperatures
+// against. Each chip measures its own die and takes that as the temperature
+// of the screw terminals the thermocouple ends at; while the two agree the
+// reading is true, and once a board warms unevenly every reading on it
+// shifts by the difference, indistinguishable from the kiln changing.
+//
+// Reference is the temperature of the terminals and the air around the
+// sensor box in normal running, in °C, which a die should read close to.
+// MaxSpread is how far apart the dies of the boards may read before the
+// sensor unit warns that they are heating unevenly. Correction, when set,
+// is the fraction of a die's rise above Reference taken off its probe's
+// reading: 1 assumes the terminals stay at Reference however warm the die
+// gets, smaller values that they warm along with it.
+type ColdJunction struct {
+	Reference  float32  `json:"reference"`
+	MaxSpread  float32  `json:"max_spread"`
+	Correction *float32 `json:"correction,omitempty"`
+}
+
+func (c *ColdJunction) validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.MaxSpread <= 0 {
+		return errors.New("sensor unit cold_junction.max_spread must be greater than zero")
+	}
+	if c.Correction != nil && (*c.Correction <= 0 || *c.Correction > 1) {
+		return errors.New("sensor unit cold_junction.correction must be above 0 and at most 1, leave it out to not correct")
+	}
+	return nil
+}
+
+// Delta returns how far a die reads from Reference, or an invalid reading
+// when the die is invalid.
+func (c *ColdJunction) Delta(die float32) float32 {
+	if die == InvalidTemperatureReading {
+		return InvalidTemperatureReading
+	}
+	return die - c.Reference
+}
+
+// Correct returns a thermocouple reading with the configured share of its
+// die's drift taken off. The reading is returned unchanged without a
+// correction, and when either it or the die is invalid: a drift that cannot
+// be measured cannot be corrected.
+func (c *ColdJunction) Correct(value, die float32) float32 {
+	if c == nil || c.Correction == nil || value == InvalidTemperatureReading || die == InvalidTemperatureReading {
+		return value
+	}
+	return value - *c.Correction*c.Delta(die)
+}
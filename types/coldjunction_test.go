@@ -0,0 +1,73 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigWithColdJunction(t *testing.T, coldJunction string) string {
+	t.Helper()
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test_halko.cfg")
+
+	data := strings.Replace(testConfigData, "/dev/ttyUSB0", filepath.Join(tempDir, "esp32"), 1)
+	data = strings.Replace(data, `"kiln_fusion": "max",`, `"kiln_fusion": "max", `+coldJunction, 1)
+
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return configPath
+}
+
+func TestLoadConfigValidatesColdJunction(t *testing.T) {
+	tests := []struct {
+		name         string
+		coldJunction string
+		wantErr      bool
+	}{
+		{"absent", ``, false},
+		{"reference and spread", `"cold_junction": {"reference": 25, "max_spread": 5},`, false},
+		{"with correction", `"cold_junction": {"reference": 25, "max_spread": 5, "correction": 0.8},`, false},
+		{"no spread", `"cold_junction": {"reference": 25},`, true},
+		{"zero correction", `"cold_junction": {"reference": 25, "max_spread": 5, "correction": 0},`, true},
+		{"correction above one", `"cold_junction": {"reference": 25, "max_spread": 5, "correction": 1.5},`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfigWithColdJunction(t, tt.coldJunction))
+			if tt.wantErr && err == nil {
+				t.Fatal("expected LoadConfig to fail, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+func TestColdJunctionCorrect(t *testing.T) {
+	correction := float32(0.5)
+	c := &ColdJunction{Reference: 25, MaxSpread: 5, Correction: &correction}
+
+	if got := c.Correct(100, 35); got != 95 {
+		t.Errorf("Correct(100, 35) = %v, want half of the 10°C drift taken off", got)
+	}
+	if got := c.Correct(100, InvalidTemperatureReading); got != 100 {
+		t.Errorf("Correct with an invalid die = %v, want the reading unchanged", got)
+	}
+	if got := c.Correct(InvalidTemperatureReading, 35); got != InvalidTemperatureReading {
+		t.Errorf("Correct of an invalid reading = %v, want it invalid", got)
+	}
+
+	c.Correction = nil
+	if got := c.Correct(100, 35); got != 100 {
+		t.Errorf("Correct without a correction = %v, want the reading unchanged", got)
+	}
+	var none *ColdJunction
+	if got := none.Correct(100, 35); got != 100 {
+		t.Errorf("nil Correct = %v, want the reading unchanged", got)
+	}
+}
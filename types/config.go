@@ -13,7 +13,32 @@ import (
 	"github.com/rmkhl/halko/types/log"
 )
 
+// DoorOpenAction values
+const (
+	DoorOpenActionPause DoorOpenAction = "pause"
+	DoorOpenActionFail  DoorOpenAction = "fail"
+)
+
+// PowerUnitSwitches is how many switches the power unit can drive: a Shelly
+// Pro 4PM's outputs. A kiln without a vent mapped runs on a three-output
+// Shelly as well, leaving the last id unused.
+const PowerUnitSwitches = 4
+
+// powerFan is the fan's name in the power unit's power_mapping, which the
+// interlocks name it by.
+const powerFan = "fan"
+
+// DegradedSensorAction values
+const (
+	DegradedSensorActionContinue DegradedSensorAction = "continue"
+	DegradedSensorActionFail     DegradedSensorAction = "fail"
+)
+
 type (
+	DoorOpenAction string
+
+	DegradedSensorAction string
+
 	EndpointWithStatus interface {
 		GetStatusURL() string
 	}
@@ -65,21 +90,51 @@ type (
 		// one concern rather than two loose keys.
 		Equalize *EqualizeDefaults `json:"equalize"`
 		// Highest target any step may ask for.
-		MaxTargetTemperature *uint8 `json:"max_target_temperature"`
+		MaxTargetTemperature *uint16 `json:"max_target_temperature"`
 		// Temperature steam cannot heat the kiln past. Above it steam is
 		// thermally neutral; below it steam outruns the heater.
-		SteamCeiling *uint8 `json:"steam_ceiling"`
+		SteamCeiling *uint16 `json:"steam_ceiling"`
 		// How long a sensor may go without a valid reading before the running
 		// program is failed and all power switched off.
 		SensorTimeout string `json:"sensor_timeout"`
 		// How often a running program appends a line to its execution log.
 		ExecutionLogInterval string `json:"execution_log_interval"`
+		// What a run does when the door switch opens. Pausing suits a kiln
+		// someone opens to check a sample; failing suits one nobody should
+		// open while it runs.
+		DoorOpenAction DoorOpenAction `json:"door_open_action"`
+		// What a run does when the sensor unit reports its kiln probes
+		// degraded. Either way it is recorded; continuing trusts the probe
+		// the sensor unit still reports, failing trusts neither.
+		DegradedSensorAction DegradedSensorAction `json:"degraded_sensor_action"`
+		// How far a reading may move from the last accepted one before it
+		// is held back as implausible.
+		Plausibility *PlausibilityDefaults `json:"plausibility"`
+		// What the kiln allows thermal modification programs, for a kiln
+		// built to run them. Optional: without it they are refused.
+		ThermalModification *ThermalModificationDefaults `json:"thermal_modification,omitempty"`
 
 		// Resolved from the strings above once, while loading. Both are
 		// compared against second counts, so they are carried as seconds
 		// rather than durations.
 		SensorTimeoutSeconds        int64 `json:"-"`
 		ExecutionLogIntervalSeconds int64 `json:"-"`
+		// FanInterlock is the most fan power any power unit interlock
+		// requires for another channel to run, or 0 without one. Resolved
+		// from the power unit's interlocks once, while loading.
+		FanInterlock uint8 `json:"-"`
+	}
+
+	// PlausibilityDefaults is the filter a reading passes before the
+	// controllers see it. A kiln cannot heat or cool faster than MaxRate °C
+	// per second, so a reading that moved more than that since the last
+	// accepted one is a garbled frame or a glitching probe, and is rejected.
+	// A real jump, such as a probe pushed back into the wood, reads the same
+	// on every sample that follows: Confirm consistent readings in a row are
+	// accepted as the new level.
+	PlausibilityDefaults struct {
+		MaxRate float32 `json:"max_rate"`
+		Confirm int     `json:"confirm"`
 	}
 
 	ControlUnitConfig struct {
@@ -88,6 +143,11 @@ type (
 		NetworkInterface string    `json:"network_interface"`
 		Defaults         *Defaults `json:"defaults"`
 
+		// ComplianceKey signs the compliance reports of heat treatment runs:
+		// the base64 of a 32 byte Ed25519 seed. Without one the reports are
+		// written unsigned.
+		ComplianceKey string `json:"compliance_key,omitempty"`
+
 		// Resolved from TickLength once, while loading.
 		TickDuration time.Duration `json:"-"`
 	}
@@ -98,14 +158,62 @@ type (
 		PowerMapping  map[string]int `json:"power_mapping"`
 		MaxIdleTime   string         `json:"max_idle_time"`
 
+		// Limits caps a channel's percentage, by power name. Interlocks keep
+		// a channel off unless another runs at a minimum. Both are optional:
+		// without them every command is applied as sent.
+		Limits     map[string]int   `json:"limits,omitempty"`
+		Interlocks []PowerInterlock `json:"interlocks,omitempty"`
+
 		// Resolved from the strings above once, while loading.
 		CycleDuration   time.Duration `json:"-"`
 		MaxIdleDuration time.Duration `json:"-"`
 	}
 
+	// PowerInterlock reads "Channel requires Requires at MinPercent or more",
+	// e.g. a heater that must not run without the fan moving the air past it.
+	PowerInterlock struct {
+		Channel    string `json:"channel"`
+		Requires   string `json:"requires"`
+		MinPercent int    `json:"min_percent"`
+	}
+
 	SensorUnitConfig struct {
 		SerialDevice string `json:"serial_device"`
 		BaudRate     int    `json:"baud_rate"`
+
+		// KilnFusion and MaterialFusion say how the probes in the kiln air
+		// and in the wood are each reduced to the one temperature the control
+		// unit works with. MaterialWeights gives each material probe its
+		// weight for MaterialFusionCoreWeighted; a probe without one is left
+		// out.
+		KilnFusion      KilnFusion         `json:"kiln_fusion"`
+		MaterialFusion  MaterialFusion     `json:"material_fusion"`
+		MaterialWeights map[string]float64 `json:"material_weights,omitempty"`
+
+		// KilnDisagreement is how far apart the two kiln probes may read,
+		// and for how long, before the sensor unit reports them degraded.
+		KilnDisagreement *KilnDisagreement `json:"kiln_disagreement"`
+
+		// Calibration corrects each probe's reading before anything uses it,
+		// keyed by probe name (kiln_primary, kiln_secondary, material,
+		// material_2, material_3). A probe without an entry is reported as
+		// the device reads it.
+		Calibration map[string]*ProbeCalibration `json:"calibration,omitempty"`
+
+		// ColdJunction is the reference the thermocouple chips' die
+		// temperatures are compared against. Without it they are only
+		// reported.
+		ColdJunction *ColdJunction `json:"cold_junction,omitempty"`
+
+		// HumiditySensor is what the board measures the kiln air's moisture
+		// with. Without one the sensor unit does not ask the device, whose
+		// firmware may predate the question, and serves no climate.
+		HumiditySensor HumiditySensor `json:"humidity_sensor,omitempty"`
+
+		// MoistureProbes are the moisture pins in the sample boards.
+		// Without them the sensor unit does not ask the device and serves
+		// no moisture content.
+		MoistureProbes *MoistureProbes `json:"moisture_probes,omitempty"`
 	}
 
 	DBusUnitConfig struct {
@@ -130,8 +238,19 @@ type (
 		// values are referenced to, kept apart from the temperatures the
 		// system controls on.
 		DieTemperatures string `json:"die_temperatures"`
-		Display         string `json:"display"`
-		Status          string `json:"status"`
+		// Diagnostics serves why each failed probe failed, as the
+		// thermocouple chips report it.
+		Diagnostics string `json:"diagnostics"`
+		// Climate serves the kiln air's humidity, wet-bulb temperature and
+		// EMC, when the board has a humidity sensor.
+		Climate string `json:"climate"`
+		// Moisture serves the sample boards' moisture content, when the
+		// board has moisture pins.
+		Moisture string `json:"moisture"`
+		// Inputs serves the emergency stop and door switch states.
+		Inputs  string `json:"inputs"`
+		Display string `json:"display"`
+		Status  string `json:"status"`
 	}
 
 	PowerUnitEndpoints struct {
@@ -246,6 +365,22 @@ func (e *SensorUnitEndpoints) GetDieTemperaturesURL() string {
 	return e.URL + e.DieTemperatures
 }
 
+func (e *SensorUnitEndpoints) GetDiagnosticsURL() string {
+	return e.URL + e.Diagnostics
+}
+
+func (e *SensorUnitEndpoints) GetClimateURL() string {
+	return e.URL + e.Climate
+}
+
+func (e *SensorUnitEndpoints) GetMoistureURL() string {
+	return e.URL + e.Moisture
+}
+
+func (e *SensorUnitEndpoints) GetInputsURL() string {
+	return e.URL + e.Inputs
+}
+
 func (e *SensorUnitEndpoints) GetDisplayURL() string {
 	return e.URL + e.Display
 }
@@ -275,6 +410,7 @@ func LoadConfig(configPath string) (*HalkoConfig, error) {
 	}
 
 	config.resolveDurations()
+	config.resolveFanInterlock()
 
 	log.Info("Configuration loaded successfully from: %s", configPath)
 	return config, nil
@@ -380,6 +516,18 @@ func (c *HalkoConfig) resolveDurations() {
 	c.ControlUnitConfig.Defaults.ExecutionLogIntervalSeconds = int64(logInterval.Seconds())
 	steamPrewarmTimeout, _ := time.ParseDuration(c.ControlUnitConfig.Defaults.Equalize.SteamPrewarmTimeout)
 	c.ControlUnitConfig.Defaults.Equalize.SteamPrewarmTimeoutSeconds = int64(steamPrewarmTimeout.Seconds())
+	c.SensorUnit.KilnDisagreement.WindowDuration, _ = time.ParseDuration(c.SensorUnit.KilnDisagreement.Window)
+}
+
+// resolveFanInterlock hands the control unit's defaults the fan power the
+// power unit's interlocks require, which a program's fan must never drop
+// below. ValidateRequired has already bounded each min_percent to 100.
+func (c *HalkoConfig) resolveFanInterlock() {
+	for _, rule := range c.PowerUnit.Interlocks {
+		if rule.Requires == powerFan {
+			c.ControlUnitConfig.Defaults.FanInterlock = max(c.ControlUnitConfig.Defaults.FanInterlock, uint8(rule.MinPercent))
+		}
+	}
 }
 
 func (c *HalkoConfig) ValidateRequired() error {
@@ -395,6 +543,11 @@ func (c *HalkoConfig) ValidateRequired() error {
 	if _, err := time.ParseDuration(c.ControlUnitConfig.TickLength); err != nil {
 		return fmt.Errorf("controlunit tick_length must be a valid duration (e.g., '6s', '100ms'): %w", err)
 	}
+	if c.ControlUnitConfig.ComplianceKey != "" {
+		if _, err := DecodeComplianceKey(c.ControlUnitConfig.ComplianceKey); err != nil {
+			return fmt.Errorf("controlunit %w", err)
+		}
+	}
 
 	// Everything the control unit falls back to has to be present and usable.
 	// Without this a missing entry arrives as a zero and the failure only
@@ -445,6 +598,31 @@ func (c *HalkoConfig) ValidateRequired() error {
 			return fmt.Errorf("controlunit defaults: %s must be a valid duration: %w", d.name, err)
 		}
 	}
+	switch defaults.DoorOpenAction {
+	case DoorOpenActionPause, DoorOpenActionFail:
+	case "":
+		return errors.New("controlunit defaults: door_open_action is required")
+	default:
+		return fmt.Errorf("controlunit defaults: door_open_action must be %q or %q, not %q",
+			DoorOpenActionPause, DoorOpenActionFail, defaults.DoorOpenAction)
+	}
+	switch defaults.DegradedSensorAction {
+	case DegradedSensorActionContinue, DegradedSensorActionFail:
+	case "":
+		return errors.New("controlunit defaults: degraded_sensor_action is required")
+	default:
+		return fmt.Errorf("controlunit defaults: degraded_sensor_action must be %q or %q, not %q",
+			DegradedSensorActionContinue, DegradedSensorActionFail, defaults.DegradedSensorAction)
+	}
+	if defaults.Plausibility == nil {
+		return errors.New("controlunit defaults: plausibility is required")
+	}
+	if defaults.Plausibility.MaxRate <= 0 {
+		return errors.New("controlunit defaults: plausibility.max_rate must be greater than zero")
+	}
+	if defaults.Plausibility.Confirm < 2 {
+		return errors.New("controlunit defaults: plausibility.confirm must be at least 2, or every reading would confirm itself")
+	}
 	for _, stepType := range []StepType{StepTypeHeating, StepTypeAcclimate} {
 		band := defaults.Deltas[stepType]
 		if band == nil {
@@ -459,6 +637,9 @@ func (c *HalkoConfig) ValidateRequired() error {
 	if acclimate := defaults.Deltas[StepTypeAcclimate]; acclimate.MinDelta >= 0 || acclimate.MaxDelta <= 0 {
 		return errors.New(`controlunit "acclimate" defaults: min_delta must be negative and max_delta positive`)
 	}
+	if err := defaults.ThermalModification.validate(defaults); err != nil {
+		return err
+	}
 
 	if c.SensorUnit == nil {
 		return errors.New("sensor unit configuration is required")
@@ -469,6 +650,30 @@ func (c *HalkoConfig) ValidateRequired() error {
 	if c.SensorUnit.BaudRate <= 0 {
 		return errors.New("sensor unit baud rate is required and must be positive")
 	}
+	if err := c.SensorUnit.validateFusion(); err != nil {
+		return err
+	}
+	if err := c.SensorUnit.KilnDisagreement.validate(); err != nil {
+		return err
+	}
+	for probe, calibration := range c.SensorUnit.Calibration {
+		if !isTemperatureProbe(probe) {
+			return fmt.Errorf("sensor unit calibration: unknown probe %q (want %s, %s or %s to %s)",
+				probe, ProbeKilnPrimary, ProbeKilnSecondary, MaterialProbeName(1), MaterialProbeName(MaxMaterialProbes))
+		}
+		if err := calibration.validate(); err != nil {
+			return fmt.Errorf("sensor unit calibration for %s %w", probe, err)
+		}
+	}
+	if err := c.SensorUnit.ColdJunction.validate(); err != nil {
+		return err
+	}
+	if err := c.SensorUnit.HumiditySensor.validate(); err != nil {
+		return err
+	}
+	if err := c.SensorUnit.MoistureProbes.validate(); err != nil {
+		return err
+	}
 
 	if c.PowerUnit == nil {
 		return errors.New("power unit configuration is required")
@@ -491,6 +696,38 @@ func (c *HalkoConfig) ValidateRequired() error {
 	if len(c.PowerUnit.PowerMapping) == 0 {
 		return errors.New("power unit power mapping is required")
 	}
+	switches := map[int]string{}
+	for name, id := range c.PowerUnit.PowerMapping {
+		if id < 0 || id >= PowerUnitSwitches {
+			return fmt.Errorf("power unit power mapping: %s must be a switch id from 0 to %d, got %d", name, PowerUnitSwitches-1, id)
+		}
+		if other, ok := switches[id]; ok {
+			return fmt.Errorf("power unit power mapping: %s and %s are both on switch %d", min(name, other), max(name, other), id)
+		}
+		switches[id] = name
+	}
+	for name, limit := range c.PowerUnit.Limits {
+		if _, ok := c.PowerUnit.PowerMapping[name]; !ok {
+			return fmt.Errorf("power unit limits: unknown power %q", name)
+		}
+		if limit < 0 || limit > 100 {
+			return fmt.Errorf("power unit limits: %s must be between 0 and 100, got %d", name, limit)
+		}
+	}
+	for i, rule := range c.PowerUnit.Interlocks {
+		if _, ok := c.PowerUnit.PowerMapping[rule.Channel]; !ok {
+			return fmt.Errorf("power unit interlock %d: unknown channel %q", i+1, rule.Channel)
+		}
+		if _, ok := c.PowerUnit.PowerMapping[rule.Requires]; !ok {
+			return fmt.Errorf("power unit interlock %d: unknown power %q in requires", i+1, rule.Requires)
+		}
+		if rule.Channel == rule.Requires {
+			return fmt.Errorf("power unit interlock %d: %s cannot require itself", i+1, rule.Channel)
+		}
+		if rule.MinPercent < 1 || rule.MinPercent > 100 {
+			return fmt.Errorf("power unit interlock %d: min_percent must be between 1 and 100, got %d", i+1, rule.MinPercent)
+		}
+	}
 
 	if c.APIEndpoints == nil {
 		return errors.New("API endpoints configuration is required")
@@ -520,6 +757,18 @@ func (c *HalkoConfig) ValidateRequired() error {
 	if c.APIEndpoints.SensorUnit.DieTemperatures == "" {
 		return errors.New("sensorunit endpoints die_temperatures path is required")
 	}
+	if c.APIEndpoints.SensorUnit.Diagnostics == "" {
+		return errors.New("sensorunit endpoints diagnostics path is required")
+	}
+	if c.APIEndpoints.SensorUnit.Climate == "" {
+		return errors.New("sensorunit endpoints climate path is required")
+	}
+	if c.APIEndpoints.SensorUnit.Moisture == "" {
+		return errors.New("sensorunit endpoints moisture path is required")
+	}
+	if c.APIEndpoints.SensorUnit.Inputs == "" {
+		return errors.New("sensorunit endpoints inputs path is required")
+	}
 	if c.APIEndpoints.SensorUnit.Display == "" {
 		return errors.New("sensorunit endpoints display path is required")
 	}
@@ -62,6 +62,9 @@ var testConfigData = `{
       "steam_ceiling": 100,
       "sensor_timeout": "120s",
       "execution_log_interval": "60s",
+      "door_open_action": "pause",
+      "degraded_sensor_action": "continue",
+      "plausibility": {"max_rate": 2.0, "confirm": 3},
       "equalize": {
         "delta": 2.0,
         "steam_prewarm": false,
@@ -81,7 +84,10 @@ var testConfigData = `{
   },
   "sensorunit": {
     "serial_device": "/dev/ttyUSB0",
-    "baud_rate": 9600
+    "baud_rate": 9600,
+    "kiln_fusion": "max",
+    "material_fusion": "min",
+    "kiln_disagreement": {"threshold": 10.0, "window": "5m"}
   },
   "api_endpoints": {
     "controlunit": {
@@ -95,6 +101,10 @@ var testConfigData = `{
       "status": "/status",
       "temperatures": "/temperatures",
       "die_temperatures": "/temperatures/die",
+      "diagnostics": "/temperatures/diagnostics",
+      "climate": "/climate",
+      "moisture": "/moisture",
+      "inputs": "/inputs",
       "display": "/display"
     },
     "powerunit": {
@@ -334,19 +344,19 @@ func TestLoadConfigRejectsUnusableDeltaDefaults(t *testing.T) {
 	}{
 		{
 			"acclimate entry missing",
-			`{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}}`,
+			`{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "degraded_sensor_action": "continue", "plausibility": {"max_rate": 2.0, "confirm": 3}, "door_open_action": "pause", "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}}`,
 		},
 		{
 			"heating entry missing",
-			`{"deltas": {"acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}}`,
+			`{"deltas": {"acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "degraded_sensor_action": "continue", "plausibility": {"max_rate": 2.0, "confirm": 3}, "door_open_action": "pause", "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}}`,
 		},
 		{
 			"collapsed band",
-			`{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 5.0}, "acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}}`,
+			`{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 5.0}, "acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "degraded_sensor_action": "continue", "plausibility": {"max_rate": 2.0, "confirm": 3}, "door_open_action": "pause", "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}}`,
 		},
 		{
 			"reversed band",
-			`{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}, "acclimate": {"min_delta": 3.0, "max_delta": -1.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}}`,
+			`{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}, "acclimate": {"min_delta": 3.0, "max_delta": -1.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "degraded_sensor_action": "continue", "plausibility": {"max_rate": 2.0, "confirm": 3}, "door_open_action": "pause", "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}}`,
 		},
 	}
 
@@ -361,7 +371,7 @@ func TestLoadConfigRejectsUnusableDeltaDefaults(t *testing.T) {
 
 func TestLoadConfigAcceptsNestedDeltaDefaults(t *testing.T) {
 	path := writeConfigWithDefaults(t,
-		`{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}, "acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}}`)
+		`{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}, "acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "degraded_sensor_action": "continue", "plausibility": {"max_rate": 2.0, "confirm": 3}, "door_open_action": "pause", "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}}`)
 
 	config, err := LoadConfig(path)
 	if err != nil {
@@ -382,7 +392,7 @@ func TestLoadConfigAcceptsNestedDeltaDefaults(t *testing.T) {
 // required.
 func TestEqualizeDefaultsLoad(t *testing.T) {
 	path := writeConfigWithDefaults(t,
-		`{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}, "acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}}`)
+		`{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}, "acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "degraded_sensor_action": "continue", "plausibility": {"max_rate": 2.0, "confirm": 3}, "door_open_action": "pause", "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}}`)
 
 	config, err := LoadConfig(path)
 	if err != nil {
@@ -399,7 +409,7 @@ func TestEqualizeDefaultsLoad(t *testing.T) {
 }
 
 func TestLoadConfigRejectsUnusableEqualizeDefaults(t *testing.T) {
-	const base = `{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}, "acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s"`
+	const base = `{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}, "acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "degraded_sensor_action": "continue", "plausibility": {"max_rate": 2.0, "confirm": 3}, "door_open_action": "pause"`
 
 	tests := []struct {
 		name     string
@@ -422,3 +432,196 @@ func TestLoadConfigRejectsUnusableEqualizeDefaults(t *testing.T) {
 		})
 	}
 }
+
+// A door that opens mid-run has to do something the operator chose. Guessing
+// between pausing and failing would be inventing a safety policy.
+func TestLoadConfigRequiresADoorOpenAction(t *testing.T) {
+	const base = `{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}, "acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "degraded_sensor_action": "continue", "plausibility": {"max_rate": 2.0, "confirm": 3}, "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}`
+
+	tests := []struct {
+		name     string
+		defaults string
+		wantErr  bool
+	}{
+		{"missing", base + `}`, true},
+		{"unknown", base + `, "door_open_action": "ignore"}`, true},
+		{"pause", base + `, "door_open_action": "pause"}`, false},
+		{"fail", base + `, "door_open_action": "fail"}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfigWithDefaults(t, tt.defaults))
+			if tt.wantErr && err == nil {
+				t.Fatal("expected LoadConfig to fail, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+// A kiln probe that the other disagrees with might be the one still reading
+// true, so whether to carry on is the operator's call, not the code's.
+func TestLoadConfigRequiresADegradedSensorAction(t *testing.T) {
+	const base = `{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}, "acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "door_open_action": "pause", "plausibility": {"max_rate": 2.0, "confirm": 3}, "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}`
+
+	tests := []struct {
+		name     string
+		defaults string
+		wantErr  bool
+	}{
+		{"missing", base + `}`, true},
+		{"unknown", base + `, "degraded_sensor_action": "ignore"}`, true},
+		{"continue", base + `, "degraded_sensor_action": "continue"}`, false},
+		{"fail", base + `, "degraded_sensor_action": "fail"}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfigWithDefaults(t, tt.defaults))
+			if tt.wantErr && err == nil {
+				t.Fatal("expected LoadConfig to fail, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+// writeConfigWithPowerRules writes the standard test config with the given
+// JSON members added to the power_unit block.
+func writeConfigWithPowerRules(t *testing.T, rules string) string {
+	t.Helper()
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test_halko.cfg")
+
+	data := strings.Replace(testConfigData, "/dev/ttyUSB0", filepath.Join(tempDir, "esp32"), 1)
+	data = strings.Replace(data, `"max_idle_time": "70s",`, `"max_idle_time": "70s", `+rules+`,`, 1)
+
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return configPath
+}
+
+// A rule naming a power that does not exist would never fire, leaving the
+// operator believing the kiln is protected when it is not.
+func TestLoadConfigValidatesPowerRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		wantErr bool
+	}{
+		{"limit", `"limits": {"steam": 60}`, false},
+		{"interlock", `"interlocks": [{"channel": "heater", "requires": "fan", "min_percent": 30}]`, false},
+		{"limit on unknown power", `"limits": {"kettle": 60}`, true},
+		{"limit above 100", `"limits": {"steam": 160}`, true},
+		{"interlock on unknown channel", `"interlocks": [{"channel": "kettle", "requires": "fan", "min_percent": 30}]`, true},
+		{"interlock requiring unknown power", `"interlocks": [{"channel": "heater", "requires": "blower", "min_percent": 30}]`, true},
+		{"interlock requiring itself", `"interlocks": [{"channel": "fan", "requires": "fan", "min_percent": 30}]`, true},
+		{"interlock without a minimum", `"interlocks": [{"channel": "heater", "requires": "fan"}]`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := LoadConfig(writeConfigWithPowerRules(t, tt.rules))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected LoadConfig to fail, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+			if len(config.PowerUnit.Limits)+len(config.PowerUnit.Interlocks) != 1 {
+				t.Fatalf("expected the rule to load, got limits %v and interlocks %v",
+					config.PowerUnit.Limits, config.PowerUnit.Interlocks)
+			}
+		})
+	}
+}
+
+// Programs are checked against the most fan any interlock requires, whichever
+// channel it keeps off.
+func TestLoadConfigResolvesTheFanInterlock(t *testing.T) {
+	config, err := LoadConfig(writeConfigWithPowerRules(t, `"interlocks": [
+		{"channel": "heater", "requires": "fan", "min_percent": 30},
+		{"channel": "steam", "requires": "fan", "min_percent": 40},
+		{"channel": "fan", "requires": "heater", "min_percent": 50}]`))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if got := config.ControlUnitConfig.Defaults.FanInterlock; got != 40 {
+		t.Errorf("FanInterlock = %d, want 40", got)
+	}
+}
+
+func TestLoadConfigValidatesPlausibility(t *testing.T) {
+	const base = `{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}, "acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "door_open_action": "pause", "degraded_sensor_action": "continue", "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}`
+
+	tests := []struct {
+		name     string
+		defaults string
+		wantErr  bool
+	}{
+		{"missing", base + `}`, true},
+		{"no max_rate", base + `, "plausibility": {"confirm": 3}}`, true},
+		{"negative max_rate", base + `, "plausibility": {"max_rate": -1, "confirm": 3}}`, true},
+		{"confirm of one", base + `, "plausibility": {"max_rate": 2.0, "confirm": 1}}`, true},
+		{"rate and confirm", base + `, "plausibility": {"max_rate": 0.5, "confirm": 2}}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfigWithDefaults(t, tt.defaults))
+			if tt.wantErr && err == nil {
+				t.Fatal("expected LoadConfig to fail, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+// The power unit indexes its switches by these ids, so one out of range or
+// two channels on one switch would either crash it or drive the wrong load.
+func TestLoadConfigValidatesPowerMapping(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping string
+		wantErr bool
+	}{
+		{"three channels", `"heater": 0, "steam": 1, "fan": 2`, false},
+		{"vent on the fourth switch", `"heater": 0, "steam": 1, "fan": 2, "vent": 3`, false},
+		{"switch beyond the fourth", `"heater": 0, "steam": 1, "fan": 2, "vent": 4`, true},
+		{"negative switch", `"heater": -1, "steam": 1, "fan": 2`, true},
+		{"two channels on one switch", `"heater": 0, "steam": 1, "fan": 2, "vent": 2`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			configPath := filepath.Join(tempDir, "test_halko.cfg")
+			data := strings.Replace(testConfigData, "/dev/ttyUSB0", filepath.Join(tempDir, "esp32"), 1)
+			data = strings.Replace(data, `"heater": 0,
+      "steam": 1,
+      "fan": 2`, tt.mapping, 1)
+			if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+				t.Fatalf("write config: %v", err)
+			}
+
+			_, err := LoadConfig(configPath)
+			if tt.wantErr && err == nil {
+				t.Fatal("expected LoadConfig to fail, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
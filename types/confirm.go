@@ -0,0 +1,65 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// What a confirm step does once its runtime is over with no one having
+	// confirmed it. Failing the run is what it does when on_timeout is left
+	// out: a step that asked for someone to look at the charge should not
+	// pass as though they had.
+	ConfirmTimeoutFail     ConfirmTimeoutAction = "fail"
+	ConfirmTimeoutContinue ConfirmTimeoutAction = "continue"
+)
+
+type (
+	ConfirmTimeoutAction string
+
+	// ConfirmationStatus is what a run waiting on an operator is waiting
+	// for. TimeoutSeconds is zero when the step waits for as long as it
+	// takes, and OnTimeout then says nothing.
+	ConfirmationStatus struct {
+		Prompt         string               `json:"prompt"`
+		Since          int64                `json:"since"`
+		TimeoutSeconds int64                `json:"timeout_seconds,omitempty"`
+		OnTimeout      ConfirmTimeoutAction `json:"on_timeout,omitempty"`
+	}
+)
+
+// validateConfirmStep checks the state a confirm step holds the kiln in while
+// nobody may be watching it. Every channel runs at a constant power, so
+// nothing in the step depends on the readings; steam stays off, since with the
+// heater off the kiln drifts back down through the steam ceiling, unless the
+// step is under the steam blanket, which it must keep.
+func (p *ProgramStep) validateConfirmStep(blanketed bool) error {
+	if p.Prompt == "" {
+		return errors.New("confirm step must have a prompt telling the operator what to do")
+	}
+	if p.TargetTemperature != 0 {
+		return errors.New("confirm step cannot have a target temperature: it holds wherever the kiln is")
+	}
+	switch p.OnTimeout {
+	case "", ConfirmTimeoutFail, ConfirmTimeoutContinue:
+	default:
+		return fmt.Errorf("confirm step on_timeout must be %q or %q", ConfirmTimeoutFail, ConfirmTimeoutContinue)
+	}
+	if p.OnTimeout != "" && p.Runtime == nil {
+		return errors.New("confirm step on_timeout needs a runtime to time out on")
+	}
+	heaterErr := p.Heater.Validate("heater")
+	if p.Heater.Type != PowerSettingTypeSimple {
+		return errors.New("confirm step heater must use simple power control")
+	}
+	if heaterErr != nil {
+		return heaterErr
+	}
+	if !blanketed && !p.steamIsOff() {
+		return errors.New("confirm step must switch steam off")
+	}
+	if p.Vent.Type != PowerSettingTypeSimple {
+		return errors.New("confirm step vent must use simple power control")
+	}
+	return nil
+}
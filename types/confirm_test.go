@@ -0,0 +1,155 @@
+package types
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func confirmStep() ProgramStep {
+	return ProgramStep{Name: "pull samples", StepType: StepTypeConfirm, Prompt: "Weigh the sample boards"}
+}
+
+// withConfirm puts a confirm step between validProgram's acclimate and its
+// cooling step.
+func withConfirm(step ProgramStep) Program {
+	program := validProgram()
+	steps := program.ProgramSteps
+	program.ProgramSteps = []ProgramStep{steps[0], steps[1], step, steps[2]}
+	return program
+}
+
+func TestConfirmStep(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(*ProgramStep)
+		contains string
+	}{
+		{"valid", func(*ProgramStep) {}, ""},
+		{"timing out", func(s *ProgramStep) {
+			s.Runtime = &StepDuration{2 * time.Hour}
+			s.OnTimeout = ConfirmTimeoutContinue
+		}, ""},
+		{"fan running", func(s *ProgramStep) { s.Fan = &PowerPidSettings{Power: u8(30)} }, ""},
+		{"no prompt", func(s *ProgramStep) { s.Prompt = "" }, "must have a prompt"},
+		{"target", func(s *ProgramStep) { s.TargetTemperature = 150 }, "cannot have a target"},
+		{"unknown timeout action", func(s *ProgramStep) {
+			s.Runtime = &StepDuration{time.Hour}
+			s.OnTimeout = "retry"
+		}, "on_timeout must be"},
+		{"timeout action without a runtime", func(s *ProgramStep) { s.OnTimeout = ConfirmTimeoutFail }, "needs a runtime"},
+		{"heater on the delta", func(s *ProgramStep) {
+			s.Heater = &PowerPidSettings{MinDelta: f32(-1), MaxDelta: f32(3)}
+		}, "heater must use simple"},
+		{"fan on the delta", func(s *ProgramStep) {
+			s.Fan = &PowerPidSettings{MinDelta: f32(1), MaxDelta: f32(3)}
+		}, "fan must use simple"},
+		{"steam on", func(s *ProgramStep) { s.Steam = &PowerPidSettings{Power: u8(40)} }, "must switch steam off"},
+		{"vent on the air", func(s *ProgramStep) {
+			s.Vent = &PowerPidSettings{MinHumidity: f32(60), MaxHumidity: f32(70)}
+		}, "vent must use simple"},
+		{"moisture target", func(s *ProgramStep) { s.TargetMoisture = f32(12) }, "only heating and acclimate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := confirmStep()
+			tt.modify(&step)
+			program := withConfirm(step)
+			program.ApplyDefaults(templateDefaults(t))
+
+			err := program.Validate()
+			if tt.contains == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Fatalf("Validate = %v, want an error containing %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+func TestPromptOnlyOnConfirmSteps(t *testing.T) {
+	program := validProgram()
+	program.ProgramSteps[1].Prompt = "Check the stack"
+	program.ApplyDefaults(templateDefaults(t))
+
+	if err := program.Validate(); err == nil || !strings.Contains(err.Error(), "only confirm steps") {
+		t.Fatalf("Validate = %v, want a prompt on an acclimate refused", err)
+	}
+}
+
+// A confirm step is passed over by the temperature progression: the steps
+// either side of it are held to each other, and it may be neither the first
+// step nor the last.
+func TestConfirmStepPlacement(t *testing.T) {
+	tests := []struct {
+		name     string
+		steps    func(Program) []ProgramStep
+		contains string
+	}{
+		{"between heating and its acclimate", func(p Program) []ProgramStep {
+			return []ProgramStep{p.ProgramSteps[0], confirmStep(), p.ProgramSteps[1], p.ProgramSteps[2]}
+		}, ""},
+		{"acclimate off the heating target", func(p Program) []ProgramStep {
+			p.ProgramSteps[1].TargetTemperature = 160
+			return []ProgramStep{p.ProgramSteps[0], confirmStep(), p.ProgramSteps[1], p.ProgramSteps[2]}
+		}, "must match the preceding heating step"},
+		{"first", func(p Program) []ProgramStep {
+			return append([]ProgramStep{confirmStep()}, p.ProgramSteps...)
+		}, "first step must be a heating step"},
+		{"last", func(p Program) []ProgramStep {
+			return append(p.ProgramSteps, confirmStep())
+		}, "last step must be a cooling step"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := validProgram()
+			program.ProgramSteps = tt.steps(program)
+			program.ApplyDefaults(templateDefaults(t))
+
+			err := program.Validate()
+			if tt.contains == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Fatalf("Validate = %v, want an error containing %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+// However hot the kiln was going in, it may have cooled to the room by the
+// time the operator confirms, so the step after a confirm cannot count on
+// being above the steam ceiling.
+func TestConstantSteamAfterAConfirmStep(t *testing.T) {
+	build := func(confirm bool) Program {
+		steps := []ProgramStep{steamHeatingStep(120, steamDelta())}
+		if confirm {
+			steps = append(steps, confirmStep())
+		}
+		steps = append(steps,
+			steamHeatingStep(150, &PowerPidSettings{Power: u8(50)}),
+			steamCoolingStep(30, &PowerPidSettings{Power: u8(0)}))
+		return Program{ProgramSteps: steps}
+	}
+
+	program := build(false)
+	program.ApplyDefaults(templateDefaults(t))
+	if err := program.Validate(); err != nil {
+		t.Fatalf("without the confirm step: Validate: %v", err)
+	}
+
+	program = build(true)
+	program.ApplyDefaults(templateDefaults(t))
+	if err := program.Validate(); err == nil || !strings.Contains(err.Error(), "enters with the kiln at 0.0°C") {
+		t.Fatalf("with the confirm step: Validate = %v, want constant steam refused", err)
+	}
+}
@@ -0,0 +1,175 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// MaxRepeatCount is the most times a repeat block may run its steps.
+	MaxRepeatCount = 50
+	// MaxExpandedSteps caps how many steps a program may expand to. Repeats
+	// nest and multiply, so a typo in a count could otherwise build a
+	// program no one meant to run, and the history would carry every step
+	// of it.
+	MaxExpandedSteps = 500
+)
+
+type (
+	// StepRepeat runs its steps Count times over, one round after the
+	// other: the alternating steam and dry phases of a conditioning
+	// schedule, written once.
+	StepRepeat struct {
+		Count int           `json:"count"`
+		Steps []ProgramStep `json:"steps"`
+	}
+
+	// ProgramLoader finds a stored program by name, for an include.
+	ProgramLoader func(name string) (*Program, error)
+)
+
+// isBlock reports whether the entry is a repeat block or an include rather
+// than a step.
+func (p *ProgramStep) isBlock() bool {
+	return p.Repeat != nil || p.Include != ""
+}
+
+// HasBlocks reports whether the program has repeat blocks or includes for
+// ExpandSteps to flatten.
+func (p *Program) HasBlocks() bool {
+	for i := range p.ProgramSteps {
+		if p.ProgramSteps[i].isBlock() {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandSteps flattens the program's repeat blocks and includes into the plain
+// step list ApplyDefaults, Validate and the control unit work from. It has to
+// run before either of them, and only the caller knows where included
+// programs are kept, so load finds them; nil means there is nowhere to look,
+// and a program that includes one is refused.
+//
+// Each round of a repeat gets copies of its steps, named with the round, so
+// the status and the history can tell "Steam (2/3)" from "Steam (1/3)". An
+// included program contributes its steps only: its name, class and equalize
+// settings stay with it. A stored program is kept as it was written; only
+// what actually runs is expanded.
+func (p *Program) ExpandSteps(load ProgramLoader) error {
+	if !p.HasBlocks() {
+		return nil
+	}
+	steps, err := expandSteps(p.ProgramSteps, load, []string{p.ProgramName})
+	if err != nil {
+		return err
+	}
+	p.ProgramSteps = steps
+	return nil
+}
+
+// expandSteps expands one list of entries. including is the chain of programs
+// the list was reached through, to refuse a program that includes itself.
+func expandSteps(entries []ProgramStep, load ProgramLoader, including []string) ([]ProgramStep, error) {
+	var steps []ProgramStep
+	for i := range entries {
+		entry := &entries[i]
+		if !entry.isBlock() {
+			steps = append(steps, entry.clone())
+			continue
+		}
+		if err := entry.validateBlock(i + 1); err != nil {
+			return nil, err
+		}
+
+		if entry.Repeat != nil {
+			round, err := expandSteps(entry.Repeat.Steps, load, including)
+			if err != nil {
+				return nil, err
+			}
+			if len(steps)+len(round)*entry.Repeat.Count > MaxExpandedSteps {
+				return nil, fmt.Errorf("program expands to more than %d steps", MaxExpandedSteps)
+			}
+			for n := 1; n <= entry.Repeat.Count; n++ {
+				for j := range round {
+					step := round[j].clone()
+					step.Name = fmt.Sprintf("%s (%d/%d)", step.Name, n, entry.Repeat.Count)
+					steps = append(steps, step)
+				}
+			}
+			continue
+		}
+
+		if load == nil {
+			return nil, fmt.Errorf("entry %d includes %q, but there are no stored programs to include from", i+1, entry.Include)
+		}
+		if slices.Contains(including, entry.Include) {
+			return nil, fmt.Errorf("program %q includes itself (%s)",
+				entry.Include, strings.Join(append(slices.Clip(including), entry.Include), " -> "))
+		}
+		included, err := load(entry.Include)
+		if err != nil {
+			return nil, fmt.Errorf("include %q: %w", entry.Include, err)
+		}
+		if included.Schedule != nil {
+			return nil, fmt.Errorf("include %q: a program with a schedule cannot be included", entry.Include)
+		}
+		if included.IsTemplate() {
+			return nil, fmt.Errorf("include %q: a template cannot be included", entry.Include)
+		}
+		inner, err := expandSteps(included.ProgramSteps, load, append(slices.Clip(including), entry.Include))
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, inner...)
+		if len(steps) > MaxExpandedSteps {
+			return nil, fmt.Errorf("program expands to more than %d steps", MaxExpandedSteps)
+		}
+	}
+	return steps, nil
+}
+
+// validateBlock checks a repeat or include entry, n its place in the list. A
+// name is allowed, as a label for whoever reads the program; anything else
+// belongs to a step and would be silently dropped.
+func (p *ProgramStep) validateBlock(n int) error {
+	if p.Repeat != nil && p.Include != "" {
+		return fmt.Errorf("entry %d cannot both repeat and include", n)
+	}
+	rest := *p
+	rest.Name, rest.Repeat, rest.Include = "", nil, ""
+	if rest != (ProgramStep{}) {
+		return fmt.Errorf("entry %d is a repeat or include, so it cannot have step settings", n)
+	}
+	if p.Repeat == nil {
+		return nil
+	}
+	if p.Repeat.Count < 1 || p.Repeat.Count > MaxRepeatCount {
+		return fmt.Errorf("entry %d repeat count must be between 1 and %d", n, MaxRepeatCount)
+	}
+	if len(p.Repeat.Steps) == 0 {
+		return fmt.Errorf("entry %d repeats no steps", n)
+	}
+	return nil
+}
+
+// clone deep copies a step, so that the copies a repeat makes do not share
+// the power settings ApplyDefaults fills in.
+func (p *ProgramStep) clone() ProgramStep {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return *p
+	}
+	var step ProgramStep
+	if err := json.Unmarshal(data, &step); err != nil {
+		return *p
+	}
+	return step
+}
+
+// errUnexpandedBlocks is what Validate says about a program whose blocks were
+// never expanded.
+var errUnexpandedBlocks = errors.New("program has repeat or include entries, which must be expanded before it is validated")
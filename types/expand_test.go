@@ -0,0 +1,137 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// conditioning is a stored program of the two phases a conditioning schedule
+// alternates between, made to be included.
+func conditioning() *Program {
+	return &Program{ProgramName: "condition", ProgramSteps: []ProgramStep{
+		steamAcclimateStep(150, &PowerPidSettings{Power: u8(0)}),
+		{Repeat: &StepRepeat{Count: 2, Steps: []ProgramStep{
+			{Name: "steam", StepType: StepTypeAcclimate, TargetTemperature: 150,
+				Runtime: &StepDuration{}, Steam: &PowerPidSettings{Power: u8(60)}},
+		}}},
+	}}
+}
+
+func storedPrograms(programs ...*Program) ProgramLoader {
+	return func(name string) (*Program, error) {
+		for _, program := range programs {
+			if program.ProgramName == name {
+				return program, nil
+			}
+		}
+		return nil, fmt.Errorf("program %s not found", name)
+	}
+}
+
+func stepNames(steps []ProgramStep) string {
+	names := make([]string, len(steps))
+	for i := range steps {
+		names[i] = steps[i].Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func TestExpandSteps(t *testing.T) {
+	program := validProgram()
+	steps := program.ProgramSteps
+	program.ProgramSteps = []ProgramStep{
+		steps[0],
+		{Name: "dry and rest", Repeat: &StepRepeat{Count: 2, Steps: []ProgramStep{steps[1], {Include: "condition"}}}},
+		steps[2],
+	}
+
+	if err := program.ExpandSteps(storedPrograms(conditioning())); err != nil {
+		t.Fatalf("ExpandSteps: %v", err)
+	}
+
+	want := "heat, hold (1/2), hold (1/2), steam (1/2) (1/2), steam (2/2) (1/2), " +
+		"hold (2/2), hold (2/2), steam (1/2) (2/2), steam (2/2) (2/2), cool"
+	if got := stepNames(program.ProgramSteps); got != want {
+		t.Fatalf("steps = %s\nwant     %s", got, want)
+	}
+	if program.HasBlocks() {
+		t.Error("blocks left after expanding")
+	}
+
+	// Each copy is a step of its own, so the defaults filled into one
+	// cannot leak into another.
+	program.ProgramSteps[3].Steam.Power = u8(10)
+	if *program.ProgramSteps[4].Steam.Power != 60 {
+		t.Error("repeated steps share their power settings")
+	}
+}
+
+// The expanded program goes through the same validation as a written-out one.
+func TestExpandedProgramValidates(t *testing.T) {
+	program := validProgram()
+	steps := program.ProgramSteps
+	program.ProgramSteps = []ProgramStep{steps[0], {Repeat: &StepRepeat{Count: 3, Steps: steps[1:2]}}, steps[2]}
+
+	if err := program.ExpandSteps(nil); err != nil {
+		t.Fatalf("ExpandSteps: %v", err)
+	}
+	program.ApplyDefaults(templateDefaults(t))
+	if err := program.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if len(program.ProgramSteps) != 5 {
+		t.Errorf("%d steps, want 5", len(program.ProgramSteps))
+	}
+}
+
+func TestValidateRefusesUnexpandedBlocks(t *testing.T) {
+	program := validProgram()
+	program.ProgramSteps = append(program.ProgramSteps[:2], ProgramStep{Include: "condition"}, program.ProgramSteps[2])
+	program.ApplyDefaults(templateDefaults(t))
+
+	if err := program.Validate(); !errors.Is(err, errUnexpandedBlocks) {
+		t.Fatalf("Validate = %v, want %v", err, errUnexpandedBlocks)
+	}
+}
+
+func TestExpandStepsRefuses(t *testing.T) {
+	selfIncluding := &Program{ProgramName: "loop", ProgramSteps: []ProgramStep{{Include: "around"}}}
+	around := &Program{ProgramName: "around", ProgramSteps: []ProgramStep{{Include: "loop"}}}
+	scheduled := &Program{ProgramName: "scheduled", Schedule: &DryingSchedule{}}
+	load := storedPrograms(conditioning(), selfIncluding, around, scheduled)
+	heat := heatingStep(steamDelta())
+
+	tests := []struct {
+		name     string
+		entry    ProgramStep
+		load     ProgramLoader
+		contains string
+	}{
+		{"no count", ProgramStep{Repeat: &StepRepeat{Steps: []ProgramStep{heat}}}, load, "between 1 and 50"},
+		{"too many rounds", ProgramStep{Repeat: &StepRepeat{Count: 51, Steps: []ProgramStep{heat}}}, load, "between 1 and 50"},
+		{"nothing to repeat", ProgramStep{Repeat: &StepRepeat{Count: 2}}, load, "repeats no steps"},
+		{"both", ProgramStep{Include: "condition", Repeat: &StepRepeat{Count: 2, Steps: []ProgramStep{heat}}}, load, "both repeat and include"},
+		{"step settings", ProgramStep{Include: "condition", StepType: StepTypeHeating}, load, "cannot have step settings"},
+		{"nowhere to include from", ProgramStep{Include: "condition"}, nil, "no stored programs"},
+		{"unknown program", ProgramStep{Include: "missing"}, load, "not found"},
+		{"scheduled program", ProgramStep{Include: "scheduled"}, load, "schedule cannot be included"},
+		{"include cycle", ProgramStep{Include: "loop"}, load, "includes itself (main -> loop -> around -> loop)"},
+		{"itself", ProgramStep{Include: "main"}, storedPrograms(&Program{ProgramName: "main"}), "includes itself"},
+		{"too many steps", ProgramStep{Repeat: &StepRepeat{Count: 50, Steps: []ProgramStep{
+			{Repeat: &StepRepeat{Count: 50, Steps: []ProgramStep{heat}}},
+		}}}, load, "more than 500 steps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := Program{ProgramName: "main", ProgramSteps: []ProgramStep{heat, tt.entry}}
+
+			err := program.ExpandSteps(tt.load)
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Fatalf("ExpandSteps = %v, want an error containing %q", err, tt.contains)
+			}
+		})
+	}
+}
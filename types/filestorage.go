@@ -15,6 +15,11 @@ var (
 	ErrProgramDoesNotExist = errors.New("program does not exist")
 )
 
+// TempFileSuffix ends the name of the temporary file WriteFileAtomic writes
+// before renaming it into place. One still there was never finished, which is
+// how a consistency scan knows it can go.
+const TempFileSuffix = ".tmp"
+
 type FileStorage struct {
 	BasePath string
 }
@@ -61,18 +66,61 @@ func (storage *FileStorage) LoadProgram(filePath string) (*Program, error) {
 }
 
 func (storage *FileStorage) SaveProgram(filePath string, program *Program) error {
-	jsonFile, err := os.Create(filePath)
+	content, err := json.Marshal(program)
 	if err != nil {
 		return err
 	}
-	defer jsonFile.Close()
+	return WriteFileAtomic(filePath, content, 0o644)
+}
 
-	content, err := json.Marshal(program)
+// WriteFileAtomic replaces the file with content so that, whenever the power
+// goes, the file is either as it was or as it is meant to be, never empty or
+// half written. Kilns run off SD cards on sites where the power is cut as
+// often as not. The content goes to a temporary file in the same directory,
+// is synced to disk, and is renamed over the file; the directory is synced
+// too, so the rename itself is not lost.
+func WriteFileAtomic(filePath string, content []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filePath)
+	tempFile, err := os.CreateTemp(dir, "."+filepath.Base(filePath)+".*"+TempFileSuffix)
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+	written := false
+	defer func() {
+		if !written {
+			_ = os.Remove(tempPath)
+		}
+	}()
+
+	if _, err := tempFile.Write(content); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return err
+	}
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tempPath, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tempPath, filePath); err != nil {
+		return err
+	}
+	written = true
+	return syncDir(dir)
+}
+
+func syncDir(dir string) error {
+	dirFile, err := os.Open(dir)
 	if err != nil {
 		return err
 	}
-	_, err = jsonFile.Write(content)
-	return err
+	defer dirFile.Close()
+	return dirFile.Sync()
 }
 
 func (storage *FileStorage) DeleteProgram(filePath string) error {
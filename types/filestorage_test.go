@@ -0,0 +1,60 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.txt")
+
+	for _, content := range []string{"running", "completed"} {
+		if err := WriteFileAtomic(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("WriteFileAtomic: %v", err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil || string(got) != content {
+			t.Fatalf("file holds %q, %v; want %q", got, err, content)
+		}
+	}
+
+	info, _ := os.Stat(path)
+	if info.Mode().Perm() != 0o644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want the one written and no temporary files", len(entries))
+	}
+}
+
+// A write that cannot be finished leaves no temporary file behind.
+func TestWriteFileAtomicFailureKeepsTheFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "program.json")
+	if err := WriteFileAtomic(path, []byte(`{"name":"Oak"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory where the file should be cannot be renamed over.
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.Mkdir(blocked, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(blocked, "keep"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(blocked, []byte("x"), 0o644); err == nil {
+		t.Fatal("WriteFileAtomic over a directory succeeded")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("directory holds %d entries, want no temporary file left", len(entries))
+	}
+	if got, _ := os.ReadFile(path); string(got) != `{"name":"Oak"}` {
+		t.Errorf("file holds %q, want it untouched", got)
+	}
+}
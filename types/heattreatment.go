@@ -0,0 +1,343 @@
+package types
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	// ISPM-15, the standard for wood packaging in international trade,
+	// requires the wood core at 56°C for 30 continuous minutes. A heat
+	// treatment step's hold defaults to it.
+	ISPM15Threshold = 56
+	ISPM15Duration  = 30 * time.Minute
+
+	// HoldDipReset starts the hold over when the material dips below the
+	// threshold, which is what "continuous" in ISPM-15 means. HoldDipPause
+	// keeps the time already held and carries on once the material is back,
+	// for the rules that ask for a total time at temperature.
+	HoldDipReset HoldDipPolicy = "reset"
+	HoldDipPause HoldDipPolicy = "pause"
+)
+
+type (
+	HoldDipPolicy string
+
+	// HeatTreatmentHold is what a heat treatment step needs the material to
+	// do: stay at or above Threshold °C for Duration. Everything left out is
+	// ISPM-15's.
+	HeatTreatmentHold struct {
+		Threshold float32       `json:"threshold,omitempty"`
+		Duration  *StepDuration `json:"duration,omitempty"`
+		OnDip     HoldDipPolicy `json:"on_dip,omitempty"`
+	}
+
+	// HeatTreatmentStatus is a running heat treatment's progress. Holding
+	// says whether the material is at or above the threshold now, and
+	// HeldSeconds how much of the hold it has to show for it.
+	HeatTreatmentStatus struct {
+		Threshold       float32 `json:"threshold"`
+		RequiredSeconds int64   `json:"required_seconds"`
+		HeldSeconds     int64   `json:"held_seconds"`
+		Holding         bool    `json:"holding"`
+	}
+
+	// HeatTreatmentResult is what the execution log shows one heat
+	// treatment step's hold came to. MetAt is when, in seconds into the run,
+	// the hold was met.
+	HeatTreatmentResult struct {
+		Step            string        `json:"step"`
+		Threshold       float32       `json:"threshold"`
+		RequiredSeconds int64         `json:"required_seconds"`
+		OnDip           HoldDipPolicy `json:"on_dip"`
+		HeldSeconds     int64         `json:"held_seconds"`
+		Dips            int           `json:"dips"`
+		Met             bool          `json:"met"`
+		MetAt           int64         `json:"met_at,omitempty"`
+	}
+
+	// ComplianceReport is the heat treatment record of a run, built from its
+	// execution log once the run is over rather than from what the control
+	// unit believed while running it, so the proof is the same data anyone
+	// can download. LogSHA256 pins the log it was built from. A kiln with a
+	// compliance_key signs the report; PublicKey is the key to check the
+	// Signature against, and comparing it with the kiln's own is what ties
+	// the report to the kiln.
+	ComplianceReport struct {
+		Run            string                `json:"run"`
+		Program        string                `json:"program"`
+		MaterialFusion MaterialFusion        `json:"material_fusion"`
+		HeatTreatments []HeatTreatmentResult `json:"heat_treatments"`
+		LogSHA256      string                `json:"log_sha256"`
+		PublicKey      string                `json:"public_key,omitempty"`
+		Signature      string                `json:"signature,omitempty"`
+	}
+)
+
+func (h *HeatTreatmentHold) applyDefaults() {
+	if h.Threshold == 0 {
+		h.Threshold = ISPM15Threshold
+	}
+	if h.Duration == nil {
+		h.Duration = &StepDuration{ISPM15Duration}
+	}
+	if h.OnDip == "" {
+		h.OnDip = HoldDipReset
+	}
+}
+
+func (p *ProgramStep) validateHeatTreatmentStep(steamCeiling uint16) error {
+	if p.Hold == nil {
+		return errors.New("heat treatment step must have a hold")
+	}
+	if p.Hold.Threshold <= 0 {
+		return errors.New("heat treatment hold threshold must be greater than zero")
+	}
+	if p.Hold.Duration == nil || p.Hold.Duration.Duration < time.Second {
+		return errors.New("heat treatment hold duration must be at least a second")
+	}
+	if p.Hold.OnDip != HoldDipReset && p.Hold.OnDip != HoldDipPause {
+		return fmt.Errorf("heat treatment hold on_dip must be %q or %q, not %q", HoldDipReset, HoldDipPause, p.Hold.OnDip)
+	}
+	// The kiln is held at the target and the wood only ever approaches it,
+	// so a target at the threshold would leave the hold waiting forever.
+	if float32(p.TargetTemperature) <= p.Hold.Threshold {
+		return fmt.Errorf("heat treatment step temperature must be above its %.1f°C hold threshold", p.Hold.Threshold)
+	}
+	return p.validateHeldAtTarget("heat treatment", steamCeiling)
+}
+
+// HeatTreats reports whether the program has a heat treatment step, which is
+// what makes its run worth a compliance report.
+func (p *Program) HeatTreats() bool {
+	for i := range p.ProgramSteps {
+		if p.ProgramSteps[i].StepType == StepTypeHeatTreatment {
+			return true
+		}
+	}
+	return false
+}
+
+// LoggedTemperature is a temperature as the execution log records it, to the
+// tenth of a degree. The control unit judges a hold on this rather than on the
+// reading itself, so that the report built from the log afterwards sees every
+// crossing of the threshold exactly where the run did.
+func LoggedTemperature(reading float32) float32 {
+	logged, _ := strconv.ParseFloat(fmt.Sprintf("%.1f", reading), 32)
+	return float32(logged)
+}
+
+// DecodeComplianceKey decodes a compliance_key: the base64 of a 32 byte
+// Ed25519 seed, such as `openssl rand -base64 32` prints.
+func DecodeComplianceKey(encoded string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("compliance_key must be base64: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("compliance_key must be %d bytes, not %d", ed25519.SeedSize, len(seed))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// CompliancePublicKey is the public half of a compliance key, as reports
+// carry it.
+func CompliancePublicKey(key ed25519.PrivateKey) string {
+	public, _ := key.Public().(ed25519.PublicKey)
+	return base64.StdEncoding.EncodeToString(public)
+}
+
+// ComplianceLogDigest is the digest a report pins its execution log with.
+func ComplianceLogDigest(executionLog []byte) string {
+	digest := sha256.Sum256(executionLog)
+	return hex.EncodeToString(digest[:])
+}
+
+// logSample is one execution log row, as much of it as a hold needs. number
+// is the step's number, 0 in logs written before the step_number column.
+type logSample struct {
+	time     int64
+	step     string
+	number   int
+	material float32
+}
+
+// BuildComplianceReport works out from a run's execution log whether each of
+// the program's heat treatment steps met its hold.
+//
+// The log samples on an interval and on every change of step or of the hold,
+// so between two rows the material stayed on the side of the threshold the
+// first was on. A span counts as held when it starts at or above the
+// threshold; the first row of the next step closes the last span of a step,
+// and a step that was still running when the log ended gets no credit for
+// time after its last row.
+func BuildComplianceReport(run string, program *Program, fusion MaterialFusion, executionLog []byte) (*ComplianceReport, error) {
+	samples, err := readLogSamples(executionLog)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ComplianceReport{
+		Run:            run,
+		Program:        program.ProgramName,
+		MaterialFusion: fusion,
+		HeatTreatments: []HeatTreatmentResult{},
+		LogSHA256:      ComplianceLogDigest(executionLog),
+	}
+	for i := range program.ProgramSteps {
+		step := &program.ProgramSteps[i]
+		if step.StepType != StepTypeHeatTreatment || step.Hold == nil || step.Hold.Duration == nil {
+			continue
+		}
+		report.HeatTreatments = append(report.HeatTreatments, holdResult(i+1, step, samples))
+	}
+	return report, nil
+}
+
+// holdResult works out the hold of step, the program's step number. Rows are
+// matched on the step's number, as names need not be unique; only a log
+// without numbers falls back to the name.
+func holdResult(number int, step *ProgramStep, samples []logSample) HeatTreatmentResult {
+	hold := step.Hold
+	result := HeatTreatmentResult{
+		Step:            step.Name,
+		Threshold:       hold.Threshold,
+		RequiredSeconds: int64(hold.Duration.Seconds()),
+		OnDip:           hold.OnDip,
+	}
+
+	var current int64
+	holding := false
+	for i, sample := range samples {
+		if sample.number != number && (sample.number != 0 || sample.step != step.Name) {
+			continue
+		}
+		if sample.material < hold.Threshold {
+			if holding {
+				result.Dips++
+				if hold.OnDip == HoldDipReset {
+					current = 0
+				}
+			}
+			holding = false
+			continue
+		}
+		holding = true
+		if i+1 >= len(samples) {
+			break
+		}
+		current += samples[i+1].time - sample.time
+		result.HeldSeconds = max(result.HeldSeconds, current)
+		if !result.Met && result.HeldSeconds >= result.RequiredSeconds {
+			result.Met = true
+			result.MetAt = samples[i+1].time
+		}
+	}
+	return result
+}
+
+func readLogSamples(executionLog []byte) ([]logSample, error) {
+	reader := csv.NewReader(bytes.NewReader(executionLog))
+	// Logs written before a column was added have fewer fields.
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("reading execution log: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("execution log is empty")
+	}
+
+	columns := map[string]int{}
+	for i, name := range rows[0] {
+		columns[name] = i
+	}
+	for _, required := range []string{"time", "step", "material"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("execution log has no %s column", required)
+		}
+	}
+
+	samples := make([]logSample, 0, len(rows)-1)
+	for n, row := range rows[1:] {
+		elapsed, err := strconv.ParseInt(row[columns["time"]], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("execution log line %d: time is not a number", n+2)
+		}
+		material, err := strconv.ParseFloat(row[columns["material"]], 32)
+		if err != nil {
+			return nil, fmt.Errorf("execution log line %d: material is not a number", n+2)
+		}
+		var number int
+		if column, ok := columns["step_number"]; ok && column < len(row) && row[column] != "" {
+			if number, err = strconv.Atoi(row[column]); err != nil {
+				return nil, fmt.Errorf("execution log line %d: step_number is not a number", n+2)
+			}
+		}
+		samples = append(samples, logSample{time: elapsed, step: row[columns["step"]], number: number, material: float32(material)})
+	}
+	return samples, nil
+}
+
+// Compliant reports whether every heat treatment in the run met its hold.
+func (r *ComplianceReport) Compliant() bool {
+	for _, treatment := range r.HeatTreatments {
+		if !treatment.Met {
+			return false
+		}
+	}
+	return len(r.HeatTreatments) > 0
+}
+
+// signedContent is what the signature covers: the whole report but the
+// signature itself.
+func (r *ComplianceReport) signedContent() ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = ""
+	return json.Marshal(unsigned)
+}
+
+// Sign signs the report with the kiln's compliance key.
+func (r *ComplianceReport) Sign(key ed25519.PrivateKey) error {
+	r.PublicKey = CompliancePublicKey(key)
+	content, err := r.signedContent()
+	if err != nil {
+		return err
+	}
+	r.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, content))
+	return nil
+}
+
+// Verify checks the signature against the public key the report carries. It
+// says the report has not changed since it was signed; whether that key is the
+// kiln's is for the caller to compare.
+func (r *ComplianceReport) Verify() error {
+	if r.Signature == "" {
+		return errors.New("report is not signed")
+	}
+	public, err := base64.StdEncoding.DecodeString(r.PublicKey)
+	if err != nil || len(public) != ed25519.PublicKeySize {
+		return errors.New("report public key is not an Ed25519 key")
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return errors.New("report signature is not base64")
+	}
+	content, err := r.signedContent()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(public), content, signature) {
+		return errors.New("signature does not match the report")
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package types
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// heatTreatmentProgram heats to 60°C and holds the wood at ISPM-15's 56°C.
+func heatTreatmentProgram() Program {
+	treatment := acclimateStep(&PowerPidSettings{MinDelta: f32(-1), MaxDelta: f32(3)})
+	treatment.Name = "ISPM-15"
+	treatment.StepType = StepTypeHeatTreatment
+	treatment.TargetTemperature = 60
+	treatment.Runtime = nil
+	treatment.Steam = &PowerPidSettings{Power: u8(0)}
+
+	heating := heatingStep(&PowerPidSettings{Power: u8(0)})
+	heating.TargetTemperature = 60
+	cooling := steamCoolingStep(30, &PowerPidSettings{Power: u8(0)})
+	cooling.Fan = &PowerPidSettings{Power: u8(100)}
+
+	return Program{ProgramName: "Pallets", ProgramSteps: []ProgramStep{heating, treatment, cooling}}
+}
+
+func TestHeatTreatmentHoldDefaultsToISPM15(t *testing.T) {
+	program := heatTreatmentProgram()
+	program.ApplyDefaults(templateDefaults(t))
+	if err := program.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	hold := program.ProgramSteps[1].Hold
+	if hold.Threshold != 56 || hold.Duration.Duration != 30*time.Minute || hold.OnDip != HoldDipReset {
+		t.Errorf("hold = %v°C for %v on dip %q, want 56°C for 30m on dip reset", hold.Threshold, hold.Duration, hold.OnDip)
+	}
+	if !program.HeatTreats() {
+		t.Error("a program with a heat treatment step does not report HeatTreats")
+	}
+}
+
+func TestHeatTreatmentValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(p *Program)
+		wantErr string
+	}{
+		{"pause policy", func(p *Program) { p.ProgramSteps[1].Hold = &HeatTreatmentHold{OnDip: HoldDipPause} }, ""},
+		{"runtime cap", func(p *Program) { p.ProgramSteps[1].Runtime = &StepDuration{2 * time.Hour} }, ""},
+		{"unknown policy", func(p *Program) { p.ProgramSteps[1].Hold = &HeatTreatmentHold{OnDip: "ignore"} }, "on_dip"},
+		{"target at the threshold", func(p *Program) {
+			p.ProgramSteps[0].TargetTemperature = 56
+			p.ProgramSteps[1].TargetTemperature = 56
+		}, "above its 56.0°C hold threshold"},
+		{"hold on an acclimate", func(p *Program) {
+			p.ProgramSteps[1].StepType = StepTypeAcclimate
+			p.ProgramSteps[1].Runtime = &StepDuration{time.Hour}
+			p.ProgramSteps[1].Hold = &HeatTreatmentHold{}
+		}, "only heat treatment steps"},
+		{"constant steam below the ceiling", func(p *Program) { p.ProgramSteps[1].Steam = &PowerPidSettings{Power: u8(50)} }, "heat treatment step below"},
+		{"cooler than the step before", func(p *Program) { p.ProgramSteps[1].TargetTemperature = 58 }, "heat treatment step temperature must be greater"},
+		{"moisture target", func(p *Program) { p.ProgramSteps[1].TargetMoisture = f32(12) }, "only heating and acclimate"},
+		{"unnamed", func(p *Program) { p.ProgramSteps[1].Name = "" }, "step 2 must have a name"},
+		{"name shared with another step", func(p *Program) { p.ProgramSteps[0].Name = "ISPM-15" }, "shares its name with step 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := heatTreatmentProgram()
+			tt.modify(&program)
+			program.ApplyDefaults(templateDefaults(t))
+			err := program.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected validation to pass, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// treatmentLog is an execution log of the heat treatment, in the columns
+// that matter to it. Each row is time,step,steptime,material.
+func treatmentLog(rows ...string) []byte {
+	return []byte("time,step,steptime,material\n" + strings.Join(rows, "\n") + "\n")
+}
+
+func defaultedTreatment(t *testing.T, onDip HoldDipPolicy) *Program {
+	t.Helper()
+	program := heatTreatmentProgram()
+	program.ProgramSteps[1].Hold = &HeatTreatmentHold{Duration: &StepDuration{10 * time.Minute}, OnDip: onDip}
+	program.ApplyDefaults(templateDefaults(t))
+	return &program
+}
+
+func TestComplianceReportFromTheLog(t *testing.T) {
+	tests := []struct {
+		name      string
+		onDip     HoldDipPolicy
+		rows      []string
+		wantHeld  int64
+		wantDips  int
+		wantMetAt int64
+	}{
+		{
+			"continuous",
+			HoldDipReset,
+			[]string{"0,Heat,0,40.0", "300,ISPM-15,0,55.0", "360,ISPM-15,60,56.0", "660,ISPM-15,360,57.0", "961,Cool,0,57.5"},
+			601, 0, 961,
+		},
+		{
+			"a dip starts it over",
+			HoldDipReset,
+			[]string{"300,ISPM-15,0,56.5", "600,ISPM-15,300,55.9", "610,ISPM-15,310,56.2", "1000,Cool,0,57.0"},
+			390, 1, 0,
+		},
+		{
+			"a dip only pauses it",
+			HoldDipPause,
+			[]string{"300,ISPM-15,0,56.5", "600,ISPM-15,300,55.9", "610,ISPM-15,310,56.2", "1000,Cool,0,57.0"},
+			690, 1, 1000,
+		},
+		{
+			"no credit past the end of the log",
+			HoldDipReset,
+			[]string{"300,ISPM-15,0,56.5", "800,ISPM-15,500,57.0"},
+			500, 0, 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executionLog := treatmentLog(tt.rows...)
+			report, err := BuildComplianceReport("Pallets@now", defaultedTreatment(t, tt.onDip), MaterialFusionMin, executionLog)
+			if err != nil {
+				t.Fatalf("BuildComplianceReport: %v", err)
+			}
+			if len(report.HeatTreatments) != 1 {
+				t.Fatalf("report has %d heat treatments, want 1", len(report.HeatTreatments))
+			}
+			result := report.HeatTreatments[0]
+			if result.HeldSeconds != tt.wantHeld || result.Dips != tt.wantDips || result.MetAt != tt.wantMetAt {
+				t.Errorf("held %ds with %d dips, met at %d; want %ds with %d dips, met at %d",
+					result.HeldSeconds, result.Dips, result.MetAt, tt.wantHeld, tt.wantDips, tt.wantMetAt)
+			}
+			if result.Met != (tt.wantMetAt != 0) || report.Compliant() != result.Met {
+				t.Errorf("met = %v, compliant = %v", result.Met, report.Compliant())
+			}
+			if report.LogSHA256 != ComplianceLogDigest(executionLog) {
+				t.Error("the report does not pin the log it was built from")
+			}
+		})
+	}
+}
+
+// Rows are matched to the heat treatment step by its number, so a step before
+// it with the same name, or none, cannot lend it its time at temperature.
+func TestComplianceReportMatchesTheStepByNumber(t *testing.T) {
+	program := defaultedTreatment(t, HoldDipReset)
+	program.ProgramSteps[0].Name = ""
+	program.ProgramSteps[1].Name = ""
+	executionLog := []byte("time,step,steptime,material,step_number\n" +
+		"0,,0,57.0,1\n" +
+		"1500,,0,58.0,2\n" +
+		"1900,,400,55.0,2\n" +
+		"2000,Cool,0,50.0,3\n")
+
+	report, err := BuildComplianceReport("Pallets@now", program, MaterialFusionMin, executionLog)
+	if err != nil {
+		t.Fatalf("BuildComplianceReport: %v", err)
+	}
+	result := report.HeatTreatments[0]
+	if result.HeldSeconds != 400 || result.Met || report.Compliant() {
+		t.Errorf("held %ds, met %v, compliant %v; want 400s and not compliant", result.HeldSeconds, result.Met, report.Compliant())
+	}
+}
+
+func TestComplianceReportRejectsALogWithoutMaterial(t *testing.T) {
+	_, err := BuildComplianceReport("Pallets@now", defaultedTreatment(t, HoldDipReset), MaterialFusionMin, []byte("time,step\n0,Heat\n"))
+	if err == nil || !strings.Contains(err.Error(), "material column") {
+		t.Fatalf("expected an error about the material column, got %v", err)
+	}
+}
+
+func testComplianceKey(t *testing.T) string {
+	t.Helper()
+	return base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+}
+
+func TestComplianceReportSignature(t *testing.T) {
+	key, err := DecodeComplianceKey(testComplianceKey(t))
+	if err != nil {
+		t.Fatalf("DecodeComplianceKey: %v", err)
+	}
+	report, err := BuildComplianceReport("Pallets@now", defaultedTreatment(t, HoldDipReset),
+		MaterialFusionMin, treatmentLog("300,ISPM-15,0,56.5", "961,Cool,0,57.0"))
+	if err != nil {
+		t.Fatalf("BuildComplianceReport: %v", err)
+	}
+
+	if err := report.Verify(); err == nil {
+		t.Fatal("an unsigned report verifies")
+	}
+	if err := report.Sign(key); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if err := report.Verify(); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if report.PublicKey != CompliancePublicKey(key) {
+		t.Error("the report does not carry the key it was signed with")
+	}
+
+	report.HeatTreatments[0].HeldSeconds = 1800
+	if err := report.Verify(); err == nil {
+		t.Fatal("a report changed after signing still verifies")
+	}
+}
+
+func TestLoadConfigValidatesComplianceKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{"absent", ``, false},
+		{"a seed", `"compliance_key": "` + testComplianceKey(t) + `",`, false},
+		{"not base64", `"compliance_key": "not a key!",`, true},
+		{"too short", `"compliance_key": "c2hvcnQ=",`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "halko.cfg")
+			data := strings.Replace(testConfigData, `"tick_length": "6s",`, `"tick_length": "6s", `+tt.key, 1)
+			if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+				t.Fatalf("write config: %v", err)
+			}
+			_, err := LoadConfig(configPath)
+			if tt.wantErr && err == nil {
+				t.Fatal("expected LoadConfig to fail, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+// A reading the log rounds up to the threshold holds, one it rounds down does
+// not, whichever side of it the reading itself was.
+func TestLoggedTemperatureMatchesTheLog(t *testing.T) {
+	tests := []struct{ reading, want float32 }{
+		{55.96, 56},
+		{55.94, 55.9},
+		{56.04, 56},
+		{56, 56},
+	}
+	for _, tt := range tests {
+		if got := LoggedTemperature(tt.reading); got != tt.want {
+			t.Errorf("LoggedTemperature(%v) = %v, want %v", tt.reading, got, tt.want)
+		}
+	}
+}
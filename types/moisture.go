@@ -0,0 +1,88 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	// MaxMoistureProbes is how many pairs of moisture pins the sensor unit
+	// firmware can read. They are named mc_1 to mc_4.
+	MaxMoistureProbes = 4
+
+	// MaxMoistureTarget is the wettest a step may end at. Above the fibre
+	// saturation point the wood's resistance barely changes with its
+	// moisture, so a resistance meter's reading there says little more than
+	// "wet" and cannot be trusted to end a step.
+	MaxMoistureTarget = 30
+)
+
+// MoistureProbes describes the resistance-type moisture pins driven into the
+// sample boards. The firmware converts each pair's resistance to a moisture
+// content on its reference scale, which is what the common meter curves are
+// for: Douglas fir at 20°C. The sensor unit corrects that to the wood's
+// temperature and then to the species being dried.
+//
+// Count is how many pairs are fitted, from mc_1 upwards. SpeciesSlope and
+// SpeciesIntercept are the species correction a meter's table gives, as the
+// reference-scale reading a + b*MC of wood at moisture content MC; both left
+// out is the reference species itself.
+type MoistureProbes struct {
+	Count            int     `json:"count"`
+	SpeciesSlope     float32 `json:"species_slope,omitempty"`
+	SpeciesIntercept float32 `json:"species_intercept,omitempty"`
+}
+
+func (m *MoistureProbes) validate() error {
+	if m == nil {
+		return nil
+	}
+	if m.Count < 1 || m.Count > MaxMoistureProbes {
+		return fmt.Errorf("sensor unit moisture_probes.count must be between 1 and %d, leave moisture_probes out without any",
+			MaxMoistureProbes)
+	}
+	// Both left out is the reference species, resolved here so the
+	// conversion need not check for it.
+	if m.SpeciesSlope == 0 && m.SpeciesIntercept == 0 {
+		m.SpeciesSlope = 1
+	}
+	if m.SpeciesSlope <= 0 {
+		return errors.New("sensor unit moisture_probes.species_slope must be greater than zero")
+	}
+	return nil
+}
+
+// MoistureProbeName returns the name of the nth moisture probe, counting
+// from 1.
+func MoistureProbeName(n int) string {
+	return "mc_" + strconv.Itoa(n)
+}
+
+// temperatureCorrected takes a reference-scale reading at the wood's
+// temperature, in °C, back to what it would read at 20°C. Wood conducts
+// better as it warms, so a meter reads high in a hot kiln: by about a fifth
+// at 60°C. This is Garrahan's fit, as the Dry Kiln Operator's Manual gives
+// it.
+func temperatureCorrected(reading, temperature float64) float64 {
+	t := temperature
+	return (reading + 0.567 - 0.0260*t + 0.000051*t*t) / (0.881 * math.Pow(1.0056, t))
+}
+
+// CorrectMoistureReading returns the moisture content, in percent of dry
+// weight, of wood at temperature °C that a pin pair read as reading on the
+// reference scale.
+func (m *MoistureProbes) CorrectMoistureReading(reading, temperature float32) float32 {
+	corrected := temperatureCorrected(float64(reading), float64(temperature))
+	return float32(math.Max(0, (corrected-float64(m.SpeciesIntercept))/float64(m.SpeciesSlope)))
+}
+
+// MoistureReading is the inverse of CorrectMoistureReading: what a pin pair
+// reads on the reference scale in wood of the given moisture content and
+// temperature. The simulator uses it to answer the way real pins would.
+func (m *MoistureProbes) MoistureReading(moistureContent, temperature float32) float32 {
+	t := float64(temperature)
+	reference := float64(m.SpeciesIntercept) + float64(m.SpeciesSlope)*float64(moistureContent)
+	return float32(reference*0.881*math.Pow(1.0056, t) - 0.567 + 0.0260*t - 0.000051*t*t)
+}
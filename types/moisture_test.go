@@ -0,0 +1,84 @@
+package types
+
+import "testing"
+
+func TestLoadConfigValidatesMoistureProbes(t *testing.T) {
+	tests := []struct {
+		name    string
+		probes  string
+		wantErr bool
+	}{
+		{"absent", ``, false},
+		{"reference species", `"moisture_probes": {"count": 2},`, false},
+		{"species correction", `"moisture_probes": {"count": 4, "species_slope": 0.9, "species_intercept": 1.2},`, false},
+		{"no probes", `"moisture_probes": {"count": 0},`, true},
+		{"too many probes", `"moisture_probes": {"count": 5},`, true},
+		{"negative slope", `"moisture_probes": {"count": 1, "species_slope": -1},`, true},
+		{"intercept without a slope", `"moisture_probes": {"count": 1, "species_intercept": 1.2},`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfigWithColdJunction(t, tt.probes))
+			if tt.wantErr && err == nil {
+				t.Fatal("expected LoadConfig to fail, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+// Leaving the species out reads on the reference scale.
+func TestLoadConfigDefaultsMoistureProbesToTheReferenceSpecies(t *testing.T) {
+	config, err := LoadConfig(writeConfigWithColdJunction(t, `"moisture_probes": {"count": 2},`))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if probes := config.SensorUnit.MoistureProbes; probes.SpeciesSlope != 1 || probes.SpeciesIntercept != 0 {
+		t.Errorf("species correction = %v, %v, want 1, 0", probes.SpeciesSlope, probes.SpeciesIntercept)
+	}
+}
+
+func TestCorrectMoistureReadingForTemperature(t *testing.T) {
+	reference := MoistureProbes{Count: 1, SpeciesSlope: 1}
+	tests := []struct {
+		reading, temperature, want float32
+	}{
+		{12, 20, 12.2}, // the reference temperature, next to no change
+		{12, 60, 9.1},  // warm wood reads wet
+		{20, 80, 13.7},
+		{0.5, 80, 0}, // never below bone dry
+	}
+
+	for _, tt := range tests {
+		got := reference.CorrectMoistureReading(tt.reading, tt.temperature)
+		if !within(got, tt.want, 0.1) {
+			t.Errorf("CorrectMoistureReading(%v, %v) = %v, want %v", tt.reading, tt.temperature, got, tt.want)
+		}
+	}
+}
+
+func TestCorrectMoistureReadingForSpecies(t *testing.T) {
+	probes := MoistureProbes{Count: 1, SpeciesSlope: 0.8, SpeciesIntercept: 2}
+	reference := MoistureProbes{Count: 1, SpeciesSlope: 1}
+
+	// A species reading 2 + 0.8*MC on the reference scale.
+	want := (reference.CorrectMoistureReading(14, 20) - 2) / 0.8
+	if got := probes.CorrectMoistureReading(14, 20); !within(got, want, 0.01) {
+		t.Errorf("CorrectMoistureReading() = %v, want %v", got, want)
+	}
+}
+
+func TestMoistureReadingInvertsTheCorrection(t *testing.T) {
+	probes := MoistureProbes{Count: 1, SpeciesSlope: 0.9, SpeciesIntercept: 1.5}
+	for _, mc := range []float32{6, 12, 25} {
+		for _, temperature := range []float32{20, 55, 90} {
+			reading := probes.MoistureReading(mc, temperature)
+			if got := probes.CorrectMoistureReading(reading, temperature); !within(got, mc, 0.001) {
+				t.Errorf("MC %v at %v°C reads %v, which corrects to %v", mc, temperature, reading, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+)
+
+const (
+	StoredProgramUnchanged StoredProgramState = "unchanged"
+	StoredProgramChanged   StoredProgramState = "changed"
+	StoredProgramDeleted   StoredProgramState = "deleted"
+)
+
+type (
+	// ProgramOverrides changes a stored program for one run without editing
+	// it: the equalize settings, and fields of steps picked out by name.
+	// Steps maps a step's name to the fields to change, written as they are
+	// in a step; a field set to null is removed.
+	ProgramOverrides struct {
+		Equalize *EqualizeSettings                     `json:"equalize,omitempty"`
+		Steps    map[string]map[string]json.RawMessage `json:"steps,omitempty"`
+	}
+
+	// StoredProgramRef records which stored program a run was started from:
+	// its name, the hash of its content when it was started, and what was
+	// overridden for the run.
+	StoredProgramRef struct {
+		Name      string            `json:"name"`
+		Hash      string            `json:"hash"`
+		Overrides *ProgramOverrides `json:"overrides,omitempty"`
+	}
+
+	// StoredProgramState tells whether the stored program a run was started
+	// from is still as it was then.
+	StoredProgramState string
+)
+
+// stepFieldsNotOverridden are the fields that make an entry what it is; an
+// override that changed them would be a different program.
+var stepFieldsNotOverridden = []string{"name", "type", "repeat", "include"}
+
+// stepFieldNames are the JSON names of a step's fields, to catch an override
+// of a misspelled one, which decoding would otherwise drop without a word.
+func stepFieldNames() []string {
+	var names []string
+	fields := reflect.TypeFor[ProgramStep]()
+	for i := range fields.NumField() {
+		name, _, _ := strings.Cut(fields.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ContentHash is a hash of the program as it is stored, to tell whether it
+// has been edited since a run was started from it. It is taken over the
+// program's JSON, so re-indenting the file does not change it.
+func (p *Program) ContentHash() (string, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+// ApplyOverrides changes the program as the overrides say. It works on the
+// steps as they run, so it goes after Render and ExpandSteps: a step inside a
+// repeat is named with its round, "Steam (2/3)". Everything an override
+// changes is still held to Validate.
+func (p *Program) ApplyOverrides(overrides *ProgramOverrides) error {
+	if overrides == nil {
+		return nil
+	}
+
+	if overrides.Equalize != nil {
+		if p.Equalize == nil {
+			p.Equalize = &EqualizeSettings{}
+		}
+		if overrides.Equalize.Delta != nil {
+			p.Equalize.Delta = overrides.Equalize.Delta
+		}
+		if overrides.Equalize.SteamPrewarm != nil {
+			p.Equalize.SteamPrewarm = overrides.Equalize.SteamPrewarm
+		}
+	}
+
+	names := make([]string, 0, len(overrides.Steps))
+	for name := range overrides.Steps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		index := -1
+		for i := range p.ProgramSteps {
+			if p.ProgramSteps[i].Name != name {
+				continue
+			}
+			if index >= 0 {
+				return fmt.Errorf("override for step %q: more than one step has that name", name)
+			}
+			index = i
+		}
+		if index < 0 {
+			return fmt.Errorf("override for step %q: the program has no such step", name)
+		}
+		if err := p.ProgramSteps[index].override(overrides.Steps[name]); err != nil {
+			return fmt.Errorf("override for step %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// override merges the fields into the step through its JSON, so that each
+// is read exactly as it would be in a program file.
+func (p *ProgramStep) override(fields map[string]json.RawMessage) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	var merged map[string]json.RawMessage
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return err
+	}
+	for field, value := range fields {
+		if slices.Contains(stepFieldsNotOverridden, field) {
+			return fmt.Errorf("%s cannot be overridden", field)
+		}
+		if !slices.Contains(stepFieldNames(), field) {
+			return fmt.Errorf("a step has no field %s", field)
+		}
+		if string(value) == "null" {
+			delete(merged, field)
+			continue
+		}
+		merged[field] = value
+	}
+
+	if data, err = json.Marshal(merged); err != nil {
+		return err
+	}
+	var step ProgramStep
+	if err := json.Unmarshal(data, &step); err != nil {
+		return err
+	}
+	if step.bindings != nil {
+		return fmt.Errorf("an override cannot use a parameter")
+	}
+	*p = step
+	return nil
+}
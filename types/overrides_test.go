@@ -0,0 +1,85 @@
+package types
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	program := validProgram()
+	program.ProgramSteps[1].TargetMoisture = f32(12)
+
+	err := program.ApplyOverrides(&ProgramOverrides{
+		Equalize: &EqualizeSettings{Delta: f32(3)},
+		Steps: map[string]map[string]json.RawMessage{
+			"hold": {"moisture_target": json.RawMessage("null"), "fan": json.RawMessage(`{"power": 40}`)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ApplyOverrides: %v", err)
+	}
+
+	hold := program.ProgramSteps[1]
+	if hold.TargetMoisture != nil {
+		t.Error("moisture target set to null was kept")
+	}
+	if hold.Fan == nil || *hold.Fan.Power != 40 {
+		t.Errorf("fan = %+v, want 40%%", hold.Fan)
+	}
+	if *program.Equalize.Delta != 3 {
+		t.Errorf("equalize delta = %v, want 3", *program.Equalize.Delta)
+	}
+
+	program.ApplyDefaults(templateDefaults(t))
+	if err := program.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+func TestApplyOverridesRefuses(t *testing.T) {
+	tests := []struct {
+		name     string
+		steps    map[string]map[string]json.RawMessage
+		contains string
+	}{
+		{"two steps of the name", map[string]map[string]json.RawMessage{"heat": {"runtime": json.RawMessage(`"1h"`)}}, "more than one step"},
+		{"a parameter", map[string]map[string]json.RawMessage{"hold": {"runtime": json.RawMessage(`"{{soak}}"`)}}, "cannot use a parameter"},
+		{"the step type", map[string]map[string]json.RawMessage{"hold": {"type": json.RawMessage(`"heating"`)}}, "type cannot be overridden"},
+		{"a value of the wrong type", map[string]map[string]json.RawMessage{"hold": {"temperature_target": json.RawMessage(`"hot"`)}}, "override for step"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := validProgram()
+			program.ProgramSteps[2].Name = "heat"
+
+			err := program.ApplyOverrides(&ProgramOverrides{Steps: tt.steps})
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Fatalf("ApplyOverrides = %v, want an error containing %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+// Re-indenting a stored program is not an edit to it.
+func TestContentHashIgnoresFormatting(t *testing.T) {
+	var compact, indented Program
+	if err := json.Unmarshal([]byte(`{"name":"Oak","steps":[{"name":"heat","type":"heating","temperature_target":60}]}`), &compact); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte("{\n  \"steps\": [\n    {\"type\": \"heating\", \"name\": \"heat\", \"temperature_target\": 60}\n  ],\n  \"name\": \"Oak\"\n}"), &indented); err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := compact.ContentHash()
+	second, _ := indented.ContentHash()
+	if first != second {
+		t.Errorf("hashes differ: %s and %s", first, second)
+	}
+
+	indented.ProgramSteps[0].TargetTemperature = 65
+	if third, _ := indented.ContentHash(); third == first {
+		t.Error("hash unchanged by an edit")
+	}
+}
@@ -0,0 +1,223 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Probe names. Calibration is keyed by them, and the sensor unit reports each
+// probe's uncorrected reading under the name with RawReadingSuffix appended.
+const (
+	ProbeKilnPrimary   = "kiln_primary"
+	ProbeKilnSecondary = "kiln_secondary"
+	ProbeMaterial      = "material"
+
+	// MaxMaterialProbes is how many thermocouples the sensor unit firmware
+	// can read in the wood. The first is ProbeMaterial, the others are
+	// numbered from 2: material_2, material_3.
+	MaxMaterialProbes = 3
+
+	// RawReadingSuffix marks a per-probe reading in the temperature
+	// response: uncorrected and before fusion, e.g. kiln_primary_raw.
+	RawReadingSuffix = "_raw"
+)
+
+// ProbeFault values. The first three are the MAX31855's own fault bits, which
+// the firmware reports after a failed probe's NaN as `NaN:<bits>`; bits of 0
+// mean the chip did not answer at all.
+const (
+	ProbeFaultOpenCircuit ProbeFault = "open_circuit"
+	ProbeFaultShortToGND  ProbeFault = "short_to_gnd"
+	ProbeFaultShortToVCC  ProbeFault = "short_to_vcc"
+	ProbeFaultNoResponse  ProbeFault = "no_response"
+)
+
+// The MAX31855 fault bits, D2..D0 of its data frame.
+const (
+	max31855FaultOpen = 0x1
+	max31855FaultGND  = 0x2
+	max31855FaultVCC  = 0x4
+)
+
+// KilnFusion values
+const (
+	KilnFusionMax     KilnFusion = "max"
+	KilnFusionMean    KilnFusion = "mean"
+	KilnFusionPrimary KilnFusion = "primary"
+	KilnFusionMedian  KilnFusion = "median"
+)
+
+// MaterialFusion values
+const (
+	MaterialFusionMin          MaterialFusion = "min"
+	MaterialFusionMean         MaterialFusion = "mean"
+	MaterialFusionCoreWeighted MaterialFusion = "core_weighted"
+)
+
+type (
+	// KilnFusion is how the kiln probes are combined into the one kiln
+	// temperature the control unit regulates on.
+	//
+	//   - max reports the hotter probe, switching only when the other one
+	//     leads by a margin. It keeps the hottest spot from being overheated.
+	//   - mean reports the average of the valid probes.
+	//   - primary reports the primary probe, and the secondary only while
+	//     the primary has failed.
+	//   - median reports the median of the valid probes. With the two probes
+	//     the firmware reads it equals mean.
+	KilnFusion string
+
+	// MaterialFusion is how the material probes are combined into the one
+	// material temperature.
+	//
+	//   - min reports the coldest probe, so a step only ends once all of the
+	//     wood has reached its target.
+	//   - mean reports the average of the valid probes.
+	//   - core_weighted reports a weighted average using
+	//     SensorUnitConfig.MaterialWeights, so a probe at the core of a thick
+	//     board can count for more than one near its surface.
+	MaterialFusion string
+
+	// ProbeFault is why a thermocouple reads NaN: a thermocouple circuit that
+	// is broken, one leaking to ground or to the supply, or a chip that does
+	// not answer. They call for different fixes (a connector, a sheath, the
+	// wiring to the board) which a bare NaN cannot tell apart.
+	ProbeFault string
+
+	// KilnDisagreement is when the two kiln probes count as degraded: reading
+	// more than Threshold °C apart for longer than Window. A probe that has
+	// come loose or drifted reads wrong without failing, and the fused kiln
+	// temperature hides it; how far apart two good probes read depends on
+	// the kiln's airflow, so the numbers are configured rather than built in.
+	KilnDisagreement struct {
+		Threshold float32 `json:"threshold"`
+		Window    string  `json:"window"`
+
+		// Resolved from Window once, while loading.
+		WindowDuration time.Duration `json:"-"`
+	}
+)
+
+// ProbeFaults lists every fault kind, in the order of the bits they come from.
+var ProbeFaults = []ProbeFault{ProbeFaultOpenCircuit, ProbeFaultShortToGND, ProbeFaultShortToVCC, ProbeFaultNoResponse}
+
+// ProbeFaultsFromBits decodes the fault bits the firmware reports for a failed
+// probe. More than one bit can be set at once; none set means the chip did
+// not answer.
+func ProbeFaultsFromBits(bits uint8) []ProbeFault {
+	if bits == 0 {
+		return []ProbeFault{ProbeFaultNoResponse}
+	}
+	var faults []ProbeFault
+	if bits&max31855FaultOpen != 0 {
+		faults = append(faults, ProbeFaultOpenCircuit)
+	}
+	if bits&max31855FaultGND != 0 {
+		faults = append(faults, ProbeFaultShortToGND)
+	}
+	if bits&max31855FaultVCC != 0 {
+		faults = append(faults, ProbeFaultShortToVCC)
+	}
+	return faults
+}
+
+// Bits returns the fault bits the firmware reports for f, the inverse of
+// ProbeFaultsFromBits. It is 0 for no_response and for anything unknown.
+func (f ProbeFault) Bits() uint8 {
+	switch f {
+	case ProbeFaultOpenCircuit:
+		return max31855FaultOpen
+	case ProbeFaultShortToGND:
+		return max31855FaultGND
+	case ProbeFaultShortToVCC:
+		return max31855FaultVCC
+	default:
+		return 0
+	}
+}
+
+// TemperatureProbes lists every probe the sensor unit can report, in the order
+// it reports them.
+var TemperatureProbes = []string{
+	ProbeKilnPrimary, ProbeKilnSecondary,
+	MaterialProbeName(1), MaterialProbeName(2), MaterialProbeName(3),
+}
+
+// MaterialProbeName returns the name of the n:th material probe, counting from
+// one as the firmware does.
+func MaterialProbeName(n int) string {
+	if n == 1 {
+		return ProbeMaterial
+	}
+	return ProbeMaterial + "_" + strconv.Itoa(n)
+}
+
+// IsMaterialProbe reports whether name is one of the material probes.
+func IsMaterialProbe(name string) bool {
+	for n := 1; n <= MaxMaterialProbes; n++ {
+		if name == MaterialProbeName(n) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTemperatureProbe(name string) bool {
+	return name == ProbeKilnPrimary || name == ProbeKilnSecondary || IsMaterialProbe(name)
+}
+
+func (c *SensorUnitConfig) validateFusion() error {
+	switch c.KilnFusion {
+	case KilnFusionMax, KilnFusionMean, KilnFusionPrimary, KilnFusionMedian:
+	case "":
+		return errors.New("sensor unit kiln_fusion is required")
+	default:
+		return fmt.Errorf("sensor unit kiln_fusion must be %q, %q, %q or %q, not %q",
+			KilnFusionMax, KilnFusionMean, KilnFusionPrimary, KilnFusionMedian, c.KilnFusion)
+	}
+
+	switch c.MaterialFusion {
+	case MaterialFusionMin, MaterialFusionMean:
+		if len(c.MaterialWeights) > 0 {
+			return fmt.Errorf("sensor unit material_weights only apply to material_fusion %q", MaterialFusionCoreWeighted)
+		}
+	case MaterialFusionCoreWeighted:
+		if len(c.MaterialWeights) == 0 {
+			return fmt.Errorf("sensor unit material_fusion %q needs material_weights", MaterialFusionCoreWeighted)
+		}
+	case "":
+		return errors.New("sensor unit material_fusion is required")
+	default:
+		return fmt.Errorf("sensor unit material_fusion must be %q, %q or %q, not %q",
+			MaterialFusionMin, MaterialFusionMean, MaterialFusionCoreWeighted, c.MaterialFusion)
+	}
+
+	for probe, weight := range c.MaterialWeights {
+		if !IsMaterialProbe(probe) {
+			return fmt.Errorf("sensor unit material_weights: %q is not a material probe (want %s to %s)",
+				probe, MaterialProbeName(1), MaterialProbeName(MaxMaterialProbes))
+		}
+		if weight <= 0 {
+			return fmt.Errorf("sensor unit material_weights: %s must be positive, leave it out to ignore the probe", probe)
+		}
+	}
+	return nil
+}
+
+func (d *KilnDisagreement) validate() error {
+	if d == nil {
+		return errors.New("sensor unit kiln_disagreement is required")
+	}
+	if d.Threshold <= 0 {
+		return errors.New("sensor unit kiln_disagreement.threshold must be greater than zero")
+	}
+	if d.Window == "" {
+		return errors.New("sensor unit kiln_disagreement.window is required")
+	}
+	if _, err := time.ParseDuration(d.Window); err != nil {
+		return fmt.Errorf("sensor unit kiln_disagreement.window must be a valid duration: %w", err)
+	}
+	return nil
+}
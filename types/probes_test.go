@@ -0,0 +1,154 @@
+package types
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfigWithFusion writes the standard test config with its fusion
+// settings replaced by the given JSON members.
+func writeConfigWithFusion(t *testing.T, fusion string) string {
+	t.Helper()
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test_halko.cfg")
+
+	data := strings.Replace(testConfigData, "/dev/ttyUSB0", filepath.Join(tempDir, "esp32"), 1)
+	data = strings.Replace(data, `"kiln_fusion": "max",
+    "material_fusion": "min"`, fusion, 1)
+
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return configPath
+}
+
+func TestMaterialProbeNames(t *testing.T) {
+	if got := MaterialProbeName(1); got != ProbeMaterial {
+		t.Fatalf("MaterialProbeName(1) = %q, want %q", got, ProbeMaterial)
+	}
+	if got := MaterialProbeName(3); got != "material_3" {
+		t.Fatalf("MaterialProbeName(3) = %q, want material_3", got)
+	}
+	if IsMaterialProbe("material_4") || IsMaterialProbe(ProbeKilnPrimary) {
+		t.Fatal("expected only material to material_3 to be material probes")
+	}
+}
+
+func TestLoadConfigValidatesFusion(t *testing.T) {
+	tests := []struct {
+		name    string
+		fusion  string
+		wantErr bool
+	}{
+		{"mean and mean", `"kiln_fusion": "mean", "material_fusion": "mean"`, false},
+		{"primary and core weighted", `"kiln_fusion": "primary", "material_fusion": "core_weighted", "material_weights": {"material": 1, "material_2": 3}`, false},
+		{"kiln fusion missing", `"material_fusion": "min"`, true},
+		{"material fusion missing", `"kiln_fusion": "max"`, true},
+		{"unknown kiln fusion", `"kiln_fusion": "hottest", "material_fusion": "min"`, true},
+		{"unknown material fusion", `"kiln_fusion": "max", "material_fusion": "max"`, true},
+		{"core weighted without weights", `"kiln_fusion": "max", "material_fusion": "core_weighted"`, true},
+		{"weights without core weighted", `"kiln_fusion": "max", "material_fusion": "mean", "material_weights": {"material": 1}`, true},
+		{"weight on a kiln probe", `"kiln_fusion": "max", "material_fusion": "core_weighted", "material_weights": {"kiln_primary": 1}`, true},
+		{"zero weight", `"kiln_fusion": "max", "material_fusion": "core_weighted", "material_weights": {"material": 0}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfigWithFusion(t, tt.fusion))
+			if tt.wantErr && err == nil {
+				t.Fatal("expected LoadConfig to fail, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+// writeConfigWithKilnDisagreement writes the standard test config with its
+// kiln_disagreement member replaced by the given JSON members, which may be
+// none.
+func writeConfigWithKilnDisagreement(t *testing.T, disagreement string) string {
+	t.Helper()
+	tempDir := t.TempDir()
+	configPath := filepath.Join(tempDir, "test_halko.cfg")
+
+	data := strings.Replace(testConfigData, "/dev/ttyUSB0", filepath.Join(tempDir, "esp32"), 1)
+	data = strings.Replace(data, `,
+    "kiln_disagreement": {"threshold": 10.0, "window": "5m"}`, disagreement, 1)
+
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return configPath
+}
+
+func TestLoadConfigValidatesKilnDisagreement(t *testing.T) {
+	tests := []struct {
+		name         string
+		disagreement string
+		wantErr      bool
+	}{
+		{"threshold and window", `, "kiln_disagreement": {"threshold": 8, "window": "10m"}`, false},
+		{"missing", ``, true},
+		{"no threshold", `, "kiln_disagreement": {"window": "5m"}`, true},
+		{"no window", `, "kiln_disagreement": {"threshold": 10}`, true},
+		{"unparseable window", `, "kiln_disagreement": {"threshold": 10, "window": "five minutes"}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfigWithKilnDisagreement(t, tt.disagreement))
+			if tt.wantErr && err == nil {
+				t.Fatal("expected LoadConfig to fail, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
+
+func TestKilnDisagreementWindowIsResolved(t *testing.T) {
+	config, err := LoadConfig(writeConfigWithKilnDisagreement(t, `, "kiln_disagreement": {"threshold": 8, "window": "10m"}`))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if got := config.SensorUnit.KilnDisagreement.WindowDuration; got != 10*time.Minute {
+		t.Fatalf("WindowDuration = %v, want 10m", got)
+	}
+}
+
+func TestProbeFaultsFromBits(t *testing.T) {
+	tests := []struct {
+		bits uint8
+		want []ProbeFault
+	}{
+		{0, []ProbeFault{ProbeFaultNoResponse}},
+		{1, []ProbeFault{ProbeFaultOpenCircuit}},
+		{2, []ProbeFault{ProbeFaultShortToGND}},
+		{4, []ProbeFault{ProbeFaultShortToVCC}},
+		{6, []ProbeFault{ProbeFaultShortToGND, ProbeFaultShortToVCC}},
+	}
+	for _, tt := range tests {
+		got := ProbeFaultsFromBits(tt.bits)
+		if len(got) != len(tt.want) {
+			t.Fatalf("ProbeFaultsFromBits(%d) = %v, want %v", tt.bits, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("ProbeFaultsFromBits(%d) = %v, want %v", tt.bits, got, tt.want)
+			}
+		}
+	}
+
+	// Every kind survives the trip through the firmware's bits.
+	for _, fault := range ProbeFaults {
+		if got := ProbeFaultsFromBits(fault.Bits()); len(got) != 1 || got[0] != fault {
+			t.Errorf("ProbeFaultsFromBits(%s.Bits()) = %v", fault, got)
+		}
+	}
+}
@@ -12,6 +12,16 @@ const (
 	StepTypeCooling   StepType = "cooling"
 	StepTypeAcclimate StepType = "acclimate"
 
+	// A heat treatment step holds the kiln until the wood has been at or
+	// above a temperature for a time, as phytosanitary rules such as
+	// ISPM-15 require. It ends only once the hold is met.
+	StepTypeHeatTreatment StepType = "heat_treatment"
+
+	// A confirm step holds the kiln in a safe state until an operator says
+	// the run may go on: to pull sample boards, turn the stack or top up the
+	// steam generator's reservoir.
+	StepTypeConfirm StepType = "confirm"
+
 	// Startup step types. The control unit synthesizes these in front of a
 	// program's own steps; a program may not name them.
 	StepTypeEqualize     StepType = "equalize"
@@ -19,6 +29,12 @@ const (
 
 	PowerSettingTypeSimple PowerSettingType = "simple"
 	PowerSettingTypeDelta  PowerSettingType = "delta"
+
+	// Climate power settings band on the kiln air rather than on a
+	// temperature, so they need a humidity sensor. Only the channels that act
+	// on the air - steam and the vent - may use them.
+	PowerSettingTypeHumidity PowerSettingType = "humidity"
+	PowerSettingTypeEMC      PowerSettingType = "emc"
 )
 
 type (
@@ -34,6 +50,18 @@ type (
 		MinDelta *float32         `json:"min_delta,omitempty"`
 		MaxDelta *float32         `json:"max_delta,omitempty"`
 		Power    *uint8           `json:"power,omitempty"`
+		// MinPower is the least a fan on a delta band runs at, with the kiln
+		// and the material converged; it scales up from there to full power
+		// across the band.
+		MinPower *uint8 `json:"min_power,omitempty"`
+
+		// A relative humidity band in percent, or an equilibrium moisture
+		// content band in percent of dry weight, for a channel that holds the
+		// kiln air inside it.
+		MinHumidity *float32 `json:"min_humidity,omitempty"`
+		MaxHumidity *float32 `json:"max_humidity,omitempty"`
+		MinEMC      *float32 `json:"min_emc,omitempty"`
+		MaxEMC      *float32 `json:"max_emc,omitempty"`
 	}
 
 	// EqualizeSettings configures the startup steps the control unit runs
@@ -48,11 +76,55 @@ type (
 	ProgramStep struct {
 		Name              string            `json:"name"`
 		StepType          StepType          `json:"type"`
-		TargetTemperature uint8             `json:"temperature_target,omitempty"`
+		TargetTemperature uint16            `json:"temperature_target,omitempty"`
 		Runtime           *StepDuration     `json:"runtime,omitempty"`
 		Heater            *PowerPidSettings `json:"heater,omitempty"`
 		Fan               *PowerPidSettings `json:"fan,omitempty"`
 		Steam             *PowerPidSettings `json:"steam,omitempty"`
+
+		// Vent opens the kiln to the room to let damp air out: the
+		// dehumidifying counterpart of steam. A step without one keeps it
+		// shut.
+		Vent *PowerPidSettings `json:"vent,omitempty"`
+
+		// TargetMoisture ends a heating or acclimate step once the sample
+		// boards' moisture content, in percent of dry weight, is down to
+		// it. A heating step still stops at its temperature target if it
+		// gets there first; an acclimate needs no runtime with one, and a
+		// runtime it has caps how long it waits for the wood to dry.
+		TargetMoisture *float32 `json:"moisture_target,omitempty"`
+
+		// Hold is what a heat treatment step must see the material do
+		// before it ends. Only heat treatment steps have one.
+		Hold *HeatTreatmentHold `json:"hold,omitempty"`
+
+		// Stability ends an acclimate once the material has settled at the
+		// target, within the rails of its runtime. Only acclimate steps
+		// have one.
+		Stability *AcclimateStability `json:"stability,omitempty"`
+
+		// MaxCoolingRate, in °C an hour, has a cooling step follow a
+		// descending ramp instead of running the fan and heater at constant
+		// power. The fan's and the heater's power are then the most the
+		// control unit uses of each. Only cooling steps have one.
+		MaxCoolingRate *float32 `json:"max_cooling_rate,omitempty"`
+
+		// Prompt tells the operator what a confirm step is waiting for them
+		// to do, and OnTimeout what becomes of the run when its runtime is
+		// over first. Only confirm steps have them.
+		Prompt    string               `json:"prompt,omitempty"`
+		OnTimeout ConfirmTimeoutAction `json:"on_timeout,omitempty"`
+
+		// Repeat and Include make the entry a block rather than a step,
+		// which ExpandSteps replaces with the steps it stands for: those of
+		// Repeat, Count times over, or those of the stored program Include
+		// names.
+		Repeat  *StepRepeat `json:"repeat,omitempty"`
+		Include string      `json:"include,omitempty"`
+
+		// bindings are the fields of a template step that are computed
+		// from its parameters, which Render fills in.
+		bindings *stepBindings
 	}
 
 	Program struct {
@@ -61,13 +133,34 @@ type (
 		// what charge it suits, why the steps are shaped the way they are. It
 		// is carried along with the program into a run and its history so the
 		// note is still there when the run is looked at later.
-		Description     string            `json:"description,omitempty"`
+		Description string `json:"description,omitempty"`
+		// Class sets which rules the program is held to. Left out, it is
+		// an ordinary drying program.
+		Class           ProgramClass      `json:"class,omitempty"`
 		Equalize        *EqualizeSettings `json:"equalize,omitempty"`
 		ProgramSteps    []ProgramStep     `json:"steps"`
 		DefaultsApplied bool              `json:"-"`
+
+		// Schedule is the alternative to authoring steps: a moisture-stage
+		// table that ApplyDefaults compiles into them.
+		Schedule    *DryingSchedule `json:"schedule,omitempty"`
+		scheduleErr error
+
+		// Parameters make the program a template, which Render turns into
+		// a program that can run; FromTemplate is then what it was
+		// rendered from.
+		Parameters   []ProgramParameter `json:"parameters,omitempty"`
+		FromTemplate *TemplateRun       `json:"from_template,omitempty"`
+
+		// StoredProgram is the stored program a run was started from by
+		// name, and what was overridden for it.
+		StoredProgram *StoredProgramRef `json:"stored_program,omitempty"`
+
 		// Captured from the defaults so Validate does not need them passed in.
-		maxTargetTemperature uint8
-		steamCeiling         uint8
+		maxTargetTemperature uint16
+		steamCeiling         uint16
+		fanInterlock         uint8
+		thermal              *ThermalModificationDefaults
 	}
 )
 
@@ -112,6 +205,8 @@ func isValidPercentage(value uint8) bool {
 
 func (p *PowerPidSettings) Validate(component string) error {
 	hasDeltas := p.MinDelta != nil || p.MaxDelta != nil
+	hasHumidity := p.MinHumidity != nil || p.MaxHumidity != nil
+	hasEMC := p.MinEMC != nil || p.MaxEMC != nil
 
 	controlMethods := 0
 	if p.Power != nil {
@@ -122,13 +217,27 @@ func (p *PowerPidSettings) Validate(component string) error {
 		controlMethods++
 		p.Type = PowerSettingTypeDelta
 	}
+	if hasHumidity {
+		controlMethods++
+		p.Type = PowerSettingTypeHumidity
+	}
+	if hasEMC {
+		controlMethods++
+		p.Type = PowerSettingTypeEMC
+	}
 	if controlMethods != 1 {
-		return errors.New(component + " must define exactly one control method: power or min/max deltas")
+		return errors.New(component + " must define exactly one control method: power, min/max deltas, min/max humidity or min/max emc")
 	}
 
 	if hasDeltas && (p.MinDelta == nil || p.MaxDelta == nil) {
 		return errors.New(component + " min and max delta must both be defined")
 	}
+	if hasHumidity && (p.MinHumidity == nil || p.MaxHumidity == nil) {
+		return errors.New(component + " min and max humidity must both be defined")
+	}
+	if hasEMC && (p.MinEMC == nil || p.MaxEMC == nil) {
+		return errors.New(component + " min and max emc must both be defined")
+	}
 
 	// A reversed or collapsed band leaves the controller with a bound it can
 	// never cross, which silently disables it rather than failing loudly.
@@ -139,35 +248,126 @@ func (p *PowerPidSettings) Validate(component string) error {
 	if p.Power != nil && !isValidPercentage(*p.Power) {
 		return errors.New(component + " power must be between 0 and 100")
 	}
+	if p.MinPower != nil && !hasDeltas {
+		return errors.New(component + " min power only applies to a delta band")
+	}
+	if p.MinPower != nil && !isValidPercentage(*p.MinPower) {
+		return errors.New(component + " min power must be between 0 and 100")
+	}
+
+	// The climate bands are hysteresis bands like the deltas: the channel
+	// switches at one edge and back at the other, so they must be ordered for
+	// the same reason.
+	if hasHumidity {
+		if *p.MinHumidity < 0 || *p.MaxHumidity > 100 {
+			return errors.New(component + " humidity must be between 0 and 100")
+		}
+		if *p.MinHumidity >= *p.MaxHumidity {
+			return errors.New(component + " min humidity must be below max humidity")
+		}
+	}
+	if hasEMC {
+		if *p.MinEMC <= 0 {
+			return errors.New(component + " min emc must be greater than zero")
+		}
+		if *p.MinEMC >= *p.MaxEMC {
+			return errors.New(component + " min emc must be below max emc")
+		}
+	}
 
 	return nil
 }
 
+// BandsOnClimate reports whether the setting holds the kiln air in a humidity
+// or EMC band rather than acting on temperature.
+func (p *PowerPidSettings) BandsOnClimate() bool {
+	return p != nil && (p.Type == PowerSettingTypeHumidity || p.Type == PowerSettingTypeEMC)
+}
+
+// climateBand returns the setting's humidity or EMC band.
+func (p *PowerPidSettings) climateBand() (float32, float32) {
+	if p.Type == PowerSettingTypeHumidity {
+		return *p.MinHumidity, *p.MaxHumidity
+	}
+	return *p.MinEMC, *p.MaxEMC
+}
+
+// isUnset reports whether the setting names no control method at all, which
+// is when ApplyDefaults may fill one in.
+func (p *PowerPidSettings) isUnset() bool {
+	return p.Power == nil && p.MinDelta == nil && p.MaxDelta == nil &&
+		p.MinHumidity == nil && p.MaxHumidity == nil && p.MinEMC == nil && p.MaxEMC == nil
+}
+
 // Validate checks a step in isolation. steamCeiling is the temperature above
 // which steam stops being able to heat the kiln, which the step needs to know
 // to decide whether it may hold steam at constant power.
-func (p *ProgramStep) Validate(steamCeiling uint8) error {
+func (p *ProgramStep) Validate(steamCeiling uint16) error {
+	return p.validate(steamCeiling, 0, false)
+}
+
+// validate is Validate for a step the program knows more about: fanInterlock
+// is the fan power the power unit's interlocks require, and blanketed says the
+// kiln can be above the steam blanket threshold during it, which keeps steam
+// on where the step would otherwise have to switch it off.
+func (p *ProgramStep) validate(steamCeiling uint16, fanInterlock uint8, blanketed bool) error {
 	// Validate fan - call validate first to capture any errors
 	fanErr := p.Fan.Validate("fan")
-	if p.Fan.Type != PowerSettingTypeSimple {
-		return errors.New("fan must use simple power control")
+	if p.Fan.Type != PowerSettingTypeSimple && p.Fan.Type != PowerSettingTypeDelta {
+		return errors.New("fan must use simple or delta power control")
 	}
 	if fanErr != nil {
 		return fanErr
 	}
+	if err := p.validateFanDelta(fanInterlock); err != nil {
+		return err
+	}
+
+	for _, other := range []struct {
+		name     string
+		settings *PowerPidSettings
+	}{{"heater", p.Heater}, {"steam", p.Steam}, {"vent", p.Vent}} {
+		if other.settings != nil && other.settings.MinPower != nil {
+			return errors.New(other.name + " cannot have a min power: only the fan scales on its delta band")
+		}
+	}
 
 	// Validate steam - this resolves Type, which the step validators restrict
 	if steamErr := p.Steam.Validate("steam"); steamErr != nil {
 		return steamErr
 	}
 
+	if err := p.validateVent(); err != nil {
+		return err
+	}
+
+	if err := p.validateTargetMoisture(); err != nil {
+		return err
+	}
+	if p.Hold != nil && p.StepType != StepTypeHeatTreatment {
+		return errors.New("only heat treatment steps can have a hold")
+	}
+	if p.Stability != nil && p.StepType != StepTypeAcclimate {
+		return errors.New("only acclimate steps can end on stability")
+	}
+	if p.MaxCoolingRate != nil && p.StepType != StepTypeCooling {
+		return errors.New("only cooling steps can have a max_cooling_rate")
+	}
+	if (p.Prompt != "" || p.OnTimeout != "") && p.StepType != StepTypeConfirm {
+		return errors.New("only confirm steps can have a prompt or an on_timeout")
+	}
+
 	switch p.StepType {
 	case StepTypeHeating:
 		return p.validateHeatingStep()
 	case StepTypeAcclimate:
 		return p.validateAcclimateStep(steamCeiling)
+	case StepTypeHeatTreatment:
+		return p.validateHeatTreatmentStep(steamCeiling)
 	case StepTypeCooling:
-		return p.validateCoolingStep()
+		return p.validateCoolingStep(blanketed)
+	case StepTypeConfirm:
+		return p.validateConfirmStep(blanketed)
 	case StepTypeEqualize, StepTypeSteamPrewarm:
 		return errors.New("equalize and steam_prewarm steps are created by the control unit and cannot be part of a program")
 	default:
@@ -193,9 +393,14 @@ func (p *ProgramStep) steamRunsOpenLoop() bool {
 // target, while an acclimate holds it within [target+min_delta,
 // target+max_delta] throughout. Either way the floor of the band is what the
 // next step can rely on - the top is a maximum, not a guarantee. Cooling always
-// runs steam off, so its exit temperature gates nothing.
+// runs steam off, so its exit temperature gates nothing. A confirm step waits
+// for as long as the operator takes, so the kiln may be back at room
+// temperature by the time it hands over.
 func (p *ProgramStep) kilnExitTemperature() float32 {
-	usesDeltaBand := p.StepType == StepTypeHeating || p.StepType == StepTypeAcclimate
+	if p.StepType == StepTypeConfirm {
+		return 0
+	}
+	usesDeltaBand := p.StepType == StepTypeHeating || p.StepType == StepTypeAcclimate || p.StepType == StepTypeHeatTreatment
 	if usesDeltaBand && p.Heater.Type == PowerSettingTypeDelta && p.Heater.MinDelta != nil {
 		return float32(p.TargetTemperature) + *p.Heater.MinDelta
 	}
@@ -206,10 +411,11 @@ func (p *ProgramStep) validateHeatingStep() error {
 	if p.Runtime != nil {
 		return errors.New("heating step cannot have runtime")
 	}
-	// Steam may be held constant or modulated against the delta; which of the
-	// two is allowed depends on the entry temperature, checked per program.
-	if p.Steam.Type != PowerSettingTypeSimple && p.Steam.Type != PowerSettingTypeDelta {
-		return errors.New("heating step steam must use simple or delta power control")
+	// Steam may be held constant, modulated against the delta or banded on
+	// the kiln air; whether constant is allowed depends on the entry
+	// temperature, checked per program.
+	if p.Steam.Type != PowerSettingTypeSimple && p.Steam.Type != PowerSettingTypeDelta && !p.Steam.BandsOnClimate() {
+		return errors.New("heating step steam must use simple, delta, humidity or emc power control")
 	}
 	if err := p.Heater.Validate("heater"); err != nil {
 		return err
@@ -222,23 +428,37 @@ func (p *ProgramStep) validateHeatingStep() error {
 	return nil
 }
 
-func (p *ProgramStep) validateAcclimateStep(steamCeiling uint8) error {
-	if p.Runtime == nil {
-		return errors.New("acclimate step must have runtime")
+func (p *ProgramStep) validateAcclimateStep(steamCeiling uint16) error {
+	if p.Runtime == nil && p.TargetMoisture == nil {
+		return errors.New("acclimate step must have runtime or a moisture target")
+	}
+	if p.Stability != nil {
+		if err := p.validateStability(); err != nil {
+			return err
+		}
 	}
-	if p.Steam.Type != PowerSettingTypeSimple {
-		return errors.New("acclimate step steam must use simple power control")
+	return p.validateHeldAtTarget("acclimate", steamCeiling)
+}
+
+// validateHeldAtTarget checks the power settings of a step that holds the
+// kiln at its target, an acclimate or a heat treatment. kind names the step in
+// the errors.
+func (p *ProgramStep) validateHeldAtTarget(kind string, steamCeiling uint16) error {
+	if p.Steam.Type != PowerSettingTypeSimple && !p.Steam.BandsOnClimate() {
+		return fmt.Errorf("%s step steam must use simple, humidity or emc power control", kind)
 	}
 	// An acclimate holds the kiln at its target, so a target below the ceiling
-	// leaves steam free to heat the kiln with nothing modulating it.
-	if p.TargetTemperature < steamCeiling && !p.steamIsOff() {
-		return fmt.Errorf("acclimate step below %d°C must switch steam off", steamCeiling)
+	// leaves constant steam free to heat the kiln with nothing modulating it.
+	// Steam banded on the air is modulated: it stops once the air is as damp
+	// as the step asks, which is what a drying schedule holds its steps at.
+	if p.TargetTemperature < steamCeiling && p.Steam.Type == PowerSettingTypeSimple && !p.steamIsOff() {
+		return fmt.Errorf("%s step below %d°C must switch steam off or band it on humidity or emc", kind, steamCeiling)
 	}
 	if err := p.Heater.Validate("heater"); err != nil {
 		return err
 	}
 	if p.Heater.Type != PowerSettingTypeDelta {
-		return errors.New("acclimate step heater must use delta power control")
+		return fmt.Errorf("%s step heater must use delta power control", kind)
 	}
 	// Unlike every other step type, an acclimate's deltas are offsets from the
 	// step target rather than from the material: the controller holds the kiln
@@ -248,15 +468,88 @@ func (p *ProgramStep) validateAcclimateStep(steamCeiling uint8) error {
 	// zero max_delta leaves the heater unable to drive the air above target,
 	// which is the only way it can pull the wood back up.
 	if *p.Heater.MinDelta >= 0 {
-		return errors.New("acclimate step heater min delta must be negative, the kiln floor below target")
+		return fmt.Errorf("%s step heater min delta must be negative, the kiln floor below target", kind)
 	}
 	if *p.Heater.MaxDelta <= 0 {
-		return errors.New("acclimate step heater max delta must be positive, the kiln ceiling above target")
+		return fmt.Errorf("%s step heater max delta must be positive, the kiln ceiling above target", kind)
 	}
 	return nil
 }
 
-func (p *ProgramStep) validateCoolingStep() error {
+// validateVent checks the vent, which may be held at a constant opening or
+// banded on the kiln air in any step. When steam and the vent both band on the
+// air, the steam band has to sit below the vent band: overlapping, the vent
+// would open to dry air the steam was still adding moisture to, and the two
+// would fight over it for the whole step.
+func (p *ProgramStep) validateVent() error {
+	if p.Vent == nil {
+		return nil
+	}
+	if err := p.Vent.Validate("vent"); err != nil {
+		return err
+	}
+	if p.Vent.Type != PowerSettingTypeSimple && !p.Vent.BandsOnClimate() {
+		return errors.New("vent must use simple, humidity or emc power control")
+	}
+	if !p.Steam.BandsOnClimate() || !p.Vent.BandsOnClimate() {
+		return nil
+	}
+	if p.Steam.Type != p.Vent.Type {
+		return errors.New("steam and vent must band on the same quantity, humidity or emc")
+	}
+	_, steamMax := p.Steam.climateBand()
+	ventMin, _ := p.Vent.climateBand()
+	if steamMax > ventMin {
+		return errors.New("steam band must end at or below where the vent band begins")
+	}
+	return nil
+}
+
+// validateFanDelta checks a fan run on the kiln/material gap, which is what
+// carries heat into the wood: min_power once the gap has closed to min_delta,
+// rising to full power as it opens to max_delta. A cooling step's kiln is
+// below the material, so there is no gap for it to follow.
+//
+// The gap closes just as the heater band fires the heater, so the fan's floor
+// is what the heater runs against. Below the fan power the power unit's
+// interlocks require, every command of that tick would be refused.
+func (p *ProgramStep) validateFanDelta(fanInterlock uint8) error {
+	if p.Fan.Type != PowerSettingTypeDelta {
+		return nil
+	}
+	if p.StepType == StepTypeCooling || p.StepType == StepTypeConfirm {
+		return fmt.Errorf("%s step fan must use simple power control", p.StepType)
+	}
+	if *p.Fan.MinDelta < 0 {
+		return errors.New("fan min delta must not be negative: the gap it follows is the kiln above the material")
+	}
+	var floor uint8
+	if p.Fan.MinPower != nil {
+		floor = *p.Fan.MinPower
+	}
+	if floor < fanInterlock {
+		return fmt.Errorf("fan min power %d%% is below the %d%% the power unit's interlocks require of the fan", floor, fanInterlock)
+	}
+	return nil
+}
+
+// validateTargetMoisture checks the moisture the step ends at. Only the steps
+// that hold the charge hot dry it; a cooling step waits for the charge to
+// cool, which it does whatever the wood's moisture.
+func (p *ProgramStep) validateTargetMoisture() error {
+	if p.TargetMoisture == nil {
+		return nil
+	}
+	if p.StepType != StepTypeHeating && p.StepType != StepTypeAcclimate {
+		return errors.New("only heating and acclimate steps can end on a moisture target")
+	}
+	if *p.TargetMoisture <= 0 || *p.TargetMoisture > MaxMoistureTarget {
+		return fmt.Errorf("moisture target must be above 0 and at most %d%%", MaxMoistureTarget)
+	}
+	return nil
+}
+
+func (p *ProgramStep) validateCoolingStep(blanketed bool) error {
 	// Runtime is optional for cooling steps - if specified, step progresses when
 	// either target temperature is reached OR runtime expires (whichever comes first)
 	// Validate heater - call validate first to capture any errors
@@ -268,14 +561,33 @@ func (p *ProgramStep) validateCoolingStep() error {
 		return heaterErr
 	}
 	// The kiln descends back through the ceiling during a cooling step, so any
-	// steam at all would start heating it again on the way down.
-	if !p.steamIsOff() {
+	// steam at all would start heating it again on the way down. Above the
+	// blanket threshold the kiln is far past the ceiling and the steam is
+	// what keeps the wood from burning, so there it stays on.
+	if !blanketed && !p.steamIsOff() {
 		return errors.New("cooling step must switch steam off")
 	}
+	if p.MaxCoolingRate != nil {
+		if *p.MaxCoolingRate <= 0 {
+			return errors.New("cooling step max_cooling_rate must be greater than zero")
+		}
+		// The fan is what does the cooling; the heater only holds the
+		// kiln back when the weather cools it faster than the ramp.
+		if p.Fan.Power == nil || *p.Fan.Power == 0 {
+			return errors.New("cooling step with a max_cooling_rate needs fan power to cool with")
+		}
+	}
 	return nil
 }
 
 func (p *Program) ApplyDefaults(defaults *Defaults) {
+	// The schedule is compiled first so that its steps take the defaults
+	// like any others. A schedule that does not compile is reported by
+	// Validate, where every other problem with a program is.
+	if p.Schedule != nil && !p.DefaultsApplied {
+		p.scheduleErr = p.compileSchedule()
+	}
+
 	for i := range p.ProgramSteps {
 		step := &p.ProgramSteps[i]
 
@@ -290,18 +602,33 @@ func (p *Program) ApplyDefaults(defaults *Defaults) {
 				band := defaults.Deltas[step.StepType]
 				step.Heater.MinDelta = &band.MinDelta
 				step.Heater.MaxDelta = &band.MaxDelta
-			case StepTypeCooling:
+			case StepTypeHeatTreatment:
+				// Held at its target the way an acclimate is, so it takes
+				// the acclimate band.
+				band := defaults.Deltas[StepTypeAcclimate]
+				step.Heater.MinDelta = &band.MinDelta
+				step.Heater.MaxDelta = &band.MaxDelta
+			case StepTypeCooling, StepTypeConfirm:
 				// A cooling step never drives the heater; it waits for the
-				// charge to fall, so there is nothing to configure.
+				// charge to fall, so there is nothing to configure. A
+				// confirm step waits on the operator, and the heater is off
+				// while it does unless the step says otherwise.
 				off := uint8(0)
 				step.Heater.Power = &off
 			}
 		}
 
+		if step.StepType == StepTypeHeatTreatment {
+			if step.Hold == nil {
+				step.Hold = &HeatTreatmentHold{}
+			}
+			step.Hold.applyDefaults()
+		}
+
 		if step.Fan == nil {
 			step.Fan = &PowerPidSettings{}
 		}
-		if step.Fan.Power == nil {
+		if step.Fan.Power == nil && step.Fan.MinDelta == nil && step.Fan.MaxDelta == nil {
 			step.Fan.Power = defaults.FanPower
 		}
 
@@ -311,10 +638,19 @@ func (p *Program) ApplyDefaults(defaults *Defaults) {
 		// Only fill in a constant power when the step named no control method
 		// at all; a step asking for delta steam must keep Power unset, or it
 		// ends up defining two methods and fails validation.
-		if step.Steam.Power == nil &&
-			step.Steam.MinDelta == nil && step.Steam.MaxDelta == nil {
+		if step.Steam.isUnset() {
 			step.Steam.Power = defaults.SteamPower
 		}
+
+		// The vent stays shut unless a step asks for it, so a kiln without
+		// one runs every program it did before.
+		if step.Vent == nil {
+			step.Vent = &PowerPidSettings{}
+		}
+		if step.Vent.isUnset() {
+			off := uint8(0)
+			step.Vent.Power = &off
+		}
 	}
 
 	if p.Equalize == nil {
@@ -329,6 +665,8 @@ func (p *Program) ApplyDefaults(defaults *Defaults) {
 
 	p.maxTargetTemperature = *defaults.MaxTargetTemperature
 	p.steamCeiling = *defaults.SteamCeiling
+	p.fanInterlock = defaults.FanInterlock
+	p.thermal = defaults.ThermalModification
 	p.DefaultsApplied = true
 }
 
@@ -351,22 +689,80 @@ func (p *Program) PrependStartupSteps() {
 	p.ProgramSteps = append(startup, p.ProgramSteps...)
 }
 
+// BandsOnClimate reports whether any step runs steam or the vent against the
+// kiln air, which needs a humidity sensor to read it.
+func (p *Program) BandsOnClimate() bool {
+	for i := range p.ProgramSteps {
+		if p.ProgramSteps[i].Steam.BandsOnClimate() || p.ProgramSteps[i].Vent.BandsOnClimate() {
+			return true
+		}
+	}
+	return false
+}
+
+// EndsOnMoisture reports whether any step ends on a moisture target, which
+// needs moisture pins to read it.
+func (p *Program) EndsOnMoisture() bool {
+	for i := range p.ProgramSteps {
+		if p.ProgramSteps[i].TargetMoisture != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// UsesVent reports whether any step ever opens the vent.
+func (p *Program) UsesVent() bool {
+	for i := range p.ProgramSteps {
+		vent := p.ProgramSteps[i].Vent
+		if vent != nil && !(vent.Type == PowerSettingTypeSimple && vent.Power != nil && *vent.Power == 0) {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Program) Validate() error {
 	if !p.DefaultsApplied {
 		return errors.New("defaults must be applied before validation")
 	}
 
+	if p.scheduleErr != nil {
+		return p.scheduleErr
+	}
+
+	if p.HasBlocks() {
+		return errUnexpandedBlocks
+	}
+
+	if p.IsTemplate() {
+		return errUnrenderedTemplate
+	}
+
 	if *p.Equalize.Delta <= 0 {
 		return errors.New("equalize delta must be greater than zero")
 	}
 
-	for _, step := range p.ProgramSteps {
-		err := step.Validate(p.steamCeiling)
+	if err := p.validateClass(); err != nil {
+		return err
+	}
+
+	if err := p.validateHeatTreatmentNames(); err != nil {
+		return err
+	}
+
+	blanketed := p.blanketedSteps()
+	for i, step := range p.ProgramSteps {
+		err := step.validate(p.steamCeiling, p.fanInterlock, blanketed[i])
 		if err != nil {
 			return err
 		}
 	}
 
+	if err := p.validateSteamBlanket(blanketed); err != nil {
+		return err
+	}
+
 	if err := p.validateSteamAgainstKilnTemperature(); err != nil {
 		return err
 	}
@@ -374,6 +770,27 @@ func (p *Program) Validate() error {
 	return p.validateStepOrderAndTemperatureProgression()
 }
 
+// validateHeatTreatmentNames requires every heat treatment step to have a
+// name no other step has, since the compliance report names the hold by it
+// and older execution logs tell steps apart by nothing else.
+func (p *Program) validateHeatTreatmentNames() error {
+	for i := range p.ProgramSteps {
+		step := &p.ProgramSteps[i]
+		if step.StepType != StepTypeHeatTreatment {
+			continue
+		}
+		if step.Name == "" {
+			return fmt.Errorf("heat treatment step %d must have a name", i+1)
+		}
+		for j := range p.ProgramSteps {
+			if j != i && p.ProgramSteps[j].Name == step.Name {
+				return fmt.Errorf("heat treatment step %q shares its name with step %d", step.Name, j+1)
+			}
+		}
+	}
+	return nil
+}
+
 // validateSteamAgainstKilnTemperature enforces where steam may run open-loop.
 // A heating step may hold steam at constant power only when the kiln is already
 // at or above the ceiling as the step begins, since below it steam outruns the
@@ -411,20 +828,27 @@ func (p *Program) validateStepOrderAndTemperatureProgression() error {
 		return errors.New("last step must be a cooling step")
 	}
 
-	for i := 0; i < len(p.ProgramSteps)-1; i++ {
-		currentStep := p.ProgramSteps[i]
-
-		if currentStep.TargetTemperature > p.maxTargetTemperature {
-			return fmt.Errorf("target temperature must not exceed %d degrees", p.maxTargetTemperature)
+	// A confirm step holds wherever the kiln happens to be, so it is passed
+	// over and the steps either side of it are held to each other.
+	currentStep := p.ProgramSteps[0]
+	for i := 1; i < len(p.ProgramSteps); i++ {
+		if limit := p.targetTemperatureLimit(); currentStep.TargetTemperature > limit {
+			return fmt.Errorf("target temperature must not exceed %d degrees", limit)
 		}
 
-		nextStep := p.ProgramSteps[i+1]
+		nextStep := p.ProgramSteps[i]
 
 		switch nextStep.StepType {
+		case StepTypeConfirm:
+			continue
 		case StepTypeHeating:
 			if nextStep.TargetTemperature <= currentStep.TargetTemperature {
 				return errors.New("heating step temperature must be higher than previous step")
 			}
+		case StepTypeHeatTreatment:
+			if nextStep.TargetTemperature < currentStep.TargetTemperature {
+				return errors.New("heat treatment step temperature must be greater than or equal to previous step")
+			}
 		case StepTypeAcclimate:
 			if currentStep.StepType == StepTypeHeating && nextStep.TargetTemperature != currentStep.TargetTemperature {
 				return errors.New("acclimate step temperature must match the preceding heating step temperature")
@@ -437,6 +861,7 @@ func (p *Program) validateStepOrderAndTemperatureProgression() error {
 				return errors.New("cooling step temperature must be lower than previous step")
 			}
 		}
+		currentStep = nextStep
 	}
 
 	return nil
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -187,7 +188,7 @@ func TestApplyDefaultsLeavesSteamDeltaControlIntact(t *testing.T) {
 	}
 }
 
-func steamHeatingStep(target uint8, steam *PowerPidSettings) ProgramStep {
+func steamHeatingStep(target uint16, steam *PowerPidSettings) ProgramStep {
 	return ProgramStep{
 		Name:              "heat",
 		StepType:          StepTypeHeating,
@@ -196,7 +197,7 @@ func steamHeatingStep(target uint8, steam *PowerPidSettings) ProgramStep {
 	}
 }
 
-func steamCoolingStep(target uint8, steam *PowerPidSettings) ProgramStep {
+func steamCoolingStep(target uint16, steam *PowerPidSettings) ProgramStep {
 	return ProgramStep{
 		Name:              "cool",
 		StepType:          StepTypeCooling,
@@ -206,7 +207,7 @@ func steamCoolingStep(target uint8, steam *PowerPidSettings) ProgramStep {
 	}
 }
 
-func steamAcclimateStep(target uint8, steam *PowerPidSettings) ProgramStep {
+func steamAcclimateStep(target uint16, steam *PowerPidSettings) ProgramStep {
 	return ProgramStep{
 		Name:              "hold",
 		StepType:          StepTypeAcclimate,
@@ -546,7 +547,7 @@ func TestTargetTemperatureCeilingComesFromDefaults(t *testing.T) {
 		t.Fatalf("Failed to read template config: %v", err)
 	}
 	defaults := config.ControlUnitConfig.Defaults
-	lowered := uint8(120)
+	lowered := uint16(120)
 	defaults.MaxTargetTemperature = &lowered
 
 	program := Program{ProgramSteps: []ProgramStep{
@@ -581,7 +582,7 @@ func TestSteamCeilingComesFromDefaults(t *testing.T) {
 		}}
 	}
 
-	high := uint8(100)
+	high := uint16(100)
 	defaults.SteamCeiling = &high
 	p := program()
 	p.ApplyDefaults(defaults)
@@ -589,7 +590,7 @@ func TestSteamCeilingComesFromDefaults(t *testing.T) {
 		t.Fatal("expected steam-on acclimate below the ceiling to be rejected")
 	}
 
-	low := uint8(80)
+	low := uint16(80)
 	defaults.SteamCeiling = &low
 	p = program()
 	p.ApplyDefaults(defaults)
@@ -767,3 +768,296 @@ func TestProgramWithoutDescriptionOmitsTheKey(t *testing.T) {
 		t.Errorf("marshaled program carries a description key: %s", encoded)
 	}
 }
+
+// steamEMC and ventEMC band the two air channels on equilibrium moisture
+// content, the steam band below the vent band as a drying schedule sets them.
+func steamEMC() *PowerPidSettings {
+	return &PowerPidSettings{MinEMC: f32(10), MaxEMC: f32(11)}
+}
+
+func ventEMC() *PowerPidSettings {
+	return &PowerPidSettings{MinEMC: f32(12), MaxEMC: f32(13)}
+}
+
+func TestClimateBandedSteamAndVent(t *testing.T) {
+	acclimate := func(target uint16, steam, vent *PowerPidSettings) ProgramStep {
+		step := acclimateStep(&PowerPidSettings{MinDelta: f32(-1), MaxDelta: f32(3)})
+		step.TargetTemperature = target
+		step.Steam = steam
+		step.Vent = vent
+		return step
+	}
+	cooling := func(vent *PowerPidSettings) ProgramStep {
+		step := steamCoolingStep(30, &PowerPidSettings{Power: u8(0)})
+		step.Fan = &PowerPidSettings{Power: u8(100)}
+		step.Vent = vent
+		return step
+	}
+
+	tests := []struct {
+		name    string
+		step    ProgramStep
+		wantErr string
+	}{
+		{"heating accepts emc steam", heatingStep(steamEMC()), ""},
+		{"heating accepts humidity steam", heatingStep(&PowerPidSettings{MinHumidity: f32(60), MaxHumidity: f32(70)}), ""},
+		{"acclimate below the ceiling accepts emc steam", acclimate(60, steamEMC(), ventEMC()), ""},
+		{"acclimate below the ceiling still rejects constant steam", acclimate(60, &PowerPidSettings{Power: u8(50)}, nil), "switch steam off"},
+		{"cooling rejects emc steam", func() ProgramStep { s := cooling(nil); s.Steam = steamEMC(); return s }(), "switch steam off"},
+		{"cooling accepts an emc vent", cooling(ventEMC()), ""},
+		{"cooling accepts a constant vent", cooling(&PowerPidSettings{Power: u8(30)}), ""},
+		{"vent rejects delta control", cooling(steamDelta()), "vent must use"},
+		{"heater rejects emc control", func() ProgramStep { s := heatingStep(nil); s.Heater = steamEMC(); return s }(), "delta power control"},
+		{"fan rejects emc control", func() ProgramStep { s := heatingStep(nil); s.Fan = steamEMC(); return s }(), "simple or delta power control"},
+		{"half a band", heatingStep(&PowerPidSettings{MinEMC: f32(10)}), "must both be defined"},
+		{"reversed emc band", heatingStep(&PowerPidSettings{MinEMC: f32(11), MaxEMC: f32(10)}), "below max emc"},
+		{"humidity above 100", heatingStep(&PowerPidSettings{MinHumidity: f32(90), MaxHumidity: f32(105)}), "between 0 and 100"},
+		{"two methods", heatingStep(&PowerPidSettings{Power: u8(10), MinEMC: f32(10), MaxEMC: f32(11)}), "exactly one"},
+		{"overlapping bands", acclimate(60, steamEMC(), &PowerPidSettings{MinEMC: f32(10.5), MaxEMC: f32(13)}), "at or below"},
+		{"mixed quantities", acclimate(60, steamEMC(), &PowerPidSettings{MinHumidity: f32(70), MaxHumidity: f32(80)}), "same quantity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.step.Steam == nil {
+				tt.step.Steam = &PowerPidSettings{Power: u8(0)}
+			}
+			err := tt.step.Validate(100)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected validation to pass, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// The vent defaults to shut, and a step that bands it on the air keeps its
+// band rather than having a constant power filled in beside it.
+func TestApplyDefaultsShutsTheVent(t *testing.T) {
+	program := validProgram()
+	program.ProgramSteps[1].Steam = steamEMC()
+	program.ProgramSteps[1].Vent = ventEMC()
+	program.ApplyDefaults(templateDefaults(t))
+
+	if err := program.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	for _, i := range []int{0, 2} {
+		vent := program.ProgramSteps[i].Vent
+		if vent == nil || vent.Power == nil || *vent.Power != 0 {
+			t.Errorf("step %d vent = %+v, want shut", i, vent)
+		}
+	}
+	if hold := program.ProgramSteps[1]; hold.Vent.Power != nil || hold.Steam.Power != nil {
+		t.Errorf("ApplyDefaults filled a power in beside the emc bands: steam %+v, vent %+v", hold.Steam, hold.Vent)
+	}
+	if !program.BandsOnClimate() || !program.UsesVent() {
+		t.Errorf("BandsOnClimate = %v, UsesVent = %v, want both true", program.BandsOnClimate(), program.UsesVent())
+	}
+
+	plain := validProgram()
+	plain.ApplyDefaults(templateDefaults(t))
+	if err := plain.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if plain.BandsOnClimate() || plain.UsesVent() {
+		t.Errorf("a program without climate control reports BandsOnClimate = %v, UsesVent = %v", plain.BandsOnClimate(), plain.UsesVent())
+	}
+}
+
+func TestMoistureTarget(t *testing.T) {
+	withTarget := func(step ProgramStep, target float32) ProgramStep {
+		step.TargetMoisture = &target
+		return step
+	}
+	untimed := func(step ProgramStep) ProgramStep {
+		step.Runtime = nil
+		return step
+	}
+	acclimate := acclimateStep(&PowerPidSettings{MinDelta: f32(-1), MaxDelta: f32(3)})
+	cooling := steamCoolingStep(30, &PowerPidSettings{Power: u8(0)})
+	cooling.Fan = &PowerPidSettings{Power: u8(100)}
+
+	tests := []struct {
+		name    string
+		step    ProgramStep
+		wantErr string
+	}{
+		{"heating may end on moisture", withTarget(heatingStep(steamDelta()), 12), ""},
+		{"acclimate may end on moisture alone", untimed(withTarget(acclimate, 12)), ""},
+		{"acclimate may cap it with a runtime", withTarget(acclimate, 12), ""},
+		{"acclimate needs one or the other", untimed(acclimate), "runtime or a moisture target"},
+		{"cooling cannot", withTarget(cooling, 12), "only heating and acclimate"},
+		{"zero", withTarget(heatingStep(steamDelta()), 0), "above 0"},
+		{"past fibre saturation", withTarget(heatingStep(steamDelta()), 35), "at most 30%"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.step.Validate(100)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected validation to pass, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEndsOnMoisture(t *testing.T) {
+	program := validProgram()
+	if program.EndsOnMoisture() {
+		t.Fatal("a program without moisture targets ends on moisture")
+	}
+	program.ProgramSteps[1].TargetMoisture = f32(12)
+	if !program.EndsOnMoisture() {
+		t.Fatal("a program with a moisture target does not end on moisture")
+	}
+}
+
+func TestMaxCoolingRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(*ProgramStep)
+		contains string
+	}{
+		{"fan and a little heater", func(*ProgramStep) {}, ""},
+		{"fan only", func(s *ProgramStep) { s.Heater.Power = u8(0) }, ""},
+		{"zero rate", func(s *ProgramStep) { s.MaxCoolingRate = f32(0) }, "greater than zero"},
+		{"no fan", func(s *ProgramStep) { s.Fan.Power = u8(0) }, "fan power to cool with"},
+		{"on an acclimate", func(s *ProgramStep) {
+			*s = acclimateStep(&PowerPidSettings{MinDelta: f32(-1), MaxDelta: f32(3)})
+			s.MaxCoolingRate = f32(5)
+		}, "only cooling steps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := steamCoolingStep(30, &PowerPidSettings{Power: u8(0)})
+			step.Heater.Power = u8(20)
+			step.Fan = &PowerPidSettings{Power: u8(100)}
+			step.MaxCoolingRate = f32(5)
+			tt.modify(&step)
+
+			err := step.Validate(100)
+			if tt.contains == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Fatalf("Validate = %v, want an error containing %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+func TestFanDeltaControl(t *testing.T) {
+	fanDelta := func(minDelta, maxDelta float32) *PowerPidSettings {
+		return &PowerPidSettings{MinDelta: f32(minDelta), MaxDelta: f32(maxDelta)}
+	}
+	tests := []struct {
+		name     string
+		step     ProgramStep
+		contains string
+	}{
+		{"on a heating step", func() ProgramStep { s := heatingStep(steamDelta()); s.Fan = fanDelta(2, 8); return s }(), ""},
+		{"on an acclimate", func() ProgramStep {
+			s := acclimateStep(&PowerPidSettings{MinDelta: f32(-1), MaxDelta: f32(3)})
+			s.Fan = fanDelta(0.5, 3)
+			return s
+		}(), ""},
+		{"reversed band", func() ProgramStep { s := heatingStep(steamDelta()); s.Fan = fanDelta(8, 2); return s }(), "min delta must be below max delta"},
+		{"kiln below the material", func() ProgramStep { s := heatingStep(steamDelta()); s.Fan = fanDelta(-2, 8); return s }(), "must not be negative"},
+		{"on a cooling step", func() ProgramStep {
+			s := steamCoolingStep(30, &PowerPidSettings{Power: u8(0)})
+			s.Fan = fanDelta(2, 8)
+			return s
+		}(), "cooling step fan must use simple"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.step.Validate(100)
+			if tt.contains == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Fatalf("Validate = %v, want an error containing %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+// The heater band fires as the gap closes, so a delta fan at its floor is what
+// the heater runs against, and the power unit refuses the heater below its
+// fan interlock.
+func TestFanDeltaFloorMeetsTheFanInterlock(t *testing.T) {
+	withFloor := func(floor *uint8) ProgramStep {
+		s := heatingStep(steamDelta())
+		s.Fan = &PowerPidSettings{MinDelta: f32(2), MaxDelta: f32(8), MinPower: floor}
+		return s
+	}
+	tests := []struct {
+		name     string
+		step     ProgramStep
+		contains string
+	}{
+		{"floor at the interlock", withFloor(u8(30)), ""},
+		{"floor above the interlock", withFloor(u8(50)), ""},
+		{"floor below the interlock", withFloor(u8(20)), "below the 30% the power unit's interlocks require"},
+		{"no floor", withFloor(nil), "fan min power 0% is below"},
+		{"floor above 100", withFloor(u8(120)), "min power must be between 0 and 100"},
+		{"floor without a band", func() ProgramStep {
+			s := heatingStep(steamDelta())
+			s.Fan = &PowerPidSettings{Power: u8(50), MinPower: u8(30)}
+			return s
+		}(), "min power only applies to a delta band"},
+		{"floor on the heater", func() ProgramStep {
+			s := withFloor(u8(30))
+			s.Heater.MinPower = u8(30)
+			return s
+		}(), "heater cannot have a min power"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.step.validate(100, 30, false)
+			if tt.contains == "" {
+				if err != nil {
+					t.Fatalf("validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Fatalf("validate = %v, want an error containing %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+func TestApplyDefaultsLeavesFanDeltaControlIntact(t *testing.T) {
+	program := validProgram()
+	program.ProgramSteps[0].Fan = &PowerPidSettings{MinDelta: f32(2), MaxDelta: f32(8)}
+	program.ApplyDefaults(templateDefaults(t))
+
+	if fan := program.ProgramSteps[0].Fan; fan.Power != nil {
+		t.Errorf("fan power = %d, want the delta band left alone", *fan.Power)
+	}
+	if err := program.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
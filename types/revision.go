@@ -0,0 +1,182 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// diffContext is how many unchanged lines a diff shows around a change.
+	diffContext = 3
+	// MaxDiffLines is the longest program, in lines of indented JSON, that
+	// DiffPrograms compares. The comparison takes memory in proportion to the
+	// product of the two lengths, and programs run to a few hundred lines.
+	MaxDiffLines = 2000
+)
+
+var (
+	ErrRevisionDoesNotExist = errors.New("revision does not exist")
+	// ErrProgramChanged is returned for a write that was made against a
+	// version of the stored program that is no longer the current one.
+	ErrProgramChanged = errors.New("stored program has changed since it was read")
+	// ErrIfMatchRequired is returned for a write that does not say which
+	// version of the stored program it replaces.
+	ErrIfMatchRequired = errors.New("If-Match is required: send the ETag the program was read with, or * to replace whatever is stored")
+	// ErrDiffTooLarge is returned for a diff of a program longer than
+	// MaxDiffLines.
+	ErrDiffTooLarge = fmt.Errorf("program is too long to compare (over %d lines)", MaxDiffLines)
+)
+
+type (
+	// ProgramRevision is one saved version of a stored program. Revisions
+	// count up from 1, the program as it was created; the newest is the
+	// Current one. Note is what whoever saved it said about the change.
+	ProgramRevision struct {
+		Revision int    `json:"revision"`
+		SavedAt  string `json:"saved_at"`
+		Note     string `json:"note,omitempty"`
+		Hash     string `json:"hash"`
+		Current  bool   `json:"current,omitempty"`
+	}
+
+	// ProgramDiff is a unified diff between two revisions of a program.
+	ProgramDiff struct {
+		From  int      `json:"from"`
+		To    int      `json:"to"`
+		Lines []string `json:"lines"`
+	}
+)
+
+// ProgramETag is the HTTP entity tag of a stored program with the content
+// hash. The hash is the one a run records and a revision lists, so a client
+// can tell which version it holds from any of them.
+func ProgramETag(hash string) string {
+	return `"` + hash + `"`
+}
+
+// IfMatches reports whether an If-Match header value lets a write go ahead
+// over the stored program with the hash. "*" is a client that means to replace
+// whatever is stored; otherwise one of the listed tags has to be the
+// program's. Weak tags are compared by their value, as a program has no other
+// representation. An empty header matches nothing.
+func IfMatches(header, hash string) bool {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if strings.Trim(tag, `"`) == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffPrograms compares two programs line by line in their indented JSON,
+// which is how an operator reads and edits them, and returns a unified diff:
+// removed lines start with "-", added ones with "+", and each change comes
+// with a few lines of context under an "@@" header. Equal programs give no
+// lines, and a program longer than MaxDiffLines gives ErrDiffTooLarge.
+func DiffPrograms(from, to *Program) ([]string, error) {
+	fromJSON, err := json.MarshalIndent(from, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	toJSON, err := json.MarshalIndent(to, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	fromLines, toLines := strings.Split(string(fromJSON), "\n"), strings.Split(string(toJSON), "\n")
+	if len(fromLines) > MaxDiffLines || len(toLines) > MaxDiffLines {
+		return nil, ErrDiffTooLarge
+	}
+	return diffLines(fromLines, toLines), nil
+}
+
+type diffOp struct {
+	kind byte // ' ', '-' or '+'
+	line string
+	a, b int // line numbers in from and to, from 1
+}
+
+func diffLines(a, b []string) []string {
+	// common[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:]. DiffPrograms keeps both under MaxDiffLines, so the
+	// table stays bounded.
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], i + 1, j + 1})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || common[i][j+1] >= common[i+1][j]):
+			ops = append(ops, diffOp{'+', b[j], i + 1, j + 1})
+			j++
+		default:
+			ops = append(ops, diffOp{'-', a[i], i + 1, j + 1})
+			i++
+		}
+	}
+
+	// Keep the changes and the context around them, and group what is
+	// kept into hunks wherever the context of two changes does not meet.
+	keep := make([]bool, len(ops))
+	for k, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		for c := max(0, k-diffContext); c <= min(len(ops)-1, k+diffContext); c++ {
+			keep[c] = true
+		}
+	}
+
+	var lines []string
+	for k := 0; k < len(ops); {
+		if !keep[k] {
+			k++
+			continue
+		}
+		end := k
+		for end < len(ops) && keep[end] {
+			end++
+		}
+		hunk := ops[k:end]
+		var fromCount, toCount int
+		for _, op := range hunk {
+			if op.kind != '+' {
+				fromCount++
+			}
+			if op.kind != '-' {
+				toCount++
+			}
+		}
+		lines = append(lines, fmt.Sprintf("@@ -%d,%d +%d,%d @@", hunk[0].a, fromCount, hunk[0].b, toCount))
+		for _, op := range hunk {
+			lines = append(lines, string(op.kind)+op.line)
+		}
+		k = end
+	}
+	return lines
+}
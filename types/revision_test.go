@@ -0,0 +1,88 @@
+package types
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestIfMatches(t *testing.T) {
+	const hash = "sha256:0123"
+
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{"", false},
+		{"*", true},
+		{`"sha256:0123"`, true},
+		{`W/"sha256:0123"`, true},
+		{`"sha256:4567", "sha256:0123"`, true},
+		{`"sha256:4567"`, false},
+	}
+	for _, tt := range tests {
+		if got := IfMatches(tt.header, hash); got != tt.want {
+			t.Errorf("IfMatches(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestDiffProgramsOfEqualPrograms(t *testing.T) {
+	from, to := validProgram(), validProgram()
+
+	lines, err := DiffPrograms(&from, &to)
+	if err != nil {
+		t.Fatalf("DiffPrograms: %v", err)
+	}
+	if len(lines) != 0 {
+		t.Errorf("diff = %q, want none", lines)
+	}
+}
+
+// Comparing takes memory in proportion to both lengths multiplied, so an
+// overlong program is refused rather than compared.
+func TestDiffProgramsRefusesOverlongPrograms(t *testing.T) {
+	from, to := validProgram(), validProgram()
+	// Every step takes at least a line.
+	for len(to.ProgramSteps) <= MaxDiffLines {
+		to.ProgramSteps = append(to.ProgramSteps, to.ProgramSteps...)
+	}
+
+	if _, err := DiffPrograms(&from, &to); !errors.Is(err, ErrDiffTooLarge) {
+		t.Fatalf("DiffPrograms = %v, want ErrDiffTooLarge", err)
+	}
+}
+
+func TestDiffPrograms(t *testing.T) {
+	from, to := validProgram(), validProgram()
+	from.ProgramName, to.ProgramName = "Oak", "Oak"
+	to.ProgramSteps[2].TargetTemperature = 25
+
+	lines, err := DiffPrograms(&from, &to)
+	if err != nil {
+		t.Fatalf("DiffPrograms: %v", err)
+	}
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "@@ -") {
+		t.Fatalf("diff = %q, want a hunk", lines)
+	}
+
+	var removed, added []string
+	for _, line := range lines[1:] {
+		switch line[0] {
+		case '-':
+			removed = append(removed, strings.TrimSpace(line[1:]))
+		case '+':
+			added = append(added, strings.TrimSpace(line[1:]))
+		}
+	}
+	if len(removed) != 1 || removed[0] != `"temperature_target": 30,` {
+		t.Errorf("removed = %q, want the old target", removed)
+	}
+	if len(added) != 1 || added[0] != `"temperature_target": 25,` {
+		t.Errorf("added = %q, want the new target", added)
+	}
+	// Three lines of context either side of the one changed line.
+	if len(lines) != 1+3+2+3 {
+		t.Errorf("diff has %d lines after its header, want 8: %q", len(lines)-1, lines)
+	}
+}
@@ -0,0 +1,279 @@
+package types
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultScheduleEMCBand is how far, in percent of dry weight, the steam and
+// vent bands of a schedule row reach from its EMC when the schedule does not
+// say.
+const DefaultScheduleEMCBand = 1
+
+type (
+	// DryingSchedule is a drying schedule in the form the published ones take:
+	// a table of rows, each holding the kiln at a dry bulb and an air
+	// condition until the charge has dried to the row's moisture content,
+	// the way the USDA T/M schedules are printed. A program carries either a
+	// schedule or steps; ApplyDefaults compiles the schedule into steps, so
+	// the control unit, the execution log and the history see the ordinary
+	// steps it stands for.
+	//
+	// EMCBand is how far the steam band reaches below each row's EMC and, with
+	// Vent set, the vent band above it. A kiln without a vent leaves Vent off
+	// and lets the air dry on its own through the leaks. CoolTo is the
+	// temperature the final cooling step brings the charge down to.
+	DryingSchedule struct {
+		EMCBand *float32      `json:"emc_band,omitempty"`
+		Vent    bool          `json:"vent,omitempty"`
+		CoolTo  uint16        `json:"cool_to"`
+		Rows    []ScheduleRow `json:"rows"`
+	}
+
+	// ScheduleRow holds the kiln at Temperature until the sample boards are
+	// down to UntilMoisture. The air is given either as a wet bulb
+	// temperature, as the schedules print it, or directly as an EMC.
+	ScheduleRow struct {
+		UntilMoisture float32  `json:"until_moisture"`
+		Temperature   uint16   `json:"temperature"`
+		WetBulb       *float32 `json:"wet_bulb,omitempty"`
+		EMC           *float32 `json:"emc,omitempty"`
+	}
+)
+
+// emc returns the equilibrium moisture content the row holds the air at.
+func (r *ScheduleRow) emc() float32 {
+	if r.EMC != nil {
+		return *r.EMC
+	}
+	return EquilibriumMoistureContent(float32(r.Temperature), RelativeHumidity(float32(r.Temperature), *r.WetBulb))
+}
+
+func (s *DryingSchedule) band() float32 {
+	if s.EMCBand == nil {
+		return DefaultScheduleEMCBand
+	}
+	return *s.EMCBand
+}
+
+// validate checks what the steps compiled from the schedule cannot say as
+// clearly: that its rows are a schedule at all. Everything the steps do say -
+// the temperature ceiling, the steam rules, the cooling target - is left to
+// the program's own validation, which the compiled steps go through the same
+// as authored ones.
+func (s *DryingSchedule) validate() error {
+	if len(s.Rows) == 0 {
+		return errors.New("schedule must have at least one row")
+	}
+	if s.band() <= 0 {
+		return errors.New("schedule emc_band must be greater than zero")
+	}
+
+	for i := range s.Rows {
+		row := &s.Rows[i]
+		n := i + 1
+
+		// Above fibre saturation the pins read little more than "wet", so
+		// a row that changes over up there would change at random. The
+		// published schedules' first rows do; they become one row ending
+		// at the fibre saturation point.
+		if row.UntilMoisture <= 0 || row.UntilMoisture > MaxMoistureTarget {
+			return fmt.Errorf("schedule row %d ends at %.1f%% moisture; rows must end above 0 and at most %d%%, merge the wetter rows into one ending there",
+				n, row.UntilMoisture, MaxMoistureTarget)
+		}
+		if (row.WetBulb == nil) == (row.EMC == nil) {
+			return fmt.Errorf("schedule row %d must give either wet_bulb or emc", n)
+		}
+		if row.WetBulb != nil && *row.WetBulb >= float32(row.Temperature) {
+			return fmt.Errorf("schedule row %d wet bulb must be below its %d°C dry bulb", n, row.Temperature)
+		}
+		// The steam band reaches down from the row's EMC, and an EMC band
+		// must stay above zero.
+		if row.emc() <= s.band() {
+			return fmt.Errorf("schedule row %d holds the air at %.1f%% EMC, which must be above the %.1f%% emc_band",
+				n, row.emc(), s.band())
+		}
+
+		if i == 0 {
+			continue
+		}
+		previous := &s.Rows[i-1]
+		if row.UntilMoisture >= previous.UntilMoisture {
+			return fmt.Errorf("schedule row %d must end drier than row %d", n, i)
+		}
+		if row.Temperature < previous.Temperature {
+			return fmt.Errorf("schedule row %d is cooler than row %d; a schedule may only hold or raise the temperature", n, i)
+		}
+	}
+	return nil
+}
+
+// steps compiles the schedule. Each row is an acclimate at its temperature
+// ending on its moisture; a row warmer than the one before it is reached by
+// a heating step first, which ends on the same moisture so that wood drying
+// faster than the kiln warms does not wait for it. The air is held in the
+// row's band throughout, and a cooling step with steam off brings the charge
+// down at the end.
+func (s *DryingSchedule) steps() []ProgramStep {
+	band := s.band()
+	steps := make([]ProgramStep, 0, 2*len(s.Rows)+1)
+
+	for i := range s.Rows {
+		row := &s.Rows[i]
+		emc := row.emc()
+		until := row.UntilMoisture
+
+		air := func() (*PowerPidSettings, *PowerPidSettings) {
+			steam := &PowerPidSettings{MinEMC: float32Ptr(emc - band), MaxEMC: float32Ptr(emc)}
+			if !s.Vent {
+				return steam, nil
+			}
+			return steam, &PowerPidSettings{MinEMC: float32Ptr(emc), MaxEMC: float32Ptr(emc + band)}
+		}
+
+		if i == 0 || row.Temperature > s.Rows[i-1].Temperature {
+			steam, vent := air()
+			steps = append(steps, ProgramStep{
+				Name:              fmt.Sprintf("Row %d: heat to %d°C", i+1, row.Temperature),
+				StepType:          StepTypeHeating,
+				TargetTemperature: row.Temperature,
+				Steam:             steam,
+				Vent:              vent,
+				TargetMoisture:    float32Ptr(until),
+			})
+		}
+
+		steam, vent := air()
+		steps = append(steps, ProgramStep{
+			Name:              fmt.Sprintf("Row %d: %d°C at %.1f%% EMC until %.1f%%", i+1, row.Temperature, emc, until),
+			StepType:          StepTypeAcclimate,
+			TargetTemperature: row.Temperature,
+			Steam:             steam,
+			Vent:              vent,
+			TargetMoisture:    float32Ptr(until),
+		})
+	}
+
+	off := uint8(0)
+	return append(steps, ProgramStep{
+		Name:              fmt.Sprintf("Cool to %d°C", s.CoolTo),
+		StepType:          StepTypeCooling,
+		TargetTemperature: s.CoolTo,
+		Steam:             &PowerPidSettings{Power: &off},
+	})
+}
+
+func float32Ptr(v float32) *float32 { return &v }
+
+// compileSchedule replaces the program's schedule with its steps. A
+// program with both would have the schedule silently override what was
+// authored, so that is refused instead. The schedule is dropped once
+// compiled, the way includes are once expanded, so the program that runs
+// and is echoed back can be sent again as it is.
+func (p *Program) compileSchedule() error {
+	if len(p.ProgramSteps) > 0 {
+		return errors.New("program must have either steps or a schedule, not both")
+	}
+	if err := p.Schedule.validate(); err != nil {
+		return err
+	}
+	p.ProgramSteps = p.Schedule.steps()
+	p.Schedule = nil
+	return nil
+}
+
+// ParseScheduleCSV reads a schedule table. The first line names the columns:
+// until_moisture and temperature, and one of wet_bulb, wet_bulb_depression or
+// emc for the air. A depression, the dry bulb less the wet bulb, is how the
+// USDA tables print it. Column order is free and blank lines are skipped; the
+// settings that are not per row - the band, the vent and the cooling target -
+// are left for the caller to fill in.
+func ParseScheduleCSV(r io.Reader) (*DryingSchedule, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.Comment = '#'
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading schedule header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"until_moisture", "temperature"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("schedule header must have a %s column", required)
+		}
+	}
+	air := ""
+	for _, name := range []string{"wet_bulb", "wet_bulb_depression", "emc"} {
+		if _, ok := columns[name]; !ok {
+			continue
+		}
+		if air != "" {
+			return nil, fmt.Errorf("schedule header has both %s and %s; give the air one way", air, name)
+		}
+		air = name
+	}
+	if air == "" {
+		return nil, errors.New("schedule header must have a wet_bulb, wet_bulb_depression or emc column")
+	}
+
+	schedule := &DryingSchedule{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading schedule: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) (float64, error) {
+			value, err := strconv.ParseFloat(strings.TrimSpace(record[columns[name]]), 32)
+			if err != nil {
+				return 0, fmt.Errorf("schedule line %d: %s is not a number", line, name)
+			}
+			return value, nil
+		}
+
+		until, err := field("until_moisture")
+		if err != nil {
+			return nil, err
+		}
+		temperature, err := field("temperature")
+		if err != nil {
+			return nil, err
+		}
+		if temperature < 0 || temperature > math.MaxUint16 || temperature != float64(uint16(temperature)) {
+			return nil, fmt.Errorf("schedule line %d: temperature must be a whole number of degrees", line)
+		}
+		value, err := field(air)
+		if err != nil {
+			return nil, err
+		}
+
+		row := ScheduleRow{UntilMoisture: float32(until), Temperature: uint16(temperature)}
+		switch air {
+		case "wet_bulb":
+			row.WetBulb = float32Ptr(float32(value))
+		case "wet_bulb_depression":
+			row.WetBulb = float32Ptr(float32(temperature - value))
+		case "emc":
+			row.EMC = float32Ptr(float32(value))
+		}
+		schedule.Rows = append(schedule.Rows, row)
+	}
+
+	if len(schedule.Rows) == 0 {
+		return nil, errors.New("schedule has no rows")
+	}
+	return schedule, nil
+}
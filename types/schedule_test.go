@@ -0,0 +1,199 @@
+package types
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// scheduleProgram is a short hardwood schedule: two rows at 50°C, then one
+// warmer to finish.
+func scheduleProgram() Program {
+	return Program{ProgramName: "Oak 4/4", Schedule: &DryingSchedule{
+		CoolTo: 30,
+		Rows: []ScheduleRow{
+			{UntilMoisture: 30, Temperature: 50, WetBulb: f32(47)},
+			{UntilMoisture: 20, Temperature: 50, WetBulb: f32(44)},
+			{UntilMoisture: 8, Temperature: 65, EMC: f32(5)},
+		},
+	}}
+}
+
+func TestScheduleCompilesIntoSteps(t *testing.T) {
+	program := scheduleProgram()
+	program.ApplyDefaults(templateDefaults(t))
+	if err := program.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	want := []struct {
+		stepType    StepType
+		temperature uint16
+		moisture    float32
+	}{
+		{StepTypeHeating, 50, 30},
+		{StepTypeAcclimate, 50, 30},
+		{StepTypeAcclimate, 50, 20},
+		{StepTypeHeating, 65, 8},
+		{StepTypeAcclimate, 65, 8},
+		{StepTypeCooling, 30, 0},
+	}
+	if len(program.ProgramSteps) != len(want) {
+		t.Fatalf("compiled %d steps, want %d", len(program.ProgramSteps), len(want))
+	}
+	for i, w := range want {
+		step := program.ProgramSteps[i]
+		if step.StepType != w.stepType || step.TargetTemperature != w.temperature {
+			t.Errorf("step %d is %s to %d°C, want %s to %d°C", i+1, step.StepType, step.TargetTemperature, w.stepType, w.temperature)
+		}
+		if w.moisture == 0 {
+			if step.TargetMoisture != nil {
+				t.Errorf("step %d ends on moisture %v, want none", i+1, *step.TargetMoisture)
+			}
+			continue
+		}
+		if step.TargetMoisture == nil || *step.TargetMoisture != w.moisture {
+			t.Errorf("step %d moisture target = %v, want %v", i+1, step.TargetMoisture, w.moisture)
+		}
+	}
+
+	// The last row gives its EMC directly; the steam band reaches down from
+	// it, and without a vent the vent stays shut.
+	last := program.ProgramSteps[4]
+	if *last.Steam.MinEMC != 4 || *last.Steam.MaxEMC != 5 {
+		t.Errorf("steam band = [%v, %v], want [4, 5]", *last.Steam.MinEMC, *last.Steam.MaxEMC)
+	}
+	if program.UsesVent() {
+		t.Error("a schedule without vent opens the vent")
+	}
+	if !program.BandsOnClimate() || !program.EndsOnMoisture() {
+		t.Error("a compiled schedule should band on the air and end on moisture")
+	}
+}
+
+func TestScheduleWetBulbBecomesEMC(t *testing.T) {
+	program := scheduleProgram()
+	program.Schedule.Vent = true
+	program.Schedule.EMCBand = f32(0.5)
+	program.ApplyDefaults(templateDefaults(t))
+	if err := program.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	want := EquilibriumMoistureContent(50, RelativeHumidity(50, 47))
+	first := program.ProgramSteps[0]
+	if !within(*first.Steam.MaxEMC, want, 0.001) || !within(*first.Steam.MinEMC, want-0.5, 0.001) {
+		t.Errorf("steam band = [%v, %v], want up to %v", *first.Steam.MinEMC, *first.Steam.MaxEMC, want)
+	}
+	if !within(*first.Vent.MinEMC, want, 0.001) || !within(*first.Vent.MaxEMC, want+0.5, 0.001) {
+		t.Errorf("vent band = [%v, %v], want from %v", *first.Vent.MinEMC, *first.Vent.MaxEMC, want)
+	}
+}
+
+func TestScheduleValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(p *Program)
+		wantErr string
+	}{
+		{"steps and a schedule", func(p *Program) { p.ProgramSteps = validProgram().ProgramSteps }, "not both"},
+		{"no rows", func(p *Program) { p.Schedule.Rows = nil }, "at least one row"},
+		{"zero band", func(p *Program) { p.Schedule.EMCBand = f32(0) }, "emc_band"},
+		{"past fibre saturation", func(p *Program) { p.Schedule.Rows[0].UntilMoisture = 50 }, "merge the wetter rows"},
+		{"no air", func(p *Program) { p.Schedule.Rows[1].WetBulb = nil }, "either wet_bulb or emc"},
+		{"both airs", func(p *Program) { p.Schedule.Rows[2].WetBulb = f32(50) }, "either wet_bulb or emc"},
+		{"wet bulb above dry bulb", func(p *Program) { p.Schedule.Rows[0].WetBulb = f32(52) }, "below its 50°C dry bulb"},
+		{"EMC inside the band", func(p *Program) { p.Schedule.Rows[2].EMC = f32(0.8) }, "above the 1.0% emc_band"},
+		{"wetter than the row before", func(p *Program) { p.Schedule.Rows[2].UntilMoisture = 25 }, "row 3 must end drier than row 2"},
+		{"cooler than the row before", func(p *Program) { p.Schedule.Rows[2].Temperature = 45 }, "cooler than row 2"},
+		// What the compiled steps say is left to the step validation.
+		{"too hot", func(p *Program) { p.Schedule.Rows[2].Temperature = 250 }, "must not exceed"},
+		{"cooling not below the last row", func(p *Program) { p.Schedule.CoolTo = 70 }, "cooling step temperature"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := scheduleProgram()
+			tt.modify(&program)
+			program.ApplyDefaults(templateDefaults(t))
+			err := program.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// The compiled program is what the run answers with and goes into the
+// history, so it has to be a program that can be sent back to start again.
+func TestCompiledScheduleSurvivesTheJSONRoundTrip(t *testing.T) {
+	program := scheduleProgram()
+	program.ApplyDefaults(templateDefaults(t))
+	if err := program.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if program.Schedule != nil {
+		t.Error("the schedule is kept alongside the steps compiled from it")
+	}
+	encoded, err := json.Marshal(program)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var decoded Program
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	decoded.ApplyDefaults(templateDefaults(t))
+	if err := decoded.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if len(decoded.ProgramSteps) != len(program.ProgramSteps) {
+		t.Errorf("sent back, the program has %d steps, want %d", len(decoded.ProgramSteps), len(program.ProgramSteps))
+	}
+}
+
+func TestParseScheduleCSV(t *testing.T) {
+	table := `# USDA T4-D2, 4/4 oak, from 30% down
+until_moisture, temperature, wet_bulb_depression
+30, 50, 3
+
+20, 50, 6
+8, 65, 22
+`
+	schedule, err := ParseScheduleCSV(strings.NewReader(table))
+	if err != nil {
+		t.Fatalf("ParseScheduleCSV: %v", err)
+	}
+	if len(schedule.Rows) != 3 {
+		t.Fatalf("parsed %d rows, want 3", len(schedule.Rows))
+	}
+	last := schedule.Rows[2]
+	if last.UntilMoisture != 8 || last.Temperature != 65 || last.WetBulb == nil || *last.WetBulb != 43 {
+		t.Errorf("last row = %+v, want until 8 at 65°C with a 43°C wet bulb", last)
+	}
+}
+
+func TestParseScheduleCSVRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		table   string
+		wantErr string
+	}{
+		{"empty", ``, "header"},
+		{"no temperature", "until_moisture,emc\n20,8\n", "temperature column"},
+		{"no air", "until_moisture,temperature\n20,50\n", "wet_bulb, wet_bulb_depression or emc"},
+		{"two airs", "until_moisture,temperature,emc,wet_bulb\n20,50,8,45\n", "give the air one way"},
+		{"no rows", "until_moisture,temperature,emc\n", "no rows"},
+		{"not a number", "until_moisture,temperature,emc\n20,50,8\nfifteen,55,6\n", "line 3: until_moisture"},
+		{"fractional temperature", "until_moisture,temperature,emc\n20,50.5,8\n", "whole number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseScheduleCSV(strings.NewReader(tt.table))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package types
+
+import (
+	"errors"
+	"time"
+)
+
+// AcclimateStability ends an acclimate once the core of the wood has settled
+// rather than after a fixed time: the material within Within °C of the target
+// and changing by less than MaxRate °C an hour, both for Hold. The step's
+// runtime is the most it waits for that; MinRuntime, if given, is the least it
+// runs however settled the material looks, for a core probe that reads the
+// outer wood too soon.
+type AcclimateStability struct {
+	Within     float32       `json:"within"`
+	MaxRate    float32       `json:"max_rate"`
+	Hold       *StepDuration `json:"hold"`
+	MinRuntime *StepDuration `json:"min_runtime,omitempty"`
+}
+
+func (p *ProgramStep) validateStability() error {
+	s := p.Stability
+	if s.Within <= 0 {
+		return errors.New("acclimate stability within must be greater than zero")
+	}
+	if s.MaxRate <= 0 {
+		return errors.New("acclimate stability max_rate must be greater than zero")
+	}
+	if s.Hold == nil || s.Hold.Duration < time.Second {
+		return errors.New("acclimate stability hold must be at least a second")
+	}
+	// Without a cap a probe that never settles would hold the kiln at
+	// temperature for ever.
+	if p.Runtime == nil {
+		return errors.New("acclimate step ending on stability must have a runtime to cap it")
+	}
+	if s.Hold.Duration > p.Runtime.Duration {
+		return errors.New("acclimate stability hold must fit within the step's runtime")
+	}
+	if s.MinRuntime != nil && (s.MinRuntime.Duration < 0 || s.MinRuntime.Duration >= p.Runtime.Duration) {
+		return errors.New("acclimate stability min_runtime must be below the step's runtime")
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package types
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcclimateStability(t *testing.T) {
+	minutes := func(n int) *StepDuration { return &StepDuration{time.Duration(n) * time.Minute} }
+	settled := func() *AcclimateStability {
+		return &AcclimateStability{Within: 1, MaxRate: 0.5, Hold: minutes(20), MinRuntime: minutes(30)}
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(*ProgramStep)
+		contains string
+	}{
+		{"valid", func(*ProgramStep) {}, ""},
+		{"without a minimum", func(s *ProgramStep) { s.Stability.MinRuntime = nil }, ""},
+		{"no band", func(s *ProgramStep) { s.Stability.Within = 0 }, "within"},
+		{"no rate", func(s *ProgramStep) { s.Stability.MaxRate = 0 }, "max_rate"},
+		{"no hold", func(s *ProgramStep) { s.Stability.Hold = nil }, "hold"},
+		{"no cap", func(s *ProgramStep) { s.Runtime = nil; s.TargetMoisture = f32(12) }, "runtime to cap it"},
+		{"hold past the cap", func(s *ProgramStep) { s.Stability.Hold = minutes(90) }, "fit within"},
+		{"minimum past the cap", func(s *ProgramStep) { s.Stability.MinRuntime = minutes(60) }, "min_runtime"},
+		{"on a heating step", func(s *ProgramStep) { s.StepType = StepTypeHeating; s.Runtime = nil }, "only acclimate steps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := acclimateStep(&PowerPidSettings{MinDelta: f32(-1), MaxDelta: f32(3)})
+			step.Stability = settled()
+			tt.modify(&step)
+
+			err := step.Validate(100)
+			if tt.contains == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Fatalf("Validate = %v, want an error containing %q", err, tt.contains)
+			}
+		})
+	}
+}
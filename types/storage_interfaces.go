@@ -9,6 +9,12 @@ type ExecutionStorage interface {
 	LoadState(programName string) (ProgramState, int64, error)
 	GetLogPath(programName string) (string, error)
 	GetRunningLogPath(programName string) (string, error)
+	LoadRunEvents(programName string) ([]RunEvent, error)
+	LoadComplianceReport(programName string) (*ComplianceReport, error)
+
+	// Integrity: runs whose files are not all where they belong
+	CheckStorage(activeRun string) ([]StorageIssue, error)
+	RepairStorage() ([]StorageIssue, error)
 
 	// System resource operations
 	GetAvailableSpaceMB() int64
@@ -20,6 +26,14 @@ type ProgramStorage interface {
 	ListStoredProgramsWithInfo() ([]StoredProgramInfo, error)
 	LoadStoredProgram(programName string) (*Program, error)
 	CreateStoredProgram(programName string, program *Program) error
-	UpdateStoredProgram(programName string, program *Program) error
-	DeleteStoredProgram(programName string) error
+	// ifMatch is the If-Match of the request making the write: a program
+	// that no longer matches it gives ErrProgramChanged, and an empty one
+	// ErrIfMatchRequired
+	UpdateStoredProgram(programName string, program *Program, note, ifMatch string) error
+	DeleteStoredProgram(programName, ifMatch string) error
+
+	// Revision history: every update keeps the version it replaces
+	ListProgramRevisions(programName string) ([]ProgramRevision, error)
+	LoadProgramRevision(programName string, revision int) (*Program, error)
+	RestoreProgramRevision(programName string, revision int, note, ifMatch string) (*Program, error)
 }
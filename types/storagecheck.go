@@ -0,0 +1,50 @@
+package types
+
+import "errors"
+
+var ErrRepairWhileRunning = errors.New("storage cannot be repaired while a program is running")
+
+type (
+	// StorageIssueKind says what is wrong with a run's files.
+	StorageIssueKind string
+
+	// StorageRepair is what repairing a StorageIssue does.
+	StorageRepair string
+
+	// StorageIssue is one file of a run that is not where the control unit
+	// expects it. A run is its program in history/ with a state, and a log,
+	// events and compliance report beside them; a move to history cut short,
+	// or a file deleted by hand, leaves the rest stranded or orphaned.
+	StorageIssue struct {
+		Run    string           `json:"run"`
+		Kind   StorageIssueKind `json:"kind"`
+		Path   string           `json:"path"`
+		Repair StorageRepair    `json:"repair"`
+		// Repaired and RepairError are only set on the issues a repair
+		// returns.
+		Repaired    bool   `json:"repaired,omitempty"`
+		RepairError string `json:"repair_error,omitempty"`
+	}
+)
+
+// StorageIssueKind values
+const (
+	// StorageIssueStranded is a file left in running/ by a run that is no
+	// longer running.
+	StorageIssueStranded StorageIssueKind = "stranded"
+	// StorageIssueNoState is a run's program without a state file.
+	StorageIssueNoState StorageIssueKind = "no_state"
+	// StorageIssueOrphaned is a state, log, events or compliance file of a
+	// run whose program is gone.
+	StorageIssueOrphaned StorageIssueKind = "orphaned"
+)
+
+// StorageRepair values
+const (
+	// StorageRepairRelink moves the file to where it belongs in history/.
+	StorageRepairRelink StorageRepair = "relink"
+	// StorageRepairMarkUnknown writes the run's state as unknown.
+	StorageRepairMarkUnknown StorageRepair = "mark_unknown"
+	// StorageRepairArchive moves the file to archive/, under the path it had.
+	StorageRepairArchive StorageRepair = "archive"
+)
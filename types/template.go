@@ -0,0 +1,617 @@
+package types
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	ParameterTypeNumber   ParameterType = "number"
+	ParameterTypeDuration ParameterType = "duration"
+)
+
+type (
+	ParameterType string
+
+	// ProgramParameter declares a value a template leaves to whoever starts
+	// it: the board thickness, the final temperature. Default is used when
+	// none is given, and Min and Max bound what may be; all three are in the
+	// parameter's type.
+	ProgramParameter struct {
+		Name        string          `json:"name"`
+		Type        ParameterType   `json:"type"`
+		Description string          `json:"description,omitempty"`
+		Default     *ParameterValue `json:"default,omitempty"`
+		Min         *ParameterValue `json:"min,omitempty"`
+		Max         *ParameterValue `json:"max,omitempty"`
+	}
+
+	// ParameterValue is a number or a duration. It is written as a JSON
+	// number or as a string: "90m" is a duration, "32" a number, so that a
+	// value typed on a command line needs no quoting rules of its own.
+	ParameterValue struct {
+		number     float64
+		duration   time.Duration
+		isDuration bool
+	}
+
+	// TemplateRun records which template a run was rendered from and with
+	// what: every parameter's value, defaults included, and the template as
+	// it was when the run was started, since the stored one may be edited
+	// afterwards.
+	TemplateRun struct {
+		Name     string                    `json:"name"`
+		Values   map[string]ParameterValue `json:"values"`
+		Template *Program                  `json:"template"`
+	}
+
+	// stepBindings maps the JSON name of a step field to the expression its
+	// value is computed from. It is held through a pointer so that
+	// ProgramStep stays comparable.
+	stepBindings map[string]string
+)
+
+// templateFields are the step fields a template may compute from its
+// parameters.
+var templateFields = []string{"temperature_target", "runtime", "moisture_target", "max_cooling_rate"}
+
+var (
+	parameterName         = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	errUnrenderedTemplate = errors.New("program is a template, which must be rendered with its parameter values before it is validated")
+)
+
+func NumberValue(number float64) ParameterValue {
+	return ParameterValue{number: number}
+}
+
+func DurationValue(duration time.Duration) ParameterValue {
+	return ParameterValue{duration: duration, isDuration: true}
+}
+
+func (v ParameterValue) Number() float64 {
+	return v.number
+}
+
+func (v ParameterValue) Duration() time.Duration {
+	return v.duration
+}
+
+func (v ParameterValue) IsDuration() bool {
+	return v.isDuration
+}
+
+func (v ParameterValue) String() string {
+	if v.isDuration {
+		return v.duration.String()
+	}
+	return strconv.FormatFloat(v.number, 'f', -1, 64)
+}
+
+// MarshalJSON implements json.Marshaler for ParameterValue
+func (v ParameterValue) MarshalJSON() ([]byte, error) {
+	if v.isDuration {
+		return json.Marshal(v.duration.String())
+	}
+	return json.Marshal(v.number)
+}
+
+// UnmarshalJSON implements json.Unmarshaler for ParameterValue
+func (v *ParameterValue) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		var number float64
+		if err := json.Unmarshal(data, &number); err != nil {
+			return errors.New("a parameter value must be a number or a duration")
+		}
+		*v = NumberValue(number)
+		return nil
+	}
+	value, err := ParseParameterValue(s)
+	if err != nil {
+		return err
+	}
+	*v = value
+	return nil
+}
+
+// ParseParameterValue reads a value as it is written in a string: a number if
+// it is one, a duration otherwise. ParseFloat also reads "NaN" and "Inf",
+// which no bound or step field can make sense of, so they are refused.
+func ParseParameterValue(s string) (ParameterValue, error) {
+	s = strings.TrimSpace(s)
+	if number, err := strconv.ParseFloat(s, 64); err == nil {
+		if math.IsNaN(number) || math.IsInf(number, 0) {
+			return ParameterValue{}, fmt.Errorf("%q is not a finite number", s)
+		}
+		return NumberValue(number), nil
+	}
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return ParameterValue{}, fmt.Errorf("%q is neither a number nor a duration", s)
+	}
+	return DurationValue(duration), nil
+}
+
+// compare orders two values of the same type.
+func (v ParameterValue) compare(other ParameterValue) int {
+	if v.isDuration {
+		return cmp.Compare(v.duration, other.duration)
+	}
+	return cmp.Compare(v.number, other.number)
+}
+
+func (v ParameterValue) typeName() ParameterType {
+	if v.isDuration {
+		return ParameterTypeDuration
+	}
+	return ParameterTypeNumber
+}
+
+// IsTemplate reports whether the program declares parameters or computes any
+// step field from them, and so has to be rendered before it can run.
+func (p *Program) IsTemplate() bool {
+	return len(p.Parameters) > 0 || hasBindings(p.ProgramSteps)
+}
+
+func hasBindings(steps []ProgramStep) bool {
+	for i := range steps {
+		if steps[i].bindings != nil {
+			return true
+		}
+		if steps[i].Repeat != nil && hasBindings(steps[i].Repeat.Steps) {
+			return true
+		}
+	}
+	return false
+}
+
+// Render turns a template into a program that can run: every field written as
+// "{{expression}}" gets the expression's value, worked out from the parameter
+// values given and the defaults of those that are not. What it was rendered
+// from is kept in FromTemplate. A program that is not a template is left as
+// it is.
+//
+// Render checks the parameters and what the expressions make of them; whether
+// the program it renders makes sense is up to Validate, as for any other.
+func (p *Program) Render(values map[string]ParameterValue) error {
+	if !p.IsTemplate() {
+		if len(values) > 0 {
+			return fmt.Errorf("program %q has no parameters to set", p.ProgramName)
+		}
+		return nil
+	}
+
+	resolved, err := p.resolveParameters(values)
+	if err != nil {
+		return err
+	}
+	template, err := p.Duplicate()
+	if err != nil {
+		return err
+	}
+	if err := renderSteps(p.ProgramSteps, resolved); err != nil {
+		return err
+	}
+
+	p.Parameters = nil
+	p.FromTemplate = &TemplateRun{Name: p.ProgramName, Values: resolved, Template: &template}
+	return nil
+}
+
+// resolveParameters checks the declarations and gives every parameter its
+// value.
+func (p *Program) resolveParameters(values map[string]ParameterValue) (map[string]ParameterValue, error) {
+	resolved := make(map[string]ParameterValue, len(p.Parameters))
+	declared := make(map[string]bool, len(p.Parameters))
+	for i := range p.Parameters {
+		parameter := &p.Parameters[i]
+		if err := parameter.validate(); err != nil {
+			return nil, err
+		}
+		if declared[parameter.Name] {
+			return nil, fmt.Errorf("parameter %q is declared twice", parameter.Name)
+		}
+		declared[parameter.Name] = true
+
+		value, given := values[parameter.Name]
+		if !given {
+			if parameter.Default == nil {
+				return nil, fmt.Errorf("parameter %q has no default, so it needs a value", parameter.Name)
+			}
+			value = *parameter.Default
+		}
+		if err := parameter.check(value); err != nil {
+			return nil, err
+		}
+		resolved[parameter.Name] = value
+	}
+
+	for name := range values {
+		if !declared[name] {
+			return nil, fmt.Errorf("program %q has no parameter %q", p.ProgramName, name)
+		}
+	}
+	return resolved, nil
+}
+
+func (p *ProgramParameter) validate() error {
+	if !parameterName.MatchString(p.Name) {
+		return fmt.Errorf("parameter name %q must be letters, digits and underscores, not starting with a digit", p.Name)
+	}
+	if p.Type != ParameterTypeNumber && p.Type != ParameterTypeDuration {
+		return fmt.Errorf("parameter %q type must be %q or %q", p.Name, ParameterTypeNumber, ParameterTypeDuration)
+	}
+	for _, bound := range []*ParameterValue{p.Min, p.Max, p.Default} {
+		if bound != nil && bound.typeName() != p.Type {
+			return fmt.Errorf("parameter %q is a %s, so its default and range must be too", p.Name, p.Type)
+		}
+	}
+	if p.Min != nil && p.Max != nil && p.Min.compare(*p.Max) > 0 {
+		return fmt.Errorf("parameter %q min must not be above its max", p.Name)
+	}
+	if p.Default != nil {
+		if err := p.check(*p.Default); err != nil {
+			return fmt.Errorf("default: %w", err)
+		}
+	}
+	return nil
+}
+
+// check tells whether a value is of the parameter's type and in its range.
+func (p *ProgramParameter) check(value ParameterValue) error {
+	if value.typeName() != p.Type {
+		return fmt.Errorf("parameter %q must be a %s, not %s", p.Name, p.Type, value)
+	}
+	if p.Min != nil && value.compare(*p.Min) < 0 {
+		return fmt.Errorf("parameter %q must be at least %s, not %s", p.Name, p.Min, value)
+	}
+	if p.Max != nil && value.compare(*p.Max) > 0 {
+		return fmt.Errorf("parameter %q must be at most %s, not %s", p.Name, p.Max, value)
+	}
+	return nil
+}
+
+// renderSteps computes the bound fields of the steps, and of those inside
+// repeat blocks, from the parameter values.
+func renderSteps(steps []ProgramStep, values map[string]ParameterValue) error {
+	for i := range steps {
+		step := &steps[i]
+		if step.Repeat != nil {
+			if err := renderSteps(step.Repeat.Steps, values); err != nil {
+				return err
+			}
+		}
+		if step.bindings == nil {
+			continue
+		}
+		for _, field := range templateFields {
+			expression, bound := (*step.bindings)[field]
+			if !bound {
+				continue
+			}
+			value, err := evaluate(expression, values)
+			if err != nil {
+				return fmt.Errorf("step %d (%s) %s: %w", i+1, step.Name, field, err)
+			}
+			if err := step.setField(field, value); err != nil {
+				return fmt.Errorf("step %d (%s) %s: %w", i+1, step.Name, field, err)
+			}
+		}
+		step.bindings = nil
+	}
+	return nil
+}
+
+// setField stores an expression's value in the field it was bound to. A
+// temperature target is rounded to the nearest degree and a runtime to the
+// second, since thickness-based arithmetic rarely comes out even.
+func (p *ProgramStep) setField(field string, value ParameterValue) error {
+	if field == "runtime" {
+		if !value.isDuration {
+			return fmt.Errorf("must be a duration, not the number %s", value)
+		}
+		if value.duration <= 0 {
+			return fmt.Errorf("must be longer than zero, not %s", value)
+		}
+		p.Runtime = &StepDuration{value.duration.Round(time.Second)}
+		return nil
+	}
+
+	if value.isDuration {
+		return fmt.Errorf("must be a number, not the duration %s", value)
+	}
+	if math.IsNaN(value.number) || math.IsInf(value.number, 0) {
+		return fmt.Errorf("%s is not a finite number", value)
+	}
+	switch field {
+	case "temperature_target":
+		target := math.Round(value.number)
+		if target < 0 || target > math.MaxUint16 {
+			return fmt.Errorf("%s is not a temperature", value)
+		}
+		p.TargetTemperature = uint16(target)
+	case "moisture_target":
+		moisture := float32(value.number)
+		p.TargetMoisture = &moisture
+	case "max_cooling_rate":
+		rate := float32(value.number)
+		p.MaxCoolingRate = &rate
+	}
+	return nil
+}
+
+// placeholder returns the expression in a field written as "{{expression}}".
+func placeholder(raw json.RawMessage) (string, bool) {
+	var s string
+	if len(raw) == 0 || json.Unmarshal(raw, &s) != nil {
+		return "", false
+	}
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "{{") || !strings.HasSuffix(s, "}}") {
+		return "", false
+	}
+	return strings.TrimSpace(s[2 : len(s)-2]), true
+}
+
+// MarshalJSON implements json.Marshaler for ProgramStep, writing the fields a
+// template computes back as their placeholders.
+func (p ProgramStep) MarshalJSON() ([]byte, error) {
+	type plainStep ProgramStep
+	data, err := json.Marshal(plainStep(p))
+	if err != nil || p.bindings == nil {
+		return data, err
+	}
+
+	out := data[:len(data)-1]
+	for _, field := range templateFields {
+		expression, bound := (*p.bindings)[field]
+		if !bound {
+			continue
+		}
+		value, err := json.Marshal("{{" + expression + "}}")
+		if err != nil {
+			return nil, err
+		}
+		out = fmt.Appendf(out, ",%q:%s", field, value)
+	}
+	return append(out, '}'), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler for ProgramStep. A field a
+// template computes cannot be decoded into its type until the template is
+// rendered, so its placeholder is set aside in the step's bindings instead.
+func (p *ProgramStep) UnmarshalJSON(data []byte) error {
+	type plainStep ProgramStep
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var bindings stepBindings
+	for _, field := range templateFields {
+		expression, ok := placeholder(fields[field])
+		if !ok {
+			continue
+		}
+		if bindings == nil {
+			bindings = stepBindings{}
+		}
+		bindings[field] = expression
+		delete(fields, field)
+	}
+	if bindings != nil {
+		var err error
+		if data, err = json.Marshal(fields); err != nil {
+			return err
+		}
+	}
+
+	var step plainStep
+	if err := json.Unmarshal(data, &step); err != nil {
+		return err
+	}
+	*p = ProgramStep(step)
+	if bindings != nil {
+		p.bindings = &bindings
+	}
+	return nil
+}
+
+// The expressions a template computes a field from are arithmetic on its
+// parameters: numbers, durations such as 90m or 1h30m, the four operators
+// and parentheses. A number times a duration is a duration, and a duration
+// over a duration is a number, so "thickness * 45m" is a runtime.
+
+type expressionParser struct {
+	tokens []string
+	pos    int
+	values map[string]ParameterValue
+}
+
+func evaluate(expression string, values map[string]ParameterValue) (ParameterValue, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return ParameterValue{}, err
+	}
+	if len(tokens) == 0 {
+		return ParameterValue{}, errors.New("empty expression")
+	}
+	parser := &expressionParser{tokens: tokens, values: values}
+	value, err := parser.sum()
+	if err != nil {
+		return ParameterValue{}, err
+	}
+	if parser.pos < len(tokens) {
+		return ParameterValue{}, fmt.Errorf("unexpected %q in %q", tokens[parser.pos], expression)
+	}
+	return value, nil
+}
+
+func tokenize(expression string) ([]string, error) {
+	var tokens []string
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case strings.ContainsRune("+-*/()", r):
+			tokens = append(tokens, string(r))
+			i++
+		case unicode.IsDigit(r) || r == '.' || r == '_' || unicode.IsLetter(r):
+			// A number runs on into its unit letters, and a unit into the
+			// next number, so 1h30m is one token.
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.' || runes[j] == '_' || unicode.IsLetter(runes[j])) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected %q in %q", r, expression)
+		}
+	}
+	return tokens, nil
+}
+
+func (e *expressionParser) peek() string {
+	if e.pos < len(e.tokens) {
+		return e.tokens[e.pos]
+	}
+	return ""
+}
+
+func (e *expressionParser) sum() (ParameterValue, error) {
+	left, err := e.product()
+	if err != nil {
+		return ParameterValue{}, err
+	}
+	for e.peek() == "+" || e.peek() == "-" {
+		operator := e.tokens[e.pos]
+		e.pos++
+		right, err := e.product()
+		if err != nil {
+			return ParameterValue{}, err
+		}
+		if left.isDuration != right.isDuration {
+			return ParameterValue{}, fmt.Errorf("cannot add or subtract a %s and a %s", left.typeName(), right.typeName())
+		}
+		if operator == "-" {
+			right = negate(right)
+		}
+		if left.isDuration {
+			left = DurationValue(left.duration + right.duration)
+		} else {
+			left = NumberValue(left.number + right.number)
+		}
+	}
+	return left, nil
+}
+
+func (e *expressionParser) product() (ParameterValue, error) {
+	left, err := e.unary()
+	if err != nil {
+		return ParameterValue{}, err
+	}
+	for e.peek() == "*" || e.peek() == "/" {
+		operator := e.tokens[e.pos]
+		e.pos++
+		right, err := e.unary()
+		if err != nil {
+			return ParameterValue{}, err
+		}
+		if operator == "*" {
+			left, err = multiply(left, right)
+		} else {
+			left, err = divide(left, right)
+		}
+		if err != nil {
+			return ParameterValue{}, err
+		}
+	}
+	return left, nil
+}
+
+func (e *expressionParser) unary() (ParameterValue, error) {
+	if e.peek() == "-" {
+		e.pos++
+		value, err := e.unary()
+		return negate(value), err
+	}
+	return e.operand()
+}
+
+func (e *expressionParser) operand() (ParameterValue, error) {
+	token := e.peek()
+	e.pos++
+	switch {
+	case token == "":
+		return ParameterValue{}, errors.New("expression ends too soon")
+	case token == "(":
+		value, err := e.sum()
+		if err != nil {
+			return ParameterValue{}, err
+		}
+		if e.peek() != ")" {
+			return ParameterValue{}, errors.New("missing )")
+		}
+		e.pos++
+		return value, nil
+	case parameterName.MatchString(token):
+		value, ok := e.values[token]
+		if !ok {
+			return ParameterValue{}, fmt.Errorf("unknown parameter %q", token)
+		}
+		return value, nil
+	case slices.Contains([]string{")", "+", "*", "/"}, token):
+		return ParameterValue{}, fmt.Errorf("unexpected %q", token)
+	}
+	value, err := ParseParameterValue(token)
+	if err != nil {
+		return ParameterValue{}, err
+	}
+	return value, nil
+}
+
+func negate(value ParameterValue) ParameterValue {
+	if value.isDuration {
+		return DurationValue(-value.duration)
+	}
+	return NumberValue(-value.number)
+}
+
+func multiply(left, right ParameterValue) (ParameterValue, error) {
+	switch {
+	case left.isDuration && right.isDuration:
+		return ParameterValue{}, errors.New("cannot multiply a duration by a duration")
+	case left.isDuration:
+		return DurationValue(time.Duration(float64(left.duration) * right.number)), nil
+	case right.isDuration:
+		return DurationValue(time.Duration(left.number * float64(right.duration))), nil
+	}
+	return NumberValue(left.number * right.number), nil
+}
+
+func divide(left, right ParameterValue) (ParameterValue, error) {
+	if (right.isDuration && right.duration == 0) || (!right.isDuration && right.number == 0) {
+		return ParameterValue{}, errors.New("division by zero")
+	}
+	switch {
+	case left.isDuration && right.isDuration:
+		return NumberValue(float64(left.duration) / float64(right.duration)), nil
+	case left.isDuration:
+		return DurationValue(time.Duration(float64(left.duration) / right.number)), nil
+	case right.isDuration:
+		return ParameterValue{}, errors.New("cannot divide a number by a duration")
+	}
+	return NumberValue(left.number / right.number), nil
+}
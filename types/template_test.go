@@ -0,0 +1,214 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// oakTemplate is a stored template as an operator would write it: the drying
+// temperature and how long to hold it follow from the board thickness.
+const oakTemplate = `{
+  "name": "Oak",
+  "parameters": [
+    {"name": "final_temp", "type": "number", "default": 60, "min": 40, "max": 80},
+    {"name": "thickness", "type": "number", "min": 10, "max": 100},
+    {"name": "soak", "type": "duration", "default": "30m", "min": "10m"}
+  ],
+  "steps": [
+    {"name": "heat", "type": "heating", "temperature_target": "{{ final_temp }}",
+     "heater": {"min_delta": 5, "max_delta": 10}},
+    {"name": "hold", "type": "acclimate", "temperature_target": "{{final_temp}}",
+     "runtime": "{{ thickness * 15m + soak }}", "heater": {"min_delta": -1, "max_delta": 3}},
+    {"name": "cool", "type": "cooling", "temperature_target": 30, "runtime": "2h"}
+  ]
+}`
+
+func loadTemplate(t *testing.T) Program {
+	t.Helper()
+
+	var program Program
+	if err := json.Unmarshal([]byte(oakTemplate), &program); err != nil {
+		t.Fatalf("decoding the template: %v", err)
+	}
+	return program
+}
+
+func durationValue(d time.Duration) *ParameterValue {
+	value := DurationValue(d)
+	return &value
+}
+
+func TestRenderTemplate(t *testing.T) {
+	program := loadTemplate(t)
+	if !program.IsTemplate() {
+		t.Fatal("template not recognised as one")
+	}
+
+	err := program.Render(map[string]ParameterValue{"thickness": NumberValue(32), "final_temp": NumberValue(65.4)})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	if program.IsTemplate() {
+		t.Error("still a template after rendering")
+	}
+	if got := program.ProgramSteps[0].TargetTemperature; got != 65 {
+		t.Errorf("heating target = %d, want 65", got)
+	}
+	if got := program.ProgramSteps[1].Runtime.Duration; got != 8*time.Hour+30*time.Minute {
+		t.Errorf("hold runtime = %s, want 8h30m", got)
+	}
+
+	// The run keeps every value it was rendered with and the template as
+	// it was, placeholders and all.
+	run := program.FromTemplate
+	if run == nil || run.Name != "Oak" {
+		t.Fatalf("from_template = %+v, want it to name the template", run)
+	}
+	if soak := run.Values["soak"]; !soak.IsDuration() || soak.Duration() != 30*time.Minute {
+		t.Errorf("recorded soak = %s, want the 30m default", soak)
+	}
+	if !run.Template.IsTemplate() || len(run.Template.Parameters) != 3 {
+		t.Error("recorded template lost its parameters")
+	}
+
+	program.ApplyDefaults(templateDefaults(t))
+	if err := program.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+// A stored template is written back as it was read, so that storage keeps the
+// placeholders rather than the zeros decoding left in their place.
+func TestTemplateRoundTrip(t *testing.T) {
+	program := loadTemplate(t)
+
+	data, err := json.Marshal(program)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	if !strings.Contains(string(data), `"runtime":"{{thickness * 15m + soak}}"`) {
+		t.Fatalf("placeholder not written back: %s", data)
+	}
+
+	var again Program
+	if err := json.Unmarshal(data, &again); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if err := again.Render(map[string]ParameterValue{"thickness": NumberValue(20)}); err != nil {
+		t.Fatalf("Render after a round trip: %v", err)
+	}
+	if got := again.ProgramSteps[1].Runtime.Duration; got != 5*time.Hour+30*time.Minute {
+		t.Errorf("hold runtime = %s, want 5h30m", got)
+	}
+}
+
+func TestValidateRefusesUnrenderedTemplate(t *testing.T) {
+	program := loadTemplate(t)
+	program.ApplyDefaults(templateDefaults(t))
+
+	if err := program.Validate(); !errors.Is(err, errUnrenderedTemplate) {
+		t.Fatalf("Validate = %v, want %v", err, errUnrenderedTemplate)
+	}
+}
+
+func TestRenderRefuses(t *testing.T) {
+	thick := map[string]ParameterValue{"thickness": NumberValue(32)}
+
+	tests := []struct {
+		name     string
+		modify   func(*Program)
+		values   map[string]ParameterValue
+		contains string
+	}{
+		{"missing value", func(*Program) {}, nil, `"thickness" has no default`},
+		{"unknown parameter", func(*Program) {}, map[string]ParameterValue{"thickness": NumberValue(32), "species": NumberValue(1)}, `no parameter "species"`},
+		{"below the range", func(*Program) {}, map[string]ParameterValue{"thickness": NumberValue(5)}, "at least 10"},
+		{"above the range", func(*Program) {}, map[string]ParameterValue{"thickness": NumberValue(32), "final_temp": NumberValue(90)}, "at most 80"},
+		{"wrong type", func(*Program) {}, map[string]ParameterValue{"thickness": DurationValue(time.Hour)}, "must be a number"},
+		{"default out of range", func(p *Program) { p.Parameters[2].Default = durationValue(time.Minute) }, thick, "default"},
+		{"range of the wrong type", func(p *Program) { p.Parameters[0].Max = durationValue(time.Hour) }, thick, "must be too"},
+		{"declared twice", func(p *Program) { p.Parameters[1].Name = "final_temp" }, thick, "declared twice"},
+		{"bad name", func(p *Program) { p.Parameters[0].Name = "final temp" }, thick, "letters, digits and underscores"},
+		{"unknown type", func(p *Program) { p.Parameters[0].Type = "text" }, thick, "type must be"},
+		{"undeclared in an expression", func(p *Program) { (*p.ProgramSteps[0].bindings)["temperature_target"] = "top_temp" }, thick, `unknown parameter "top_temp"`},
+		{"number as a runtime", func(p *Program) { (*p.ProgramSteps[1].bindings)["runtime"] = "thickness * 15" }, thick, "must be a duration"},
+		{"duration as a temperature", func(p *Program) { (*p.ProgramSteps[0].bindings)["temperature_target"] = "soak" }, thick, "must be a number"},
+		{"negative runtime", func(p *Program) { (*p.ProgramSteps[1].bindings)["runtime"] = "soak - 1h" }, thick, "longer than zero"},
+		{"mixed sum", func(p *Program) { (*p.ProgramSteps[1].bindings)["runtime"] = "soak + 1" }, thick, "cannot add or subtract"},
+		{"division by zero", func(p *Program) {
+			(*p.ProgramSteps[0].bindings)["temperature_target"] = "final_temp / (thickness - 32)"
+		}, thick, "division by zero"},
+		{"overflow", func(p *Program) {
+			(*p.ProgramSteps[0].bindings)["temperature_target"] = "final_temp * 1e308 * 10"
+		}, thick, "not a finite number"},
+		{"bad syntax", func(p *Program) { (*p.ProgramSteps[0].bindings)["temperature_target"] = "final_temp +" }, thick, "ends too soon"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := loadTemplate(t)
+			tt.modify(&program)
+
+			err := program.Render(tt.values)
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Fatalf("Render = %v, want an error containing %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+// A program with nothing to fill in renders to itself, unless it is handed
+// values it has no parameters for.
+func TestRenderPlainProgram(t *testing.T) {
+	program := validProgram()
+
+	if err := program.Render(nil); err != nil || program.FromTemplate != nil {
+		t.Fatalf("Render = %v with from_template %+v, want the program left alone", err, program.FromTemplate)
+	}
+	if err := program.Render(map[string]ParameterValue{"thickness": NumberValue(32)}); err == nil {
+		t.Fatal("values for a program without parameters accepted")
+	}
+}
+
+func TestParseParameterValueRefusesNonFiniteNumbers(t *testing.T) {
+	for _, s := range []string{"NaN", "nan", "Inf", "+Inf", "-Inf", "infinity"} {
+		if value, err := ParseParameterValue(s); err == nil {
+			t.Errorf("ParseParameterValue(%q) = %s, want an error", s, value)
+		}
+	}
+	if value, err := ParseParameterValue("1e3"); err != nil || value.String() != "1000" {
+		t.Errorf("ParseParameterValue(1e3) = %s, %v, want 1000", value, err)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	values := map[string]ParameterValue{"thickness": NumberValue(25), "soak": DurationValue(time.Hour)}
+
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{"thickness", "25"},
+		{"-thickness + 30", "5"},
+		{"2 * (thickness + 5) / 4", "15"},
+		{"thickness * 1h30m", "37h30m0s"},
+		{"soak / 2", "30m0s"},
+		{"soak / 15m", "4"},
+		{"soak * 2 - 30m", "1h30m0s"},
+	}
+
+	for _, tt := range tests {
+		value, err := evaluate(tt.expression, values)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expression, err)
+			continue
+		}
+		if value.String() != tt.want {
+			t.Errorf("%s = %s, want %s", tt.expression, value, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// ProgramClassDrying is every ordinary program, and what a program that
+	// names no class is. ProgramClassThermalModification is the tonewood
+	// and cladding treatment that takes the kiln to 160-220°C, which a
+	// program has to ask for by name and the kiln has to be configured for.
+	ProgramClassDrying              ProgramClass = "drying"
+	ProgramClassThermalModification ProgramClass = "thermal_modification"
+)
+
+type (
+	ProgramClass string
+
+	// ThermalModificationDefaults is what a kiln built for thermal
+	// modification can do, and a kiln without it refuses such programs. Its
+	// MaxTargetTemperature replaces max_target_temperature for them. Above
+	// SteamBlanketAbove °C the wood would burn in air, so the kiln has to be
+	// kept full of steam, at BlanketSteamPower percent or more with the vent
+	// shut, and an open door there fails the run whatever door_open_action
+	// says. MaxCoolingRate, in °C an hour, is how fast the kiln may come back
+	// down through the blanketed range: hot, dry wood cracks if it is cooled
+	// faster.
+	ThermalModificationDefaults struct {
+		MaxTargetTemperature uint16  `json:"max_target_temperature"`
+		SteamBlanketAbove    uint16  `json:"steam_blanket_above"`
+		BlanketSteamPower    uint8   `json:"blanket_steam_power"`
+		MaxCoolingRate       float32 `json:"max_cooling_rate"`
+	}
+)
+
+func (t *ThermalModificationDefaults) validate(defaults *Defaults) error {
+	if t == nil {
+		return nil
+	}
+	// Below the steam ceiling steam heats the kiln, so a blanket there
+	// would fight the heater instead of standing in for the air.
+	if t.SteamBlanketAbove < *defaults.SteamCeiling {
+		return fmt.Errorf("controlunit defaults: thermal_modification.steam_blanket_above must be at least steam_ceiling (%d°C)",
+			*defaults.SteamCeiling)
+	}
+	if t.MaxTargetTemperature <= t.SteamBlanketAbove {
+		return errors.New("controlunit defaults: thermal_modification.max_target_temperature must be above steam_blanket_above")
+	}
+	if t.BlanketSteamPower == 0 || t.BlanketSteamPower > 100 {
+		return errors.New("controlunit defaults: thermal_modification.blanket_steam_power must be between 1 and 100")
+	}
+	if t.MaxCoolingRate <= 0 {
+		return errors.New("controlunit defaults: thermal_modification.max_cooling_rate must be greater than zero")
+	}
+	return nil
+}
+
+// IsThermalModification reports whether the program is of the thermal
+// modification class.
+func (p *Program) IsThermalModification() bool {
+	return p.Class == ProgramClassThermalModification
+}
+
+// targetTemperatureLimit is the highest target the program's class allows.
+func (p *Program) targetTemperatureLimit() uint16 {
+	if p.IsThermalModification() && p.thermal != nil {
+		return p.thermal.MaxTargetTemperature
+	}
+	return p.maxTargetTemperature
+}
+
+// kilnPeakTemperature is the hottest the step can take the kiln, given the
+// hottest it can be as the step begins. A delta-banded step may run the kiln
+// up to target+max_delta; a cooling step only ever brings it down from where
+// it was handed over.
+func (p *ProgramStep) kilnPeakTemperature(entry float32) float32 {
+	if p.StepType == StepTypeCooling {
+		return entry
+	}
+	peak := float32(p.TargetTemperature)
+	if p.Heater != nil && p.Heater.MaxDelta != nil {
+		peak += *p.Heater.MaxDelta
+	}
+	return max(peak, entry)
+}
+
+// blanketedSteps says, step by step, whether the kiln can be above the steam
+// blanket threshold during it. A kiln without thermal modification settings
+// has no threshold and blankets nothing.
+func (p *Program) blanketedSteps() []bool {
+	blanketed := make([]bool, len(p.ProgramSteps))
+	if p.thermal == nil {
+		return blanketed
+	}
+
+	entry := float32(0)
+	for i := range p.ProgramSteps {
+		step := &p.ProgramSteps[i]
+		peak := step.kilnPeakTemperature(entry)
+		blanketed[i] = peak > float32(p.thermal.SteamBlanketAbove)
+		// A cooling step hands over once the material is down to its
+		// target, with the kiln below it; the others can hand over at
+		// their peak.
+		if step.StepType == StepTypeCooling {
+			entry = float32(step.TargetTemperature)
+		} else {
+			entry = peak
+		}
+	}
+	return blanketed
+}
+
+func (p *Program) validateClass() error {
+	switch p.Class {
+	case "", ProgramClassDrying:
+		return nil
+	case ProgramClassThermalModification:
+	default:
+		return fmt.Errorf("program class must be %q or %q, not %q",
+			ProgramClassDrying, ProgramClassThermalModification, p.Class)
+	}
+	if p.thermal == nil {
+		return errors.New("this kiln is not configured for thermal_modification programs")
+	}
+	// The steam generator has to be proved before the kiln heats, or the
+	// blanket could be missing by the time the kiln needs it.
+	if !*p.Equalize.SteamPrewarm {
+		return errors.New("thermal_modification program must warm the steam generator up first (equalize steam_prewarm)")
+	}
+	return nil
+}
+
+// validateSteamBlanket holds every step that can take the kiln above the
+// blanket threshold to a steam blanket: steam at blanket power or more, held
+// constant so nothing backs it off, and the vent shut so the steam stays in.
+// Only a thermal modification program may go there at all, so that a
+// drying program on a kiln built for both cannot wander up by accident.
+func (p *Program) validateSteamBlanket(blanketed []bool) error {
+	for i := range p.ProgramSteps {
+		step := &p.ProgramSteps[i]
+		if !blanketed[i] {
+			continue
+		}
+		if !p.IsThermalModification() {
+			return fmt.Errorf("step %q can take the kiln above %d°C, which only a %q program may do",
+				step.Name, p.thermal.SteamBlanketAbove, ProgramClassThermalModification)
+		}
+		if step.Steam.Type != PowerSettingTypeSimple || step.Steam.Power == nil || *step.Steam.Power < p.thermal.BlanketSteamPower {
+			return fmt.Errorf("step %q can take the kiln above %d°C, so it must hold steam at %d%% or more",
+				step.Name, p.thermal.SteamBlanketAbove, p.thermal.BlanketSteamPower)
+		}
+		if step.Vent != nil && !(step.Vent.Type == PowerSettingTypeSimple && step.Vent.Power != nil && *step.Vent.Power == 0) {
+			return fmt.Errorf("step %q can take the kiln above %d°C, so it must keep the vent shut",
+				step.Name, p.thermal.SteamBlanketAbove)
+		}
+		// The blanket comes off in a cooling step of its own, once the
+		// kiln is back below the threshold.
+		if step.StepType == StepTypeCooling && step.TargetTemperature < p.thermal.SteamBlanketAbove {
+			return fmt.Errorf("cooling step %q starts above %d°C, so it must stop there and leave the rest to a step without the blanket",
+				step.Name, p.thermal.SteamBlanketAbove)
+		}
+	}
+	return nil
+}
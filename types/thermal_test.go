@@ -0,0 +1,133 @@
+package types
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func thermalDefaults(t *testing.T) *Defaults {
+	t.Helper()
+	defaults := templateDefaults(t)
+	defaults.ThermalModification = &ThermalModificationDefaults{
+		MaxTargetTemperature: 220, SteamBlanketAbove: 150, BlanketSteamPower: 80, MaxCoolingRate: 10,
+	}
+	return defaults
+}
+
+// thermalProgram heats to the steam ceiling with steam off, takes the kiln
+// up to 210°C under the blanket, holds it there, and cools back to the
+// threshold before the blanket comes off.
+func thermalProgram() Program {
+	blanket := func() *PowerPidSettings { return &PowerPidSettings{Power: u8(80)} }
+	withFan := func(step ProgramStep) ProgramStep {
+		step.Fan = &PowerPidSettings{Power: u8(100)}
+		return step
+	}
+	return Program{
+		ProgramName: "thermo-ash",
+		Class:       ProgramClassThermalModification,
+		Equalize:    &EqualizeSettings{SteamPrewarm: b(true)},
+		ProgramSteps: []ProgramStep{
+			withFan(steamHeatingStep(100, &PowerPidSettings{Power: u8(0)})),
+			withFan(steamHeatingStep(210, blanket())),
+			withFan(ProgramStep{Name: "treat", StepType: StepTypeAcclimate, TargetTemperature: 210,
+				Runtime: &StepDuration{3 * time.Hour}, Steam: blanket()}),
+			withFan(steamCoolingStep(150, blanket())),
+			withFan(steamCoolingStep(40, &PowerPidSettings{Power: u8(0)})),
+		},
+	}
+}
+
+func TestThermalModificationProgram(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(*Program, *Defaults)
+		contains string
+	}{
+		{"valid", func(*Program, *Defaults) {}, ""},
+		{"kiln without the settings", func(_ *Program, d *Defaults) { d.ThermalModification = nil }, "not configured"},
+		{"unknown class", func(p *Program, _ *Defaults) { p.Class = "torrefaction" }, "program class must be"},
+		{"as a drying program", func(p *Program, _ *Defaults) { p.Class = "" }, "only a \"thermal_modification\" program"},
+		{"without steam warm-up", func(p *Program, _ *Defaults) { p.Equalize.SteamPrewarm = b(false) }, "steam_prewarm"},
+		{"above the thermal limit", func(p *Program, _ *Defaults) {
+			p.ProgramSteps[1].TargetTemperature = 230
+			p.ProgramSteps[2].TargetTemperature = 230
+		}, "must not exceed 220"},
+		{"thin blanket", func(p *Program, _ *Defaults) { p.ProgramSteps[2].Steam.Power = u8(50) }, "steam at 80% or more"},
+		{"blanket on the delta", func(p *Program, _ *Defaults) {
+			p.ProgramSteps[1].Steam = &PowerPidSettings{MinDelta: f32(5), MaxDelta: f32(10)}
+		}, "steam at 80% or more"},
+		{"vent open", func(p *Program, _ *Defaults) { p.ProgramSteps[2].Vent = &PowerPidSettings{Power: u8(20)} }, "vent shut"},
+		{"blanket off too early", func(p *Program, _ *Defaults) {
+			p.ProgramSteps[3].TargetTemperature = 120
+		}, "must stop there"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			program := thermalProgram()
+			defaults := thermalDefaults(t)
+			tt.modify(&program, defaults)
+			program.ApplyDefaults(defaults)
+
+			err := program.Validate()
+			if tt.contains == "" {
+				if err != nil {
+					t.Fatalf("Validate: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.contains) {
+				t.Fatalf("Validate = %v, want an error containing %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+// A kiln configured for thermal modification still runs its drying programs,
+// as long as the top of their delta bands keeps the kiln at or below the
+// blanket threshold. validProgram heats to 150°C with a 10°C max_delta.
+func TestDryingProgramOnAThermalKiln(t *testing.T) {
+	for _, tt := range []struct {
+		threshold uint16
+		wantErr   bool
+	}{{160, false}, {159, true}} {
+		program := validProgram()
+		defaults := thermalDefaults(t)
+		defaults.ThermalModification.SteamBlanketAbove = tt.threshold
+		program.ApplyDefaults(defaults)
+		if err := program.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("blanket above %d°C: Validate = %v, want error %v", tt.threshold, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadConfigValidatesThermalModification(t *testing.T) {
+	const base = `{"deltas": {"heating": {"min_delta": 5.0, "max_delta": 10.0}, "acclimate": {"min_delta": -1.0, "max_delta": 3.0}}, "fan_power": 0, "steam_power": 0, "max_target_temperature": 200, "steam_ceiling": 100, "sensor_timeout": "120s", "execution_log_interval": "60s", "door_open_action": "pause", "degraded_sensor_action": "continue", "plausibility": {"max_rate": 2.0, "confirm": 3}, "equalize": {"delta": 2.0, "steam_prewarm": false, "steam_prewarm_timeout": "20m"}`
+
+	tests := []struct {
+		name     string
+		defaults string
+		wantErr  bool
+	}{
+		{"left out", base + `}`, false},
+		{"complete", base + `, "thermal_modification": {"max_target_temperature": 220, "steam_blanket_above": 150, "blanket_steam_power": 80, "max_cooling_rate": 10}}`, false},
+		{"blanket below the steam ceiling", base + `, "thermal_modification": {"max_target_temperature": 220, "steam_blanket_above": 90, "blanket_steam_power": 80, "max_cooling_rate": 10}}`, true},
+		{"limit at the threshold", base + `, "thermal_modification": {"max_target_temperature": 150, "steam_blanket_above": 150, "blanket_steam_power": 80, "max_cooling_rate": 10}}`, true},
+		{"no blanket power", base + `, "thermal_modification": {"max_target_temperature": 220, "steam_blanket_above": 150, "max_cooling_rate": 10}}`, true},
+		{"no cooling rate", base + `, "thermal_modification": {"max_target_temperature": 220, "steam_blanket_above": 150, "blanket_steam_power": 80}}`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfigWithDefaults(t, tt.defaults))
+			if tt.wantErr && err == nil {
+				t.Fatal("expected LoadConfig to fail, got nil")
+			}
+			if !tt.wantErr && err != nil {
+				t.Fatalf("LoadConfig: %v", err)
+			}
+		})
+	}
+}
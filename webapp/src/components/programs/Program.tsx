@@ -1,5 +1,5 @@
 import React, { useMemo, useEffect, useRef } from "react";
-import { Program as ApiProgram } from "../../types/api";
+import { Program as ApiProgram, EntityWithMeta, ProgramConflict, RTKQueryError } from "../../types/api";
 import { setEditProgram } from "../../store/features/programsSlice";
 import { useDispatch, useSelector } from "react-redux";
 import { NameComponent } from "../form";
@@ -88,7 +88,7 @@ export const Program: React.FC = () => {
   // All hooks and variables declared once at the top
   const { name } = useParams();
   const { data } = useGetProgramQuery(name || "", { skip: !name || name === "new", refetchOnMountOrArgChange: true });
-  const [saveProgram, { isSuccess }] = useSaveProgramMutation();
+  const [saveProgram, { isSuccess, error: saveError, reset: resetSave }] = useSaveProgramMutation();
   const editProgram = useSelector((state: RootState) => state.programs.editRecord);
   const dispatch = useDispatch();
   const navigate = useNavigate();
@@ -98,11 +98,35 @@ export const Program: React.FC = () => {
   const program = useMemo(() => {
     if (!data) return undefined;
     if (typeof data === 'object' && data !== null && 'data' in data) {
-      return (data as { data: ApiProgram }).data;
+      return (data as { data: EntityWithMeta<ApiProgram> }).data;
     }
-    return data as ApiProgram;
+    return data as EntityWithMeta<ApiProgram>;
   }, [data]);
 
+  // A 409 means someone else saved the program after it was loaded here. The
+  // answer carries their version, so the operator can overwrite it or take it.
+  const conflict = useMemo(() => {
+    const error = saveError as RTKQueryError | undefined;
+    return error?.status === 409 ? (error.data as unknown as ProgramConflict) : undefined;
+  }, [saveError]);
+
+  const saveErrorMessage = useMemo(() => {
+    if (!saveError || conflict) return undefined;
+    const error = saveError as RTKQueryError;
+    return error?.data?.error || error?.message || String(error?.status ?? "unknown error");
+  }, [saveError, conflict]);
+
+  const handleSaveAnyway = () => {
+    if (!editProgram || !conflict || !name) return;
+    saveProgram({ ...normalize(editProgram), isNew: false, etag: conflict.etag });
+  };
+
+  const handleDiscardChanges = () => {
+    if (!conflict) return;
+    dispatch(setEditProgram({ ...conflict.current, etag: conflict.etag }));
+    resetSave();
+  };
+
   // useFormData must be above any useMemo that uses nameUsed
   const {
     editing,
@@ -282,6 +306,32 @@ export const Program: React.FC = () => {
 
         <Divider sx={{ marginBottom: 2 }} />
 
+        {/* Save Errors */}
+        {conflict && (
+          <Alert
+            severity="warning"
+            sx={{ marginBottom: 2 }}
+            action={
+              <Box sx={{ display: "flex", gap: 1 }}>
+                <Button color="inherit" size="small" onClick={handleSaveAnyway}>
+                  Save anyway
+                </Button>
+                <Button color="inherit" size="small" onClick={handleDiscardChanges}>
+                  Discard my changes
+                </Button>
+              </Box>
+            }
+          >
+            {conflict.error}. Save anyway to replace revision {conflict.revision} with your
+            changes, or discard them and continue from the stored program.
+          </Alert>
+        )}
+        {saveErrorMessage && (
+          <Alert severity="error" sx={{ marginBottom: 2 }}>
+            Failed to save program: {saveErrorMessage}
+          </Alert>
+        )}
+
         {/* Validation Errors */}
         {editing && validationErrors.length > 0 && (
           <Alert severity="error" sx={{ marginBottom: 2 }}>
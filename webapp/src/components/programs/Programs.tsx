@@ -133,13 +133,14 @@ export const Programs: React.FC = () => {
   const [sortOrder, setSortOrder] = useState<SortOrder>("asc");
   const [deleteDialogOpen, setDeleteDialogOpen] = useState(false);
   const [programToDelete, setProgramToDelete] = useState<string | null>(null);
+  const [deleteError, setDeleteError] = useState<string | null>(null);
   const [jsonDialogOpen, setJsonDialogOpen] = useState(false);
   const { data, isLoading, error } = useGetProgramsQuery(undefined, { refetchOnMountOrArgChange: true });
   const { data: programData, isLoading: isLoadingProgram } = useGetProgramQuery(selectedProgram || "", {
     skip: !selectedProgram,
     refetchOnMountOrArgChange: true,
   });
-  const [deleteProgram] = useDeleteProgramMutation();
+  const [deleteProgram, { isLoading: isDeleting }] = useDeleteProgramMutation();
   const navigate = useNavigate();
   const dispatch = useDispatch();
 
@@ -176,34 +177,44 @@ export const Programs: React.FC = () => {
     return programs;
   }, [programInfos, sortBy, sortOrder]);
 
-  const selectedProgramData = (() => {
-    if (!programData) return null;
-    // Check if wrapped in APIResponse
-    if (typeof programData === 'object' && 'data' in programData) {
-      return (programData as APIResponse<ApiProgram>).data;
-    }
-    return programData as ApiProgram;
+  // The ETag of the loaded program is kept apart from the program itself, so
+  // it is sent back as If-Match but never run, downloaded or shown.
+  const { program: selectedProgramData, etag: selectedProgramETag } = (() => {
+    if (!programData) return { program: null, etag: undefined };
+    const { etag, ...program } = programData.data;
+    return { program: program as ApiProgram, etag };
   })();
 
   const handleDelete = async (name: string, event: React.MouseEvent) => {
     event.stopPropagation();
+    // Deleting needs the program's current ETag, so load it first.
+    setSelectedProgram(name);
     setProgramToDelete(name);
+    setDeleteError(null);
     setDeleteDialogOpen(true);
   };
 
+  const deleteETag = selectedProgramData?.name === programToDelete ? selectedProgramETag : undefined;
+
   const confirmDelete = async () => {
-    if (programToDelete) {
-      await deleteProgram(programToDelete);
-      if (selectedProgram === programToDelete) {
-        setSelectedProgram(null);
-      }
-      setProgramToDelete(null);
+    if (!programToDelete || !deleteETag) return;
+    try {
+      await deleteProgram({ name: programToDelete, etag: deleteETag }).unwrap();
+    } catch (e: unknown) {
+      const error = e as RTKQueryError;
+      setDeleteError(error?.data?.error || error?.message || String(e));
+      return;
     }
+    if (selectedProgram === programToDelete) {
+      setSelectedProgram(null);
+    }
+    setProgramToDelete(null);
     setDeleteDialogOpen(false);
   };
 
   const cancelDelete = () => {
     setProgramToDelete(null);
+    setDeleteError(null);
     setDeleteDialogOpen(false);
   };
 
@@ -239,7 +250,7 @@ export const Programs: React.FC = () => {
   const handleEdit = (name: string) => {
     // Find the selected program data
     if (selectedProgramData && selectedProgramData.name === name) {
-      dispatch(setEditProgram(selectedProgramData));
+      dispatch(setEditProgram({ ...selectedProgramData, etag: selectedProgramETag }));
     }
     navigate(`/programs/${encodeURIComponent(name)}`);
   };
@@ -501,12 +512,23 @@ export const Programs: React.FC = () => {
           <DialogContentText id="delete-dialog-description">
             Are you sure you want to delete &quot;{programToDelete}&quot;? This action cannot be undone.
           </DialogContentText>
+          {deleteError && (
+            <Alert severity="error" sx={{ mt: 2 }}>
+              Failed to delete program: {deleteError}
+            </Alert>
+          )}
         </DialogContent>
         <DialogActions>
           <Button onClick={cancelDelete} color="primary">
             Cancel
           </Button>
-          <Button onClick={confirmDelete} color="error" variant="contained" autoFocus>
+          <Button
+            onClick={confirmDelete}
+            color="error"
+            variant="contained"
+            disabled={!deleteETag || isDeleting}
+            autoFocus
+          >
             Delete
           </Button>
         </DialogActions>
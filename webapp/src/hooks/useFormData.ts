@@ -127,14 +127,14 @@ export const useFormData = <T extends Named>(props: Props<T>) => {
 
     const { name: editName } = editData;
 
-    dispatch(setEditData(undefined));
-
-    if (name === "new" || (name !== "new" && name !== editName)) {
+    if (isNew || isRename) {
       // Navigating to new name if created or renamed
+      dispatch(setEditData(undefined));
       navigate(`${rootPath}${editName.length ? `/${editName}` : ""}`);
+      setMode("view");
     }
-
-    setMode("view");
+    // An update keeps the edits until the save succeeds, so a refused one
+    // (a conflict with someone else's save) can be retried or discarded.
   };
 
   const handleCancel = () => {
@@ -1,4 +1,4 @@
-import { Program } from "../../types/api";
+import { EntityWithMeta, Program } from "../../types/api";
 import { getJSONFromSessionStorage } from "../../util";
 import { createEntitySlice } from "./entitySlice";
 
@@ -7,8 +7,8 @@ const editKey = "editProgram";
 export const programsSlice = createEntitySlice({
   sliceName: "programs",
   editRecordSessionStorageKey: editKey,
-  initialRecords: [] as Program[],
-  initialEditRecord: getJSONFromSessionStorage<Program>(editKey),
+  initialRecords: [] as EntityWithMeta<Program>[],
+  initialEditRecord: getJSONFromSessionStorage<EntityWithMeta<Program>>(editKey),
   reducers: {},
 });
 
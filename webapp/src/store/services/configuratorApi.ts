@@ -1,7 +1,8 @@
 import { createApi } from "@reduxjs/toolkit/query/react";
-import { fetchBaseQuery } from "@reduxjs/toolkit/query/react";
+import { fetchBaseQuery, FetchBaseQueryMeta } from "@reduxjs/toolkit/query/react";
 import { fetchQuery, saveMutation } from "./queryBuilders";
 import { API_ENDPOINTS } from "../../config/api";
+import { APIResponse, EntityWithMeta, Program } from "../../types/api";
 
 const programsTag = "programs" as const;
 const programsEndpoint = "/programs";
@@ -14,15 +15,22 @@ export const configuratorApi = createApi({
   tagTypes: [programsTag],
   endpoints: (builder) => ({
     getPrograms: fetchQuery(builder, programsEndpoint, programsTag),
-    getProgram: builder.query({
+    getProgram: builder.query<APIResponse<EntityWithMeta<Program>>, string>({
       query: (name: string) => `${programsEndpoint}/${encodeURIComponent(name)}`,
+      // The ETag goes with the record, so that saving or deleting it is
+      // refused if the program has been changed since it was loaded.
+      transformResponse: (response: APIResponse<Program>, meta: FetchBaseQueryMeta | undefined) => ({
+        ...response,
+        data: { ...response.data, etag: meta?.response?.headers.get("ETag") ?? undefined },
+      }),
       providesTags: [programsTag],
     }),
     saveProgram: saveMutation(builder, programsEndpoint, programsTag),
-    deleteProgram: builder.mutation<void, string>({
-      query: (name: string) => ({
+    deleteProgram: builder.mutation<void, { name: string; etag: string }>({
+      query: ({ name, etag }) => ({
         url: `${programsEndpoint}/${encodeURIComponent(name)}`,
         method: "DELETE",
+        headers: { "If-Match": etag },
       }),
       invalidatesTags: [programsTag],
     }),
@@ -56,14 +56,19 @@ export const saveMutation = <T extends { name: string }, Tag extends string, Pat
     query: (record) => {
       // Use explicit isNew flag if present
       const isNew = record.isNew === true;
-      // Remove isNew from payload (eslint: _isNew is intentionally unused)
+      // Remove isNew and etag from payload (eslint: _isNew is intentionally unused)
       // eslint-disable-next-line @typescript-eslint/no-unused-vars
-      const { isNew: _isNew, ...payload } = record;
+      const { isNew: _isNew, etag, ...payload } = record;
       return {
         url: isNew ? endpoint : `${endpoint}/${encodeURIComponent(record.name)}`,
         method: "POST",
         body: JSON.stringify(payload),
-        headers: { "Content-type": "application/json" },
+        // An update is made against the version the record was loaded as,
+        // and refused if someone else has saved since. Without one the
+        // server refuses it too, rather than overwrite whatever is stored.
+        headers: isNew || !etag
+          ? { "Content-type": "application/json" }
+          : { "Content-type": "application/json", "If-Match": etag },
       };
     },
     invalidatesTags: (_, error) => (error ? [] : [{ type: tag, id: list }]),
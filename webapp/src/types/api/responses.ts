@@ -86,9 +86,21 @@ export interface StoredProgramInfo {
 
 /**
  * Utility type for entities with metadata flags
- * Used in forms and mutations
+ * Used in forms and mutations. etag is the stored version the record was
+ * loaded as, sent back as If-Match when it is saved or deleted.
  */
-export type EntityWithMeta<T> = T & { isNew?: boolean };
+export type EntityWithMeta<T> = T & { isNew?: boolean; etag?: string };
+
+/**
+ * A write refused because the stored program changed since it was read
+ * Matches Go's ProgramConflict struct
+ */
+export interface ProgramConflict {
+  error: string;
+  etag: string;
+  revision: number;
+  current: Program;
+}
 
 /**
  * Program with optional fields for form handling